@@ -48,6 +48,17 @@ var (
 			return artifacts.PrintDistArtifacts(projectInfo, projectParam, distgo.ToProductDistIDs(args), artifactsAbsPathFlagVal, cmd.OutOrStdout())
 		},
 	}
+	artifactsAllSubcmd = &cobra.Command{
+		Use:   "all [flags] [product-ids]",
+		Short: "Print the absolute paths to all of the build and distribution artifacts for products",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectInfo, projectParam, err := distgoProjectParamFromFlags()
+			if err != nil {
+				return err
+			}
+			return artifacts.PrintAllArtifacts(projectInfo, projectParam, distgo.ToProductIDs(args), artifactsJSONFlagVal, cmd.OutOrStdout())
+		},
+	}
 	artifactsDockerSubcmd = &cobra.Command{
 		Use:   "docker [flags] [product-docker-ids]",
 		Short: "Print the tags for the Docker images for products",
@@ -68,6 +79,7 @@ var (
 	artifactsAbsPathFlagVal          bool
 	artifactsRequiresBuildFlagVal    bool
 	artifactsDockerRepositoryFlagVal string
+	artifactsJSONFlagVal             bool
 )
 
 func init() {
@@ -78,6 +90,9 @@ func init() {
 	artifactsDistSubcmd.Flags().BoolVar(&artifactsAbsPathFlagVal, "absolute", false, "print the absolute path for artifacts")
 	artifactsCmd.AddCommand(artifactsDistSubcmd)
 
+	artifactsAllSubcmd.Flags().BoolVar(&artifactsJSONFlagVal, "json", false, "print the artifacts as a JSON array of objects that contain the product, type, OS/architecture or dist ID and path of each artifact")
+	artifactsCmd.AddCommand(artifactsAllSubcmd)
+
 	artifactsDockerSubcmd.Flags().StringVar(&artifactsDockerRepositoryFlagVal, "repository", "", "specifies the value that should be used for the Docker repository (overrides any value(s) specified in configuration)")
 	artifactsCmd.AddCommand(artifactsDockerSubcmd)
 
@@ -33,34 +33,45 @@ var (
 			}
 			var osArchs []osarch.OSArch
 			for _, osArchStr := range buildOSArchsFlagVal {
-				osArchVal, err := osarch.New(osArchStr)
+				osArchVal, err := distgo.NewOSArch(osArchStr)
 				if err != nil {
 					return errors.Wrapf(err, "invalid os-arch: %s", osArchStr)
 				}
 				osArchs = append(osArchs, osArchVal)
 			}
-			return build.Products(projectInfo, projectParam, distgo.ToProductBuildIDs(args), build.Options{
-				Parallel: buildParallelFlagVal,
-				Install:  buildInstallFlagVal,
-				DryRun:   buildDryRunFlagVal,
-				OSArchs:  osArchs,
+			ctx, stop := interruptContext()
+			defer stop()
+			return build.ProductsWithContext(ctx, projectInfo, projectParam, distgo.ToProductBuildIDs(args), build.Options{
+				Parallel:       buildParallelFlagVal,
+				Install:        buildInstallFlagVal,
+				DryRun:         buildDryRunFlagVal,
+				OSArchs:        osArchs,
+				MaxParallelism: buildMaxParallelismFlagVal,
+				Force:          buildForceFlagVal,
+				SummaryFile:    buildSummaryFileFlagVal,
 			}, cmd.OutOrStdout())
 		},
 	}
 )
 
 var (
-	buildParallelFlagVal bool
-	buildInstallFlagVal  bool
-	buildOSArchsFlagVal  []string
-	buildDryRunFlagVal   bool
+	buildParallelFlagVal       bool
+	buildMaxParallelismFlagVal int
+	buildInstallFlagVal        bool
+	buildOSArchsFlagVal        []string
+	buildDryRunFlagVal         bool
+	buildForceFlagVal          bool
+	buildSummaryFileFlagVal    string
 )
 
 func init() {
 	buildCmd.Flags().BoolVar(&buildParallelFlagVal, "parallel", true, "build binaries in parallel")
+	buildCmd.Flags().IntVar(&buildMaxParallelismFlagVal, "max-parallelism", 0, "maximum number of binaries to build concurrently when building in parallel (if not positive, uses the number of logical CPUs)")
 	buildCmd.Flags().BoolVar(&buildInstallFlagVal, "install", false, "build products with the '-i' flag")
-	buildCmd.Flags().StringSliceVar(&buildOSArchsFlagVal, "os-arch", nil, "if specified, only builds the binaries for the specified GOOS-GOARCH(s)")
+	buildCmd.Flags().StringSliceVar(&buildOSArchsFlagVal, "os-arch", nil, "if specified, only builds the binaries for the specified GOOS-GOARCH(s) (or GOOS-GOARCH-VARIANT(s))")
 	buildCmd.Flags().BoolVar(&buildDryRunFlagVal, "dry-run", false, "print the operations that would be performed")
+	buildCmd.Flags().BoolVar(&buildForceFlagVal, "force", false, "build outputs even if they are considered up-to-date")
+	buildCmd.Flags().StringVar(&buildSummaryFileFlagVal, "summary-file", "", "if specified, writes a JSON summary of the build outputs to the specified file")
 
 	rootCmd.AddCommand(buildCmd)
 }
@@ -17,6 +17,7 @@ package cmd
 import (
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/distgo/clean"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -29,15 +30,25 @@ var (
 			if err != nil {
 				return err
 			}
+			if cleanStaleFlagVal {
+				return clean.StaleProducts(projectInfo, projectParam, distgo.ToProductIDs(args), cleanKeepVersionsFlagVal, cleanDryRunFlagVal, cmd.OutOrStdout())
+			}
+			if cmd.Flags().Changed("keep-versions") {
+				return errors.Errorf("--keep-versions can only be specified with --stale")
+			}
 			return clean.Products(projectInfo, projectParam, distgo.ToProductIDs(args), cleanDryRunFlagVal, cmd.OutOrStdout())
 		},
 	}
 
-	cleanDryRunFlagVal bool
+	cleanDryRunFlagVal       bool
+	cleanStaleFlagVal        bool
+	cleanKeepVersionsFlagVal int
 )
 
 func init() {
 	cleanCmd.Flags().BoolVar(&cleanDryRunFlagVal, "dry-run", false, "print the paths that would be removed by the operation without actually removing them")
+	cleanCmd.Flags().BoolVar(&cleanStaleFlagVal, "stale", false, "only remove the outputs for versions other than the current version")
+	cleanCmd.Flags().IntVar(&cleanKeepVersionsFlagVal, "keep-versions", 0, "number of the most recently modified versions other than the current version whose outputs are kept when --stale is specified")
 
 	rootCmd.AddCommand(cleanCmd)
 }
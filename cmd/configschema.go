@@ -0,0 +1,42 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/palantir/distgo/dister/disterfactory"
+	"github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/publisher/publisherfactory"
+	"github.com/spf13/cobra"
+)
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "config-schema",
+	Short: "Print the JSON Schema for the distgo configuration file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := config.JSONSchema(disterfactory.BuiltinConfigs(), publisherfactory.BuiltinConfigs())
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configSchemaCmd)
+}
@@ -37,19 +37,37 @@ var (
 				// if force flag is false, use modification time of configuration file
 				configFileModTime = distgoConfigModTime()
 			}
-			return dist.Products(projectInfo, projectParam, configFileModTime, distgo.ToProductDistIDs(args), distDryRunFlagVal, cmd.OutOrStdout())
+			ctx, stop := interruptContext()
+			defer stop()
+			return dist.ProductsWithContext(ctx, projectInfo, projectParam, configFileModTime, distgo.ToProductDistIDs(args), dist.Options{
+				DryRun:         distDryRunFlagVal,
+				SHA256Files:    distSHA256FlagVal,
+				SHA256Sums:     distSHA256SumsFlagVal,
+				Force:          distForceFlagVal,
+				Parallel:       distParallelFlagVal,
+				MaxParallelism: distMaxParallelismFlagVal,
+			}, cmd.OutOrStdout())
 		},
 	}
 )
 
 var (
-	distDryRunFlagVal bool
-	distForceFlagVal  bool
+	distDryRunFlagVal         bool
+	distForceFlagVal          bool
+	distSHA256FlagVal         bool
+	distSHA256SumsFlagVal     bool
+	distParallelFlagVal       bool
+	distMaxParallelismFlagVal int
 )
 
 func init() {
 	distCmd.Flags().BoolVar(&distDryRunFlagVal, "dry-run", false, "print the operations that would be performed")
 	distCmd.Flags().BoolVar(&distForceFlagVal, "force", false, "create distribution outputs even if they are considered up-to-date")
+	distCmd.Flags().BoolVar(&distSHA256FlagVal, "sha256", false, "write a checksum file in 'sha256sum' format next to each distribution artifact using the checksum algorithm of the distribution ('.sha256' by default)")
+	distCmd.Flags().BoolVar(&distSHA256SumsFlagVal, "sha256sums", false, "write a SHA256SUMS file in 'sha256sum' format containing the checksums of the distribution artifacts of each product")
+
+	distCmd.Flags().BoolVar(&distParallelFlagVal, "parallel", false, "create the distributions for different products concurrently (products are still processed in dependency order)")
+	distCmd.Flags().IntVar(&distMaxParallelismFlagVal, "max-parallelism", 0, "maximum number of products whose distributions are created concurrently when running in parallel (if not positive, uses the number of logical CPUs)")
 
 	rootCmd.AddCommand(distCmd)
 }
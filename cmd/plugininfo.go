@@ -35,12 +35,16 @@ var (
 		newTaskInfoFromCmd(artifactsCmd),
 		newTaskInfoFromCmd(buildCmd),
 		newTaskInfoFromCmd(cleanCmd),
+		newTaskInfoFromCmd(configSchemaCmd),
 		newTaskInfoFromCmd(distCmd),
 		newTaskInfoFromCmd(dockerCmd),
 		newTaskInfoFromCmd(productsCmd),
 		newTaskInfoFromCmd(projectVersionCmd),
 		newTaskInfoFromCmd(publishCmd),
 		newTaskInfoFromCmd(runCmd),
+		newTaskInfoFromCmd(upgradeConfigFileCmd),
+		newTaskInfoFromCmd(verifyBuildCmd),
+		newTaskInfoFromCmd(verifyReproducibleCmd),
 		pluginapi.PluginInfoUpgradeConfigTaskInfo(
 			pluginapi.UpgradeConfigTaskInfoCommand("upgrade-config"),
 			pluginapi.LegacyConfigFile("dist.yml"),
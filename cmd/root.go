@@ -15,8 +15,11 @@
 package cmd
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/palantir/distgo/assetapi"
@@ -24,6 +27,7 @@ import (
 	"github.com/palantir/distgo/dister/disterfactory"
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/distgo/run"
 	"github.com/palantir/distgo/dockerbuilder"
 	"github.com/palantir/distgo/dockerbuilder/dockerbuilderfactory"
 	"github.com/palantir/distgo/projectversioner/projectversionerfactory"
@@ -56,7 +60,13 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() int {
-	return cobracli.ExecuteWithDebugVarAndDefaultParams(rootCmd, &debugFlagVal)
+	return cobracli.ExecuteWithDebugVarAndDefaultParams(rootCmd, &debugFlagVal, cobracli.ExitCodeExtractorParam(func(err error) int {
+		// if a product that was run exited with a non-zero exit code, exit with the same code
+		if exitErr, ok := errors.Cause(err).(*run.ExitError); ok {
+			return exitErr.ExitCode
+		}
+		return 1
+	}))
 }
 
 func restoreRootFlagsFn() func() {
@@ -161,6 +171,26 @@ func distgoProjectParamFromFlags() (distgo.ProjectInfo, distgo.ProjectParam, err
 	return distgoProjectParamFromVals(projectDirFlagVal, distgoConfigFileFlagVal, godelConfigFileFlagVal, cliProjectVersionerFactory, cliDisterFactory, cliDefaultDisterCfg, cliDockerBuilderFactory, cliPublisherFactory)
 }
 
+// interruptContext returns a context that is canceled when the process receives an interrupt or termination signal so
+// that long-running tasks can stop and clean up their partial outputs. The returned function must be called to stop
+// handling the signals once the context is no longer needed.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel()
+	}
+}
+
 func distgoConfigModTime() *time.Time {
 	if distgoConfigFileFlagVal == "" {
 		return nil
@@ -23,8 +23,11 @@ import (
 
 var (
 	runCmd = &cobra.Command{
-		Use:   "run [product-id] [arguments...]",
-		Short: "Run product",
+		Use:   "run [product-id] [-- arguments...]",
+		Short: "Build and run the executable for a product",
+		Long: `Builds the executable for the specified product for the current OS/architecture (if it is not up-to-date) and
+runs it with the provided arguments. Arguments that are flags must be preceded by "--". The exit code of the command
+is the exit code of the executable.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return errors.Errorf("a single product must be specified as the first argument")
@@ -0,0 +1,48 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/palantir/distgo/distgo/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var upgradeConfigFileCmd = &cobra.Command{
+	Use:   "upgrade-config-file",
+	Short: "Upgrade the distgo configuration file in place",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if distgoConfigFileFlagVal == "" {
+			return errors.Errorf("configuration file must be specified")
+		}
+		changed, err := config.UpgradeConfigFile(distgoConfigFileFlagVal, cliProjectVersionerFactory, cliDisterFactory, cliDockerBuilderFactory, cliPublisherFactory)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Fprintf(cmd.OutOrStdout(), "Configuration file %s is up-to-date\n", distgoConfigFileFlagVal)
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Upgraded configuration file %s\n", distgoConfigFileFlagVal)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeConfigFileCmd)
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/distgo/build"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyBuildCmd = &cobra.Command{
+		Use:   "verify-build [flags] [product-build-ids]",
+		Short: "Verify that the existing build outputs for products match the checksums in a build summary file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if verifyBuildSummaryFileFlagVal == "" {
+				return errors.Errorf("--summary-file must be specified")
+			}
+			projectInfo, projectParam, err := distgoProjectParamFromFlags()
+			if err != nil {
+				return err
+			}
+			return build.VerifyProducts(projectInfo, projectParam, distgo.ToProductBuildIDs(args), verifyBuildSummaryFileFlagVal, cmd.OutOrStdout())
+		},
+	}
+)
+
+var (
+	verifyBuildSummaryFileFlagVal string
+)
+
+func init() {
+	verifyBuildCmd.Flags().StringVar(&verifyBuildSummaryFileFlagVal, "summary-file", "", "the build summary file (written by 'build --summary-file') that contains the checksums of the build outputs")
+
+	rootCmd.AddCommand(verifyBuildCmd)
+}
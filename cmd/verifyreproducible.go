@@ -0,0 +1,41 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/distgo/build"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyReproducibleCmd = &cobra.Command{
+		Use:   "verify-reproducible [flags] [product-build-ids]",
+		Short: "Verify that products are reproducible by building them twice and comparing the build outputs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectInfo, projectParam, err := distgoProjectParamFromFlags()
+			if err != nil {
+				return err
+			}
+			ctx, stop := interruptContext()
+			defer stop()
+			return build.VerifyReproducibleProducts(ctx, projectInfo, projectParam, distgo.ToProductBuildIDs(args), cmd.OutOrStdout())
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(verifyReproducibleCmd)
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/palantir/distgo/dister/bin"
+	v0 "github.com/palantir/distgo/dister/bin/config/internal/v0"
+	"github.com/palantir/distgo/dister/internal/tgz"
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+type Bin v0.Config
+
+func (cfg *Bin) ToDister() (distgo.Dister, error) {
+	if cfg.CompressionLevel != nil {
+		if err := tgz.ValidateCompressionLevel(*cfg.CompressionLevel); err != nil {
+			return nil, errors.Wrapf(err, "invalid compression-level")
+		}
+	}
+	tarFormat, err := tgz.ParseFormat(cfg.TarFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid tar-format")
+	}
+	return &bin.Dister{
+		PreserveSymlinks: cfg.PreserveSymlinks,
+		CompressionLevel: cfg.CompressionLevel,
+		TarFormat:        tarFormat,
+	}, nil
+}
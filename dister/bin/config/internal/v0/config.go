@@ -15,9 +15,34 @@
 package v0
 
 import (
-	"github.com/palantir/godel/v2/pkg/versionedconfig"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
 )
 
+type Config struct {
+	// PreserveSymlinks specifies that symbolic links in the distribution directory should be written to the TGZ
+	// archive as symbolic links rather than as copies of their targets. If true, the target of every link must be a
+	// relative path that resolves to a location within the archive.
+	PreserveSymlinks bool `yaml:"preserve-symlinks,omitempty"`
+
+	// CompressionLevel is the gzip compression level used for the TGZ archive. Valid values range from -2 (Huffman
+	// encoding only) to 9 (best compression), where 0 disables compression and -1 selects the default level. Lower
+	// levels are faster but produce larger archives: for large executables, 1 (best speed) is significantly faster than
+	// the default, while 9 produces the smallest archives at the cost of the most CPU time. If not specified, defaults
+	// to -1.
+	CompressionLevel *int `yaml:"compression-level,omitempty"`
+
+	// TarFormat is the format used for the headers of the entries in the TGZ archive: one of "pax", "gnu" or "ustar".
+	// The PAX and GNU formats support paths of any length, but some extractors mishandle the headers that the GNU
+	// format uses for long paths. The USTAR format is the most portable, but creating the distribution fails if a
+	// path in it is too long for the format. If not specified, defaults to "pax".
+	TarFormat string `yaml:"tar-format,omitempty"`
+}
+
 func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
-	return versionedconfig.ConfigNotSupported("bin dister", cfgBytes)
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal bin dister v0 configuration")
+	}
+	return cfgBytes, nil
 }
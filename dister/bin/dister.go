@@ -15,24 +15,38 @@
 package bin
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"path"
-	"sort"
 
-	"github.com/mholt/archiver/v3"
+	"github.com/palantir/distgo/dister/internal/buildartifact"
+	"github.com/palantir/distgo/dister/internal/tgz"
 	"github.com/palantir/distgo/distgo"
-	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
-	"github.com/termie/go-shutil"
 )
 
 const TypeName = "bin" // distribution that consists of the binaries in a "bin" directory
 
-type Dister struct{}
+type Dister struct {
+	// PreserveSymlinks specifies that symbolic links in the distribution directory are written to the archive as
+	// symbolic links rather than as copies of their targets.
+	PreserveSymlinks bool
+
+	// CompressionLevel is the gzip compression level used for the TGZ archive. Must be between gzip.HuffmanOnly (-2)
+	// and gzip.BestCompression (9). If nil, gzip.DefaultCompression is used.
+	CompressionLevel *int
+
+	// TarFormat is the format used for the headers of the entries in the TGZ archive. Must be tar.FormatPAX,
+	// tar.FormatGNU or tar.FormatUSTAR.
+	TarFormat tar.Format
+}
 
 func New() distgo.Dister {
-	return &Dister{}
+	return &Dister{
+		TarFormat: tar.FormatPAX,
+	}
 }
 
 func (d *Dister) TypeName() (string, error) {
@@ -53,7 +67,7 @@ func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.Prod
 	}
 
 	for _, osArch := range productTaskOutputInfo.Product.BuildOutputInfo.OSArchs {
-		if err := verifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
+		if err := buildartifact.VerifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
 			return nil, err
 		}
 	}
@@ -64,81 +78,49 @@ func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.Prod
 	}
 
 	for _, osArch := range productTaskOutputInfo.Product.BuildOutputInfo.OSArchs {
-		for _, currProductOutputInfo := range productTaskOutputInfo.AllProductOutputInfos() {
-			// copy executable for current product
-			if _, err := copyArtifactForOSArch(distWorkDirBinDir, productTaskOutputInfo.Project, currProductOutputInfo, osArch); err != nil {
-				return nil, err
-			}
+		// copy executables for product and its dependencies
+		if _, err := buildartifact.CopyAllForOSArch(path.Join(distWorkDirBinDir, osArch.String()), distID, productTaskOutputInfo, osArch); err != nil {
+			return nil, err
 		}
 	}
 	return nil, nil
 }
 
 func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, runDistResult []byte) error {
+	modTime, err := tgz.ModTime(productTaskOutputInfo.Project)
+	if err != nil {
+		return err
+	}
 	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
 	dstPath := productTaskOutputInfo.ProductDistArtifactPaths()[distID][0]
-	if err := archiver.DefaultTarGz.Archive([]string{distWorkDir}, dstPath); err != nil {
+	if err := tgz.Archive([]string{distWorkDir}, dstPath, tgz.Options{
+		PreserveSymlinks: d.PreserveSymlinks,
+		CompressionLevel: d.compressionLevel(),
+		Format:           d.TarFormat,
+		ExecutableMode:   productTaskOutputInfo.ProductDistExecutableMode(distID),
+		ModTime:          modTime,
+	}); err != nil {
 		return errors.Wrapf(err, "failed to create TGZ archive")
 	}
 	return nil
 }
 
-func verifyDistTargetSupported(osArch osarch.OSArch, productTaskOutputInfo distgo.ProductTaskOutputInfo) error {
-	if err := verifySingleProduct(osArch, productTaskOutputInfo.Product); err != nil {
-		return err
-	}
-	var keys []distgo.ProductID
-	for k := range productTaskOutputInfo.Deps {
-		keys = append(keys, k)
-	}
-	sort.Sort(distgo.ByProductID(keys))
-	for _, currKey := range keys {
-		currSpec := productTaskOutputInfo.Deps[currKey]
-		if err := verifySingleProduct(osArch, currSpec); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func verifySingleProduct(osArch osarch.OSArch, productOutputInfo distgo.ProductOutputInfo) error {
-	if !osArchInBuildSpec(osArch, productOutputInfo) {
-		buildOSArchs := "[none]"
-		if productOutputInfo.BuildOutputInfo != nil {
-			buildOSArchs = fmt.Sprint(productOutputInfo.BuildOutputInfo.OSArchs)
-		}
-		return errors.Errorf("the OS/Arch specified for the distribution of a product must be specified as a build target for the product, "+
-			"but product %s does not specify %s as one of its build targets (current build targets: %s)", productOutputInfo.ID, osArch, buildOSArchs)
-	}
-	return nil
-}
-
-func osArchInBuildSpec(osArch osarch.OSArch, productOutputInfo distgo.ProductOutputInfo) bool {
-	if productOutputInfo.BuildOutputInfo == nil {
-		return false
-	}
-	found := false
-	for _, currBuildOSArch := range productOutputInfo.BuildOutputInfo.OSArchs {
-		if currBuildOSArch == osArch {
-			found = true
-			break
-		}
-	}
-	return found
+// ArtifactContents returns the contents of the artifact, which contains the dist work directory as its top-level
+// directory.
+func (d *Dister) ArtifactContents(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (map[string]distgo.ArtifactContents, error) {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	return map[string]distgo.ArtifactContents{
+		productTaskOutputInfo.ProductDistArtifactPaths()[distID][0]: {
+			Dir:        distWorkDir,
+			PathPrefix: path.Base(distWorkDir) + "/",
+		},
+	}, nil
 }
 
-func copyArtifactForOSArch(outputDir string, projectInfo distgo.ProjectInfo, productInfo distgo.ProductOutputInfo, osArch osarch.OSArch) (string, error) {
-	artifactPath, ok := distgo.ProductBuildArtifactPaths(projectInfo, productInfo)[osArch]
-	if !ok {
-		return "", errors.Errorf("no build artifacts exist for %s", osArch)
-	}
-
-	dst := path.Join(outputDir, osArch.String(), distgo.ExecutableName(productInfo.BuildOutputInfo.BuildNameTemplateRendered, osArch.OS))
-	if err := os.MkdirAll(path.Dir(dst), 0755); err != nil {
-		return "", errors.Wrapf(err, "failed to create output directory for artifact")
-	}
-	if _, err := shutil.Copy(artifactPath, dst, false); err != nil {
-		return "", errors.Wrapf(err, "failed to copy build artifact from %s to %s", artifactPath, dst)
+// compressionLevel returns the gzip compression level used for the TGZ archive.
+func (d *Dister) compressionLevel() int {
+	if d.CompressionLevel == nil {
+		return gzip.DefaultCompression
 	}
-	return dst, nil
+	return *d.CompressionLevel
 }
@@ -0,0 +1,83 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bin_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/bin"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinDistZeroValueCompresses(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// the zero value of the dister uses the default compression level rather than gzip.NoCompression
+	dister := &bin.Dister{}
+	const distID = distgo.DistID("bin")
+	artifactNames, err := dister.Artifacts("foo-1.0.0")
+	require.NoError(t, err)
+
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			BuildOutputInfo: &distgo.BuildOutputInfo{
+				BuildNameTemplateRendered: "foo",
+				BuildOutputDir:            "out/build",
+				OSArchs:                   []osarch.OSArch{linuxAMD64},
+			},
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{distID},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					distID: {
+						DistNameTemplateRendered: "foo-1.0.0",
+						DistArtifactNames:        artifactNames,
+						PackagingExtension:       "tgz",
+					},
+				},
+			},
+		},
+	}
+	executableContent := bytes.Repeat([]byte("foo"), 64*1024)
+	executablePath := path.Join(tmp, "out", "build", "foo", "1.0.0", "linux-amd64", "foo")
+	require.NoError(t, os.MkdirAll(path.Dir(executablePath), 0755))
+	require.NoError(t, ioutil.WriteFile(executablePath, executableContent, 0755))
+	require.NoError(t, os.MkdirAll(productTaskOutputInfo.ProductDistWorkDirs()[distID], 0755))
+
+	runDistResult, err := dister.RunDist(distID, productTaskOutputInfo)
+	require.NoError(t, err)
+	err = dister.GenerateDistArtifacts(distID, productTaskOutputInfo, runDistResult)
+	require.NoError(t, err)
+
+	fi, err := os.Stat(productTaskOutputInfo.ProductDistArtifactPaths()[distID][0])
+	require.NoError(t, err)
+	assert.True(t, fi.Size() < int64(len(executableContent))/10, "archive of size %d was not compressed", fi.Size())
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deb
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	arMagic = "!<arch>\n"
+	// debianBinary is the content of the "debian-binary" member, which specifies the version of the package format.
+	debianBinary = "2.0\n"
+)
+
+// writeDeb writes a Debian package to dstPath. The package is an "ar" archive whose members are "debian-binary",
+// "control.tar.gz" and "data.tar.gz" (in that order, as required by dpkg). All members have the provided modification
+// time.
+func writeDeb(dstPath, controlArchivePath, dataArchivePath string, modTime time.Time) (rErr error) {
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", dstPath)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close %s", dstPath)
+		}
+	}()
+
+	if _, err := io.WriteString(f, arMagic); err != nil {
+		return errors.Wrapf(err, "failed to write ar header")
+	}
+	if err := writeARMember(f, "debian-binary", modTime.Unix(), int64(len(debianBinary)), strings.NewReader(debianBinary)); err != nil {
+		return err
+	}
+	for _, archivePath := range []string{controlArchivePath, dataArchivePath} {
+		if err := writeARFileMember(f, archivePath, modTime.Unix()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeARFileMember(w io.Writer, filePath string, modTime int64) error {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %s", filePath)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", filePath)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return writeARMember(w, path.Base(filePath), modTime, fi.Size(), f)
+}
+
+// writeARMember writes a member with the provided name and content to an "ar" archive. The member is owned by uid and
+// gid 0 and has mode 0644. The content is padded to an even length as required by the format.
+func writeARMember(w io.Writer, name string, modTime, size int64, content io.Reader) error {
+	if len(name) > 16 {
+		return errors.Errorf("ar member name %s is longer than 16 characters", name)
+	}
+	header := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8s%-10d`\n", name, modTime, 0, 0, "100644", size)
+	if _, err := io.WriteString(w, header); err != nil {
+		return errors.Wrapf(err, "failed to write ar header for %s", name)
+	}
+	if _, err := io.CopyN(w, content, size); err != nil {
+		return errors.Wrapf(err, "failed to write ar member %s", name)
+	}
+	if size%2 != 0 {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return errors.Wrapf(err, "failed to write padding for ar member %s", name)
+		}
+	}
+	return nil
+}
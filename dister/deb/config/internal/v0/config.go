@@ -0,0 +1,61 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	// OSArchs specifies the GOOS and GOARCH pairs for which Debian packages are created. The GOOS of every entry must
+	// be "linux". If blank, defaults to the GOOS and GOARCH of the host system at runtime.
+	OSArchs []osarch.OSArch `yaml:"os-archs,omitempty"`
+
+	// Maintainer is the name and email address of the maintainer of the package (for example,
+	// "Jane Doe <jane@example.com>"). It is used as the value of the "Maintainer" field of the control file and must be
+	// specified.
+	Maintainer string `yaml:"maintainer,omitempty"`
+
+	// Description is the description of the package used for the "Description" field of the control file. If not
+	// specified, the name of the product is used.
+	Description string `yaml:"description,omitempty"`
+
+	// Depends specifies the packages that the package depends on. Each entry uses the format of the "Depends" field of
+	// the control file. For example:
+	//
+	//   depends:
+	//     - libc6 (>= 2.17)
+	//     - ca-certificates
+	Depends []string `yaml:"depends,omitempty"`
+
+	// PostInstallScript is the content of the "postinst" script of the package, which is run by dpkg after the package
+	// is installed. For example:
+	//
+	//   post-install-script: |
+	//     #!/bin/sh
+	//     set -e
+	//     systemctl daemon-reload
+	PostInstallScript string `yaml:"post-install-script,omitempty"`
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal deb dister v0 configuration")
+	}
+	return cfgBytes, nil
+}
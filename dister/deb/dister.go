@@ -0,0 +1,292 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/dister/internal/buildartifact"
+	"github.com/palantir/distgo/dister/internal/tgz"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+const TypeName = "deb" // distribution that consists of the binaries for a specific OS/Architecture packaged as a Debian package
+
+const (
+	// dataDirName is the name of the directory in the work directory for an OS/Architecture that contains the files
+	// that are installed by the package.
+	dataDirName = "data"
+	// controlDirName is the name of the directory in the work directory for an OS/Architecture that contains the
+	// control files for the package.
+	controlDirName = "control"
+)
+
+type Dister struct {
+	OSArchs []osarch.OSArch
+
+	// Maintainer is the value of the "Maintainer" field of the control file.
+	Maintainer string
+
+	// Description is the value of the "Description" field of the control file.
+	Description string
+
+	// Depends are the packages that the package depends on. Each entry is a dependency in the format used by the
+	// "Depends" field of the control file (for example, "libc6 (>= 2.17)").
+	Depends []string
+
+	// PostInstallScript is the content of the "postinst" maintainer script of the package. If empty, the package does
+	// not contain a "postinst" script.
+	PostInstallScript string
+}
+
+func New(maintainer string, osArchs ...osarch.OSArch) distgo.Dister {
+	return &Dister{
+		OSArchs:    osArchs,
+		Maintainer: maintainer,
+	}
+}
+
+func (d *Dister) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+func (d *Dister) Artifacts(renderedName string) ([]string, error) {
+	var outPaths []string
+	for _, osArch := range d.OSArchs {
+		outPaths = append(outPaths, fmt.Sprintf("%s-%s.deb", renderedName, osArch.String()))
+	}
+	return outPaths, nil
+}
+
+func (d *Dister) PackagingExtension() (string, error) {
+	return "deb", nil
+}
+
+func (d *Dister) ArtifactOSArchs(renderedName string) (map[string]osarch.OSArch, error) {
+	artifactOSArchs := make(map[string]osarch.OSArch)
+	for _, osArch := range d.OSArchs {
+		artifactOSArchs[fmt.Sprintf("%s-%s.deb", renderedName, osArch.String())] = osArch
+	}
+	return artifactOSArchs, nil
+}
+
+func (d *Dister) osArchFromArtifactPath(distID distgo.DistID, artifactPath string, productTaskOutputInfo distgo.ProductTaskOutputInfo) (osarch.OSArch, error) {
+	for _, osArch := range d.OSArchs {
+		if strings.HasSuffix(artifactPath, fmt.Sprintf("%s-%s.deb", productTaskOutputInfo.Product.DistOutputInfos.DistInfos[distID].DistNameTemplateRendered, osArch.String())) {
+			return osArch, nil
+		}
+	}
+	return osarch.OSArch{}, errors.Errorf("failed to determine OS/Arch for artifact with Path %s", artifactPath)
+}
+
+// RunDist copies the executables for each OS/architecture of the dister into "{{OSArch}}/data/usr/bin" in the dist
+// work directory. The "data" directory for an OS/Architecture is the root of the file system tree that is installed
+// by the package, so the dist script can add files to the package by writing them to this directory.
+func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]byte, error) {
+	for _, osArch := range d.OSArchs {
+		if osArch.OS != "linux" {
+			return nil, errors.Errorf("deb dist failed: Debian packages can only be created for linux, but %s was specified", osArch)
+		}
+		if err := buildartifact.VerifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
+			return nil, err
+		}
+	}
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	outputPathsForOSArchs := make(map[string][]string)
+	for _, osArch := range d.OSArchs {
+		dataDir := path.Join(distWorkDir, osArch.String(), dataDirName)
+		// copy executables for product and its dependencies
+		dsts, err := buildartifact.CopyAllForOSArch(path.Join(dataDir, "usr", "bin"), distID, productTaskOutputInfo, osArch)
+		if err != nil {
+			return nil, err
+		}
+		outputPathsForOSArchs[osArch.String()] = dsts
+	}
+	jsonBytes, err := json.Marshal(outputPathsForOSArchs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal outputPathsForOSArchs as JSON")
+	}
+	return jsonBytes, nil
+}
+
+// GenerateDistArtifacts creates a Debian package for each OS/architecture. The package is an "ar" archive that
+// contains the "debian-binary" file, the "control.tar.gz" archive with the control file (and the "postinst" script if
+// one is specified) and the "data.tar.gz" archive with the contents of the "data" directory for the OS/architecture.
+func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, runDistResult []byte) error {
+	modTime, err := tgz.ModTime(productTaskOutputInfo.Project)
+	if err != nil {
+		return err
+	}
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return err
+		}
+		osArchWorkDir := path.Join(distWorkDir, currOSArch.String())
+		dataDir := path.Join(osArchWorkDir, dataDirName)
+		controlDir := path.Join(osArchWorkDir, controlDirName)
+
+		installedSize, err := installedSizeKB(dataDir)
+		if err != nil {
+			return err
+		}
+		control, err := d.controlFileContent(string(productTaskOutputInfo.Product.ID), productTaskOutputInfo.Project.Version, currOSArch, installedSize)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(controlDir, 0755); err != nil {
+			return errors.Wrapf(err, "failed to create control directory")
+		}
+		if err := ioutil.WriteFile(path.Join(controlDir, "control"), []byte(control), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write control file")
+		}
+		if d.PostInstallScript != "" {
+			if err := ioutil.WriteFile(path.Join(controlDir, "postinst"), []byte(d.PostInstallScript), 0755); err != nil {
+				return errors.Wrapf(err, "failed to write postinst script")
+			}
+		}
+
+		controlArchivePath := path.Join(osArchWorkDir, "control.tar.gz")
+		if err := archiveDirContents(controlDir, controlArchivePath, distgo.DefaultExecutableMode, modTime); err != nil {
+			return errors.Wrapf(err, "failed to create control archive")
+		}
+		dataArchivePath := path.Join(osArchWorkDir, "data.tar.gz")
+		if err := archiveDirContents(dataDir, dataArchivePath, productTaskOutputInfo.ProductDistExecutableMode(distID), modTime); err != nil {
+			return errors.Wrapf(err, "failed to create data archive")
+		}
+		if err := writeDeb(artifactPath, controlArchivePath, dataArchivePath, modTime); err != nil {
+			return errors.Wrapf(err, "failed to create Debian package")
+		}
+	}
+	return nil
+}
+
+// ArtifactContents returns the contents of each artifact, which installs the contents of the "data" directory for its
+// OS/architecture. The paths are the paths of the entries in the data archive of the package, and a manifest that is
+// included in the package is installed in the documentation directory of the package.
+func (d *Dister) ArtifactContents(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (map[string]distgo.ArtifactContents, error) {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	packageName := string(productTaskOutputInfo.Product.ID)
+	contents := make(map[string]distgo.ArtifactContents)
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return nil, err
+		}
+		contents[artifactPath] = distgo.ArtifactContents{
+			Dir:         path.Join(distWorkDir, currOSArch.String(), dataDirName),
+			PathPrefix:  "./",
+			ManifestDir: path.Join("usr", "share", "doc", packageName),
+		}
+	}
+	return contents, nil
+}
+
+// controlFileContent returns the content of the control file for the package.
+func (d *Dister) controlFileContent(packageName, version string, osArch osarch.OSArch, installedSize int64) (string, error) {
+	arch, err := debArch(osArch)
+	if err != nil {
+		return "", err
+	}
+	description := d.Description
+	if description == "" {
+		description = packageName
+	}
+
+	var content strings.Builder
+	writeField := func(name, value string) {
+		content.WriteString(fmt.Sprintf("%s: %s\n", name, value))
+	}
+	writeField("Package", packageName)
+	writeField("Version", version)
+	writeField("Architecture", arch)
+	writeField("Maintainer", d.Maintainer)
+	writeField("Installed-Size", fmt.Sprint(installedSize))
+	if len(d.Depends) > 0 {
+		writeField("Depends", strings.Join(d.Depends, ", "))
+	}
+	writeField("Description", description)
+	return content.String(), nil
+}
+
+// debArch returns the Debian architecture for the provided OS/architecture.
+func debArch(osArch osarch.OSArch) (string, error) {
+	goarch, variant := distgo.GOARCHAndVariant(osArch)
+	switch goarch {
+	case "amd64", "arm64", "mips", "mipsel", "riscv64", "s390x", "ppc64":
+		return goarch, nil
+	case "386":
+		return "i386", nil
+	case "arm":
+		if variant == "5" || variant == "6" {
+			return "armel", nil
+		}
+		return "armhf", nil
+	case "mips64le":
+		return "mips64el", nil
+	case "ppc64le":
+		return "ppc64el", nil
+	default:
+		return "", errors.Errorf("no Debian architecture is known for %s", osArch)
+	}
+}
+
+// installedSizeKB returns the total size of the regular files in the provided directory in KiB (rounded up).
+func installedSizeKB(dir string) (int64, error) {
+	var size int64
+	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to compute size of %s", dir)
+	}
+	return (size + 1023) / 1024, nil
+}
+
+// archiveDirContents writes a TGZ archive of the contents of the provided directory in which the names of all of the
+// entries are prefixed with "./", which is the format used by "dpkg-deb". Executable files are written with the
+// provided mode and all entries have the provided modification time.
+func archiveDirContents(dir, dstPath string, executableMode os.FileMode, modTime time.Time) error {
+	items, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list items in %s", dir)
+	}
+	itemPaths := make([]string, len(items))
+	for i, item := range items {
+		itemPaths[i] = path.Join(dir, item.Name())
+	}
+	return tgz.Archive(itemPaths, dstPath, tgz.Options{
+		CompressionLevel: gzip.DefaultCompression,
+		NamePrefix:       "./",
+		ExecutableMode:   executableMode,
+		ModTime:          modTime,
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deb_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/deb"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebDist(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	dister := &deb.Dister{
+		OSArchs:     []osarch.OSArch{linuxAMD64},
+		Maintainer:  "Foo Maintainer <foo@example.com>",
+		Description: "Foo does things",
+		Depends:     []string{"libc6 (>= 2.17)", "ca-certificates"},
+		PostInstallScript: `#!/bin/sh
+echo "installed foo"
+`,
+	}
+	const distID = distgo.DistID("deb")
+	artifactNames, err := dister.Artifacts("foo-1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo-1.0.0-linux-amd64.deb"}, artifactNames)
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			BuildOutputInfo: &distgo.BuildOutputInfo{
+				BuildNameTemplateRendered: "foo",
+				BuildOutputDir:            "out/build",
+				OSArchs:                   []osarch.OSArch{linuxAMD64},
+			},
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{distID},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					distID: {
+						DistNameTemplateRendered: "foo-1.0.0",
+						DistArtifactNames:        artifactNames,
+						PackagingExtension:       "deb",
+					},
+				},
+			},
+		},
+	}
+	executablePath := path.Join(tmp, "out", "build", "foo", "1.0.0", "linux-amd64", "foo")
+	require.NoError(t, os.MkdirAll(path.Dir(executablePath), 0755))
+	require.NoError(t, ioutil.WriteFile(executablePath, []byte("foo executable"), 0755))
+	require.NoError(t, os.MkdirAll(productTaskOutputInfo.ProductDistWorkDirs()[distID], 0755))
+
+	runDistResult, err := dister.RunDist(distID, productTaskOutputInfo)
+	require.NoError(t, err)
+	err = dister.GenerateDistArtifacts(distID, productTaskOutputInfo, runDistResult)
+	require.NoError(t, err)
+
+	debPath := path.Join(tmp, "out", "dist", "foo", "1.0.0", "deb", "foo-1.0.0-linux-amd64.deb")
+	members := readAR(t, debPath)
+	require.Len(t, members, 3)
+	assert.Equal(t, "debian-binary", members[0].name)
+	assert.Equal(t, "2.0\n", string(members[0].content))
+	assert.Equal(t, "control.tar.gz", members[1].name)
+	assert.Equal(t, "data.tar.gz", members[2].name)
+
+	controlEntries := readTGZ(t, members[1].content)
+	assert.Equal(t, []string{"./control", "./postinst"}, entryNames(controlEntries))
+	assert.Equal(t, `Package: foo
+Version: 1.0.0
+Architecture: amd64
+Maintainer: Foo Maintainer <foo@example.com>
+Installed-Size: 1
+Depends: libc6 (>= 2.17), ca-certificates
+Description: Foo does things
+`, controlEntries[0].content)
+	assert.Equal(t, int64(0644), controlEntries[0].mode)
+	assert.Equal(t, dister.PostInstallScript, controlEntries[1].content)
+	assert.Equal(t, int64(0755), controlEntries[1].mode)
+
+	dataEntries := readTGZ(t, members[2].content)
+	assert.Equal(t, []string{"./usr/", "./usr/bin/", "./usr/bin/foo"}, entryNames(dataEntries))
+	assert.Equal(t, "foo executable", dataEntries[2].content)
+	assert.Equal(t, int64(0755), dataEntries[2].mode)
+
+	// verify that the package can be read by "dpkg-deb" if it is available
+	if dpkgDebPath, err := exec.LookPath("dpkg-deb"); err == nil {
+		output, err := exec.Command(dpkgDebPath, "--field", debPath, "Package", "Version", "Depends").CombinedOutput()
+		require.NoError(t, err, "dpkg-deb failed: %s", string(output))
+		assert.Equal(t, "Package: foo\nVersion: 1.0.0\nDepends: libc6 (>= 2.17), ca-certificates\n", string(output))
+	}
+}
+
+func TestDebDistRequiresLinux(t *testing.T) {
+	dister := deb.New("Foo Maintainer <foo@example.com>", osarch.OSArch{OS: "darwin", Arch: "amd64"})
+	_, err := dister.RunDist("deb", distgo.ProductTaskOutputInfo{})
+	assert.EqualError(t, err, "deb dist failed: Debian packages can only be created for linux, but darwin-amd64 was specified")
+}
+
+type arMember struct {
+	name    string
+	content []byte
+}
+
+// readAR returns the members of the "ar" archive at the provided path.
+func readAR(t *testing.T, arPath string) []arMember {
+	arBytes, err := ioutil.ReadFile(arPath)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(arBytes, []byte("!<arch>\n")), "missing ar magic")
+	arBytes = arBytes[len("!<arch>\n"):]
+
+	var members []arMember
+	for len(arBytes) > 0 {
+		require.True(t, len(arBytes) >= 60, "truncated ar header")
+		header := string(arBytes[:60])
+		require.Equal(t, "`\n", header[58:60], "invalid ar header terminator")
+		size, err := strconv.ParseInt(strings.TrimSpace(header[48:58]), 10, 64)
+		require.NoError(t, err)
+		arBytes = arBytes[60:]
+		members = append(members, arMember{
+			name:    strings.TrimSpace(header[:16]),
+			content: arBytes[:size],
+		})
+		if size%2 != 0 {
+			size++
+		}
+		arBytes = arBytes[size:]
+	}
+	return members
+}
+
+type tarEntry struct {
+	name    string
+	mode    int64
+	content string
+}
+
+func readTGZ(t *testing.T, tgzBytes []byte) []tarEntry {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(tgzBytes))
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	var entries []tarEntry
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		entries = append(entries, tarEntry{name: hdr.Name, mode: hdr.Mode, content: string(content)})
+	}
+	return entries
+}
+
+func entryNames(entries []tarEntry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.name)
+	}
+	return names
+}
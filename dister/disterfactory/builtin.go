@@ -18,10 +18,18 @@ import (
 	"github.com/palantir/distgo/dister"
 	"github.com/palantir/distgo/dister/bin"
 	binconfig "github.com/palantir/distgo/dister/bin/config"
+	"github.com/palantir/distgo/dister/deb"
+	debconfig "github.com/palantir/distgo/dister/deb/config"
 	"github.com/palantir/distgo/dister/manual"
 	manualconfig "github.com/palantir/distgo/dister/manual/config"
 	"github.com/palantir/distgo/dister/osarchbin"
 	osarchbinconfig "github.com/palantir/distgo/dister/osarchbin/config"
+	"github.com/palantir/distgo/dister/rpm"
+	rpmconfig "github.com/palantir/distgo/dister/rpm/config"
+	"github.com/palantir/distgo/dister/selfextracting"
+	selfextractingconfig "github.com/palantir/distgo/dister/selfextracting/config"
+	"github.com/palantir/distgo/dister/zip"
+	zipconfig "github.com/palantir/distgo/dister/zip/config"
 	"github.com/palantir/distgo/distgo"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -30,15 +38,34 @@ import (
 type creatorWithUpgrader struct {
 	creator  dister.CreatorFunction
 	upgrader distgo.ConfigUpgrader
+	// config is a value of the configuration struct for the dister. Nil if the dister does not have any configuration.
+	config interface{}
+}
+
+// BuiltinConfigs returns a map from the type name of each built-in dister to a value of the struct that represents the
+// configuration of the dister. Disters that do not have any configuration are not included.
+func BuiltinConfigs() map[string]interface{} {
+	configs := make(map[string]interface{})
+	for typeName, dister := range builtinDisters() {
+		if dister.config != nil {
+			configs[typeName] = dister.config
+		}
+	}
+	return configs
 }
 
 func builtinDisters() map[string]creatorWithUpgrader {
 	return map[string]creatorWithUpgrader{
 		bin.TypeName: {
 			creator: func(cfgYML []byte) (distgo.Dister, error) {
-				return bin.New(), nil
+				var cfg binconfig.Bin
+				if err := yaml.UnmarshalStrict(cfgYML, &cfg); err != nil {
+					return nil, errors.Wrapf(err, "failed to unmarshal YAML")
+				}
+				return cfg.ToDister()
 			},
 			upgrader: distgo.NewConfigUpgrader(bin.TypeName, binconfig.UpgradeConfig),
+			config:   binconfig.Bin{},
 		},
 		osarchbin.TypeName: {
 			creator: func(cfgYML []byte) (distgo.Dister, error) {
@@ -46,9 +73,10 @@ func builtinDisters() map[string]creatorWithUpgrader {
 				if err := yaml.UnmarshalStrict(cfgYML, &cfg); err != nil {
 					return nil, errors.Wrapf(err, "failed to unmarshal YAML")
 				}
-				return cfg.ToDister(), nil
+				return cfg.ToDister()
 			},
 			upgrader: distgo.NewConfigUpgrader(osarchbin.TypeName, osarchbinconfig.UpgradeConfig),
+			config:   osarchbinconfig.OSArchBin{},
 		},
 		manual.TypeName: {
 			creator: func(cfgYML []byte) (distgo.Dister, error) {
@@ -59,6 +87,46 @@ func builtinDisters() map[string]creatorWithUpgrader {
 				return cfg.ToDister(), nil
 			},
 			upgrader: distgo.NewConfigUpgrader(manual.TypeName, manualconfig.UpgradeConfig),
+			config:   manualconfig.Manual{},
+		},
+		deb.TypeName: {
+			creator: func(cfgYML []byte) (distgo.Dister, error) {
+				var cfg debconfig.Deb
+				if err := yaml.UnmarshalStrict(cfgYML, &cfg); err != nil {
+					return nil, errors.Wrapf(err, "failed to unmarshal YAML")
+				}
+				return cfg.ToDister()
+			},
+			upgrader: distgo.NewConfigUpgrader(deb.TypeName, debconfig.UpgradeConfig),
+			config:   debconfig.Deb{},
+		},
+		rpm.TypeName: {
+			creator: func(cfgYML []byte) (distgo.Dister, error) {
+				var cfg rpmconfig.RPM
+				if err := yaml.UnmarshalStrict(cfgYML, &cfg); err != nil {
+					return nil, errors.Wrapf(err, "failed to unmarshal YAML")
+				}
+				return cfg.ToDister()
+			},
+			upgrader: distgo.NewConfigUpgrader(rpm.TypeName, rpmconfig.UpgradeConfig),
+			config:   rpmconfig.RPM{},
+		},
+		selfextracting.TypeName: {
+			creator: func(cfgYML []byte) (distgo.Dister, error) {
+				var cfg selfextractingconfig.SelfExtracting
+				if err := yaml.UnmarshalStrict(cfgYML, &cfg); err != nil {
+					return nil, errors.Wrapf(err, "failed to unmarshal YAML")
+				}
+				return cfg.ToDister()
+			},
+			upgrader: distgo.NewConfigUpgrader(selfextracting.TypeName, selfextractingconfig.UpgradeConfig),
+			config:   selfextractingconfig.SelfExtracting{},
+		},
+		zip.TypeName: {
+			creator: func(cfgYML []byte) (distgo.Dister, error) {
+				return zip.New(), nil
+			},
+			upgrader: distgo.NewConfigUpgrader(zip.TypeName, zipconfig.UpgradeConfig),
 		},
 	}
 }
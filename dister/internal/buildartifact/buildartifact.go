@@ -0,0 +1,117 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buildartifact provides functions used by disters to verify and copy the build artifacts of the products
+// that are part of a distribution.
+package buildartifact
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+	"github.com/termie/go-shutil"
+)
+
+// VerifyDistTargetSupported returns an error if the provided OS/architecture is not a build target of the product or
+// of any of its dependencies.
+func VerifyDistTargetSupported(osArch osarch.OSArch, productTaskOutputInfo distgo.ProductTaskOutputInfo) error {
+	if err := verifySingleProduct(osArch, productTaskOutputInfo.Product); err != nil {
+		return err
+	}
+	var keys []distgo.ProductID
+	for k := range productTaskOutputInfo.Deps {
+		keys = append(keys, k)
+	}
+	sort.Sort(distgo.ByProductID(keys))
+	for _, currKey := range keys {
+		currSpec := productTaskOutputInfo.Deps[currKey]
+		if err := verifySingleProduct(osArch, currSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifySingleProduct(osArch osarch.OSArch, productOutputInfo distgo.ProductOutputInfo) error {
+	if !osArchInBuildSpec(osArch, productOutputInfo) {
+		buildOSArchs := "[none]"
+		if productOutputInfo.BuildOutputInfo != nil {
+			buildOSArchs = fmt.Sprint(productOutputInfo.BuildOutputInfo.OSArchs)
+		}
+		return errors.Errorf("the OS/Arch specified for the distribution of a product must be specified as a build target for the product, "+
+			"but product %s does not specify %s as one of its build targets (current build targets: %s)", productOutputInfo.ID, osArch, buildOSArchs)
+	}
+	return nil
+}
+
+func osArchInBuildSpec(osArch osarch.OSArch, productOutputInfo distgo.ProductOutputInfo) bool {
+	if productOutputInfo.BuildOutputInfo == nil {
+		return false
+	}
+	for _, currBuildOSArch := range productOutputInfo.BuildOutputInfo.OSArchs {
+		if currBuildOSArch == osArch {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyAllForOSArch copies the build artifacts of the product and its dependencies for the provided OS/architecture
+// into outputDir and returns the paths to the copies. Products that do not have build artifacts because they do not
+// specify a main package are skipped. outputDir is created even if none of the products have build artifacts.
+func CopyAllForOSArch(outputDir string, distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, osArch osarch.OSArch) ([]string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create output directory for %s", osArch)
+	}
+	var dsts []string
+	for _, currProductOutputInfo := range productTaskOutputInfo.AllProductOutputInfos() {
+		if currProductOutputInfo.BuildOutputInfo != nil && currProductOutputInfo.BuildOutputInfo.NoArtifacts {
+			// product does not have a main package, so there is no executable to copy
+			continue
+		}
+		dst, err := CopyForOSArch(outputDir, distID, productTaskOutputInfo, currProductOutputInfo, osArch)
+		if err != nil {
+			return nil, err
+		}
+		dsts = append(dsts, dst)
+	}
+	return dsts, nil
+}
+
+// CopyForOSArch copies the build artifact of productInfo for the provided OS/architecture into outputDir and returns
+// the path to the copy. The mode of the copy is set to the executable mode of the distribution with the provided ID
+// so that the executable is packaged with the expected mode regardless of the mode of the build artifact.
+func CopyForOSArch(outputDir string, distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, productInfo distgo.ProductOutputInfo, osArch osarch.OSArch) (string, error) {
+	artifactPath, ok := distgo.ProductBuildArtifactPaths(productTaskOutputInfo.Project, productInfo)[osArch]
+	if !ok {
+		return "", errors.Errorf("no build artifacts exist for %s", osArch)
+	}
+
+	dst := path.Join(outputDir, productInfo.BuildOutputInfo.ArtifactName(osArch))
+	if err := os.MkdirAll(path.Dir(dst), 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create output directory for artifact")
+	}
+	if _, err := shutil.Copy(artifactPath, dst, false); err != nil {
+		return "", errors.Wrapf(err, "failed to copy build artifact from %s to %s", artifactPath, dst)
+	}
+	if err := os.Chmod(dst, productTaskOutputInfo.ProductDistExecutableMode(distID)); err != nil {
+		return "", errors.Wrapf(err, "failed to set mode of %s", dst)
+	}
+	return dst, nil
+}
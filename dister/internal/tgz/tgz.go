@@ -0,0 +1,314 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tgz creates reproducible gzip-compressed tar archives.
+package tgz
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+// Names of the tar formats that are supported by ParseFormat.
+const (
+	FormatNameGNU   = "gnu"
+	FormatNamePAX   = "pax"
+	FormatNameUSTAR = "ustar"
+)
+
+// USTAR headers store the name of an entry in a 100-byte field and an optional 155-byte prefix, and the target of a
+// link in a 100-byte field.
+const (
+	ustarNameSize   = 100
+	ustarPrefixSize = 155
+)
+
+// ParseFormat returns the tar format with the provided name, which must be "gnu", "pax" or "ustar". An empty name
+// returns tar.FormatPAX, which is the default format for archives.
+func ParseFormat(name string) (tar.Format, error) {
+	switch name {
+	case "", FormatNamePAX:
+		return tar.FormatPAX, nil
+	case FormatNameGNU:
+		return tar.FormatGNU, nil
+	case FormatNameUSTAR:
+		return tar.FormatUSTAR, nil
+	default:
+		return tar.FormatUnknown, errors.Errorf("tar format must be one of %q, %q or %q, was %q", FormatNameGNU, FormatNamePAX, FormatNameUSTAR, name)
+	}
+}
+
+// Options specifies the options for creating an archive.
+type Options struct {
+	// PreserveSymlinks specifies that symbolic links should be written to the archive as symbolic link entries rather
+	// than as copies of the files or directories they point to. The target of every link must be a relative path that
+	// resolves to a location within the archive: Archive returns an error for links with absolute targets or with
+	// targets that escape the root of the archive.
+	PreserveSymlinks bool
+
+	// CompressionLevel is the gzip compression level used for the archive. Valid values range from gzip.HuffmanOnly
+	// (-2) to gzip.BestCompression (9), and gzip.DefaultCompression (-1) selects the default level. Lower levels are
+	// faster but produce larger archives: for large executables, gzip.BestSpeed (1) is significantly faster than the
+	// default level, while gzip.BestCompression produces the smallest archives at the cost of the most CPU time.
+	// gzip.NoCompression (0) stores the tar data without compressing it.
+	CompressionLevel int
+
+	// NamePrefix is prepended to the names of all of the entries in the archive. For example, a prefix of "./" writes
+	// the entries for a source directory "usr" as "./usr/", "./usr/bin/" and so on.
+	NamePrefix string
+
+	// Format is the format used for the headers of the entries in the archive, and must be tar.FormatPAX,
+	// tar.FormatGNU or tar.FormatUSTAR. If tar.FormatUnknown (the zero value), tar.FormatPAX is used. The PAX and GNU
+	// formats support entries with paths and link targets of any length, while the USTAR format does not (refer to
+	// the documentation of the archive/tar package): Archive returns an error if the name or link target of an entry is
+	// too long for the USTAR format. The PAX format is the most widely supported format that can represent all entries,
+	// and some extractors mishandle the headers that the GNU format uses for long paths.
+	Format tar.Format
+
+	// ExecutableMode is the permission bits used for the entries of executable files (files for which any execute
+	// permission bit is set). If 0, 0755 is used.
+	ExecutableMode os.FileMode
+
+	// ModTime is the modification time of all of the entries in the archive. If zero, the Unix epoch is used. Disters
+	// should use the time returned by ModTime for the project so that archives respect SOURCE_DATE_EPOCH.
+	ModTime time.Time
+}
+
+// ValidateCompressionLevel returns an error if the provided value is not a valid gzip compression level.
+func ValidateCompressionLevel(level int) error {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return errors.Errorf("compression level must be between %d and %d, was %d", gzip.HuffmanOnly, gzip.BestCompression, level)
+	}
+	return nil
+}
+
+// Archive writes a gzip-compressed tar archive of the provided source paths to dstPath. The entries for a source path
+// are rooted at its base name: a directory is added recursively and a file is added as a single entry. The archive is
+// reproducible: the output depends only on the paths, contents and executable bits of the sources and on the options.
+// The entries for each directory are written in sorted order, all entries have the same modification time
+// (opts.ModTime), the owner and group of every entry is 0, and permissions are normalized to 0755 for directories,
+// opts.ExecutableMode for executable files and 0644 for all other files. Symbolic links are dereferenced unless
+// opts.PreserveSymlinks is true.
+func Archive(srcPaths []string, dstPath string, opts Options) (rErr error) {
+	if err := ValidateCompressionLevel(opts.CompressionLevel); err != nil {
+		return err
+	}
+	format := opts.Format
+	switch format {
+	case tar.FormatUnknown:
+		format = tar.FormatPAX
+	case tar.FormatPAX, tar.FormatGNU, tar.FormatUSTAR:
+	default:
+		return errors.Errorf("unsupported tar format %v", format)
+	}
+	executableMode := opts.ExecutableMode
+	if executableMode == 0 {
+		executableMode = 0755
+	}
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		modTime = time.Unix(0, 0).UTC()
+	}
+
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create archive file")
+	}
+	defer func() {
+		if err := f.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close archive file")
+		}
+	}()
+
+	gzipWriter, err := gzip.NewWriterLevel(f, opts.CompressionLevel)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create gzip writer")
+	}
+	tarWriter := tar.NewWriter(gzipWriter)
+	w := &archiveWriter{
+		tarWriter:        tarWriter,
+		modTime:          modTime,
+		preserveSymlinks: opts.PreserveSymlinks,
+		namePrefix:       opts.NamePrefix,
+		format:           format,
+		executableMode:   int64(executableMode.Perm()),
+	}
+	for _, srcPath := range srcPaths {
+		if err := w.addPath(srcPath, filepath.Base(srcPath)); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close tar writer")
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close gzip writer")
+	}
+	return nil
+}
+
+// ModTime returns the modification time that should be used for all of the entries in the archives for the provided
+// project: the time specified by SOURCE_DATE_EPOCH if it is set for the project (see
+// distgo.ProjectInfo.LookupSourceDateEpoch) and the Unix epoch otherwise.
+func ModTime(projectInfo distgo.ProjectInfo) (time.Time, error) {
+	if _, ok := projectInfo.LookupSourceDateEpoch(); ok {
+		return projectInfo.BuildTime()
+	}
+	return time.Unix(0, 0).UTC(), nil
+}
+
+type archiveWriter struct {
+	tarWriter        *tar.Writer
+	modTime          time.Time
+	preserveSymlinks bool
+	namePrefix       string
+	format           tar.Format
+	executableMode   int64
+}
+
+// addPath adds the file or directory at srcPath to the archive as the entry with the provided name. If srcPath is a
+// directory, its contents are added recursively in sorted order.
+func (w *archiveWriter) addPath(srcPath, name string) error {
+	if w.preserveSymlinks {
+		fi, err := os.Lstat(srcPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to stat %s", srcPath)
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return w.addSymlink(srcPath, name)
+		}
+	}
+
+	fi, err := os.Stat(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %s", srcPath)
+	}
+
+	if fi.IsDir() {
+		if err := w.writeHeader(w.header(name+"/", tar.TypeDir, 0755, 0), srcPath); err != nil {
+			return err
+		}
+		// ioutil.ReadDir returns the entries sorted by name
+		children, err := ioutil.ReadDir(srcPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list directory %s", srcPath)
+		}
+		for _, child := range children {
+			if err := w.addPath(filepath.Join(srcPath, child.Name()), path.Join(name, child.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !fi.Mode().IsRegular() {
+		return errors.Errorf("cannot add %s to archive: not a regular file or directory (mode %v)", srcPath, fi.Mode())
+	}
+	var mode int64 = 0644
+	if fi.Mode()&0111 != 0 {
+		mode = w.executableMode
+	}
+	if err := w.writeHeader(w.header(name, tar.TypeReg, mode, fi.Size()), srcPath); err != nil {
+		return err
+	}
+	return w.writeFileContent(srcPath)
+}
+
+// addSymlink adds the symbolic link at srcPath to the archive as a symbolic link entry with the provided name. Returns
+// an error if the target of the link is absolute or resolves to a location outside of the root of the archive.
+func (w *archiveWriter) addSymlink(srcPath, name string) error {
+	target, err := os.Readlink(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read symbolic link %s", srcPath)
+	}
+	target = filepath.ToSlash(target)
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		return errors.Errorf("cannot add symbolic link %s to archive: target %s is an absolute path", srcPath, target)
+	}
+	if resolved := path.Join(path.Dir(name), target); resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return errors.Errorf("cannot add symbolic link %s to archive: target %s resolves to a location outside of the archive", srcPath, target)
+	}
+	hdr := w.header(name, tar.TypeSymlink, 0777, 0)
+	hdr.Linkname = target
+	return w.writeHeader(hdr, srcPath)
+}
+
+// writeHeader writes the provided header for the entry for srcPath. Returns an error if the header cannot be encoded
+// using the format of the archive.
+func (w *archiveWriter) writeHeader(hdr *tar.Header, srcPath string) error {
+	if w.format == tar.FormatUSTAR {
+		if !fitsUSTARName(hdr.Name) {
+			return errors.Errorf("cannot add %s to archive: path %s is too long for the %s tar format", srcPath, hdr.Name, FormatNameUSTAR)
+		}
+		if len(hdr.Linkname) > ustarNameSize {
+			return errors.Errorf("cannot add %s to archive: link target %s is too long for the %s tar format", srcPath, hdr.Linkname, FormatNameUSTAR)
+		}
+	}
+	if err := w.tarWriter.WriteHeader(hdr); err != nil {
+		return errors.Wrapf(err, "failed to write tar header for %s", srcPath)
+	}
+	return nil
+}
+
+func (w *archiveWriter) writeFileContent(srcPath string) error {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", srcPath)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := io.Copy(w.tarWriter, f); err != nil {
+		return errors.Wrapf(err, "failed to write %s to archive", srcPath)
+	}
+	return nil
+}
+
+func (w *archiveWriter) header(name string, typeFlag byte, mode, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: typeFlag,
+		Name:     w.namePrefix + name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  w.modTime,
+		Format:   w.format,
+	}
+}
+
+// fitsUSTARName returns true if the provided name can be stored in a USTAR header: either it fits in the name field, or
+// it can be split at a "/" into a prefix that fits in the prefix field and a non-empty remainder that fits in the name
+// field.
+func fitsUSTARName(name string) bool {
+	if len(name) <= ustarNameSize {
+		return true
+	}
+	// the trailing "/" of a directory is not used as a split point
+	trimmed := strings.TrimSuffix(name, "/")
+	for i := 1; i < len(trimmed) && i <= ustarPrefixSize; i++ {
+		if trimmed[i] == '/' && len(name)-i-1 <= ustarNameSize {
+			return true
+		}
+	}
+	return false
+}
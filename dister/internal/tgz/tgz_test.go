@@ -0,0 +1,447 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tgz_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/internal/tgz"
+	"github.com/palantir/distgo/distgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveIsReproducible(t *testing.T) {
+	defer setSourceDateEpoch(t, nil)()
+
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	srcDir := path.Join(tmp, "foo-1.0.0")
+	writeFile(t, path.Join(srcDir, "bin", "linux-amd64", "foo"), "foo-linux", 0755)
+	writeFile(t, path.Join(srcDir, "bin", "darwin-amd64", "foo"), "foo-darwin", 0700)
+	writeFile(t, path.Join(srcDir, "README.txt"), "readme", 0600)
+
+	firstPath := path.Join(tmp, "first.tgz")
+	require.NoError(t, tgz.Archive([]string{srcDir}, firstPath, tgz.Options{CompressionLevel: gzip.DefaultCompression}))
+
+	// change modification times and non-executable permissions of the inputs
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path.Join(srcDir, "README.txt"), later, later))
+	require.NoError(t, os.Chtimes(path.Join(srcDir, "bin"), later, later))
+	require.NoError(t, os.Chmod(path.Join(srcDir, "README.txt"), 0664))
+
+	secondPath := path.Join(tmp, "second.tgz")
+	require.NoError(t, tgz.Archive([]string{srcDir}, secondPath, tgz.Options{CompressionLevel: gzip.DefaultCompression}))
+
+	firstBytes, err := ioutil.ReadFile(firstPath)
+	require.NoError(t, err)
+	secondBytes, err := ioutil.ReadFile(secondPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(firstBytes, secondBytes), "archives created from identical inputs differ")
+
+	type entry struct {
+		name    string
+		mode    int64
+		content string
+	}
+	var gotEntries []entry
+	for _, hdr := range readTGZ(t, firstPath) {
+		assert.Equal(t, 0, hdr.Uid, hdr.Name)
+		assert.Equal(t, 0, hdr.Gid, hdr.Name)
+		assert.Equal(t, "", hdr.Uname, hdr.Name)
+		assert.Equal(t, "", hdr.Gname, hdr.Name)
+		assert.True(t, hdr.ModTime.Equal(time.Unix(0, 0)), "unexpected modification time %v for %s", hdr.ModTime, hdr.Name)
+		gotEntries = append(gotEntries, entry{name: hdr.Name, mode: hdr.Mode, content: hdr.content})
+	}
+	assert.Equal(t, []entry{
+		{name: "foo-1.0.0/", mode: 0755},
+		{name: "foo-1.0.0/README.txt", mode: 0644, content: "readme"},
+		{name: "foo-1.0.0/bin/", mode: 0755},
+		{name: "foo-1.0.0/bin/darwin-amd64/", mode: 0755},
+		{name: "foo-1.0.0/bin/darwin-amd64/foo", mode: 0755, content: "foo-darwin"},
+		{name: "foo-1.0.0/bin/linux-amd64/", mode: 0755},
+		{name: "foo-1.0.0/bin/linux-amd64/foo", mode: 0755, content: "foo-linux"},
+	}, gotEntries)
+}
+
+func TestArchiveUsesModTime(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	srcFile := path.Join(tmp, "foo")
+	writeFile(t, srcFile, "foo", 0755)
+	dstPath := path.Join(tmp, "foo.tgz")
+	require.NoError(t, tgz.Archive([]string{srcFile}, dstPath, tgz.Options{
+		CompressionLevel: gzip.DefaultCompression,
+		ModTime:          time.Unix(1600000000, 0),
+	}))
+
+	headers := readTGZ(t, dstPath)
+	require.Len(t, headers, 1)
+	assert.Equal(t, "foo", headers[0].Name)
+	assert.True(t, headers[0].ModTime.Equal(time.Unix(1600000000, 0)), "unexpected modification time %v", headers[0].ModTime)
+}
+
+func TestModTime(t *testing.T) {
+	restoreFn := setSourceDateEpoch(t, nil)
+	got, err := tgz.ModTime(distgo.ProjectInfo{})
+	require.NoError(t, err)
+	assert.True(t, got.Equal(time.Unix(0, 0)), "unexpected modification time %v", got)
+
+	// SOURCE_DATE_EPOCH of the project is used if the environment variable is not set
+	got, err = tgz.ModTime(distgo.ProjectInfo{SourceDateEpoch: "1700000000"})
+	require.NoError(t, err)
+	assert.True(t, got.Equal(time.Unix(1700000000, 0)), "unexpected modification time %v", got)
+	restoreFn()
+
+	epoch := "1600000000"
+	defer setSourceDateEpoch(t, &epoch)()
+	got, err = tgz.ModTime(distgo.ProjectInfo{})
+	require.NoError(t, err)
+	assert.True(t, got.Equal(time.Unix(1600000000, 0)), "unexpected modification time %v", got)
+
+	// SOURCE_DATE_EPOCH of the project takes precedence over the environment variable
+	got, err = tgz.ModTime(distgo.ProjectInfo{SourceDateEpoch: "1700000000"})
+	require.NoError(t, err)
+	assert.True(t, got.Equal(time.Unix(1700000000, 0)), "unexpected modification time %v", got)
+}
+
+func TestArchivePreservesSymlinks(t *testing.T) {
+	defer setSourceDateEpoch(t, nil)()
+
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	srcDir := path.Join(tmp, "foo")
+	writeFile(t, path.Join(srcDir, "1.0.0", "bin", "foo"), "foo", 0755)
+	require.NoError(t, os.Symlink("1.0.0", path.Join(srcDir, "current")))
+	require.NoError(t, os.Symlink("../1.0.0/bin/foo", path.Join(srcDir, "1.0.0", "foo")))
+
+	dstPath := path.Join(tmp, "foo.tgz")
+	require.NoError(t, tgz.Archive([]string{srcDir}, dstPath, tgz.Options{
+		PreserveSymlinks: true,
+		CompressionLevel: gzip.DefaultCompression,
+	}))
+
+	extractDir := path.Join(tmp, "extracted")
+	extractTGZ(t, dstPath, extractDir)
+	for i, tc := range []struct {
+		name   string
+		target string
+	}{
+		{name: "foo/current", target: "1.0.0"},
+		{name: "foo/1.0.0/foo", target: "../1.0.0/bin/foo"},
+	} {
+		fi, err := os.Lstat(path.Join(extractDir, tc.name))
+		require.NoError(t, err, "Case %d", i)
+		assert.True(t, fi.Mode()&os.ModeSymlink != 0, "Case %d: %s is not a symbolic link", i, tc.name)
+		target, err := os.Readlink(path.Join(extractDir, tc.name))
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.target, target, "Case %d", i)
+	}
+	content, err := ioutil.ReadFile(path.Join(extractDir, "foo", "current", "bin", "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+}
+
+func TestArchiveSymlinkErrors(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	for i, tc := range []struct {
+		target  string
+		wantErr string
+	}{
+		{
+			target:  "/etc/passwd",
+			wantErr: "target /etc/passwd is an absolute path",
+		},
+		{
+			target:  "../../../outside",
+			wantErr: "target ../../../outside resolves to a location outside of the archive",
+		},
+		{
+			target:  "../sibling/../../..",
+			wantErr: "target ../sibling/../../.. resolves to a location outside of the archive",
+		},
+	} {
+		srcDir := path.Join(tmp, fmt.Sprintf("case-%d", i), "foo")
+		require.NoError(t, os.MkdirAll(path.Join(srcDir, "dir"), 0755), "Case %d", i)
+		require.NoError(t, os.Symlink(tc.target, path.Join(srcDir, "dir", "link")), "Case %d", i)
+
+		err := tgz.Archive([]string{srcDir}, path.Join(tmp, fmt.Sprintf("case-%d.tgz", i)), tgz.Options{
+			PreserveSymlinks: true,
+			CompressionLevel: gzip.DefaultCompression,
+		})
+		require.Error(t, err, "Case %d", i)
+		assert.Contains(t, err.Error(), tc.wantErr, "Case %d", i)
+	}
+}
+
+func TestArchiveCompressionLevel(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	srcFile := path.Join(tmp, "foo")
+	writeFile(t, srcFile, strings.Repeat("foo", 1024), 0755)
+
+	for i, level := range []int{gzip.HuffmanOnly, gzip.NoCompression, gzip.BestSpeed, gzip.BestCompression} {
+		dstPath := path.Join(tmp, fmt.Sprintf("foo-%d.tgz", i))
+		require.NoError(t, tgz.Archive([]string{srcFile}, dstPath, tgz.Options{
+			CompressionLevel: level,
+		}), "Case %d", i)
+
+		headers := readTGZ(t, dstPath)
+		require.Len(t, headers, 1, "Case %d", i)
+		assert.Equal(t, "foo", headers[0].Name, "Case %d", i)
+		assert.Equal(t, strings.Repeat("foo", 1024), headers[0].content, "Case %d", i)
+	}
+
+	for i, level := range []int{-3, 10} {
+		err := tgz.Archive([]string{srcFile}, path.Join(tmp, "invalid.tgz"), tgz.Options{
+			CompressionLevel: level,
+		})
+		require.Error(t, err, "Case %d", i)
+		assert.EqualError(t, err, fmt.Sprintf("compression level must be between -2 and 9, was %d", level), "Case %d", i)
+	}
+	_, err = os.Stat(path.Join(tmp, "invalid.tgz"))
+	assert.True(t, os.IsNotExist(err), "archive should not be created for an invalid compression level")
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// the name of the file is longer than the name field of a USTAR header, so its path cannot be split to fit
+	longFileName := strings.Repeat("f", 120)
+	srcDir := path.Join(tmp, "foo")
+	writeFile(t, path.Join(srcDir, longFileName), "long", 0644)
+	writeFile(t, path.Join(tmp, "short"), "short", 0644)
+
+	for i, tc := range []struct {
+		name       string
+		format     tar.Format
+		srcPath    string
+		wantFormat tar.Format
+		wantErr    string
+	}{
+		{
+			name:       "default format is PAX",
+			format:     tar.FormatUnknown,
+			srcPath:    srcDir,
+			wantFormat: tar.FormatPAX,
+		},
+		{
+			name:       "PAX format with long path",
+			format:     tar.FormatPAX,
+			srcPath:    srcDir,
+			wantFormat: tar.FormatPAX,
+		},
+		{
+			name:       "GNU format with long path",
+			format:     tar.FormatGNU,
+			srcPath:    srcDir,
+			wantFormat: tar.FormatGNU,
+		},
+		{
+			name:       "USTAR format with short path",
+			format:     tar.FormatUSTAR,
+			srcPath:    path.Join(tmp, "short"),
+			wantFormat: tar.FormatUSTAR,
+		},
+		{
+			name:    "USTAR format with long path",
+			format:  tar.FormatUSTAR,
+			srcPath: srcDir,
+			wantErr: fmt.Sprintf("cannot add %s to archive: path foo/%s is too long for the ustar tar format", path.Join(srcDir, longFileName), longFileName),
+		},
+	} {
+		dstPath := path.Join(tmp, fmt.Sprintf("case-%d.tgz", i))
+		err := tgz.Archive([]string{tc.srcPath}, dstPath, tgz.Options{
+			CompressionLevel: gzip.DefaultCompression,
+			Format:           tc.format,
+		})
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		headers := readTGZ(t, dstPath)
+		require.NotEmpty(t, headers, "Case %d: %s", i, tc.name)
+		lastHeader := headers[len(headers)-1]
+		// the last entry has the longest path, so its header can only be decoded as the format of the archive
+		assert.Equal(t, tc.wantFormat, lastHeader.Format, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestArchiveExecutableMode(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	srcDir := path.Join(tmp, "foo")
+	writeFile(t, path.Join(srcDir, "executable"), "executable", 0700)
+	writeFile(t, path.Join(srcDir, "file"), "file", 0600)
+
+	for i, tc := range []struct {
+		name           string
+		executableMode os.FileMode
+		wantModes      map[string]int64
+	}{
+		{
+			name: "default executable mode",
+			wantModes: map[string]int64{
+				"foo/":           0755,
+				"foo/executable": 0755,
+				"foo/file":       0644,
+			},
+		},
+		{
+			name:           "custom executable mode",
+			executableMode: 0750,
+			wantModes: map[string]int64{
+				"foo/":           0755,
+				"foo/executable": 0750,
+				"foo/file":       0644,
+			},
+		},
+	} {
+		dstPath := path.Join(tmp, fmt.Sprintf("case-%d.tgz", i))
+		err := tgz.Archive([]string{srcDir}, dstPath, tgz.Options{
+			CompressionLevel: gzip.DefaultCompression,
+			ExecutableMode:   tc.executableMode,
+		})
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		gotModes := make(map[string]int64)
+		for _, hdr := range readTGZ(t, dstPath) {
+			gotModes[hdr.Name] = hdr.Mode
+		}
+		assert.Equal(t, tc.wantModes, gotModes, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for i, tc := range []struct {
+		name    string
+		want    tar.Format
+		wantErr string
+	}{
+		{"", tar.FormatPAX, ""},
+		{"pax", tar.FormatPAX, ""},
+		{"gnu", tar.FormatGNU, ""},
+		{"ustar", tar.FormatUSTAR, ""},
+		{"v7", tar.FormatUnknown, `tar format must be one of "gnu", "pax" or "ustar", was "v7"`},
+	} {
+		got, err := tgz.ParseFormat(tc.name)
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d", i)
+			continue
+		}
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.want, got, "Case %d", i)
+	}
+}
+
+type headerWithContent struct {
+	*tar.Header
+	content string
+}
+
+func readTGZ(t *testing.T, tgzPath string) []headerWithContent {
+	f, err := os.Open(tgzPath)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	gzipReader, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	var headers []headerWithContent
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		headers = append(headers, headerWithContent{Header: hdr, content: string(content)})
+	}
+	return headers
+}
+
+// extractTGZ extracts the directories, regular files and symbolic links in the archive at tgzPath to dstDir.
+func extractTGZ(t *testing.T, tgzPath, dstDir string) {
+	for _, hdr := range readTGZ(t, tgzPath) {
+		dstPath := path.Join(dstDir, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			require.NoError(t, os.MkdirAll(dstPath, 0755))
+		case tar.TypeReg:
+			writeFile(t, dstPath, hdr.content, os.FileMode(hdr.Mode))
+		case tar.TypeSymlink:
+			require.NoError(t, os.MkdirAll(path.Dir(dstPath), 0755))
+			require.NoError(t, os.Symlink(hdr.Linkname, dstPath))
+		default:
+			require.Fail(t, "unexpected entry type", "entry %s has type %v", hdr.Name, hdr.Typeflag)
+		}
+	}
+}
+
+func writeFile(t *testing.T, filePath, content string, mode os.FileMode) {
+	require.NoError(t, os.MkdirAll(path.Dir(filePath), 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(content), mode))
+	// set mode explicitly so that it is not affected by umask
+	require.NoError(t, os.Chmod(filePath, mode))
+}
+
+// setSourceDateEpoch sets the SOURCE_DATE_EPOCH environment variable to the provided value (or unsets it if the value
+// is nil) and returns a function that restores the original value.
+func setSourceDateEpoch(t *testing.T, val *string) func() {
+	origVal, origSet := os.LookupEnv(distgo.SourceDateEpochEnvVar)
+	if val == nil {
+		require.NoError(t, os.Unsetenv(distgo.SourceDateEpochEnvVar))
+	} else {
+		require.NoError(t, os.Setenv(distgo.SourceDateEpochEnvVar, *val))
+	}
+	return func() {
+		if origSet {
+			require.NoError(t, os.Setenv(distgo.SourceDateEpochEnvVar, origVal))
+		} else {
+			require.NoError(t, os.Unsetenv(distgo.SourceDateEpochEnvVar))
+		}
+	}
+}
@@ -15,20 +15,35 @@
 package config
 
 import (
+	"github.com/palantir/distgo/dister/internal/tgz"
 	"github.com/palantir/distgo/dister/osarchbin"
 	v0 "github.com/palantir/distgo/dister/osarchbin/config/internal/v0"
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
 )
 
 type OSArchBin v0.Config
 
-func (cfg *OSArchBin) ToDister() distgo.Dister {
+func (cfg *OSArchBin) ToDister() (distgo.Dister, error) {
+	if cfg.CompressionLevel != nil {
+		if err := tgz.ValidateCompressionLevel(*cfg.CompressionLevel); err != nil {
+			return nil, errors.Wrapf(err, "invalid compression-level")
+		}
+	}
+	tarFormat, err := tgz.ParseFormat(cfg.TarFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid tar-format")
+	}
+
 	osArchs := cfg.OSArchs
 	if len(osArchs) == 0 {
 		osArchs = []osarch.OSArch{osarch.Current()}
 	}
 	return &osarchbin.Dister{
-		OSArchs: osArchs,
-	}
+		OSArchs:          osArchs,
+		PreserveSymlinks: cfg.PreserveSymlinks,
+		CompressionLevel: cfg.CompressionLevel,
+		TarFormat:        tarFormat,
+	}, nil
 }
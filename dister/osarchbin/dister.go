@@ -15,31 +15,44 @@
 package osarchbin
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"path"
 	"path/filepath"
-	"sort"
 	"strings"
 
-	"github.com/mholt/archiver/v3"
+	"github.com/palantir/distgo/dister/internal/buildartifact"
+	"github.com/palantir/distgo/dister/internal/tgz"
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
-	"github.com/termie/go-shutil"
 )
 
 const TypeName = "os-arch-bin" // distribution that consists of the binaries for a specific OS/Architecture
 
 type Dister struct {
 	OSArchs []osarch.OSArch
+
+	// PreserveSymlinks specifies that symbolic links in the distribution directory are written to the archives as
+	// symbolic links rather than as copies of their targets.
+	PreserveSymlinks bool
+
+	// CompressionLevel is the gzip compression level used for the TGZ archives. Must be between gzip.HuffmanOnly (-2)
+	// and gzip.BestCompression (9). If nil, gzip.DefaultCompression is used.
+	CompressionLevel *int
+
+	// TarFormat is the format used for the headers of the entries in the TGZ archives. Must be tar.FormatPAX,
+	// tar.FormatGNU or tar.FormatUSTAR.
+	TarFormat tar.Format
 }
 
 func New(osArchs ...osarch.OSArch) distgo.Dister {
 	return &Dister{
-		OSArchs: osArchs,
+		OSArchs:   osArchs,
+		TarFormat: tar.FormatPAX,
 	}
 }
 
@@ -59,6 +72,14 @@ func (d *Dister) PackagingExtension() (string, error) {
 	return "tgz", nil
 }
 
+func (d *Dister) ArtifactOSArchs(renderedName string) (map[string]osarch.OSArch, error) {
+	artifactOSArchs := make(map[string]osarch.OSArch)
+	for _, osArch := range d.OSArchs {
+		artifactOSArchs[fmt.Sprintf("%s-%s.tgz", renderedName, osArch.String())] = osArch
+	}
+	return artifactOSArchs, nil
+}
+
 func (d *Dister) osArchFromArtifactPath(distID distgo.DistID, artifactPath string, productTaskOutputInfo distgo.ProductTaskOutputInfo) (osarch.OSArch, error) {
 	for _, osArch := range d.OSArchs {
 		if strings.HasSuffix(artifactPath, fmt.Sprintf("%s-%s.tgz", productTaskOutputInfo.Product.DistOutputInfos.DistInfos[distID].DistNameTemplateRendered, osArch.String())) {
@@ -70,21 +91,20 @@ func (d *Dister) osArchFromArtifactPath(distID distgo.DistID, artifactPath strin
 
 func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]byte, error) {
 	for _, osArch := range d.OSArchs {
-		if err := verifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
+		if err := buildartifact.VerifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
 			return nil, err
 		}
 	}
 	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
 	outputPathsForOSArchs := make(map[string][]string)
 	for _, osArch := range d.OSArchs {
-		for _, currProductOutputInfo := range productTaskOutputInfo.AllProductOutputInfos() {
-			// copy executable for current product
-			dst, err := copyArtifactForOSArch(distWorkDir, productTaskOutputInfo.Project, currProductOutputInfo, osArch)
-			if err != nil {
-				return nil, err
-			}
-			outputPathsForOSArchs[osArch.String()] = append(outputPathsForOSArchs[osArch.String()], dst)
+		osArchWorkDir := path.Join(distWorkDir, osArch.String())
+		// copy executables for product and its dependencies
+		dsts, err := buildartifact.CopyAllForOSArch(osArchWorkDir, distID, productTaskOutputInfo, osArch)
+		if err != nil {
+			return nil, err
 		}
+		outputPathsForOSArchs[osArch.String()] = dsts
 	}
 	jsonBytes, err := json.Marshal(outputPathsForOSArchs)
 	if err != nil {
@@ -94,6 +114,10 @@ func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.Prod
 }
 
 func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, runDistResult []byte) error {
+	modTime, err := tgz.ModTime(productTaskOutputInfo.Project)
+	if err != nil {
+		return err
+	}
 	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
 	outputArtifactPaths := productTaskOutputInfo.ProductDistArtifactPaths()[distID]
 	for _, artifactPath := range outputArtifactPaths {
@@ -112,69 +136,40 @@ func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputIn
 		for i, item := range items {
 			itemPaths[i] = filepath.Join(workDir, item.Name())
 		}
-		if err := archiver.DefaultTarGz.Archive(itemPaths, artifactPath); err != nil {
+		if err := tgz.Archive(itemPaths, artifactPath, tgz.Options{
+			PreserveSymlinks: d.PreserveSymlinks,
+			CompressionLevel: d.compressionLevel(),
+			Format:           d.TarFormat,
+			ExecutableMode:   productTaskOutputInfo.ProductDistExecutableMode(distID),
+			ModTime:          modTime,
+		}); err != nil {
 			return errors.Wrapf(err, "failed to create TGZ archive")
 		}
 	}
 	return nil
 }
 
-func verifyDistTargetSupported(osArch osarch.OSArch, productTaskOutputInfo distgo.ProductTaskOutputInfo) error {
-	if err := verifySingleProduct(osArch, productTaskOutputInfo.Product); err != nil {
-		return err
-	}
-	var keys []distgo.ProductID
-	for k := range productTaskOutputInfo.Deps {
-		keys = append(keys, k)
-	}
-	sort.Sort(distgo.ByProductID(keys))
-	for _, currKey := range keys {
-		currSpec := productTaskOutputInfo.Deps[currKey]
-		if err := verifySingleProduct(osArch, currSpec); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func verifySingleProduct(osArch osarch.OSArch, productOutputInfo distgo.ProductOutputInfo) error {
-	if !osArchInBuildSpec(osArch, productOutputInfo) {
-		buildOSArchs := "[none]"
-		if productOutputInfo.BuildOutputInfo != nil {
-			buildOSArchs = fmt.Sprint(productOutputInfo.BuildOutputInfo.OSArchs)
+// ArtifactContents returns the contents of each artifact, which contains the contents of the directory for its
+// OS/architecture in the dist work directory.
+func (d *Dister) ArtifactContents(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (map[string]distgo.ArtifactContents, error) {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	contents := make(map[string]distgo.ArtifactContents)
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return nil, err
 		}
-		return errors.Errorf("the OS/Arch specified for the distribution of a product must be specified as a build target for the product, "+
-			"but product %s does not specify %s as one of its build targets (current build targets: %s)", productOutputInfo.ID, osArch, buildOSArchs)
-	}
-	return nil
-}
-
-func osArchInBuildSpec(osArch osarch.OSArch, productOutputInfo distgo.ProductOutputInfo) bool {
-	if productOutputInfo.BuildOutputInfo == nil {
-		return false
-	}
-	found := false
-	for _, currBuildOSArch := range productOutputInfo.BuildOutputInfo.OSArchs {
-		if currBuildOSArch == osArch {
-			found = true
-			break
+		contents[artifactPath] = distgo.ArtifactContents{
+			Dir: path.Join(distWorkDir, currOSArch.String()),
 		}
 	}
-	return found
+	return contents, nil
 }
 
-func copyArtifactForOSArch(outputDir string, projectInfo distgo.ProjectInfo, productInfo distgo.ProductOutputInfo, osArch osarch.OSArch) (string, error) {
-	artifactPath, ok := distgo.ProductBuildArtifactPaths(projectInfo, productInfo)[osArch]
-	if !ok {
-		return "", errors.Errorf("no build artifacts exist for %s", osArch)
-	}
-
-	dst := path.Join(outputDir, osArch.String(), distgo.ExecutableName(productInfo.BuildOutputInfo.BuildNameTemplateRendered, osArch.OS))
-	if err := os.MkdirAll(path.Dir(dst), 0755); err != nil {
-		return "", errors.Wrapf(err, "failed to create output directory for artifact")
-	}
-	if _, err := shutil.Copy(artifactPath, dst, false); err != nil {
-		return "", errors.Wrapf(err, "failed to copy build artifact from %s to %s", artifactPath, dst)
+// compressionLevel returns the gzip compression level used for the TGZ archives.
+func (d *Dister) compressionLevel() int {
+	if d.CompressionLevel == nil {
+		return gzip.DefaultCompression
 	}
-	return dst, nil
+	return *d.CompressionLevel
 }
@@ -0,0 +1,77 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/palantir/distgo/dister/rpm"
+	v0 "github.com/palantir/distgo/dister/rpm/config/internal/v0"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+type RPM v0.Config
+
+func (cfg *RPM) ToDister() (distgo.Dister, error) {
+	if cfg.License == "" {
+		return nil, errors.Errorf("license must be specified for rpm dister")
+	}
+	osArchs := cfg.OSArchs
+	if len(osArchs) == 0 {
+		osArchs = []osarch.OSArch{osarch.Current()}
+	}
+	for _, osArch := range osArchs {
+		if osArch.OS != "linux" {
+			return nil, errors.Errorf("os-archs for rpm dister must have an OS of linux, but contained %s", osArch)
+		}
+	}
+	var files []rpm.FileMapping
+	for _, fileCfg := range cfg.Files {
+		if fileCfg.Source == "" {
+			return nil, errors.Errorf("source must be specified for file with destination %q", fileCfg.Destination)
+		}
+		if !path.IsAbs(fileCfg.Destination) {
+			return nil, errors.Errorf("destination for file %s must be an absolute path, was %q", fileCfg.Source, fileCfg.Destination)
+		}
+		var mode os.FileMode
+		if fileCfg.Mode != "" {
+			modeVal, err := strconv.ParseUint(fileCfg.Mode, 8, 32)
+			if err != nil || modeVal > 07777 {
+				return nil, errors.Errorf("mode for file %s must be an octal value between 0000 and 7777, was %q", fileCfg.Source, fileCfg.Mode)
+			}
+			mode = os.FileMode(modeVal)
+		}
+		files = append(files, rpm.FileMapping{
+			Source:      fileCfg.Source,
+			Destination: fileCfg.Destination,
+			Mode:        mode,
+			Config:      fileCfg.Config,
+		})
+	}
+	return &rpm.Dister{
+		OSArchs:     osArchs,
+		PackageName: cfg.PackageName,
+		Version:     cfg.Version,
+		Release:     cfg.Release,
+		License:     cfg.License,
+		Summary:     cfg.Summary,
+		Description: cfg.Description,
+		Files:       files,
+	}, nil
+}
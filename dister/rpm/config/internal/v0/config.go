@@ -0,0 +1,81 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	// OSArchs specifies the GOOS and GOARCH pairs for which RPMs are created. The GOOS of every entry must be "linux".
+	// If blank, defaults to the GOOS and GOARCH of the host system at runtime.
+	OSArchs []osarch.OSArch `yaml:"os-archs,omitempty"`
+
+	// PackageName is the name of the package. If not specified, the name of the product is used.
+	PackageName string `yaml:"package-name,omitempty"`
+
+	// Version is the version of the package. If not specified, the version of the project is used with any "-"
+	// characters replaced by "_".
+	Version string `yaml:"version,omitempty"`
+
+	// Release is the release of the package. If not specified, defaults to "1".
+	Release string `yaml:"release,omitempty"`
+
+	// License is the license of the package (for example, "ASL 2.0"). Must be specified.
+	License string `yaml:"license,omitempty"`
+
+	// Summary is the one-line summary of the package. If not specified, the package name is used.
+	Summary string `yaml:"summary,omitempty"`
+
+	// Description is the description of the package. If not specified, the summary is used.
+	Description string `yaml:"description,omitempty"`
+
+	// Files specifies the files that are installed by the package in addition to the executables of the product, which
+	// are always installed in "/usr/bin". For example:
+	//
+	//   files:
+	//     - source: config/foo.yml
+	//       destination: /etc/foo/foo.yml
+	//       mode: "0640"
+	//       config: true
+	Files []FileConfig `yaml:"files,omitempty"`
+}
+
+type FileConfig struct {
+	// Source is the path to the file relative to the project directory.
+	Source string `yaml:"source,omitempty"`
+
+	// Destination is the absolute path at which the file is installed.
+	Destination string `yaml:"destination,omitempty"`
+
+	// Mode is the octal representation of the permission bits of the installed file (for example, "0644"). If not
+	// specified, the mode is the executable-mode of the distribution ("0755" by default) if the source file is
+	// executable and "0644" otherwise.
+	Mode string `yaml:"mode,omitempty"`
+
+	// Config specifies whether the file is a configuration file. Modifications made to configuration files are
+	// preserved when the package is upgraded or removed.
+	Config bool `yaml:"config,omitempty"`
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal rpm dister v0 configuration")
+	}
+	return cfgBytes, nil
+}
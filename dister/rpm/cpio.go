@@ -0,0 +1,83 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const cpioTrailerName = "TRAILER!!!"
+
+// cpioWriter writes an archive in the "newc" cpio format, which is the payload format of an RPM.
+type cpioWriter struct {
+	w       io.Writer
+	written int64
+}
+
+// writeFile writes the entry for the provided file. The name of the entry is the install path of the file prefixed
+// with ".".
+func (c *cpioWriter) writeFile(f packageFile, inode int, mtime int64) error {
+	src, err := os.Open(f.srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", f.srcPath)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+	if err := c.writeHeader("."+f.path, inode, fileModeRegular|uint32(f.mode), mtime, f.size, 1); err != nil {
+		return err
+	}
+	n, err := io.Copy(c.w, src)
+	c.written += n
+	if err != nil {
+		return errors.Wrapf(err, "failed to write %s to payload", f.srcPath)
+	}
+	if n != f.size {
+		return errors.Errorf("size of %s changed while it was being written to payload", f.srcPath)
+	}
+	return c.pad()
+}
+
+// close writes the trailer entry that terminates the archive.
+func (c *cpioWriter) close() error {
+	return c.writeHeader(cpioTrailerName, 0, 0, 0, 0, 1)
+}
+
+func (c *cpioWriter) writeHeader(name string, inode int, mode uint32, mtime, size int64, nlink int) error {
+	header := fmt.Sprintf("070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+		inode, mode, 0, 0, nlink, mtime, size, 0, 0, 0, 0, len(name)+1, 0)
+	if err := c.write([]byte(header + name + "\x00")); err != nil {
+		return errors.Wrapf(err, "failed to write payload header for %s", name)
+	}
+	return c.pad()
+}
+
+// pad pads the archive to a multiple of 4 bytes.
+func (c *cpioWriter) pad() error {
+	if rem := c.written % 4; rem != 0 {
+		return c.write(make([]byte, 4-rem))
+	}
+	return nil
+}
+
+func (c *cpioWriter) write(b []byte) error {
+	n, err := c.w.Write(b)
+	c.written += int64(n)
+	return err
+}
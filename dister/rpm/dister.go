@@ -0,0 +1,313 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/dister/internal/buildartifact"
+	"github.com/palantir/distgo/dister/internal/tgz"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+	"github.com/termie/go-shutil"
+)
+
+const TypeName = "rpm" // distribution that consists of the binaries for a specific OS/Architecture packaged as an RPM
+
+// dataDirName is the name of the directory in the work directory for an OS/Architecture that contains the files that
+// are installed by the package.
+const dataDirName = "data"
+
+type Dister struct {
+	OSArchs []osarch.OSArch
+
+	// PackageName is the name of the package. If empty, the ID of the product is used.
+	PackageName string
+
+	// Version is the version of the package. If empty, the version of the project is used with any "-" characters
+	// replaced by "_" (RPM versions cannot contain "-").
+	Version string
+
+	// Release is the release of the package. If empty, "1" is used.
+	Release string
+
+	// License is the license of the package.
+	License string
+
+	// Summary is the one-line summary of the package. If empty, the package name is used.
+	Summary string
+
+	// Description is the description of the package. If empty, the summary is used.
+	Description string
+
+	// Files are the files in addition to the executables of the product that are installed by the package.
+	Files []FileMapping
+}
+
+// FileMapping specifies a file that is installed by the package.
+type FileMapping struct {
+	// Source is the path to the file. A relative path is resolved relative to the project directory.
+	Source string
+
+	// Destination is the absolute path at which the file is installed.
+	Destination string
+
+	// Mode is the permission bits of the installed file. If 0, the mode is the executable mode of the distribution
+	// (see distgo.DisterParam.ExecutableMode) if the source file is executable and 0644 otherwise.
+	Mode os.FileMode
+
+	// Config marks the file as a configuration file.
+	Config bool
+}
+
+func New(license string, osArchs ...osarch.OSArch) distgo.Dister {
+	return &Dister{
+		OSArchs: osArchs,
+		License: license,
+	}
+}
+
+func (d *Dister) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+func (d *Dister) Artifacts(renderedName string) ([]string, error) {
+	var outPaths []string
+	for _, osArch := range d.OSArchs {
+		outPaths = append(outPaths, fmt.Sprintf("%s-%s.rpm", renderedName, osArch.String()))
+	}
+	return outPaths, nil
+}
+
+func (d *Dister) PackagingExtension() (string, error) {
+	return "rpm", nil
+}
+
+func (d *Dister) ArtifactOSArchs(renderedName string) (map[string]osarch.OSArch, error) {
+	artifactOSArchs := make(map[string]osarch.OSArch)
+	for _, osArch := range d.OSArchs {
+		artifactOSArchs[fmt.Sprintf("%s-%s.rpm", renderedName, osArch.String())] = osArch
+	}
+	return artifactOSArchs, nil
+}
+
+func (d *Dister) osArchFromArtifactPath(distID distgo.DistID, artifactPath string, productTaskOutputInfo distgo.ProductTaskOutputInfo) (osarch.OSArch, error) {
+	for _, osArch := range d.OSArchs {
+		if strings.HasSuffix(artifactPath, fmt.Sprintf("%s-%s.rpm", productTaskOutputInfo.Product.DistOutputInfos.DistInfos[distID].DistNameTemplateRendered, osArch.String())) {
+			return osArch, nil
+		}
+	}
+	return osarch.OSArch{}, errors.Errorf("failed to determine OS/Arch for artifact with Path %s", artifactPath)
+}
+
+// RunDist copies the executables for each OS/architecture of the dister into "{{OSArch}}/data/usr/bin" in the dist
+// work directory and copies the files specified by the file mappings to their destinations in "{{OSArch}}/data". The
+// "data" directory for an OS/Architecture is the root of the file system tree that is installed by the package, so the
+// dist script can add files to the package by writing them to this directory.
+func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]byte, error) {
+	for _, osArch := range d.OSArchs {
+		if osArch.OS != "linux" {
+			return nil, errors.Errorf("rpm dist failed: RPMs can only be created for linux, but %s was specified", osArch)
+		}
+		if err := buildartifact.VerifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
+			return nil, err
+		}
+	}
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	outputPathsForOSArchs := make(map[string][]string)
+	for _, osArch := range d.OSArchs {
+		dataDir := path.Join(distWorkDir, osArch.String(), dataDirName)
+		// copy executables for product and its dependencies
+		dsts, err := buildartifact.CopyAllForOSArch(path.Join(dataDir, "usr", "bin"), distID, productTaskOutputInfo, osArch)
+		if err != nil {
+			return nil, err
+		}
+		outputPathsForOSArchs[osArch.String()] = dsts
+		for _, fileMapping := range d.Files {
+			src := fileMapping.Source
+			if !filepath.IsAbs(src) {
+				src = path.Join(productTaskOutputInfo.Project.ProjectDir, src)
+			}
+			dst := path.Join(dataDir, fileMapping.Destination)
+			if err := os.MkdirAll(path.Dir(dst), 0755); err != nil {
+				return nil, errors.Wrapf(err, "failed to create directory for %s", fileMapping.Destination)
+			}
+			if _, err := shutil.Copy(src, dst, false); err != nil {
+				return nil, errors.Wrapf(err, "failed to copy %s to %s", src, dst)
+			}
+		}
+	}
+	jsonBytes, err := json.Marshal(outputPathsForOSArchs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal outputPathsForOSArchs as JSON")
+	}
+	return jsonBytes, nil
+}
+
+// GenerateDistArtifacts creates an RPM for each OS/architecture that installs all of the regular files in the "data"
+// directory for the OS/architecture. The RPM is constructed directly and does not require "rpmbuild".
+func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, runDistResult []byte) error {
+	buildTime, err := tgz.ModTime(productTaskOutputInfo.Project)
+	if err != nil {
+		return err
+	}
+	fileMappings := make(map[string]FileMapping)
+	for _, fileMapping := range d.Files {
+		fileMappings[path.Clean("/"+fileMapping.Destination)] = fileMapping
+	}
+
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return err
+		}
+		arch, err := rpmArch(currOSArch)
+		if err != nil {
+			return err
+		}
+		osArchWorkDir := path.Join(distWorkDir, currOSArch.String())
+		files, err := packageFiles(path.Join(osArchWorkDir, dataDirName), fileMappings, productTaskOutputInfo.ProductDistExecutableMode(distID))
+		if err != nil {
+			return err
+		}
+		if err := writeRPM(artifactPath, osArchWorkDir, d.packageInfo(productTaskOutputInfo, arch, buildTime), files); err != nil {
+			return errors.Wrapf(err, "failed to create RPM")
+		}
+	}
+	return nil
+}
+
+// ArtifactContents returns the contents of each artifact, which installs the contents of the "data" directory for its
+// OS/architecture. The paths are the paths at which the files are installed, and a manifest that is included in the
+// package is installed in the documentation directory of the package.
+func (d *Dister) ArtifactContents(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (map[string]distgo.ArtifactContents, error) {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	packageName := d.packageInfo(productTaskOutputInfo, "", time.Time{}).name
+	contents := make(map[string]distgo.ArtifactContents)
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return nil, err
+		}
+		contents[artifactPath] = distgo.ArtifactContents{
+			Dir:         path.Join(distWorkDir, currOSArch.String(), dataDirName),
+			PathPrefix:  "/",
+			ManifestDir: path.Join("usr", "share", "doc", packageName),
+		}
+	}
+	return contents, nil
+}
+
+func (d *Dister) packageInfo(productTaskOutputInfo distgo.ProductTaskOutputInfo, arch string, buildTime time.Time) packageInfo {
+	info := packageInfo{
+		name:        d.PackageName,
+		version:     d.Version,
+		release:     d.Release,
+		summary:     d.Summary,
+		description: d.Description,
+		license:     d.License,
+		arch:        arch,
+		buildTime:   buildTime,
+	}
+	if info.name == "" {
+		info.name = string(productTaskOutputInfo.Product.ID)
+	}
+	if info.version == "" {
+		info.version = strings.Replace(productTaskOutputInfo.Project.Version, "-", "_", -1)
+	}
+	if info.release == "" {
+		info.release = "1"
+	}
+	if info.summary == "" {
+		info.summary = info.name
+	}
+	if info.description == "" {
+		info.description = info.summary
+	}
+	return info
+}
+
+// packageFiles returns the files for all of the regular files in the provided data directory. The mode and
+// configuration flag of a file are determined by its file mapping if one exists. Otherwise, the mode is the provided
+// executable mode if the file is executable and 0644 if it is not.
+func packageFiles(dataDir string, fileMappings map[string]FileMapping, executableMode os.FileMode) ([]packageFile, error) {
+	var files []packageFile
+	if err := filepath.Walk(dataDir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return errors.Errorf("%s is not a regular file", currPath)
+		}
+		relPath, err := filepath.Rel(dataDir, currPath)
+		if err != nil {
+			return err
+		}
+		installPath := "/" + filepath.ToSlash(relPath)
+
+		var mode os.FileMode = 0644
+		if info.Mode()&0111 != 0 {
+			mode = executableMode
+		}
+		fileMapping := fileMappings[installPath]
+		if fileMapping.Mode != 0 {
+			mode = fileMapping.Mode
+		}
+		files = append(files, packageFile{
+			path:    installPath,
+			srcPath: currPath,
+			size:    info.Size(),
+			mode:    mode,
+			config:  fileMapping.Config,
+		})
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to determine files in %s", dataDir)
+	}
+	return files, nil
+}
+
+// rpmArch returns the RPM architecture for the provided OS/architecture.
+func rpmArch(osArch osarch.OSArch) (string, error) {
+	goarch, variant := distgo.GOARCHAndVariant(osArch)
+	switch goarch {
+	case "amd64":
+		return "x86_64", nil
+	case "386":
+		return "i386", nil
+	case "arm64":
+		return "aarch64", nil
+	case "arm":
+		if variant == "5" || variant == "6" {
+			return "armv" + variant + "l", nil
+		}
+		return "armv7hl", nil
+	case "ppc64le", "s390x", "riscv64":
+		return goarch, nil
+	default:
+		return "", errors.Errorf("no RPM architecture is known for %s", osArch)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpm_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/rpm"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPMDist(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	dister := &rpm.Dister{
+		OSArchs: []osarch.OSArch{linuxAMD64},
+		License: "ASL 2.0",
+		Summary: "Foo does things",
+		Files: []rpm.FileMapping{
+			{
+				Source:      "config/foo.yml",
+				Destination: "/etc/foo/foo.yml",
+				Mode:        0640,
+				Config:      true,
+			},
+			{
+				Source:      "README.md",
+				Destination: "/usr/share/doc/foo/README.md",
+			},
+		},
+	}
+	const distID = distgo.DistID("rpm")
+	artifactNames, err := dister.Artifacts("foo-1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo-1.0.0-linux-amd64.rpm"}, artifactNames)
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0-2-gabcdef",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			BuildOutputInfo: &distgo.BuildOutputInfo{
+				BuildNameTemplateRendered: "foo",
+				BuildOutputDir:            "out/build",
+				OSArchs:                   []osarch.OSArch{linuxAMD64},
+			},
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{distID},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					distID: {
+						DistNameTemplateRendered: "foo-1.0.0",
+						DistArtifactNames:        artifactNames,
+						PackagingExtension:       "rpm",
+					},
+				},
+			},
+		},
+	}
+	writeFile(t, path.Join(tmp, "out", "build", "foo", "1.0.0-2-gabcdef", "linux-amd64", "foo"), "foo executable", 0755)
+	writeFile(t, path.Join(tmp, "config", "foo.yml"), "key: value\n", 0644)
+	writeFile(t, path.Join(tmp, "README.md"), "# foo\n", 0644)
+	require.NoError(t, os.MkdirAll(productTaskOutputInfo.ProductDistWorkDirs()[distID], 0755))
+
+	runDistResult, err := dister.RunDist(distID, productTaskOutputInfo)
+	require.NoError(t, err)
+	err = dister.GenerateDistArtifacts(distID, productTaskOutputInfo, runDistResult)
+	require.NoError(t, err)
+
+	rpmBytes, err := ioutil.ReadFile(path.Join(tmp, "out", "dist", "foo", "1.0.0-2-gabcdef", "rpm", "foo-1.0.0-linux-amd64.rpm"))
+	require.NoError(t, err)
+
+	// lead
+	require.True(t, len(rpmBytes) > 96)
+	assert.Equal(t, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0}, rpmBytes[:6])
+	assert.Equal(t, "foo-1.0.0_2_gabcdef-1", strings.TrimRight(string(rpmBytes[10:76]), "\x00"))
+	assert.Equal(t, uint16(5), binary.BigEndian.Uint16(rpmBytes[78:80]), "signature type")
+
+	// signature
+	sig, sigLen := parseHeader(t, rpmBytes[96:])
+	assert.Equal(t, int32(7), sig[62].typ, "signature region tag")
+	hdrStart := 96 + sigLen
+	if rem := sigLen % 8; rem != 0 {
+		hdrStart += 8 - rem
+	}
+	hdr, hdrLen := parseHeader(t, rpmBytes[hdrStart:])
+	hdrBytes := rpmBytes[hdrStart : hdrStart+hdrLen]
+	payload := rpmBytes[hdrStart+hdrLen:]
+
+	assert.Equal(t, []uint32{uint32(hdrLen + len(payload))}, sig[1000].int32s(), "size")
+	wantMD5 := md5.Sum(rpmBytes[hdrStart:])
+	assert.Equal(t, wantMD5[:], sig[1004].data, "md5")
+	assert.Equal(t, []string{fmt.Sprintf("%x", sha256.Sum256(hdrBytes))}, sig[273].strings(), "sha256")
+
+	// header
+	assert.Equal(t, int32(7), hdr[63].typ, "header region tag")
+	for tag, want := range map[int32]string{
+		1000: "foo",
+		1001: "1.0.0_2_gabcdef",
+		1002: "1",
+		1004: "Foo does things",
+		1005: "Foo does things",
+		1014: "ASL 2.0",
+		1021: "linux",
+		1022: "x86_64",
+		1124: "cpio",
+		1125: "gzip",
+	} {
+		assert.Equal(t, []string{want}, hdr[tag].strings(), "tag %d", tag)
+	}
+	assert.Equal(t, []string{"foo.yml", "foo", "README.md"}, hdr[1117].strings(), "basenames")
+	assert.Equal(t, []string{"/etc/foo/", "/usr/bin/", "/usr/share/doc/foo/"}, hdr[1118].strings(), "dirnames")
+	assert.Equal(t, []uint32{0, 1, 2}, hdr[1116].int32s(), "dirindexes")
+	assert.Equal(t, []uint16{0100640, 0100755, 0100644}, hdr[1030].int16s(), "filemodes")
+	assert.Equal(t, []uint32{1, 0, 0}, hdr[1037].int32s(), "fileflags")
+	assert.Equal(t, []uint32{11, 14, 6}, hdr[1028].int32s(), "filesizes")
+	assert.Equal(t, []uint32{31}, hdr[1009].int32s(), "size")
+	assert.Equal(t, []string{
+		fmt.Sprintf("%x", sha256.Sum256([]byte("key: value\n"))),
+		fmt.Sprintf("%x", sha256.Sum256([]byte("foo executable"))),
+		fmt.Sprintf("%x", sha256.Sum256([]byte("# foo\n"))),
+	}, hdr[1035].strings(), "filedigests")
+
+	// payload
+	gzipReader, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	cpioBytes, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{uint32(len(cpioBytes))}, sig[1007].int32s(), "payload size")
+	assert.Equal(t, map[string]string{
+		"./etc/foo/foo.yml":             "key: value\n",
+		"./usr/bin/foo":                 "foo executable",
+		"./usr/share/doc/foo/README.md": "# foo\n",
+	}, readCPIO(t, cpioBytes))
+}
+
+func TestRPMDistRequiresLinux(t *testing.T) {
+	dister := rpm.New("ASL 2.0", osarch.OSArch{OS: "windows", Arch: "amd64"})
+	_, err := dister.RunDist("rpm", distgo.ProductTaskOutputInfo{})
+	assert.EqualError(t, err, "rpm dist failed: RPMs can only be created for linux, but windows-amd64 was specified")
+}
+
+type tagData struct {
+	typ   int32
+	count int32
+	data  []byte
+}
+
+func (d tagData) strings() []string {
+	if d.data == nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(d.data), "\x00"), "\x00")[:d.count]
+}
+
+func (d tagData) int32s() []uint32 {
+	var out []uint32
+	for i := 0; i < int(d.count); i++ {
+		out = append(out, binary.BigEndian.Uint32(d.data[4*i:]))
+	}
+	return out
+}
+
+func (d tagData) int16s() []uint16 {
+	var out []uint16
+	for i := 0; i < int(d.count); i++ {
+		out = append(out, binary.BigEndian.Uint16(d.data[2*i:]))
+	}
+	return out
+}
+
+// parseHeader parses the header structure at the start of the provided bytes and returns the data for each tag and
+// the length of the header structure.
+func parseHeader(t *testing.T, b []byte) (map[int32]tagData, int) {
+	require.Equal(t, []byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0}, b[:8], "header magic")
+	numEntries := int(binary.BigEndian.Uint32(b[8:12]))
+	storeLen := int(binary.BigEndian.Uint32(b[12:16]))
+	storeStart := 16 + 16*numEntries
+	store := b[storeStart : storeStart+storeLen]
+
+	type indexEntry struct {
+		Tag, Type, Offset, Count int32
+	}
+	var entries []indexEntry
+	for i := 0; i < numEntries; i++ {
+		var entry indexEntry
+		require.NoError(t, binary.Read(bytes.NewReader(b[16+16*i:32+16*i]), binary.BigEndian, &entry))
+		entries = append(entries, entry)
+	}
+	// entries after the region tag must be sorted by tag
+	for i := 2; i < len(entries); i++ {
+		assert.True(t, entries[i-1].Tag < entries[i].Tag, "tags are not sorted: %d before %d", entries[i-1].Tag, entries[i].Tag)
+	}
+
+	tags := make(map[int32]tagData)
+	for i, entry := range entries {
+		end := storeLen
+		for _, other := range entries {
+			if other.Offset > entry.Offset && int(other.Offset) < end {
+				end = int(other.Offset)
+			}
+		}
+		data := store[entry.Offset:end]
+		switch entry.Type {
+		case 3:
+			data = data[:2*entry.Count]
+		case 4:
+			data = data[:4*entry.Count]
+		case 7:
+			data = data[:entry.Count]
+		}
+		if i == 0 {
+			// the region trailer points back to the start of the index
+			assert.Equal(t, int32(-16*numEntries), int32(binary.BigEndian.Uint32(data[8:12])), "region trailer offset")
+		}
+		tags[entry.Tag] = tagData{typ: entry.Type, count: entry.Count, data: data}
+	}
+	return tags, storeStart + storeLen
+}
+
+// readCPIO returns the names and contents of the regular files in the provided "newc" cpio archive.
+func readCPIO(t *testing.T, b []byte) map[string]string {
+	files := make(map[string]string)
+	offset := 0
+	align := func(n int) int {
+		return (n + 3) &^ 3
+	}
+	for {
+		header := string(b[offset : offset+110])
+		require.Equal(t, "070701", header[:6])
+		field := func(i int) int {
+			val, err := strconv.ParseUint(header[6+8*i:14+8*i], 16, 32)
+			require.NoError(t, err)
+			return int(val)
+		}
+		size, nameSize := field(6), field(11)
+		name := string(b[offset+110 : offset+110+nameSize-1])
+		if name == "TRAILER!!!" {
+			return files
+		}
+		dataStart := align(offset + 110 + nameSize)
+		files[name] = string(b[dataStart : dataStart+size])
+		offset = align(dataStart + size)
+	}
+}
+
+func writeFile(t *testing.T, filePath, content string, mode os.FileMode) {
+	require.NoError(t, os.MkdirAll(path.Dir(filePath), 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(content), mode))
+	require.NoError(t, os.Chmod(filePath, mode))
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Header tag data types.
+const (
+	typeInt16       = 3
+	typeInt32       = 4
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+// Region tags, which mark the tags of a header as immutable.
+const (
+	tagHeaderSignatures = 62
+	tagHeaderImmutable  = 63
+)
+
+// headerMagic is the magic number and version of a header structure followed by 4 reserved bytes.
+var headerMagic = []byte{0x8e, 0xad, 0xe8, 0x01, 0x00, 0x00, 0x00, 0x00}
+
+// header is a header structure of an RPM. The signature and the header sections of an RPM both use this structure.
+type header struct {
+	entries []headerEntry
+}
+
+type headerEntry struct {
+	tag   int32
+	typ   int32
+	count int32
+	data  []byte
+}
+
+func (h *header) addString(tag int32, val string) {
+	h.entries = append(h.entries, headerEntry{tag: tag, typ: typeString, count: 1, data: nulTerminated(val)})
+}
+
+func (h *header) addI18NString(tag int32, val string) {
+	h.entries = append(h.entries, headerEntry{tag: tag, typ: typeI18NString, count: 1, data: nulTerminated(val)})
+}
+
+func (h *header) addStringArray(tag int32, vals []string) {
+	var data []byte
+	for _, val := range vals {
+		data = append(data, nulTerminated(val)...)
+	}
+	h.entries = append(h.entries, headerEntry{tag: tag, typ: typeStringArray, count: int32(len(vals)), data: data})
+}
+
+func (h *header) addInt16(tag int32, vals []uint16) {
+	data := make([]byte, 2*len(vals))
+	for i, val := range vals {
+		binary.BigEndian.PutUint16(data[2*i:], val)
+	}
+	h.entries = append(h.entries, headerEntry{tag: tag, typ: typeInt16, count: int32(len(vals)), data: data})
+}
+
+func (h *header) addInt32(tag int32, vals []uint32) {
+	data := make([]byte, 4*len(vals))
+	for i, val := range vals {
+		binary.BigEndian.PutUint32(data[4*i:], val)
+	}
+	h.entries = append(h.entries, headerEntry{tag: tag, typ: typeInt32, count: int32(len(vals)), data: data})
+}
+
+func (h *header) addBin(tag int32, val []byte) {
+	h.entries = append(h.entries, headerEntry{tag: tag, typ: typeBin, count: int32(len(val)), data: val})
+}
+
+// bytes returns the serialized form of the header. The entries are written in tag order and preceded by an entry for
+// the provided region tag that marks all of the entries as belonging to the immutable region of the header.
+func (h *header) bytes(regionTag int32) []byte {
+	entries := make([]headerEntry, len(h.entries))
+	copy(entries, h.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].tag < entries[j].tag
+	})
+
+	var index, store bytes.Buffer
+	writeIndexEntry := func(tag, typ, offset, count int32) {
+		_ = binary.Write(&index, binary.BigEndian, []int32{tag, typ, offset, count})
+	}
+
+	numIndexEntries := int32(len(entries) + 1)
+	var entryIndex bytes.Buffer
+	for _, entry := range entries {
+		// pad the store so that numeric data is aligned to its size
+		if alignment := typeAlignment(entry.typ); alignment > 1 {
+			for store.Len()%alignment != 0 {
+				store.WriteByte(0)
+			}
+		}
+		_ = binary.Write(&entryIndex, binary.BigEndian, []int32{entry.tag, entry.typ, int32(store.Len()), entry.count})
+		store.Write(entry.data)
+	}
+
+	// the region trailer is stored at the end of the data and has a negative offset that specifies the size of the
+	// index of the region
+	trailerOffset := int32(store.Len())
+	_ = binary.Write(&store, binary.BigEndian, []int32{regionTag, typeBin, -numIndexEntries * 16, 16})
+	writeIndexEntry(regionTag, typeBin, trailerOffset, 16)
+	index.Write(entryIndex.Bytes())
+
+	var out bytes.Buffer
+	out.Write(headerMagic)
+	_ = binary.Write(&out, binary.BigEndian, []int32{numIndexEntries, int32(store.Len())})
+	out.Write(index.Bytes())
+	out.Write(store.Bytes())
+	return out.Bytes()
+}
+
+func typeAlignment(typ int32) int {
+	switch typ {
+	case typeInt16:
+		return 2
+	case typeInt32:
+		return 4
+	default:
+		return 1
+	}
+}
+
+func nulTerminated(val string) []byte {
+	return append([]byte(val), 0)
+}
@@ -0,0 +1,357 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpm
+
+import (
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tags of the signature section.
+const (
+	sigTagSHA1        = 269
+	sigTagSHA256      = 273
+	sigTagSize        = 1000
+	sigTagMD5         = 1004
+	sigTagPayloadSize = 1007
+)
+
+// Tags of the header section.
+const (
+	tagHeaderI18NTable   = 100
+	tagName              = 1000
+	tagVersion           = 1001
+	tagRelease           = 1002
+	tagSummary           = 1004
+	tagDescription       = 1005
+	tagBuildTime         = 1006
+	tagBuildHost         = 1007
+	tagSize              = 1009
+	tagLicense           = 1014
+	tagGroup             = 1016
+	tagOS                = 1021
+	tagArch              = 1022
+	tagFileSizes         = 1028
+	tagFileModes         = 1030
+	tagFileRDevs         = 1033
+	tagFileMTimes        = 1034
+	tagFileDigests       = 1035
+	tagFileLinkTos       = 1036
+	tagFileFlags         = 1037
+	tagFileUserName      = 1039
+	tagFileGroupName     = 1040
+	tagFileDevices       = 1095
+	tagFileINodes        = 1096
+	tagFileLangs         = 1097
+	tagDirIndexes        = 1116
+	tagBaseNames         = 1117
+	tagDirNames          = 1118
+	tagPayloadFormat     = 1124
+	tagPayloadCompressor = 1125
+	tagPayloadFlags      = 1126
+	tagFileDigestAlgo    = 5011
+)
+
+const (
+	// fileFlagConfig marks a file as a configuration file.
+	fileFlagConfig = 1 << 0
+	// fileModeRegular is the file type bits of the mode of a regular file.
+	fileModeRegular = 0100000
+	// digestAlgoSHA256 is the identifier of SHA-256 for the file digest algorithm tag.
+	digestAlgoSHA256 = 8
+)
+
+var leadMagic = []byte{0xed, 0xab, 0xee, 0xdb}
+
+// packageInfo is the information that describes an RPM.
+type packageInfo struct {
+	name        string
+	version     string
+	release     string
+	summary     string
+	description string
+	license     string
+	arch        string
+	buildTime   time.Time
+}
+
+// packageFile is a file that is installed by an RPM.
+type packageFile struct {
+	// path is the absolute install path of the file.
+	path string
+	// srcPath is the path to the content of the file.
+	srcPath string
+	size    int64
+	mode    os.FileMode
+	config  bool
+}
+
+// writeRPM writes a binary RPM that installs the provided files to dstPath. The payload is a gzip-compressed cpio
+// archive. The temporary payload file is written to workDir.
+func writeRPM(dstPath, workDir string, info packageInfo, files []packageFile) (rErr error) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+
+	payloadFile, err := ioutil.TempFile(workDir, "payload-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create payload file")
+	}
+	defer func() {
+		_ = payloadFile.Close()
+		_ = os.Remove(payloadFile.Name())
+	}()
+	uncompressedSize, err := writePayload(payloadFile, files, info.buildTime.Unix())
+	if err != nil {
+		return err
+	}
+	payloadSize, err := payloadFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.Wrapf(err, "failed to determine payload size")
+	}
+
+	hdr, err := newHeader(info, files)
+	if err != nil {
+		return err
+	}
+	hdrBytes := hdr.bytes(tagHeaderImmutable)
+
+	// the MD5 digest in the signature covers both the header and the payload
+	if _, err := payloadFile.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "failed to read payload file")
+	}
+	md5Hash := md5.New()
+	md5Hash.Write(hdrBytes)
+	if _, err := io.Copy(md5Hash, payloadFile); err != nil {
+		return errors.Wrapf(err, "failed to compute digest of payload")
+	}
+	sig := &header{}
+	sig.addString(sigTagSHA1, fmt.Sprintf("%x", sha1.Sum(hdrBytes)))
+	sig.addString(sigTagSHA256, fmt.Sprintf("%x", sha256.Sum256(hdrBytes)))
+	sig.addInt32(sigTagSize, []uint32{uint32(int64(len(hdrBytes)) + payloadSize)})
+	sig.addBin(sigTagMD5, md5Hash.Sum(nil))
+	sig.addInt32(sigTagPayloadSize, []uint32{uint32(uncompressedSize)})
+	sigBytes := sig.bytes(tagHeaderSignatures)
+	// the signature is padded to a multiple of 8 bytes
+	if rem := len(sigBytes) % 8; rem != 0 {
+		sigBytes = append(sigBytes, make([]byte, 8-rem)...)
+	}
+
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", dstPath)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close %s", dstPath)
+		}
+	}()
+	for _, b := range [][]byte{lead(info), sigBytes, hdrBytes} {
+		if _, err := f.Write(b); err != nil {
+			return errors.Wrapf(err, "failed to write %s", dstPath)
+		}
+	}
+	if _, err := payloadFile.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "failed to read payload file")
+	}
+	if _, err := io.Copy(f, payloadFile); err != nil {
+		return errors.Wrapf(err, "failed to write payload to %s", dstPath)
+	}
+	return nil
+}
+
+// writePayload writes the gzip-compressed cpio archive of the provided files to w and returns the uncompressed size
+// of the archive.
+func writePayload(w io.Writer, files []packageFile, mtime int64) (int64, error) {
+	gzipWriter, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to create gzip writer")
+	}
+	cpio := &cpioWriter{w: gzipWriter}
+	for i, f := range files {
+		if err := cpio.writeFile(f, i+1, mtime); err != nil {
+			return 0, err
+		}
+	}
+	if err := cpio.close(); err != nil {
+		return 0, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return 0, errors.Wrapf(err, "failed to close gzip writer")
+	}
+	return cpio.written, nil
+}
+
+// newHeader returns the header section for the provided package information and files.
+func newHeader(info packageInfo, files []packageFile) (*header, error) {
+	var (
+		totalSize   int64
+		sizes       []uint32
+		modes       []uint16
+		rdevs       []uint16
+		mtimes      []uint32
+		digests     []string
+		linkTos     []string
+		flags       []uint32
+		userNames   []string
+		groupNames  []string
+		devices     []uint32
+		inodes      []uint32
+		langs       []string
+		dirIndexes  []uint32
+		baseNames   []string
+		dirNames    []string
+		dirNameIdxs = make(map[string]int)
+	)
+	for i, f := range files {
+		digest, err := fileSHA256(f.srcPath)
+		if err != nil {
+			return nil, err
+		}
+		totalSize += f.size
+		sizes = append(sizes, uint32(f.size))
+		modes = append(modes, uint16(fileModeRegular|uint32(f.mode.Perm())))
+		rdevs = append(rdevs, 0)
+		mtimes = append(mtimes, uint32(info.buildTime.Unix()))
+		digests = append(digests, digest)
+		linkTos = append(linkTos, "")
+		var fileFlags uint32
+		if f.config {
+			fileFlags |= fileFlagConfig
+		}
+		flags = append(flags, fileFlags)
+		userNames = append(userNames, "root")
+		groupNames = append(groupNames, "root")
+		devices = append(devices, 1)
+		inodes = append(inodes, uint32(i+1))
+		langs = append(langs, "")
+
+		dir := path.Dir(f.path)
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+		idx, ok := dirNameIdxs[dir]
+		if !ok {
+			idx = len(dirNames)
+			dirNameIdxs[dir] = idx
+			dirNames = append(dirNames, dir)
+		}
+		dirIndexes = append(dirIndexes, uint32(idx))
+		baseNames = append(baseNames, path.Base(f.path))
+	}
+
+	h := &header{}
+	h.addStringArray(tagHeaderI18NTable, []string{"C"})
+	h.addString(tagName, info.name)
+	h.addString(tagVersion, info.version)
+	h.addString(tagRelease, info.release)
+	h.addI18NString(tagSummary, info.summary)
+	h.addI18NString(tagDescription, info.description)
+	h.addInt32(tagBuildTime, []uint32{uint32(info.buildTime.Unix())})
+	h.addString(tagBuildHost, "localhost")
+	h.addInt32(tagSize, []uint32{uint32(totalSize)})
+	h.addString(tagLicense, info.license)
+	h.addI18NString(tagGroup, "Unspecified")
+	h.addString(tagOS, "linux")
+	h.addString(tagArch, info.arch)
+	h.addString(tagPayloadFormat, "cpio")
+	h.addString(tagPayloadCompressor, "gzip")
+	h.addString(tagPayloadFlags, "9")
+	if len(files) > 0 {
+		h.addInt32(tagFileSizes, sizes)
+		h.addInt16(tagFileModes, modes)
+		h.addInt16(tagFileRDevs, rdevs)
+		h.addInt32(tagFileMTimes, mtimes)
+		h.addStringArray(tagFileDigests, digests)
+		h.addStringArray(tagFileLinkTos, linkTos)
+		h.addInt32(tagFileFlags, flags)
+		h.addStringArray(tagFileUserName, userNames)
+		h.addStringArray(tagFileGroupName, groupNames)
+		h.addInt32(tagFileDevices, devices)
+		h.addInt32(tagFileINodes, inodes)
+		h.addStringArray(tagFileLangs, langs)
+		h.addInt32(tagDirIndexes, dirIndexes)
+		h.addStringArray(tagBaseNames, baseNames)
+		h.addStringArray(tagDirNames, dirNames)
+		h.addInt32(tagFileDigestAlgo, []uint32{digestAlgoSHA256})
+	}
+	return h, nil
+}
+
+// lead returns the lead section of the RPM, which identifies the file as an RPM.
+func lead(info packageInfo) []byte {
+	out := make([]byte, 96)
+	copy(out, leadMagic)
+	// format version 3.0
+	out[4] = 3
+	out[5] = 0
+	// type: binary package
+	binary.BigEndian.PutUint16(out[6:], 0)
+	binary.BigEndian.PutUint16(out[8:], leadArchNum(info.arch))
+	// name is a NUL-terminated string of at most 65 characters
+	name := fmt.Sprintf("%s-%s-%s", info.name, info.version, info.release)
+	if len(name) > 65 {
+		name = name[:65]
+	}
+	copy(out[10:76], name)
+	// OS: linux
+	binary.BigEndian.PutUint16(out[76:], 1)
+	// signature type: header-style signature
+	binary.BigEndian.PutUint16(out[78:], 5)
+	return out
+}
+
+func leadArchNum(arch string) uint16 {
+	switch arch {
+	case "x86_64", "i386", "i686":
+		return 1
+	case "ppc64le":
+		return 16
+	case "s390x":
+		return 15
+	case "aarch64":
+		return 19
+	default:
+		return 0
+	}
+}
+
+func fileSHA256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", filePath)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "failed to compute digest of %s", filePath)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/palantir/distgo/dister/selfextracting"
+	v0 "github.com/palantir/distgo/dister/selfextracting/config/internal/v0"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+type SelfExtracting v0.Config
+
+func (cfg *SelfExtracting) ToDister() (distgo.Dister, error) {
+	osArchs := cfg.OSArchs
+	if len(osArchs) == 0 {
+		osArchs = []osarch.OSArch{osarch.Current()}
+	}
+	for _, osArch := range osArchs {
+		if osArch.OS == "windows" {
+			return nil, errors.Errorf("os-archs for self-extracting dister cannot have an OS of windows, but contained %s", osArch)
+		}
+	}
+	return &selfextracting.Dister{
+		OSArchs: osArchs,
+		Exec:    cfg.Exec,
+	}, nil
+}
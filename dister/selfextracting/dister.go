@@ -0,0 +1,354 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selfextracting
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/palantir/distgo/dister/internal/buildartifact"
+	"github.com/palantir/distgo/dister/internal/tgz"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+const TypeName = "self-extracting" // distribution that consists of the binaries for a specific OS/Architecture packaged as a self-extracting shell installer
+
+// stubTemplate is the template for the shell script at the beginning of an installer. The TGZ archive of the
+// distribution is appended to the rendered script starting at line ArchiveLine.
+const stubTemplate = `#!/bin/sh
+# Self-extracting installer for {{.Product}} {{.Version}} ({{.OSArch}}).
+#
+# Usage: sh {{.ArtifactName}} [--target DIR] [--extract-only] [--] [ARGS...]
+#
+# Verifies the SHA-256 checksum of the embedded archive and extracts it into DIR (defaults to
+# "{{.DefaultTargetDir}}" in the working directory).{{if .Exec}} After extracting, runs {{.Executable}} with ARGS
+# unless --extract-only is specified.{{end}}
+set -eu
+
+ARCHIVE_LINE={{.ArchiveLine}}
+ARCHIVE_SHA256={{.ArchiveSHA256}}
+TARGET_DIR={{shellQuote .DefaultTargetDir}}
+EXTRACT_ONLY=false
+
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --target)
+      if [ $# -lt 2 ]; then
+        echo "--target requires an argument" >&2
+        exit 1
+      fi
+      TARGET_DIR=$2
+      shift 2
+      ;;
+    --extract-only)
+      EXTRACT_ONLY=true
+      shift
+      ;;
+    --)
+      shift
+      break
+      ;;
+    *)
+      break
+      ;;
+  esac
+done
+
+ARCHIVE_FILE=$(mktemp)
+trap 'rm -f "$ARCHIVE_FILE"' EXIT
+tail -n +"$ARCHIVE_LINE" "$0" > "$ARCHIVE_FILE"
+
+if command -v sha256sum > /dev/null 2>&1; then
+  ACTUAL_SHA256=$(sha256sum "$ARCHIVE_FILE" | cut -d ' ' -f 1)
+elif command -v shasum > /dev/null 2>&1; then
+  ACTUAL_SHA256=$(shasum -a 256 "$ARCHIVE_FILE" | cut -d ' ' -f 1)
+else
+  echo "cannot verify installer: neither sha256sum nor shasum is available" >&2
+  exit 1
+fi
+if [ "$ACTUAL_SHA256" != "$ARCHIVE_SHA256" ]; then
+  echo "installer is corrupt: expected SHA-256 checksum $ARCHIVE_SHA256 for the embedded archive, was $ACTUAL_SHA256" >&2
+  exit 1
+fi
+
+mkdir -p "$TARGET_DIR"
+tar -xzf "$ARCHIVE_FILE" -C "$TARGET_DIR"
+{{- if .Exec}}
+
+if [ "$EXTRACT_ONLY" = false ]; then
+  # the EXIT trap does not run when the shell is replaced using exec
+  rm -f "$ARCHIVE_FILE"
+  exec "$TARGET_DIR"/{{shellQuote .Executable}} "$@"
+fi
+{{- end}}
+exit 0
+`
+
+type Dister struct {
+	OSArchs []osarch.OSArch
+
+	// Exec specifies that the installer should run the executable of the product (with the arguments provided to the
+	// installer) after extracting the distribution.
+	Exec bool
+}
+
+func New(osArchs ...osarch.OSArch) distgo.Dister {
+	return &Dister{
+		OSArchs: osArchs,
+	}
+}
+
+func (d *Dister) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+func (d *Dister) Artifacts(renderedName string) ([]string, error) {
+	var outPaths []string
+	for _, osArch := range d.OSArchs {
+		outPaths = append(outPaths, fmt.Sprintf("%s-%s.run", renderedName, osArch.String()))
+	}
+	return outPaths, nil
+}
+
+func (d *Dister) PackagingExtension() (string, error) {
+	return "run", nil
+}
+
+func (d *Dister) ArtifactOSArchs(renderedName string) (map[string]osarch.OSArch, error) {
+	artifactOSArchs := make(map[string]osarch.OSArch)
+	for _, osArch := range d.OSArchs {
+		artifactOSArchs[fmt.Sprintf("%s-%s.run", renderedName, osArch.String())] = osArch
+	}
+	return artifactOSArchs, nil
+}
+
+func (d *Dister) osArchFromArtifactPath(distID distgo.DistID, artifactPath string, productTaskOutputInfo distgo.ProductTaskOutputInfo) (osarch.OSArch, error) {
+	for _, osArch := range d.OSArchs {
+		if strings.HasSuffix(artifactPath, fmt.Sprintf("%s-%s.run", productTaskOutputInfo.Product.DistOutputInfos.DistInfos[distID].DistNameTemplateRendered, osArch.String())) {
+			return osArch, nil
+		}
+	}
+	return osarch.OSArch{}, errors.Errorf("failed to determine OS/Arch for artifact with Path %s", artifactPath)
+}
+
+// RunDist copies the executables for each OS/architecture of the dister into "{{OSArch}}" in the dist work directory.
+// The directory for an OS/architecture is extracted by the installer, so the dist script can add files to the
+// installer by writing them to this directory.
+func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]byte, error) {
+	for _, osArch := range d.OSArchs {
+		if osArch.OS == "windows" {
+			return nil, errors.Errorf("self-extracting dist failed: self-extracting installers are shell scripts and cannot be created for windows, but %s was specified", osArch)
+		}
+		if err := buildartifact.VerifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
+			return nil, err
+		}
+	}
+	if d.Exec && productTaskOutputInfo.Product.BuildOutputInfo != nil && productTaskOutputInfo.Product.BuildOutputInfo.NoArtifacts {
+		return nil, errors.Errorf("self-extracting dist failed: exec is true, but product %s does not have an executable because it does not specify a main package", productTaskOutputInfo.Product.ID)
+	}
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	outputPathsForOSArchs := make(map[string][]string)
+	for _, osArch := range d.OSArchs {
+		osArchWorkDir := path.Join(distWorkDir, osArch.String())
+		// copy executables for product and its dependencies
+		dsts, err := buildartifact.CopyAllForOSArch(osArchWorkDir, distID, productTaskOutputInfo, osArch)
+		if err != nil {
+			return nil, err
+		}
+		outputPathsForOSArchs[osArch.String()] = dsts
+	}
+	jsonBytes, err := json.Marshal(outputPathsForOSArchs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal outputPathsForOSArchs as JSON")
+	}
+	return jsonBytes, nil
+}
+
+// GenerateDistArtifacts creates an installer for each OS/architecture. The installer is a shell script followed by a
+// TGZ archive of the contents of the work directory for the OS/architecture. When the installer is run, the script
+// verifies the SHA-256 checksum of the archive (which is embedded in the script) before extracting it and, if Exec is
+// true, runs the executable of the product.
+func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, runDistResult []byte) error {
+	modTime, err := tgz.ModTime(productTaskOutputInfo.Project)
+	if err != nil {
+		return err
+	}
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return err
+		}
+		osArchWorkDir := path.Join(distWorkDir, currOSArch.String())
+		archivePath := path.Join(distWorkDir, currOSArch.String()+".tgz")
+		if err := archiveDirContents(osArchWorkDir, archivePath, productTaskOutputInfo.ProductDistExecutableMode(distID), modTime); err != nil {
+			return errors.Wrapf(err, "failed to create TGZ archive")
+		}
+		archiveSHA256, err := sha256Checksum(archivePath)
+		if err != nil {
+			return err
+		}
+		stub, err := renderStub(stubParams{
+			Product:          string(productTaskOutputInfo.Product.ID),
+			Version:          productTaskOutputInfo.Project.Version,
+			OSArch:           currOSArch.String(),
+			ArtifactName:     path.Base(artifactPath),
+			DefaultTargetDir: productTaskOutputInfo.Product.DistOutputInfos.DistInfos[distID].DistNameTemplateRendered,
+			ArchiveSHA256:    archiveSHA256,
+			Exec:             d.Exec,
+			Executable:       productTaskOutputInfo.Product.BuildOutputInfo.ArtifactName(currOSArch),
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeInstaller(artifactPath, stub, archivePath); err != nil {
+			return errors.Wrapf(err, "failed to create self-extracting installer")
+		}
+	}
+	return nil
+}
+
+// ArtifactContents returns the contents of each artifact, which contains the contents of the directory for its
+// OS/architecture in the dist work directory.
+func (d *Dister) ArtifactContents(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (map[string]distgo.ArtifactContents, error) {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	contents := make(map[string]distgo.ArtifactContents)
+	for _, artifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[distID] {
+		currOSArch, err := d.osArchFromArtifactPath(distID, artifactPath, productTaskOutputInfo)
+		if err != nil {
+			return nil, err
+		}
+		contents[artifactPath] = distgo.ArtifactContents{
+			Dir: path.Join(distWorkDir, currOSArch.String()),
+		}
+	}
+	return contents, nil
+}
+
+type stubParams struct {
+	Product          string
+	Version          string
+	OSArch           string
+	ArtifactName     string
+	DefaultTargetDir string
+	ArchiveSHA256    string
+	ArchiveLine      int
+	Exec             bool
+	Executable       string
+}
+
+// renderStub renders the script of the installer for the provided parameters. The line at which the archive starts
+// is set to the line after the last line of the script.
+func renderStub(params stubParams) (string, error) {
+	t, err := template.New("stub").Funcs(template.FuncMap{
+		"shellQuote": shellQuote,
+	}).Parse(stubTemplate)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse installer template")
+	}
+
+	// the number of lines of the script does not depend on the value of ArchiveLine, so the script is rendered once to
+	// count its lines and then rendered again with the correct value
+	var stub string
+	for i := 0; i < 2; i++ {
+		buf := &bytes.Buffer{}
+		if err := t.Execute(buf, params); err != nil {
+			return "", errors.Wrapf(err, "failed to render installer template")
+		}
+		stub = buf.String()
+		params.ArchiveLine = strings.Count(stub, "\n") + 1
+	}
+	return stub, nil
+}
+
+// shellQuote returns the provided value quoted so that it is interpreted literally by the shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// writeInstaller writes an executable file at dstPath that consists of the provided script followed by the content of
+// the archive.
+func writeInstaller(dstPath, stub, archivePath string) (rErr error) {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open archive")
+	}
+	defer func() {
+		_ = archive.Close()
+	}()
+
+	f, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create installer")
+	}
+	defer func() {
+		if err := f.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close installer")
+		}
+	}()
+	if _, err := io.WriteString(f, stub); err != nil {
+		return errors.Wrapf(err, "failed to write installer script")
+	}
+	if _, err := io.Copy(f, archive); err != nil {
+		return errors.Wrapf(err, "failed to write archive to installer")
+	}
+	return nil
+}
+
+// archiveDirContents writes a TGZ archive of the contents of the provided directory. Executable files are written with
+// the provided mode and all entries have the provided modification time.
+func archiveDirContents(dir, dstPath string, executableMode os.FileMode, modTime time.Time) error {
+	items, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list items in %s", dir)
+	}
+	itemPaths := make([]string, len(items))
+	for i, item := range items {
+		itemPaths[i] = filepath.Join(dir, item.Name())
+	}
+	return tgz.Archive(itemPaths, dstPath, tgz.Options{
+		CompressionLevel: gzip.DefaultCompression,
+		ExecutableMode:   executableMode,
+		ModTime:          modTime,
+	})
+}
+
+func sha256Checksum(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open archive")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "failed to compute checksum of archive")
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
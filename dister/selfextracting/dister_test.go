@@ -0,0 +1,199 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selfextracting_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/selfextracting"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExecutable = `#!/bin/sh
+echo "foo ran with args: $*"
+`
+
+func TestSelfExtractingDist(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	installerPath := createInstaller(t, tmp, "foo", &selfextracting.Dister{
+		Exec: true,
+	})
+
+	// installer extracts into the target directory and runs the executable with the provided arguments
+	targetDir := path.Join(tmp, "install")
+	output, err := runInstaller(installerPath, tmp, "--target", targetDir, "--", "bar", "baz")
+	require.NoError(t, err, "Output: %s", output)
+	assert.Equal(t, "foo ran with args: bar baz\n", output)
+	assertExtracted(t, targetDir, "foo")
+
+	// installer extracts into a directory with the name of the dist in the working directory by default and does not
+	// run the executable if --extract-only is specified
+	workDir := path.Join(tmp, "work")
+	require.NoError(t, os.Mkdir(workDir, 0755))
+	output, err = runInstaller(installerPath, workDir, "--extract-only")
+	require.NoError(t, err, "Output: %s", output)
+	assert.Equal(t, "", output)
+	assertExtracted(t, path.Join(workDir, "foo-1.0.0"), "foo")
+}
+
+func TestSelfExtractingDistNoExec(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	installerPath := createInstaller(t, tmp, "foo", &selfextracting.Dister{})
+
+	targetDir := path.Join(tmp, "install")
+	output, err := runInstaller(installerPath, tmp, "--target", targetDir, "bar")
+	require.NoError(t, err, "Output: %s", output)
+	assert.Equal(t, "", output)
+	assertExtracted(t, targetDir, "foo")
+}
+
+func TestSelfExtractingDistQuotesExecutable(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// shell metacharacters in the name of the executable are interpreted literally
+	const executableName = `foo $(touch pwned) 'bar'`
+	installerPath := createInstaller(t, tmp, executableName, &selfextracting.Dister{
+		Exec: true,
+	})
+
+	targetDir := path.Join(tmp, "install")
+	output, err := runInstaller(installerPath, tmp, "--target", targetDir, "--", "bar")
+	require.NoError(t, err, "Output: %s", output)
+	assert.Equal(t, "foo ran with args: bar\n", output)
+	assertExtracted(t, targetDir, executableName)
+	_, err = os.Stat(path.Join(tmp, "pwned"))
+	assert.True(t, os.IsNotExist(err), "installer ran command in the name of the executable")
+}
+
+func TestSelfExtractingDistVerifiesChecksum(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	installerPath := createInstaller(t, tmp, "foo", &selfextracting.Dister{
+		Exec: true,
+	})
+
+	// corrupt the last byte of the embedded archive
+	installerBytes, err := ioutil.ReadFile(installerPath)
+	require.NoError(t, err)
+	installerBytes[len(installerBytes)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(installerPath, installerBytes, 0755))
+
+	targetDir := path.Join(tmp, "install")
+	output, err := runInstaller(installerPath, tmp, "--target", targetDir)
+	require.Error(t, err)
+	assert.Contains(t, output, "installer is corrupt: expected SHA-256 checksum")
+	_, err = os.Stat(targetDir)
+	assert.True(t, os.IsNotExist(err), "installer with invalid checksum created target directory")
+}
+
+func TestSelfExtractingDistWindowsNotSupported(t *testing.T) {
+	windowsAMD64 := osarch.OSArch{OS: "windows", Arch: "amd64"}
+	dister := selfextracting.New(windowsAMD64)
+	_, err := dister.RunDist("self-extracting", distgo.ProductTaskOutputInfo{})
+	assert.EqualError(t, err, "self-extracting dist failed: self-extracting installers are shell scripts and cannot be created for windows, but windows-amd64 was specified")
+}
+
+// createInstaller creates the installer for a product "foo" whose executable is a shell script with the provided name
+// using the provided dister and returns its path.
+func createInstaller(t *testing.T, projectDir, executableName string, dister *selfextracting.Dister) string {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	dister.OSArchs = []osarch.OSArch{linuxAMD64}
+
+	const distID = distgo.DistID("self-extracting")
+	artifactNames, err := dister.Artifacts("foo-1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo-1.0.0-linux-amd64.run"}, artifactNames)
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: projectDir,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			BuildOutputInfo: &distgo.BuildOutputInfo{
+				BuildNameTemplateRendered: executableName,
+				BuildOutputDir:            "out/build",
+				OSArchs:                   []osarch.OSArch{linuxAMD64},
+			},
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{distID},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					distID: {
+						DistNameTemplateRendered: "foo-1.0.0",
+						DistArtifactNames:        artifactNames,
+						PackagingExtension:       "run",
+					},
+				},
+			},
+		},
+	}
+	executablePath := path.Join(projectDir, "out", "build", "foo", "1.0.0", "linux-amd64", executableName)
+	require.NoError(t, os.MkdirAll(path.Dir(executablePath), 0755))
+	require.NoError(t, ioutil.WriteFile(executablePath, []byte(testExecutable), 0755))
+	require.NoError(t, os.MkdirAll(productTaskOutputInfo.ProductDistWorkDirs()[distID], 0755))
+
+	runDistResult, err := dister.RunDist(distID, productTaskOutputInfo)
+	require.NoError(t, err)
+	err = dister.GenerateDistArtifacts(distID, productTaskOutputInfo, runDistResult)
+	require.NoError(t, err)
+
+	installerPath := path.Join(projectDir, "out", "dist", "foo", "1.0.0", "self-extracting", "foo-1.0.0-linux-amd64.run")
+	fi, err := os.Stat(installerPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	return installerPath
+}
+
+// runInstaller runs the installer at the provided path in the provided working directory with the provided arguments
+// and returns its combined output.
+func runInstaller(installerPath, workDir string, args ...string) (string, error) {
+	cmd := exec.Command("sh", append([]string{installerPath}, args...)...)
+	cmd.Dir = workDir
+	output := &bytes.Buffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err := cmd.Run()
+	return output.String(), err
+}
+
+func assertExtracted(t *testing.T, targetDir, executableName string) {
+	content, err := ioutil.ReadFile(path.Join(targetDir, executableName))
+	require.NoError(t, err)
+	assert.Equal(t, testExecutable, string(content))
+	fi, err := os.Stat(path.Join(targetDir, executableName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+}
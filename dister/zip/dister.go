@@ -0,0 +1,96 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/palantir/distgo/dister/internal/buildartifact"
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+const TypeName = "zip" // distribution that consists of the binaries in a "bin" directory packaged as a ZIP archive
+
+type Dister struct{}
+
+func New() distgo.Dister {
+	return &Dister{}
+}
+
+func (d *Dister) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+func (d *Dister) Artifacts(renderedName string) ([]string, error) {
+	return []string{fmt.Sprintf("%s.zip", renderedName)}, nil
+}
+
+func (d *Dister) PackagingExtension() (string, error) {
+	return "zip", nil
+}
+
+// RunDist creates the same layout as the "bin" dister: the executables for all of the OS/architectures of the product
+// (and its dependencies) are copied into "bin/{{OSArch}}" in the dist work directory.
+func (d *Dister) RunDist(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]byte, error) {
+	if productTaskOutputInfo.Product.BuildOutputInfo == nil {
+		return nil, errors.Errorf("zip dist failed: no build outputs for product %s", productTaskOutputInfo.Product.ID)
+	}
+
+	for _, osArch := range productTaskOutputInfo.Product.BuildOutputInfo.OSArchs {
+		if err := buildartifact.VerifyDistTargetSupported(osArch, productTaskOutputInfo); err != nil {
+			return nil, err
+		}
+	}
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	distWorkDirBinDir := path.Join(distWorkDir, "bin")
+	if err := os.Mkdir(distWorkDirBinDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create bin directory")
+	}
+
+	for _, osArch := range productTaskOutputInfo.Product.BuildOutputInfo.OSArchs {
+		// copy executables for product and its dependencies
+		if _, err := buildartifact.CopyAllForOSArch(path.Join(distWorkDirBinDir, osArch.String()), distID, productTaskOutputInfo, osArch); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// GenerateDistArtifacts creates a ZIP archive of the dist work directory. The permissions of the files in the work
+// directory (including the executable bits) are recorded in the external attributes of the entries of the archive.
+func (d *Dister) GenerateDistArtifacts(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, runDistResult []byte) error {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	dstPath := productTaskOutputInfo.ProductDistArtifactPaths()[distID][0]
+	if err := archiver.DefaultZip.Archive([]string{distWorkDir}, dstPath); err != nil {
+		return errors.Wrapf(err, "failed to create ZIP archive")
+	}
+	return nil
+}
+
+// ArtifactContents returns the contents of the artifact, which contains the dist work directory as its top-level
+// directory.
+func (d *Dister) ArtifactContents(distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (map[string]distgo.ArtifactContents, error) {
+	distWorkDir := productTaskOutputInfo.ProductDistWorkDirs()[distID]
+	return map[string]distgo.ArtifactContents{
+		productTaskOutputInfo.ProductDistArtifactPaths()[distID][0]: {
+			Dir:        distWorkDir,
+			PathPrefix: path.Base(distWorkDir) + "/",
+		},
+	}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/distgo/dister/distertester"
+	"github.com/palantir/godel/v2/framework/pluginapitester"
+	"github.com/palantir/godel/v2/pkg/products"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipDist(t *testing.T) {
+	const godelYML = `exclude:
+  names:
+    - "\\..+"
+    - "vendor"
+  paths:
+    - "godel"
+`
+
+	pluginPath, err := products.Bin("dist-plugin")
+	require.NoError(t, err)
+
+	distertester.RunAssetDistTest(t,
+		pluginapitester.NewPluginProvider(pluginPath),
+		nil,
+		[]distertester.TestCase{
+			{
+				Name: "zip creates expected output",
+				Specs: []gofiles.GoFileSpec{
+					{
+						RelPath: "go.mod",
+						Src:     `module foo`,
+					},
+					{
+						RelPath: "foo/foo.go",
+						Src:     `package main; func main() {}`,
+					},
+				},
+				ConfigFiles: map[string]string{
+					"godel/config/godel.yml": godelYML,
+					"godel/config/dist-plugin.yml": `
+products:
+  foo:
+    build:
+      main-pkg: ./foo
+      os-archs:
+        - os: linux
+          arch: amd64
+        - os: windows
+          arch: amd64
+    dist:
+      disters:
+        type: zip
+        script: |
+                #!/usr/bin/env bash
+                echo "hello" > $DIST_WORK_DIR/foo.txt
+`,
+				},
+				WantOutput: func(projectDir string) string {
+					return `Creating distribution for foo at out/dist/foo/1.0.0/zip/foo-1.0.0.zip
+Finished creating zip distribution for foo
+`
+				},
+				Validate: func(projectDir string) {
+					zipReader, err := zip.OpenReader(path.Join(projectDir, "out", "dist", "foo", "1.0.0", "zip", "foo-1.0.0.zip"))
+					require.NoError(t, err)
+					defer func() {
+						_ = zipReader.Close()
+					}()
+
+					files := make(map[string]*zip.File)
+					var names []string
+					for _, f := range zipReader.File {
+						files[f.Name] = f
+						names = append(names, f.Name)
+					}
+					sort.Strings(names)
+					assert.Equal(t, []string{
+						"foo-1.0.0/",
+						"foo-1.0.0/bin/",
+						"foo-1.0.0/bin/linux-amd64/",
+						"foo-1.0.0/bin/linux-amd64/foo",
+						"foo-1.0.0/bin/windows-amd64/",
+						"foo-1.0.0/bin/windows-amd64/foo.exe",
+						"foo-1.0.0/foo.txt",
+					}, names)
+
+					// executables are expanded with their executable bits and match the build outputs
+					for entryName, buildOutputPath := range map[string]string{
+						"foo-1.0.0/bin/linux-amd64/foo":       path.Join(projectDir, "out", "build", "foo", "1.0.0", "linux-amd64", "foo"),
+						"foo-1.0.0/bin/windows-amd64/foo.exe": path.Join(projectDir, "out", "build", "foo", "1.0.0", "windows-amd64", "foo.exe"),
+					} {
+						f := files[entryName]
+						require.NotNil(t, f, "missing entry %s", entryName)
+						assert.Equal(t, "-rwxr-xr-x", f.Mode().String(), "unexpected mode for %s", entryName)
+						wantContent, err := ioutil.ReadFile(buildOutputPath)
+						require.NoError(t, err)
+						assert.Equal(t, wantContent, readZipFile(t, f), "unexpected content for %s", entryName)
+					}
+
+					f := files["foo-1.0.0/foo.txt"]
+					require.NotNil(t, f)
+					assert.Equal(t, "-rw-r--r--", f.Mode().String())
+					assert.Equal(t, "hello\n", string(readZipFile(t, f)))
+				},
+			},
+			{
+				Name: "zip does not panic if build output does not exist",
+				ConfigFiles: map[string]string{
+					"godel/config/godel.yml": godelYML,
+					"godel/config/dist-plugin.yml": `
+products:
+  foo:
+    dist:
+      disters:
+        type: zip
+`,
+				},
+				WantError: true,
+				WantOutput: func(projectDir string) string {
+					return `Creating distribution for foo at out/dist/foo/1.0.0/zip/foo-1.0.0.zip
+Error: dist failed for foo: zip dist failed: no build outputs for product foo
+`
+				},
+			},
+		},
+	)
+}
+
+func readZipFile(t *testing.T, f *zip.File) []byte {
+	rc, err := f.Open()
+	require.NoError(t, err)
+	defer func() {
+		_ = rc.Close()
+	}()
+	content, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	return content
+}
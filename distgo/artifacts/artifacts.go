@@ -15,6 +15,7 @@
 package artifacts
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -114,6 +115,119 @@ func Dist(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam) (
 	return outputPaths, nil
 }
 
+// ArtifactType is the type of an artifact returned by All.
+type ArtifactType string
+
+const (
+	BuildArtifactType ArtifactType = "build"
+	DistArtifactType  ArtifactType = "dist"
+)
+
+// Artifact is a build or dist output of a product.
+type Artifact struct {
+	ProductID distgo.ProductID `json:"productId"`
+	Type      ArtifactType     `json:"type"`
+	// OSArch is the OS/architecture of a build artifact. Empty for dist artifacts.
+	OSArch string `json:"osArch,omitempty"`
+	// DistID is the DistID of a dist artifact. Empty for build artifacts.
+	DistID distgo.DistID `json:"distId,omitempty"`
+	// Path is the absolute path to the artifact.
+	Path string `json:"path"`
+}
+
+// PrintAllArtifacts prints the absolute paths of all of the build and dist artifacts of the specified products. If
+// jsonOutput is true, the artifacts are printed as a JSON array of Artifact objects. Otherwise, the paths are printed
+// one per line. No builds or dists are run, so the printed artifacts may not exist.
+func PrintAllArtifacts(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productIDs []distgo.ProductID, jsonOutput bool, stdout io.Writer) error {
+	productParams, err := distgo.ProductParamsForProductArgs(projectParam.Products, productIDs...)
+	if err != nil {
+		return err
+	}
+	allArtifacts, err := All(projectInfo, productParams)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		// print an empty array rather than "null" if there are no artifacts
+		if allArtifacts == nil {
+			allArtifacts = []Artifact{}
+		}
+		jsonBytes, err := json.MarshalIndent(allArtifacts, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal artifacts as JSON")
+		}
+		_, _ = fmt.Fprintln(stdout, string(jsonBytes))
+		return nil
+	}
+	for _, artifact := range allArtifacts {
+		_, _ = fmt.Fprintln(stdout, artifact.Path)
+	}
+	return nil
+}
+
+// All returns all of the build and dist artifacts of the provided products. The artifacts are derived from the
+// configuration of the products (the build and dists are not run). The artifacts are sorted by product ID, then with
+// build artifacts (sorted by OS/architecture) before dist artifacts (sorted by DistID and path). All of the paths are
+// absolute.
+func All(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam) ([]Artifact, error) {
+	absProjectDir, err := filepath.Abs(projectInfo.ProjectDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine absolute path of project directory")
+	}
+	projectInfo.ProjectDir = absProjectDir
+
+	sortedProductParams := make([]distgo.ProductParam, len(productParams))
+	copy(sortedProductParams, productParams)
+	sort.Slice(sortedProductParams, func(i, j int) bool {
+		return sortedProductParams[i].ID < sortedProductParams[j].ID
+	})
+
+	var allArtifacts []Artifact
+	for _, currProductParam := range sortedProductParams {
+		outputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, currProductParam)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compute output info for %s", currProductParam.ID)
+		}
+
+		buildArtifactPaths := outputInfo.ProductBuildArtifactPaths()
+		var osArchs []osarch.OSArch
+		for osArch := range buildArtifactPaths {
+			osArchs = append(osArchs, osArch)
+		}
+		sort.Slice(osArchs, func(i, j int) bool {
+			return osArchs[i].String() < osArchs[j].String()
+		})
+		for _, osArch := range osArchs {
+			allArtifacts = append(allArtifacts, Artifact{
+				ProductID: currProductParam.ID,
+				Type:      BuildArtifactType,
+				OSArch:    osArch.String(),
+				Path:      buildArtifactPaths[osArch],
+			})
+		}
+
+		distArtifactPaths := outputInfo.ProductDistArtifactPaths()
+		var distIDs []distgo.DistID
+		for distID := range distArtifactPaths {
+			distIDs = append(distIDs, distID)
+		}
+		sort.Sort(distgo.ByDistID(distIDs))
+		for _, distID := range distIDs {
+			paths := append([]string(nil), distArtifactPaths[distID]...)
+			sort.Strings(paths)
+			for _, currPath := range paths {
+				allArtifacts = append(allArtifacts, Artifact{
+					ProductID: currProductParam.ID,
+					Type:      DistArtifactType,
+					DistID:    distID,
+					Path:      currPath,
+				})
+			}
+		}
+	}
+	return allArtifacts, nil
+}
+
 func PrintDockerArtifacts(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productDockerIDs []distgo.ProductDockerID, stdout io.Writer) error {
 	productParams, err := distgo.ProductParamsForDockerProductArgs(projectParam.Products, productDockerIDs...)
 	if err != nil {
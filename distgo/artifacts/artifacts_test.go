@@ -95,6 +95,7 @@ out/build/foo/unspecified/%v/foo
 				Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
 					"foo": {
 						Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+							MainPkg:   stringPtr("./foo"),
 							OutputDir: stringPtr("build-output"),
 							OSArchs: &[]osarch.OSArch{
 								osarch.Current(),
@@ -360,6 +361,109 @@ func TestDistArtifacts(t *testing.T) {
 	}
 }
 
+func TestPrintAllArtifacts(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	darwinAMD64 := osarch.OSArch{OS: "darwin", Arch: "amd64"}
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	fooParam := createDistSpec("foo", osarchbin.New(darwinAMD64, linuxAMD64))
+	fooParam.Build = &distgo.BuildParam{
+		NameTemplate: "{{Product}}",
+		OutputDir:    "out/build",
+		MainPkg:      "./foo",
+		OSArchs:      []osarch.OSArch{linuxAMD64, darwinAMD64},
+	}
+	projectParam := distgo.ProjectParam{
+		Products: map[distgo.ProductID]distgo.ProductParam{
+			"foo": fooParam,
+			"bar": {
+				ID: "bar",
+				Build: &distgo.BuildParam{
+					NameTemplate: "{{Product}}",
+					OutputDir:    "out/build",
+					MainPkg:      "./bar",
+					OSArchs:      []osarch.OSArch{linuxAMD64},
+				},
+			},
+		},
+	}
+
+	for i, tc := range []struct {
+		name       string
+		productIDs []distgo.ProductID
+		jsonOutput bool
+		want       func(projectDir string) string
+	}{
+		{
+			name: "prints paths of all artifacts",
+			want: func(projectDir string) string {
+				return fmt.Sprintf(`%s/out/build/bar/0.1.0/linux-amd64/bar
+%s/out/build/foo/0.1.0/darwin-amd64/foo
+%s/out/build/foo/0.1.0/linux-amd64/foo
+%s/out/dist/foo/0.1.0/os-arch-bin/foo-0.1.0-darwin-amd64.tgz
+%s/out/dist/foo/0.1.0/os-arch-bin/foo-0.1.0-linux-amd64.tgz
+`, projectDir, projectDir, projectDir, projectDir, projectDir)
+			},
+		},
+		{
+			name:       "prints paths of artifacts of specified products",
+			productIDs: []distgo.ProductID{"bar"},
+			want: func(projectDir string) string {
+				return fmt.Sprintf(`%s/out/build/bar/0.1.0/linux-amd64/bar
+`, projectDir)
+			},
+		},
+		{
+			name:       "prints artifacts as JSON",
+			productIDs: []distgo.ProductID{"foo"},
+			jsonOutput: true,
+			want: func(projectDir string) string {
+				return fmt.Sprintf(`[
+  {
+    "productId": "foo",
+    "type": "build",
+    "osArch": "darwin-amd64",
+    "path": "%s/out/build/foo/0.1.0/darwin-amd64/foo"
+  },
+  {
+    "productId": "foo",
+    "type": "build",
+    "osArch": "linux-amd64",
+    "path": "%s/out/build/foo/0.1.0/linux-amd64/foo"
+  },
+  {
+    "productId": "foo",
+    "type": "dist",
+    "distId": "os-arch-bin",
+    "path": "%s/out/dist/foo/0.1.0/os-arch-bin/foo-0.1.0-darwin-amd64.tgz"
+  },
+  {
+    "productId": "foo",
+    "type": "dist",
+    "distId": "os-arch-bin",
+    "path": "%s/out/dist/foo/0.1.0/os-arch-bin/foo-0.1.0-linux-amd64.tgz"
+  }
+]
+`, projectDir, projectDir, projectDir, projectDir)
+			},
+		},
+	} {
+		projectDir, err := ioutil.TempDir(tmpDir, "")
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		projectInfo := distgo.ProjectInfo{
+			ProjectDir: projectDir,
+			Version:    "0.1.0",
+		}
+		buf := &bytes.Buffer{}
+		err = artifacts.PrintAllArtifacts(projectInfo, projectParam, tc.productIDs, tc.jsonOutput, buf)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want(projectDir), buf.String(), "Case %d: %s", i, tc.name)
+	}
+}
+
 func TestPrintDockerArtifacts(t *testing.T) {
 	tmpDir, cleanup, err := dirs.TempDir("", "")
 	defer cleanup()
@@ -15,14 +15,17 @@
 package build
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -43,36 +46,232 @@ type Options struct {
 	Install  bool
 	DryRun   bool
 	OSArchs  []osarch.OSArch
+
+	// MaxParallelism is the maximum number of builds that are run concurrently when Parallel is true. If less than or
+	// equal to 0, the number of logical processors reported by Go is used.
+	MaxParallelism int
+
+	// Force specifies that outputs should be built even if they are up-to-date. By default, the build for an output is
+	// skipped if the output exists and the hash of its inputs matches the hash recorded when it was last built.
+	Force bool
+
+	// SummaryFile is the path to which a JSON summary of the build outputs is written after all of the builds succeed.
+	// The summary contains the rendered name, absolute path, size and SHA-256 checksum of each output and the IDs of
+	// the products that were skipped by their build script. If empty, no summary is written. The summary is not written
+	// for dry runs.
+	SummaryFile string
 }
 
 func Products(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productBuildIDs []distgo.ProductBuildID, buildOpts Options, stdout io.Writer) error {
+	return ProductsWithContext(context.Background(), projectInfo, projectParam, productBuildIDs, buildOpts, stdout)
+}
+
+// ProductsWithContext is like Products, but the builds are canceled if the provided context is done. Refer to
+// RunWithContext for more information.
+func ProductsWithContext(ctx context.Context, projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productBuildIDs []distgo.ProductBuildID, buildOpts Options, stdout io.Writer) error {
 	productParams, err := distgo.ProductParamsForBuildProductArgs(projectParam.Products, buildOpts.OSArchs, productBuildIDs...)
 	if err != nil {
 		return err
 	}
-	return Run(projectInfo, productParams, buildOpts, stdout)
+	return RunWithContext(ctx, projectInfo, productParams, buildOpts, stdout)
+}
+
+// Errors is the error returned by Run when multiple builds run in parallel fail. It contains the error for each failed
+// build keyed by the ProductBuildID of the build.
+type Errors struct {
+	Errors map[distgo.ProductBuildID]error
+}
+
+func (e *Errors) Error() string {
+	var ids []string
+	for id := range e.Errors {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	var parts []string
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s: %v", id, e.Errors[distgo.ProductBuildID(id)]))
+	}
+	return fmt.Sprintf("%d builds failed:\n%s", len(ids), strings.Join(parts, "\n"))
+}
+
+// CommandError is the error returned when the "build" command for a product fails. It records the product and
+// OS/architecture that were being built, the exact command that was run and the output of the command. The errors
+// returned by Run wrap the *CommandError, so it can be retrieved from them using errors.Cause. The error returned by
+// running the command (typically an *exec.ExitError) can be retrieved from the *CommandError using Unwrap.
+type CommandError struct {
+	ProductID distgo.ProductID
+	OSArch    osarch.OSArch
+	// Args is the full argv of the command, including the path to the Go executable.
+	Args []string
+	// Dir is the working directory in which the command was run.
+	Dir string
+	// Env contains the environment variables that were set for the command in addition to the inherited environment.
+	Env []string
+	// Output is the combined standard output and standard error of the command.
+	Output string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("build of %s for %s failed: build command %v run in directory %s with additional environment variables %v failed (%v) with output:\n%s",
+		e.ProductID, e.OSArch, e.Args, e.Dir, e.Env, e.Err, e.Output)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
 }
 
 // Run builds the executables for the products specified by productParams using the options specified in buildOpts. If
-// buildOpts.Parallel is true, then the products will be built in parallel with N workers, where N is the number of
-// logical processors reported by Go. When builds occur in parallel, each (Product, OSArch) pair is treated as an
-// individual unit of work. Thus, it is possible that different products may be built in parallel. If any build process
-// returns an error, the first error returned is propagated back (and any builds that have not started will not be
-// started).
+// buildOpts.Parallel is true, then the products will be built in parallel with N workers, where N is
+// buildOpts.MaxParallelism (or the number of logical processors reported by Go if buildOpts.MaxParallelism is not
+// positive). When builds occur in parallel, each (Product, OSArch) pair is treated as an individual unit of work. Thus,
+// it is possible that different products may be built in parallel. When building in parallel, all of the builds are
+// run even if some of them fail: if exactly one build fails, its error is returned, and if multiple builds fail, an
+// *Errors that contains all of the errors is returned. When building serially, the first error is returned and any
+// builds that have not started will not be started. If the build script of a product exits with
+// distgo.BuildScriptSkipExitCode, the product is skipped and is not built. Products that do not specify a main package
+// are also skipped.
+//
+// If any of the provided products depend on other provided products, the products are built in dependency order: the
+// builds (and build scripts) for a product are not started until the builds of all of the products that it depends on
+// have finished. Products whose dependencies have all been built are built in parallel if buildOpts.Parallel is true.
+//
+// If a product specifies a VersionFile, the file is generated after the build script of the product is run and before
+// any of the builds of its dependency level start. After all of the builds of the level have finished (whether or not
+// they succeeded), the file that existed at the path of the version file is restored (or the generated file is removed
+// if no file existed) so that the generated file does not modify the project.
+//
+// After all of the builds for the products in a dependency level succeed, the LatestLinkName entry is updated for the
+// products in the level that specify LatestLink.
 func Run(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, buildOpts Options, stdout io.Writer) error {
-	var units []buildUnit
+	return RunWithContext(context.Background(), projectInfo, productParams, buildOpts, stdout)
+}
+
+// RunWithContext is like Run, but the builds are canceled if the provided context is done. When the context is done,
+// the running build commands and build scripts are killed, the partial outputs of the builds that were running are
+// removed and builds that have not started are not started. The returned error wraps the error of the context.
+func RunWithContext(ctx context.Context, projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, buildOpts Options, stdout io.Writer) error {
+	builtProductParams, skippedProductIDs, err := run(ctx, projectInfo, productParams, buildOpts, stdout)
+	if err != nil {
+		return err
+	}
+	if buildOpts.SummaryFile != "" && !buildOpts.DryRun {
+		summary, err := NewSummary(projectInfo, builtProductParams)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create build summary")
+		}
+		summary.Skipped = skippedProductIDs
+		if err := WriteSummaryFile(summary, buildOpts.SummaryFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run performs the builds for Run and returns the products that were built and the IDs of the products that were
+// skipped by their build scripts.
+func run(ctx context.Context, projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, buildOpts Options, stdout io.Writer) ([]distgo.ProductParam, []distgo.ProductID, error) {
+	levels, err := distgo.ProductParamsByDependencyLevel(productParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	productTaskOutputInfos := make(map[distgo.ProductID]distgo.ProductTaskOutputInfo)
 	for _, currProductParam := range productParams {
 		currProductTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, currProductParam)
 		if err != nil {
-			return errors.Wrapf(err, "failed to compute output information for %s", currProductParam.ID)
+			return nil, nil, errors.Wrapf(err, "failed to compute output information for %s", currProductParam.ID)
+		}
+		productTaskOutputInfos[currProductParam.ID] = currProductTaskOutputInfo
+		if currProductParam.Build == nil || currProductParam.Build.MainPkg == "" {
+			continue
+		}
+
+		// fail fast if any of the OS/architectures are not supported by the Go executable used for the build. If the
+		// Go executable cannot be resolved, the error is reported when the build is run.
+		if goBinaryPath, err := currProductParam.Build.GoBinaryPath(projectInfo.ProjectDir); err == nil {
+			if err := validateOSArchs(goBinaryPath, currProductParam.ID, currProductParam.Build.OSArchs); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var builtProductParams []distgo.ProductParam
+	var skippedProductIDs []distgo.ProductID
+	for _, currLevel := range levels {
+		levelBuiltProductParams, levelSkippedProductIDs, err := runLevel(ctx, projectInfo, currLevel, productTaskOutputInfos, buildOpts, stdout)
+		if err != nil {
+			return nil, nil, err
 		}
+		builtProductParams = append(builtProductParams, levelBuiltProductParams...)
+		skippedProductIDs = append(skippedProductIDs, levelSkippedProductIDs...)
+	}
+	return builtProductParams, skippedProductIDs, nil
+}
+
+// runLevel runs the build scripts and builds for the provided products, none of which depend on each other. Returns the
+// products that were built and the IDs of the products that were skipped by their build scripts.
+func runLevel(ctx context.Context, projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, productTaskOutputInfos map[distgo.ProductID]distgo.ProductTaskOutputInfo, buildOpts Options, stdout io.Writer) (_ []distgo.ProductParam, _ []distgo.ProductID, rErr error) {
+	var units []buildUnit
+	var builtProductParams []distgo.ProductParam
+	var skippedProductIDs []distgo.ProductID
+
+	// version files are generated before the builds of the level and restored after all of them have finished
+	versionFileProducts := make(map[string]distgo.ProductID)
+	var versionFiles []*generatedVersionFile
+	defer func() {
+		for _, versionFile := range versionFiles {
+			if err := versionFile.restore(); err != nil && rErr == nil {
+				rErr = err
+			}
+		}
+	}()
+	for _, currProductParam := range productParams {
+		currProductTaskOutputInfo := productTaskOutputInfos[currProductParam.ID]
 		if currProductParam.Build == nil {
 			continue
 		}
+		if currProductParam.Build.MainPkg == "" {
+			// product does not have a main package, so there is nothing to build
+			distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Skipping build for %s because it does not specify a main package", currProductParam.ID), buildOpts.DryRun)
+			continue
+		}
 
+		if err := ctx.Err(); err != nil {
+			return nil, nil, errors.Wrapf(err, "build canceled")
+		}
 		// execute build script
-		if err := distgo.WriteAndExecuteScript(projectInfo, currProductParam.Build.Script, distgo.BuildScriptEnvVariables(currProductTaskOutputInfo), stdout); err != nil {
-			return errors.Wrapf(err, "failed to execute build script")
+		if buildOpts.DryRun {
+			if currProductParam.Build.Script != "" {
+				distgo.DryRunPrintln(stdout, fmt.Sprintf("Run build script for %s", currProductParam.ID))
+			}
+		} else if err := distgo.WriteAndExecuteScriptContext(ctx, projectInfo, currProductParam.Build.Script, distgo.BuildScriptEnvVariables(currProductTaskOutputInfo), stdout); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, nil, errors.Wrapf(ctxErr, "build canceled")
+			}
+			if !distgo.IsScriptExitCode(err, distgo.BuildScriptSkipExitCode) {
+				return nil, nil, errors.Wrapf(err, "failed to execute build script")
+			}
+			_, _ = fmt.Fprintf(stdout, "Skipping build for %s because its build script exited with code %d\n", currProductParam.ID, distgo.BuildScriptSkipExitCode)
+			skippedProductIDs = append(skippedProductIDs, currProductParam.ID)
+			continue
+		}
+		builtProductParams = append(builtProductParams, currProductParam)
+
+		if currProductParam.Build.VersionFile != nil {
+			versionFilePath := currProductParam.Build.VersionFile.Path(currProductParam.Build.ModuleDirPath(projectInfo.ProjectDir))
+			if otherProductID, ok := versionFileProducts[versionFilePath]; ok {
+				return nil, nil, errors.Errorf("products %s and %s cannot both generate version file %s in the same build", otherProductID, currProductParam.ID, versionFilePath)
+			}
+			versionFileProducts[versionFilePath] = currProductParam.ID
+		}
+		versionFile, err := writeVersionFile(currProductParam, currProductTaskOutputInfo, buildOpts.DryRun, stdout)
+		if err != nil {
+			return nil, nil, err
+		}
+		if versionFile != nil {
+			versionFiles = append(versionFiles, versionFile)
 		}
 
 		for _, currOSArch := range currProductParam.Build.OSArchs {
@@ -84,17 +283,24 @@ func Run(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, bu
 		}
 	}
 
+	if err := runBuildUnits(ctx, units, buildOpts, stdout); err != nil {
+		return nil, nil, err
+	}
+	if err := updateLatestLinks(builtProductParams, productTaskOutputInfos, buildOpts.DryRun, stdout); err != nil {
+		return nil, nil, err
+	}
+	return builtProductParams, skippedProductIDs, nil
+}
+
+func runBuildUnits(ctx context.Context, units []buildUnit, buildOpts Options, stdout io.Writer) error {
 	if len(units) == 1 || !buildOpts.Parallel {
 		// process serially
 		for _, currUnit := range units {
-			if err := executeBuild(currUnit, buildOpts, stdout); err != nil {
+			if err := executeBuild(ctx, currUnit, buildOpts, stdout); err != nil {
 				return err
 			}
 		}
 	} else {
-		done := make(chan struct{})
-		defer close(done)
-
 		// send all jobs
 		nUnits := len(units)
 		buildUnitsJobs := make(chan buildUnit, nUnits)
@@ -104,39 +310,68 @@ func Run(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, bu
 		close(buildUnitsJobs)
 
 		// create workers
-		nWorkers := runtime.NumCPU()
+		nWorkers := buildOpts.MaxParallelism
+		if nWorkers <= 0 {
+			nWorkers = runtime.NumCPU()
+		}
 		if nUnits < nWorkers {
 			nWorkers = nUnits
 		}
-		var cs []<-chan error
+		// workers share the output writer, so serialize writes to it
+		workerStdout := &lockedWriter{w: stdout}
+		var cs []<-chan buildResult
 		for i := 0; i < nWorkers; i++ {
-			cs = append(cs, worker(buildUnitsJobs, buildOpts, stdout))
+			cs = append(cs, worker(ctx, buildUnitsJobs, buildOpts, workerStdout))
 		}
 
-		for err := range merge(done, cs...) {
-			if err != nil {
+		buildErrs := make(map[distgo.ProductBuildID]error)
+		for result := range merge(cs...) {
+			if result.err != nil {
+				buildErrs[result.id] = result.err
+			}
+		}
+		switch len(buildErrs) {
+		case 0:
+			return nil
+		case 1:
+			for _, err := range buildErrs {
 				return err
 			}
+		default:
+			return &Errors{Errors: buildErrs}
 		}
 	}
 
 	return nil
 }
 
-// merge handles "fanning in" the result of multiple output channels into a single output channel. If a signal is
-// received on the "done" channel, output processing will stop.
-func merge(done <-chan struct{}, cs ...<-chan error) <-chan error {
+type buildResult struct {
+	id  distgo.ProductBuildID
+	err error
+}
+
+// lockedWriter is an io.Writer that serializes calls to Write on the wrapped writer.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// merge handles "fanning in" the result of multiple output channels into a single output channel. The returned channel
+// is closed once all of the provided channels are closed.
+func merge(cs ...<-chan buildResult) <-chan buildResult {
 	var wg sync.WaitGroup
-	out := make(chan error)
+	out := make(chan buildResult)
 
-	output := func(c <-chan error) {
+	output := func(c <-chan buildResult) {
 		defer wg.Done()
-		for err := range c {
-			select {
-			case out <- err:
-			case <-done:
-				return
-			}
+		for result := range c {
+			out <- result
 		}
 	}
 
@@ -152,18 +387,21 @@ func merge(done <-chan struct{}, cs ...<-chan error) <-chan error {
 	return out
 }
 
-func worker(in <-chan buildUnit, buildOpts Options, stdout io.Writer) <-chan error {
-	out := make(chan error)
+func worker(ctx context.Context, in <-chan buildUnit, buildOpts Options, stdout io.Writer) <-chan buildResult {
+	out := make(chan buildResult)
 	go func() {
 		for unit := range in {
-			out <- executeBuild(unit, buildOpts, stdout)
+			out <- buildResult{
+				id:  distgo.NewProductBuildID(unit.productTaskOutputInfo.Product.ID, unit.osArch),
+				err: executeBuild(ctx, unit, buildOpts, stdout),
+			}
 		}
 		close(out)
 	}()
 	return out
 }
 
-func executeBuild(unit buildUnit, buildOpts Options, stdout io.Writer) error {
+func executeBuild(ctx context.Context, unit buildUnit, buildOpts Options, stdout io.Writer) error {
 	name := unit.productTaskOutputInfo.Product.ID
 
 	osArch := unit.osArch
@@ -178,38 +416,152 @@ func executeBuild(unit buildUnit, buildOpts Options, stdout io.Writer) error {
 			outputArtifactDisplayPath = relPath
 		}
 	}
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrapf(err, "build of %s for %s canceled", name, osArch.String())
+	}
+
+	// if the input hash cannot be computed, the build is run and no hash is recorded for the output
+	hash, err := inputHash(unit)
+	if err != nil {
+		hash = ""
+	}
+	if hash != "" && !buildOpts.Force && upToDate(outputArtifactPath, hash) {
+		distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("%s for %s at %s is up-to-date", name, osArch.String(), outputArtifactDisplayPath), buildOpts.DryRun)
+		return nil
+	}
 	distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Building %s for %s at %s", name, osArch.String(), outputArtifactDisplayPath), buildOpts.DryRun)
 
+	if unit.buildParam.CleanOutputDir {
+		// only the directory for the OS/architecture being built is removed
+		osArchOutputDir := path.Join(unit.productTaskOutputInfo.ProductBuildOutputDir(), osArch.String())
+		if buildOpts.DryRun {
+			distgo.DryRunPrintln(stdout, fmt.Sprintf("Remove build output directory %s", osArchOutputDir))
+		} else if err := os.RemoveAll(osArchOutputDir); err != nil {
+			return errors.Wrapf(err, "failed to remove build output directory %s", osArchOutputDir)
+		}
+	}
 	if !buildOpts.DryRun {
 		if err := os.MkdirAll(path.Dir(outputArtifactPath), 0755); err != nil {
 			return errors.Wrapf(err, "failed to create directories for %s", path.Dir(outputArtifactPath))
 		}
+		// remove the recorded hash so that it is not considered valid if the build fails
+		if err := os.Remove(inputHashFilePath(outputArtifactPath)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove input hash file")
+		}
 	}
-	if err := doBuildAction(unit, outputArtifactPath, buildOpts.Install, buildOpts.DryRun, stdout); err != nil {
+	if err := doBuildAction(ctx, unit, outputArtifactPath, buildOpts.Install, buildOpts.DryRun, stdout); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && !buildOpts.DryRun {
+			// the build command was killed, so remove any partial output that it may have written
+			if err := os.Remove(outputArtifactPath); err != nil && !os.IsNotExist(err) {
+				return errors.Wrapf(err, "build of %s for %s canceled, but failed to remove partial output %s", name, osArch.String(), outputArtifactPath)
+			}
+			return errors.Wrapf(ctxErr, "build of %s for %s canceled", name, osArch.String())
+		}
 		return errors.Wrapf(err, "go build failed")
 	}
+	if !buildOpts.DryRun {
+		if err := checkOutputSize(unit, outputArtifactPath); err != nil {
+			return err
+		}
+		if err := checkVersion(unit, outputArtifactPath); err != nil {
+			return err
+		}
+	}
+	if !buildOpts.DryRun && hash != "" {
+		if err := ioutil.WriteFile(inputHashFilePath(outputArtifactPath), []byte(hash+"\n"), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write input hash file")
+		}
+	}
 
 	elapsed := time.Since(start)
 	distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Finished building %s for %s (%.3fs)", name, osArch.String(), elapsed.Seconds()), buildOpts.DryRun)
 	return nil
 }
 
-func doBuildAction(unit buildUnit, outputArtifactPath string, doInstall, dryRun bool, stdout io.Writer) error {
-	osArch := unit.osArch
+// checkOutputSize returns an error if MaxOutputBytes is specified for the build unit and the size of the build output at
+// the provided path exceeds it.
+func checkOutputSize(unit buildUnit, outputArtifactPath string) error {
+	maxOutputBytes := unit.buildParam.MaxOutputBytes
+	if maxOutputBytes <= 0 {
+		return nil
+	}
+	fi, err := os.Stat(outputArtifactPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to determine size of build output")
+	}
+	if fi.Size() > maxOutputBytes {
+		return errors.Errorf("build output %s for %s for %s is %d bytes, which exceeds the maximum output size of %d bytes", outputArtifactPath, unit.productTaskOutputInfo.Product.ID, unit.osArch.String(), fi.Size(), maxOutputBytes)
+	}
+	return nil
+}
 
-	cmd := exec.Command("go")
+// checkVersion runs the build output at the provided path with the VersionCheckArgs of the build unit and returns an
+// error if the output does not contain the version of the project. The check is skipped if VersionCheckArgs is empty,
+// if the build unit is not for the OSArch of the host or if the build mode does not produce an executable.
+func checkVersion(unit buildUnit, outputArtifactPath string) error {
+	if len(unit.buildParam.VersionCheckArgs) == 0 || unit.osArch != osarch.Current() || !distgo.IsExecutableBuildMode(unit.buildParam.BuildMode) {
+		return nil
+	}
+	version := unit.productTaskOutputInfo.Project.Version
+	cmd := exec.Command(outputArtifactPath, unit.buildParam.VersionCheckArgs...)
 	cmd.Dir = unit.productTaskOutputInfo.Project.ProjectDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "version check of %s for %s failed: command %v failed with output:\n%s", unit.productTaskOutputInfo.Product.ID, unit.osArch.String(), cmd.Args, strings.TrimSpace(string(output)))
+	}
+	if !strings.Contains(string(output), version) {
+		return errors.Errorf("version check of %s for %s failed: output of command %v does not contain version %q:\n%s", unit.productTaskOutputInfo.Product.ID, unit.osArch.String(), cmd.Args, version, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
 
-	var env []string
-	if osArch.OS != "" {
-		env = append(env, "GOOS="+osArch.OS)
+// buildEnvironment returns the environment variables that are set for the build command of the provided unit in
+// addition to the inherited environment. The configured environment variables are added in sorted order so that the
+// output of dry runs is deterministic.
+func buildEnvironment(unit buildUnit) []string {
+	env := distgo.GoEnvironment(unit.osArch)
+	buildEnv := unit.buildParam.EnvironmentForOSArch(unit.osArch)
+	if unit.buildParam.CgoDisabledForOSArch(unit.osArch) {
+		if buildEnv == nil {
+			buildEnv = make(map[string]string)
+		}
+		buildEnv["CGO_ENABLED"] = "0"
+	}
+	if sourceDateEpoch, ok := unit.productTaskOutputInfo.Project.LookupSourceDateEpoch(); ok {
+		if buildEnv == nil {
+			buildEnv = make(map[string]string)
+		}
+		buildEnv[distgo.SourceDateEpochEnvVar] = sourceDateEpoch
 	}
-	if osArch.Arch != "" {
-		env = append(env, "GOARCH="+osArch.Arch)
+	var buildEnvKeys []string
+	for k := range buildEnv {
+		buildEnvKeys = append(buildEnvKeys, k)
 	}
-	for k, v := range unit.buildParam.Environment {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	sort.Strings(buildEnvKeys)
+	for _, k := range buildEnvKeys {
+		env = append(env, fmt.Sprintf("%s=%s", k, buildEnv[k]))
 	}
+	return env
+}
+
+func doBuildAction(ctx context.Context, unit buildUnit, outputArtifactPath string, doInstall, dryRun bool, stdout io.Writer) error {
+	osArch := unit.osArch
+	if err := unit.buildParam.ValidateEnvironmentForOSArch(osArch); err != nil {
+		return err
+	}
+
+	goBinaryPath, err := unit.buildParam.GoBinaryPath(unit.productTaskOutputInfo.Project.ProjectDir)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(goBinaryPath)
+	cmd.Dir = unit.buildParam.ModuleDirPath(unit.productTaskOutputInfo.Project.ProjectDir)
+
+	if unit.buildParam.CgoDisabledForOSArch(osArch) {
+		_, _ = fmt.Fprintf(stdout, "Warning: disabling CGo for build of %s for %s because it is cross-compiled and no C compiler is configured for %s\n", unit.productTaskOutputInfo.Product.ID, osArch, osArch)
+	}
+	env := buildEnvironment(unit)
 	cmd.Env = append(os.Environ(), env...)
 
 	args := []string{cmd.Path}
@@ -219,32 +571,42 @@ func doBuildAction(unit buildUnit, outputArtifactPath string, doInstall, dryRun
 	}
 
 	if !path.IsAbs(outputArtifactPath) {
-		// if outputArtifactPath is relative, then if it starts with ProjectDir the prefix needs to be trimmed because
-		// the working directory for the build command is set to the project directory
-		outputArtifactPath = strings.TrimPrefix(outputArtifactPath, path.Clean(unit.productTaskOutputInfo.Project.ProjectDir)+"/")
+		// if outputArtifactPath is relative, it must be made relative to the working directory for the build command,
+		// which is the module directory of the product
+		if relPath, err := filepath.Rel(cmd.Dir, outputArtifactPath); err == nil {
+			outputArtifactPath = relPath
+		}
 	}
 	args = append(args, "-o", outputArtifactPath)
 
-	buildArgs, err := unit.buildParam.BuildArgs(unit.productTaskOutputInfo)
+	buildArgs, err := unit.buildParam.BuildArgsForOSArch(unit.productTaskOutputInfo, osArch)
 	if err != nil {
 		return err
 	}
 	args = append(args, buildArgs...)
 
-	mainPkg := unit.buildParam.MainPkg
+	mainPkg := unit.buildParam.MainPkgForOSArch(osArch)
 	args = append(args, mainPkg)
 	cmd.Args = args
 
 	if dryRun {
-		dryRunMsg := fmt.Sprintf("Run: %s", strings.Join(cmd.Args, " "))
+		dryRunMsg := fmt.Sprintf("Run: %s in directory %s", strings.Join(cmd.Args, " "), cmd.Dir)
 		if len(env) > 0 {
 			dryRunMsg += fmt.Sprintf(" with additional environment variables %v", env)
 		}
 		distgo.DryRunPrintln(stdout, dryRunMsg)
 	} else {
-		if output, err := cmd.CombinedOutput(); err != nil {
+		if output, err := runBuildCommand(ctx, cmd, unit.buildParam.BuildRetries, unit.buildParam.BuildRetryBackoff, stdout); err != nil {
 			errOutput := strings.TrimSpace(string(output))
-			err = fmt.Errorf("build command %v run in directory %s with additional environment variables %v failed with output:\n%s", cmd.Args, cmd.Dir, env, errOutput)
+			err = &CommandError{
+				ProductID: unit.productTaskOutputInfo.Product.ID,
+				OSArch:    osArch,
+				Args:      cmd.Args,
+				Dir:       cmd.Dir,
+				Env:       env,
+				Output:    errOutput,
+				Err:       err,
+			}
 			if regexp.MustCompile(installPermissionDenied).MatchString(errOutput) {
 				// if "install" command failed due to lack of permissions, return error that contains explanation
 				return fmt.Errorf(goInstallErrorMsg(osArch, err))
@@ -255,6 +617,56 @@ func doBuildAction(unit buildUnit, outputArtifactPath string, doInstall, dryRun
 	return nil
 }
 
+// transientBuildFailurePatterns match output of the "build" command that indicates that the failure was caused by a
+// transient condition (typically a network failure while downloading modules) rather than by the code being built.
+var transientBuildFailurePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)dial tcp`),
+	regexp.MustCompile(`(?i)i/o timeout`),
+	regexp.MustCompile(`(?i)TLS handshake timeout`),
+	regexp.MustCompile(`(?i)connection (reset by peer|refused)`),
+	regexp.MustCompile(`(?i)temporary failure in name resolution`),
+	regexp.MustCompile(`(?i)unexpected EOF`),
+	regexp.MustCompile(`\b(429 Too Many Requests|500 Internal Server Error|502 Bad Gateway|503 Service Unavailable|504 Gateway Timeout)\b`),
+}
+
+// isTransientBuildFailure returns true if the provided output of a failed "build" command indicates that the failure
+// was transient and that the command may succeed if it is retried.
+func isTransientBuildFailure(output string) bool {
+	for _, pattern := range transientBuildFailurePatterns {
+		if pattern.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// runBuildCommand runs the provided command and returns its combined output. If the command fails with output that
+// indicates a transient failure, it is retried up to the specified number of times, waiting for the specified backoff
+// (which doubles after every retry) between attempts. The command is killed if the provided context is done, and no
+// further attempts are made.
+func runBuildCommand(ctx context.Context, cmd *exec.Cmd, retries int, backoff time.Duration, stdout io.Writer) ([]byte, error) {
+	if backoff <= 0 {
+		backoff = distgo.DefaultBuildRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		currCmd := exec.CommandContext(ctx, cmd.Path)
+		currCmd.Args = cmd.Args
+		currCmd.Dir = cmd.Dir
+		currCmd.Env = cmd.Env
+		output, err := currCmd.CombinedOutput()
+		if err == nil || attempt >= retries || !isTransientBuildFailure(string(output)) {
+			return output, err
+		}
+		_, _ = fmt.Fprintf(stdout, "Build command %v failed with a transient error, retrying in %v (retry %d of %d)\n", cmd.Args, backoff, attempt+1, retries)
+		select {
+		case <-ctx.Done():
+			return output, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
 const installPermissionDenied = `(?s)^go build [a-zA-Z0-9_/]+: mkdir [^:]+: permission denied.+`
 
 func goInstallErrorMsg(osArch osarch.OSArch, err error) string {
@@ -262,10 +674,11 @@ func goInstallErrorMsg(osArch osarch.OSArch, err error) string {
 	if output, err := exec.Command("command", "-v", "go").CombinedOutput(); err == nil {
 		goBinary = strings.TrimSpace(string(output))
 	}
+	goarch, _ := distgo.GOARCHAndVariant(osArch)
 	return strings.Join([]string{
 		`failed to install a Go standard library package due to insufficient permissions to create directory.`,
 		`This typically means that the standard library for the OS/architecture combination have not been installed locally and the current user does not have write permissions to GOROOT/pkg.`,
-		fmt.Sprintf(`Run "sudo env GOOS=%s GOARCH=%s %s install std" to install the standard packages for this combination as root and then try again.`, osArch.OS, osArch.Arch, goBinary),
+		fmt.Sprintf(`Run "sudo env GOOS=%s GOARCH=%s %s install std" to install the standard packages for this combination as root and then try again.`, osArch.OS, goarch, goBinary),
 		fmt.Sprintf(`Full error: %s`, err.Error()),
 	}, "\n")
 }
@@ -16,6 +16,9 @@ package build_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -23,15 +26,20 @@ import (
 	"path"
 	"reflect"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/distgo/build"
+	distgoconfig "github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/distgo/testfuncs"
 	"github.com/palantir/distgo/pkg/git"
 	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/palantir/pkg/gittest"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -328,8 +336,8 @@ func TestBuildErrorMessage(t *testing.T) {
 		param.Build.MainPkg = "./foo"
 	})
 
-	want := fmt.Sprintf(`(?s)^go build failed: build command \[.+go build -i -o out/build/testProduct/%v/testProduct ./foo\] run in directory %s with additional environment variables \[GOOS=.+ GOARCH=.+\] failed with output:.+foo/main.go:1:15: syntax error: non-declaration statement outside function body$`,
-		osarch.Current(), tmpDir)
+	want := fmt.Sprintf(`(?s)^go build failed: build of testProduct for %v failed: build command \[.+go build -i -o out/build/testProduct/%v/testProduct ./foo\] run in directory %s with additional environment variables \[GOOS=.+ GOARCH=.+\] failed \(exit status 1\) with output:.+foo/main.go:1:15: syntax error: non-declaration statement outside function body$`,
+		osarch.Current(), osarch.Current(), tmpDir)
 
 	buf := &bytes.Buffer{}
 	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
@@ -339,6 +347,42 @@ func TestBuildErrorMessage(t *testing.T) {
 	assert.Regexp(t, want, err.Error())
 }
 
+func TestBuildCommandError(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main; asdfa"), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(nil)
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+	require.Error(t, err)
+
+	// message identifies the failing target and contains the compiler output
+	assert.Contains(t, err.Error(), fmt.Sprintf("build of testProduct for %s failed", osarch.Current()))
+	assert.Contains(t, err.Error(), "syntax error: non-declaration statement outside function body")
+
+	cmdErr, ok := errors.Cause(err).(*build.CommandError)
+	require.True(t, ok, "cause of error should be *build.CommandError, was %T", errors.Cause(err))
+	assert.Equal(t, distgo.ProductID("testProduct"), cmdErr.ProductID)
+	assert.Equal(t, osarch.Current(), cmdErr.OSArch)
+	assert.Equal(t, tmp, cmdErr.Dir)
+	require.True(t, len(cmdErr.Args) > 2, "Args: %v", cmdErr.Args)
+	assert.Equal(t, []string{"build", "-o"}, cmdErr.Args[1:3])
+	assert.Equal(t, ".", cmdErr.Args[len(cmdErr.Args)-1])
+	assert.Contains(t, cmdErr.Output, "main.go:1:15: syntax error")
+
+	_, ok = cmdErr.Unwrap().(*exec.ExitError)
+	assert.True(t, ok, "CommandError should wrap *exec.ExitError, was %T", cmdErr.Unwrap())
+}
+
 // TODO: run test in environment where current user is not root and re-enable
 //func TestBuildInstallErrorMessage(t *testing.T) {
 //	tmp, cleanup, err := dirs.TempDir(".", "")
@@ -483,24 +527,1462 @@ func TestBuildAllParallel(t *testing.T) {
 	}
 }
 
-func createBuildProductParam(fn func(*distgo.ProductParam)) distgo.ProductParam {
-	param := distgo.ProductParam{
-		ID: "testProduct",
-		Build: &distgo.BuildParam{
-			NameTemplate: "{{Product}}",
-			MainPkg:      ".",
-			OutputDir:    "out/build",
-			OSArchs: []osarch.OSArch{
-				osarch.Current(),
+func TestBuildAllParallelMaxParallelism(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = os.MkdirAll(path.Join(tmp, "foo"), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "foo", "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.MainPkg = "./foo"
+		param.Build.OSArchs = []osarch.OSArch{
+			{
+				OS:   "darwin",
+				Arch: "amd64",
 			},
+			{
+				OS:   "linux",
+				Arch: "amd64",
+			},
+			{
+				OS:   "windows",
+				Arch: "amd64",
+			},
+		}
+	})
+
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		Parallel:       true,
+		MaxParallelism: 2,
+	}, ioutil.Discard)
+	require.NoError(t, err)
+
+	for _, currOSArch := range productParam.Build.OSArchs {
+		_, err := os.Stat(path.Join(tmp, "out", "build", "testProduct", "0.1.0", currOSArch.String(), distgo.ExecutableName("testProduct", currOSArch.OS)))
+		assert.NoError(t, err, "expected executable for %s to exist", currOSArch)
+	}
+}
+
+func TestBuildAllParallelReportsAllErrors(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	for _, dir := range []string{"foo", "bar"} {
+		err = os.MkdirAll(path.Join(tmp, dir), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path.Join(tmp, dir, "main.go"), []byte(`package main; asdfa`), 0644)
+		require.NoError(t, err)
+	}
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	fooOSArch := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	barOSArch := osarch.OSArch{OS: "darwin", Arch: "amd64"}
+	productParams := []distgo.ProductParam{
+		createBuildProductParam(func(param *distgo.ProductParam) {
+			param.ID = "foo"
+			param.Build.MainPkg = "./foo"
+			param.Build.OSArchs = []osarch.OSArch{fooOSArch}
+		}),
+		createBuildProductParam(func(param *distgo.ProductParam) {
+			param.ID = "bar"
+			param.Build.MainPkg = "./bar"
+			param.Build.OSArchs = []osarch.OSArch{barOSArch}
+		}),
+	}
+
+	err = build.Run(projectInfo, productParams, build.Options{
+		Parallel: true,
+	}, ioutil.Discard)
+	require.Error(t, err)
+
+	buildErrs, ok := err.(*build.Errors)
+	require.True(t, ok, "expected error to be *build.Errors, was %T: %v", err, err)
+	assert.Len(t, buildErrs.Errors, 2)
+	assert.Contains(t, buildErrs.Errors, distgo.NewProductBuildID("foo", fooOSArch))
+	assert.Contains(t, buildErrs.Errors, distgo.NewProductBuildID("bar", barOSArch))
+	assert.Regexp(t, `(?s)^2 builds failed:\nbar\.darwin-amd64: go build failed: .+\nfoo\.linux-amd64: go build failed: `, err.Error())
+}
+
+func TestBuildOSArchVariants(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	wantBuildSettings := map[osarch.OSArch]string{
+		{OS: "linux", Arch: "arm-6"}:          "GOARM=6",
+		{OS: "linux", Arch: "arm-7"}:          "GOARM=7",
+		{OS: "linux", Arch: "mips-softfloat"}: "GOMIPS=softfloat",
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.OSArchs = []osarch.OSArch{
+			{OS: "linux", Arch: "arm-6"},
+			{OS: "linux", Arch: "arm-7"},
+			{OS: "linux", Arch: "mips-softfloat"},
+		}
+	})
+
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		Parallel: false,
+	}, ioutil.Discard)
+	require.NoError(t, err)
+
+	for osArch, wantBuildSetting := range wantBuildSettings {
+		outputPath := path.Join(tmp, "out", "build", "testProduct", "0.1.0", osArch.String(), "testProduct")
+		output, err := exec.Command("go", "version", "-m", outputPath).CombinedOutput()
+		require.NoError(t, err, "failed to read build information for %s: %s", osArch, string(output))
+		assert.Contains(t, string(output), wantBuildSetting, "build information for %s did not contain %s", osArch, wantBuildSetting)
+	}
+}
+
+func TestBuildWASM(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	jsWASM := osarch.OSArch{OS: "js", Arch: "wasm"}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.VersionVar = "main.testVersionVar"
+		param.Build.OSArchs = []osarch.OSArch{jsWASM}
+	})
+
+	buffer := &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		DryRun: true,
+	}, buffer)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`Run: .*go build -o .*out/build/testProduct/0.1.0/js-wasm/testProduct.wasm -ldflags -X main.testVersionVar=0.1.0 \. in directory .+ with additional environment variables \[GOOS=js GOARCH=wasm\]`), buffer.String())
+
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+	require.NoError(t, err)
+
+	outputBytes, err := ioutil.ReadFile(path.Join(tmp, "out", "build", "testProduct", "0.1.0", "js-wasm", "testProduct.wasm"))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(outputBytes, []byte("\x00asm")), "build output is not a WebAssembly module")
+}
+
+func TestBuildOSArchMainPkg(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	for _, currPkg := range []string{"app", "app-windows"} {
+		err = os.MkdirAll(path.Join(tmp, currPkg), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path.Join(tmp, currPkg, "main.go"), []byte(testMain), 0644)
+		require.NoError(t, err)
+	}
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	windowsAMD64 := osarch.OSArch{OS: "windows", Arch: "amd64"}
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.MainPkg = "./app"
+		param.Build.OSArchMainPkg = map[osarch.OSArch]string{
+			windowsAMD64: "./app-windows",
+		}
+		param.Build.OSArchs = []osarch.OSArch{linuxAMD64, windowsAMD64}
+	})
+
+	buffer := &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		DryRun: true,
+	}, buffer)
+	require.NoError(t, err)
+	// OS/architecture with an entry uses the override
+	assert.Regexp(t, regexp.MustCompile(`Run: .*go build -o .*out/build/testProduct/0.1.0/windows-amd64/testProduct.exe \./app-windows in directory .+ with additional environment variables \[GOOS=windows GOARCH=amd64\]`), buffer.String())
+	// OS/architecture without an entry falls back to the default main package
+	assert.Regexp(t, regexp.MustCompile(`Run: .*go build -o .*out/build/testProduct/0.1.0/linux-amd64/testProduct \./app in directory .+ with additional environment variables \[GOOS=linux GOARCH=amd64\]`), buffer.String())
+}
+
+func TestBuildDisableCgoForCrossCompile(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	hostOSArch := osarch.Current()
+	crossOSArch := osarch.OSArch{OS: "linux", Arch: "arm64"}
+	if crossOSArch == hostOSArch {
+		crossOSArch.Arch = "amd64"
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.DisableCgoForCrossCompile = true
+		param.Build.OSArchs = []osarch.OSArch{hostOSArch, crossOSArch}
+	})
+
+	buffer := &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		DryRun: true,
+	}, buffer)
+	require.NoError(t, err)
+	output := buffer.String()
+	// cross build disables CGo and prints a warning
+	assert.Contains(t, output, fmt.Sprintf("Warning: disabling CGo for build of testProduct for %s because it is cross-compiled and no C compiler is configured for %s\n", crossOSArch, crossOSArch))
+	assert.Regexp(t, regexp.MustCompile(fmt.Sprintf(`Run: .*go build -o .*out/build/testProduct/0.1.0/%s/testProduct \. in directory .+ with additional environment variables \[GOOS=%s GOARCH=%s CGO_ENABLED=0\]`, crossOSArch, crossOSArch.OS, crossOSArch.Arch)), output)
+	// host build is not affected
+	assert.NotContains(t, output, fmt.Sprintf("for %s because it is cross-compiled", hostOSArch))
+	assert.Regexp(t, regexp.MustCompile(fmt.Sprintf(`Run: .*go build -o .*out/build/testProduct/0.1.0/%s/testProduct \. in directory .+ with additional environment variables \[GOOS=%s GOARCH=%s\]`, hostOSArch, hostOSArch.OS, hostOSArch.Arch)), output)
+
+	// cross build with a C compiler configured for the OSArch is not affected
+	productParam.Build.CrossCompilers = map[osarch.OSArch]distgo.CrossCompilerSpec{
+		crossOSArch: {
+			CC: "cross-gcc",
 		},
 	}
-	if fn != nil {
-		fn(&param)
+	buffer = &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		DryRun: true,
+	}, buffer)
+	require.NoError(t, err)
+	assert.NotContains(t, buffer.String(), "Warning: disabling CGo")
+	assert.Regexp(t, regexp.MustCompile(fmt.Sprintf(`Run: .*go build -o .*out/build/testProduct/0.1.0/%s/testProduct \. in directory .+ with additional environment variables \[GOOS=%s GOARCH=%s CC=cross-gcc\]`, crossOSArch, crossOSArch.OS, crossOSArch.Arch)), buffer.String())
+
+	// race detector with a cross build without a C compiler fails before running the build
+	productParam.Build.CrossCompilers = nil
+	productParam.Build.Race = true
+	buffer = &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		DryRun: true,
+	}, buffer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("race detector requires CGo, but CGo is disabled for %s because it is cross-compiled and no C compiler is configured for it", crossOSArch))
+	assert.NotContains(t, buffer.String(), "CGO_ENABLED=0")
+}
+
+func TestBuildCanceled(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// stub Go executable writes a partial output and then blocks until it is killed
+	stubGoPath := path.Join(tmp, "toolchain", "bin", "go")
+	err = os.MkdirAll(path.Dir(stubGoPath), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(stubGoPath, []byte("#!/bin/sh\nif [ \"$1\" != \"build\" ]; then exit 1; fi\necho partial > \"$3\"\nexec sleep 60\n"), 0755)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
 	}
-	return param
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.GoBinary = stubGoPath
+	})
+	outputPath := path.Join(tmp, "out", "build", "testProduct", "0.1.0", osarch.Current().String(), "testProduct")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// cancel once the build has started writing its output
+		for {
+			if _, err := os.Stat(outputPath); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	err = build.RunWithContext(ctx, projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+	require.Error(t, err)
+	assert.Equal(t, context.Canceled, errors.Cause(err))
+	assert.Equal(t, fmt.Sprintf("build of testProduct for %s canceled: context canceled", osarch.Current()), err.Error())
+	assert.True(t, time.Since(start) < 30*time.Second, "build command should be killed when the context is canceled")
+
+	_, err = os.Stat(outputPath)
+	assert.True(t, os.IsNotExist(err), "partial build output should be removed")
 }
 
-func stringVar(in string) *string {
-	return &in
+func TestBuildCustomGoBinary(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// stub Go executable records its arguments in a file instead of building
+	argsFile := path.Join(tmp, "go-args.txt")
+	stubGoPath := path.Join(tmp, "toolchain", "bin", "go")
+	err = os.MkdirAll(path.Dir(stubGoPath), 0755)
+	require.NoError(t, err)
+	err = os.Chmod(path.Dir(stubGoPath), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(stubGoPath, []byte(fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\n", argsFile)), 0755)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.GoBinary = stubGoPath
+	})
+
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+	require.NoError(t, err)
+
+	gotArgs, err := ioutil.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("build -o %s/out/build/testProduct/0.1.0/%s/testProduct .\n", tmp, osarch.Current()), string(gotArgs))
+
+	productParam.Build.GoBinary = path.Join(tmp, "toolchain", "bin")
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+	assert.EqualError(t, err, fmt.Sprintf("go build failed: Go executable %s is not an executable file (mode %v)", productParam.Build.GoBinary, os.ModeDir|0755))
+}
+
+func TestBuildNestedModule(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// the main package imports a package that can only be resolved using the nested module
+	for relPath, content := range map[string]string{
+		"go.mod":                            "module example.com/root\n",
+		"tools/go.mod":                      "module example.com/tools\n",
+		"tools/internal/message/message.go": "package message\n\nconst Message = \"built from nested module\"\n",
+		"tools/cmd/gen/main.go":             "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/tools/internal/message\"\n)\n\nfunc main() {\n\tfmt.Println(message.Message)\n}\n",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(tmp, relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(tmp, relPath), []byte(content), 0644))
+	}
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.ModuleDir = "tools"
+		param.Build.MainPkg = "./cmd/gen"
+	})
+
+	buffer := &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, buffer)
+	require.NoError(t, err, "Output: %s", buffer.String())
+
+	outputPath := path.Join(tmp, "out", "build", "testProduct", "0.1.0", osarch.Current().String(), "testProduct")
+	output, err := exec.Command(outputPath).CombinedOutput()
+	require.NoError(t, err, "Output: %s", string(output))
+	assert.Equal(t, "built from nested module\n", string(output))
+}
+
+func TestBuildNoMainPkg(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.MainPkg = ""
+	})
+
+	buffer := &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, buffer)
+	require.NoError(t, err)
+	assert.Equal(t, "Skipping build for testProduct because it does not specify a main package\n", buffer.String())
+
+	_, err = os.Stat(path.Join(tmp, "out", "build"))
+	assert.True(t, os.IsNotExist(err), "build output directory should not exist")
+
+	requiresBuild, err := build.RequiresBuild(projectInfo, productParam)
+	require.NoError(t, err)
+	assert.Nil(t, requiresBuild)
+}
+
+func TestBuildRetriesTransientFailures(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+
+	for i, tc := range []struct {
+		name         string
+		failures     int
+		failOutput   string
+		wantError    bool
+		wantAttempts int
+	}{
+		{
+			name:         "transient failures are retried",
+			failures:     2,
+			failOutput:   "go: github.com/org/repo@v1.0.0: Get https://proxy.golang.org/: dial tcp: i/o timeout",
+			wantAttempts: 3,
+		},
+		{
+			name:         "compile errors are not retried",
+			failures:     2,
+			failOutput:   "./main.go:3:1: syntax error: non-declaration statement outside function body",
+			wantError:    true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "transient failures are retried at most the configured number of times",
+			failures:     5,
+			failOutput:   "go: downloading github.com/org/repo v1.0.0: 502 Bad Gateway",
+			wantError:    true,
+			wantAttempts: 4,
+		},
+	} {
+		caseDir := path.Join(tmp, fmt.Sprintf("case-%d", i))
+		err := os.MkdirAll(caseDir, 0755)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		// stub Go executable fails with the specified output for the configured number of attempts and then succeeds
+		attemptsFile := path.Join(caseDir, "attempts.txt")
+		stubGoPath := path.Join(caseDir, "go")
+		err = ioutil.WriteFile(stubGoPath, []byte(fmt.Sprintf(`#!/bin/sh
+if [ "$1" != "build" ]; then
+  exit 1
+fi
+echo attempt >> %s
+if [ $(wc -l < %s) -le %d ]; then
+  echo "%s"
+  exit 1
+fi
+`, attemptsFile, attemptsFile, tc.failures, tc.failOutput)), 0755)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		err = os.Chmod(stubGoPath, 0755)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+			param.Build.GoBinary = stubGoPath
+			param.Build.BuildRetries = 3
+			param.Build.BuildRetryBackoff = time.Millisecond
+		})
+		err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+			Force: true,
+		}, ioutil.Discard)
+		if tc.wantError {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Contains(t, err.Error(), tc.failOutput, "Case %d: %s", i, tc.name)
+		} else {
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+		}
+
+		attempts, err := ioutil.ReadFile(attemptsFile)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantAttempts, strings.Count(string(attempts), "attempt"), "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestBuildSkipsUnchangedInputs(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// specify the go directive so that "go build" does not modify go.mod (which is an input for the build)
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo\n\ngo 1.13\n"), 0644)
+	require.NoError(t, err)
+	mainFilePath := path.Join(tmp, "main.go")
+	err = ioutil.WriteFile(mainFilePath, []byte("package main\n\nfunc main() {}\n"), 0644)
+	require.NoError(t, err)
+	// directory in which the version file is generated
+	err = os.MkdirAll(path.Join(tmp, "internal", "version"), 0755)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(nil)
+	wantBuilt := regexp.MustCompile(`(?m)^Building testProduct for ` + osarch.Current().String())
+	wantUpToDate := regexp.MustCompile(`(?m)^testProduct for ` + osarch.Current().String() + ` at .+ is up-to-date$`)
+
+	for i, tc := range []struct {
+		name      string
+		setup     func()
+		force     bool
+		wantBuild bool
+	}{
+		{
+			name:      "initial build",
+			wantBuild: true,
+		},
+		{
+			name:      "unchanged inputs are not rebuilt",
+			wantBuild: false,
+		},
+		{
+			name:      "force rebuilds unchanged inputs",
+			force:     true,
+			wantBuild: true,
+		},
+		{
+			name: "source change is rebuilt",
+			setup: func() {
+				err := ioutil.WriteFile(mainFilePath, []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0644)
+				require.NoError(t, err)
+			},
+			wantBuild: true,
+		},
+		{
+			name:      "unchanged inputs are not rebuilt after source change",
+			wantBuild: false,
+		},
+		{
+			name: "build parameter change is rebuilt",
+			setup: func() {
+				productParam.Build.VersionVar = "main.version"
+			},
+			wantBuild: true,
+		},
+		{
+			name: "build arguments script change is rebuilt",
+			setup: func() {
+				productParam.Build.BuildArgsScript = `echo "-a"`
+			},
+			wantBuild: true,
+		},
+		{
+			name:      "unchanged inputs are not rebuilt after parameter changes",
+			wantBuild: false,
+		},
+		{
+			name: "version file change is rebuilt",
+			setup: func() {
+				productParam.Build.VersionFile = &distgo.VersionFileSpec{
+					Dir: "internal/version",
+				}
+			},
+			wantBuild: true,
+		},
+		{
+			name: "unchanged inputs are not rebuilt with equal version file",
+			setup: func() {
+				// use a new value so that the hash does not depend on the address of the version file
+				productParam.Build.VersionFile = &distgo.VersionFileSpec{
+					Dir: "internal/version",
+				}
+			},
+			wantBuild: false,
+		},
+		{
+			name: "import of in-module package is rebuilt",
+			setup: func() {
+				err := os.MkdirAll(path.Join(tmp, "lib"), 0755)
+				require.NoError(t, err)
+				err = ioutil.WriteFile(path.Join(tmp, "lib", "lib.go"), []byte("package lib\n\nfunc Lib() {}\n"), 0644)
+				require.NoError(t, err)
+				err = ioutil.WriteFile(path.Join(tmp, "lib", "lib_prod.go"), []byte("//go:build prod\n\npackage lib\n\nfunc Prod() {}\n"), 0644)
+				require.NoError(t, err)
+				err = ioutil.WriteFile(path.Join(tmp, "lib", "lib_amd64.s"), []byte("// assembly file\n"), 0644)
+				require.NoError(t, err)
+				err = ioutil.WriteFile(mainFilePath, []byte("package main\n\nimport \"foo/lib\"\n\nfunc main() {\n\tlib.Lib()\n}\n"), 0644)
+				require.NoError(t, err)
+			},
+			wantBuild: true,
+		},
+		{
+			name:      "unchanged inputs are not rebuilt after import of in-module package",
+			wantBuild: false,
+		},
+		{
+			name: "change to in-module package without dot in import path is rebuilt",
+			setup: func() {
+				err := ioutil.WriteFile(path.Join(tmp, "lib", "lib.go"), []byte("package lib\n\nfunc Lib() {\n\tprintln()\n}\n"), 0644)
+				require.NoError(t, err)
+			},
+			wantBuild: true,
+		},
+		{
+			name: "change to file excluded by build constraints is not rebuilt",
+			setup: func() {
+				err := ioutil.WriteFile(path.Join(tmp, "lib", "lib_prod.go"), []byte("//go:build prod\n\npackage lib\n\nfunc Prod() {\n\tprintln()\n}\n"), 0644)
+				require.NoError(t, err)
+			},
+			wantBuild: false,
+		},
+		{
+			name: "build tags change is rebuilt",
+			setup: func() {
+				productParam.Build.BuildTags = []string{"prod"}
+			},
+			wantBuild: true,
+		},
+		{
+			name: "change to file included by build tags is rebuilt",
+			setup: func() {
+				err := ioutil.WriteFile(path.Join(tmp, "lib", "lib_prod.go"), []byte("//go:build prod\n\npackage lib\n\nfunc Prod() {}\n"), 0644)
+				require.NoError(t, err)
+			},
+			wantBuild: true,
+		},
+		{
+			name: "change to non-Go source file is rebuilt",
+			setup: func() {
+				err := ioutil.WriteFile(path.Join(tmp, "lib", "lib_amd64.s"), []byte("// modified assembly file\n"), 0644)
+				require.NoError(t, err)
+			},
+			wantBuild: runtime.GOARCH == "amd64",
+		},
+		{
+			name: "missing output is rebuilt",
+			setup: func() {
+				err := os.Remove(path.Join(tmp, "out", "build", "testProduct", "0.1.0", osarch.Current().String(), "testProduct"))
+				require.NoError(t, err)
+			},
+			wantBuild: true,
+		},
+	} {
+		if tc.setup != nil {
+			tc.setup()
+		}
+		buf := &bytes.Buffer{}
+		err := build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+			Force: tc.force,
+		}, buf)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		if tc.wantBuild {
+			assert.Regexp(t, wantBuilt, buf.String(), "Case %d: %s", i, tc.name)
+		} else {
+			assert.Regexp(t, wantUpToDate, buf.String(), "Case %d: %s", i, tc.name)
+			assert.NotRegexp(t, wantBuilt, buf.String(), "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestBuildInvalidOSArchs(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	goBinaryPath, err := exec.LookPath("go")
+	require.NoError(t, err)
+
+	for i, tc := range []struct {
+		name      string
+		osArchs   []osarch.OSArch
+		wantError string
+	}{
+		{
+			name: "valid targets",
+			osArchs: []osarch.OSArch{
+				{OS: "linux", Arch: "amd64"},
+				{OS: "darwin", Arch: "arm64"},
+				{OS: "linux", Arch: "arm-7"},
+			},
+		},
+		{
+			name: "invalid targets",
+			osArchs: []osarch.OSArch{
+				{OS: "linxu", Arch: "amd64"},
+				{OS: "linux", Arch: "amd64"},
+				{OS: "plan10", Arch: "quantum"},
+			},
+			wantError: "os-archs for testProduct contain targets that are not supported by " + goBinaryPath + ": linxu-amd64 (did you mean linux-amd64?), plan10-quantum",
+		},
+		{
+			name: "case variant targets",
+			osArchs: []osarch.OSArch{
+				{OS: "Linux", Arch: "AMD64"},
+			},
+			wantError: "os-archs for testProduct contain targets that are not supported by " + goBinaryPath + ": Linux-AMD64 (did you mean linux-amd64?)",
+		},
+	} {
+		productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+			param.Build.OSArchs = tc.osArchs
+		})
+		err := build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+			DryRun: true,
+		}, ioutil.Discard)
+		if tc.wantError == "" {
+			assert.NoError(t, err, "Case %d: %s", i, tc.name)
+		} else {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestBuildDryRun(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	osArchs := []osarch.OSArch{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "windows", Arch: "amd64"},
+	}
+	scriptOutputPath := path.Join(tmp, "script-output")
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.VersionVar = "main.testVersionVar"
+		param.Build.StripDebug = true
+		param.Build.Environment = map[string]string{
+			"CGO_ENABLED": "0",
+			"A_VAR":       "a",
+		}
+		param.Build.Script = "#!/usr/bin/env bash\ntouch " + scriptOutputPath + "\n"
+		param.Build.OSArchs = osArchs
+	})
+
+	buf := &bytes.Buffer{}
+	err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+		DryRun: true,
+	}, buf)
+	require.NoError(t, err)
+
+	goBinaryPath, err := exec.LookPath("go")
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "[DRY RUN] Run build script for testProduct\n")
+	for _, osArch := range osArchs {
+		outputPath := path.Join(tmp, "out", "build", "testProduct", "0.1.0", osArch.String(), distgo.ExecutableName("testProduct", osArch.OS))
+		wantCmd := fmt.Sprintf("[DRY RUN] Run: %s build -o %s -ldflags -s -w -X main.testVersionVar=0.1.0 . in directory %s with additional environment variables [GOOS=%s GOARCH=%s A_VAR=a CGO_ENABLED=0]\n",
+			goBinaryPath, outputPath, tmp, osArch.OS, osArch.Arch)
+		assert.Contains(t, buf.String(), wantCmd, "OSArch %s", osArch)
+	}
+
+	_, err = os.Stat(path.Join(tmp, "out"))
+	assert.True(t, os.IsNotExist(err), "dry run created output directory")
+	_, err = os.Stat(scriptOutputPath)
+	assert.True(t, os.IsNotExist(err), "dry run executed build script")
+}
+
+func TestBuildSummaryFile(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	osArchs := []osarch.OSArch{
+		{OS: "windows", Arch: "amd64"},
+		{OS: "linux", Arch: "amd64"},
+		{OS: "darwin", Arch: "amd64"},
+	}
+	var productParams []distgo.ProductParam
+	for _, productID := range []distgo.ProductID{"zProduct", "aProduct"} {
+		productParams = append(productParams, createBuildProductParam(func(param *distgo.ProductParam) {
+			param.ID = productID
+			param.Build.OSArchs = osArchs
+		}))
+	}
+	summaryFile := path.Join(tmp, "summary.json")
+
+	err = build.Run(projectInfo, productParams, build.Options{
+		Parallel:    true,
+		SummaryFile: summaryFile,
+	}, ioutil.Discard)
+	require.NoError(t, err)
+
+	summaryBytes, err := ioutil.ReadFile(summaryFile)
+	require.NoError(t, err)
+
+	var summary map[string][]struct {
+		ProductID string                   `json:"productId"`
+		Outputs   []map[string]interface{} `json:"outputs"`
+	}
+	err = json.Unmarshal(summaryBytes, &summary)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(summary), "unexpected keys in summary: %s", string(summaryBytes))
+	products := summary["products"]
+	require.Equal(t, 2, len(products))
+	wantProductIDs := []string{"aProduct", "zProduct"}
+	wantOSArchs := []string{"darwin-amd64", "linux-amd64", "windows-amd64"}
+	for i, product := range products {
+		assert.Equal(t, wantProductIDs[i], product.ProductID)
+		require.Equal(t, len(wantOSArchs), len(product.Outputs))
+		for j, output := range product.Outputs {
+			var keys []string
+			for k := range output {
+				keys = append(keys, k)
+			}
+			assert.ElementsMatch(t, []string{"osArch", "name", "path", "size", "sha256"}, keys, "Product %s output %d", product.ProductID, j)
+
+			wantName := product.ProductID
+			if wantOSArchs[j] == "windows-amd64" {
+				wantName += ".exe"
+			}
+			wantPath := path.Join(tmp, "out", "build", product.ProductID, "0.1.0", wantOSArchs[j], wantName)
+			outputBytes, err := ioutil.ReadFile(wantPath)
+			require.NoError(t, err)
+
+			assert.Equal(t, wantOSArchs[j], output["osArch"], "Product %s output %d", product.ProductID, j)
+			assert.Equal(t, wantName, output["name"], "Product %s output %d", product.ProductID, j)
+			assert.Equal(t, wantPath, output["path"], "Product %s output %d", product.ProductID, j)
+			assert.Equal(t, float64(len(outputBytes)), output["size"], "Product %s output %d", product.ProductID, j)
+			assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(outputBytes)), output["sha256"], "Product %s output %d", product.ProductID, j)
+		}
+	}
+}
+
+func TestBuildScriptExitCodes(t *testing.T) {
+	for i, tc := range []struct {
+		name             string
+		script           string
+		wantBuilt        bool
+		wantSkipped      []string
+		wantOutputPrefix string
+		wantError        string
+	}{
+		{
+			name:             "exit code 0 builds product",
+			script:           "exit 0",
+			wantBuilt:        true,
+			wantOutputPrefix: "Building testProduct for ",
+		},
+		{
+			name:             "exit code 2 skips product",
+			script:           "echo 'codegen not required'\nexit 2",
+			wantSkipped:      []string{"testProduct"},
+			wantOutputPrefix: "codegen not required\nSkipping build for testProduct because its build script exited with code 2\n",
+		},
+		{
+			name:      "other non-zero exit code fails build",
+			script:    "exit 1",
+			wantError: "failed to execute build script: script execution failed: exit status 1",
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err)
+			err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+			require.NoError(t, err)
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+				param.Build.Script = tc.script
+			})
+			summaryFile := path.Join(tmp, "summary.json")
+
+			buffer := &bytes.Buffer{}
+			err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+				SummaryFile: summaryFile,
+			}, buffer)
+			if tc.wantError != "" {
+				require.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+				return
+			}
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			assert.True(t, strings.HasPrefix(buffer.String(), tc.wantOutputPrefix), "Case %d: %s\nOutput: %s", i, tc.name, buffer.String())
+
+			_, err = os.Stat(path.Join(tmp, "out", "build", "testProduct", "0.1.0", osarch.Current().String(), "testProduct"))
+			assert.Equal(t, tc.wantBuilt, err == nil, "Case %d: %s", i, tc.name)
+
+			summaryBytes, err := ioutil.ReadFile(summaryFile)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			var summary struct {
+				Products []interface{} `json:"products"`
+				Skipped  []string      `json:"skipped"`
+			}
+			err = json.Unmarshal(summaryBytes, &summary)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			assert.Equal(t, tc.wantSkipped, summary.Skipped, "Case %d: %s", i, tc.name)
+			assert.Equal(t, tc.wantBuilt, len(summary.Products) == 1, "Case %d: %s", i, tc.name)
+		}()
+	}
+}
+
+func TestBuildCleanOutputDir(t *testing.T) {
+	otherOSArch := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	if osarch.Current() == otherOSArch {
+		otherOSArch = osarch.OSArch{OS: "darwin", Arch: "amd64"}
+	}
+
+	for i, tc := range []struct {
+		name           string
+		cleanOutputDir bool
+		wantStaleFile  bool
+	}{
+		{
+			name:           "stale file is removed if clean-output-dir is true",
+			cleanOutputDir: true,
+			wantStaleFile:  false,
+		},
+		{
+			name:           "stale file is preserved if clean-output-dir is false",
+			cleanOutputDir: false,
+			wantStaleFile:  true,
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err)
+			err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+			require.NoError(t, err)
+
+			outputDir := path.Join(tmp, "out", "build")
+			staleFile := path.Join(outputDir, "testProduct", "0.1.0", osarch.Current().String(), "libtestProduct.so")
+			// files for other OS/architectures, versions and products must never be removed
+			preservedFiles := []string{
+				path.Join(outputDir, "testProduct", "0.1.0", otherOSArch.String(), "testProduct"),
+				path.Join(outputDir, "testProduct", "0.0.9", osarch.Current().String(), "testProduct"),
+				path.Join(outputDir, "otherProduct", "0.1.0", osarch.Current().String(), "otherProduct"),
+			}
+			for _, currFile := range append([]string{staleFile}, preservedFiles...) {
+				require.NoError(t, os.MkdirAll(path.Dir(currFile), 0755), "Case %d: %s", i, tc.name)
+				require.NoError(t, ioutil.WriteFile(currFile, []byte("stale"), 0644), "Case %d: %s", i, tc.name)
+			}
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+				param.Build.CleanOutputDir = tc.cleanOutputDir
+			})
+			err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+			_, err = os.Stat(path.Join(outputDir, "testProduct", "0.1.0", osarch.Current().String(), "testProduct"))
+			assert.NoError(t, err, "Case %d: %s: build output should exist", i, tc.name)
+			_, err = os.Stat(staleFile)
+			assert.Equal(t, tc.wantStaleFile, err == nil, "Case %d: %s: unexpected state for stale file", i, tc.name)
+			for _, currFile := range preservedFiles {
+				_, err = os.Stat(currFile)
+				assert.NoError(t, err, "Case %d: %s: file %s should not have been removed", i, tc.name, currFile)
+			}
+		}()
+	}
+}
+
+func TestBuildMaxOutputBytes(t *testing.T) {
+	for i, tc := range []struct {
+		name           string
+		maxOutputBytes int64
+		wantError      *regexp.Regexp
+	}{
+		{
+			name:           "size is not checked if max-output-bytes is not specified",
+			maxOutputBytes: 0,
+		},
+		{
+			name:           "build succeeds if output is within max-output-bytes",
+			maxOutputBytes: 1 << 40,
+		},
+		{
+			name:           "build fails if output exceeds max-output-bytes",
+			maxOutputBytes: 1,
+			wantError:      regexp.MustCompile(`^build output .+/testProduct for testProduct for ` + regexp.QuoteMeta(osarch.Current().String()) + ` is [0-9]+ bytes, which exceeds the maximum output size of 1 bytes$`),
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err)
+			err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+			require.NoError(t, err)
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+				param.Build.StripDebug = true
+				param.Build.MaxOutputBytes = tc.maxOutputBytes
+			})
+			err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+			outputPath := path.Join(tmp, "out", "build", "testProduct", "0.1.0", osarch.Current().String(), "testProduct")
+			if tc.wantError == nil {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+				_, err = os.Stat(outputPath + ".inputhash")
+				assert.NoError(t, err, "Case %d: %s: input hash should be recorded", i, tc.name)
+				return
+			}
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Regexp(t, tc.wantError, errors.Cause(err).Error(), "Case %d: %s", i, tc.name)
+			_, err = os.Stat(outputPath + ".inputhash")
+			assert.True(t, os.IsNotExist(err), "Case %d: %s: input hash should not be recorded for output that exceeds budget", i, tc.name)
+		}()
+	}
+}
+
+func TestBuildLatestLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links may not be supported")
+	}
+
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	require.NoError(t, err)
+
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.LatestLink = true
+	})
+	latestPath := path.Join(tmp, "out", "build", "testProduct", build.LatestLinkName)
+
+	for _, version := range []string{"0.1.0", "0.2.0"} {
+		projectInfo := distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    version,
+		}
+		err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+		require.NoError(t, err, "Version %s", version)
+
+		target, err := os.Readlink(latestPath)
+		require.NoError(t, err, "Version %s", version)
+		assert.Equal(t, version, target, "Version %s", version)
+
+		_, err = os.Stat(path.Join(latestPath, osarch.Current().String(), "testProduct"))
+		assert.NoError(t, err, "Version %s: build output should be accessible through link", version)
+	}
+}
+
+func TestBuildLatestLinkNotCreatedByDefault(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	err = build.Run(projectInfo, []distgo.ProductParam{createBuildProductParam(nil)}, build.Options{}, ioutil.Discard)
+	require.NoError(t, err)
+
+	_, err = os.Lstat(path.Join(tmp, "out", "build", "testProduct", build.LatestLinkName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBuildVersionFile(t *testing.T) {
+	const (
+		mainFile = `package main
+
+import (
+	"fmt"
+
+	"foo/internal/version"
+)
+
+func main() {
+	fmt.Println(version.Info.Version)
+}
+`
+		infoFile = `package version
+
+type BuildInfo struct {
+	Version string
+}
+`
+		placeholderFile = `package version
+
+var Info = BuildInfo{Version: "unspecified"}
+`
+	)
+
+	for i, tc := range []struct {
+		name        string
+		placeholder bool
+	}{
+		{
+			name: "version file is removed after build",
+		},
+		{
+			name:        "placeholder file is restored after build",
+			placeholder: true,
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+			files := map[string]string{
+				"go.mod":                   "module foo",
+				"main.go":                  mainFile,
+				"internal/version/info.go": infoFile,
+			}
+			if tc.placeholder {
+				files["internal/version/distgo_version.go"] = placeholderFile
+			}
+			for relPath, content := range files {
+				require.NoError(t, os.MkdirAll(path.Dir(path.Join(tmp, relPath)), 0755), "Case %d: %s", i, tc.name)
+				require.NoError(t, ioutil.WriteFile(path.Join(tmp, relPath), []byte(content), 0644), "Case %d: %s", i, tc.name)
+			}
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+				param.Build.VersionFile = &distgo.VersionFileSpec{
+					Dir:      "internal/version",
+					Template: `var Info = BuildInfo{Version: {{printf "%q" Version}}}`,
+				}
+			})
+			err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+			outputPath := path.Join(tmp, "out", "build", "testProduct", "0.1.0", osarch.Current().String(), "testProduct")
+			output, err := exec.Command(outputPath).CombinedOutput()
+			require.NoError(t, err, "Case %d: %s: %s", i, tc.name, string(output))
+			assert.Equal(t, "0.1.0\n", string(output), "Case %d: %s", i, tc.name)
+
+			versionFileContent, err := ioutil.ReadFile(path.Join(tmp, "internal", "version", "distgo_version.go"))
+			if tc.placeholder {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+				assert.Equal(t, placeholderFile, string(versionFileContent), "Case %d: %s", i, tc.name)
+			} else {
+				assert.True(t, os.IsNotExist(err), "Case %d: %s: generated version file should be removed", i, tc.name)
+			}
+		}()
+	}
+}
+
+func TestBuildVersionCheck(t *testing.T) {
+	otherOSArch := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	if osarch.Current() == otherOSArch {
+		otherOSArch = osarch.OSArch{OS: "darwin", Arch: "amd64"}
+	}
+
+	const printVersionMain = `package main
+
+import "fmt"
+
+var version = "unset"
+
+func main() {
+	fmt.Println("foo version", version)
+}
+`
+	const printWrongVersionMain = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("foo version 0.0.1")
+}
+`
+	for i, tc := range []struct {
+		name      string
+		mainFile  string
+		osArch    osarch.OSArch
+		wantError *regexp.Regexp
+	}{
+		{
+			name:     "executable that prints correct version passes check",
+			mainFile: printVersionMain,
+			osArch:   osarch.Current(),
+		},
+		{
+			name:      "executable that prints wrong version fails check",
+			mainFile:  printWrongVersionMain,
+			osArch:    osarch.Current(),
+			wantError: regexp.MustCompile(`^version check of testProduct for ` + regexp.QuoteMeta(osarch.Current().String()) + ` failed: output of command \[.+/testProduct --version\] does not contain version "0\.1\.0":\nfoo version 0\.0\.1$`),
+		},
+		{
+			name:     "executable for non-host OS/architecture is not checked",
+			mainFile: printWrongVersionMain,
+			osArch:   otherOSArch,
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err)
+			err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte(tc.mainFile), 0644)
+			require.NoError(t, err)
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+				param.Build.VersionVar = "main.version"
+				param.Build.VersionCheckArgs = []string{"--version"}
+				param.Build.OSArchs = []osarch.OSArch{tc.osArch}
+			})
+			err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, ioutil.Discard)
+			if tc.wantError == nil {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+				return
+			}
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Regexp(t, tc.wantError, errors.Cause(err).Error(), "Case %d: %s", i, tc.name)
+		}()
+	}
+}
+
+func TestBuildDependencyOrder(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	for _, currPkg := range []string{"gen", "app"} {
+		err = os.MkdirAll(path.Join(tmp, currPkg), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path.Join(tmp, currPkg, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+		require.NoError(t, err)
+	}
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	genParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.ID = "gen"
+		param.Build.MainPkg = "./gen"
+	})
+	appParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.ID = "app"
+		param.Build.MainPkg = "./app"
+		// build script fails if the output of the dependency has not been built
+		param.Build.Script = fmt.Sprintf("test -f out/build/gen/0.1.0/%s/gen", osarch.Current())
+		param.FirstLevelDependencies = []distgo.ProductID{"gen"}
+	})
+
+	buffer := &bytes.Buffer{}
+	// "app" is provided first and would be built first if dependencies were not considered
+	err = build.Run(projectInfo, []distgo.ProductParam{appParam, genParam}, build.Options{
+		Parallel: true,
+	}, buffer)
+	require.NoError(t, err, "Output: %s", buffer.String())
+
+	output := buffer.String()
+	genFinishedIdx := strings.Index(output, "Finished building gen")
+	appStartIdx := strings.Index(output, "Building app")
+	require.True(t, genFinishedIdx != -1 && appStartIdx != -1, "Output: %s", output)
+	assert.True(t, genFinishedIdx < appStartIdx, "dependency should be built before dependent product. Output: %s", output)
+}
+
+func createBuildProductParam(fn func(*distgo.ProductParam)) distgo.ProductParam {
+	param := distgo.ProductParam{
+		ID: "testProduct",
+		Build: &distgo.BuildParam{
+			NameTemplate: "{{Product}}",
+			MainPkg:      ".",
+			OutputDir:    "out/build",
+			OSArchs: []osarch.OSArch{
+				osarch.Current(),
+			},
+		},
+	}
+	if fn != nil {
+		fn(&param)
+	}
+	return param
+}
+
+func stringVar(in string) *string {
+	return &in
+}
+
+func TestProduct(t *testing.T) {
+	const taggedFile = `// +build foo
+
+package main
+
+func init() {
+	testTagVar = "tagged"
+}
+`
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	gittest.InitGitDir(t, tmp)
+	for relPath, content := range map[string]string{
+		"go.mod":    "module foo",
+		"main.go":   "package main\n\nimport \"fmt\"\n\nvar testVersionVar = \"defaultVersion\"\nvar testTagVar = \"untagged\"\n\nfunc main() {\n\tfmt.Println(testVersionVar, testTagVar)\n}\n",
+		"tagged.go": taggedFile,
+	} {
+		require.NoError(t, ioutil.WriteFile(path.Join(tmp, relPath), []byte(content), 0644))
+	}
+	gittest.CommitAllFiles(t, tmp, "Commit")
+	gittest.CreateGitTag(t, tmp, "1.0.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+			"foo": {
+				Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+					MainPkg:    stringVar("."),
+					VersionVar: stringVar("main.testVersionVar"),
+					Environment: &map[string]string{
+						"GOFLAGS": "-tags=foo",
+					},
+					Script: stringVar(`#!/usr/bin/env bash
+echo "$PRODUCT $VERSION" > "$PROJECT_DIR/script-output.txt"
+`),
+				}),
+			},
+			"bar": {
+				Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+					MainPkg: stringVar("."),
+				}),
+			},
+		}),
+	}, tmp, "")
+
+	outputs, err := build.Product(context.Background(), tmp, "foo", projectParam, nil, ioutil.Discard)
+	require.NoError(t, err)
+	require.Len(t, outputs, 1)
+
+	wantPath := path.Join(tmp, "out", "build", "foo", "1.0.0", osarch.Current().String(), "foo")
+	assert.Equal(t, distgo.BuildOSArchID(osarch.Current().String()), outputs[0].OSArch)
+	assert.Equal(t, "foo", outputs[0].Name)
+	assert.Equal(t, wantPath, outputs[0].Path)
+	outputBytes, err := ioutil.ReadFile(wantPath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(outputBytes)), outputs[0].Size)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(outputBytes)), outputs[0].SHA256)
+
+	// version variable is set using ldflags and the build tag is set by the environment
+	output, err := exec.Command(wantPath).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0 tagged\n", string(output))
+
+	scriptOutput, err := ioutil.ReadFile(path.Join(tmp, "script-output.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo 1.0.0\n", string(scriptOutput))
+
+	// only the specified product is built
+	_, err = os.Stat(path.Join(tmp, "out", "build", "bar"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestProductErrors(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	gittest.InitGitDir(t, tmp)
+	require.NoError(t, ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	gittest.CommitAllFiles(t, tmp, "Commit")
+	gittest.CreateGitTag(t, tmp, "1.0.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+			"foo": {
+				Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+					MainPkg: stringVar("."),
+				}),
+			},
+		}),
+	}, tmp, "")
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i, tc := range []struct {
+		name      string
+		ctx       context.Context
+		productID distgo.ProductID
+		wantError string
+	}{
+		{
+			name:      "unknown product",
+			ctx:       context.Background(),
+			productID: "unknown",
+			wantError: "project does not contain product unknown",
+		},
+		{
+			name:      "context is already canceled",
+			ctx:       canceledCtx,
+			productID: "foo",
+			wantError: "build canceled: context canceled",
+		},
+	} {
+		_, err := build.Product(tc.ctx, tmp, tc.productID, projectParam, nil, ioutil.Discard)
+		assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+	}
+	_, err = os.Stat(path.Join(tmp, "out"))
+	assert.True(t, os.IsNotExist(err), "nothing should be built")
 }
@@ -0,0 +1,176 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// inputHashFileSuffix is the suffix of the file that stores the input hash for a build output. The file is written to
+// the same directory as the build output and its name is the name of the build output with this suffix appended.
+const inputHashFileSuffix = ".inputhash"
+
+func inputHashFilePath(outputArtifactPath string) string {
+	return outputArtifactPath + inputHashFileSuffix
+}
+
+// inputHash returns a hash of all of the inputs that affect the output of the provided build unit. The hash covers
+// the output of "go version" for the Go executable used for the build, the build parameters (including the content
+// of BuildArgsScript), the expanded build environment, the product ID, project version and OS/architecture, the go.mod
+// and go.sum files of the project and the content of all of the source files (including Cgo, assembly and embedded
+// files) of the non-standard library packages required to build the main package.
+func inputHash(unit buildUnit) (string, error) {
+	projectDir := unit.productTaskOutputInfo.Project.ProjectDir
+	h := sha256.New()
+
+	goBinaryPath, err := unit.buildParam.GoBinaryPath(projectDir)
+	if err != nil {
+		return "", err
+	}
+	goVersionOutput, err := exec.Command(goBinaryPath, "version").CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to determine Go version: %s", strings.TrimSpace(string(goVersionOutput)))
+	}
+	writeHashEntry(h, "go-version", goVersionOutput)
+	// formatting with %#v prints map entries in sorted key order, so the output is deterministic. Pointer fields are
+	// printed as addresses, so they are cleared and their values are written as separate entries.
+	buildParam := unit.buildParam
+	buildParam.VersionFile = nil
+	writeHashEntry(h, "build-param", []byte(fmt.Sprintf("%#v", buildParam)))
+	if unit.buildParam.VersionFile != nil {
+		writeHashEntry(h, "version-file", []byte(fmt.Sprintf("%#v", *unit.buildParam.VersionFile)))
+	}
+	// the build environment is included separately because its values may reference variables in the environment of
+	// the process
+	writeHashEntry(h, "environment", []byte(fmt.Sprintf("%#v", unit.buildParam.EnvironmentForOSArch(unit.osArch))))
+	writeHashEntry(h, "product", []byte(unit.productTaskOutputInfo.Product.ID))
+	writeHashEntry(h, "version", []byte(unit.productTaskOutputInfo.Project.Version))
+	writeHashEntry(h, "os-arch", []byte(unit.osArch.String()))
+
+	for _, moduleFile := range []string{"go.mod", "go.sum"} {
+		content, err := ioutil.ReadFile(path.Join(unit.buildParam.ModuleDirPath(projectDir), moduleFile))
+		if err != nil && !os.IsNotExist(err) {
+			return "", errors.Wrapf(err, "failed to read %s", moduleFile)
+		}
+		writeHashEntry(h, moduleFile, content)
+	}
+
+	srcFiles, err := sourceFiles(goBinaryPath, unit)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to determine source files")
+	}
+	for _, currFile := range srcFiles {
+		content, err := ioutil.ReadFile(currFile)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read source file")
+		}
+		writeHashEntry(h, currFile, content)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// listedPackage contains the fields of the output of "go list -json" that are used to determine the source files of
+// a package.
+type listedPackage struct {
+	Dir          string
+	Standard     bool
+	GoFiles      []string
+	CgoFiles     []string
+	CFiles       []string
+	CXXFiles     []string
+	MFiles       []string
+	HFiles       []string
+	FFiles       []string
+	SFiles       []string
+	SwigFiles    []string
+	SwigCXXFiles []string
+	SysoFiles    []string
+	EmbedFiles   []string
+}
+
+// sourceFiles returns the sorted paths of all of the source files of the non-standard library packages that are
+// required to build the main package of the provided build unit. The packages are loaded using "go list" with the Go
+// executable, environment and package loading flags (including the build tags) of the build, so files that are
+// excluded by build constraints are only omitted if they are also omitted from the build. Standard library packages
+// are identified using the "Standard" field reported by "go list" rather than by their import path, so in-module
+// packages whose import path does not contain a dot are included.
+func sourceFiles(goBinaryPath string, unit buildUnit) ([]string, error) {
+	args := []string{"list", "-deps", "-json"}
+	args = append(args, unit.buildParam.PackageLoadFlags()...)
+	args = append(args, unit.buildParam.MainPkgForOSArch(unit.osArch))
+	cmd := exec.Command(goBinaryPath, args...)
+	cmd.Dir = unit.buildParam.ModuleDirPath(unit.productTaskOutputInfo.Project.ProjectDir)
+	cmd.Env = append(os.Environ(), buildEnvironment(unit)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list packages: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var files []string
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var pkg listedPackage
+		if err := decoder.Decode(&pkg); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse output of go list")
+		}
+		if pkg.Standard {
+			continue
+		}
+		for _, pkgFiles := range [][]string{
+			pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.MFiles, pkg.HFiles, pkg.FFiles, pkg.SFiles,
+			pkg.SwigFiles, pkg.SwigCXXFiles, pkg.SysoFiles, pkg.EmbedFiles,
+		} {
+			for _, currFile := range pkgFiles {
+				files = append(files, path.Join(pkg.Dir, currFile))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// writeHashEntry writes the provided key and value to the provided writer. Lengths are written before the key and
+// value so that distinct entries cannot produce the same input.
+func writeHashEntry(w io.Writer, key string, value []byte) {
+	_, _ = fmt.Fprintf(w, "%d:%s%d:", len(key), key, len(value))
+	_, _ = w.Write(value)
+}
+
+// upToDate returns true if the build output at the provided path exists and the input hash stored for it matches the
+// provided hash.
+func upToDate(outputArtifactPath, hash string) bool {
+	if _, err := os.Stat(outputArtifactPath); err != nil {
+		return false
+	}
+	storedHash, err := ioutil.ReadFile(inputHashFilePath(outputArtifactPath))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(storedHash)) == hash
+}
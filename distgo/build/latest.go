@@ -0,0 +1,73 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+// LatestLinkName is the name of the entry in the "{{OutputDir}}/{{ID}}" directory of a product that refers to the
+// version directory of the most recent build of the product if distgo.BuildParam.LatestLink is true.
+const LatestLinkName = "latest"
+
+// updateLatestLinks updates the LatestLinkName entry for each of the provided products whose build parameters specify
+// LatestLink so that it refers to the build output directory of the current version of the product.
+func updateLatestLinks(productParams []distgo.ProductParam, productTaskOutputInfos map[distgo.ProductID]distgo.ProductTaskOutputInfo, dryRun bool, stdout io.Writer) error {
+	for _, currProductParam := range productParams {
+		if currProductParam.Build == nil || !currProductParam.Build.LatestLink {
+			continue
+		}
+		outputInfo := productTaskOutputInfos[currProductParam.ID]
+		versionDir := outputInfo.ProductBuildOutputDir()
+		if dryRun {
+			distgo.DryRunPrintln(stdout, fmt.Sprintf("Update %s to refer to %s", path.Join(path.Dir(versionDir), LatestLinkName), versionDir))
+			continue
+		}
+		if err := updateLatestLink(versionDir, os.Symlink); err != nil {
+			return errors.Wrapf(err, "failed to update %s link for %s", LatestLinkName, currProductParam.ID)
+		}
+	}
+	return nil
+}
+
+// updateLatestLink creates or replaces the LatestLinkName entry in the parent of the provided version directory so that
+// it refers to the version directory. The entry is created as a relative symbolic link using the provided symlink
+// function. If the symbolic link cannot be created, a regular file whose content is the name of the version directory
+// is written instead. The new entry is created at a temporary path and renamed so that the existing entry is replaced
+// atomically.
+func updateLatestLink(versionDir string, symlink func(oldname, newname string) error) error {
+	latestPath := path.Join(path.Dir(versionDir), LatestLinkName)
+	tmpPath := latestPath + ".tmp"
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove %s", tmpPath)
+	}
+	if err := symlink(path.Base(versionDir), tmpPath); err != nil {
+		// symbolic links are not supported: fall back to a pointer file
+		if err := ioutil.WriteFile(tmpPath, []byte(path.Base(versionDir)+"\n"), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", tmpPath)
+		}
+	}
+	if err := os.Rename(tmpPath, latestPath); err != nil {
+		return errors.Wrapf(err, "failed to rename %s to %s", tmpPath, latestPath)
+	}
+	return nil
+}
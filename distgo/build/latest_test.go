@@ -0,0 +1,70 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateLatestLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links may not be supported")
+	}
+
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	latestPath := path.Join(tmp, LatestLinkName)
+	for _, version := range []string{"0.1.0", "0.2.0"} {
+		require.NoError(t, updateLatestLink(path.Join(tmp, version), os.Symlink), "Version %s", version)
+
+		target, err := os.Readlink(latestPath)
+		require.NoError(t, err, "Version %s", version)
+		assert.Equal(t, version, target, "Version %s", version)
+	}
+}
+
+func TestUpdateLatestLinkPointerFileFallback(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	failingSymlink := func(oldname, newname string) error {
+		return errors.New("symbolic links are not supported")
+	}
+
+	latestPath := path.Join(tmp, LatestLinkName)
+	for _, version := range []string{"0.1.0", "0.2.0"} {
+		require.NoError(t, updateLatestLink(path.Join(tmp, version), failingSymlink), "Version %s", version)
+
+		fi, err := os.Lstat(latestPath)
+		require.NoError(t, err, "Version %s", version)
+		assert.True(t, fi.Mode().IsRegular(), "Version %s: expected regular file, was %s", version, fi.Mode())
+
+		content, err := ioutil.ReadFile(latestPath)
+		require.NoError(t, err, "Version %s", version)
+		assert.Equal(t, version+"\n", string(content), "Version %s", version)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+)
+
+const maxOSArchSuggestionDistance = 3
+
+var (
+	supportedTargetsMu sync.Mutex
+	// supportedTargets caches the targets supported by a Go executable keyed by the path to the executable. A nil value
+	// indicates that the supported targets could not be determined.
+	supportedTargets = make(map[string]map[string]struct{})
+)
+
+// validateOSArchs returns an error if any of the provided OS/architectures are not supported by the provided Go
+// executable. The returned error lists the invalid entries and the closest supported targets for each entry. The
+// variant of an architecture is not considered, as it is validated when the OSArch is parsed. If the supported targets
+// cannot be determined (for example, if the Go executable does not support "go tool dist list"), no validation is
+// performed.
+func validateOSArchs(goBinaryPath string, productID distgo.ProductID, osArchs []osarch.OSArch) error {
+	targets := goSupportedTargets(goBinaryPath)
+	if targets == nil {
+		return nil
+	}
+
+	var invalid []string
+	for _, currOSArch := range osArchs {
+		goarch, _ := distgo.GOARCHAndVariant(currOSArch)
+		target := currOSArch.OS + "-" + goarch
+		if _, ok := targets[target]; ok {
+			continue
+		}
+		msg := currOSArch.String()
+		if suggestions := closestTargets(target, targets); len(suggestions) > 0 {
+			msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(suggestions, " or "))
+		}
+		invalid = append(invalid, msg)
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("os-archs for %s contain targets that are not supported by %s: %s", productID, goBinaryPath, strings.Join(invalid, ", "))
+}
+
+// goSupportedTargets returns the "GOOS-GOARCH" targets supported by the provided Go executable as reported by
+// "go tool dist list". The result is cached for the lifetime of the process. Returns nil if the targets cannot be
+// determined.
+func goSupportedTargets(goBinaryPath string) map[string]struct{} {
+	supportedTargetsMu.Lock()
+	defer supportedTargetsMu.Unlock()
+
+	if targets, ok := supportedTargets[goBinaryPath]; ok {
+		return targets
+	}
+	var targets map[string]struct{}
+	if output, err := exec.Command(goBinaryPath, "tool", "dist", "list").Output(); err == nil {
+		targets = make(map[string]struct{})
+		for _, line := range strings.Split(string(output), "\n") {
+			parts := strings.Split(strings.TrimSpace(line), "/")
+			if len(parts) != 2 {
+				continue
+			}
+			targets[parts[0]+"-"+parts[1]] = struct{}{}
+		}
+		if len(targets) == 0 {
+			targets = nil
+		}
+	}
+	supportedTargets[goBinaryPath] = targets
+	return targets
+}
+
+// closestTargets returns the supported targets that are closest to the provided target in sorted order. Targets are
+// compared case-insensitively, and only targets within maxOSArchSuggestionDistance edits are returned.
+func closestTargets(target string, targets map[string]struct{}) []string {
+	target = strings.ToLower(target)
+	minDistance := maxOSArchSuggestionDistance + 1
+	var closest []string
+	for currTarget := range targets {
+		distance := levenshteinDistance(target, currTarget)
+		switch {
+		case distance < minDistance:
+			minDistance = distance
+			closest = []string{currTarget}
+		case distance == minDistance:
+			closest = append(closest, currTarget)
+		}
+	}
+	sort.Strings(closest)
+	return closest
+}
+
+func levenshteinDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(first int, rest ...int) int {
+	min := first
+	for _, v := range rest {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
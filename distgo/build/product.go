@@ -0,0 +1,69 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"context"
+	"io"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+// Product builds the product with the provided ID in the project in the provided directory and returns the summary of
+// each of its build outputs sorted by OS/architecture. It is intended for programs that use distgo as a library: the
+// build is performed in the same manner as the "build" task (the build script, environment, ldflags and all of the
+// other build parameters of the product are honored and outputs that are up-to-date are not rebuilt), but the outputs
+// are returned rather than printed. Progress messages are written to stdout.
+//
+// If osArchs is non-empty, only the specified OS/architectures of the product are built. Otherwise, all of the
+// OS/architectures specified by the build parameters of the product are built. Returns an empty slice if the product
+// does not specify a main package or if its build script exited with distgo.BuildScriptSkipExitCode. The build is
+// canceled if the provided context is done (refer to RunWithContext).
+func Product(ctx context.Context, projectDir string, productID distgo.ProductID, projectParam distgo.ProjectParam, osArchs []osarch.OSArch, stdout io.Writer) ([]OutputSummary, error) {
+	productParam, ok := projectParam.Products[productID]
+	if !ok {
+		return nil, errors.Errorf("project does not contain product %s", productID)
+	}
+	if productParam.Build == nil {
+		return nil, errors.Errorf("product %s does not specify build parameters", productID)
+	}
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	productParams, err := distgo.ProductParamsForBuildProductArgs(map[distgo.ProductID]distgo.ProductParam{
+		productID: productParam,
+	}, osArchs)
+	if err != nil {
+		return nil, err
+	}
+	builtProductParams, _, err := run(ctx, projectInfo, productParams, Options{
+		Parallel: true,
+	}, stdout)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := NewSummary(projectInfo, builtProductParams)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []OutputSummary{}
+	for _, currProductSummary := range summary.Products {
+		outputs = append(outputs, currProductSummary.Outputs...)
+	}
+	return outputs, nil
+}
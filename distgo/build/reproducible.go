@@ -0,0 +1,191 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+// VerifyReproducibleProducts verifies that the products specified by productBuildIDs are reproducible. See
+// VerifyReproducible for more information.
+func VerifyReproducibleProducts(ctx context.Context, projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productBuildIDs []distgo.ProductBuildID, stdout io.Writer) error {
+	productParams, err := distgo.ProductParamsForBuildProductArgs(projectParam.Products, nil, productBuildIDs...)
+	if err != nil {
+		return err
+	}
+	return VerifyReproducible(ctx, projectInfo, productParams, stdout)
+}
+
+// VerifyReproducible verifies that the provided products are reproducible by building each of them twice into separate
+// temporary output directories and comparing the resulting build outputs byte-for-byte. Both builds are run with
+// "-trimpath" and with SOURCE_DATE_EPOCH set so that the build time (see distgo.ProjectInfo.BuildTime) is the same for
+// both builds: if SOURCE_DATE_EPOCH is not set for the project, the builds use the current time as its value. Outputs
+// are always rebuilt, and the existing build outputs of the products are not modified.
+//
+// Returns an error that describes all of the build outputs that differ between the builds, including the offset of the
+// first byte at which they differ. Products that are skipped by their build script and products that do not create
+// build artifacts are not verified.
+func VerifyReproducible(ctx context.Context, projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, stdout io.Writer) error {
+	if _, ok := projectInfo.LookupSourceDateEpoch(); !ok {
+		projectInfo.SourceDateEpoch = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "distgo-reproducible-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary directory")
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	var builtProductParams [2][]distgo.ProductParam
+	for i := range builtProductParams {
+		outputDir := filepath.Join(tmpDir, strconv.Itoa(i))
+		builtProductParams[i], _, err = run(ctx, projectInfo, reproducibleProductParams(productParams, outputDir), Options{
+			Parallel: true,
+			Force:    true,
+		}, stdout)
+		if err != nil {
+			return err
+		}
+	}
+
+	verifyErrs := make(map[distgo.ProductBuildID]string)
+	nVerified := 0
+	secondBuildProductParams := make(map[distgo.ProductID]distgo.ProductParam)
+	for _, currProductParam := range builtProductParams[1] {
+		secondBuildProductParams[currProductParam.ID] = currProductParam
+	}
+	for _, currProductParam := range builtProductParams[0] {
+		if currProductParam.Build == nil {
+			continue
+		}
+		secondBuildProductParam, ok := secondBuildProductParams[currProductParam.ID]
+		if !ok {
+			verifyErrs[distgo.ProductBuildID(currProductParam.ID)] = "product was built by the first build but not by the second build"
+			continue
+		}
+		var artifactPaths [2]map[osarch.OSArch]string
+		for j, currBuiltProductParam := range []distgo.ProductParam{currProductParam, secondBuildProductParam} {
+			productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, currBuiltProductParam)
+			if err != nil {
+				return errors.Wrapf(err, "failed to compute output information for %s", currBuiltProductParam.ID)
+			}
+			artifactPaths[j] = productTaskOutputInfo.ProductBuildArtifactPaths()
+		}
+		for _, currOSArch := range currProductParam.Build.OSArchs {
+			artifactPath, ok := artifactPaths[0][currOSArch]
+			if !ok {
+				continue
+			}
+			productBuildID := summaryProductBuildID(currProductParam.ID, distgo.BuildOSArchID(currOSArch.String()))
+			offset, differ, err := firstDifferingOffset(artifactPath, artifactPaths[1][currOSArch])
+			if err != nil {
+				verifyErrs[productBuildID] = err.Error()
+				continue
+			}
+			if differ {
+				verifyErrs[productBuildID] = fmt.Sprintf("build outputs differ starting at byte offset %d", offset)
+				continue
+			}
+			nVerified++
+		}
+	}
+
+	if len(verifyErrs) > 0 {
+		var ids []string
+		for id := range verifyErrs {
+			ids = append(ids, string(id))
+		}
+		sort.Strings(ids)
+		var parts []string
+		for _, id := range ids {
+			parts = append(parts, fmt.Sprintf("  %s: %s", id, verifyErrs[distgo.ProductBuildID(id)]))
+		}
+		return errors.Errorf("%d build outputs are not reproducible:\n%s", len(ids), strings.Join(parts, "\n"))
+	}
+	_, _ = fmt.Fprintf(stdout, "Verified that %d build outputs are reproducible\n", nVerified)
+	return nil
+}
+
+// reproducibleProductParams returns copies of the provided product parameters whose builds write their outputs to the
+// provided output directory and use "-trimpath".
+func reproducibleProductParams(productParams []distgo.ProductParam, outputDir string) []distgo.ProductParam {
+	out := make([]distgo.ProductParam, len(productParams))
+	for i, currProductParam := range productParams {
+		if currProductParam.Build != nil {
+			buildParam := *currProductParam.Build
+			buildParam.OutputDir = outputDir
+			buildParam.Trimpath = true
+			currProductParam.Build = &buildParam
+		}
+		out[i] = currProductParam
+	}
+	return out
+}
+
+// firstDifferingOffset compares the content of the provided files and returns the offset of the first byte at which
+// they differ and true if they differ. If one file is a prefix of the other, the offset is the length of the shorter
+// file.
+func firstDifferingOffset(pathA, pathB string) (int64, bool, error) {
+	fA, err := os.Open(pathA)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "failed to open build output")
+	}
+	defer func() {
+		_ = fA.Close()
+	}()
+	fB, err := os.Open(pathB)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "failed to open build output")
+	}
+	defer func() {
+		_ = fB.Close()
+	}()
+
+	rA, rB := bufio.NewReader(fA), bufio.NewReader(fB)
+	var offset int64
+	for {
+		a, errA := rA.ReadByte()
+		b, errB := rB.ReadByte()
+		if errA != nil && errA != io.EOF {
+			return 0, false, errors.Wrapf(errA, "failed to read build output %s", pathA)
+		}
+		if errB != nil && errB != io.EOF {
+			return 0, false, errors.Wrapf(errB, "failed to read build output %s", pathB)
+		}
+		if errA == io.EOF || errB == io.EOF {
+			return offset, errA != errB, nil
+		}
+		if a != b {
+			return offset, true, nil
+		}
+		offset++
+	}
+}
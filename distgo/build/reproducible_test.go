@@ -0,0 +1,110 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/distgo/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyReproducible(t *testing.T) {
+	origSourceDateEpoch, origSourceDateEpochSet := os.LookupEnv(distgo.SourceDateEpochEnvVar)
+
+	for i, tc := range []struct {
+		name         string
+		modify       func(param *distgo.ProductParam)
+		wantOutput   string
+		wantErrorRgx string
+	}{
+		{
+			name: "reproducible product",
+			modify: func(param *distgo.ProductParam) {
+				param.Build.VersionVar = "main.testVersionVar"
+			},
+			wantOutput: "Verified that 1 build outputs are reproducible\n",
+		},
+		{
+			name: "product that embeds the build time is reproducible",
+			modify: func(param *distgo.ProductParam) {
+				param.Build.VersionVar = "main.testVersionVar"
+				param.Build.VersionVarValueTemplate = "{{BuildTime}}"
+			},
+			wantOutput: "Verified that 1 build outputs are reproducible\n",
+		},
+		{
+			name: "product that embeds SOURCE_DATE_EPOCH from the build arguments script is reproducible",
+			modify: func(param *distgo.ProductParam) {
+				param.Build.BuildArgsScript = `echo "-ldflags"
+echo "-X main.testVersionVar=$SOURCE_DATE_EPOCH"`
+			},
+			wantOutput: "Verified that 1 build outputs are reproducible\n",
+		},
+		{
+			name: "product that embeds the clock is not reproducible",
+			modify: func(param *distgo.ProductParam) {
+				param.Build.BuildArgsScript = `echo "-ldflags"
+echo "-X main.testVersionVar=$(date +%s%N)"`
+			},
+			wantErrorRgx: `^1 build outputs are not reproducible:\n  testProduct\.[a-z0-9]+-[a-z0-9]+: build outputs differ starting at byte offset [0-9]+$`,
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err)
+			err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte(testMain), 0644)
+			require.NoError(t, err)
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(tc.modify)
+
+			buffer := &bytes.Buffer{}
+			err = build.VerifyReproducible(context.Background(), projectInfo, []distgo.ProductParam{productParam}, buffer)
+			if tc.wantErrorRgx == "" {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+				assert.Contains(t, buffer.String(), tc.wantOutput, "Case %d: %s", i, tc.name)
+			} else {
+				require.Error(t, err, "Case %d: %s", i, tc.name)
+				assert.Regexp(t, regexp.MustCompile(tc.wantErrorRgx), err.Error(), "Case %d: %s", i, tc.name)
+			}
+
+			// verification does not write to the output directory of the product
+			_, err = os.Stat(path.Join(tmp, "out"))
+			assert.True(t, os.IsNotExist(err), "Case %d: %s", i, tc.name)
+
+			// verification does not modify the environment of the process
+			sourceDateEpoch, sourceDateEpochSet := os.LookupEnv(distgo.SourceDateEpochEnvVar)
+			assert.Equal(t, origSourceDateEpochSet, sourceDateEpochSet, "Case %d: %s", i, tc.name)
+			assert.Equal(t, origSourceDateEpoch, sourceDateEpoch, "Case %d: %s", i, tc.name)
+		}()
+	}
+}
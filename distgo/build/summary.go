@@ -0,0 +1,142 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+// Summary is a machine-readable description of the outputs of a build.
+type Summary struct {
+	// Products contains the summary for each product that was built, sorted by ProductID.
+	Products []ProductSummary `json:"products"`
+	// Skipped contains the IDs of the products that were not built because their build script exited with
+	// distgo.BuildScriptSkipExitCode.
+	Skipped []distgo.ProductID `json:"skipped,omitempty"`
+}
+
+// ProductSummary describes the build outputs for a single product.
+type ProductSummary struct {
+	ProductID distgo.ProductID `json:"productId"`
+	// Outputs contains the summary for each OS/architecture that was built, sorted by BuildOSArchID.
+	Outputs []OutputSummary `json:"outputs"`
+}
+
+// OutputSummary describes the build output for a single OS/architecture of a product.
+type OutputSummary struct {
+	OSArch distgo.BuildOSArchID `json:"osArch"`
+	// Name is the rendered name of the build output.
+	Name string `json:"name"`
+	// Path is the absolute path to the build output.
+	Path string `json:"path"`
+	// Size is the size of the build output in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex-encoded SHA-256 checksum of the build output.
+	SHA256 string `json:"sha256"`
+}
+
+// NewSummary returns the Summary for the build outputs of the provided products. Only the OS/architectures specified
+// in the build parameters of the products are included. Returns an error if any of the build outputs do not exist.
+func NewSummary(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam) (Summary, error) {
+	summary := Summary{
+		Products: []ProductSummary{},
+	}
+	for _, currProductParam := range productParams {
+		if currProductParam.Build == nil {
+			continue
+		}
+		productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, currProductParam)
+		if err != nil {
+			return Summary{}, errors.Wrapf(err, "failed to compute output information for %s", currProductParam.ID)
+		}
+		artifactPaths := productTaskOutputInfo.ProductBuildArtifactPaths()
+
+		productSummary := ProductSummary{
+			ProductID: currProductParam.ID,
+			Outputs:   []OutputSummary{},
+		}
+		var osArchIDs []distgo.BuildOSArchID
+		osArchIDToPath := make(map[distgo.BuildOSArchID]string)
+		osArchIDToName := make(map[distgo.BuildOSArchID]string)
+		for _, currOSArch := range currProductParam.Build.OSArchs {
+			osArchID := distgo.BuildOSArchID(currOSArch.String())
+			osArchIDs = append(osArchIDs, osArchID)
+			osArchIDToPath[osArchID] = artifactPaths[currOSArch]
+			osArchIDToName[osArchID] = productTaskOutputInfo.Product.BuildOutputInfo.ArtifactName(currOSArch)
+		}
+		sort.Sort(distgo.ByBuildOSArchID(osArchIDs))
+
+		for _, osArchID := range osArchIDs {
+			outputSummary, err := newOutputSummary(osArchID, osArchIDToName[osArchID], osArchIDToPath[osArchID])
+			if err != nil {
+				return Summary{}, errors.Wrapf(err, "failed to create summary for %s", summaryProductBuildID(currProductParam.ID, osArchID))
+			}
+			productSummary.Outputs = append(productSummary.Outputs, outputSummary)
+		}
+		summary.Products = append(summary.Products, productSummary)
+	}
+	sort.Slice(summary.Products, func(i, j int) bool {
+		return summary.Products[i].ProductID < summary.Products[j].ProductID
+	})
+	return summary, nil
+}
+
+func newOutputSummary(osArchID distgo.BuildOSArchID, name, artifactPath string) (OutputSummary, error) {
+	absPath, err := filepath.Abs(artifactPath)
+	if err != nil {
+		return OutputSummary{}, errors.Wrapf(err, "failed to determine absolute path for %s", artifactPath)
+	}
+	f, err := os.Open(absPath)
+	if err != nil {
+		return OutputSummary{}, errors.Wrapf(err, "failed to open build output")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return OutputSummary{}, errors.Wrapf(err, "failed to compute checksum of build output")
+	}
+	return OutputSummary{
+		OSArch: osArchID,
+		Name:   name,
+		Path:   absPath,
+		Size:   size,
+		SHA256: fmt.Sprintf("%x", h.Sum(nil)),
+	}, nil
+}
+
+// WriteSummaryFile writes the JSON representation of the provided Summary to the provided path.
+func WriteSummaryFile(summary Summary, summaryFile string) error {
+	summaryBytes, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal build summary")
+	}
+	if err := ioutil.WriteFile(summaryFile, append(summaryBytes, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write build summary to %s", summaryFile)
+	}
+	return nil
+}
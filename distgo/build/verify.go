@@ -0,0 +1,151 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+// ReadSummaryFile reads the Summary from the provided JSON file. The file is typically one written by WriteSummaryFile.
+func ReadSummaryFile(summaryFile string) (Summary, error) {
+	summaryBytes, err := ioutil.ReadFile(summaryFile)
+	if err != nil {
+		return Summary{}, errors.Wrapf(err, "failed to read build summary from %s", summaryFile)
+	}
+	var summary Summary
+	if err := json.Unmarshal(summaryBytes, &summary); err != nil {
+		return Summary{}, errors.Wrapf(err, "failed to unmarshal build summary from %s", summaryFile)
+	}
+	return summary, nil
+}
+
+// VerifyProducts verifies that the existing build outputs of the products specified by productBuildIDs match the
+// checksums recorded in the provided build summary file. See Verify for more information.
+func VerifyProducts(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productBuildIDs []distgo.ProductBuildID, summaryFile string, stdout io.Writer) error {
+	productParams, err := distgo.ProductParamsForBuildProductArgs(projectParam.Products, nil, productBuildIDs...)
+	if err != nil {
+		return err
+	}
+	summary, err := ReadSummaryFile(summaryFile)
+	if err != nil {
+		return err
+	}
+	return Verify(projectInfo, productParams, summary, stdout)
+}
+
+// Verify verifies that the existing build outputs of the provided products match the SHA-256 checksums recorded in the
+// provided Summary without building any of the products. The build outputs are located using the build output
+// information of the products, so the paths recorded in the summary are not used. Returns an error that describes all
+// of the build outputs that are missing, that do not match the recorded checksum or whose checksum was not recorded in
+// the summary. Products that are recorded as skipped in the summary and products that do not create build artifacts
+// are not verified.
+func Verify(projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, summary Summary, stdout io.Writer) error {
+	checksums := make(map[distgo.ProductBuildID]string)
+	for _, productSummary := range summary.Products {
+		for _, outputSummary := range productSummary.Outputs {
+			checksums[summaryProductBuildID(productSummary.ProductID, outputSummary.OSArch)] = outputSummary.SHA256
+		}
+	}
+	skipped := make(map[distgo.ProductID]struct{})
+	for _, productID := range summary.Skipped {
+		skipped[productID] = struct{}{}
+	}
+
+	verifyErrs := make(map[distgo.ProductBuildID]string)
+	nVerified := 0
+	for _, currProductParam := range productParams {
+		if currProductParam.Build == nil {
+			continue
+		}
+		if _, ok := skipped[currProductParam.ID]; ok {
+			continue
+		}
+		productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, currProductParam)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compute output information for %s", currProductParam.ID)
+		}
+		artifactPaths := productTaskOutputInfo.ProductBuildArtifactPaths()
+		for _, currOSArch := range currProductParam.Build.OSArchs {
+			artifactPath, ok := artifactPaths[currOSArch]
+			if !ok {
+				continue
+			}
+			productBuildID := summaryProductBuildID(currProductParam.ID, distgo.BuildOSArchID(currOSArch.String()))
+			wantChecksum, ok := checksums[productBuildID]
+			if !ok {
+				verifyErrs[productBuildID] = "no checksum recorded in build summary"
+				continue
+			}
+			gotChecksum, err := sha256Checksum(artifactPath)
+			if err != nil {
+				if os.IsNotExist(errors.Cause(err)) {
+					verifyErrs[productBuildID] = fmt.Sprintf("build output %s does not exist", artifactPath)
+				} else {
+					verifyErrs[productBuildID] = err.Error()
+				}
+				continue
+			}
+			if gotChecksum != wantChecksum {
+				verifyErrs[productBuildID] = fmt.Sprintf("SHA-256 checksum of build output %s is %s, but build summary records %s", artifactPath, gotChecksum, wantChecksum)
+				continue
+			}
+			nVerified++
+		}
+	}
+
+	if len(verifyErrs) > 0 {
+		var ids []string
+		for id := range verifyErrs {
+			ids = append(ids, string(id))
+		}
+		sort.Strings(ids)
+		var parts []string
+		for _, id := range ids {
+			parts = append(parts, fmt.Sprintf("  %s: %s", id, verifyErrs[distgo.ProductBuildID(id)]))
+		}
+		return errors.Errorf("%d build outputs failed verification:\n%s", len(ids), strings.Join(parts, "\n"))
+	}
+	_, _ = fmt.Fprintf(stdout, "Verified %d build outputs\n", nVerified)
+	return nil
+}
+
+func summaryProductBuildID(productID distgo.ProductID, osArchID distgo.BuildOSArchID) distgo.ProductBuildID {
+	return distgo.ProductBuildID(fmt.Sprintf("%s.%s", productID, osArchID))
+}
+
+func sha256Checksum(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open build output")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "failed to compute checksum of build output")
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/distgo/build"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	darwinAMD64 := osarch.OSArch{OS: "darwin", Arch: "amd64"}
+
+	for i, tc := range []struct {
+		name   string
+		modify func(outputDir string)
+		// wantError is a function that takes the build output directory of the product and the recorded build summary
+		// and returns the expected error. If nil, verification is expected to succeed.
+		wantError func(outputDir string, summary build.Summary) string
+	}{
+		{
+			name:   "intact build outputs",
+			modify: func(outputDir string) {},
+		},
+		{
+			name: "missing build output",
+			modify: func(outputDir string) {
+				require.NoError(t, os.Remove(path.Join(outputDir, "linux-amd64", "testProduct")))
+			},
+			wantError: func(outputDir string, summary build.Summary) string {
+				return fmt.Sprintf("1 build outputs failed verification:\n  testProduct.linux-amd64: build output %s does not exist", path.Join(outputDir, "linux-amd64", "testProduct"))
+			},
+		},
+		{
+			name: "tampered build outputs",
+			modify: func(outputDir string) {
+				for _, osArch := range []string{"darwin-amd64", "linux-amd64"} {
+					require.NoError(t, ioutil.WriteFile(path.Join(outputDir, osArch, "testProduct"), []byte("tampered"), 0755))
+				}
+			},
+			wantError: func(outputDir string, summary build.Summary) string {
+				want := "2 build outputs failed verification:"
+				for _, output := range summary.Products[0].Outputs {
+					want += fmt.Sprintf("\n  testProduct.%s: SHA-256 checksum of build output %s is %x, but build summary records %s", output.OSArch, path.Join(outputDir, string(output.OSArch), "testProduct"), sha256.Sum256([]byte("tampered")), output.SHA256)
+				}
+				return want
+			},
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+
+			err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err)
+			err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+			require.NoError(t, err)
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "0.1.0",
+			}
+			productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+				param.Build.OSArchs = []osarch.OSArch{linuxAMD64, darwinAMD64}
+			})
+			summaryFile := path.Join(tmp, "summary.json")
+			err = build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{
+				SummaryFile: summaryFile,
+			}, ioutil.Discard)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+			outputDir := path.Join(tmp, "out", "build", "testProduct", "0.1.0")
+			tc.modify(outputDir)
+
+			summary, err := build.ReadSummaryFile(summaryFile)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+			buffer := &bytes.Buffer{}
+			err = build.Verify(projectInfo, []distgo.ProductParam{productParam}, summary, buffer)
+			if tc.wantError == nil {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+				assert.Equal(t, "Verified 2 build outputs\n", buffer.String(), "Case %d: %s", i, tc.name)
+				return
+			}
+			assert.EqualError(t, err, tc.wantError(outputDir, summary), "Case %d: %s", i, tc.name)
+		}()
+	}
+}
+
+func TestVerifyChecksumNotRecorded(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+	productParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.Build.OSArchs = []osarch.OSArch{{OS: "linux", Arch: "amd64"}}
+	})
+	skippedProductParam := createBuildProductParam(func(param *distgo.ProductParam) {
+		param.ID = "skippedProduct"
+	})
+
+	err = build.Verify(projectInfo, []distgo.ProductParam{productParam, skippedProductParam}, build.Summary{
+		Skipped: []distgo.ProductID{"skippedProduct"},
+	}, ioutil.Discard)
+	assert.EqualError(t, err, "1 build outputs failed verification:\n  testProduct.linux-amd64: no checksum recorded in build summary")
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+// generatedVersionFile records a version file that was written before a build so that the file that existed at its
+// path (if any) can be restored after the build.
+type generatedVersionFile struct {
+	path string
+	// original is the content of the file that existed at path before the version file was written. Nil if no file
+	// existed.
+	original []byte
+	mode     os.FileMode
+}
+
+// restore restores the file that existed at the path of the generated version file before it was written, or removes
+// the generated version file if no file existed.
+func (f generatedVersionFile) restore() error {
+	if f.original == nil {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove version file %s", f.path)
+		}
+		return nil
+	}
+	if err := ioutil.WriteFile(f.path, f.original, f.mode); err != nil {
+		return errors.Wrapf(err, "failed to restore %s", f.path)
+	}
+	return nil
+}
+
+// writeVersionFile writes the version file specified by the build parameters of the provided product. If a file
+// already exists at the path of the version file (for example, a committed placeholder that allows the package to be
+// compiled and tested without distgo), its content is recorded in the returned generatedVersionFile so that it can be
+// restored after the build. Returns nil if the product does not specify a version file.
+func writeVersionFile(productParam distgo.ProductParam, productTaskOutputInfo distgo.ProductTaskOutputInfo, dryRun bool, stdout io.Writer) (*generatedVersionFile, error) {
+	if productParam.Build == nil || productParam.Build.VersionFile == nil {
+		return nil, nil
+	}
+	moduleDir := productParam.Build.ModuleDirPath(productTaskOutputInfo.Project.ProjectDir)
+	versionFilePath := productParam.Build.VersionFile.Path(moduleDir)
+	if dryRun {
+		distgo.DryRunPrintln(stdout, fmt.Sprintf("Generate version file %s for %s", versionFilePath, productParam.ID))
+		return nil, nil
+	}
+	content, err := productParam.Build.VersionFile.Content(productTaskOutputInfo, moduleDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate version file for %s", productParam.ID)
+	}
+
+	generated := &generatedVersionFile{
+		path: versionFilePath,
+		mode: 0644,
+	}
+	if fi, err := os.Stat(versionFilePath); err == nil {
+		if generated.original, err = ioutil.ReadFile(versionFilePath); err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", versionFilePath)
+		}
+		generated.mode = fi.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to stat %s", versionFilePath)
+	}
+	if err := ioutil.WriteFile(versionFilePath, content, generated.mode); err != nil {
+		return nil, errors.Wrapf(err, "failed to write version file %s", versionFilePath)
+	}
+	return generated, nil
+}
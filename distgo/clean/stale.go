@@ -0,0 +1,177 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clean
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+// StaleProducts removes the outputs of the specified products (and their dependencies) for versions other than the
+// current version. The outputs of the keepVersions most recently modified versions other than the current version are
+// also retained.
+func StaleProducts(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, productIDs []distgo.ProductID, keepVersions int, dryRun bool, stdout io.Writer) error {
+	if keepVersions < 0 {
+		return errors.Errorf("number of versions to keep must be non-negative, was %d", keepVersions)
+	}
+	productParams, err := distgo.ProductParamsForProductArgs(projectParam.Products, productIDs...)
+	if err != nil {
+		return err
+	}
+	for _, productParam := range productParams {
+		if err := RunStale(projectInfo, productParam, keepVersions, dryRun, stdout); err != nil {
+			return errors.Wrapf(err, "failed to clean stale outputs for %s", productParam.ID)
+		}
+	}
+	return nil
+}
+
+// RunStale removes the outputs generated by the specified product (and its dependencies) for versions other than the
+// current version and the keepVersions most recently modified other versions. The version directories are determined
+// using the same "{{OutputDir}}/{{ID}}/{{Version}}" layout used by the build and dist tasks, and a directory in the
+// layout is only removed if its content matches the content generated by the task: the version directory of a build
+// output may only contain OS/architecture directories and the version directory of a dist output may only contain
+// directories.
+func RunStale(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, keepVersions int, dryRun bool, stdout io.Writer) error {
+	// maps the "{{OutputDir}}/{{ID}}" directories to the function used to verify that a version directory is an output
+	// directory for the task
+	productOutputDirs := make(map[string]func(versionDir string) (bool, error))
+	// maps the "{{OutputDir}}/{{ID}}" directories to the current version of the product (which may differ between
+	// products if a product derives its version from git)
+	currentVersions := make(map[string]string)
+	for _, currProductParam := range productParam.AllProductParams() {
+		outputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, currProductParam)
+		if err != nil {
+			return err
+		}
+		if currProductParam.Build != nil {
+			productOutputDirs[path.Dir(outputInfo.ProductBuildOutputDir())] = isBuildVersionDir
+			currentVersions[path.Dir(outputInfo.ProductBuildOutputDir())] = outputInfo.Project.Version
+		}
+		if currProductParam.Dist != nil {
+			for distID := range currProductParam.Dist.DistParams {
+				productOutputDirs[path.Dir(path.Dir(outputInfo.ProductDistOutputDir(distID)))] = isDistVersionDir
+				currentVersions[path.Dir(path.Dir(outputInfo.ProductDistOutputDir(distID)))] = outputInfo.Project.Version
+			}
+		}
+	}
+
+	var sortedDirs []string
+	for k := range productOutputDirs {
+		sortedDirs = append(sortedDirs, k)
+	}
+	sort.Strings(sortedDirs)
+
+	if dryRun {
+		distgo.DryRunPrintln(stdout, fmt.Sprintf("Clean stale outputs of %s will remove paths:", productParam.ID))
+	}
+	for _, productOutputDir := range sortedDirs {
+		staleDirs, err := staleVersionDirs(productOutputDir, currentVersions[productOutputDir], keepVersions, productOutputDirs[productOutputDir])
+		if err != nil {
+			return err
+		}
+		for _, staleDir := range staleDirs {
+			if dryRun {
+				distgo.DryRunPrintln(stdout, fmt.Sprintf("    %s", staleDir))
+				continue
+			}
+			if err := os.RemoveAll(staleDir); err != nil {
+				return errors.Wrapf(err, "failed to remove directory %s", staleDir)
+			}
+			_, _ = fmt.Fprintf(stdout, "Removed %s\n", staleDir)
+		}
+	}
+	return nil
+}
+
+// staleVersionDirs returns the version directories in the provided product output directory that should be removed.
+// The directory for the current version and the directories for the keepVersions most recently modified other
+// versions are not returned, and neither are entries that are not verified as version output directories.
+func staleVersionDirs(productOutputDir, currentVersion string, keepVersions int, isVersionDir func(string) (bool, error)) ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(productOutputDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read directory %s", productOutputDir)
+	}
+
+	var versionDirs []os.FileInfo
+	for _, fi := range fileInfos {
+		if !fi.IsDir() || fi.Name() == currentVersion {
+			continue
+		}
+		ok, err := isVersionDir(path.Join(productOutputDir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		versionDirs = append(versionDirs, fi)
+	}
+
+	// retain the most recently modified versions
+	sort.SliceStable(versionDirs, func(i, j int) bool {
+		return versionDirs[i].ModTime().After(versionDirs[j].ModTime())
+	})
+	if keepVersions >= len(versionDirs) {
+		return nil, nil
+	}
+	var staleDirs []string
+	for _, fi := range versionDirs[keepVersions:] {
+		staleDirs = append(staleDirs, path.Join(productOutputDir, fi.Name()))
+	}
+	sort.Strings(staleDirs)
+	return staleDirs, nil
+}
+
+// isBuildVersionDir returns true if all of the entries in the provided directory are directories whose names are
+// OS/architectures.
+func isBuildVersionDir(dir string) (bool, error) {
+	return allEntriesMatch(dir, func(fi os.FileInfo) bool {
+		if !fi.IsDir() {
+			return false
+		}
+		_, err := distgo.NewOSArch(fi.Name())
+		return err == nil
+	})
+}
+
+// isDistVersionDir returns true if all of the entries in the provided directory are directories.
+func isDistVersionDir(dir string) (bool, error) {
+	return allEntriesMatch(dir, func(fi os.FileInfo) bool {
+		return fi.IsDir()
+	})
+}
+
+func allEntriesMatch(dir string, match func(fi os.FileInfo) bool) (bool, error) {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read directory %s", dir)
+	}
+	for _, fi := range fileInfos {
+		if !match(fi) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clean_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/osarchbin"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/distgo/clean"
+	distgoconfig "github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/distgo/testfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanStale(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectCfg := distgoconfig.ProjectConfig{
+		Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+			"foo": {
+				Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+					MainPkg: stringPtr("foo"),
+				}),
+				Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+					Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+						osarchbin.TypeName: distgoconfig.ToDisterConfig(distgoconfig.DisterConfig{
+							Type: stringPtr(osarchbin.TypeName),
+						}),
+					}),
+				}),
+			},
+		}),
+	}
+
+	// files that are not version outputs of foo and must never be removed
+	unrelatedFiles := []string{
+		"out/build/foo/notes/README.txt",
+		"out/build/foo/unrelated.txt",
+		"out/build/bar/1.0.0/linux-amd64/bar",
+		"out/dist/foo/1.0.0.tgz",
+		"out/dist/foo/scratch/notes.txt",
+	}
+
+	for i, tc := range []struct {
+		name         string
+		keepVersions int
+		dryRun       bool
+		wantRemoved  []string
+	}{
+		{
+			"removes outputs for all versions other than the current version",
+			0,
+			false,
+			[]string{"1.0.0", "1.1.0"},
+		},
+		{
+			"keeps outputs for the most recent versions",
+			1,
+			false,
+			[]string{"1.0.0"},
+		},
+		{
+			"keeps all outputs if the number of versions to keep exceeds the number of versions",
+			5,
+			false,
+			nil,
+		},
+		{
+			"dry run does not remove outputs",
+			0,
+			true,
+			nil,
+		},
+	} {
+		projectDir, err := ioutil.TempDir(tmp, "")
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		now := time.Now()
+		for versionIdx, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+			for _, versionDir := range []string{
+				path.Join(projectDir, "out", "build", "foo", version),
+				path.Join(projectDir, "out", "dist", "foo", version),
+			} {
+				writeTestFile(t, path.Join(versionDir, "linux-amd64", "foo"))
+				modTime := now.Add(time.Duration(versionIdx-3) * time.Hour)
+				require.NoError(t, os.Chtimes(versionDir, modTime, modTime), "Case %d: %s", i, tc.name)
+			}
+		}
+		for _, unrelatedFile := range unrelatedFiles {
+			writeTestFile(t, path.Join(projectDir, unrelatedFile))
+		}
+
+		projectParam := testfuncs.NewProjectParam(t, projectCfg, projectDir, fmt.Sprintf("Case %d: %s", i, tc.name))
+		projectInfo := distgo.ProjectInfo{
+			ProjectDir: projectDir,
+			Version:    "1.2.0",
+		}
+
+		buffer := &bytes.Buffer{}
+		err = clean.StaleProducts(projectInfo, projectParam, nil, tc.keepVersions, tc.dryRun, buffer)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		wantRemoved := make(map[string]bool)
+		for _, version := range tc.wantRemoved {
+			wantRemoved[version] = true
+		}
+		for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+			for _, versionDir := range []string{
+				path.Join(projectDir, "out", "build", "foo", version),
+				path.Join(projectDir, "out", "dist", "foo", version),
+			} {
+				_, err := os.Stat(versionDir)
+				if wantRemoved[version] {
+					assert.True(t, os.IsNotExist(err), "Case %d: %s: expected %s to be removed", i, tc.name, versionDir)
+				} else {
+					assert.NoError(t, err, "Case %d: %s: expected %s to exist", i, tc.name, versionDir)
+				}
+			}
+		}
+		for _, unrelatedFile := range unrelatedFiles {
+			_, err := os.Stat(path.Join(projectDir, unrelatedFile))
+			assert.NoError(t, err, "Case %d: %s: expected %s to exist", i, tc.name, unrelatedFile)
+		}
+
+		if tc.dryRun {
+			assert.Equal(t, fmt.Sprintf(`[DRY RUN] Clean stale outputs of foo will remove paths:
+[DRY RUN]     %s/out/build/foo/1.0.0
+[DRY RUN]     %s/out/build/foo/1.1.0
+[DRY RUN]     %s/out/dist/foo/1.0.0
+[DRY RUN]     %s/out/dist/foo/1.1.0
+`, projectDir, projectDir, projectDir, projectDir), buffer.String(), "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestCleanStaleInvalidKeepVersions(t *testing.T) {
+	err := clean.StaleProducts(distgo.ProjectInfo{}, distgo.ProjectParam{}, nil, -1, false, ioutil.Discard)
+	require.EqualError(t, err, "number of versions to keep must be non-negative, was -1")
+}
+
+func writeTestFile(t *testing.T, filePath string) {
+	require.NoError(t, os.MkdirAll(path.Dir(filePath), 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("test"), 0644))
+}
@@ -1000,6 +1000,213 @@ products:
 	}
 }
 
+func TestProjectConfig_BuildNameTemplate(t *testing.T) {
+	for i, tc := range []struct {
+		name         string
+		nameTemplate string
+		wantError    string
+	}{
+		{
+			name:         "default template",
+			nameTemplate: "",
+		},
+		{
+			name:         "all supported variables",
+			nameTemplate: "{{Product}}-{{Version}}-{{OSArch}}{{ExeExt}}",
+		},
+		{
+			name:         "GOOS and GOARCH",
+			nameTemplate: "{{Product}}_{{GOOS}}_{{GOARCH}}",
+		},
+		{
+			name:         "supported variables with builtin functions",
+			nameTemplate: `{{if eq (OSArch) "windows-amd64"}}{{printf "%s-win" Product}}{{else}}{{Product}}{{end}}`,
+		},
+		{
+			name:         "supported variables with string functions",
+			nameTemplate: `{{Product | upper}}-{{Version | trimPrefix "v" | replace "." "_" | lower}}`,
+		},
+		{
+			name:         "unknown variable",
+			nameTemplate: "{{Prodct}}",
+			wantError:    `invalid name-template for product test-1: failed to parse template {{Prodct}} (supported variables are [ExeExt GOARCH GOOS OSArch Product Version]): template: distgoTemplate:1: function "Prodct" not defined`,
+		},
+		{
+			name:         "field reference",
+			nameTemplate: "{{Product}}-{{.Version}}",
+			wantError:    "invalid name-template for product test-1: template {{Product}}-{{.Version}} references unknown variable {{.Version}} (supported variables are [ExeExt GOARCH GOOS OSArch Product Version])",
+		},
+		{
+			name:         "field reference in branch",
+			nameTemplate: "{{Product}}{{if OSArch}}-{{.OSArch}}{{end}}",
+			wantError:    "invalid name-template for product test-1: template {{Product}}{{if OSArch}}-{{.OSArch}}{{end}} references unknown variable {{.OSArch}} (supported variables are [ExeExt GOARCH GOOS OSArch Product Version])",
+		},
+		{
+			name:         "dot reference",
+			nameTemplate: "{{Product}}{{.}}",
+			wantError:    "invalid name-template for product test-1: template {{Product}}{{.}} references unknown variable {{.}} (supported variables are [ExeExt GOARCH GOOS OSArch Product Version])",
+		},
+		{
+			name:         "undefined template variable",
+			nameTemplate: "{{$name}}",
+			wantError:    `invalid name-template for product test-1: failed to parse template {{$name}} (supported variables are [ExeExt GOARCH GOOS OSArch Product Version]): template: distgoTemplate:1: undefined variable "$name"`,
+		},
+		{
+			name:         "malformed template",
+			nameTemplate: "{{Product",
+			wantError:    `invalid name-template for product test-1: failed to parse template {{Product (supported variables are [ExeExt GOARCH GOOS OSArch Product Version]): template: distgoTemplate:1: unclosed action`,
+		},
+	} {
+		buildCfg := distgoconfig.BuildConfig{
+			MainPkg: stringPtr("./main"),
+		}
+		if tc.nameTemplate != "" {
+			buildCfg.NameTemplate = stringPtr(tc.nameTemplate)
+		}
+		gotCfg := distgoconfig.ProjectConfig{
+			Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+				"test-1": {
+					Build: distgoconfig.ToBuildConfig(&buildCfg),
+				},
+			}),
+		}
+		_, err := testfuncs.NewProjectParamReturnError(t, gotCfg, "", fmt.Sprintf("Case %d: %s", i, tc.name))
+		if tc.wantError == "" {
+			assert.NoError(t, err, "Case %d: %s", i, tc.name)
+		} else {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestProjectConfig_VersionFromGit(t *testing.T) {
+	for i, tc := range []struct {
+		name                 string
+		yml                  string
+		wantVersionFromGit   bool
+		wantVersionTagPrefix string
+		wantError            string
+	}{
+		{
+			name: "version-from-git is not specified",
+			yml: `
+products:
+  test-1:
+    build:
+      main-pkg: ./main
+`,
+		},
+		{
+			name: "version-from-git with version-tag-prefix",
+			yml: `
+products:
+  test-1:
+    build:
+      main-pkg: ./main
+    version-from-git: true
+    version-tag-prefix: test-1@
+`,
+			wantVersionFromGit:   true,
+			wantVersionTagPrefix: "test-1@",
+		},
+		{
+			name: "version-tag-prefix without version-from-git",
+			yml: `
+products:
+  test-1:
+    build:
+      main-pkg: ./main
+    version-tag-prefix: test-1@
+`,
+			wantError: "version-tag-prefix for product test-1 cannot be specified if version-from-git is not true",
+		},
+	} {
+		var gotCfg distgoconfig.ProjectConfig
+		err := yaml.Unmarshal([]byte(tc.yml), &gotCfg)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		projectParam, err := testfuncs.NewProjectParamReturnError(t, gotCfg, "", fmt.Sprintf("Case %d: %s", i, tc.name))
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantVersionFromGit, projectParam.Products["test-1"].VersionFromGit, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantVersionTagPrefix, projectParam.Products["test-1"].VersionTagPrefix, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestProjectConfig_ChecksumAlgorithm(t *testing.T) {
+	for i, tc := range []struct {
+		name          string
+		yml           string
+		wantAlgorithm distgo.ChecksumAlgorithm
+		wantError     string
+	}{
+		{
+			name: "checksum-algorithm is not specified",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+`,
+		},
+		{
+			name: "checksum-algorithm is specified",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        checksum-algorithm: blake2b
+`,
+			wantAlgorithm: distgo.ChecksumBLAKE2b,
+		},
+		{
+			name: "checksum-algorithm from product defaults",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+product-defaults:
+  dist:
+    disters:
+      type: os-arch-bin
+      checksum-algorithm: sha512
+`,
+			wantAlgorithm: distgo.ChecksumSHA512,
+		},
+		{
+			name: "unsupported checksum-algorithm",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        checksum-algorithm: md5
+`,
+			wantError: `invalid checksum-algorithm "md5": must be one of "sha256", "sha512" or "blake2b"`,
+		},
+	} {
+		var gotCfg distgoconfig.ProjectConfig
+		err := yaml.Unmarshal([]byte(tc.yml), &gotCfg)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		projectParam, err := testfuncs.NewProjectParamReturnError(t, gotCfg, "", fmt.Sprintf("Case %d: %s", i, tc.name))
+		if tc.wantError != "" {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Contains(t, err.Error(), tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantAlgorithm, projectParam.Products["test-1"].Dist.DistParams["os-arch-bin"].ChecksumAlgorithm, "Case %d: %s", i, tc.name)
+	}
+}
+
 func TestProductTaskParam_ToProductTaskOutputInfo(t *testing.T) {
 	tmpDir, cleanup, err := dirs.TempDir("", "")
 	require.NoError(t, err)
@@ -1023,6 +1230,7 @@ func TestProductTaskParam_ToProductTaskOutputInfo(t *testing.T) {
 products:
   test-one:
     build:
+      main-pkg: ./test-one
       name-template: "{{Product}}-{{Version}}-cli"
 `,
 			map[distgo.ProductID]distgo.ProductTaskOutputInfo{
@@ -1032,13 +1240,15 @@ products:
 						Version:    "1.0.0",
 					},
 					Product: distgo.ProductOutputInfo{
-						ID: "test-one",
+						ID:      "test-one",
+						Version: "1.0.0",
 						BuildOutputInfo: &distgo.BuildOutputInfo{
 							BuildNameTemplateRendered: "test-one-1.0.0-cli",
 							BuildOutputDir:            "out/build",
 							OSArchs: []osarch.OSArch{
 								osarch.Current(),
 							},
+							MainPkg: "./test-one",
 						},
 					},
 				},
@@ -1074,3 +1284,154 @@ func mustOSArch(in string) osarch.OSArch {
 	}
 	return osArch
 }
+
+func TestProjectConfig_ExcludePatterns(t *testing.T) {
+	for i, tc := range []struct {
+		name      string
+		yml       string
+		want      []string
+		wantError string
+	}{
+		{
+			name: "exclude-patterns is specified",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        exclude-patterns:
+          - "*.debug"
+          - testdata
+`,
+			want: []string{"*.debug", "testdata"},
+		},
+		{
+			name: "exclude-patterns from product defaults",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+product-defaults:
+  dist:
+    disters:
+      type: os-arch-bin
+      exclude-patterns:
+        - "*.debug"
+`,
+			want: []string{"*.debug"},
+		},
+		{
+			name: "invalid exclude-patterns entry",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        exclude-patterns:
+          - "[a-"
+`,
+			wantError: `invalid exclude-patterns entry "[a-": syntax error in pattern`,
+		},
+	} {
+		var gotCfg distgoconfig.ProjectConfig
+		err := yaml.Unmarshal([]byte(tc.yml), &gotCfg)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		projectParam, err := testfuncs.NewProjectParamReturnError(t, gotCfg, "", fmt.Sprintf("Case %d: %s", i, tc.name))
+		if tc.wantError != "" {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Contains(t, err.Error(), tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, projectParam.Products["test-1"].Dist.DistParams["os-arch-bin"].ExcludePatterns, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestProjectConfig_ExecutableMode(t *testing.T) {
+	for i, tc := range []struct {
+		name      string
+		yml       string
+		want      os.FileMode
+		wantError string
+	}{
+		{
+			name: "executable-mode is not specified",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+`,
+			want: 0,
+		},
+		{
+			name: "executable-mode is specified",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        executable-mode: 0750
+`,
+			want: 0750,
+		},
+		{
+			name: "executable-mode from product defaults",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+product-defaults:
+  dist:
+    disters:
+      type: os-arch-bin
+      executable-mode: "0700"
+`,
+			want: 0700,
+		},
+		{
+			name: "executable-mode that does not grant owner execute permission",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        executable-mode: 0644
+`,
+			wantError: `executable-mode must be an octal value between 0100 and 0777 that grants execute permission to the owner, was "0644"`,
+		},
+		{
+			name: "executable-mode that is not octal",
+			yml: `
+products:
+  test-1:
+    dist:
+      disters:
+        type: os-arch-bin
+        executable-mode: rwxr-xr-x
+`,
+			wantError: `executable-mode must be an octal value between 0100 and 0777 that grants execute permission to the owner, was "rwxr-xr-x"`,
+		},
+	} {
+		var gotCfg distgoconfig.ProjectConfig
+		err := yaml.Unmarshal([]byte(tc.yml), &gotCfg)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		projectParam, err := testfuncs.NewProjectParamReturnError(t, gotCfg, "", fmt.Sprintf("Case %d: %s", i, tc.name))
+		if tc.wantError != "" {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Contains(t, err.Error(), tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, projectParam.Products["test-1"].Dist.DistParams["os-arch-bin"].ExecutableMode, "Case %d: %s", i, tc.name)
+	}
+}
@@ -17,6 +17,7 @@ package config
 import (
 	"path"
 	"strings"
+	"time"
 
 	"github.com/palantir/distgo/distgo"
 	v0 "github.com/palantir/distgo/distgo/config/internal/v0"
@@ -43,15 +44,205 @@ func (cfg *BuildConfig) ToParam(scriptIncludes string, defaultCfg BuildConfig) (
 	if mainPkg != "" && !strings.HasPrefix(mainPkg, "./") {
 		mainPkg = "./" + mainPkg
 	}
+	moduleDir := getConfigStringValue(cfg.ModuleDir, defaultCfg.ModuleDir, "")
+	if moduleDir != "" {
+		moduleDir = path.Clean(moduleDir)
+		if path.IsAbs(moduleDir) || moduleDir == ".." || strings.HasPrefix(moduleDir, "../") {
+			return distgo.BuildParam{}, errors.Errorf("module-dir must be a path within the project directory")
+		}
+		if moduleDir == "." {
+			moduleDir = ""
+		}
+	}
+
+	var osArchMainPkg map[osarch.OSArch]string
+	if osArchMainPkgCfg := getConfigValue(cfg.OSArchMainPkg, defaultCfg.OSArchMainPkg, nil).(map[string]string); len(osArchMainPkgCfg) > 0 {
+		if mainPkg == "" {
+			return distgo.BuildParam{}, errors.Errorf("os-arch-main-pkg cannot be specified if main-pkg is not specified")
+		}
+		osArchMainPkg = make(map[osarch.OSArch]string, len(osArchMainPkgCfg))
+		for osArchStr, currMainPkg := range osArchMainPkgCfg {
+			osArchVal, err := distgo.NewOSArch(osArchStr)
+			if err != nil {
+				return distgo.BuildParam{}, errors.Wrapf(err, "invalid os-arch-main-pkg key")
+			}
+			if currMainPkg == "" {
+				return distgo.BuildParam{}, errors.Errorf("os-arch-main-pkg value for %s cannot be empty", osArchStr)
+			}
+			if !strings.HasPrefix(currMainPkg, "./") {
+				currMainPkg = "./" + currMainPkg
+			}
+			osArchMainPkg[osArchVal] = currMainPkg
+		}
+	}
+
+	var osArchEnv map[osarch.OSArch]map[string]string
+	if osArchEnvCfg := getConfigValue(cfg.OSArchEnvironment, defaultCfg.OSArchEnvironment, nil).(map[string]map[string]string); len(osArchEnvCfg) > 0 {
+		osArchEnv = make(map[osarch.OSArch]map[string]string, len(osArchEnvCfg))
+		for osArchStr, env := range osArchEnvCfg {
+			osArchVal, err := distgo.NewOSArch(osArchStr)
+			if err != nil {
+				return distgo.BuildParam{}, errors.Wrapf(err, "invalid os-arch-environment key")
+			}
+			osArchEnv[osArchVal] = env
+		}
+	}
+
+	var crossCompilers map[osarch.OSArch]distgo.CrossCompilerSpec
+	if crossCompilersCfg := getConfigValue(cfg.CrossCompilers, defaultCfg.CrossCompilers, nil).(map[string]v0.CrossCompilerConfig); len(crossCompilersCfg) > 0 {
+		crossCompilers = make(map[osarch.OSArch]distgo.CrossCompilerSpec, len(crossCompilersCfg))
+		for osArchStr, crossCompilerCfg := range crossCompilersCfg {
+			osArchVal, err := distgo.NewOSArch(osArchStr)
+			if err != nil {
+				return distgo.BuildParam{}, errors.Wrapf(err, "invalid cross-compilers key")
+			}
+			crossCompilers[osArchVal] = (*CrossCompilerConfig)(&crossCompilerCfg).ToParam()
+		}
+	}
+
+	var versionVars []distgo.VersionVarSpec
+	for _, versionVarCfg := range getConfigValue(cfg.VersionVars, defaultCfg.VersionVars, nil).([]v0.VersionVarConfig) {
+		if versionVarCfg.Variable == "" {
+			return distgo.BuildParam{}, errors.Errorf("variable must be specified for all version-vars entries")
+		}
+		versionVars = append(versionVars, (*VersionVarConfig)(&versionVarCfg).ToParam())
+	}
+
+	var versionFile *distgo.VersionFileSpec
+	versionFileCfg := cfg.VersionFile
+	if versionFileCfg == nil {
+		versionFileCfg = defaultCfg.VersionFile
+	}
+	if versionFileCfg != nil {
+		versionFile = (*VersionFileConfig)(versionFileCfg).ToParam()
+		if err := versionFile.Validate(); err != nil {
+			return distgo.BuildParam{}, errors.Wrapf(err, "invalid version-file")
+		}
+	}
+
+	buildRetries := getConfigValue(cfg.BuildRetries, defaultCfg.BuildRetries, 0).(int)
+	if buildRetries < 0 {
+		return distgo.BuildParam{}, errors.Errorf("build-retries cannot be negative")
+	}
+	maxOutputBytes := getConfigValue(cfg.MaxOutputBytes, defaultCfg.MaxOutputBytes, int64(0)).(int64)
+	if maxOutputBytes < 0 {
+		return distgo.BuildParam{}, errors.Errorf("max-output-bytes cannot be negative")
+	}
+	var buildRetryBackoff time.Duration
+	if buildRetryBackoffStr := getConfigStringValue(cfg.BuildRetryBackoff, defaultCfg.BuildRetryBackoff, ""); buildRetryBackoffStr != "" {
+		var err error
+		if buildRetryBackoff, err = time.ParseDuration(buildRetryBackoffStr); err != nil {
+			return distgo.BuildParam{}, errors.Wrapf(err, "invalid build-retry-backoff")
+		}
+	}
+
+	goFlags := getConfigValue(cfg.GoFlags, defaultCfg.GoFlags, nil).([]string)
+	for _, goFlag := range goFlags {
+		if !strings.HasPrefix(goFlag, "-") {
+			return distgo.BuildParam{}, errors.Errorf("go-flags entry %q is not a flag: entries must start with '-'", goFlag)
+		}
+	}
+
+	buildArgsScript := getConfigStringValue(cfg.BuildArgsScript, defaultCfg.BuildArgsScript, "")
+	buildArgsScriptFile := getConfigStringValue(cfg.BuildArgsScriptFile, defaultCfg.BuildArgsScriptFile, "")
+	if buildArgsScript != "" && buildArgsScriptFile != "" {
+		return distgo.BuildParam{}, errors.Errorf("build-args-script and build-args-script-file cannot both be specified")
+	}
+	var buildArgsScriptIncludes string
+	if buildArgsScriptFile != "" {
+		buildArgsScriptIncludes = scriptIncludes
+	}
 
-	return distgo.BuildParam{
-		NameTemplate:    getConfigStringValue(cfg.NameTemplate, defaultCfg.NameTemplate, "{{Product}}"),
-		OutputDir:       outputDir,
-		MainPkg:         mainPkg,
-		BuildArgsScript: distgo.CreateScriptContent(getConfigStringValue(cfg.BuildArgsScript, defaultCfg.BuildArgsScript, ""), scriptIncludes),
-		VersionVar:      getConfigStringValue(cfg.VersionVar, defaultCfg.VersionVar, ""),
-		Script:          getConfigStringValue(cfg.Script, defaultCfg.Script, ""),
-		Environment:     getConfigValue(cfg.Environment, defaultCfg.Environment, nil).(map[string]string),
-		OSArchs:         getConfigValue(cfg.OSArchs, defaultCfg.OSArchs, []osarch.OSArch{osarch.Current()}).([]osarch.OSArch),
-	}, nil
+	buildParam := distgo.BuildParam{
+		NameTemplate:              getConfigStringValue(cfg.NameTemplate, defaultCfg.NameTemplate, "{{Product}}"),
+		OutputDir:                 outputDir,
+		MainPkg:                   mainPkg,
+		OSArchMainPkg:             osArchMainPkg,
+		ModuleDir:                 moduleDir,
+		BuildArgsScript:           distgo.CreateScriptContent(buildArgsScript, scriptIncludes),
+		BuildArgsScriptFile:       buildArgsScriptFile,
+		BuildArgsScriptIncludes:   buildArgsScriptIncludes,
+		VersionVar:                getConfigStringValue(cfg.VersionVar, defaultCfg.VersionVar, ""),
+		VersionVarValueTemplate:   getConfigStringValue(cfg.VersionVarValue, defaultCfg.VersionVarValue, ""),
+		VersionVars:               versionVars,
+		VersionFile:               versionFile,
+		VersionCheckArgs:          getConfigValue(cfg.VersionCheckArgs, defaultCfg.VersionCheckArgs, nil).([]string),
+		Script:                    getConfigStringValue(cfg.Script, defaultCfg.Script, ""),
+		Environment:               getConfigValue(cfg.Environment, defaultCfg.Environment, nil).(map[string]string),
+		OSArchEnvironment:         osArchEnv,
+		CrossCompilers:            crossCompilers,
+		DisableCgoForCrossCompile: getConfigValue(cfg.DisableCgoForCrossCompile, defaultCfg.DisableCgoForCrossCompile, false).(bool),
+		GoExperiment:              getConfigStringValue(cfg.GoExperiment, defaultCfg.GoExperiment, ""),
+		GoFlags:                   goFlags,
+		Trimpath:                  getConfigValue(cfg.Trimpath, defaultCfg.Trimpath, false).(bool),
+		BuildTags:                 getConfigValue(cfg.BuildTags, defaultCfg.BuildTags, nil).([]string),
+		GCFlags:                   getConfigValue(cfg.GCFlags, defaultCfg.GCFlags, nil).([]string),
+		AsmFlags:                  getConfigValue(cfg.AsmFlags, defaultCfg.AsmFlags, nil).([]string),
+		LDFlags:                   getConfigValue(cfg.LDFlags, defaultCfg.LDFlags, nil).([]string),
+		Race:                      getConfigValue(cfg.Race, defaultCfg.Race, false).(bool),
+		BuildMode:                 getConfigStringValue(cfg.BuildMode, defaultCfg.BuildMode, ""),
+		StripDebug:                getConfigValue(cfg.StripDebug, defaultCfg.StripDebug, false).(bool),
+		MaxOutputBytes:            maxOutputBytes,
+		CleanOutputDir:            getConfigValue(cfg.CleanOutputDir, defaultCfg.CleanOutputDir, false).(bool),
+		LatestLink:                getConfigValue(cfg.LatestLink, defaultCfg.LatestLink, false).(bool),
+		GoBinary:                  getConfigStringValue(cfg.GoBinary, defaultCfg.GoBinary, ""),
+		BuildRetries:              buildRetries,
+		BuildRetryBackoff:         buildRetryBackoff,
+		OSArchs:                   getConfigValue(cfg.OSArchs, defaultCfg.OSArchs, []osarch.OSArch{osarch.Current()}).([]osarch.OSArch),
+	}
+	if err := distgo.ValidateBuildMode(buildParam.BuildMode); err != nil {
+		return distgo.BuildParam{}, err
+	}
+	for _, currOSArch := range buildParam.OSArchs {
+		if _, variant := distgo.GOARCHAndVariant(currOSArch); variant != "" {
+			if _, err := distgo.NewOSArch(currOSArch.String()); err != nil {
+				return distgo.BuildParam{}, errors.Wrapf(err, "invalid os-archs entry")
+			}
+		}
+		if err := buildParam.ValidateEnvironmentForOSArch(currOSArch); err != nil {
+			return distgo.BuildParam{}, err
+		}
+	}
+	return buildParam, nil
+}
+
+type VersionVarConfig v0.VersionVarConfig
+
+func ToVersionVarConfig(in *VersionVarConfig) *v0.VersionVarConfig {
+	return (*v0.VersionVarConfig)(in)
+}
+
+func (cfg *VersionVarConfig) ToParam() distgo.VersionVarSpec {
+	return distgo.VersionVarSpec{
+		Variable:      cfg.Variable,
+		ValueTemplate: cfg.Value,
+	}
+}
+
+type VersionFileConfig v0.VersionFileConfig
+
+func ToVersionFileConfig(in *VersionFileConfig) *v0.VersionFileConfig {
+	return (*v0.VersionFileConfig)(in)
+}
+
+func (cfg *VersionFileConfig) ToParam() *distgo.VersionFileSpec {
+	return &distgo.VersionFileSpec{
+		Dir:      cfg.Dir,
+		FileName: cfg.FileName,
+		Template: cfg.Template,
+	}
+}
+
+type CrossCompilerConfig v0.CrossCompilerConfig
+
+func ToCrossCompilerConfig(in *CrossCompilerConfig) *v0.CrossCompilerConfig {
+	return (*v0.CrossCompilerConfig)(in)
+}
+
+func (cfg *CrossCompilerConfig) ToParam() distgo.CrossCompilerSpec {
+	return distgo.CrossCompilerSpec{
+		CC:        cfg.CC,
+		CXX:       cfg.CXX,
+		CGOCFlags: cfg.CGOCFlags,
+	}
 }
@@ -0,0 +1,417 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/palantir/distgo/distgo"
+	distgoconfig "github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBuildConfig_ToParam(t *testing.T) {
+	for i, tc := range []struct {
+		name      string
+		yml       string
+		want      func(param *distgo.BuildParam)
+		wantError string
+	}{
+		{
+			name: "os-arch-environment is parsed",
+			yml: `
+os-archs:
+  - os: linux
+    arch: amd64
+  - os: linux
+    arch: arm64
+environment:
+  CGO_ENABLED: "1"
+os-arch-environment:
+  linux-arm64:
+    CC: aarch64-linux-gnu-gcc
+`,
+			want: func(param *distgo.BuildParam) {
+				param.Environment = map[string]string{
+					"CGO_ENABLED": "1",
+				}
+				param.OSArchEnvironment = map[osarch.OSArch]map[string]string{
+					mustOSArch("linux-arm64"): {
+						"CC": "aarch64-linux-gnu-gcc",
+					},
+				}
+				param.OSArchs = []osarch.OSArch{
+					mustOSArch("linux-amd64"),
+					mustOSArch("linux-arm64"),
+				}
+			},
+		},
+		{
+			name: "cross-compilers is parsed",
+			yml: `
+os-archs:
+  - os: linux
+    arch: arm64
+cross-compilers:
+  linux-arm64:
+    cc: aarch64-linux-gnu-gcc
+    cxx: aarch64-linux-gnu-g++
+    cgo-cflags: -march=armv8-a
+`,
+			want: func(param *distgo.BuildParam) {
+				param.CrossCompilers = map[osarch.OSArch]distgo.CrossCompilerSpec{
+					mustOSArch("linux-arm64"): {
+						CC:        "aarch64-linux-gnu-gcc",
+						CXX:       "aarch64-linux-gnu-g++",
+						CGOCFlags: "-march=armv8-a",
+					},
+				}
+				param.OSArchs = []osarch.OSArch{
+					mustOSArch("linux-arm64"),
+				}
+			},
+		},
+		{
+			name: "disable-cgo-for-cross-compile is parsed",
+			yml: `
+disable-cgo-for-cross-compile: true
+`,
+			want: func(param *distgo.BuildParam) {
+				param.DisableCgoForCrossCompile = true
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "gc-flags and asm-flags are parsed",
+			yml: `
+gc-flags:
+  - all=-N
+  - -l
+asm-flags:
+  - -trimpath
+`,
+			want: func(param *distgo.BuildParam) {
+				param.GCFlags = []string{"all=-N", "-l"}
+				param.AsmFlags = []string{"-trimpath"}
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "ld-flags are parsed",
+			yml: `
+ld-flags:
+  - -linkmode
+  - external
+  - -extldflags
+  - "'-static'"
+`,
+			want: func(param *distgo.BuildParam) {
+				param.LDFlags = []string{"-linkmode", "external", "-extldflags", "'-static'"}
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "race with CGo disabled",
+			yml: `
+race: true
+environment:
+  CGO_ENABLED: "0"
+os-archs:
+  - os: linux
+    arch: amd64
+`,
+			wantError: "race detector requires CGo, but CGO_ENABLED is set to 0 for linux-amd64",
+		},
+		{
+			name: "build-mode is parsed",
+			yml: `
+build-mode: c-shared
+`,
+			want: func(param *distgo.BuildParam) {
+				param.BuildMode = "c-shared"
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "os-arch-main-pkg is parsed",
+			yml: `
+main-pkg: ./cmd/app
+os-arch-main-pkg:
+  windows-amd64: cmd/app-windows
+`,
+			want: func(param *distgo.BuildParam) {
+				param.MainPkg = "./cmd/app"
+				param.OSArchMainPkg = map[osarch.OSArch]string{
+					{OS: "windows", Arch: "amd64"}: "./cmd/app-windows",
+				}
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "os-arch-main-pkg without main-pkg",
+			yml: `
+os-arch-main-pkg:
+  windows-amd64: ./cmd/app-windows
+`,
+			wantError: "os-arch-main-pkg cannot be specified if main-pkg is not specified",
+		},
+		{
+			name: "clean-output-dir is parsed",
+			yml: `
+clean-output-dir: true
+`,
+			want: func(param *distgo.BuildParam) {
+				param.CleanOutputDir = true
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "invalid build-mode",
+			yml: `
+build-mode: dll
+`,
+			wantError: `invalid build mode "dll": must be one of [archive c-archive c-shared default exe pie plugin shared]`,
+		},
+		{
+			name: "version-vars are parsed",
+			yml: `
+version-var: main.version
+version-var-value: "{{Version}}-{{GitCommit}}"
+version-vars:
+  - variable: main.productVersion
+  - variable: main.product
+    value: "{{Product}}"
+`,
+			want: func(param *distgo.BuildParam) {
+				param.VersionVar = "main.version"
+				param.VersionVarValueTemplate = "{{Version}}-{{GitCommit}}"
+				param.VersionVars = []distgo.VersionVarSpec{
+					{
+						Variable: "main.productVersion",
+					},
+					{
+						Variable:      "main.product",
+						ValueTemplate: "{{Product}}",
+					},
+				}
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "build retries are parsed",
+			yml: `
+build-retries: 3
+build-retry-backoff: 500ms
+`,
+			want: func(param *distgo.BuildParam) {
+				param.BuildRetries = 3
+				param.BuildRetryBackoff = 500 * time.Millisecond
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "module-dir is parsed",
+			yml: `
+module-dir: ./tools/
+main-pkg: ./cmd/gen
+`,
+			want: func(param *distgo.BuildParam) {
+				param.ModuleDir = "tools"
+				param.MainPkg = "./cmd/gen"
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "module-dir outside of project directory",
+			yml: `
+module-dir: ../tools
+`,
+			wantError: "module-dir must be a path within the project directory",
+		},
+		{
+			name: "build-args-script-file is parsed",
+			yml: `
+build-args-script-file: scripts/build-args.sh
+`,
+			want: func(param *distgo.BuildParam) {
+				param.BuildArgsScriptFile = "scripts/build-args.sh"
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "build-args-script and build-args-script-file",
+			yml: `
+build-args-script: echo "-v"
+build-args-script-file: scripts/build-args.sh
+`,
+			wantError: "build-args-script and build-args-script-file cannot both be specified",
+		},
+		{
+			name: "max-output-bytes is parsed",
+			yml: `
+strip-debug: true
+max-output-bytes: 1048576
+`,
+			want: func(param *distgo.BuildParam) {
+				param.StripDebug = true
+				param.MaxOutputBytes = 1048576
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "negative max-output-bytes",
+			yml: `
+max-output-bytes: -1
+`,
+			wantError: "max-output-bytes cannot be negative",
+		},
+		{
+			name: "version-check-args is parsed",
+			yml: `
+version-check-args:
+  - --version
+`,
+			want: func(param *distgo.BuildParam) {
+				param.VersionCheckArgs = []string{"--version"}
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "go-flags and go-experiment are parsed",
+			yml: `
+go-flags:
+  - -mod=vendor
+  - -ldflags=-extldflags=-static
+go-experiment: loopvar
+`,
+			want: func(param *distgo.BuildParam) {
+				param.GoFlags = []string{"-mod=vendor", "-ldflags=-extldflags=-static"}
+				param.GoExperiment = "loopvar"
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "version-file is parsed",
+			yml: `
+version-file:
+  dir: internal/version
+  template: const Version = {{printf "%q" Version}}
+`,
+			want: func(param *distgo.BuildParam) {
+				param.VersionFile = &distgo.VersionFileSpec{
+					Dir:      "internal/version",
+					Template: `const Version = {{printf "%q" Version}}`,
+				}
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "version-file without dir",
+			yml: `
+version-file:
+  file-name: version.go
+`,
+			wantError: "invalid version-file: dir must be specified",
+		},
+		{
+			name: "version-file with file name that is ignored by build",
+			yml: `
+version-file:
+  dir: internal/version
+  file-name: _version.go
+`,
+			wantError: "invalid version-file: file name _version.go must be the name of a non-test Go file that does not start with '_' or '.'",
+		},
+		{
+			name: "latest-link is parsed",
+			yml: `
+latest-link: true
+`,
+			want: func(param *distgo.BuildParam) {
+				param.LatestLink = true
+				param.OSArchs = []osarch.OSArch{osarch.Current()}
+			},
+		},
+		{
+			name: "go-flags entry that is not a flag",
+			yml: `
+go-flags:
+  - mod=vendor
+`,
+			wantError: `go-flags entry "mod=vendor" is not a flag: entries must start with '-'`,
+		},
+		{
+			name: "negative build retries",
+			yml: `
+build-retries: -1
+`,
+			wantError: "build-retries cannot be negative",
+		},
+		{
+			name: "invalid build retry backoff",
+			yml: `
+build-retry-backoff: soon
+`,
+			wantError: `invalid build-retry-backoff: time: invalid duration "soon"`,
+		},
+		{
+			name: "version-vars entry without variable",
+			yml: `
+version-vars:
+  - value: "{{Product}}"
+`,
+			wantError: "variable must be specified for all version-vars entries",
+		},
+		{
+			name: "invalid os-arch-environment key",
+			yml: `
+os-arch-environment:
+  linux:
+    CC: gcc
+`,
+			wantError: "invalid os-arch-environment key: not a valid OSArch value: linux",
+		},
+		{
+			name: "invalid cross-compilers key",
+			yml: `
+cross-compilers:
+  linux:
+    cc: gcc
+`,
+			wantError: "invalid cross-compilers key: not a valid OSArch value: linux",
+		},
+	} {
+		var cfg distgoconfig.BuildConfig
+		require.NoError(t, yaml.Unmarshal([]byte(tc.yml), &cfg), "Case %d: %s", i, tc.name)
+
+		got, err := cfg.ToParam("", distgoconfig.BuildConfig{})
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		want := distgo.BuildParam{
+			NameTemplate: "{{Product}}",
+			OutputDir:    "out/build",
+		}
+		tc.want(&want)
+		assert.Equal(t, want, got, "Case %d: %s", i, tc.name)
+	}
+}
@@ -15,7 +15,10 @@
 package config
 
 import (
+	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/palantir/distgo/distgo"
 	v0 "github.com/palantir/distgo/distgo/config/internal/v0"
@@ -46,6 +49,7 @@ func (cfg *DistConfig) ToParam(scriptIncludes string, defaultCfg DistConfig, dis
 	return distgo.DistParam{
 		OutputDir:  outputDir,
 		DistParams: disters,
+		GPGKey:     getConfigStringValue(cfg.GPGKey, defaultCfg.GPGKey, ""),
 	}, nil
 }
 
@@ -66,11 +70,91 @@ func (cfg *DisterConfig) ToParam(defaultCfg DisterConfig, scriptIncludes string,
 	}
 
 	inputDirCfg := getConfigValue((*InputDirConfig)(cfg.InputDir), (*InputDirConfig)(defaultCfg.InputDir), InputDirConfig{}).(InputDirConfig)
+	var inputFiles []distgo.FileMapping
+	for _, fileMappingCfg := range getConfigValue(cfg.InputFiles, defaultCfg.InputFiles, []v0.FileMappingConfig(nil)).([]v0.FileMappingConfig) {
+		fileMapping, err := (*FileMappingConfig)(&fileMappingCfg).ToParam()
+		if err != nil {
+			return distgo.DisterParam{}, errors.Wrapf(err, "invalid input-files")
+		}
+		inputFiles = append(inputFiles, fileMapping)
+	}
+	excludePatterns := getConfigValue(cfg.ExcludePatterns, defaultCfg.ExcludePatterns, []string(nil)).([]string)
+	for _, pattern := range excludePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return distgo.DisterParam{}, errors.Errorf("invalid exclude-patterns entry %q: %v", pattern, err)
+		}
+	}
+	var executableMode os.FileMode
+	if executableModeCfg := getConfigStringValue(cfg.ExecutableMode, defaultCfg.ExecutableMode, ""); executableModeCfg != "" {
+		modeVal, err := strconv.ParseUint(executableModeCfg, 8, 32)
+		if err != nil || modeVal > 0777 || modeVal&0100 == 0 {
+			return distgo.DisterParam{}, errors.Errorf("executable-mode must be an octal value between 0100 and 0777 that grants execute permission to the owner, was %q", executableModeCfg)
+		}
+		executableMode = os.FileMode(modeVal)
+	}
+	manifest := distgo.ManifestLocation(getConfigStringValue(cfg.Manifest, defaultCfg.Manifest, ""))
+	switch manifest {
+	case distgo.ManifestNone, distgo.ManifestArchive, distgo.ManifestSidecar:
+	default:
+		return distgo.DisterParam{}, errors.Errorf("invalid manifest %q: must be one of %q or %q", manifest, distgo.ManifestArchive, distgo.ManifestSidecar)
+	}
+	checksumAlgorithm := distgo.ChecksumAlgorithm(getConfigStringValue(cfg.ChecksumAlgorithm, defaultCfg.ChecksumAlgorithm, ""))
+	switch checksumAlgorithm {
+	case "", distgo.ChecksumSHA256, distgo.ChecksumSHA512, distgo.ChecksumBLAKE2b:
+	default:
+		return distgo.DisterParam{}, errors.Errorf("invalid checksum-algorithm %q: must be one of %q, %q or %q", checksumAlgorithm, distgo.ChecksumSHA256, distgo.ChecksumSHA512, distgo.ChecksumBLAKE2b)
+	}
 	return distgo.DisterParam{
-		NameTemplate: getConfigStringValue(cfg.NameTemplate, defaultCfg.NameTemplate, "{{Product}}-{{Version}}"),
-		InputDir:     inputDirCfg.ToParam(),
-		Script:       distgo.CreateScriptContent(getConfigStringValue(cfg.Script, defaultCfg.Script, ""), scriptIncludes),
-		Dister:       dister,
+		NameTemplate:      getConfigStringValue(cfg.NameTemplate, defaultCfg.NameTemplate, "{{Product}}-{{Version}}"),
+		InputDir:          inputDirCfg.ToParam(),
+		InputFiles:        inputFiles,
+		ExcludePatterns:   excludePatterns,
+		ExecutableMode:    executableMode,
+		Script:            distgo.CreateScriptContent(getConfigStringValue(cfg.Script, defaultCfg.Script, ""), scriptIncludes),
+		Manifest:          manifest,
+		ChecksumAlgorithm: checksumAlgorithm,
+		Dister:            dister,
+	}, nil
+}
+
+type FileMappingConfig v0.FileMappingConfig
+
+func ToFileMappingConfigs(in []FileMappingConfig) *[]v0.FileMappingConfig {
+	if in == nil {
+		return nil
+	}
+	out := make([]v0.FileMappingConfig, len(in))
+	for i, v := range in {
+		out[i] = v0.FileMappingConfig(v)
+	}
+	return &out
+}
+
+func (cfg *FileMappingConfig) ToParam() (distgo.FileMapping, error) {
+	if cfg.Source == "" {
+		return distgo.FileMapping{}, errors.Errorf("source must be specified")
+	}
+	if path.IsAbs(cfg.Source) {
+		return distgo.FileMapping{}, errors.Errorf("source %s must be a path relative to the project directory", cfg.Source)
+	}
+	if cfg.Destination == "" {
+		return distgo.FileMapping{}, errors.Errorf("destination must be specified for source %s", cfg.Source)
+	}
+	if cleanDst := path.Clean(cfg.Destination); path.IsAbs(cleanDst) || cleanDst == ".." || strings.HasPrefix(cleanDst, "../") {
+		return distgo.FileMapping{}, errors.Errorf("destination %s for source %s must be a path within the dist work directory", cfg.Destination, cfg.Source)
+	}
+	var mode os.FileMode
+	if cfg.Mode != "" {
+		modeVal, err := strconv.ParseUint(cfg.Mode, 8, 32)
+		if err != nil || modeVal > 0777 {
+			return distgo.FileMapping{}, errors.Errorf("mode for source %s must be an octal value between 0000 and 0777, was %q", cfg.Source, cfg.Mode)
+		}
+		mode = os.FileMode(modeVal)
+	}
+	return distgo.FileMapping{
+		Source:      cfg.Source,
+		Destination: cfg.Destination,
+		Mode:        mode,
 	}, nil
 }
 
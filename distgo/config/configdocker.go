@@ -17,6 +17,7 @@ package config
 import (
 	"github.com/palantir/distgo/distgo"
 	v0 "github.com/palantir/distgo/distgo/config/internal/v0"
+	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
@@ -32,9 +33,60 @@ func (cfg *DockerConfig) ToParam(scriptIncludes string, defaultCfg DockerConfig,
 	if err != nil {
 		return distgo.DockerParam{}, err
 	}
+
+	var manifestListParams map[distgo.DockerManifestListID]distgo.DockerManifestListParam
+	if manifestListCfgs := getConfigValue(cfg.ManifestLists, defaultCfg.ManifestLists, nil).(map[distgo.DockerManifestListID]v0.DockerManifestListConfig); len(manifestListCfgs) > 0 {
+		manifestListParams = make(map[distgo.DockerManifestListID]distgo.DockerManifestListParam, len(manifestListCfgs))
+		for manifestListID, manifestListCfg := range manifestListCfgs {
+			currParam, err := (*DockerManifestListConfig)(&manifestListCfg).ToParam()
+			if err != nil {
+				return distgo.DockerParam{}, errors.Wrapf(err, "failed to generate parameter for manifest list configuration %s", manifestListID)
+			}
+			manifestListParams[manifestListID] = currParam
+		}
+	}
+
 	return distgo.DockerParam{
 		Repository:          getConfigStringValue(cfg.Repository, defaultCfg.Repository, ""),
 		DockerBuilderParams: dockerBuilderParams,
+		ManifestListParams:  manifestListParams,
+	}, nil
+}
+
+type DockerManifestListConfig v0.DockerManifestListConfig
+
+func ToManifestLists(in *map[distgo.DockerManifestListID]DockerManifestListConfig) *map[distgo.DockerManifestListID]v0.DockerManifestListConfig {
+	if in == nil {
+		return nil
+	}
+	out := make(map[distgo.DockerManifestListID]v0.DockerManifestListConfig, len(*in))
+	for k, v := range *in {
+		out[k] = v0.DockerManifestListConfig(v)
+	}
+	return &out
+}
+
+func (cfg *DockerManifestListConfig) ToParam() (distgo.DockerManifestListParam, error) {
+	if cfg.TagTemplate == nil || *cfg.TagTemplate == "" {
+		return distgo.DockerManifestListParam{}, errors.Errorf("tag-template must be non-empty")
+	}
+	if cfg.Images == nil || len(*cfg.Images) == 0 {
+		return distgo.DockerManifestListParam{}, errors.Errorf("images must be non-empty")
+	}
+	imageTagTemplates := make(map[osarch.OSArch]string, len(*cfg.Images))
+	for osArchStr, imageTagTemplate := range *cfg.Images {
+		osArchVal, err := distgo.NewOSArch(osArchStr)
+		if err != nil {
+			return distgo.DockerManifestListParam{}, errors.Wrapf(err, "invalid images key")
+		}
+		if imageTagTemplate == "" {
+			return distgo.DockerManifestListParam{}, errors.Errorf("image tag for %s must be non-empty", osArchStr)
+		}
+		imageTagTemplates[osArchVal] = imageTagTemplate
+	}
+	return distgo.DockerManifestListParam{
+		TagTemplate:       *cfg.TagTemplate,
+		ImageTagTemplates: imageTagTemplates,
 	}, nil
 }
 
@@ -129,6 +181,7 @@ func (cfg *DockerBuilderConfig) ToParam(scriptIncludes string, defaultCfg Docker
 		InputBuilds:              getConfigValue(cfg.InputBuilds, defaultCfg.InputBuilds, nil).([]distgo.ProductBuildID),
 		InputDists:               getConfigValue(cfg.InputDists, defaultCfg.InputDists, nil).([]distgo.ProductDistID),
 		InputDistsOutputPaths:    getConfigValue(cfg.InputDistsOutputPaths, defaultCfg.InputDistsOutputPaths, nil).(map[distgo.ProductDistID][]string),
+		StagedDistArtifactsPath:  getConfigStringValue(cfg.StagedDistArtifactsPath, defaultCfg.StagedDistArtifactsPath, ""),
 		TagTemplates:             tagTemplates.ToParam(),
 	}, nil
 }
@@ -17,6 +17,7 @@ package config
 import (
 	"github.com/palantir/distgo/distgo"
 	v0 "github.com/palantir/distgo/distgo/config/internal/v0"
+	"github.com/pkg/errors"
 )
 
 type ProductConfig v0.ProductConfig
@@ -36,6 +37,9 @@ func (cfg *ProductConfig) ToParam(productID distgo.ProductID, scriptIncludes str
 		if err != nil {
 			return distgo.ProductParam{}, err
 		}
+		if err := distgo.ValidateBuildNameTemplate(buildParamVar.NameTemplate); err != nil {
+			return distgo.ProductParam{}, errors.Wrapf(err, "invalid name-template for product %s", productID)
+		}
 		buildParam = &buildParamVar
 	}
 
@@ -100,6 +104,11 @@ func (cfg *ProductConfig) ToParam(productID distgo.ProductID, scriptIncludes str
 			firstLevelDeps = append(firstLevelDeps, currDep)
 		}
 	}
+	versionFromGit := getConfigValue(cfg.VersionFromGit, defaultCfg.VersionFromGit, false).(bool)
+	versionTagPrefix := getConfigStringValue(cfg.VersionTagPrefix, defaultCfg.VersionTagPrefix, "")
+	if versionTagPrefix != "" && !versionFromGit {
+		return distgo.ProductParam{}, errors.Errorf("version-tag-prefix for product %s cannot be specified if version-from-git is not true", productID)
+	}
 	return distgo.ProductParam{
 		ID:                     productID,
 		Build:                  buildParam,
@@ -108,5 +117,7 @@ func (cfg *ProductConfig) ToParam(productID distgo.ProductID, scriptIncludes str
 		Publish:                publishParam,
 		Docker:                 dockerParam,
 		FirstLevelDependencies: firstLevelDeps,
+		VersionFromGit:         versionFromGit,
+		VersionTagPrefix:       versionTagPrefix,
 	}, nil
 }
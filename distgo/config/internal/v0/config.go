@@ -157,7 +157,8 @@ func upgradeProductAssets(
 
 		for _, distID := range sortedDistIDs {
 			dister := (*cfg.Dist.Disters)[distID]
-			if dister.Config == nil {
+			if dister.Config == nil || dister.Type == nil || !containsType(disterFactory.Types(), *dister.Type) {
+				// configuration for disters of unknown types is left untouched
 				continue
 			}
 
@@ -245,7 +246,8 @@ func upgradeProductAssets(
 
 		for _, publisherTypeID := range sortedPublisherTypeIDs {
 			publisher := (*cfg.Publish.PublishInfo)[publisherTypeID]
-			if publisher.Config == nil {
+			if publisher.Config == nil || !containsType(publisherFactory.Types(), string(publisherTypeID)) {
+				// configuration for publishers of unknown types is left untouched
 				continue
 			}
 
@@ -280,3 +282,12 @@ func upgradeProductAssets(
 	}
 	return changed, nil
 }
+
+func containsType(types []string, typeName string) bool {
+	for _, currType := range types {
+		if currType == typeName {
+			return true
+		}
+	}
+	return false
+}
@@ -23,8 +23,20 @@ type BuildConfig struct {
 	// template:
 	//   * {{Product}}: the name of the product
 	//   * {{Version}}: the version of the project
+	//   * {{OSArch}}: the OS/architecture of the executable (for example, "linux-amd64")
+	//   * {{GOOS}}: the GOOS of the executable (for example, "linux")
+	//   * {{GOARCH}}: the GOARCH of the executable without the architecture variant (for example, "arm" for "arm-7")
+	//   * {{ExeExt}}: ".exe" if the OS of the executable is Windows, ".wasm" if the OS of the executable is "js" or
+	//     "wasip1", and the empty string otherwise
 	//
-	// If a value is not specified, "{{Product}}" is used as the default value.
+	// The template can also use the "lower", "upper", "trimPrefix" and "replace" string functions (refer to the
+	// documentation for the distgo.StringTemplateFunctions function). For example, the following template removes a
+	// leading "v" from the version:
+	//
+	//   name-template: '{{Product}}-{{Version | trimPrefix "v"}}'
+	//
+	// The template is validated when the configuration is loaded, and referencing any other parameter is an error. If a
+	// value is not specified, "{{Product}}" is used as the default value.
 	NameTemplate *string `yaml:"name-template,omitempty"`
 
 	// OutputDir specifies the default build output directory for products executables built by the "build" task. The
@@ -34,15 +46,41 @@ type BuildConfig struct {
 	OutputDir *string `yaml:"output-dir,omitempty"`
 
 	// MainPkg is the location of the main package for the product relative to the project root directory. For example,
-	// "./distgo/main".
+	// "./distgo/main". If ModuleDir is specified, MainPkg is relative to ModuleDir instead. If not specified, the product
+	// does not have a main package: the "build" task does not create any executables for the product, but the other
+	// tasks (such as "dist" and "publish") can still be run for it. This can be used for products that are not written in
+	// Go whose dist outputs consist only of input files.
 	MainPkg *string `yaml:"main-pkg,omitempty"`
 
+	// OSArchMainPkg specifies main packages that are built instead of MainPkg for specific GOOS-GOARCHs. The keys must
+	// be of the form "GOOS-GOARCH" or "GOOS-GOARCH-VARIANT" and the values are interpreted in the same manner as MainPkg.
+	// MainPkg is built for any OS/architecture that does not have an entry. Can only be specified if MainPkg is
+	// specified. For example, the following builds a main package that embeds a resource manifest for Windows:
+	//
+	//   main-pkg: ./cmd/app
+	//   os-arch-main-pkg:
+	//     windows-amd64: ./cmd/app-windows
+	OSArchMainPkg *map[string]string `yaml:"os-arch-main-pkg,omitempty"`
+
+	// ModuleDir is the directory of the Go module that contains MainPkg relative to the project root directory. Must be
+	// specified if the main package is in a nested module rather than in the module at the project root. The "build"
+	// command is run with ModuleDir as its working directory, so the go.mod of that module (and its vendor directory and
+	// any GOFLAGS specified in Environment) is used for the build. For example, if the main package is in
+	// "./tools/cmd/gen" and "./tools" contains its own go.mod:
+	//
+	//   module-dir: tools
+	//   main-pkg: ./cmd/gen
+	ModuleDir *string `yaml:"module-dir,omitempty"`
+
 	// BuildArgsScript is the content of a script that is written to a file and run before this product is built
 	// to provide supplemental build arguments for the product. The content of this value is written to a file and
 	// executed. The script process uses the project directory as its working directory and inherits the environment
-	// variables of the Go process. Each line of the output of the script is provided to the "build" command as a
-	// separate argument. For example, the following script would add the arguments "-ldflags" "-X" "main.year=$YEAR" to
-	// the build command:
+	// variables of the Go process. The script is run for each OS/architecture that is built, and the PRODUCT, VERSION,
+	// OS and ARCH environment variables are set to the product, version, GOOS and GOARCH of the build (ARCH_VARIANT is
+	// also set if the OS/architecture specifies a variant). Refer to the documentation for the
+	// distgo.BuildArgsScriptEnvVariables function for all of the environment variables. Each line of the output of the
+	// script is provided to the "build" command as a separate argument. For example, the following script would add the
+	// arguments "-ldflags" "-X" "main.year=$YEAR" to the build command:
 	//
 	//   #!/usr/bin/env bash
 	//   YEAR=$(date +%Y)
@@ -51,24 +89,252 @@ type BuildConfig struct {
 	//   echo "main.year=$YEAR"
 	BuildArgsScript *string `yaml:"build-args-script,omitempty"`
 
+	// BuildArgsScriptFile is the path to a file relative to the project directory that contains the build arguments
+	// script. Useful for long scripts that are unwieldy to specify inline. The content of the file is used in exactly
+	// the same manner as BuildArgsScript (including the script includes of the project). Cannot be specified if
+	// BuildArgsScript is specified.
+	BuildArgsScriptFile *string `yaml:"build-args-script-file,omitempty"`
+
 	// VersionVar is the path to a variable that is set with the version information for the build. For example,
 	// "github.com/palantir/godel/v2/cmd/godel.Version". If specified, it is provided to the "build" command as an
 	// ldflag.
 	VersionVar *string `yaml:"version-var,omitempty"`
 
+	// VersionVarValue is the template that is rendered to produce the value of VersionVar. The template can use the
+	// "{{Product}}", "{{Version}}", "{{GitCommit}}" and "{{BuildTime}}" functions and the "lower", "upper", "trimPrefix"
+	// and "replace" string functions. If not specified, defaults to "{{Version}}".
+	VersionVarValue *string `yaml:"version-var-value,omitempty"`
+
+	// VersionVars specifies additional variables whose values are set using the "-X" linker flag. The value of each
+	// variable is a template that can use the "{{Product}}", "{{Version}}", "{{GitCommit}}" and "{{BuildTime}}"
+	// functions, and defaults to "{{Version}}" if not specified. For example:
+	//
+	//   version-vars:
+	//     - variable: main.version
+	//     - variable: github.com/org/product/internal/build.GitCommit
+	//       value: "{{GitCommit}}"
+	VersionVars *[]VersionVarConfig `yaml:"version-vars,omitempty"`
+
+	// VersionFile specifies a Go file with build metadata that is generated in a package of the product before it is
+	// built. Unlike version variables, the generated file can declare typed constants and struct values. The file is
+	// removed after the build (or, if a file already existed at its path, the original file is restored), so a
+	// placeholder version of the file can be committed so that the package compiles without distgo. For example:
+	//
+	//   version-file:
+	//     dir: internal/version
+	//     template: |
+	//       var Info = BuildInfo{
+	//         Version: {{printf "%q" Version}},
+	//         Commit:  {{printf "%q" GitCommit}},
+	//       }
+	VersionFile *VersionFileConfig `yaml:"version-file,omitempty"`
+
+	// VersionCheckArgs specifies the arguments with which the built executable is run after it is built to verify that
+	// its version is correct. If specified, the executable built for the OS/architecture of the host is run with the
+	// arguments and the build fails if its output does not contain the version of the project. Executables built for
+	// other OS/architectures are not checked. For example:
+	//
+	//   version-check-args:
+	//     - --version
+	VersionCheckArgs *[]string `yaml:"version-check-args,omitempty"`
+
 	// Environment specifies values for the environment variables that should be set for the build. For example,
 	// the following sets CGO to false:
 	//
 	//   environment:
 	//     CGO_ENABLED: "0"
+	//
+	// References to environment variables of the form "$VAR" or "${VAR}" in the values are expanded using the
+	// environment of the process that runs the build, and references to variables that are not set expand to the empty
+	// string. Use "$$" for a literal "$". For example, the following sets the "-mod" flag based on the value of the
+	// GO_MOD_MODE environment variable:
+	//
+	//   environment:
+	//     GOFLAGS: "-mod=${GO_MOD_MODE}"
 	Environment *map[string]string `yaml:"environment,omitempty"`
 
+	// OSArchEnvironment specifies values for environment variables that should be set only when building for a
+	// specific GOOS-GOARCH. The keys must be of the form "GOOS-GOARCH" or "GOOS-GOARCH-VARIANT". The values for an
+	// OSArch are merged over the values in Environment, and the OSArch-specific value is used if a variable is defined
+	// in both. References to environment variables in the values are expanded in the same manner as in Environment.
+	// For example, the following uses a different C compiler when building for linux-arm64:
+	//
+	//   os-arch-environment:
+	//     linux-arm64:
+	//       CC: aarch64-linux-gnu-gcc
+	OSArchEnvironment *map[string]map[string]string `yaml:"os-arch-environment,omitempty"`
+
+	// CrossCompilers specifies the C and C++ compilers that CGo should use when building for specific GOOS-GOARCHs.
+	// The keys must be of the form "GOOS-GOARCH" or "GOOS-GOARCH-VARIANT". The compilers for an OSArch are exported as
+	// the CC and CXX environment variables only when building for that OSArch, and the value of "cgo-cflags" is
+	// appended to CGO_CFLAGS. Values in OSArchEnvironment take precedence over these values. For example:
+	//
+	//   cross-compilers:
+	//     linux-arm64:
+	//       cc: aarch64-linux-gnu-gcc
+	//       cxx: aarch64-linux-gnu-g++
+	CrossCompilers *map[string]CrossCompilerConfig `yaml:"cross-compilers,omitempty"`
+
+	// DisableCgoForCrossCompile specifies whether CGo should be disabled automatically when cross-compiling. If true,
+	// builds for a GOOS-GOARCH that differs from that of the host set CGO_ENABLED to "0" (and print a warning) unless
+	// the environment for the GOOS-GOARCH sets CC (for example, using cross-compilers) or CGO_ENABLED. Builds for the
+	// host GOOS-GOARCH are not affected. Because the race detector requires CGo, building a GOOS-GOARCH for which CGo
+	// would be disabled in this manner is an error if "race" is true. If not specified, defaults to false.
+	DisableCgoForCrossCompile *bool `yaml:"disable-cgo-for-cross-compile,omitempty"`
+
+	// GoExperiment specifies the value of the GOEXPERIMENT environment variable for the build. If the environment for
+	// the build also sets GOEXPERIMENT, this value is appended to it using a comma rather than replacing it, so it
+	// takes precedence for any experiment that is specified in both. For example:
+	//
+	//   go-experiment: loopvar
+	GoExperiment *string `yaml:"go-experiment,omitempty"`
+
+	// GoFlags specifies additional flags that are provided to the "build" command. Every entry must start with "-".
+	// The flags are provided after the arguments generated by build-args-script and before the flags that distgo
+	// generates. If a "-tags", "-gcflags", "-asmflags" or "-ldflags" flag is specified, the values that distgo
+	// generates for that flag (for example, from build-tags or version-var) are merged into its value rather than
+	// overriding it. For example:
+	//
+	//   go-flags:
+	//     - -mod=vendor
+	//     - -ldflags=-extldflags=-static
+	GoFlags *[]string `yaml:"go-flags,omitempty"`
+
+	// Trimpath specifies whether the "-trimpath" flag should be provided to the "build" command. If true, file system
+	// paths are removed from the resulting executable. If not specified, defaults to false.
+	Trimpath *bool `yaml:"trimpath,omitempty"`
+
+	// BuildTags specifies the build tags that should be provided to the "build" command. For example:
+	//
+	//   build-tags:
+	//     - enterprise
+	//     - netgo
+	BuildTags *[]string `yaml:"build-tags,omitempty"`
+
+	// GCFlags specifies the arguments that should be provided to the compiler using the "-gcflags" flag of the "build"
+	// command. The entries are joined using spaces and provided as a single argument. For example, the following
+	// disables optimizations and inlining for all packages:
+	//
+	//   gc-flags:
+	//     - all=-N
+	//     - -l
+	GCFlags *[]string `yaml:"gc-flags,omitempty"`
+
+	// AsmFlags specifies the arguments that should be provided to the assembler using the "-asmflags" flag of the
+	// "build" command. The entries are joined using spaces and provided as a single argument.
+	AsmFlags *[]string `yaml:"asm-flags,omitempty"`
+
+	// LDFlags specifies the arguments that should be provided to the linker using the "-ldflags" flag of the "build"
+	// command. The entries are joined using spaces in the order in which they are specified and are combined with the
+	// linker flags that distgo generates (for "strip-debug" and the version variables) into a single "-ldflags"
+	// argument, so they do not override the version variables. For example:
+	//
+	//   ld-flags:
+	//     - -linkmode
+	//     - external
+	//     - -extldflags
+	//     - "'-static'"
+	LDFlags *[]string `yaml:"ld-flags,omitempty"`
+
+	// Race specifies whether the "-race" flag should be provided to the "build" command. The race detector requires
+	// CGo, so the environment for the build must not set CGO_ENABLED to "0" if this value is true. If not specified,
+	// defaults to false.
+	Race *bool `yaml:"race,omitempty"`
+
+	// BuildMode specifies the value of the "-buildmode" flag that is provided to the "build" command. If specified,
+	// must be one of the build modes supported by Go (see "go help buildmode"). If the build mode produces a library
+	// (for example, "c-shared"), the extension for the library (for example, ".so") is appended to the name of the
+	// build output.
+	BuildMode *string `yaml:"build-mode,omitempty"`
+
+	// StripDebug specifies whether the symbol table and DWARF debugging information should be omitted from the
+	// resulting executable. If true, "-s -w" is added to the linker flags provided to the "build" command. If not
+	// specified, defaults to false.
+	StripDebug *bool `yaml:"strip-debug,omitempty"`
+
+	// MaxOutputBytes is the maximum size in bytes of the build output for each OS/architecture. If specified and
+	// greater than 0, the build fails if the size of the executable (or library) it produces exceeds this value. The
+	// size is checked after any other options (such as StripDebug) have been applied. If not specified, the size of
+	// the build output is not checked.
+	MaxOutputBytes *int64 `yaml:"max-output-bytes,omitempty"`
+
+	// CleanOutputDir specifies whether the build output directory for an OS/architecture of the product
+	// ("{{OutputDir}}/{{ID}}/{{Version}}/{{OSArch}}") is removed before the product is built for that OS/architecture.
+	// If true, files from previous builds (for example, a ".so" file from a build that used a different build mode) are
+	// removed so that they are not included in distributions. The output directories for other products, versions and
+	// OS/architectures are never removed. If not specified, defaults to false.
+	CleanOutputDir *bool `yaml:"clean-output-dir,omitempty"`
+
+	// LatestLink specifies whether "{{OutputDir}}/{{ID}}/latest" is updated to refer to the version directory of the
+	// product after every successful build. The entry is a symbolic link if symbolic links are supported and a text
+	// file that contains the name of the version directory otherwise. If not specified, defaults to false.
+	LatestLink *bool `yaml:"latest-link,omitempty"`
+
 	// Script is the content of a script that is written to a file and run before the build processes start. The script
 	// process inherits the environment variables of the Go process and also has project-related environment variables.
 	// Refer to the documentation for the distgo.BuildScriptEnvVariables function for the extra environment variables.
+	// The exit code of the script determines how the build proceeds:
+	//   * 0: the product is built
+	//   * 2: the product is skipped: it is not built and the "build" task reports it as skipped
+	//   * any other value: the "build" task fails
 	Script *string `yaml:"script,omitempty"`
 
+	// GoBinary specifies the Go executable that is used to build the product. The value can be an absolute path, a path
+	// relative to the project directory or the name of an executable on the PATH. If not specified, defaults to "go".
+	GoBinary *string `yaml:"go-binary,omitempty"`
+
+	// BuildRetries is the number of times the "build" command is retried if it fails with an error that appears to be
+	// transient, such as a network error encountered while downloading modules. Compilation errors are not retried. If
+	// not specified, defaults to 0.
+	BuildRetries *int `yaml:"build-retries,omitempty"`
+
+	// BuildRetryBackoff is the duration to wait before the first retry of the "build" command (for example, "5s"). The
+	// duration doubles for every subsequent retry. If not specified, defaults to "1s".
+	BuildRetryBackoff *string `yaml:"build-retry-backoff,omitempty"`
+
 	// OSArchs specifies the GOOS and GOARCH pairs for which the product is built. If blank, defaults to the GOOS
-	// and GOARCH of the host system at runtime.
+	// and GOARCH of the host system at runtime. The arch may specify a variant of the architecture in the form
+	// "GOARCH-VARIANT", in which case the variant is set using the corresponding environment variable (GOARM, GOMIPS,
+	// GOMIPS64, GO386, GOAMD64 or GOPPC64) when building. For example, the following builds for ARMv6 and ARMv7:
+	//
+	//   os-archs:
+	//     - os: linux
+	//       arch: arm-6
+	//     - os: linux
+	//       arch: arm-7
 	OSArchs *[]osarch.OSArch `yaml:"os-archs,omitempty"`
 }
+
+type VersionVarConfig struct {
+	// Variable is the path to the variable whose value should be set. For example, "main.version".
+	Variable string `yaml:"variable,omitempty"`
+
+	// Value is the template that is rendered to produce the value of the variable. If not specified, defaults to
+	// "{{Version}}".
+	Value string `yaml:"value,omitempty"`
+}
+
+type VersionFileConfig struct {
+	// Dir is the directory of the package in which the file is generated, relative to the module directory of the
+	// product. Must be specified.
+	Dir string `yaml:"dir,omitempty"`
+
+	// FileName is the name of the generated file. If not specified, defaults to "distgo_version.go".
+	FileName string `yaml:"file-name,omitempty"`
+
+	// Template is the template that is rendered to produce the declarations in the generated file. The package clause
+	// is generated by distgo. The template can use the "{{Product}}", "{{Version}}", "{{GitCommit}}" and
+	// "{{BuildTime}}" functions. If not specified, the file declares the string constants "Product" and "Version".
+	Template string `yaml:"template,omitempty"`
+}
+
+type CrossCompilerConfig struct {
+	// CC is the C compiler that is exported as the CC environment variable.
+	CC string `yaml:"cc,omitempty"`
+
+	// CXX is the C++ compiler that is exported as the CXX environment variable.
+	CXX string `yaml:"cxx,omitempty"`
+
+	// CGOCFlags specifies additional flags for the C compiler that are appended to CGO_CFLAGS.
+	CGOCFlags string `yaml:"cgo-cflags,omitempty"`
+}
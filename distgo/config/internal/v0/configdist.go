@@ -33,6 +33,13 @@ type DistConfig struct {
 	// Disters is the configuration for the disters for this product. The YAML representation can be a single DisterConfig
 	// or a map[DistID]DisterConfig.
 	Disters *DistersConfig `yaml:"disters,omitempty"`
+
+	// GPGKey specifies the GPG key used to sign the distribution artifacts. The value is either the path to a file that
+	// contains the key (absolute or relative to the project directory) or the ID of a key in the default keyring. If
+	// specified, a detached armored signature is written to "{{Artifact}}.asc" for every distribution artifact. The
+	// passphrase for the key cannot be specified in configuration: if the key requires a passphrase, it must be
+	// provided using the DISTGO_GPG_PASSPHRASE environment variable.
+	GPGKey *string `yaml:"gpg-key,omitempty"`
 }
 
 type DisterConfig struct {
@@ -47,6 +54,9 @@ type DisterConfig struct {
 	//   * {{Product}}: the name of the product.
 	//   * {{Version}}: the version of the project.
 	//
+	// The template can also use the "lower", "upper", "trimPrefix" and "replace" string functions (refer to the
+	// documentation for the distgo.StringTemplateFunctions function).
+	//
 	// If a value is not specified, "{{Product}}-{{Version}}" is used as the default value.
 	NameTemplate *string `yaml:"name-template,omitempty"`
 
@@ -55,12 +65,74 @@ type DisterConfig struct {
 	// skipped.
 	InputDir *InputDirConfig `yaml:"input-dir,omitempty"`
 
+	// InputFiles specifies files that are copied to the dist work directory before the distribution operation is run.
+	// Files are copied after the contents of InputDir. The source of an entry is a path relative to the project
+	// directory and may be a glob pattern. The destination is a path relative to the dist work directory: if the source
+	// is a glob pattern or the destination ends with "/", the destination is treated as a directory and the matching
+	// files are copied into it. For example:
+	//
+	//   input-files:
+	//     - source: LICENSE
+	//       destination: LICENSE
+	//     - source: deploy/*.service
+	//       destination: service/
+	//     - source: config/sample.yml
+	//       destination: config/foo.yml
+	//       mode: "0600"
+	InputFiles *[]FileMappingConfig `yaml:"input-files,omitempty"`
+
+	// ExcludePatterns specifies glob patterns for files that are excluded from the distribution. The patterns are
+	// applied to the dist work directory after the dist script is run (and before the manifest and the dist artifacts
+	// are generated), so they apply consistently to build artifacts, the contents of InputDir and InputFiles. A pattern
+	// that does not contain a "/" is matched against the base name of every file and directory; otherwise, it is
+	// matched against the path relative to the dist work directory. A directory that matches a pattern is excluded
+	// with all of its contents. For example:
+	//
+	//   exclude-patterns:
+	//     - "*.debug"
+	//     - testdata
+	//     - bin/*/fixtures
+	ExcludePatterns *[]string `yaml:"exclude-patterns,omitempty"`
+
+	// ExecutableMode is the octal representation of the permission bits of the executables of the product (and its
+	// dependencies) in the distribution (for example, "0750"). The executables are packaged with this mode even if the
+	// build outputs on disk have a different mode, and disters that create archives use this mode for all of the
+	// executable files in the archive. The mode must grant execute permission to the owner. If not specified, "0755" is
+	// used.
+	ExecutableMode *string `yaml:"executable-mode,omitempty"`
+
 	// Script is the content of a script that is written to a file and run after the initial distribution process but
 	// before the artifact generation process. The content of this value is written to a file and executed with the
 	// project directory as the working directory. The script process inherits the environment variables of the Go
 	// process and also has dist-related environment variables. Refer to the documentation for the
 	// distgo.DistScriptEnvVariables function for the extra environment variables.
 	Script *string `yaml:"script,omitempty"`
+
+	// Manifest specifies whether a manifest that lists every file in a dist artifact (with its path in the artifact, its
+	// size and its SHA-256 checksum) is created for each artifact of the distribution. If "archive", the manifest is
+	// written to a "manifest.json" file that is packaged in the artifact: this is the root directory of the archive for
+	// archive disters and "usr/share/doc/{{Package}}" for package disters. If "sidecar", the manifest is written to
+	// "{{Artifact}}.manifest.json" next to the artifact (for example, "foo-1.0.0.tgz.manifest.json"). If not specified,
+	// no manifest is created.
+	Manifest *string `yaml:"manifest,omitempty"`
+
+	// ChecksumAlgorithm specifies the algorithm used to compute the checksum file that is written next to each
+	// distribution artifact when checksum files are requested (for example, using the "--sha256" flag of the "dist"
+	// task). Must be one of "sha256", "sha512" or "blake2b". The checksum file is named "{{Artifact}}.{{Algorithm}}"
+	// (for example, "foo-1.0.0.tgz.sha512"). If not specified, "sha256" is used.
+	ChecksumAlgorithm *string `yaml:"checksum-algorithm,omitempty"`
+}
+
+type FileMappingConfig struct {
+	// Source is the path to the file relative to the project directory. May be a glob pattern.
+	Source string `yaml:"source,omitempty"`
+
+	// Destination is the path of the file relative to the dist work directory.
+	Destination string `yaml:"destination,omitempty"`
+
+	// Mode is the octal representation of the permission bits of the copied file (for example, "0644"). If not
+	// specified, the mode of the source file is used.
+	Mode string `yaml:"mode,omitempty"`
 }
 
 type InputDirConfig struct {
@@ -27,6 +27,32 @@ type DockerConfig struct {
 
 	// DockerBuilderParams contains the Docker params for this distribution.
 	DockerBuildersConfig *DockerBuildersConfig `yaml:"docker-builders,omitempty"`
+
+	// ManifestLists specifies the multi-architecture manifest lists that are assembled from the per-architecture images
+	// of the product when it is pushed. The manifest lists are pushed after all of the images produced by the Docker
+	// builders have been pushed. For example, the following pushes a single manifest list that resolves to the image for
+	// the architecture of the host pulling it:
+	//
+	//   manifest-lists:
+	//     latest:
+	//       tag-template: "{{Repository}}foo:{{Version}}"
+	//       images:
+	//         linux-amd64: "{{Repository}}foo:{{Version}}-amd64"
+	//         linux-arm64: "{{Repository}}foo:{{Version}}-arm64"
+	ManifestLists *map[distgo.DockerManifestListID]DockerManifestListConfig `yaml:"manifest-lists,omitempty"`
+}
+
+type DockerManifestListConfig struct {
+	// TagTemplate is the template for the tag of the manifest list. The manifest list is created and pushed using the
+	// "docker manifest" commands, so the tag must be in the same registry as all of the images referenced by the
+	// manifest list. The template can use the "{{Product}}", "{{Version}}", "{{Repository}}" and "{{RepositoryLiteral}}"
+	// functions.
+	TagTemplate *string `yaml:"tag-template,omitempty"`
+
+	// Images maps each OS/architecture to the template for the tag of the already-built image for that OS/architecture.
+	// The keys must be of the form "GOOS-GOARCH" or "GOOS-GOARCH-VARIANT" and the values support the same templates as
+	// TagTemplate.
+	Images *map[string]string `yaml:"images,omitempty"`
 }
 
 type DockerBuildersConfig map[distgo.DockerID]DockerBuilderConfig
@@ -83,6 +109,19 @@ type DockerBuilderConfig struct {
 	// artifact is placed only in that location, and that location is returned by the {{InputDistArtifacts}} template
 	// function.
 	InputDistsOutputPaths *map[distgo.ProductDistID][]string `yaml:"input-dist-output-paths,omitempty"`
+	// StagedDistArtifactsPath is the template for the path relative to ContextDir to which the OS/architecture-specific
+	// dist artifacts of the product are hard-linked before the image is built. The staged artifacts are removed after
+	// the image is built. An artifact is specific to an OS/architecture if the dister that creates it is specific to an
+	// OS/architecture (for example, the "os-arch-bin", "deb", "rpm" and "self-extracting" disters), and artifacts that are not
+	// specific to an OS/architecture are not staged. The following template parameters can be used in the template:
+	//   * {{Product}}: the name of the product
+	//   * {{Version}}: the version of the project
+	//   * {{DistID}}: the DistID of the dist that produced the artifact
+	//   * {{OSArch}}: the OS/architecture of the artifact (for example, "linux-amd64")
+	//   * {{Artifact}}: the file name of the artifact
+	//
+	// For example, "dist/{{OSArch}}/{{Artifact}}" stages the artifacts in a directory per OS/architecture.
+	StagedDistArtifactsPath *string `yaml:"staged-dist-artifacts-path,omitempty"`
 	// TagTemplates specifies the templates that should be used to render the tag(s) for the Docker image. If multiple
 	// values are specified, the image will be tagged with all of them.
 	TagTemplates *TagTemplatesMap `yaml:"tag-templates,omitempty"`
@@ -35,6 +35,20 @@ type ProductConfig struct {
 	// Docker specifies the Docker configuration for the product.
 	Docker *DockerConfig `yaml:"docker,omitempty"`
 
-	// Dependencies specifies the first-level dependencies of this product. Stores the IDs of the products.
+	// Dependencies specifies the first-level dependencies of this product. Stores the IDs of the products. When products
+	// are built or dist'd together, the dependencies of a product are built and dist'd before the product itself. The
+	// dependencies must not contain a cycle.
 	Dependencies *[]distgo.ProductID `yaml:"dependencies,omitempty"`
+
+	// VersionFromGit specifies whether the version of this product is derived from the git repository of the project
+	// rather than using the project version. If true, the version is the output of "git describe --tags" for the
+	// project directory (with a ".dirty" suffix if the repository has uncommitted changes, or "unspecified" if no tags
+	// describe the current commit). The version is used everywhere the project version would otherwise be used for
+	// the product, including VersionVar, output paths and templates. If not specified, defaults to false.
+	VersionFromGit *bool `yaml:"version-from-git,omitempty"`
+
+	// VersionTagPrefix specifies the prefix of the tags that are considered when deriving the version of this product
+	// from git. Only used if VersionFromGit is true. The prefix is removed from the resulting version. Useful if a single
+	// repository contains multiple products that are tagged independently (for example, "foo@1.0.0" and "bar@2.0.0").
+	VersionTagPrefix *string `yaml:"version-tag-prefix,omitempty"`
 }
@@ -0,0 +1,285 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	v0 "github.com/palantir/distgo/distgo/config/internal/v0"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+type jsonSchema map[string]interface{}
+
+// JSONSchema returns a JSON Schema that describes the distgo project configuration. The schema is generated from the
+// configuration structs, so it matches the YAML that is accepted when configuration is unmarshalled. disterConfigs and
+// publisherConfigs map the type names of disters and publishers to a value of the struct that represents their
+// configuration: if a dister or publisher of a known type is specified, its "config" value is validated against the
+// schema for that struct. The "config" values of other types are not constrained.
+func JSONSchema(disterConfigs, publisherConfigs map[string]interface{}) ([]byte, error) {
+	disterSchemas, err := configSchemas(disterConfigs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate schema for dister configuration")
+	}
+	publisherSchemas, err := configSchemas(publisherConfigs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate schema for publisher configuration")
+	}
+
+	g := &schemaGenerator{
+		overrides: map[reflect.Type]func() (jsonSchema, error){},
+	}
+	g.overrides[reflect.TypeOf(yaml.MapSlice{})] = func() (jsonSchema, error) {
+		return jsonSchema{"type": "object"}, nil
+	}
+	g.overrides[reflect.TypeOf(v0.InputDirConfig{})] = func() (jsonSchema, error) {
+		type inputDirConfigAlias v0.InputDirConfig
+		objSchema, err := g.schemaFor(reflect.TypeOf(inputDirConfigAlias{}))
+		if err != nil {
+			return nil, err
+		}
+		return jsonSchema{"anyOf": []interface{}{jsonSchema{"type": "string"}, objSchema}}, nil
+	}
+	g.overrides[reflect.TypeOf(v0.DisterConfig{})] = func() (jsonSchema, error) {
+		type disterConfigAlias v0.DisterConfig
+		schema, err := g.schemaFor(reflect.TypeOf(disterConfigAlias{}))
+		if err != nil {
+			return nil, err
+		}
+		if conditions := typedConfigConditions(disterSchemas); len(conditions) > 0 {
+			schema["allOf"] = conditions
+		}
+		return schema, nil
+	}
+	g.overrides[reflect.TypeOf(v0.DistersConfig{})] = func() (jsonSchema, error) {
+		disterSchema, err := g.schemaFor(reflect.TypeOf(v0.DisterConfig{}))
+		if err != nil {
+			return nil, err
+		}
+		// a single dister configuration is only valid if its "type" is specified
+		singleSchema := jsonSchema{}
+		for k, v := range disterSchema {
+			singleSchema[k] = v
+		}
+		singleSchema["required"] = []interface{}{"type"}
+		return jsonSchema{"anyOf": []interface{}{
+			singleSchema,
+			jsonSchema{"type": "object", "additionalProperties": disterSchema},
+		}}, nil
+	}
+	g.overrides[reflect.TypeOf(v0.TagTemplatesMap{})] = func() (jsonSchema, error) {
+		return jsonSchema{"anyOf": []interface{}{
+			jsonSchema{"type": "string"},
+			jsonSchema{"type": "array", "items": jsonSchema{"type": "string"}},
+			jsonSchema{"type": "object", "additionalProperties": jsonSchema{"type": "string"}},
+		}}, nil
+	}
+	g.overrides[reflect.TypeOf(v0.PublishConfig{})] = func() (jsonSchema, error) {
+		type publishConfigAlias v0.PublishConfig
+		schema, err := g.schemaFor(reflect.TypeOf(publishConfigAlias{}))
+		if err != nil {
+			return nil, err
+		}
+		publisherSchema, err := g.schemaFor(reflect.TypeOf(v0.PublisherConfig{}))
+		if err != nil {
+			return nil, err
+		}
+		infoProperties := jsonSchema{}
+		for typeName, cfgSchema := range publisherSchemas {
+			infoProperties[typeName] = jsonSchema{
+				"type": "object",
+				"properties": jsonSchema{
+					"config": cfgSchema,
+				},
+				"additionalProperties": false,
+			}
+		}
+		infoSchema := jsonSchema{
+			"type":                 "object",
+			"additionalProperties": publisherSchema,
+		}
+		if len(infoProperties) > 0 {
+			infoSchema["properties"] = infoProperties
+		}
+		schema["properties"].(jsonSchema)["info"] = infoSchema
+		return schema, nil
+	}
+
+	schema, err := g.schemaFor(reflect.TypeOf(v0.ProjectConfig{}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate schema for project configuration")
+	}
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "distgo project configuration"
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal schema as JSON")
+	}
+	return out, nil
+}
+
+func configSchemas(configs map[string]interface{}) (map[string]jsonSchema, error) {
+	g := &schemaGenerator{
+		overrides: map[reflect.Type]func() (jsonSchema, error){
+			reflect.TypeOf(yaml.MapSlice{}): func() (jsonSchema, error) {
+				return jsonSchema{"type": "object"}, nil
+			},
+		},
+	}
+	schemas := make(map[string]jsonSchema, len(configs))
+	for typeName, cfg := range configs {
+		if cfg == nil {
+			continue
+		}
+		schema, err := g.schemaFor(reflect.TypeOf(cfg))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate schema for configuration of type %s", typeName)
+		}
+		schemas[typeName] = schema
+	}
+	return schemas, nil
+}
+
+// typedConfigConditions returns conditions that require the "config" value of an object to match the schema for the type
+// specified by the "type" value of the object.
+func typedConfigConditions(schemas map[string]jsonSchema) []interface{} {
+	var typeNames []string
+	for typeName := range schemas {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	var conditions []interface{}
+	for _, typeName := range typeNames {
+		conditions = append(conditions, jsonSchema{
+			"if": jsonSchema{
+				"properties": jsonSchema{
+					"type": jsonSchema{"const": typeName},
+				},
+				"required": []interface{}{"type"},
+			},
+			"then": jsonSchema{
+				"properties": jsonSchema{
+					"config": schemas[typeName],
+				},
+			},
+		})
+	}
+	return conditions
+}
+
+type schemaGenerator struct {
+	overrides map[reflect.Type]func() (jsonSchema, error)
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) (jsonSchema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if override, ok := g.overrides[t]; ok {
+		return override()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}, nil
+	case reflect.String:
+		return jsonSchema{"type": "string"}, nil
+	case reflect.Interface:
+		return jsonSchema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return jsonSchema{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := g.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return jsonSchema{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		properties := jsonSchema{}
+		if err := g.addStructProperties(t, properties); err != nil {
+			return nil, err
+		}
+		return jsonSchema{"type": "object", "properties": properties, "additionalProperties": false}, nil
+	default:
+		return nil, errors.Errorf("unsupported type %v", t)
+	}
+}
+
+// addStructProperties adds the schemas for the fields of the provided struct type to properties. Field names are
+// determined in the same manner as they are when the struct is unmarshalled from YAML.
+func (g *schemaGenerator) addStructProperties(t reflect.Type, properties jsonSchema) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported field
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		tagParts := strings.Split(tag, ",")
+		name := tagParts[0]
+		inline := false
+		for _, flag := range tagParts[1:] {
+			if flag == "inline" {
+				inline = true
+			}
+		}
+
+		if inline {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() != reflect.Struct {
+				return errors.Errorf("inline field %s of %v is not a struct", field.Name, t)
+			}
+			if err := g.addStructProperties(fieldType, properties); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fieldSchema, err := g.schemaFor(field.Type)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate schema for field %s of %v", field.Name, t)
+		}
+		properties[name] = fieldSchema
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/palantir/distgo/dister/disterfactory"
+	distgoconfig "github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/publisher/publisherfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schemaBytes, err := distgoconfig.JSONSchema(disterfactory.BuiltinConfigs(), publisherfactory.BuiltinConfigs())
+	require.NoError(t, err)
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(schemaBytes, &schema))
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema["$schema"])
+
+	for i, tc := range []struct {
+		name  string
+		yml   string
+		valid bool
+	}{
+		{
+			"valid configuration",
+			`
+products:
+  foo:
+    build:
+      main-pkg: ./foo
+      os-archs:
+        - os: darwin
+          arch: amd64
+      environment:
+        CGO_ENABLED: "0"
+    dist:
+      disters:
+        type: os-arch-bin
+        config:
+          os-archs:
+            - os: linux
+              arch: amd64
+    publish:
+      group-id: com.palantir.foo
+      info:
+        bintray:
+          config:
+            url: https://api.bintray.com
+            username: user
+            subject: palantir
+            repository: releases
+            product: foo
+            publish: true
+            downloads-list: true
+    docker:
+      docker-builders:
+        default:
+          type: default
+          tag-templates:
+            - "{{Repository}}foo:latest"
+  bar:
+    dist:
+      disters:
+        bin:
+          type: bin
+        manual:
+          type: manual
+          config:
+            extension: tgz
+          input-dir: resources
+product-defaults:
+  publish:
+    group-id: com.palantir
+exclude:
+  names:
+    - vendor
+`,
+			true,
+		},
+		{
+			"unknown field in build configuration",
+			`
+products:
+  foo:
+    build:
+      main-package: ./foo
+`,
+			false,
+		},
+		{
+			"wrong type for field in bintray configuration",
+			`
+products:
+  foo:
+    publish:
+      info:
+        bintray:
+          config:
+            subject: palantir
+            downloads-list: "yes"
+`,
+			false,
+		},
+		{
+			"unknown field in bintray configuration",
+			`
+products:
+  foo:
+    publish:
+      info:
+        bintray:
+          config:
+            subjects: palantir
+`,
+			false,
+		},
+		{
+			"unknown field in dister configuration",
+			`
+products:
+  foo:
+    dist:
+      disters:
+        type: os-arch-bin
+        config:
+          os-arch: linux-amd64
+`,
+			false,
+		},
+	} {
+		var cfg interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(tc.yml), &cfg), "Case %d: %s", i, tc.name)
+
+		// verify that the expected result of the schema is consistent with the result of unmarshalling the configuration
+		if tc.valid {
+			var projectCfg distgoconfig.ProjectConfig
+			require.NoError(t, yaml.UnmarshalStrict([]byte(tc.yml), &projectCfg), "Case %d: %s", i, tc.name)
+		}
+
+		errs := validateJSONSchema(schema, toJSONValue(cfg), "")
+		if tc.valid {
+			assert.Empty(t, errs, "Case %d: %s", i, tc.name)
+		} else {
+			assert.NotEmpty(t, errs, "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+// toJSONValue converts a value unmarshalled from YAML into the form that it would have if it were unmarshalled from JSON.
+func toJSONValue(in interface{}) interface{} {
+	switch v := in.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[fmt.Sprint(k)] = toJSONValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = toJSONValue(val)
+		}
+		return out
+	case int:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// validateJSONSchema validates the provided value against the provided schema and returns the validation errors. Only
+// supports the subset of JSON Schema keywords that are emitted by distgoconfig.JSONSchema.
+func validateJSONSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var errs []string
+	if wantType, ok := schema["type"].(string); ok && !isJSONType(value, wantType) {
+		return []string{fmt.Sprintf("%s: expected %s, was %v", path, wantType, value)}
+	}
+	if wantConst, ok := schema["const"]; ok && !reflect.DeepEqual(wantConst, value) {
+		errs = append(errs, fmt.Sprintf("%s: expected %v, was %v", path, wantConst, value))
+	}
+	if obj, ok := value.(map[string]interface{}); ok {
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, k := range required {
+				if _, ok := obj[k.(string)]; !ok {
+					errs = append(errs, fmt.Sprintf("%s: missing required property %v", path, k))
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		var keys []string
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if propSchema, ok := properties[k]; ok {
+				errs = append(errs, validateJSONSchema(propSchema.(map[string]interface{}), obj[k], path+"."+k)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, fmt.Sprintf("%s: unknown property %s", path, k))
+				}
+			case map[string]interface{}:
+				errs = append(errs, validateJSONSchema(additional, obj[k], path+"."+k)...)
+			}
+		}
+	}
+	if arr, ok := value.([]interface{}); ok {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range arr {
+				errs = append(errs, validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, subSchema := range anyOf {
+			if len(validateJSONSchema(subSchema.(map[string]interface{}), value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s: value does not match any of the allowed schemas", path))
+		}
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, subSchema := range allOf {
+			errs = append(errs, validateJSONSchema(subSchema.(map[string]interface{}), value, path)...)
+		}
+	}
+	if ifSchema, ok := schema["if"].(map[string]interface{}); ok && len(validateJSONSchema(ifSchema, value, path)) == 0 {
+		if thenSchema, ok := schema["then"].(map[string]interface{}); ok {
+			errs = append(errs, validateJSONSchema(thenSchema, value, path)...)
+		}
+	}
+	return errs
+}
+
+func isJSONType(value interface{}, jsonType string) bool {
+	switch jsonType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	}
+	return false
+}
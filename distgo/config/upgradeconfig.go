@@ -15,6 +15,10 @@
 package config
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/distgo/config/internal/legacy"
 	v0 "github.com/palantir/distgo/distgo/config/internal/v0"
@@ -22,6 +26,39 @@ import (
 	"github.com/pkg/errors"
 )
 
+// UpgradeConfigFile upgrades the distgo configuration in the specified file and writes the upgraded configuration back
+// to the file. The configuration of every dister, Docker builder, publisher and project versioner in the file is
+// upgraded using the upgrader registered for its type; the configuration for types that are not registered is left
+// untouched. The file is only rewritten if the upgrade changes the configuration, so files that are already up-to-date
+// (including their comments and formatting) are preserved exactly. Returns true if the file was rewritten.
+func UpgradeConfigFile(
+	cfgFile string,
+	projectVersionerFactory distgo.ProjectVersionerFactory,
+	disterFactory distgo.DisterFactory,
+	dockerBuilderFactory distgo.DockerBuilderFactory,
+	publisherFactory distgo.PublisherFactory) (bool, error) {
+
+	fi, err := os.Stat(cfgFile)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to stat configuration file")
+	}
+	cfgBytes, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read configuration file")
+	}
+	upgradedCfgBytes, err := UpgradeConfig(cfgBytes, projectVersionerFactory, disterFactory, dockerBuilderFactory, publisherFactory)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to upgrade configuration file %s", cfgFile)
+	}
+	if bytes.Equal(cfgBytes, upgradedCfgBytes) {
+		return false, nil
+	}
+	if err := ioutil.WriteFile(cfgFile, upgradedCfgBytes, fi.Mode()); err != nil {
+		return false, errors.Wrapf(err, "failed to write upgraded configuration file")
+	}
+	return true, nil
+}
+
 func UpgradeConfig(
 	cfgBytes []byte,
 	projectVersionerFactory distgo.ProjectVersionerFactory,
@@ -0,0 +1,141 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/disterfactory"
+	"github.com/palantir/distgo/distgo"
+	distgoconfig "github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/dockerbuilder/dockerbuilderfactory"
+	"github.com/palantir/distgo/projectversioner/projectversionerfactory"
+	"github.com/palantir/distgo/publisher/bintray"
+	"github.com/palantir/distgo/publisher/publisherfactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renamingPublisherFactory is a publisher factory whose config upgrader for bintray renames the "repo" key to
+// "repository".
+type renamingPublisherFactory struct {
+	distgo.PublisherFactory
+}
+
+func (f renamingPublisherFactory) ConfigUpgrader(typeName string) (distgo.ConfigUpgrader, error) {
+	if typeName != bintray.TypeName {
+		return f.PublisherFactory.ConfigUpgrader(typeName)
+	}
+	return distgo.NewConfigUpgrader(bintray.TypeName, func(cfgBytes []byte) ([]byte, error) {
+		return []byte(strings.Replace(string(cfgBytes), "repo:", "repository:", -1)), nil
+	}), nil
+}
+
+func TestUpgradeConfigFile(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectVersionerFactory, err := projectversionerfactory.New(nil, nil)
+	require.NoError(t, err)
+	disterFactory, err := disterfactory.New(nil, nil)
+	require.NoError(t, err)
+	dockerBuilderFactory, err := dockerbuilderfactory.New(nil, nil)
+	require.NoError(t, err)
+	publisherFactory, err := publisherfactory.New(nil, nil)
+	require.NoError(t, err)
+	publisherFactory = renamingPublisherFactory{PublisherFactory: publisherFactory}
+
+	for i, tc := range []struct {
+		name        string
+		in          string
+		wantChanged bool
+		want        string
+	}{
+		{
+			"bintray section is upgraded and unknown section is left untouched",
+			`products:
+  foo:
+    build:
+      main-pkg: ./foo
+    publish:
+      group-id: com.test.group
+      info:
+        bintray:
+          config:
+            subject: testSubject
+            repo: testRepo
+        custom-publisher:
+          config:
+            repo: customRepo
+`,
+			true,
+			`products:
+  foo:
+    build:
+      main-pkg: ./foo
+    publish:
+      group-id: com.test.group
+      info:
+        bintray:
+          config:
+            subject: testSubject
+            repository: testRepo
+        custom-publisher:
+          config:
+            repo: customRepo
+`,
+		},
+		{
+			"configuration that does not require upgrade is not rewritten",
+			`products:
+  foo:
+    # comment
+    publish:
+      info:
+        bintray:
+          config:
+            subject:   testSubject
+            repository: testRepo
+`,
+			false,
+			`products:
+  foo:
+    # comment
+    publish:
+      info:
+        bintray:
+          config:
+            subject:   testSubject
+            repository: testRepo
+`,
+		},
+	} {
+		cfgFile := path.Join(tmpDir, "dist-plugin.yml")
+		require.NoError(t, ioutil.WriteFile(cfgFile, []byte(tc.in), 0644), "Case %d: %s", i, tc.name)
+
+		changed, err := distgoconfig.UpgradeConfigFile(cfgFile, projectVersionerFactory, disterFactory, dockerBuilderFactory, publisherFactory)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantChanged, changed, "Case %d: %s", i, tc.name)
+
+		gotBytes, err := ioutil.ReadFile(cfgFile)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, string(gotBytes), "Case %d: %s", i, tc.name)
+	}
+}
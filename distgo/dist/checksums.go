@@ -0,0 +1,170 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dist
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// SHA256FileSuffix is the suffix of the file that contains the SHA-256 checksum of a dist artifact.
+	SHA256FileSuffix = ".sha256"
+	// SHA256SumsFileName is the name of the file that contains the SHA-256 checksums of all of the dist artifacts of a
+	// product.
+	SHA256SumsFileName = "SHA256SUMS"
+)
+
+type sha256SumsEntry struct {
+	path   string
+	digest string
+}
+
+// ChecksumFileSuffix returns the suffix of the file that contains the checksum of a dist artifact computed using the
+// provided algorithm. For example, the suffix for distgo.ChecksumSHA256 is SHA256FileSuffix. If the algorithm is empty,
+// the suffix for distgo.ChecksumSHA256 is returned.
+func ChecksumFileSuffix(algorithm distgo.ChecksumAlgorithm) string {
+	if algorithm == "" {
+		algorithm = distgo.ChecksumSHA256
+	}
+	return "." + string(algorithm)
+}
+
+// newChecksumHash returns a new hash.Hash for the provided algorithm. If the algorithm is empty, SHA-256 is used.
+func newChecksumHash(algorithm distgo.ChecksumAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case "", distgo.ChecksumSHA256:
+		return sha256.New(), nil
+	case distgo.ChecksumSHA512:
+		return sha512.New(), nil
+	case distgo.ChecksumBLAKE2b:
+		return blake2b.New512(nil)
+	default:
+		return nil, errors.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+// sha256Digest returns the hex-encoded SHA-256 checksum of the file at the provided path.
+func sha256Digest(filePath string) (string, error) {
+	return checksumDigest(filePath, distgo.ChecksumSHA256)
+}
+
+// checksumDigest returns the hex-encoded checksum of the file at the provided path computed using the provided
+// algorithm.
+func checksumDigest(filePath string, algorithm distgo.ChecksumAlgorithm) (string, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", filePath)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "failed to compute %s checksum of %s", algorithm, filePath)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// sha256SumLine returns the line for the provided checksum and file name in the format used by "sha256sum". The
+// "sha512sum" and "b2sum" utilities use the same format.
+func sha256SumLine(digest, name string) string {
+	return fmt.Sprintf("%s  %s\n", digest, name)
+}
+
+// writeChecksumFile writes the checksum file for the artifact at the provided path. The checksum file is written next
+// to the artifact, its suffix is determined by the provided algorithm (see ChecksumFileSuffix) and it refers to the
+// artifact by its base name so that it can be verified using "sha256sum -c" (or the equivalent utility for the
+// algorithm) in that directory.
+func writeChecksumFile(artifactPath string, algorithm distgo.ChecksumAlgorithm, digest string) error {
+	checksumFilePath := artifactPath + ChecksumFileSuffix(algorithm)
+	if err := ioutil.WriteFile(checksumFilePath, []byte(sha256SumLine(digest, path.Base(artifactPath))), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write checksum file %s", checksumFilePath)
+	}
+	return nil
+}
+
+// writeSHA256SumsFile writes a SHA256SUMS file to the provided directory for the provided entries. The paths in the
+// file are relative to the directory.
+func writeSHA256SumsFile(dir string, entries []sha256SumsEntry) error {
+	var content strings.Builder
+	for _, entry := range entries {
+		relPath, err := filepath.Rel(dir, entry.path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to determine path of %s relative to %s", entry.path, dir)
+		}
+		content.WriteString(sha256SumLine(entry.digest, filepath.ToSlash(relPath)))
+	}
+	sumsFilePath := path.Join(dir, SHA256SumsFileName)
+	if err := ioutil.WriteFile(sumsFilePath, []byte(content.String()), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write checksums file %s", sumsFilePath)
+	}
+	return nil
+}
+
+// writeProductSHA256SumsFile writes the SHA256SUMS file for the provided product to the dist output directory for the
+// version of the product. The file contains the checksums of the existing artifacts of all of the dists of the product,
+// regardless of whether they were created by the current invocation. The file is not written if none of the artifacts
+// exist.
+func writeProductSHA256SumsFile(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam) error {
+	if productParam.Dist == nil {
+		return nil
+	}
+	productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
+	if err != nil {
+		return err
+	}
+	distIDs := productTaskOutputInfo.Product.DistOutputInfos.DistIDs
+	if len(distIDs) == 0 {
+		return nil
+	}
+	distArtifactPaths := productTaskOutputInfo.ProductDistArtifactPaths()
+	var entries []sha256SumsEntry
+	for _, distID := range distIDs {
+		for _, artifactPath := range distArtifactPaths[distID] {
+			if _, err := os.Stat(artifactPath); os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return errors.Wrapf(err, "failed to stat %s", artifactPath)
+			}
+			digest, err := sha256Digest(artifactPath)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, sha256SumsEntry{path: artifactPath, digest: digest})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	// all dist output directories for a product share the same parent directory
+	sumsDir := path.Dir(distgo.ProductDistOutputDir(productTaskOutputInfo.Project, productTaskOutputInfo.Product, distIDs[0]))
+	return writeSHA256SumsFile(sumsDir, entries)
+}
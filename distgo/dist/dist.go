@@ -15,14 +15,17 @@
 package dist
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/palantir/distgo/distgo"
@@ -32,7 +35,83 @@ import (
 	"github.com/termie/go-shutil"
 )
 
+// Options specifies the options for creating distributions.
+type Options struct {
+	// DryRun specifies that the operations that would be performed should be printed rather than run.
+	DryRun bool
+
+	// SHA256Files specifies that a checksum file should be written next to each dist artifact. The checksum is computed
+	// using the ChecksumAlgorithm of the dist (SHA-256 by default) and the file is named "{{Artifact}}.{{Algorithm}}"
+	// (for example, "{{Artifact}}.sha256"). The file contains the hex-encoded checksum and the name of the artifact in
+	// the format used by "sha256sum".
+	SHA256Files bool
+
+	// SHA256Sums specifies that a single SHA256SUMS file should be written to the dist output directory for the
+	// version of each product ("{{OutputDir}}/{{ProductID}}/{{Version}}"). The file contains the checksums of all of
+	// the existing dist artifacts of all of the dists of the product in the format used by "sha256sum", with artifact
+	// paths relative to the directory that contains the file. The file is written even if the dists of the product are
+	// up-to-date, and it includes the artifacts of dists that are not run because they were not specified.
+	SHA256Sums bool
+
+	// Force specifies that dist outputs should be created even if they are up-to-date. By default, a dist is skipped if
+	// its artifacts exist and the hash of its inputs matches the hash recorded when the artifacts were created.
+	Force bool
+
+	// Parallel specifies that the dists for different products should be created concurrently. Products are still
+	// processed in dependency order: the dists for a product are not started until the dists of all of the products
+	// that it depends on have finished.
+	Parallel bool
+
+	// MaxParallelism is the maximum number of products whose dists are created concurrently when Parallel is true. If
+	// less than or equal to 0, the number of logical processors reported by Go is used.
+	MaxParallelism int
+}
+
+// Errors is the error returned by Products when the dists for multiple products fail when running in parallel. It
+// contains the error for each failed product keyed by the ID of the product.
+type Errors struct {
+	Errors map[distgo.ProductID]error
+}
+
+func (e *Errors) Error() string {
+	var ids []string
+	for id := range e.Errors {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	var parts []string
+	for _, id := range ids {
+		parts = append(parts, e.Errors[distgo.ProductID(id)].Error())
+	}
+	return fmt.Sprintf("dist failed for %d products:\n%s", len(ids), strings.Join(parts, "\n"))
+}
+
+// Products creates the dists for the specified products (and the products that they depend on), building any outputs
+// that are required first. Products are processed serially in dependency order and the first error is returned.
 func Products(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, configModTime *time.Time, productDistIDs []distgo.ProductDistID, dryRun bool, stdout io.Writer) error {
+	return ProductsWithOptions(projectInfo, projectParam, configModTime, productDistIDs, Options{
+		DryRun: dryRun,
+	}, stdout)
+}
+
+// ProductsWithOptions is like Products, but the dists are created using the provided options. If distOpts.Parallel is
+// false, the products are processed serially and the first error is returned. Otherwise, the products in each
+// dependency level are processed concurrently and the dists for all of the products that do not depend on a failed
+// product are created: if exactly one product fails, its error is returned, and if multiple products fail, an *Errors
+// that contains all of the errors is returned.
+func ProductsWithOptions(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, configModTime *time.Time, productDistIDs []distgo.ProductDistID, distOpts Options, stdout io.Writer) error {
+	return ProductsWithContext(context.Background(), projectInfo, projectParam, configModTime, productDistIDs, distOpts, stdout)
+}
+
+// ProductsWithContext is like ProductsWithOptions, but the builds and dists are canceled if the provided context is
+// done. Refer to build.RunWithContext and RunWithContext for more information.
+func ProductsWithContext(ctx context.Context, projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam, configModTime *time.Time, productDistIDs []distgo.ProductDistID, distOpts Options, stdout io.Writer) error {
+	// expand any glob patterns so that the dependencies of all of the matching products are included
+	productDistIDs, err := distgo.ExpandProductDistIDs(projectParam.Products, productDistIDs...)
+	if err != nil {
+		return err
+	}
+
 	// pre-filter step: expand productDistIDs to include all dependent products
 	var allDepProductDistIDs []distgo.ProductDistID
 	for _, currDistID := range productDistIDs {
@@ -49,6 +128,8 @@ func Products(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam,
 		return err
 	}
 
+	// the SHA256SUMS file of a product covers all of its dists, including the dists that are filtered out
+	allDistsProducts := projectParam.Products
 	filteredDistProductsMap := make(map[distgo.ProductID]distgo.ProductParam)
 	// copy old values into new map
 	for k, v := range projectParam.Products {
@@ -80,9 +161,9 @@ func Products(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam,
 		productParamsToBuild = append(productParamsToBuild, *requiresBuildParam)
 	}
 	if len(productParamsToBuild) != 0 {
-		if err := build.Run(projectInfo, productParamsToBuild, build.Options{
+		if err := build.RunWithContext(ctx, projectInfo, productParamsToBuild, build.Options{
 			Parallel: true,
-			DryRun:   dryRun,
+			DryRun:   distOpts.DryRun,
 		}, stdout); err != nil {
 			return err
 		}
@@ -95,45 +176,204 @@ func Products(projectInfo distgo.ProjectInfo, projectParam distgo.ProjectParam,
 	if err != nil {
 		return err
 	}
+	if !distOpts.Parallel {
+		for _, currProductID := range topoOrderedIDs {
+			if err := runProduct(ctx, projectInfo, targetProducts[currProductID], allDistsProducts[currProductID], configModTime, distOpts, stdout); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var targetProductParams []distgo.ProductParam
 	for _, currProductID := range topoOrderedIDs {
-		requiresDistParam, err := RequiresDist(projectInfo, targetProducts[currProductID], configModTime)
-		if err != nil {
+		targetProductParams = append(targetProductParams, targetProducts[currProductID])
+	}
+	levels, err := distgo.ProductParamsByDependencyLevel(targetProductParams)
+	if err != nil {
+		return err
+	}
+	// workers share the output writer, so serialize writes to it
+	workerStdout := &lockedWriter{w: stdout}
+	distErrs := make(map[distgo.ProductID]error)
+	for _, currLevel := range levels {
+		var levelProductParams []distgo.ProductParam
+		for _, currProductParam := range currLevel {
+			// a product whose dependency failed is not run, but the products that do not depend on a failed product are
+			if failedDepID, ok := failedDependency(currProductParam, distErrs); ok {
+				_, _ = fmt.Fprintf(workerStdout, "Skipping dist for %s because the dist for its dependency %s failed\n", currProductParam.ID, failedDepID)
+				continue
+			}
+			levelProductParams = append(levelProductParams, currProductParam)
+		}
+		for productID, err := range runProductsParallel(ctx, projectInfo, levelProductParams, allDistsProducts, configModTime, distOpts, workerStdout) {
+			distErrs[productID] = err
+		}
+	}
+	switch len(distErrs) {
+	case 0:
+		return nil
+	case 1:
+		for _, err := range distErrs {
 			return err
 		}
-		if requiresDistParam == nil {
-			continue
+	}
+	return &Errors{Errors: distErrs}
+}
+
+// runProduct creates the dists for the provided product if they are required. If distOpts.SHA256Sums is true, the
+// SHA256SUMS file for the product is written for all of the dists of allDistsProductParam, which is the parameter for
+// the product before its dists were filtered, even if the dists of the product are not required.
+func runProduct(ctx context.Context, projectInfo distgo.ProjectInfo, productParam, allDistsProductParam distgo.ProductParam, configModTime *time.Time, distOpts Options, stdout io.Writer) error {
+	requiresDistParam, err := RequiresDist(projectInfo, productParam, configModTime)
+	if err != nil {
+		return err
+	}
+	if requiresDistParam != nil {
+		// the SHA256SUMS file is written below for all of the dists of the product
+		runDistOpts := distOpts
+		runDistOpts.SHA256Sums = false
+		if err := RunWithContext(ctx, projectInfo, *requiresDistParam, runDistOpts, stdout); err != nil {
+			return errors.Wrapf(err, "dist failed for %s", productParam.ID)
 		}
-		if err := Run(projectInfo, *requiresDistParam, dryRun, stdout); err != nil {
-			return errors.Wrapf(err, "dist failed for %s", currProductID)
+	}
+	if distOpts.SHA256Sums && !distOpts.DryRun {
+		if err := writeProductSHA256SumsFile(projectInfo, allDistsProductParam); err != nil {
+			return errors.Wrapf(err, "dist failed for %s", productParam.ID)
 		}
 	}
 	return nil
 }
 
+// runProductsParallel runs runProduct for all of the provided products using at most distOpts.MaxParallelism
+// concurrent workers. The provided products must not depend on each other. All of the products are run even if some of
+// them fail. Returns the errors for the products that failed keyed by product ID.
+func runProductsParallel(ctx context.Context, projectInfo distgo.ProjectInfo, productParams []distgo.ProductParam, allDistsProducts map[distgo.ProductID]distgo.ProductParam, configModTime *time.Time, distOpts Options, stdout io.Writer) map[distgo.ProductID]error {
+	jobs := make(chan distgo.ProductParam, len(productParams))
+	for _, currProductParam := range productParams {
+		jobs <- currProductParam
+	}
+	close(jobs)
+
+	nWorkers := distOpts.MaxParallelism
+	if nWorkers <= 0 {
+		nWorkers = runtime.NumCPU()
+	}
+	if len(productParams) < nWorkers {
+		nWorkers = len(productParams)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	distErrs := make(map[distgo.ProductID]error)
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for currProductParam := range jobs {
+				if err := runProduct(ctx, projectInfo, currProductParam, allDistsProducts[currProductParam.ID], configModTime, distOpts, stdout); err != nil {
+					mu.Lock()
+					distErrs[currProductParam.ID] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return distErrs
+}
+
+// failedDependency returns the ID of a dependency of the provided product whose dist failed and true if such a
+// dependency exists.
+func failedDependency(productParam distgo.ProductParam, distErrs map[distgo.ProductID]error) (distgo.ProductID, bool) {
+	for _, depID := range productParam.AllDependenciesSortedIDs() {
+		if _, ok := distErrs[depID]; ok {
+			return depID, true
+		}
+	}
+	return "", false
+}
+
+// lockedWriter is an io.Writer that serializes calls to Write on the wrapped writer.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
 // Run executes the Dist action for the specified product. Produces both the dist output directory and the dist
 // artifacts for all of the disters for the product. The outputs for the dependent products for the provided product
 // must already exist in the proper locations.
 func Run(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, dryRun bool, stdout io.Writer) error {
+	return RunWithOptions(projectInfo, productParam, Options{
+		DryRun: dryRun,
+	}, stdout)
+}
+
+// RunWithOptions is like Run, but the dist is created using the provided options.
+func RunWithOptions(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, distOpts Options, stdout io.Writer) error {
+	return RunWithContext(context.Background(), projectInfo, productParam, distOpts, stdout)
+}
+
+// RunWithContext is like RunWithOptions, but the dist is canceled if the provided context is done. When the context is done, the
+// running dist script is killed, the dist work directory and any artifacts of the dist that was being created are
+// removed and dists that have not started are not started. A Dister that is running when the context is done is not
+// interrupted, but its output is removed after it returns. The returned error wraps the error of the context.
+func RunWithContext(ctx context.Context, projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, distOpts Options, stdout io.Writer) error {
+	dryRun := distOpts.DryRun
 	if productParam.Dist == nil {
 		distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("%s does not define a dist configuration; skipping dist", productParam.ID), dryRun)
 		return nil
 	}
 
-	productOutputInfo, err := productParam.ToProductOutputInfo(projectInfo.Version)
-	if err != nil {
-		return err
-	}
-
 	productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
 	if err != nil {
 		return err
 	}
+	// use the version of the product for the remainder of the dist
+	projectInfo = productTaskOutputInfo.Project
+	productOutputInfo := productTaskOutputInfo.Product
 	distWorkDirs := distgo.ProductDistWorkDirs(projectInfo, productOutputInfo)
+	distArtifactPaths := distgo.ProductDistArtifactPaths(projectInfo, productOutputInfo)
+
+	var signer *gpgSigner
+	if productParam.Dist.GPGKey != "" && !dryRun {
+		currSigner, cleanup, err := newGPGSigner(projectInfo.ProjectDir, productParam.Dist.GPGKey)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		signer = currSigner
+	}
 
 	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
-		// create empty output directory
 		distWorkDir := distWorkDirs[currDistID]
+		if err := ctx.Err(); err != nil {
+			return errors.Wrapf(err, "%s distribution for %s canceled", currDistID, productParam.ID)
+		}
+
+		// if the hash cannot be computed (for example, because the build artifacts do not exist yet in a dry run),
+		// consider the dist out-of-date
+		hash, err := inputHash(projectInfo, productParam, productTaskOutputInfo, currDistID, distOpts)
+		if err != nil {
+			hash = ""
+		}
+		if hash != "" && !distOpts.Force && upToDate(distWorkDir, distArtifactPaths[currDistID], hash) {
+			distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("%s distribution for %s at %v is up-to-date", currDistID, productParam.ID, strings.Join(outputArtifactDisplayPaths(distArtifactPaths[currDistID]), ", ")), dryRun)
+			continue
+		}
+
+		// create empty output directory
 		if !dryRun {
+			// remove stored input hash so that the outputs are not considered up-to-date if the dist fails
+			if err := os.Remove(inputHashFilePath(distWorkDir)); err != nil && !os.IsNotExist(err) {
+				return errors.Wrapf(err, "failed to remove input hash file for dist output directory %s", distWorkDir)
+			}
 			// remove output directory if it already exists
 			if err := os.RemoveAll(distWorkDir); err != nil {
 				return errors.Wrapf(err, "failed to remove dist output directory %s", distWorkDir)
@@ -154,26 +394,108 @@ func Run(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, dryRu
 					return errors.Wrapf(err, "failed to copy input directory")
 				}
 			}
+			// copy input files
+			if err := copyInputFiles(projectInfo.ProjectDir, currDistParam.InputFiles, distWorkDir); err != nil {
+				return errors.Wrapf(err, "failed to copy input files")
+			}
 
 			// run dist task
 			runDistOutput, err := currDistParam.Dister.RunDist(currDistID, productTaskOutputInfo)
 			if err != nil {
 				return err
 			}
+			if err := ctx.Err(); err != nil {
+				return removeCanceledDist(err, productParam.ID, currDistID, distWorkDir, distArtifactPaths[currDistID])
+			}
 			// execute dist script
-			if err := distgo.WriteAndExecuteScript(projectInfo, currDistParam.Script, distgo.DistScriptEnvVariables(currDistID, productTaskOutputInfo), stdout); err != nil {
+			if err := distgo.WriteAndExecuteScriptContext(ctx, projectInfo, currDistParam.Script, distgo.DistScriptEnvVariables(currDistID, productTaskOutputInfo), stdout); err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return removeCanceledDist(ctxErr, productParam.ID, currDistID, distWorkDir, distArtifactPaths[currDistID])
+				}
 				return errors.Wrapf(err, "failed to execute dist script")
 			}
+			// remove excluded files so that they are not included in the manifest or the dist artifacts
+			if err := removeExcludedFiles(distWorkDir, currDistParam.ExcludePatterns); err != nil {
+				return err
+			}
+			// create manifests of the contents of the dist artifacts
+			var manifests []artifactManifest
+			if currDistParam.Manifest != distgo.ManifestNone {
+				if manifests, err = newArtifactManifests(currDistParam.Dister, currDistID, productTaskOutputInfo, distArtifactPaths[currDistID], distWorkDir); err != nil {
+					return err
+				}
+			}
+			if currDistParam.Manifest == distgo.ManifestArchive {
+				for _, currManifest := range manifests {
+					manifestPath := path.Join(currManifest.contents.Dir, currManifest.contents.ManifestDir, ManifestFileName)
+					if err := writeManifestFile(currManifest.manifest, manifestPath); err != nil {
+						return err
+					}
+				}
+			}
 			// generate dist artifacts
+			if err := ctx.Err(); err != nil {
+				return removeCanceledDist(err, productParam.ID, currDistID, distWorkDir, distArtifactPaths[currDistID])
+			}
 			if err := currDistParam.Dister.GenerateDistArtifacts(currDistID, productTaskOutputInfo, runDistOutput); err != nil {
 				return err
 			}
+			if err := ctx.Err(); err != nil {
+				return removeCanceledDist(err, productParam.ID, currDistID, distWorkDir, distArtifactPaths[currDistID])
+			}
+			if currDistParam.Manifest == distgo.ManifestSidecar {
+				for _, currManifest := range manifests {
+					if err := writeManifestFile(currManifest.manifest, currManifest.artifactPath+ManifestSidecarFileSuffix); err != nil {
+						return err
+					}
+				}
+			}
+			if distOpts.SHA256Files {
+				for _, currArtifactPath := range distArtifactPaths[currDistID] {
+					digest, err := checksumDigest(currArtifactPath, currDistParam.ChecksumAlgorithm)
+					if err != nil {
+						return err
+					}
+					if err := writeChecksumFile(currArtifactPath, currDistParam.ChecksumAlgorithm, digest); err != nil {
+						return err
+					}
+				}
+			}
+			if signer != nil {
+				for _, currArtifactPath := range distArtifactPaths[currDistID] {
+					if err := signer.sign(currArtifactPath); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if hash != "" && !dryRun {
+			if err := ioutil.WriteFile(inputHashFilePath(distWorkDir), []byte(hash+"\n"), 0644); err != nil {
+				return errors.Wrapf(err, "failed to write input hash file for dist output directory %s", distWorkDir)
+			}
 		}
 		distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Finished creating %s distribution for %s", currDistID, productParam.ID), dryRun)
 	}
+
+	if distOpts.SHA256Sums && !dryRun {
+		if err := writeProductSHA256SumsFile(projectInfo, productParam); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// removeCanceledDist removes the dist work directory and the artifacts of a dist whose creation was canceled so that no
+// partial output remains and returns an error that wraps the provided context error.
+func removeCanceledDist(ctxErr error, productID distgo.ProductID, distID distgo.DistID, distWorkDir string, artifactPaths []string) error {
+	for _, removePath := range append([]string{distWorkDir}, artifactPaths...) {
+		if err := os.RemoveAll(removePath); err != nil {
+			return errors.Wrapf(err, "%s distribution for %s canceled, but failed to remove partial output %s", distID, productID, removePath)
+		}
+	}
+	return errors.Wrapf(ctxErr, "%s distribution for %s canceled", distID, productID)
+}
+
 func copyInputDir(inputDir string, exclude matcher.Matcher, dstDir string) error {
 	inputDirFiles, err := ioutil.ReadDir(inputDir)
 	if err != nil {
@@ -227,6 +549,48 @@ func copyInputDir(inputDir string, exclude matcher.Matcher, dstDir string) error
 	return nil
 }
 
+// copyInputFiles copies the files specified by the provided file mappings to the destination directory. The source of a
+// mapping is resolved relative to the project directory and may be a glob pattern. If the source is a glob pattern or
+// the destination ends with "/", the matching files are copied into the destination as a directory.
+func copyInputFiles(projectDir string, fileMappings []distgo.FileMapping, dstDir string) error {
+	for _, fileMapping := range fileMappings {
+		isGlob := strings.ContainsAny(fileMapping.Source, "*?[")
+		srcPaths, err := filepath.Glob(path.Join(projectDir, fileMapping.Source))
+		if err != nil {
+			return errors.Wrapf(err, "invalid source %s", fileMapping.Source)
+		}
+		if len(srcPaths) == 0 {
+			return errors.Errorf("source %s does not match any files", fileMapping.Source)
+		}
+		for _, srcPath := range srcPaths {
+			fi, err := os.Stat(srcPath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to stat %s", srcPath)
+			}
+			if fi.IsDir() {
+				return errors.Errorf("source %s matches directory %s: only files can be specified as input files", fileMapping.Source, srcPath)
+			}
+
+			dstPath := path.Join(dstDir, fileMapping.Destination)
+			if isGlob || strings.HasSuffix(fileMapping.Destination, "/") {
+				dstPath = path.Join(dstPath, path.Base(srcPath))
+			}
+			if err := os.MkdirAll(path.Dir(dstPath), 0755); err != nil {
+				return errors.Wrapf(err, "failed to create directory for %s", dstPath)
+			}
+			if _, err := shutil.Copy(srcPath, dstPath, false); err != nil {
+				return errors.Wrapf(err, "failed to copy %s to %s", srcPath, dstPath)
+			}
+			if fileMapping.Mode != 0 {
+				if err := os.Chmod(dstPath, fileMapping.Mode); err != nil {
+					return errors.Wrapf(err, "failed to set mode of %s", dstPath)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func outputArtifactDisplayPaths(in []string) []string {
 	if in == nil {
 		return nil
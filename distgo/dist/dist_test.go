@@ -15,15 +15,30 @@
 package dist_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/distgo/dister/bin"
+	"github.com/palantir/distgo/dister/deb"
 	"github.com/palantir/distgo/dister/disterfactory"
 	"github.com/palantir/distgo/dister/osarchbin"
 	"github.com/palantir/distgo/distgo"
@@ -33,8 +48,11 @@ import (
 	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/palantir/pkg/gittest"
 	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -466,6 +484,1202 @@ func main() {}
 	}
 }
 
+func TestDistParallel(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	productCfg := func(distScript string, deps ...distgo.ProductID) distgoconfig.ProductConfig {
+		cfg := distgoconfig.ProductConfig{
+			Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+				MainPkg: stringPtr("foo"),
+			}),
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+					bin.TypeName: {
+						Type:   stringPtr(bin.TypeName),
+						Script: stringPtr(distScript),
+					},
+				}),
+			}),
+		}
+		if len(deps) > 0 {
+			cfg.Dependencies = &deps
+		}
+		return cfg
+	}
+
+	for i, tc := range []struct {
+		name            string
+		products        map[distgo.ProductID]distgoconfig.ProductConfig
+		wantDists       []distgo.ProductID
+		wantNoDists     []distgo.ProductID
+		wantErrorRegexp string
+	}{
+		{
+			name: "dists for all products are created",
+			products: map[distgo.ProductID]distgoconfig.ProductConfig{
+				"foo": productCfg(""),
+				"bar": productCfg(""),
+				"baz": productCfg(""),
+				// the dist script of qux verifies that the dist of its dependency was created first
+				"qux": productCfg(`#!/usr/bin/env bash
+test -f "$DEP_PRODUCT_ID_0_DIST_ID_0_DIST_DIR/$DEP_PRODUCT_ID_0_DIST_ID_0_DIST_ARTIFACT_0"
+`, "baz"),
+			},
+			wantDists: []distgo.ProductID{"bar", "baz", "foo", "qux"},
+		},
+		{
+			name: "failure does not abort dists of unrelated products",
+			products: map[distgo.ProductID]distgoconfig.ProductConfig{
+				"foo": productCfg(""),
+				"bar": productCfg(""),
+				"baz": productCfg(`#!/usr/bin/env bash
+exit 1
+`),
+				"qux": productCfg("", "baz"),
+			},
+			wantDists:       []distgo.ProductID{"bar", "foo"},
+			wantNoDists:     []distgo.ProductID{"baz", "qux"},
+			wantErrorRegexp: `^dist failed for baz: failed to execute dist script: .*exit status 1$`,
+		},
+		{
+			name: "failures of multiple products are aggregated",
+			products: map[distgo.ProductID]distgoconfig.ProductConfig{
+				"foo": productCfg(""),
+				"bar": productCfg(`#!/usr/bin/env bash
+exit 1
+`),
+				"baz": productCfg(`#!/usr/bin/env bash
+exit 2
+`),
+			},
+			wantDists:       []distgo.ProductID{"foo"},
+			wantNoDists:     []distgo.ProductID{"bar", "baz"},
+			wantErrorRegexp: `(?s)^dist failed for 2 products:\ndist failed for bar: .*exit status 1\ndist failed for baz: .*exit status 2$`,
+		},
+	} {
+		projectDir, err := ioutil.TempDir(tmp, "")
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		gittest.InitGitDir(t, projectDir)
+		require.NoError(t, os.MkdirAll(path.Join(projectDir, "foo"), 0755), "Case %d: %s", i, tc.name)
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644), "Case %d: %s", i, tc.name)
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644), "Case %d: %s", i, tc.name)
+		gittest.CommitAllFiles(t, projectDir, "Commit")
+		gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+		projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+			Products: distgoconfig.ToProductsMap(tc.products),
+		}, projectDir, fmt.Sprintf("Case %d: %s", i, tc.name))
+		projectInfo, err := projectParam.ProjectInfo(projectDir)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		err = dist.ProductsWithOptions(projectInfo, projectParam, nil, nil, dist.Options{
+			Parallel:       true,
+			MaxParallelism: 2,
+		}, ioutil.Discard)
+		if tc.wantErrorRegexp == "" {
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+		} else {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Regexp(t, regexp.MustCompile(tc.wantErrorRegexp), err.Error(), "Case %d: %s", i, tc.name)
+		}
+
+		for _, productID := range tc.wantDists {
+			_, err := os.Stat(path.Join(projectDir, "out", "dist", string(productID), "0.1.0", bin.TypeName, fmt.Sprintf("%s-0.1.0.tgz", productID)))
+			assert.NoError(t, err, "Case %d: %s: dist for %s should exist", i, tc.name, productID)
+		}
+		for _, productID := range tc.wantNoDists {
+			_, err := os.Stat(path.Join(projectDir, "out", "dist", string(productID), "0.1.0", bin.TypeName, fmt.Sprintf("%s-0.1.0.tgz", productID)))
+			assert.True(t, os.IsNotExist(err), "Case %d: %s: dist for %s should not exist", i, tc.name, productID)
+		}
+	}
+}
+
 func stringPtr(in string) *string {
 	return &in
 }
+
+func TestDistSHA256Files(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	err = os.MkdirAll(path.Join(projectDir, "foo"), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.ProductsWithOptions(projectInfo, projectParam, nil, nil, dist.Options{
+		SHA256Files: true,
+		SHA256Sums:  true,
+	}, ioutil.Discard)
+	require.NoError(t, err)
+
+	versionDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0")
+	artifactName := fmt.Sprintf("foo-0.1.0-%s.tgz", osarch.Current().String())
+	artifactBytes, err := ioutil.ReadFile(path.Join(versionDir, "os-arch-bin", artifactName))
+	require.NoError(t, err)
+	wantDigest := fmt.Sprintf("%x", sha256.Sum256(artifactBytes))
+
+	sidecarBytes, err := ioutil.ReadFile(path.Join(versionDir, "os-arch-bin", artifactName+dist.SHA256FileSuffix))
+	require.NoError(t, err)
+	assert.Equal(t, wantDigest+"  "+artifactName+"\n", string(sidecarBytes))
+
+	sumsBytes, err := ioutil.ReadFile(path.Join(versionDir, dist.SHA256SumsFileName))
+	require.NoError(t, err)
+	assert.Equal(t, wantDigest+"  os-arch-bin/"+artifactName+"\n", string(sumsBytes))
+
+	// verify that the files can be checked by "sha256sum" if it is available
+	if sha256sumPath, err := exec.LookPath("sha256sum"); err == nil {
+		for _, tc := range []struct {
+			dir  string
+			file string
+		}{
+			{dir: path.Join(versionDir, "os-arch-bin"), file: artifactName + dist.SHA256FileSuffix},
+			{dir: versionDir, file: dist.SHA256SumsFileName},
+		} {
+			cmd := exec.Command(sha256sumPath, "-c", tc.file)
+			cmd.Dir = tc.dir
+			output, err := cmd.CombinedOutput()
+			assert.NoError(t, err, "sha256sum -c %s failed: %s", tc.file, string(output))
+		}
+	}
+}
+
+func TestDistSHA256SumsCoversAllDists(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	err = os.MkdirAll(path.Join(projectDir, "foo"), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+			"foo": {
+				Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+					MainPkg: stringPtr("./foo"),
+				}),
+				Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+					Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+						bin.TypeName: {
+							Type: stringPtr(bin.TypeName),
+						},
+						osarchbin.TypeName: {
+							Type: stringPtr(osarchbin.TypeName),
+						},
+					}),
+				}),
+			},
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	versionDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0")
+	sumsPath := path.Join(versionDir, dist.SHA256SumsFileName)
+	wantSums := func() string {
+		var want string
+		for _, relPath := range []string{
+			"bin/foo-0.1.0.tgz",
+			fmt.Sprintf("os-arch-bin/foo-0.1.0-%s.tgz", osarch.Current().String()),
+		} {
+			artifactBytes, err := ioutil.ReadFile(path.Join(versionDir, relPath))
+			require.NoError(t, err)
+			want += fmt.Sprintf("%x  %s\n", sha256.Sum256(artifactBytes), relPath)
+		}
+		return want
+	}
+
+	err = dist.ProductsWithOptions(projectInfo, projectParam, nil, nil, dist.Options{
+		SHA256Sums: true,
+	}, ioutil.Discard)
+	require.NoError(t, err)
+	sumsBytes, err := ioutil.ReadFile(sumsPath)
+	require.NoError(t, err)
+	assert.Equal(t, wantSums(), string(sumsBytes))
+
+	for i, tc := range []struct {
+		name           string
+		productDistIDs []distgo.ProductDistID
+		configModTime  *time.Time
+	}{
+		{
+			name:           "dists that are not run are included",
+			productDistIDs: []distgo.ProductDistID{"foo.bin"},
+		},
+		{
+			name:          "dists of products that are skipped are included",
+			configModTime: &time.Time{},
+		},
+	} {
+		require.NoError(t, os.Remove(sumsPath), "Case %d: %s", i, tc.name)
+		err = dist.ProductsWithOptions(projectInfo, projectParam, tc.configModTime, tc.productDistIDs, dist.Options{
+			SHA256Sums: true,
+		}, ioutil.Discard)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		sumsBytes, err := ioutil.ReadFile(sumsPath)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, wantSums(), string(sumsBytes), "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestDistChecksumAlgorithm(t *testing.T) {
+	for i, tc := range []struct {
+		algorithm  string
+		wantSuffix string
+		digest     func([]byte) string
+	}{
+		{
+			algorithm:  "",
+			wantSuffix: ".sha256",
+			digest: func(b []byte) string {
+				return fmt.Sprintf("%x", sha256.Sum256(b))
+			},
+		},
+		{
+			algorithm:  "sha256",
+			wantSuffix: ".sha256",
+			digest: func(b []byte) string {
+				return fmt.Sprintf("%x", sha256.Sum256(b))
+			},
+		},
+		{
+			algorithm:  "sha512",
+			wantSuffix: ".sha512",
+			digest: func(b []byte) string {
+				return fmt.Sprintf("%x", sha512.Sum512(b))
+			},
+		},
+		{
+			algorithm:  "blake2b",
+			wantSuffix: ".blake2b",
+			digest: func(b []byte) string {
+				return fmt.Sprintf("%x", blake2b.Sum512(b))
+			},
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err, "Case %d", i)
+
+			projectDir, err := ioutil.TempDir(tmp, "")
+			require.NoError(t, err, "Case %d", i)
+			gittest.InitGitDir(t, projectDir)
+			err = os.MkdirAll(path.Join(projectDir, "foo"), 0755)
+			require.NoError(t, err, "Case %d", i)
+			err = ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644)
+			require.NoError(t, err, "Case %d", i)
+			err = ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644)
+			require.NoError(t, err, "Case %d", i)
+			gittest.CommitAllFiles(t, projectDir, "Commit")
+			gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+			disterCfg := distgoconfig.DisterConfig{
+				Type: stringPtr(osarchbin.TypeName),
+			}
+			if tc.algorithm != "" {
+				disterCfg.ChecksumAlgorithm = stringPtr(tc.algorithm)
+			}
+			projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+				ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+					Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+						Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+							osarchbin.TypeName: distgoconfig.ToDisterConfig(disterCfg),
+						}),
+					}),
+				}),
+			}, projectDir, fmt.Sprintf("Case %d", i))
+			projectInfo, err := projectParam.ProjectInfo(projectDir)
+			require.NoError(t, err, "Case %d", i)
+
+			err = dist.ProductsWithOptions(projectInfo, projectParam, nil, nil, dist.Options{
+				SHA256Files: true,
+				SHA256Sums:  true,
+			}, ioutil.Discard)
+			require.NoError(t, err, "Case %d", i)
+
+			versionDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0")
+			artifactName := fmt.Sprintf("foo-0.1.0-%s.tgz", osarch.Current().String())
+			artifactBytes, err := ioutil.ReadFile(path.Join(versionDir, "os-arch-bin", artifactName))
+			require.NoError(t, err, "Case %d", i)
+
+			assert.Equal(t, tc.wantSuffix, dist.ChecksumFileSuffix(distgo.ChecksumAlgorithm(tc.algorithm)), "Case %d", i)
+			sidecarBytes, err := ioutil.ReadFile(path.Join(versionDir, "os-arch-bin", artifactName+tc.wantSuffix))
+			require.NoError(t, err, "Case %d", i)
+			assert.Equal(t, tc.digest(artifactBytes)+"  "+artifactName+"\n", string(sidecarBytes), "Case %d", i)
+
+			// the SHA256SUMS file always uses SHA-256
+			sumsBytes, err := ioutil.ReadFile(path.Join(versionDir, dist.SHA256SumsFileName))
+			require.NoError(t, err, "Case %d", i)
+			assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(artifactBytes))+"  os-arch-bin/"+artifactName+"\n", string(sumsBytes), "Case %d", i)
+		}()
+	}
+}
+
+func TestDistGPGSign(t *testing.T) {
+	gpgPath, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg is not available")
+	}
+
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// generate a throwaway key protected by a passphrase and export it to a file in the project
+	const passphrase = "test-passphrase"
+	keyringDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	err = os.Chmod(keyringDir, 0700)
+	require.NoError(t, err)
+	defer func() {
+		_ = exec.Command("gpgconf", "--homedir", keyringDir, "--kill", "gpg-agent").Run()
+	}()
+	gpgArgs := []string{"--batch", "--homedir", keyringDir, "--pinentry-mode", "loopback", "--passphrase", passphrase}
+	output, err := exec.Command(gpgPath, append(gpgArgs, "--quick-gen-key", "distgo test <test@example.com>", "default", "default", "never")...).CombinedOutput()
+	require.NoError(t, err, "failed to generate key: %s", string(output))
+	secretKey, err := exec.Command(gpgPath, append(gpgArgs, "--armor", "--export-secret-keys")...).Output()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	err = os.MkdirAll(path.Join(projectDir, "foo"), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "signing-key.asc"), secretKey, 0600)
+	require.NoError(t, err)
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	err = os.Setenv(dist.GPGPassphraseEnvVar, passphrase)
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Unsetenv(dist.GPGPassphraseEnvVar)
+	}()
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				GPGKey: stringPtr("signing-key.asc"),
+			}),
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.Products(projectInfo, projectParam, nil, nil, false, ioutil.Discard)
+	require.NoError(t, err)
+
+	artifactPath := path.Join(projectDir, "out", "dist", "foo", "0.1.0", "os-arch-bin", fmt.Sprintf("foo-0.1.0-%s.tgz", osarch.Current().String()))
+	signaturePath := artifactPath + dist.GPGSignatureSuffix
+	signatureBytes, err := ioutil.ReadFile(signaturePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(signatureBytes), "-----BEGIN PGP SIGNATURE-----")
+
+	output, err = exec.Command(gpgPath, "--batch", "--homedir", keyringDir, "--verify", signaturePath, artifactPath).CombinedOutput()
+	assert.NoError(t, err, "failed to verify signature: %s", string(output))
+}
+
+func TestDistInputFiles(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	for relPath, content := range map[string]string{
+		"foo/main.go":           testMain,
+		"go.mod":                "module foo",
+		"LICENSE":               "license",
+		"deploy/foo.service":    "foo service",
+		"deploy/foo-db.service": "foo-db service",
+		"deploy/README.md":      "readme",
+		"config/sample.yml":     "key: value",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(projectDir, relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, relPath), []byte(content), 0644))
+	}
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+					bin.TypeName: {
+						Type: stringPtr(bin.TypeName),
+						InputFiles: distgoconfig.ToFileMappingConfigs([]distgoconfig.FileMappingConfig{
+							{
+								Source:      "LICENSE",
+								Destination: "LICENSE",
+							},
+							{
+								Source:      "deploy/*.service",
+								Destination: "service",
+							},
+							{
+								Source:      "config/sample.yml",
+								Destination: "config/foo.yml",
+								Mode:        "0600",
+							},
+						}),
+					},
+				}),
+			}),
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.Products(projectInfo, projectParam, nil, nil, false, ioutil.Discard)
+	require.NoError(t, err)
+
+	workDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0", "bin", "foo-0.1.0")
+	for i, tc := range []struct {
+		relPath string
+		content string
+		mode    os.FileMode
+	}{
+		{relPath: "LICENSE", content: "license", mode: 0644},
+		{relPath: "service/foo-db.service", content: "foo-db service", mode: 0644},
+		{relPath: "service/foo.service", content: "foo service", mode: 0644},
+		{relPath: "config/foo.yml", content: "key: value", mode: 0600},
+	} {
+		fi, err := os.Stat(path.Join(workDir, tc.relPath))
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.mode, fi.Mode().Perm(), "Case %d", i)
+		content, err := ioutil.ReadFile(path.Join(workDir, tc.relPath))
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.content, string(content), "Case %d", i)
+	}
+	_, err = os.Stat(path.Join(workDir, "service", "README.md"))
+	assert.True(t, os.IsNotExist(err), "file that does not match glob should not be copied")
+}
+
+func TestDistExcludePatterns(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	for relPath, content := range map[string]string{
+		"foo/main.go":              testMain,
+		"go.mod":                   "module foo",
+		"LICENSE":                  "license",
+		"resources/NOTES.txt":      "notes",
+		"resources/notes.debug":    "notes debug",
+		"fixtures/testdata/in.txt": "fixture",
+		"fixtures/README.md":       "readme",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(projectDir, relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, relPath), []byte(content), 0644))
+	}
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+					bin.TypeName: {
+						Type: stringPtr(bin.TypeName),
+						InputDir: distgoconfig.ToInputDirConfig(&distgoconfig.InputDirConfig{
+							Path: "resources",
+						}),
+						InputFiles: distgoconfig.ToFileMappingConfigs([]distgoconfig.FileMappingConfig{
+							{
+								Source:      "LICENSE",
+								Destination: "LICENSE",
+							},
+							{
+								Source:      "fixtures/testdata/in.txt",
+								Destination: "docs/testdata/",
+							},
+							{
+								Source:      "fixtures/README.md",
+								Destination: "docs/",
+							},
+						}),
+						// the script writes a debug file next to each build artifact
+						Script: stringPtr(`#!/usr/bin/env bash
+for dir in "$DIST_WORK_DIR"/bin/*/; do
+  touch "$dir/foo.debug"
+done
+`),
+						ExcludePatterns: &[]string{
+							"*.debug",
+							"docs/testdata",
+						},
+					},
+				}),
+			}),
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.Products(projectInfo, projectParam, nil, nil, false, ioutil.Discard)
+	require.NoError(t, err)
+
+	wantFiles := []string{
+		"LICENSE",
+		"NOTES.txt",
+		"bin/" + osarch.Current().String() + "/foo",
+		"docs/README.md",
+	}
+	workDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0", "bin", "foo-0.1.0")
+	var workDirFiles []string
+	require.NoError(t, filepath.Walk(workDir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			relPath, err := filepath.Rel(workDir, currPath)
+			if err != nil {
+				return err
+			}
+			workDirFiles = append(workDirFiles, filepath.ToSlash(relPath))
+		}
+		return nil
+	}))
+	assert.Equal(t, wantFiles, workDirFiles)
+	_, err = os.Stat(path.Join(workDir, "docs", "testdata"))
+	assert.True(t, os.IsNotExist(err), "excluded directory should be removed")
+
+	archiveFiles := readTGZFiles(t, path.Join(projectDir, "out", "dist", "foo", "0.1.0", "bin", "foo-0.1.0.tgz"), "foo-0.1.0/")
+	assert.Equal(t, wantFiles, sortedKeys(archiveFiles))
+}
+
+func TestDistExecutableMode(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	for relPath, content := range map[string]string{
+		"foo/main.go": testMain,
+		"go.mod":      "module foo",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(projectDir, relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, relPath), []byte(content), 0644))
+	}
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+					bin.TypeName: {
+						Type: stringPtr(bin.TypeName),
+					},
+					osarchbin.TypeName: {
+						Type:           stringPtr(osarchbin.TypeName),
+						ExecutableMode: stringPtr("0750"),
+					},
+				}),
+			}),
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.Products(projectInfo, projectParam, nil, nil, false, ioutil.Discard)
+	require.NoError(t, err)
+
+	distDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0")
+	for i, tc := range []struct {
+		name        string
+		workDirPath string
+		tgzPath     string
+		entryName   string
+		wantMode    os.FileMode
+	}{
+		{
+			name:        "bin dister uses default executable mode",
+			workDirPath: path.Join(distDir, "bin", "foo-0.1.0", "bin", osarch.Current().String(), "foo"),
+			tgzPath:     path.Join(distDir, "bin", "foo-0.1.0.tgz"),
+			entryName:   "foo-0.1.0/bin/" + osarch.Current().String() + "/foo",
+			wantMode:    distgo.DefaultExecutableMode,
+		},
+		{
+			name:        "os-arch-bin dister uses configured executable mode",
+			workDirPath: path.Join(distDir, "os-arch-bin", "foo-0.1.0", osarch.Current().String(), "foo"),
+			tgzPath:     path.Join(distDir, "os-arch-bin", fmt.Sprintf("foo-0.1.0-%s.tgz", osarch.Current().String())),
+			entryName:   "foo",
+			wantMode:    0750,
+		},
+	} {
+		fi, err := os.Stat(tc.workDirPath)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantMode, fi.Mode().Perm(), "Case %d: %s", i, tc.name)
+
+		entryModes := readTGZModes(t, tc.tgzPath)
+		require.Contains(t, entryModes, tc.entryName, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantMode, entryModes[tc.entryName], "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestDistCanceled(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	require.NoError(t, os.MkdirAll(path.Join(projectDir, "foo"), 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644))
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+					bin.TypeName: {
+						Type: stringPtr(bin.TypeName),
+						// the script writes a partial output and then blocks until it is killed
+						Script: stringPtr(`#!/usr/bin/env bash
+echo partial > "$DIST_WORK_DIR/partial.txt"
+exec sleep 60
+`),
+					},
+				}),
+			}),
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	distDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0", bin.TypeName)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// cancel once the dist script has started writing its output
+		for {
+			if _, err := os.Stat(path.Join(distDir, "foo-0.1.0", "partial.txt")); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	err = dist.ProductsWithContext(ctx, projectInfo, projectParam, nil, nil, dist.Options{}, ioutil.Discard)
+	require.Error(t, err)
+	assert.Equal(t, context.Canceled, errors.Cause(err))
+	assert.EqualError(t, err, "dist failed for foo: bin distribution for foo canceled: context canceled")
+	assert.True(t, time.Since(start) < 30*time.Second, "dist script should be killed when the context is canceled")
+
+	for _, partialPath := range []string{
+		path.Join(distDir, "foo-0.1.0"),
+		path.Join(distDir, "foo-0.1.0.tgz"),
+	} {
+		_, err := os.Stat(partialPath)
+		assert.True(t, os.IsNotExist(err), "partial output %s should be removed", partialPath)
+	}
+
+	// a dist that is started with a context that is already done does not create any output
+	err = dist.RunWithContext(ctx, projectInfo, projectParam.Products["foo"], dist.Options{}, ioutil.Discard)
+	assert.EqualError(t, err, "bin distribution for foo canceled: context canceled")
+	_, err = os.Stat(path.Join(distDir, "foo-0.1.0"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDistNoMainPkg(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	for relPath, content := range map[string]string{
+		"scripts/tool.sh": "#!/usr/bin/env bash\necho tool",
+		"LICENSE":         "license",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(projectDir, relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, relPath), []byte(content), 0644))
+	}
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+			"tool": {
+				Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{}),
+				Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+					Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+						bin.TypeName: {
+							Type: stringPtr(bin.TypeName),
+							InputFiles: distgoconfig.ToFileMappingConfigs([]distgoconfig.FileMappingConfig{
+								{
+									Source:      "scripts/tool.sh",
+									Destination: "scripts/tool.sh",
+								},
+								{
+									Source:      "LICENSE",
+									Destination: "LICENSE",
+								},
+							}),
+						},
+						osarchbin.TypeName: {
+							Type: stringPtr(osarchbin.TypeName),
+						},
+					}),
+				}),
+			},
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.Products(projectInfo, projectParam, nil, nil, false, ioutil.Discard)
+	require.NoError(t, err)
+
+	// build is a no-op, so no build outputs should exist
+	_, err = os.Stat(path.Join(projectDir, "out", "build"))
+	assert.True(t, os.IsNotExist(err), "build output directory should not exist")
+
+	workDir := path.Join(projectDir, "out", "dist", "tool", "0.1.0", "bin", "tool-0.1.0")
+	for i, tc := range []struct {
+		relPath string
+		content string
+	}{
+		{relPath: "scripts/tool.sh", content: "#!/usr/bin/env bash\necho tool"},
+		{relPath: "LICENSE", content: "license"},
+	} {
+		content, err := ioutil.ReadFile(path.Join(workDir, tc.relPath))
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.content, string(content), "Case %d", i)
+	}
+	_, err = os.Stat(path.Join(projectDir, "out", "dist", "tool", "0.1.0", "bin", "tool-0.1.0.tgz"))
+	assert.NoError(t, err)
+
+	// os-arch-bin dist does not contain any executables
+	osArchBinFiles := readTGZFiles(t, path.Join(projectDir, "out", "dist", "tool", "0.1.0", "os-arch-bin", fmt.Sprintf("tool-0.1.0-%s.tgz", osarch.Current())), "")
+	assert.Empty(t, osArchBinFiles)
+}
+
+func TestDistInputHash(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	for relPath, content := range map[string]string{
+		"foo/main.go": testMain,
+		// go directive is specified so that building does not modify go.mod
+		"go.mod": "module foo\n\ngo 1.13\n",
+		// outputs and input file are ignored so that creating or modifying them does not change the version of the project
+		".gitignore":      "/input/\n/out/\n",
+		"input/notes.txt": "notes",
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(projectDir, relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(projectDir, relPath), []byte(content), 0644))
+	}
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	projectParamWithCompressionLevel := func(compressionLevel int) distgo.ProjectParam {
+		return testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+			ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+				Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+					Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+						bin.TypeName: {
+							Type: stringPtr(bin.TypeName),
+							Config: &yaml.MapSlice{
+								{Key: "compression-level", Value: compressionLevel},
+							},
+							InputFiles: distgoconfig.ToFileMappingConfigs([]distgoconfig.FileMappingConfig{
+								{
+									Source:      "input/notes.txt",
+									Destination: "notes.txt",
+								},
+							}),
+						},
+					}),
+				}),
+			}),
+		}, projectDir, "")
+	}
+	workDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0", "bin", "foo-0.1.0")
+	markerPath := path.Join(workDir, "marker")
+
+	for i, tc := range []struct {
+		name             string
+		compressionLevel int
+		sourceDateEpoch  string
+		force            bool
+		setup            func()
+		wantUpToDate     bool
+	}{
+		{
+			name:             "initial dist creates outputs",
+			compressionLevel: 1,
+		},
+		{
+			name:             "dist with same inputs is skipped",
+			compressionLevel: 1,
+			wantUpToDate:     true,
+		},
+		{
+			name:             "changed compression level creates outputs",
+			compressionLevel: 9,
+		},
+		{
+			name:             "dist with same inputs is skipped after parameter change",
+			compressionLevel: 9,
+			wantUpToDate:     true,
+		},
+		{
+			name:             "force creates outputs",
+			compressionLevel: 9,
+			force:            true,
+		},
+		{
+			name:             "changed input file creates outputs",
+			compressionLevel: 9,
+			setup: func() {
+				require.NoError(t, ioutil.WriteFile(path.Join(projectDir, "input", "notes.txt"), []byte("updated notes"), 0644))
+			},
+		},
+		{
+			name:             "removed dist artifact creates outputs",
+			compressionLevel: 9,
+			setup: func() {
+				require.NoError(t, os.Remove(path.Join(projectDir, "out", "dist", "foo", "0.1.0", "bin", "foo-0.1.0.tgz")))
+			},
+		},
+		{
+			name:             "changed SOURCE_DATE_EPOCH creates outputs",
+			compressionLevel: 9,
+			sourceDateEpoch:  "1000000000",
+		},
+		{
+			name:             "dist with same SOURCE_DATE_EPOCH is skipped",
+			compressionLevel: 9,
+			sourceDateEpoch:  "1000000000",
+			wantUpToDate:     true,
+		},
+		{
+			name:             "changed SOURCE_DATE_EPOCH value creates outputs",
+			compressionLevel: 9,
+			sourceDateEpoch:  "1100000000",
+		},
+	} {
+		if tc.setup != nil {
+			tc.setup()
+		}
+		projectParam := projectParamWithCompressionLevel(tc.compressionLevel)
+		projectInfo, err := projectParam.ProjectInfo(projectDir)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		projectInfo.SourceDateEpoch = tc.sourceDateEpoch
+
+		buffer := &bytes.Buffer{}
+		// configuration modification time is not provided so that the dist is only skipped based on its input hash
+		err = dist.ProductsWithOptions(projectInfo, projectParam, nil, nil, dist.Options{
+			Force: tc.force,
+		}, buffer)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		_, err = os.Stat(markerPath)
+		if tc.wantUpToDate {
+			assert.Contains(t, buffer.String(), "bin distribution for foo at", "Case %d: %s", i, tc.name)
+			assert.Contains(t, buffer.String(), "is up-to-date", "Case %d: %s", i, tc.name)
+			assert.NoError(t, err, "Case %d: %s: dist work directory should not have been recreated", i, tc.name)
+		} else {
+			assert.Contains(t, buffer.String(), "Creating distribution for foo at", "Case %d: %s", i, tc.name)
+			assert.True(t, os.IsNotExist(err), "Case %d: %s: dist work directory should have been recreated", i, tc.name)
+			require.NoError(t, ioutil.WriteFile(markerPath, []byte("marker"), 0644), "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestDistManifest(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmp, "")
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	err = os.MkdirAll(path.Join(projectDir, "foo"), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "foo", "main.go"), []byte(testMain), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo"), 0644)
+	require.NoError(t, err)
+	gittest.CommitAllFiles(t, projectDir, "Commit")
+	gittest.CreateGitTag(t, projectDir, "0.1.0")
+
+	disterCfg := func(typeName, manifest string) distgoconfig.DisterConfig {
+		return distgoconfig.DisterConfig{
+			Type:     stringPtr(typeName),
+			Manifest: stringPtr(manifest),
+			Script: stringPtr(`#!/usr/bin/env bash
+mkdir -p $DIST_WORK_DIR/docs
+echo "readme" > $DIST_WORK_DIR/docs/README.txt
+echo "notes" > $DIST_WORK_DIR/NOTES.txt`),
+		}
+	}
+	debCfg := func(manifest string) distgoconfig.DisterConfig {
+		cfg := disterCfg(deb.TypeName, manifest)
+		cfg.Config = &yaml.MapSlice{
+			{Key: "maintainer", Value: "Test <test@example.com>"},
+		}
+		return cfg
+	}
+	disters := distgoconfig.DistersConfig{
+		"bin-archive":         distgoconfig.ToDisterConfig(disterCfg(bin.TypeName, "archive")),
+		"bin-sidecar":         distgoconfig.ToDisterConfig(disterCfg(bin.TypeName, "sidecar")),
+		"os-arch-bin-archive": distgoconfig.ToDisterConfig(disterCfg(osarchbin.TypeName, "archive")),
+	}
+	// Debian packages can only be created for linux
+	if osarch.Current().OS == "linux" {
+		disters["deb-archive"] = distgoconfig.ToDisterConfig(debCfg("archive"))
+		disters["deb-sidecar"] = distgoconfig.ToDisterConfig(debCfg("sidecar"))
+	}
+	projectParam := testfuncs.NewProjectParam(t, distgoconfig.ProjectConfig{
+		ProductDefaults: *distgoconfig.ToProductConfig(&distgoconfig.ProductConfig{
+			Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+				Disters: distgoconfig.ToDistersConfig(&disters),
+			}),
+		}),
+	}, projectDir, "")
+	projectInfo, err := projectParam.ProjectInfo(projectDir)
+	require.NoError(t, err)
+
+	err = dist.Products(projectInfo, projectParam, nil, nil, false, ioutil.Discard)
+	require.NoError(t, err)
+
+	versionDir := path.Join(projectDir, "out", "dist", "foo", "0.1.0")
+	for i, tc := range []struct {
+		name string
+		// artifactPath is the path of the artifact relative to the version directory
+		artifactPath string
+		readFiles    func(artifactPath string) map[string][]byte
+		// archiveManifestPath is the path of the manifest in the artifact, or empty if the manifest is a sidecar
+		archiveManifestPath string
+		wantPaths           []string
+	}{
+		{
+			name:         "bin archive manifest",
+			artifactPath: path.Join("bin-archive", "foo-0.1.0.tgz"),
+			readFiles: func(artifactPath string) map[string][]byte {
+				return readTGZFiles(t, artifactPath, "")
+			},
+			archiveManifestPath: "foo-0.1.0/" + dist.ManifestFileName,
+			wantPaths: []string{
+				"foo-0.1.0/NOTES.txt",
+				"foo-0.1.0/bin/" + osarch.Current().String() + "/foo",
+				"foo-0.1.0/docs/README.txt",
+			},
+		},
+		{
+			name:         "bin sidecar manifest",
+			artifactPath: path.Join("bin-sidecar", "foo-0.1.0.tgz"),
+			readFiles: func(artifactPath string) map[string][]byte {
+				return readTGZFiles(t, artifactPath, "")
+			},
+			wantPaths: []string{
+				"foo-0.1.0/NOTES.txt",
+				"foo-0.1.0/bin/" + osarch.Current().String() + "/foo",
+				"foo-0.1.0/docs/README.txt",
+			},
+		},
+		{
+			name:         "os-arch-bin archive manifest only lists files in the artifact",
+			artifactPath: path.Join("os-arch-bin-archive", "foo-0.1.0-"+osarch.Current().String()+".tgz"),
+			readFiles: func(artifactPath string) map[string][]byte {
+				return readTGZFiles(t, artifactPath, "")
+			},
+			archiveManifestPath: dist.ManifestFileName,
+			wantPaths: []string{
+				"foo",
+			},
+		},
+		{
+			name:         "deb archive manifest is installed in the documentation directory of the package",
+			artifactPath: path.Join("deb-archive", "foo-0.1.0-"+osarch.Current().String()+".deb"),
+			readFiles: func(artifactPath string) map[string][]byte {
+				return readDebDataFiles(t, artifactPath)
+			},
+			archiveManifestPath: "./usr/share/doc/foo/" + dist.ManifestFileName,
+			wantPaths: []string{
+				"./usr/bin/foo",
+			},
+		},
+		{
+			name:         "deb sidecar manifest lists the files in the data archive",
+			artifactPath: path.Join("deb-sidecar", "foo-0.1.0-"+osarch.Current().String()+".deb"),
+			readFiles: func(artifactPath string) map[string][]byte {
+				return readDebDataFiles(t, artifactPath)
+			},
+			wantPaths: []string{
+				"./usr/bin/foo",
+			},
+		},
+	} {
+		if _, ok := disters[distgo.DistID(path.Dir(tc.artifactPath))]; !ok {
+			continue
+		}
+		artifactPath := path.Join(versionDir, tc.artifactPath)
+		archiveFiles := tc.readFiles(artifactPath)
+
+		var manifestBytes []byte
+		if tc.archiveManifestPath != "" {
+			var ok bool
+			manifestBytes, ok = archiveFiles[tc.archiveManifestPath]
+			require.True(t, ok, "Case %d: %s: manifest not found in archive", i, tc.name)
+			delete(archiveFiles, tc.archiveManifestPath)
+		} else {
+			manifestBytes, err = ioutil.ReadFile(artifactPath + dist.ManifestSidecarFileSuffix)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+		}
+		var manifest dist.Manifest
+		require.NoError(t, json.Unmarshal(manifestBytes, &manifest), "Case %d: %s", i, tc.name)
+
+		var wantEntries []dist.ManifestEntry
+		for _, name := range sortedKeys(archiveFiles) {
+			wantEntries = append(wantEntries, dist.ManifestEntry{
+				Path:   name,
+				Size:   int64(len(archiveFiles[name])),
+				SHA256: fmt.Sprintf("%x", sha256.Sum256(archiveFiles[name])),
+			})
+		}
+		assert.Equal(t, wantEntries, manifest.Entries, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantPaths, sortedKeys(archiveFiles), "Case %d: %s", i, tc.name)
+	}
+}
+
+// readDebDataFiles returns the contents of the regular files in the data archive of the provided Debian package keyed
+// by name.
+func readDebDataFiles(t *testing.T, debPath string) map[string][]byte {
+	debBytes, err := ioutil.ReadFile(debPath)
+	require.NoError(t, err)
+	const arMagic = "!<arch>\n"
+	require.True(t, bytes.HasPrefix(debBytes, []byte(arMagic)), "%s is not an ar archive", debPath)
+
+	// each member has a 60-byte header that contains the name of the member in the first 16 bytes and its size in
+	// bytes 48-58. The content of a member is padded to an even length.
+	for remaining := debBytes[len(arMagic):]; len(remaining) >= 60; {
+		name := strings.TrimRight(strings.TrimSpace(string(remaining[:16])), "/")
+		var size int
+		_, err := fmt.Sscanf(strings.TrimSpace(string(remaining[48:58])), "%d", &size)
+		require.NoError(t, err)
+		content := remaining[60 : 60+size]
+		if name == "data.tar.gz" {
+			dataPath := path.Join(path.Dir(debPath), "data.tar.gz")
+			require.NoError(t, ioutil.WriteFile(dataPath, content, 0644))
+			defer func() {
+				_ = os.Remove(dataPath)
+			}()
+			return readTGZFiles(t, dataPath, "")
+		}
+		remaining = remaining[60+size+size%2:]
+	}
+	require.Fail(t, "data.tar.gz not found", "Debian package %s does not contain data.tar.gz", debPath)
+	return nil
+}
+
+// readTGZFiles returns the contents of the regular files in the provided TGZ archive keyed by name with the provided
+// prefix removed.
+func readTGZFiles(t *testing.T, tgzPath, prefix string) map[string][]byte {
+	f, err := os.Open(tgzPath)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	gzipReader, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[strings.TrimPrefix(hdr.Name, prefix)] = content
+	}
+	return files
+}
+
+// readTGZModes returns a map from the name of each regular file in the archive at tgzPath to its permission bits.
+func readTGZModes(t *testing.T, tgzPath string) map[string]os.FileMode {
+	f, err := os.Open(tgzPath)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	gzipReader, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	modes := make(map[string]os.FileMode)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		modes[hdr.Name] = hdr.FileInfo().Mode().Perm()
+	}
+	return modes
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
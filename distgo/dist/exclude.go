@@ -0,0 +1,79 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dist
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// removeExcludedFiles removes all of the files and directories in the provided dist work directory whose path matches
+// any of the provided exclude patterns (see excludePatternMatches). A directory that matches a pattern is removed with
+// all of its contents.
+func removeExcludedFiles(distWorkDir string, excludePatterns []string) error {
+	if len(excludePatterns) == 0 {
+		return nil
+	}
+	var excludedPaths []string
+	if err := filepath.Walk(distWorkDir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if currPath == distWorkDir {
+			return nil
+		}
+		relPath, err := filepath.Rel(distWorkDir, currPath)
+		if err != nil {
+			return err
+		}
+		if !excludePatternMatches(excludePatterns, filepath.ToSlash(relPath)) {
+			return nil
+		}
+		excludedPaths = append(excludedPaths, currPath)
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "failed to walk dist work directory %s", distWorkDir)
+	}
+	for _, currPath := range excludedPaths {
+		if err := os.RemoveAll(currPath); err != nil {
+			return errors.Wrapf(err, "failed to remove excluded path %s", currPath)
+		}
+	}
+	return nil
+}
+
+// excludePatternMatches returns true if the provided slash-separated path relative to the dist work directory matches
+// any of the provided glob patterns. Patterns are matched using path.Match. A pattern that does not contain a '/' is
+// matched against the base name of the path (so "*.debug" matches "bin/foo.debug"); otherwise, the pattern is matched
+// against the full relative path (a leading '/' in the pattern is ignored).
+func excludePatternMatches(excludePatterns []string, relPath string) bool {
+	for _, pattern := range excludePatterns {
+		matchPath := relPath
+		if !strings.Contains(pattern, "/") {
+			matchPath = path.Base(relPath)
+		}
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), matchPath); ok {
+			return true
+		}
+	}
+	return false
+}
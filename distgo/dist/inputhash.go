@@ -0,0 +1,203 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dist
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+// inputHashFileSuffix is the suffix of the file that stores the input hash for a dist. The file is written next to the
+// dist work directory and its name is the name of the dist work directory with this suffix appended.
+const inputHashFileSuffix = ".inputhash"
+
+func inputHashFilePath(distWorkDir string) string {
+	return path.Join(path.Dir(distWorkDir), path.Base(distWorkDir)+inputHashFileSuffix)
+}
+
+// inputHash returns a hash of all of the inputs that affect the outputs of the dist with the provided DistID. The hash
+// covers the product ID, project version and DistID, the dister parameters (including the configuration of the Dister,
+// which includes settings such as the compression level of archives), the dist options and GPG key, the value of
+// SOURCE_DATE_EPOCH for the project, the SHA-256 digests of the build artifacts of the product and its dependencies and
+// the paths and content of the files copied from the input directory and the input files.
+func inputHash(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, productTaskOutputInfo distgo.ProductTaskOutputInfo, distID distgo.DistID, distOpts Options) (string, error) {
+	distParam := productParam.Dist.DistParams[distID]
+	h := sha256.New()
+
+	writeHashEntry(h, "product", []byte(productParam.ID))
+	writeHashEntry(h, "version", []byte(projectInfo.Version))
+	writeHashEntry(h, "dist-id", []byte(distID))
+	writeHashEntry(h, "name-template", []byte(distParam.NameTemplate))
+	writeHashEntry(h, "script", []byte(distParam.Script))
+	writeHashEntry(h, "manifest", []byte(distParam.Manifest))
+	writeHashEntry(h, "checksum-algorithm", []byte(distParam.ChecksumAlgorithm))
+	writeHashEntry(h, "input-files", []byte(fmt.Sprintf("%#v", distParam.InputFiles)))
+	writeHashEntry(h, "exclude-patterns", []byte(strings.Join(distParam.ExcludePatterns, "\n")))
+	writeHashEntry(h, "executable-mode", []byte(distParam.ExecutableMode.String()))
+	// the formatted value contains the type and all of the fields of the Dister, so a change to any of its
+	// configuration changes the hash
+	writeHashEntry(h, "dister", []byte(formatValue(reflect.ValueOf(distParam.Dister))))
+	writeHashEntry(h, "gpg-key", []byte(productParam.Dist.GPGKey))
+	// the SHA256SUMS file is written for the product as a whole regardless of whether its dists are up-to-date, so
+	// distOpts.SHA256Sums does not affect the outputs of the dist
+	writeHashEntry(h, "dist-options", []byte(fmt.Sprintf("sha256=%t", distOpts.SHA256Files)))
+	// disters that create archives set the modification time of the entries to the time specified by
+	// SOURCE_DATE_EPOCH (see tgz.ModTime)
+	sourceDateEpoch, _ := projectInfo.LookupSourceDateEpoch()
+	writeHashEntry(h, "source-date-epoch", []byte(sourceDateEpoch))
+
+	for _, currProductOutputInfo := range productTaskOutputInfo.AllProductOutputInfos() {
+		buildArtifactPaths := distgo.ProductBuildArtifactPaths(projectInfo, currProductOutputInfo)
+		var osArchs []osarch.OSArch
+		for osArch := range buildArtifactPaths {
+			osArchs = append(osArchs, osArch)
+		}
+		sort.Slice(osArchs, func(i, j int) bool {
+			return osArchs[i].String() < osArchs[j].String()
+		})
+		for _, currOSArch := range osArchs {
+			digest, err := sha256Digest(buildArtifactPaths[currOSArch])
+			if err != nil {
+				return "", err
+			}
+			writeHashEntry(h, fmt.Sprintf("build-artifact:%s.%s", currProductOutputInfo.ID, currOSArch), []byte(digest))
+		}
+	}
+
+	if distParam.InputDir.Path != "" {
+		writeHashEntry(h, "input-dir", []byte(distParam.InputDir.Path))
+		if err := writeInputDirHashEntries(h, path.Join(projectInfo.ProjectDir, distParam.InputDir.Path), distParam.InputDir); err != nil {
+			return "", err
+		}
+	}
+	for _, fileMapping := range distParam.InputFiles {
+		srcPaths, err := filepath.Glob(path.Join(projectInfo.ProjectDir, fileMapping.Source))
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid source %s", fileMapping.Source)
+		}
+		sort.Strings(srcPaths)
+		for _, srcPath := range srcPaths {
+			if err := writeFileHashEntry(h, "input-file:"+srcPath, srcPath); err != nil {
+				return "", err
+			}
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// writeInputDirHashEntries writes a hash entry for every file in the provided input directory that is copied to the
+// dist work directory (that is, every file that is not matched by the exclude matcher of the input directory).
+func writeInputDirHashEntries(w io.Writer, inputDir string, inputDirParam distgo.InputDirParam) error {
+	return filepath.Walk(inputDir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(inputDir, currPath)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		if inputDirParam.Exclude != nil && inputDirParam.Exclude.Match(relPath) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			writeHashEntry(w, "input-dir-entry:"+relPath, []byte(info.Mode().String()))
+			return nil
+		}
+		return writeFileHashEntry(w, "input-dir-file:"+relPath, currPath)
+	})
+}
+
+func writeFileHashEntry(w io.Writer, key, filePath string) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read input file %s", filePath)
+	}
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat input file %s", filePath)
+	}
+	writeHashEntry(w, key, content)
+	writeHashEntry(w, key+":mode", []byte(fi.Mode().String()))
+	return nil
+}
+
+// writeHashEntry writes the provided key and value to the provided writer. Lengths are written before the key and
+// value so that distinct entries cannot produce the same input.
+func writeHashEntry(w io.Writer, key string, value []byte) {
+	_, _ = fmt.Fprintf(w, "%d:%s%d:", len(key), key, len(value))
+	_, _ = w.Write(value)
+}
+
+// upToDate returns true if all of the provided dist artifacts exist and the input hash stored for the provided dist
+// work directory matches the provided hash.
+func upToDate(distWorkDir string, distArtifactPaths []string, hash string) bool {
+	for _, currArtifactPath := range append([]string{distWorkDir}, distArtifactPaths...) {
+		if _, err := os.Stat(currArtifactPath); err != nil {
+			return false
+		}
+	}
+	storedHash, err := ioutil.ReadFile(inputHashFilePath(distWorkDir))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(storedHash)) == hash
+}
+
+// formatValue formats the provided value in the same manner as the %#v verb, except that pointers are formatted as the
+// values they point to rather than as addresses. This ensures that values with equal contents are formatted equally
+// even if their pointer fields point to different locations (for example, a Dister with a *int compression level).
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return fmt.Sprintf("%s(nil)", v.Type())
+		}
+		return "&" + formatValue(v.Elem())
+	case reflect.Struct:
+		fields := make([]string, v.NumField())
+		for i := range fields {
+			fields[i] = v.Type().Field(i).Name + ":" + formatValue(v.Field(i))
+		}
+		return fmt.Sprintf("%s{%s}", v.Type(), strings.Join(fields, ", "))
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatValue(v.Index(i))
+		}
+		return fmt.Sprintf("%s{%s}", v.Type(), strings.Join(elems, ", "))
+	case reflect.Invalid:
+		return "nil"
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
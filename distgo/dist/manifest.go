@@ -0,0 +1,158 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManifestFileName is the name of the manifest file that is packaged in a dist artifact when the manifest is
+	// included in the distribution.
+	ManifestFileName = "manifest.json"
+	// ManifestSidecarFileSuffix is the suffix of the manifest file that is written next to a dist artifact. The name
+	// of the file is the name of the artifact followed by this suffix.
+	ManifestSidecarFileSuffix = ".manifest.json"
+)
+
+// Manifest lists the files in a distribution.
+type Manifest struct {
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry describes a single file in a dist artifact. Path is the path of the file in the artifact and uses "/"
+// as the separator. Size and SHA256 are set for regular files, and LinkTarget is set for symbolic links.
+type ManifestEntry struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256,omitempty"`
+	LinkTarget string `json:"linkTarget,omitempty"`
+}
+
+// artifactManifest is the manifest for a dist artifact.
+type artifactManifest struct {
+	artifactPath string
+	contents     distgo.ArtifactContents
+	manifest     Manifest
+}
+
+// newArtifactManifests returns the manifests for the provided artifacts of the distribution. The contents of the
+// artifacts are determined by the Dister if it implements distgo.ArtifactContentsDister: otherwise, every artifact is
+// considered to contain the files in the dist work directory.
+func newArtifactManifests(dister distgo.Dister, distID distgo.DistID, productTaskOutputInfo distgo.ProductTaskOutputInfo, artifactPaths []string, distWorkDir string) ([]artifactManifest, error) {
+	var contents map[string]distgo.ArtifactContents
+	if contentsDister, ok := dister.(distgo.ArtifactContentsDister); ok {
+		var err error
+		if contents, err = contentsDister.ArtifactContents(distID, productTaskOutputInfo); err != nil {
+			return nil, errors.Wrapf(err, "failed to determine contents of dist artifacts")
+		}
+	} else {
+		contents = make(map[string]distgo.ArtifactContents)
+		for _, artifactPath := range artifactPaths {
+			contents[artifactPath] = distgo.ArtifactContents{
+				Dir: distWorkDir,
+			}
+		}
+	}
+
+	var manifests []artifactManifest
+	for _, artifactPath := range artifactPaths {
+		currContents, ok := contents[artifactPath]
+		if !ok {
+			return nil, errors.Errorf("contents of dist artifact %s are not known", artifactPath)
+		}
+		manifest, err := newManifest(currContents.Dir, currContents.PathPrefix)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, artifactManifest{
+			artifactPath: artifactPath,
+			contents:     currContents,
+			manifest:     manifest,
+		})
+	}
+	return manifests, nil
+}
+
+// newManifest returns the manifest for the files in the provided directory. The path of each entry is its path
+// relative to the directory prefixed with the provided prefix. Directories are not listed and symbolic links are not
+// followed. The entries are sorted by path.
+func newManifest(dir, pathPrefix string) (Manifest, error) {
+	var entries []ManifestEntry
+	if err := filepath.Walk(dir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, currPath)
+		if err != nil {
+			return err
+		}
+		entry := ManifestEntry{
+			Path: pathPrefix + filepath.ToSlash(relPath),
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(currPath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read symbolic link %s", currPath)
+			}
+			entry.LinkTarget = filepath.ToSlash(target)
+		case info.Mode().IsRegular():
+			digest, err := sha256Digest(currPath)
+			if err != nil {
+				return err
+			}
+			entry.Size = info.Size()
+			entry.SHA256 = digest
+		default:
+			return errors.Errorf("%s is not a regular file, directory or symbolic link", currPath)
+		}
+		entries = append(entries, entry)
+		return nil
+	}); err != nil {
+		return Manifest{}, errors.Wrapf(err, "failed to create manifest for %s", dir)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return Manifest{
+		Entries: entries,
+	}, nil
+}
+
+func writeManifestFile(manifest Manifest, filePath string) error {
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal manifest as JSON")
+	}
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create directory for manifest")
+	}
+	if err := ioutil.WriteFile(filePath, append(manifestBytes, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write manifest %s", filePath)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dist
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// GPGSignatureSuffix is the suffix of the file that contains the detached GPG signature of a dist artifact.
+	GPGSignatureSuffix = ".asc"
+	// GPGPassphraseEnvVar is the environment variable that contains the passphrase for the GPG key used to sign dist
+	// artifacts. The passphrase can only be specified using this environment variable and is never read from
+	// configuration.
+	GPGPassphraseEnvVar = "DISTGO_GPG_PASSPHRASE"
+)
+
+// gpgSigner creates detached armored signatures for files using the "gpg" executable.
+type gpgSigner struct {
+	gpgPath string
+	// homeDir is the GPG home directory that contains the signing key. If empty, the default home directory of gpg is
+	// used.
+	homeDir string
+	// localUser is the ID of the key used for signing. If empty, the default key in the home directory is used.
+	localUser string
+}
+
+// newGPGSigner returns a signer for the specified key. If the key refers to an existing file (either as an absolute path
+// or a path relative to the project directory), the key in the file is imported into a temporary GPG home directory
+// that is used for signing. Otherwise, the key is treated as the ID of a key in the default keyring. The returned
+// cleanup function must be called once signing is complete.
+func newGPGSigner(projectDir, key string) (*gpgSigner, func(), error) {
+	gpgPath, err := exec.LookPath("gpg")
+	if err != nil {
+		return nil, nil, errors.Errorf("gpg must be installed and on the PATH to sign dist artifacts")
+	}
+
+	keyFilePath := key
+	if !path.IsAbs(keyFilePath) {
+		keyFilePath = path.Join(projectDir, keyFilePath)
+	}
+	if fi, err := os.Stat(keyFilePath); err != nil || fi.IsDir() {
+		return &gpgSigner{
+			gpgPath:   gpgPath,
+			localUser: key,
+		}, func() {}, nil
+	}
+
+	homeDir, err := ioutil.TempDir("", "distgo-gpg-")
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create temporary GPG home directory")
+	}
+	cleanup := func() {
+		// stop any agent started for the temporary home directory before removing it
+		_ = exec.Command("gpgconf", "--homedir", homeDir, "--kill", "gpg-agent").Run()
+		_ = os.RemoveAll(homeDir)
+	}
+	signer := &gpgSigner{
+		gpgPath: gpgPath,
+		homeDir: homeDir,
+	}
+	if output, err := signer.command("--import", keyFilePath).CombinedOutput(); err != nil {
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "failed to import GPG key from %s: %s", keyFilePath, strings.TrimSpace(string(output)))
+	}
+	return signer, cleanup, nil
+}
+
+// sign writes the detached armored signature for the file at the provided path to "{{artifactPath}}.asc".
+func (s *gpgSigner) sign(artifactPath string) error {
+	var args []string
+	if s.localUser != "" {
+		args = append(args, "--local-user", s.localUser)
+	}
+	passphrase, hasPassphrase := os.LookupEnv(GPGPassphraseEnvVar)
+	if hasPassphrase {
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+	}
+	args = append(args, "--armor", "--detach-sign", "--output", artifactPath+GPGSignatureSuffix, artifactPath)
+
+	cmd := s.command(args...)
+	if hasPassphrase {
+		cmd.Stdin = bytes.NewBufferString(passphrase)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to sign %s: %s", artifactPath, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (s *gpgSigner) command(args ...string) *exec.Cmd {
+	allArgs := []string{"--batch", "--yes"}
+	if s.homeDir != "" {
+		allArgs = append(allArgs, "--homedir", s.homeDir)
+	}
+	return exec.Command(s.gpgPath, append(allArgs, args...)...)
+}
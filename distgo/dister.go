@@ -14,6 +14,10 @@
 
 package distgo
 
+import (
+	"github.com/palantir/godel/v2/pkg/osarch"
+)
+
 type Dister interface {
 	// TypeName returns the type of this dister.
 	TypeName() (string, error)
@@ -43,6 +47,39 @@ type Dister interface {
 	GenerateDistArtifacts(distID DistID, productTaskOutputInfo ProductTaskOutputInfo, runDistResult []byte) error
 }
 
+// ArtifactContentsDister is implemented by Disters that can describe which of the files in the dist work directory are
+// packaged in each of their artifacts and the paths of the files in the artifacts. It is used to create a manifest
+// for each artifact. If a Dister does not implement this interface, every artifact is considered to contain all of the
+// files in the dist work directory at their paths relative to the dist work directory.
+type ArtifactContentsDister interface {
+	// ArtifactContents returns the contents of each of the artifacts of the distribution keyed by the path of the
+	// artifact (as returned by ProductTaskOutputInfo.ProductDistArtifactPaths). It is called after RunDist and the
+	// dist script have been run and before GenerateDistArtifacts is called.
+	ArtifactContents(distID DistID, productTaskOutputInfo ProductTaskOutputInfo) (map[string]ArtifactContents, error)
+}
+
+// ArtifactContents describes the files that are packaged in a dist artifact.
+type ArtifactContents struct {
+	// Dir is the directory whose files are packaged in the artifact.
+	Dir string
+
+	// PathPrefix is prepended to the path of a file relative to Dir to form the path of the file in the artifact. For
+	// example, "foo-1.0.0/" for an archive that contains Dir as its top-level directory "foo-1.0.0".
+	PathPrefix string
+
+	// ManifestDir is the directory relative to Dir into which a manifest that is included in the artifact is
+	// written. If empty, the manifest is written to Dir.
+	ManifestDir string
+}
+
+// OSArchArtifactsDister is implemented by Disters whose artifacts are specific to an OS/architecture.
+type OSArchArtifactsDister interface {
+	// ArtifactOSArchs returns the OS/architecture of each of the artifacts returned by Artifacts for the provided
+	// rendered name keyed by the name of the artifact. Artifacts that are not specific to an OS/architecture do not have
+	// an entry.
+	ArtifactOSArchs(renderedName string) (map[string]osarch.OSArch, error)
+}
+
 type DisterFactory interface {
 	Types() []string
 	NewDister(typeName string, cfgYMLBytes []byte) (Dister, error)
@@ -23,6 +23,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strings"
 	"text/template"
 	"time"
 
@@ -120,6 +121,8 @@ func RunBuild(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam,
 	if err != nil {
 		return err
 	}
+	// use the version of the product for the remainder of the build
+	projectInfo = productTaskOutputInfo.Project
 
 	allBuildArtifactPaths := productTaskOutputInfo.ProductDockerBuildArtifactPaths()
 	allDistArtifactPaths := productTaskOutputInfo.ProductDockerDistArtifactPaths()
@@ -185,6 +188,22 @@ func runSingleDockerBuild(
 			}
 		}
 
+		// stage OS/architecture-specific dist artifacts of the product into context directory and remove them after the
+		// build
+		if dockerBuilderParam.StagedDistArtifactsPath != "" {
+			stagedPaths, err := stageDistArtifacts(projectInfo, dockerBuilderParam, productTaskOutputInfo)
+			defer func() {
+				for _, stagedPath := range stagedPaths {
+					if err := os.Remove(stagedPath); err != nil && !os.IsNotExist(err) && rErr == nil {
+						rErr = errors.Wrapf(err, "failed to remove staged dist artifact")
+					}
+				}
+			}()
+			if err != nil {
+				return err
+			}
+		}
+
 		// write and execute Docker script
 		if err := distgo.WriteAndExecuteScript(projectInfo, dockerBuilderParam.Script, distgo.DockerScriptEnvVariables(dockerID, productTaskOutputInfo), stdout); err != nil {
 			return errors.Wrapf(err, "failed to execute Docker script")
@@ -242,6 +261,59 @@ func runSingleDockerBuild(
 	return dockerBuilderParam.DockerBuilder.RunDockerBuild(dockerID, productTaskOutputInfo, verbose, dryRun, stdout)
 }
 
+// stageDistArtifacts hard-links the dist artifacts of the product that are specific to an OS/architecture to the paths
+// specified by the StagedDistArtifactsPath template. Returns the paths of the staged artifacts, which includes the
+// paths that were staged before an error occurred.
+func stageDistArtifacts(projectInfo distgo.ProjectInfo, dockerBuilderParam distgo.DockerBuilderParam, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]string, error) {
+	productOutputInfo := productTaskOutputInfo.Product
+	if productOutputInfo.DistOutputInfos == nil {
+		return nil, nil
+	}
+	pathToContextDir := path.Join(projectInfo.ProjectDir, dockerBuilderParam.ContextDir)
+	distArtifactPaths := distgo.ProductDistArtifactPaths(projectInfo, productOutputInfo)
+
+	var stagedPaths []string
+	// maps destination paths to the source path so that conflicting destinations can be detected
+	srcPaths := make(map[string]string)
+	for _, distID := range productOutputInfo.DistOutputInfos.DistIDs {
+		artifactOSArchs := productOutputInfo.DistOutputInfos.DistInfos[distID].DistArtifactOSArchs
+		for _, srcPath := range distArtifactPaths[distID] {
+			artifactName := path.Base(srcPath)
+			osArch, ok := artifactOSArchs[artifactName]
+			if !ok {
+				continue
+			}
+			renderedPath, err := distgo.RenderTemplate(dockerBuilderParam.StagedDistArtifactsPath, nil,
+				distgo.ProductTemplateFunction(productOutputInfo.ID),
+				distgo.VersionTemplateFunction(projectInfo.Version),
+				distgo.TemplateValueFunction("DistID", distID),
+				distgo.TemplateValueFunction("OSArch", osArch.String()),
+				distgo.TemplateValueFunction("Artifact", artifactName),
+			)
+			if err != nil {
+				return stagedPaths, errors.Wrapf(err, "failed to render staged-dist-artifacts-path")
+			}
+			if renderedPath = path.Clean(renderedPath); path.IsAbs(renderedPath) || renderedPath == ".." || strings.HasPrefix(renderedPath, "../") {
+				return stagedPaths, errors.Errorf("staged path %s for dist artifact %s must be within the context directory", renderedPath, artifactName)
+			}
+			dstPath := path.Join(pathToContextDir, renderedPath)
+			if prevSrcPath, ok := srcPaths[dstPath]; ok {
+				return stagedPaths, errors.Errorf("dist artifacts %s and %s are both staged at %s", prevSrcPath, srcPath, renderedPath)
+			}
+			srcPaths[dstPath] = srcPath
+
+			if err := os.MkdirAll(path.Dir(dstPath), 0755); err != nil {
+				return stagedPaths, errors.Wrapf(err, "failed to create directories")
+			}
+			if err := createNewHardLink(srcPath, dstPath); err != nil {
+				return stagedPaths, errors.Wrapf(err, "failed to stage dist artifact into context directory")
+			}
+			stagedPaths = append(stagedPaths, dstPath)
+		}
+	}
+	return stagedPaths, nil
+}
+
 func inputBuildArtifactTemplateFunction(dockerID distgo.DockerID, pathToContextDir string, buildArtifactPaths map[distgo.ProductID]map[osarch.OSArch]string) distgo.TemplateFunction {
 	return func(fnMap template.FuncMap) {
 		fnMap["InputBuildArtifact"] = func(productID, osArchStr string) (string, error) {
@@ -249,7 +321,7 @@ func inputBuildArtifactTemplateFunction(dockerID distgo.DockerID, pathToContextD
 			if !ok {
 				return "", errors.Errorf("product %s is not a build input for Docker task %s", productID, dockerID)
 			}
-			osArch, err := osarch.New(osArchStr)
+			osArch, err := distgo.NewOSArch(osArchStr)
 			if err != nil {
 				return "", errors.Wrapf(err, "input %s is not a valid OS/Arch", osArchStr)
 			}
@@ -21,11 +21,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/dister/bin"
+	"github.com/palantir/distgo/dister/deb"
 	"github.com/palantir/distgo/dister/disterfactory"
 	"github.com/palantir/distgo/dister/manual"
 	"github.com/palantir/distgo/dister/osarchbin"
@@ -753,6 +756,169 @@ RUN echo 'Tags for foo: {{Tags "foo" "print-dockerfile"}}'
 				assert.Equal(t, originalDockerfileContent, string(bytes))
 			},
 		},
+		{
+			"OS/architecture-specific dist artifacts are staged into context directory",
+			distgoconfig.ProjectConfig{
+				Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+					"foo": {
+						Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+							MainPkg: stringPtr("./foo"),
+							OSArchs: &[]osarch.OSArch{
+								{OS: "linux", Arch: "amd64"},
+								{OS: "linux", Arch: "arm64"},
+							},
+						}),
+						Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+							Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+								osarchbin.TypeName: distgoconfig.ToDisterConfig(distgoconfig.DisterConfig{
+									Type: stringPtr(osarchbin.TypeName),
+									Config: &yaml.MapSlice{
+										{
+											Key: "os-archs",
+											Value: []interface{}{
+												yaml.MapSlice{{Key: "os", Value: "linux"}, {Key: "arch", Value: "amd64"}},
+												yaml.MapSlice{{Key: "os", Value: "linux"}, {Key: "arch", Value: "arm64"}},
+											},
+										},
+									},
+								}),
+								deb.TypeName: distgoconfig.ToDisterConfig(distgoconfig.DisterConfig{
+									Type: stringPtr(deb.TypeName),
+									Config: &yaml.MapSlice{
+										{
+											Key: "os-archs",
+											Value: []interface{}{
+												yaml.MapSlice{{Key: "os", Value: "linux"}, {Key: "arch", Value: "amd64"}},
+											},
+										},
+										{
+											Key:   "maintainer",
+											Value: "Foo <foo@example.com>",
+										},
+									},
+								}),
+								bin.TypeName: distgoconfig.ToDisterConfig(distgoconfig.DisterConfig{
+									Type: stringPtr(bin.TypeName),
+								}),
+							}),
+						}),
+						Docker: distgoconfig.ToDockerConfig(&distgoconfig.DockerConfig{
+							DockerBuildersConfig: distgoconfig.ToDockerBuildersConfig(&distgoconfig.DockerBuildersConfig{
+								printDockerfileDockerBuilderTypeName: distgoconfig.ToDockerBuilderConfig(distgoconfig.DockerBuilderConfig{
+									Type: stringPtr(printDockerfileDockerBuilderTypeName),
+									Script: stringPtr(`#!/usr/bin/env bash
+cp -R docker-context-dir/staging staging-snapshot`),
+									ContextDir:              stringPtr("docker-context-dir"),
+									StagedDistArtifactsPath: stringPtr("staging/{{OSArch}}/{{DistID}}/{{Artifact}}"),
+									TagTemplates: distgoconfig.ToTagTemplatesMap(mustTagTemplatesMap(
+										"default", "foo:latest",
+									)),
+								}),
+							}),
+						}),
+					},
+				}),
+			},
+			nil,
+			func(projectDir string, projectCfg distgoconfig.ProjectConfig) {
+				contextDir := path.Join(projectDir, "docker-context-dir")
+				err := os.Mkdir(contextDir, 0755)
+				require.NoError(t, err)
+				dockerfile := path.Join(contextDir, "Dockerfile")
+				err = ioutil.WriteFile(dockerfile, []byte(testDockerfile), 0644)
+				require.NoError(t, err)
+				gittest.CommitAllFiles(t, projectDir, "Commit files")
+				gittest.CreateGitTag(t, projectDir, "0.1.0")
+			},
+			"",
+			"",
+			func(caseNum int, name, projectDir string) {
+				// the staged artifacts are copied to "staging-snapshot" by the Docker script
+				snapshotDir := path.Join(projectDir, "staging-snapshot")
+				for _, currArtifact := range []struct {
+					osArch string
+					distID string
+					name   string
+				}{
+					{"linux-amd64", osarchbin.TypeName, "foo-0.1.0-linux-amd64.tgz"},
+					{"linux-arm64", osarchbin.TypeName, "foo-0.1.0-linux-arm64.tgz"},
+					{"linux-amd64", deb.TypeName, "foo-0.1.0-linux-amd64.deb"},
+				} {
+					stagedFiles, err := ioutil.ReadDir(path.Join(snapshotDir, currArtifact.osArch, currArtifact.distID))
+					require.NoError(t, err, "Case %d: %s", caseNum, name)
+					require.Len(t, stagedFiles, 1, "Case %d: %s", caseNum, name)
+					assert.Equal(t, currArtifact.name, stagedFiles[0].Name(), "Case %d: %s", caseNum, name)
+
+					stagedBytes, err := ioutil.ReadFile(path.Join(snapshotDir, currArtifact.osArch, currArtifact.distID, currArtifact.name))
+					require.NoError(t, err, "Case %d: %s", caseNum, name)
+					distArtifactBytes, err := ioutil.ReadFile(path.Join(projectDir, "out", "dist", "foo", "0.1.0", currArtifact.distID, currArtifact.name))
+					require.NoError(t, err, "Case %d: %s", caseNum, name)
+					assert.Equal(t, distArtifactBytes, stagedBytes, "Case %d: %s: staged artifact %s is not the dist artifact", caseNum, name, currArtifact.name)
+				}
+				snapshotOSArchDirs, err := ioutil.ReadDir(snapshotDir)
+				require.NoError(t, err, "Case %d: %s", caseNum, name)
+				assert.Len(t, snapshotOSArchDirs, 2, "Case %d: %s: artifacts of the bin dist should not be staged", caseNum, name)
+
+				// staged artifacts are removed after the build
+				var stagedFiles []string
+				err = filepath.Walk(path.Join(projectDir, "docker-context-dir", "staging"), func(currPath string, info os.FileInfo, err error) error {
+					if err != nil {
+						return err
+					}
+					if !info.IsDir() {
+						stagedFiles = append(stagedFiles, currPath)
+					}
+					return nil
+				})
+				require.NoError(t, err, "Case %d: %s", caseNum, name)
+				assert.Empty(t, stagedFiles, "Case %d: %s", caseNum, name)
+			},
+		},
+		{
+			"staged dist artifacts must be within the context directory",
+			distgoconfig.ProjectConfig{
+				Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+					"foo": {
+						Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+							MainPkg: stringPtr("./foo"),
+						}),
+						Dist: distgoconfig.ToDistConfig(&distgoconfig.DistConfig{
+							Disters: distgoconfig.ToDistersConfig(&distgoconfig.DistersConfig{
+								osarchbin.TypeName: distgoconfig.ToDisterConfig(distgoconfig.DisterConfig{
+									Type: stringPtr(osarchbin.TypeName),
+								}),
+							}),
+						}),
+						Docker: distgoconfig.ToDockerConfig(&distgoconfig.DockerConfig{
+							DockerBuildersConfig: distgoconfig.ToDockerBuildersConfig(&distgoconfig.DockerBuildersConfig{
+								printDockerfileDockerBuilderTypeName: distgoconfig.ToDockerBuilderConfig(distgoconfig.DockerBuilderConfig{
+									Type:                    stringPtr(printDockerfileDockerBuilderTypeName),
+									ContextDir:              stringPtr("docker-context-dir"),
+									StagedDistArtifactsPath: stringPtr("../{{Artifact}}"),
+									TagTemplates: distgoconfig.ToTagTemplatesMap(mustTagTemplatesMap(
+										"default", "foo:latest",
+									)),
+								}),
+							}),
+						}),
+					},
+				}),
+			},
+			nil,
+			func(projectDir string, projectCfg distgoconfig.ProjectConfig) {
+				contextDir := path.Join(projectDir, "docker-context-dir")
+				err := os.Mkdir(contextDir, 0755)
+				require.NoError(t, err)
+				dockerfile := path.Join(contextDir, "Dockerfile")
+				err = ioutil.WriteFile(dockerfile, []byte(testDockerfile), 0644)
+				require.NoError(t, err)
+				gittest.CommitAllFiles(t, projectDir, "Commit files")
+				gittest.CreateGitTag(t, projectDir, "0.1.0")
+			},
+			`^staged path \.\./foo-0\.1\.0-.+\.tgz for dist artifact foo-0\.1\.0-.+\.tgz must be within the context directory$`,
+			"",
+			nil,
+		},
 	} {
 		projectDir, err := ioutil.TempDir(tmp, "")
 		require.NoError(t, err, "Case %d: %s", i, tc.name)
@@ -68,6 +68,20 @@ func RunPush(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, d
 			return err
 		}
 	}
+
+	// manifest lists reference the per-architecture images, so they are pushed after all of the images are pushed
+	dockerOutputInfos := productTaskOutputInfo.Product.DockerOutputInfos
+	for _, manifestListID := range dockerOutputInfos.ManifestListIDs {
+		if err := runManifestListPush(
+			productParam.ID,
+			manifestListID,
+			dockerOutputInfos.ManifestListOutputInfos[manifestListID],
+			dryRun,
+			stdout,
+		); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
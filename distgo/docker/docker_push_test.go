@@ -283,6 +283,55 @@ func TestDockerPublish(t *testing.T) {
 			`[DRY RUN] Running Docker push for configuration print-dockerfile of product foo...
 [DRY RUN] Run [docker push foo:latest]
 [DRY RUN] Run [docker push foo:0.1.0]
+`,
+		},
+		{
+			"publish pushes manifest lists after Docker images",
+			distgoconfig.ProjectConfig{
+				Products: distgoconfig.ToProductsMap(map[distgo.ProductID]distgoconfig.ProductConfig{
+					"foo": {
+						Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+							MainPkg: stringPtr("./foo"),
+						}),
+						Docker: distgoconfig.ToDockerConfig(&distgoconfig.DockerConfig{
+							Repository: stringPtr("registry.example.com"),
+							DockerBuildersConfig: distgoconfig.ToDockerBuildersConfig(&distgoconfig.DockerBuildersConfig{
+								printDockerfileDockerBuilderTypeName: distgoconfig.ToDockerBuilderConfig(distgoconfig.DockerBuilderConfig{
+									Type:       stringPtr(printDockerfileDockerBuilderTypeName),
+									ContextDir: stringPtr("docker-context-dir"),
+									TagTemplates: distgoconfig.ToTagTemplatesMap(mustTagTemplatesMap(
+										"amd64", "{{Repository}}foo:{{Version}}-amd64",
+										"arm64", "{{Repository}}foo:{{Version}}-arm64",
+									)),
+								}),
+							}),
+							ManifestLists: distgoconfig.ToManifestLists(&map[distgo.DockerManifestListID]distgoconfig.DockerManifestListConfig{
+								"multi-arch": {
+									TagTemplate: stringPtr("{{Repository}}foo:{{Version}}"),
+									Images: &map[string]string{
+										"linux-amd64": "{{Repository}}foo:{{Version}}-amd64",
+										"linux-arm64": "{{Repository}}foo:{{Version}}-arm64",
+									},
+								},
+							}),
+						}),
+					},
+				}),
+			},
+			nil,
+			nil,
+			func(projectDir string, projectCfg distgoconfig.ProjectConfig) {
+				gittest.CreateGitTag(t, projectDir, "0.1.0")
+			},
+			"",
+			`[DRY RUN] Running Docker push for configuration print-dockerfile of product foo...
+[DRY RUN] Run [docker push registry.example.com/foo:0.1.0-amd64]
+[DRY RUN] Run [docker push registry.example.com/foo:0.1.0-arm64]
+[DRY RUN] Pushing manifest list multi-arch for product foo as registry.example.com/foo:0.1.0...
+[DRY RUN] Run [docker manifest create --amend registry.example.com/foo:0.1.0 registry.example.com/foo:0.1.0-amd64 registry.example.com/foo:0.1.0-arm64]
+[DRY RUN] Run [docker manifest annotate --os linux --arch amd64 registry.example.com/foo:0.1.0 registry.example.com/foo:0.1.0-amd64]
+[DRY RUN] Run [docker manifest annotate --os linux --arch arm64 registry.example.com/foo:0.1.0 registry.example.com/foo:0.1.0-arm64]
+[DRY RUN] Run [docker manifest push --purge registry.example.com/foo:0.1.0]
 `,
 		},
 	} {
@@ -0,0 +1,83 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/palantir/distgo/distgo"
+)
+
+// runManifestListPush creates the manifest list using "docker manifest create", annotates the platform of each of its
+// images and pushes it using "docker manifest push". The images referenced by the manifest list must already have been
+// pushed.
+func runManifestListPush(
+	productID distgo.ProductID,
+	manifestListID distgo.DockerManifestListID,
+	outputInfo distgo.DockerManifestListOutputInfo,
+	dryRun bool,
+	stdout io.Writer) error {
+
+	distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Pushing manifest list %s for product %s as %s...", manifestListID, productID, outputInfo.RenderedTag), dryRun)
+
+	// "--amend" is specified so that a local manifest list left by a previous invocation is replaced
+	createCmd := exec.Command("docker", "manifest", "create", "--amend", outputInfo.RenderedTag)
+	for _, osArchID := range outputInfo.OSArchIDs() {
+		createCmd.Args = append(createCmd.Args, outputInfo.RenderedImageTags[osArchID])
+	}
+	if err := distgo.RunCommandWithVerboseOption(createCmd, true, dryRun, stdout); err != nil {
+		return err
+	}
+
+	for _, osArchID := range outputInfo.OSArchIDs() {
+		osArch, err := distgo.NewOSArch(string(osArchID))
+		if err != nil {
+			return err
+		}
+		goarch, variant := distgo.GOARCHAndVariant(osArch)
+		annotateCmd := exec.Command("docker", "manifest", "annotate", "--os", osArch.OS, "--arch", goarch)
+		if platformVariant := platformVariant(goarch, variant); platformVariant != "" {
+			annotateCmd.Args = append(annotateCmd.Args, "--variant", platformVariant)
+		}
+		annotateCmd.Args = append(annotateCmd.Args, outputInfo.RenderedTag, outputInfo.RenderedImageTags[osArchID])
+		if err := distgo.RunCommandWithVerboseOption(annotateCmd, true, dryRun, stdout); err != nil {
+			return err
+		}
+	}
+
+	// "--purge" removes the local manifest list after it is pushed
+	pushCmd := exec.Command("docker", "manifest", "push", "--purge", outputInfo.RenderedTag)
+	return distgo.RunCommandWithVerboseOption(pushCmd, true, dryRun, stdout)
+}
+
+// platformVariant returns the variant of the platform in the form used by image manifests for the provided GOARCH and
+// distgo variant. The ARM variant "7" is "v7" in a manifest, while amd64 variants such as "v3" are used as-is. Variants
+// of other architectures do not have a manifest representation and are omitted.
+func platformVariant(goarch, variant string) string {
+	if variant == "" {
+		return ""
+	}
+	switch goarch {
+	case "arm", "arm64":
+		return "v" + strings.TrimPrefix(variant, "v")
+	case "amd64":
+		return variant
+	default:
+		return ""
+	}
+}
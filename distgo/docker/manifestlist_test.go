@@ -0,0 +1,200 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	distgoconfig "github.com/palantir/distgo/distgo/config"
+	"github.com/palantir/distgo/distgo/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerPushManifestList(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	productParam := manifestListProductParam(t, "registry.example.com", map[string]string{
+		"linux-amd64": "{{Repository}}foo:{{Version}}-amd64",
+		"linux-arm64": "{{Repository}}foo:{{Version}}-arm64",
+		"linux-arm-7": "{{Repository}}foo:{{Version}}-arm-7",
+	})
+
+	buffer := &bytes.Buffer{}
+	err = docker.RunPush(distgo.ProjectInfo{ProjectDir: tmp, Version: "1.0.0"}, productParam, true, buffer)
+	require.NoError(t, err, "Output:\n%s", buffer.String())
+	assert.Equal(t, `[DRY RUN] Pushing manifest list multi-arch for product foo as registry.example.com/foo:1.0.0...
+[DRY RUN] Run [docker manifest create --amend registry.example.com/foo:1.0.0 registry.example.com/foo:1.0.0-amd64 registry.example.com/foo:1.0.0-arm-7 registry.example.com/foo:1.0.0-arm64]
+[DRY RUN] Run [docker manifest annotate --os linux --arch amd64 registry.example.com/foo:1.0.0 registry.example.com/foo:1.0.0-amd64]
+[DRY RUN] Run [docker manifest annotate --os linux --arch arm --variant v7 registry.example.com/foo:1.0.0 registry.example.com/foo:1.0.0-arm-7]
+[DRY RUN] Run [docker manifest annotate --os linux --arch arm64 registry.example.com/foo:1.0.0 registry.example.com/foo:1.0.0-arm64]
+[DRY RUN] Run [docker manifest push --purge registry.example.com/foo:1.0.0]
+`, buffer.String())
+}
+
+func TestDockerPushManifestListToRegistry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake docker executable is a shell script")
+	}
+
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	registryDir := installFakeDocker(t, tmp)
+	origPath := os.Getenv("PATH")
+	require.NoError(t, os.Setenv("PATH", path.Join(tmp, "bin")+string(os.PathListSeparator)+origPath))
+	defer func() {
+		_ = os.Setenv("PATH", origPath)
+	}()
+
+	// the per-arch images have already been pushed to the registry
+	imageDigests := make(map[string]string)
+	for _, image := range []string{
+		"registry.example.com/foo:1.0.0-amd64",
+		"registry.example.com/foo:1.0.0-arm64",
+		"registry.example.com/foo:1.0.0-arm-7",
+	} {
+		imageDigests[image] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(image)))
+		require.NoError(t, ioutil.WriteFile(path.Join(registryDir, fakeRegistryKey(image)), []byte(imageDigests[image]+"\n"), 0644))
+	}
+
+	productParam := manifestListProductParam(t, "registry.example.com", map[string]string{
+		"linux-amd64": "{{Repository}}foo:{{Version}}-amd64",
+		"linux-arm64": "{{Repository}}foo:{{Version}}-arm64",
+		"linux-arm-7": "{{Repository}}foo:{{Version}}-arm-7",
+	})
+	buffer := &bytes.Buffer{}
+	err = docker.RunPush(distgo.ProjectInfo{ProjectDir: tmp, Version: "1.0.0"}, productParam, false, buffer)
+	require.NoError(t, err, "Output:\n%s", buffer.String())
+
+	// the pushed manifest list references the digest of each per-arch image with the platform of that image
+	manifestList, err := ioutil.ReadFile(path.Join(registryDir, fakeRegistryKey("registry.example.com/foo:1.0.0")))
+	require.NoError(t, err, "Output:\n%s", buffer.String())
+	assert.Equal(t, []string{
+		imageDigests["registry.example.com/foo:1.0.0-amd64"] + " linux/amd64",
+		imageDigests["registry.example.com/foo:1.0.0-arm-7"] + " linux/arm/v7",
+		imageDigests["registry.example.com/foo:1.0.0-arm64"] + " linux/arm64",
+	}, strings.Split(strings.TrimSuffix(string(manifestList), "\n"), "\n"))
+}
+
+// fakeDockerScript is a "docker" executable that implements the "docker manifest" commands used to push manifest lists
+// against a registry stored in a directory. The registry stores the digest of each image in a file named after the
+// image. A pushed manifest list is stored as a file with a "<digest> <platform>" line for each of its images.
+const fakeDockerScript = `#!/bin/sh
+set -e
+registry={{DIR}}/registry
+manifests={{DIR}}/manifests
+key() { echo "$1" | tr '/:' '__'; }
+
+if [ "$1" != manifest ]; then
+	echo "unsupported command: $*" >&2
+	exit 1
+fi
+cmd=$2
+shift 2
+case "$cmd" in
+create)
+	if [ "$1" = --amend ]; then shift; fi
+	list="$manifests/$(key "$1")"
+	shift
+	rm -rf "$list"
+	mkdir -p "$list"
+	for image in "$@"; do
+		if [ ! -f "$registry/$(key "$image")" ]; then
+			echo "no such manifest: $image" >&2
+			exit 1
+		fi
+		cp "$registry/$(key "$image")" "$list/$(key "$image")"
+		echo unknown > "$list/$(key "$image").platform"
+	done
+	;;
+annotate)
+	os=; arch=; variant=
+	while [ $# -gt 2 ]; do
+		case "$1" in
+		--os) os=$2 ;;
+		--arch) arch=$2 ;;
+		--variant) variant=$2 ;;
+		*) echo "unsupported flag: $1" >&2; exit 1 ;;
+		esac
+		shift 2
+	done
+	entry="$manifests/$(key "$1")/$(key "$2")"
+	if [ ! -f "$entry" ]; then
+		echo "manifest for image $2 does not exist in $1" >&2
+		exit 1
+	fi
+	echo "$os/$arch${variant:+/$variant}" > "$entry.platform"
+	;;
+push)
+	if [ "$1" = --purge ]; then shift; fi
+	list="$manifests/$(key "$1")"
+	for entry in "$list"/*; do
+		case "$entry" in *.platform) continue ;; esac
+		echo "$(cat "$entry") $(cat "$entry.platform")"
+	done > "$registry/$(key "$1")"
+	rm -rf "$list"
+	;;
+*)
+	echo "unsupported command: manifest $cmd" >&2
+	exit 1
+	;;
+esac
+`
+
+// installFakeDocker writes fakeDockerScript as "bin/docker" in the provided directory and returns the directory that
+// stores its registry.
+func installFakeDocker(t *testing.T, dir string) string {
+	registryDir := path.Join(dir, "registry")
+	require.NoError(t, os.MkdirAll(registryDir, 0755))
+	require.NoError(t, os.MkdirAll(path.Join(dir, "bin"), 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, "bin", "docker"), []byte(strings.Replace(fakeDockerScript, "{{DIR}}", dir, -1)), 0755))
+	return registryDir
+}
+
+func fakeRegistryKey(image string) string {
+	return strings.NewReplacer("/", "_", ":", "_").Replace(image)
+}
+
+func manifestListProductParam(t *testing.T, repository string, images map[string]string) distgo.ProductParam {
+	dockerCfg := distgoconfig.DockerConfig{
+		Repository: stringPtr(repository),
+		ManifestLists: distgoconfig.ToManifestLists(&map[distgo.DockerManifestListID]distgoconfig.DockerManifestListConfig{
+			"multi-arch": {
+				TagTemplate: stringPtr("{{Repository}}foo:{{Version}}"),
+				Images:      &images,
+			},
+		}),
+	}
+	dockerParam, err := dockerCfg.ToParam("", distgoconfig.DockerConfig{}, nil)
+	require.NoError(t, err)
+	return distgo.ProductParam{
+		ID:     "foo",
+		Docker: &dockerParam,
+	}
+}
@@ -16,10 +16,44 @@ package distgo
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strconv"
+	"time"
+
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
 )
 
+// SourceDateEpochEnvVar is the environment variable that specifies the build time for reproducible builds as the number
+// of seconds since the Unix epoch. See https://reproducible-builds.org/specs/source-date-epoch/.
+const SourceDateEpochEnvVar = "SOURCE_DATE_EPOCH"
+
+// LookupSourceDateEpoch returns the value of SOURCE_DATE_EPOCH for the tasks of the project, which is
+// ProjectInfo.SourceDateEpoch if it is non-empty and the value of the SOURCE_DATE_EPOCH environment variable otherwise.
+// The returned boolean is false if neither is set.
+func (p ProjectInfo) LookupSourceDateEpoch() (string, bool) {
+	if p.SourceDateEpoch != "" {
+		return p.SourceDateEpoch, true
+	}
+	return os.LookupEnv(SourceDateEpochEnvVar)
+}
+
+// BuildTime returns the time that should be used as the time of the builds of the project. If SOURCE_DATE_EPOCH is set
+// for the project (see LookupSourceDateEpoch), the time it specifies is returned (and an error is returned if its
+// value is not a valid integer). Otherwise, the current time is returned.
+func (p ProjectInfo) BuildTime() (time.Time, error) {
+	sourceDateEpoch, ok := p.LookupSourceDateEpoch()
+	if !ok {
+		return time.Now(), nil
+	}
+	epochSeconds, err := strconv.ParseInt(sourceDateEpoch, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid value for %s: %q", SourceDateEpochEnvVar, sourceDateEpoch)
+	}
+	return time.Unix(epochSeconds, 0).UTC(), nil
+}
+
 func CreateScriptContent(script, scriptIncludes string) string {
 	if scriptIncludes == "" || script == "" {
 		return script
@@ -27,6 +61,21 @@ func CreateScriptContent(script, scriptIncludes string) string {
 	return scriptIncludes + "\n" + script
 }
 
+// BuildScriptEnvVariableNames contains the names of all of the environment variables that may be defined in the map
+// returned by BuildScriptEnvVariables. In names that contain "{#}", "{#}" is a non-negative integer index. Build scripts
+// depend on these variables, so variables must not be removed or renamed and any change to the environment returned
+// by BuildScriptEnvVariables must also update this list and the corresponding test.
+var BuildScriptEnvVariableNames = []string{
+	"PROJECT_DIR",
+	"VERSION",
+	"PRODUCT",
+	SourceDateEpochEnvVar,
+	"BUILD_DIR",
+	"BUILD_NAME",
+	"BUILD_OS_ARCH_COUNT",
+	"BUILD_OS_ARCH_{#}",
+}
+
 // BuildScriptEnvVariables returns a map of environment variables for the script for the builder. The returned map
 // contains the following environment variables:
 //
@@ -34,23 +83,52 @@ func CreateScriptContent(script, scriptIncludes string) string {
 //   VERSION: the version of the project
 //   PRODUCT: the name of the product
 //
+// The following environment variable is defined if it is set in the environment of the current process:
+//   SOURCE_DATE_EPOCH: the time used as the build time for reproducible builds (see ProjectInfo.BuildTime)
+//
 // The following environment variables are defined if the build configuration for the product is non-nil:
 //   BUILD_DIR: the build output directory for the product ("{{OutputDir}}/{{ProductID}}/{{Version}}")
-//   BUILD_NAME: the rendered NameTemplate for the build for this product
+//   BUILD_NAME: the rendered NameTemplate for the build for this product (not defined if NameTemplate uses
+//     OS/arch-specific parameters)
 //   BUILD_OS_ARCH_COUNT: the number of OS/arch combinations for this product
 //   BUILD_OS_ARCH_{#}: for 0 <= # < BUILD_OS_ARCHS_COUNT, contains the OS/arch for the build
+//
+// The names of all of the variables are listed in BuildScriptEnvVariableNames.
 func BuildScriptEnvVariables(outputInfo ProductTaskOutputInfo) map[string]string {
 	m := map[string]string{
 		"PROJECT_DIR": outputInfo.Project.ProjectDir,
 		"VERSION":     outputInfo.Project.Version,
 		"PRODUCT":     string(outputInfo.Product.ID),
 	}
+	if sourceDateEpoch, ok := outputInfo.Project.LookupSourceDateEpoch(); ok {
+		m[SourceDateEpochEnvVar] = sourceDateEpoch
+	}
 
 	// add build environment variables for current product
 	addProductBuildEnvVariables(m, "", outputInfo.Project, outputInfo.Product)
 	return m
 }
 
+// BuildArgsScriptEnvVariables returns a map of environment variables for the build arguments script for the provided
+// output configuration when building for the provided OSArch. The returned map contains all of the environment
+// variables returned by BuildScriptEnvVariables and the following environment variables:
+//
+//   OS: the GOOS of the build
+//   ARCH: the GOARCH of the build
+//
+// The following environment variable is defined if the OSArch specifies a variant of the architecture:
+//   ARCH_VARIANT: the variant of the architecture (for example, "7" for "linux-arm-7")
+func BuildArgsScriptEnvVariables(outputInfo ProductTaskOutputInfo, osArch osarch.OSArch) map[string]string {
+	m := BuildScriptEnvVariables(outputInfo)
+	goarch, variant := GOARCHAndVariant(osArch)
+	m["OS"] = osArch.OS
+	m["ARCH"] = goarch
+	if variant != "" {
+		m["ARCH_VARIANT"] = variant
+	}
+	return m
+}
+
 // DistScriptEnvVariables returns a map of environment variables for the script for the dister with the specified
 // DistID in the provided output configuration. The returned map contains the following environment variables:
 //
@@ -62,7 +140,8 @@ func BuildScriptEnvVariables(outputInfo ProductTaskOutputInfo) map[string]string
 //
 // The following environment variables are defined if the build configuration for the product is non-nil:
 //   BUILD_DIR: the build output directory for the product ("{{OutputDir}}/{{ProductID}}/{{Version}}")
-//   BUILD_NAME: the rendered NameTemplate for the build for this product
+//   BUILD_NAME: the rendered NameTemplate for the build for this product (not defined if NameTemplate uses
+//     OS/arch-specific parameters)
 //   BUILD_OS_ARCH_COUNT: the number of OS/arch combinations for this product
 //   BUILD_OS_ARCH_{#}: for 0 <= # < BUILD_OS_ARCHS_COUNT, contains the OS/arch for the build
 //
@@ -79,7 +158,8 @@ func BuildScriptEnvVariables(outputInfo ProductTaskOutputInfo) map[string]string
 //
 // The following environment variables are defined if the build configuration for the product is non-nil:
 //   DEP_PRODUCT_ID_{#}_BUILD_DIR: the build output directory for the product ("{{OutputDir}}/{{ProductID}}/{{Version}}")
-//   DEP_PRODUCT_ID_{#}_BUILD_NAME: the rendered NameTemplate for the build for this product
+//   DEP_PRODUCT_ID_{#}_BUILD_NAME: the rendered NameTemplate for the build for this product (not defined if
+//     NameTemplate uses OS/arch-specific parameters)
 //   DEP_PRODUCT_ID_{#}_BUILD_OS_ARCH_COUNT: the number of OS/arch combinations for this product
 //   DEP_PRODUCT_ID_{#}_BUILD_OS_ARCH_{##}: for 0 <= ## < BUILD_OS_ARCH_COUNT, contains the OS/arch for the build
 //
@@ -162,7 +242,9 @@ func addProductBuildEnvVariables(varMap map[string]string, prefix string, projec
 		return
 	}
 	varMap[prefix+"BUILD_DIR"] = ProductBuildOutputDir(projectInfo, productInfo)
-	varMap[prefix+"BUILD_NAME"] = productInfo.BuildOutputInfo.BuildNameTemplateRendered
+	if productInfo.BuildOutputInfo.BuildNameTemplateRendered != "" {
+		varMap[prefix+"BUILD_NAME"] = productInfo.BuildOutputInfo.BuildNameTemplateRendered
+	}
 	varMap[prefix+"BUILD_OS_ARCH_COUNT"] = strconv.Itoa(len(productInfo.BuildOutputInfo.OSArchs))
 	for i, osArch := range productInfo.BuildOutputInfo.OSArchs {
 		varMap[prefix+"BUILD_OS_ARCH_"+strconv.Itoa(i)] = osArch.String()
@@ -0,0 +1,151 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildScriptEnvVariablesSourceDateEpoch(t *testing.T) {
+	restoreFn := setSourceDateEpoch(t, nil)
+	outputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: "/project",
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+
+	_, ok := distgo.BuildScriptEnvVariables(outputInfo)["SOURCE_DATE_EPOCH"]
+	assert.False(t, ok, "SOURCE_DATE_EPOCH should not be defined if it is not set")
+	restoreFn()
+
+	epoch := "1600000000"
+	defer setSourceDateEpoch(t, &epoch)()
+	assert.Equal(t, "1600000000", distgo.BuildScriptEnvVariables(outputInfo)["SOURCE_DATE_EPOCH"])
+
+	// SOURCE_DATE_EPOCH of the project takes precedence over the environment variable
+	outputInfo.Project.SourceDateEpoch = "1700000000"
+	assert.Equal(t, "1700000000", distgo.BuildScriptEnvVariables(outputInfo)["SOURCE_DATE_EPOCH"])
+}
+
+// TestBuildScriptEnvVariablesGolden verifies the exact environment provided to build scripts. Build scripts depend on
+// this environment, so this test must only be updated for changes to the environment that are intentional.
+func TestBuildScriptEnvVariablesGolden(t *testing.T) {
+	epoch := "1600000000"
+	defer setSourceDateEpoch(t, &epoch)()
+
+	outputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: "/project",
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			BuildOutputInfo: &distgo.BuildOutputInfo{
+				BuildNameTemplateRendered: "foo-bin",
+				BuildOutputDir:            "out/build",
+				OSArchs: []osarch.OSArch{
+					{OS: "darwin", Arch: "amd64"},
+					{OS: "linux", Arch: "arm64"},
+				},
+			},
+		},
+	}
+
+	got := distgo.BuildScriptEnvVariables(outputInfo)
+	assert.Equal(t, map[string]string{
+		"PROJECT_DIR":         "/project",
+		"VERSION":             "1.0.0",
+		"PRODUCT":             "foo",
+		"SOURCE_DATE_EPOCH":   "1600000000",
+		"BUILD_DIR":           "/project/out/build/foo/1.0.0",
+		"BUILD_NAME":          "foo-bin",
+		"BUILD_OS_ARCH_COUNT": "2",
+		"BUILD_OS_ARCH_0":     "darwin-amd64",
+		"BUILD_OS_ARCH_1":     "linux-arm64",
+	}, got)
+
+	// every variable in the environment must be documented in BuildScriptEnvVariableNames
+	var namePatterns []*regexp.Regexp
+	for _, name := range distgo.BuildScriptEnvVariableNames {
+		namePatterns = append(namePatterns, regexp.MustCompile("^"+strings.Replace(regexp.QuoteMeta(name), regexp.QuoteMeta("{#}"), "[0-9]+", -1)+"$"))
+	}
+	for k := range got {
+		matched := false
+		for _, pattern := range namePatterns {
+			if pattern.MatchString(k) {
+				matched = true
+				break
+			}
+		}
+		assert.True(t, matched, "variable %s is not in BuildScriptEnvVariableNames", k)
+	}
+}
+
+func TestBuildScriptEnvVariablesOSArchSpecificName(t *testing.T) {
+	outputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: "/project",
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			BuildOutputInfo: &distgo.BuildOutputInfo{
+				BuildOutputDir: "out/build",
+				OSArchs: []osarch.OSArch{
+					{OS: "linux", Arch: "arm64"},
+				},
+				OSArchBuildNamesRendered: map[distgo.BuildOSArchID]string{
+					"linux-arm64": "foo-linux-arm64",
+				},
+			},
+		},
+	}
+
+	// the name template can only be rendered for a specific OS/arch, so BUILD_NAME is not defined
+	got := distgo.BuildScriptEnvVariables(outputInfo)
+	_, ok := got["BUILD_NAME"]
+	assert.False(t, ok, "BUILD_NAME should not be defined, was %q", got["BUILD_NAME"])
+	assert.Equal(t, "linux-arm64", got["BUILD_OS_ARCH_0"])
+}
+
+// setSourceDateEpoch sets the SOURCE_DATE_EPOCH environment variable to the provided value (or unsets it if the value
+// is nil) and returns a function that restores the original value.
+func setSourceDateEpoch(t *testing.T, val *string) func() {
+	origVal, origSet := os.LookupEnv(distgo.SourceDateEpochEnvVar)
+	if val == nil {
+		require.NoError(t, os.Unsetenv(distgo.SourceDateEpochEnvVar))
+	} else {
+		require.NoError(t, os.Setenv(distgo.SourceDateEpochEnvVar, *val))
+	}
+	return func() {
+		if origSet {
+			require.NoError(t, os.Setenv(distgo.SourceDateEpochEnvVar, origVal))
+		} else {
+			require.NoError(t, os.Unsetenv(distgo.SourceDateEpochEnvVar))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/pkg/errors"
+)
+
+// variantEnvVars maps the GOARCH values that support variants to the environment variable that specifies the variant.
+var variantEnvVars = map[string]string{
+	"386":      "GO386",
+	"amd64":    "GOAMD64",
+	"arm":      "GOARM",
+	"mips":     "GOMIPS",
+	"mipsle":   "GOMIPS",
+	"mips64":   "GOMIPS64",
+	"mips64le": "GOMIPS64",
+	"ppc64":    "GOPPC64",
+	"ppc64le":  "GOPPC64",
+}
+
+// NewOSArch returns the osarch.OSArch represented by the provided input, which must be of the form "GOOS-GOARCH" or
+// "GOOS-GOARCH-VARIANT". If a variant is specified, it is stored as part of the Arch field of the returned value (for
+// example, "linux-arm-7" results in an OSArch with OS "linux" and Arch "arm-7") so that the string representation of
+// the returned value is equal to the input. Returns an error if the input is not of the correct form or if a variant
+// is specified for a GOARCH that does not support variants.
+func NewOSArch(input string) (osarch.OSArch, error) {
+	parts := strings.Split(input, "-")
+	switch len(parts) {
+	case 2:
+		return osarch.New(input)
+	case 3:
+		osArch, err := osarch.New(parts[0] + "-" + parts[1])
+		if err != nil || parts[2] == "" {
+			return osarch.OSArch{}, errors.Errorf("not a valid OSArch value: %s", input)
+		}
+		if _, ok := variantEnvVars[osArch.Arch]; !ok {
+			return osarch.OSArch{}, errors.Errorf("GOARCH %s does not support variants: must be one of %v", osArch.Arch, variantGOARCHs())
+		}
+		osArch.Arch += "-" + parts[2]
+		return osArch, nil
+	default:
+		return osarch.OSArch{}, errors.Errorf("not a valid OSArch value: %s", input)
+	}
+}
+
+// GOARCHAndVariant returns the GOARCH and variant for the provided OSArch. The variant is the portion of the Arch field
+// after the first hyphen, and is empty if the Arch field does not specify a variant.
+func GOARCHAndVariant(osArch osarch.OSArch) (goarch, variant string) {
+	if hyphenIdx := strings.Index(osArch.Arch, "-"); hyphenIdx != -1 {
+		return osArch.Arch[:hyphenIdx], osArch.Arch[hyphenIdx+1:]
+	}
+	return osArch.Arch, ""
+}
+
+// GoEnvironment returns the Go environment variables that should be set to build for the provided OSArch in
+// "KEY=VALUE" form. The returned slice contains GOOS and GOARCH (if they are non-empty), followed by the variable that
+// specifies the variant of the architecture (for example, GOARM for arm or GOMIPS for mips) if the OSArch specifies a
+// variant.
+func GoEnvironment(osArch osarch.OSArch) []string {
+	var env []string
+	if osArch.OS != "" {
+		env = append(env, "GOOS="+osArch.OS)
+	}
+	goarch, variant := GOARCHAndVariant(osArch)
+	if goarch != "" {
+		env = append(env, "GOARCH="+goarch)
+	}
+	if variantEnvVar, ok := variantEnvVars[goarch]; ok && variant != "" {
+		env = append(env, variantEnvVar+"="+variant)
+	}
+	return env
+}
+
+func variantGOARCHs() []string {
+	var goarchs []string
+	for k := range variantEnvVars {
+		goarchs = append(goarchs, k)
+	}
+	sort.Strings(goarchs)
+	return goarchs
+}
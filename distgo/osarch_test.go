@@ -0,0 +1,107 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOSArch(t *testing.T) {
+	for i, tc := range []struct {
+		input     string
+		want      osarch.OSArch
+		wantError string
+	}{
+		{
+			input: "linux-amd64",
+			want:  osarch.OSArch{OS: "linux", Arch: "amd64"},
+		},
+		{
+			input: "linux-arm-6",
+			want:  osarch.OSArch{OS: "linux", Arch: "arm-6"},
+		},
+		{
+			input: "linux-arm-7",
+			want:  osarch.OSArch{OS: "linux", Arch: "arm-7"},
+		},
+		{
+			input: "linux-mips-softfloat",
+			want:  osarch.OSArch{OS: "linux", Arch: "mips-softfloat"},
+		},
+		{
+			input:     "linux-arm64-8",
+			wantError: "GOARCH arm64 does not support variants: must be one of [386 amd64 arm mips mips64 mips64le mipsle ppc64 ppc64le]",
+		},
+		{
+			input:     "linux-arm-",
+			wantError: "not a valid OSArch value: linux-arm-",
+		},
+		{
+			input:     "linux",
+			wantError: "not a valid OSArch value: linux",
+		},
+		{
+			input:     "linux-arm-7-extra",
+			wantError: "not a valid OSArch value: linux-arm-7-extra",
+		},
+	} {
+		got, err := distgo.NewOSArch(tc.input)
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d", i)
+			continue
+		}
+		assert.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.want, got, "Case %d", i)
+		assert.Equal(t, tc.input, got.String(), "Case %d", i)
+	}
+}
+
+func TestGoEnvironment(t *testing.T) {
+	for i, tc := range []struct {
+		osArch osarch.OSArch
+		want   []string
+	}{
+		{
+			osArch: osarch.OSArch{},
+			want:   nil,
+		},
+		{
+			osArch: osarch.OSArch{OS: "linux", Arch: "amd64"},
+			want:   []string{"GOOS=linux", "GOARCH=amd64"},
+		},
+		{
+			osArch: osarch.OSArch{OS: "linux", Arch: "arm-6"},
+			want:   []string{"GOOS=linux", "GOARCH=arm", "GOARM=6"},
+		},
+		{
+			osArch: osarch.OSArch{OS: "linux", Arch: "arm-7"},
+			want:   []string{"GOOS=linux", "GOARCH=arm", "GOARM=7"},
+		},
+		{
+			osArch: osarch.OSArch{OS: "linux", Arch: "mips-softfloat"},
+			want:   []string{"GOOS=linux", "GOARCH=mips", "GOMIPS=softfloat"},
+		},
+		{
+			osArch: osarch.OSArch{OS: "linux", Arch: "mips64le-hardfloat"},
+			want:   []string{"GOOS=linux", "GOARCH=mips64le", "GOMIPS64=hardfloat"},
+		},
+	} {
+		assert.Equal(t, tc.want, distgo.GoEnvironment(tc.osArch), "Case %d", i)
+	}
+}
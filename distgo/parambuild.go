@@ -16,6 +16,14 @@ package distgo
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
@@ -35,22 +43,43 @@ type BuildParam struct {
 	// template:
 	//   * {{Product}}: the name of the product
 	//   * {{Version}}: the version of the project
+	//   * {{OSArch}}: the OS/architecture of the executable (for example, "linux-amd64")
+	//   * {{GOOS}}: the GOOS of the executable (for example, "linux")
+	//   * {{GOARCH}}: the GOARCH of the executable without the architecture variant (for example, "arm" for "arm-7")
+	//   * {{ExeExt}}: ".exe" if the OS of the executable is Windows, ".wasm" if the OS of the executable is "js" or
+	//     "wasip1", and the empty string otherwise
+	// A template that uses any of the OSArch-specific parameters can only be rendered for a specific OSArch: in that
+	// case, BuildOutputInfo.BuildNameTemplateRendered is empty and BuildOutputInfo.OSArchBuildNamesRendered contains
+	// the rendered name for each OSArch.
 	NameTemplate string
 
 	// OutputDir specifies the default build output directory for products executables built by the "build" task. The
 	// executables generated by "build" are written to "{{OutputDir}}/{{ID}}/{{Version}}/{{OSArch}}/{{NameTemplate}}".
 	OutputDir string
 
-	// MainPkg is the location of the main package for the product relative to the project root directory. For example,
-	// "distgo/main".
+	// MainPkg is the location of the main package for the product relative to ModuleDir. For example, "distgo/main".
+	// If empty, the product does not have a main package and the build of the product is a no-op: no executables are
+	// created, but the BuildOutputInfo of the product is still computed so that tasks such as "dist" can be run for it.
 	MainPkg string
 
+	// OSArchMainPkg specifies main packages that are used instead of MainPkg when building for specific OSArchs. For
+	// example, a product may use a different main package for Windows that embeds a resource manifest. The packages are
+	// relative to ModuleDir. MainPkg is used for any OSArch that does not have an entry. Entries are ignored if MainPkg
+	// is empty.
+	OSArchMainPkg map[osarch.OSArch]string
+
+	// ModuleDir is the directory of the Go module that contains MainPkg relative to the project root directory. The
+	// "build" command is run in this directory. If empty, the project root directory is used.
+	ModuleDir string
+
 	// BuildArgsScript is the content of a script that is written to a file and run before this product is built
 	// to provide supplemental build arguments for the product. The content of this value is written to a file and
 	// executed. The script process uses the project directory as its working directory and inherits the environment
-	// variables of the Go process. Each line of output of the script is provided to the "build" command as a separate
-	// argument. For example, the following script would add the arguments "-ldflags" "-X" "main.year=$YEAR" to the
-	// build command:
+	// variables of the Go process. The script is run separately for each OSArch that is built, and the environment of
+	// the script contains the product, version and target OS/architecture of the build (refer to the documentation for
+	// the BuildArgsScriptEnvVariables function for the environment variables). Each line of output of the script is
+	// provided to the "build" command as a separate argument. For example, the following script would add the arguments
+	// "-ldflags" "-X" "main.year=$YEAR" to the build command:
 	//
 	//   #!/usr/bin/env bash
 	//   YEAR=$(date +%Y)
@@ -59,49 +88,734 @@ type BuildParam struct {
 	//   echo "main.year=$YEAR"
 	BuildArgsScript string
 
+	// BuildArgsScriptFile is the path to a file relative to the project directory whose content is used as the build
+	// arguments script. The content of the file is prefixed with BuildArgsScriptIncludes and is then written and
+	// executed in exactly the same manner as BuildArgsScript. At most one of BuildArgsScript and BuildArgsScriptFile
+	// may be non-empty.
+	BuildArgsScriptFile string
+
+	// BuildArgsScriptIncludes is the content that is prepended to the content of BuildArgsScriptFile. Typically set to
+	// the script includes of the project so that the content of the file is treated in the same manner as an inline
+	// BuildArgsScript.
+	BuildArgsScriptIncludes string
+
 	// VersionVar is the path to a variable that is set with the version information for the build. For example,
 	// "github.com/palantir/godel/v2/cmd/godel.Version". If specified, it is provided to the "build" command as an
 	// ldflag.
 	VersionVar string
 
+	// VersionVarValueTemplate is the template that is rendered to produce the value of VersionVar. The template is
+	// rendered in the same manner as VersionVarSpec.ValueTemplate. If empty, "{{Version}}" is used.
+	VersionVarValueTemplate string
+
+	// VersionVars specifies additional variables whose values are set using the "-X" linker flag. If VersionVar is
+	// non-empty, it is treated as if it were the first entry of VersionVars with VersionVarValueTemplate as its
+	// ValueTemplate. All of the variables are provided as a single "-ldflags" argument to the "build" command.
+	VersionVars []VersionVarSpec
+
+	// VersionFile specifies a Go source file that contains build metadata and is generated in a package of the product
+	// before the product is built. Unlike VersionVars, the generated file can declare typed constants and values that
+	// are used to initialize struct fields. If nil, no file is generated.
+	VersionFile *VersionFileSpec
+
+	// VersionCheckArgs specifies the arguments (for example, "--version") with which the built executable is run after
+	// it is built to verify that the version was set correctly. If non-empty, the build fails if the combined output of
+	// the executable does not contain the version of the project. The check is only performed for the OSArch of the
+	// host (executables built for other OSArchs cannot be run) and is skipped if the build mode does not produce an
+	// executable.
+	VersionCheckArgs []string
+
 	// Environment specifies values for the environment variables that should be set for the build. For example,
-	// a value of map[string]string{"CGO_ENABLED": "0"} would build with CGo disabled.
+	// a value of map[string]string{"CGO_ENABLED": "0"} would build with CGo disabled. References to environment
+	// variables of the form "$VAR" or "${VAR}" in the values are expanded using the environment of the Go process when
+	// the build is run (see ExpandEnvironmentValue).
 	Environment map[string]string
 
+	// OSArchEnvironment specifies values for environment variables that should be set only when building for a
+	// specific OSArch. The values for an OSArch are merged over the values in Environment: if both Environment and the
+	// entry for the OSArch being built define the same variable, the value in OSArchEnvironment is used. The values are
+	// expanded in the same manner as the values in Environment.
+	OSArchEnvironment map[osarch.OSArch]map[string]string
+
+	// CrossCompilers specifies the C and C++ compilers that CGo should use when building for specific OSArchs. The
+	// environment variables for the entry for the OSArch being built are merged over the values in Environment, and
+	// the values in OSArchEnvironment take precedence over them. Entries for other OSArchs are ignored.
+	CrossCompilers map[osarch.OSArch]CrossCompilerSpec
+
+	// DisableCgoForCrossCompile specifies whether CGo should be disabled automatically when cross-compiling. If true,
+	// the build for an OSArch whose GOOS or GOARCH differs from that of the host sets CGO_ENABLED to "0" unless the
+	// environment for the OSArch (see EnvironmentForOSArch) specifies a C compiler using CC or sets CGO_ENABLED itself.
+	// Builds for the GOOS and GOARCH of the host are not affected. Building with CGo enabled for a different platform
+	// without a cross-compiling C toolchain typically fails with errors from the linker, so this is useful for products
+	// that use CGo only optionally. Because the race detector requires CGo, it is an error to build an OSArch for which
+	// CGo would be disabled in this manner if Race is true.
+	DisableCgoForCrossCompile bool
+
+	// GoExperiment specifies the value of the GOEXPERIMENT environment variable for the build (for example,
+	// "loopvar"). If the environment for the OSArch being built (see EnvironmentForOSArch) also sets GOEXPERIMENT, the
+	// value is appended to the value from the environment using a comma rather than replacing it. Because later
+	// entries in GOEXPERIMENT take precedence over earlier ones, the value of GoExperiment wins for any experiment that
+	// is specified in both.
+	GoExperiment string
+
+	// GoFlags specifies additional flags that are provided to the "build" command. For example,
+	// []string{"-mod=vendor", "-tags=netgo"}. The flags are provided after the arguments generated by the build
+	// arguments script and before the flags that distgo generates from the other build parameters. If GoFlags or the
+	// build arguments script specify a "-tags", "-gcflags", "-asmflags" or "-ldflags" flag, the values that distgo
+	// generates for that flag (from BuildTags, GCFlags, AsmFlags, LDFlags, StripDebug and the version variables) are
+	// merged into the value of the last such flag rather than provided as a separate flag that would override it.
+	GoFlags []string
+
+	// Trimpath specifies whether the "-trimpath" flag should be provided to the "build" command. If true, file system
+	// paths are removed from the resulting executable.
+	Trimpath bool
+
+	// BuildTags specifies the build tags that should be provided to the "build" command. If non-empty, the tags are
+	// joined using spaces and provided as a single argument to the "-tags" flag.
+	BuildTags []string
+
+	// GCFlags specifies the arguments that should be provided to the compiler using the "-gcflags" flag of the "build"
+	// command. If non-empty, the entries are joined using spaces and provided as a single argument to "-gcflags". For
+	// example, a value of []string{"all=-N", "-l"} results in the arguments "-gcflags" and "all=-N -l".
+	GCFlags []string
+
+	// AsmFlags specifies the arguments that should be provided to the assembler using the "-asmflags" flag of the
+	// "build" command. If non-empty, the entries are joined using spaces and provided as a single argument to
+	// "-asmflags".
+	AsmFlags []string
+
+	// LDFlags specifies the arguments that should be provided to the linker using the "-ldflags" flag of the "build"
+	// command (for example, []string{"-linkmode", "external", "-extldflags", "'-static'"}). The "build" command only
+	// honors the last "-ldflags" flag, so the entries are not provided as a separate flag: they are joined using spaces
+	// in the order in which they are specified and provided in the same "-ldflags" argument as the flags that are
+	// generated for StripDebug and the version variables. Each entry is provided verbatim, so a value that contains
+	// spaces must be quoted (for example, "-extldflags '-static -lm'").
+	LDFlags []string
+
+	// Race specifies whether the "-race" flag should be provided to the "build" command. If true, the race detector is
+	// enabled for the resulting executable. Because the race detector requires CGo, it is an error for the environment
+	// used to build an OSArch to set CGO_ENABLED to "0" if Race is true.
+	Race bool
+
+	// BuildMode specifies the value of the "-buildmode" flag that is provided to the "build" command. If non-empty,
+	// must be one of the build modes supported by Go (see "go help buildmode"). If the build mode produces a library
+	// rather than an executable, the extension for the library is appended to the rendered name of the build output.
+	BuildMode string
+
+	// StripDebug specifies whether the symbol table and DWARF debugging information should be omitted from the
+	// resulting executable. If true, "-s -w" is added to the "-ldflags" argument that is provided to the "build"
+	// command (the same argument that sets the values of the version variables).
+	StripDebug bool
+
+	// MaxOutputBytes is the maximum size in bytes of the output of the build for each OSArch. If greater than 0, the
+	// size of the build output is checked after every successful build and the build fails if the output is larger.
+	// The check is performed on the final output, so it accounts for the effect of options such as StripDebug.
+	MaxOutputBytes int64
+
+	// CleanOutputDir specifies whether the output directory for an OS/architecture of the product
+	// ("{{OutputDir}}/{{ID}}/{{Version}}/{{OSArch}}") is removed before the product is built for the OS/architecture.
+	// This ensures that files from previous builds (for example, a library created using a different build mode) are
+	// not included in the outputs. Only the directory for the OS/architecture being built is removed: the outputs for
+	// other products, versions and OS/architectures are not modified.
+	CleanOutputDir bool
+
+	// LatestLink specifies whether a "latest" entry that refers to the version directory of the most recent successful
+	// build is maintained in the output directory of the product ("{{OutputDir}}/{{ID}}/latest"). The entry is a
+	// symbolic link to the version directory. If a symbolic link cannot be created (for example, on Windows systems
+	// where creating symbolic links requires elevated privileges), a regular file whose content is the name of the
+	// version directory is written instead.
+	LatestLink bool
+
 	// Script is the content of a script that is written to a file and run before the build processes start. The script
 	// process inherits the environment variables of the Go process and also has project-related environment variables.
 	// Refer to the documentation for the distgo.BuildScriptEnvVariables function for the extra environment variables.
+	// If the script exits with BuildScriptSkipExitCode, the build of the product is skipped. If the script exits with
+	// any other non-zero exit code, the build fails.
 	Script string
 
-	// OSArchs specifies the GOOS and GOARCH pairs for which the product is built.
+	// GoBinary specifies the Go executable that is used to run the "build" command. The value can be an absolute path,
+	// a relative path (which is resolved relative to the project directory) or the name of an executable on the PATH.
+	// If empty, "go" is used.
+	GoBinary string
+
+	// BuildRetries is the number of times the "build" command is retried if it fails with an error that appears to be
+	// transient (for example, a network error encountered while downloading modules). Failures that are not transient,
+	// such as compilation errors, are never retried.
+	BuildRetries int
+
+	// BuildRetryBackoff is the amount of time to wait before the first retry of the "build" command. The wait time
+	// doubles for every subsequent retry. If zero, DefaultBuildRetryBackoff is used.
+	BuildRetryBackoff time.Duration
+
+	// OSArchs specifies the GOOS and GOARCH pairs for which the product is built. The Arch of an OSArch may specify a
+	// variant of the architecture in the form "GOARCH-VARIANT" (for example, "arm-7" or "mips-softfloat"), in which
+	// case the variant is set using the corresponding environment variable (for example, GOARM or GOMIPS) when
+	// building. Refer to the documentation for the NewOSArch function for more information.
 	OSArchs []osarch.OSArch
 }
 
 type BuildOutputInfo struct {
 	BuildNameTemplateRendered string          `json:"buildNameTemplateRendered"`
 	BuildOutputDir            string          `json:"buildOutputDir"`
+	BuildMode                 string          `json:"buildMode,omitempty"`
 	OSArchs                   []osarch.OSArch `json:"osArchs"`
+
+	// OSArchBuildNamesRendered contains the name template rendered for each OSArch. Only populated if the rendered name
+	// for at least one OSArch differs from BuildNameTemplateRendered, which is the case if the template uses an
+	// OSArch-specific parameter such as {{OSArch}} (in which case BuildNameTemplateRendered is empty).
+	OSArchBuildNamesRendered map[BuildOSArchID]string `json:"osArchBuildNamesRendered,omitempty"`
+
+	// NoArtifacts is true if the build of the product is a no-op because the product does not specify a main package.
+	// If true, the build does not create any artifacts for any of the OSArchs.
+	NoArtifacts bool `json:"noArtifacts,omitempty"`
+
+	// MainPkg is the main package that is built for the OSArchs that do not have an entry in OSArchMainPkgs.
+	MainPkg string `json:"mainPkg,omitempty"`
+
+	// OSArchMainPkgs contains the main package for each OSArch in OSArchs whose main package differs from MainPkg.
+	OSArchMainPkgs map[BuildOSArchID]string `json:"osArchMainPkgs,omitempty"`
+}
+
+// MainPkgForOSArch returns the main package that is built for the provided OSArch.
+func (b *BuildOutputInfo) MainPkgForOSArch(osArch osarch.OSArch) string {
+	if mainPkg, ok := b.OSArchMainPkgs[BuildOSArchID(osArch.String())]; ok {
+		return mainPkg
+	}
+	return b.MainPkg
+}
+
+// BuildNameRendered returns the rendered name template for the provided OSArch.
+func (b *BuildOutputInfo) BuildNameRendered(osArch osarch.OSArch) string {
+	if name, ok := b.OSArchBuildNamesRendered[BuildOSArchID(osArch.String())]; ok {
+		return name
+	}
+	return b.BuildNameTemplateRendered
+}
+
+// AbsoluteBuildOutputDir returns the absolute path of BuildOutputDir. BuildOutputDir is returned unmodified if it is
+// already absolute. Otherwise, it is resolved relative to the provided project directory, which is itself resolved
+// relative to the working directory if it is not absolute.
+func (b *BuildOutputInfo) AbsoluteBuildOutputDir(projectDir string) (string, error) {
+	if filepath.IsAbs(b.BuildOutputDir) {
+		return filepath.Clean(b.BuildOutputDir), nil
+	}
+	absPath, err := filepath.Abs(filepath.Join(projectDir, b.BuildOutputDir))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to determine absolute path of build output directory %s", b.BuildOutputDir)
+	}
+	return absPath, nil
+}
+
+// ArtifactName returns the file name of the build artifact for the provided OSArch. If the build mode produces an
+// executable, this is the name returned by ExecutableName for the rendered name for the OSArch. Otherwise, the library
+// extension for the OS is already part of the rendered name for the OSArch. If the OSArch does not have its own
+// rendered name, the extension of BuildNameTemplateRendered is replaced with the extension for the OS.
+func (b *BuildOutputInfo) ArtifactName(osArch osarch.OSArch) string {
+	if IsExecutableBuildMode(b.BuildMode) {
+		return ExecutableName(b.BuildNameRendered(osArch), osArch.OS)
+	}
+	if name, ok := b.OSArchBuildNamesRendered[BuildOSArchID(osArch.String())]; ok {
+		return name
+	}
+	return strings.TrimSuffix(b.BuildNameTemplateRendered, buildModeExtension(b.BuildMode, "")) + buildModeExtension(b.BuildMode, osArch.OS)
+}
+
+// isOSArchSpecificNameTemplate returns true if the result of rendering the provided name template depends on the OSArch
+// for which it is rendered, which is the case if the template uses an OSArch-specific parameter such as {{OSArch}}.
+func isOSArchSpecificNameTemplate(nameTemplate string, productID ProductID, version string) (bool, error) {
+	noOSArchName, err := renderBuildNameTemplate(nameTemplate, productID, version, osarch.OSArch{})
+	if err != nil {
+		return false, err
+	}
+	for _, osArch := range []osarch.OSArch{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "windows", Arch: "arm64"},
+	} {
+		osArchName, err := renderBuildNameTemplate(nameTemplate, productID, version, osArch)
+		if err != nil {
+			return false, err
+		}
+		if osArchName != noOSArchName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// buildModeExtension returns the file extension of the artifact produced by the provided build mode for the provided
+// OS. Shared libraries use the extension of the platform's dynamic libraries; if the OS is empty, ".so" is used.
+func buildModeExtension(buildMode, goos string) string {
+	ext := buildModeExtensions[buildMode]
+	if ext != ".so" {
+		return ext
+	}
+	switch goos {
+	case "windows":
+		return ".dll"
+	case "darwin":
+		return ".dylib"
+	default:
+		return ext
+	}
+}
+
+// buildModeExtensions maps the build modes supported by Go to the file extension of the artifact produced by the build
+// mode. Build modes that produce executables map to the empty string. The extension for shared libraries is the one
+// used on Linux: buildModeExtension returns the extension for a specific OS.
+var buildModeExtensions = map[string]string{
+	"archive":   ".a",
+	"c-archive": ".a",
+	"c-shared":  ".so",
+	"default":   "",
+	"exe":       "",
+	"pie":       "",
+	"plugin":    ".so",
+	"shared":    ".so",
+}
+
+// IsExecutableBuildMode returns true if the provided build mode produces an executable rather than a library.
+func IsExecutableBuildMode(buildMode string) bool {
+	return buildModeExtensions[buildMode] == ""
+}
+
+// ValidateBuildMode returns an error if the provided value is not empty and is not a build mode supported by Go.
+func ValidateBuildMode(buildMode string) error {
+	if buildMode == "" {
+		return nil
+	}
+	if _, ok := buildModeExtensions[buildMode]; !ok {
+		var validModes []string
+		for k := range buildModeExtensions {
+			validModes = append(validModes, k)
+		}
+		sort.Strings(validModes)
+		return errors.Errorf("invalid build mode %q: must be one of %v", buildMode, validModes)
+	}
+	return nil
 }
 
 func (p *BuildParam) ToBuildOutputInfo(productID ProductID, version string) (BuildOutputInfo, error) {
-	renderedName, err := renderNameTemplate(p.NameTemplate, productID, version)
+	if err := ValidateBuildMode(p.BuildMode); err != nil {
+		return BuildOutputInfo{}, err
+	}
+	osArchSpecific, err := isOSArchSpecificNameTemplate(p.NameTemplate, productID, version)
 	if err != nil {
 		return BuildOutputInfo{}, errors.Wrapf(err, "failed to render name template")
 	}
+	// a name template that uses OSArch-specific parameters can only be rendered for a specific OSArch, so the rendered
+	// name for the product as a whole is empty and the names for all of the OSArchs are recorded
+	var renderedName string
+	if !osArchSpecific {
+		if renderedName, err = renderBuildNameTemplate(p.NameTemplate, productID, version, osarch.OSArch{}); err != nil {
+			return BuildOutputInfo{}, errors.Wrapf(err, "failed to render name template")
+		}
+		renderedName += buildModeExtension(p.BuildMode, "")
+	}
+
+	var osArchNames map[BuildOSArchID]string
+	for _, osArch := range p.OSArchs {
+		osArchName, err := renderBuildNameTemplate(p.NameTemplate, productID, version, osArch)
+		if err != nil {
+			return BuildOutputInfo{}, errors.Wrapf(err, "failed to render name template for %s", osArch)
+		}
+		osArchName += buildModeExtension(p.BuildMode, osArch.OS)
+		if osArchName == renderedName {
+			continue
+		}
+		if osArchNames == nil {
+			osArchNames = make(map[BuildOSArchID]string)
+		}
+		osArchNames[BuildOSArchID(osArch.String())] = osArchName
+	}
+	if osArchNames != nil {
+		// if any OSArch has a distinct name, record the names for all of the OSArchs
+		for _, osArch := range p.OSArchs {
+			if _, ok := osArchNames[BuildOSArchID(osArch.String())]; !ok {
+				osArchNames[BuildOSArchID(osArch.String())] = renderedName
+			}
+		}
+	}
+
+	var osArchMainPkgs map[BuildOSArchID]string
+	for _, osArch := range p.OSArchs {
+		if mainPkg := p.MainPkgForOSArch(osArch); mainPkg != p.MainPkg {
+			if osArchMainPkgs == nil {
+				osArchMainPkgs = make(map[BuildOSArchID]string)
+			}
+			osArchMainPkgs[BuildOSArchID(osArch.String())] = mainPkg
+		}
+	}
+
 	return BuildOutputInfo{
+		OSArchBuildNamesRendered:  osArchNames,
 		BuildNameTemplateRendered: renderedName,
 		BuildOutputDir:            p.OutputDir,
+		BuildMode:                 p.BuildMode,
 		OSArchs:                   p.OSArchs,
+		NoArtifacts:               p.MainPkg == "",
+		MainPkg:                   p.MainPkg,
+		OSArchMainPkgs:            osArchMainPkgs,
 	}, nil
 }
 
+// VersionVarSpec specifies a variable whose value is set using the "-X" linker flag.
+type VersionVarSpec struct {
+	// Variable is the path to the variable whose value should be set. For example, "main.version".
+	Variable string
+
+	// ValueTemplate is the template that is rendered to produce the value of the variable. The template is rendered
+	// with the ProductTaskOutputInfo for the build as its data and can use the following functions:
+	//
+	//   {{Product}}: the ID of the product
+	//   {{Version}}: the version of the project
+	//   {{GitCommit}}: the full SHA of the git commit checked out in the project directory
+	//   {{BuildTime}}: the time returned by ProjectInfo.BuildTime in UTC, formatted using RFC 3339
+	//
+	// If empty, "{{Version}}" is used.
+	ValueTemplate string
+}
+
+// versionVarSpecs returns all of the VersionVarSpecs for the build, which is VersionVars with VersionVar (if non-empty)
+// prepended to it.
+func (p *BuildParam) versionVarSpecs() []VersionVarSpec {
+	if p.VersionVar == "" {
+		return p.VersionVars
+	}
+	return append([]VersionVarSpec{{Variable: p.VersionVar, ValueTemplate: p.VersionVarValueTemplate}}, p.VersionVars...)
+}
+
+// DefaultBuildRetryBackoff is the amount of time to wait before the first retry of the "build" command if
+// BuildRetryBackoff is not specified.
+const DefaultBuildRetryBackoff = time.Second
+
+// CrossCompilerSpec specifies the compilers that CGo should use when building for a specific OSArch.
+type CrossCompilerSpec struct {
+	// CC is the C compiler that is exported as the CC environment variable. For example, "aarch64-linux-gnu-gcc".
+	CC string
+	// CXX is the C++ compiler that is exported as the CXX environment variable. For example, "aarch64-linux-gnu-g++".
+	CXX string
+	// CGOCFlags specifies additional flags for the C compiler. If non-empty, the value is appended to the value of
+	// CGO_CFLAGS in the environment for the build.
+	CGOCFlags string
+}
+
+// EnvironmentForOSArch returns the environment variables that should be set when building for the provided OSArch. The
+// variables for the entry in CrossCompilers for the OSArch are merged over the entries in Environment, and the entries
+// in OSArchEnvironment for the OSArch are merged over the result, so if a variable is defined in multiple places the
+// OSArch-specific value is used. The values from Environment and OSArchEnvironment are expanded using
+// ExpandEnvironmentValue. If GoExperiment is non-empty, it is appended to the resulting value of GOEXPERIMENT (see
+// GoExperiment). Returns nil if no environment variables are defined for the OSArch.
+func (p *BuildParam) EnvironmentForOSArch(osArch osarch.OSArch) map[string]string {
+	osArchEnv := p.OSArchEnvironment[osArch]
+	crossCompiler, hasCrossCompiler := p.CrossCompilers[osArch]
+	if len(p.Environment) == 0 && len(osArchEnv) == 0 && !hasCrossCompiler && p.GoExperiment == "" {
+		return nil
+	}
+	env := make(map[string]string, len(p.Environment)+len(osArchEnv)+3)
+	for k, v := range p.Environment {
+		env[k] = ExpandEnvironmentValue(v)
+	}
+	if crossCompiler.CC != "" {
+		env["CC"] = crossCompiler.CC
+	}
+	if crossCompiler.CXX != "" {
+		env["CXX"] = crossCompiler.CXX
+	}
+	if crossCompiler.CGOCFlags != "" {
+		env["CGO_CFLAGS"] = joinNonEmpty(env["CGO_CFLAGS"], crossCompiler.CGOCFlags)
+	}
+	for k, v := range osArchEnv {
+		env[k] = ExpandEnvironmentValue(v)
+	}
+	if p.GoExperiment != "" {
+		if env["GOEXPERIMENT"] == "" {
+			env["GOEXPERIMENT"] = p.GoExperiment
+		} else {
+			env["GOEXPERIMENT"] = env["GOEXPERIMENT"] + "," + p.GoExperiment
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return env
+}
+
+// ModuleDirPath returns the path to the directory of the Go module of the product for the provided project directory.
+// This is the working directory for the "build" command. Returns projectDir unmodified if ModuleDir is not specified.
+func (p *BuildParam) ModuleDirPath(projectDir string) string {
+	if p.ModuleDir == "" {
+		return projectDir
+	}
+	return path.Join(projectDir, p.ModuleDir)
+}
+
+// MainPkgPath returns the path to the main package of the product for the provided project directory.
+func (p *BuildParam) MainPkgPath(projectDir string) string {
+	return path.Join(p.ModuleDirPath(projectDir), p.MainPkg)
+}
+
+// MainPkgForOSArch returns the main package that is built for the provided OSArch, which is the entry for the OSArch
+// in OSArchMainPkg if one exists and MainPkg otherwise.
+func (p *BuildParam) MainPkgForOSArch(osArch osarch.OSArch) string {
+	if p.MainPkg == "" {
+		return ""
+	}
+	if mainPkg, ok := p.OSArchMainPkg[osArch]; ok && mainPkg != "" {
+		return mainPkg
+	}
+	return p.MainPkg
+}
+
+// MainPkgPathForOSArch returns the path to the main package that is built for the provided OSArch for the provided
+// project directory.
+func (p *BuildParam) MainPkgPathForOSArch(projectDir string, osArch osarch.OSArch) string {
+	return path.Join(p.ModuleDirPath(projectDir), p.MainPkgForOSArch(osArch))
+}
+
+// GoBinaryPath returns the path to the Go executable that should be used for the build. If GoBinary is empty, the "go"
+// executable on the PATH is used. If GoBinary contains a path separator, it is resolved relative to the provided
+// project directory (unless it is absolute). Returns an error if the resolved path is not an executable file.
+func (p *BuildParam) GoBinaryPath(projectDir string) (string, error) {
+	goBinary := p.GoBinary
+	if goBinary == "" {
+		goBinary = "go"
+	}
+	if !strings.Contains(goBinary, string(filepath.Separator)) {
+		goBinaryPath, err := exec.LookPath(goBinary)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to find Go executable %q on PATH", goBinary)
+		}
+		return goBinaryPath, nil
+	}
+	if !filepath.IsAbs(goBinary) {
+		goBinary = filepath.Join(projectDir, goBinary)
+	}
+	fi, err := os.Stat(goBinary)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to stat Go executable %s", goBinary)
+	}
+	if fi.IsDir() || fi.Mode()&0111 == 0 {
+		return "", errors.Errorf("Go executable %s is not an executable file (mode %v)", goBinary, fi.Mode())
+	}
+	return goBinary, nil
+}
+
+// CgoDisabledForOSArch returns true if CGo should be disabled for the build of the provided OSArch because
+// DisableCgoForCrossCompile is true, the GOOS or GOARCH of the OSArch differs from that of the host and the environment
+// for the OSArch does not set CC or CGO_ENABLED.
+func (p *BuildParam) CgoDisabledForOSArch(osArch osarch.OSArch) bool {
+	if !p.DisableCgoForCrossCompile {
+		return false
+	}
+	host := osarch.Current()
+	if goarch, _ := GOARCHAndVariant(osArch); osArch.OS == host.OS && goarch == host.Arch {
+		return false
+	}
+	env := p.EnvironmentForOSArch(osArch)
+	if _, ok := env["CGO_ENABLED"]; ok {
+		return false
+	}
+	return env["CC"] == ""
+}
+
+// ValidateEnvironmentForOSArch returns an error if the environment used to build the provided OSArch is not compatible
+// with the other build options. Currently, this verifies that CGo is not disabled (either by the environment or by
+// CgoDisabledForOSArch) if Race is true.
+func (p *BuildParam) ValidateEnvironmentForOSArch(osArch osarch.OSArch) error {
+	if !p.Race {
+		return nil
+	}
+	if p.EnvironmentForOSArch(osArch)["CGO_ENABLED"] == "0" {
+		return errors.Errorf("race detector requires CGo, but CGO_ENABLED is set to 0 for %s", osArch)
+	}
+	if p.CgoDisabledForOSArch(osArch) {
+		return errors.Errorf("race detector requires CGo, but CGo is disabled for %s because it is cross-compiled and no C compiler is configured for it: configure a C compiler for %s or disable the race detector", osArch, osArch)
+	}
+	return nil
+}
+
+// buildArgsScriptContent returns the content of the build arguments script. If BuildArgsScriptFile is non-empty, its
+// content is read from the file (resolved relative to the provided project directory) and BuildArgsScriptIncludes is
+// prepended to it. Returns an error if both BuildArgsScript and BuildArgsScriptFile are non-empty.
+func (p *BuildParam) buildArgsScriptContent(projectDir string) (string, error) {
+	if p.BuildArgsScriptFile == "" {
+		return p.BuildArgsScript, nil
+	}
+	if p.BuildArgsScript != "" {
+		return "", errors.Errorf("BuildArgsScript and BuildArgsScriptFile cannot both be specified")
+	}
+	scriptFile := p.BuildArgsScriptFile
+	if !filepath.IsAbs(scriptFile) {
+		scriptFile = filepath.Join(projectDir, scriptFile)
+	}
+	scriptBytes, err := ioutil.ReadFile(scriptFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read build arguments script file")
+	}
+	return CreateScriptContent(string(scriptBytes), p.BuildArgsScriptIncludes), nil
+}
+
 func (p *BuildParam) BuildArgs(productTaskOutputInfo ProductTaskOutputInfo) ([]string, error) {
-	buildArgs, err := BuildArgsFromScript(productTaskOutputInfo, p.BuildArgsScript)
+	return p.BuildArgsForOSArch(productTaskOutputInfo, osarch.Current())
+}
+
+// BuildArgsForOSArch is like BuildArgs, but returns the arguments for building for the provided OSArch rather than the
+// current OSArch. The OSArch is made available to BuildArgsScript (see BuildArgsScriptEnvVariables).
+func (p *BuildParam) BuildArgsForOSArch(productTaskOutputInfo ProductTaskOutputInfo, osArch osarch.OSArch) ([]string, error) {
+	buildArgsScript, err := p.buildArgsScriptContent(productTaskOutputInfo.Project.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	buildArgs, err := BuildArgsFromScriptForOSArch(productTaskOutputInfo, osArch, buildArgsScript)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to execute script to generate build arguments")
 	}
-	if versionVar := p.VersionVar; versionVar != "" {
-		buildArgs = append(buildArgs, "-ldflags", fmt.Sprintf("-X %s=%s", versionVar, productTaskOutputInfo.Project.Version))
+	buildArgs = append(buildArgs, p.GoFlags...)
+	if p.Trimpath {
+		buildArgs = append(buildArgs, "-trimpath")
+	}
+	if len(p.BuildTags) > 0 {
+		buildArgs = mergeFlagValue(buildArgs, "tags", strings.Join(p.BuildTags, " "), joinTags)
+	}
+	if len(p.GCFlags) > 0 {
+		buildArgs = mergeFlagValue(buildArgs, "gcflags", strings.Join(p.GCFlags, " "), joinNonEmpty)
+	}
+	if len(p.AsmFlags) > 0 {
+		buildArgs = mergeFlagValue(buildArgs, "asmflags", strings.Join(p.AsmFlags, " "), joinNonEmpty)
+	}
+	if p.Race {
+		buildArgs = append(buildArgs, "-race")
+	}
+	if p.BuildMode != "" {
+		if err := ValidateBuildMode(p.BuildMode); err != nil {
+			return nil, err
+		}
+		buildArgs = append(buildArgs, "-buildmode="+p.BuildMode)
+	}
+	ldFlags, err := p.ldFlags(productTaskOutputInfo)
+	if err != nil {
+		return nil, err
+	}
+	if len(ldFlags) > 0 {
+		buildArgs = mergeFlagValue(buildArgs, "ldflags", strings.Join(ldFlags, " "), joinNonEmpty)
 	}
 	return buildArgs, nil
 }
+
+// PackageLoadFlags returns the flags that determine which packages and files are built using the BuildParam: GoFlags
+// with BuildTags merged into its "-tags" flag and "-race" if Race is true. The flags can be provided to "go list" to
+// load the packages that are built.
+func (p *BuildParam) PackageLoadFlags() []string {
+	flags := append([]string(nil), p.GoFlags...)
+	if len(p.BuildTags) > 0 {
+		flags = mergeFlagValue(flags, "tags", strings.Join(p.BuildTags, " "), joinTags)
+	}
+	if p.Race {
+		flags = append(flags, "-race")
+	}
+	return flags
+}
+
+// mergeFlagValue returns the provided build arguments with the provided value added to the value of the flag with the
+// provided name. If the arguments already contain the flag (specified either as "-flag" followed by a separate value
+// argument or as "-flag=value"), the value of the last such flag is replaced with the result of calling join with its
+// current value and the provided value, since "go build" only honors the last occurrence of the flag. Otherwise, the
+// flag and the provided value are appended to the arguments. The order of all other arguments is preserved.
+func mergeFlagValue(buildArgs []string, flagName, value string, join func(first, second string) string) []string {
+	for i := len(buildArgs) - 1; i >= 0; i-- {
+		flag, currValue, hasValue := splitFlag(buildArgs[i])
+		if flag != flagName {
+			continue
+		}
+		merged := make([]string, len(buildArgs))
+		copy(merged, buildArgs)
+		switch {
+		case hasValue:
+			merged[i] = fmt.Sprintf("%s=%s", strings.SplitN(buildArgs[i], "=", 2)[0], join(currValue, value))
+		case i+1 < len(buildArgs):
+			merged[i+1] = join(buildArgs[i+1], value)
+		default:
+			// flag is the last argument and does not have a value: provide the value as its value
+			merged = append(merged, value)
+		}
+		return merged
+	}
+	return append(buildArgs, "-"+flagName, value)
+}
+
+// splitFlag returns the name of the flag specified by the provided argument (with leading hyphens removed) and its
+// value if the argument is of the form "-flag=value". Returns an empty name if the argument is not a flag.
+func splitFlag(arg string) (name, value string, hasValue bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", "", false
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+		return parts[0], parts[1], true
+	}
+	return name, "", false
+}
+
+// ExpandEnvironmentValue returns the provided environment variable value with references to environment variables of
+// the form "$VAR" or "${VAR}" replaced by the value of the variable in the environment of the Go process. References to
+// variables that are not set are replaced by the empty string. "$$" is replaced by a literal "$", so "$$VAR" expands to
+// "$VAR".
+func ExpandEnvironmentValue(value string) string {
+	return os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// joinTags returns the build tags in the provided values joined using spaces. The first value may separate its tags
+// using either commas or spaces (both forms are accepted by the "-tags" flag), but the tags are always joined using
+// spaces so that the "build" command does not interpret the combined value as a single tag.
+func joinTags(first, second string) string {
+	tags := strings.FieldsFunc(first, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	return joinNonEmpty(strings.Join(tags, " "), second)
+}
+
+func joinNonEmpty(first, second string) string {
+	if first == "" {
+		return second
+	}
+	return first + " " + second
+}
+
+// ldFlags returns the linker flags that are generated from the build parameters. The flags for StripDebug are first,
+// followed by the entries of LDFlags and then the "-X" flags for the version variables.
+func (p *BuildParam) ldFlags(productTaskOutputInfo ProductTaskOutputInfo) ([]string, error) {
+	var ldFlags []string
+	if p.StripDebug {
+		ldFlags = append(ldFlags, "-s", "-w")
+	}
+	ldFlags = append(ldFlags, p.LDFlags...)
+	if versionVarSpecs := p.versionVarSpecs(); len(versionVarSpecs) > 0 {
+		buildTime, err := productTaskOutputInfo.Project.BuildTime()
+		if err != nil {
+			return nil, err
+		}
+		templateFns := []TemplateFunction{
+			ProductTemplateFunction(productTaskOutputInfo.Product.ID),
+			VersionTemplateFunction(productTaskOutputInfo.Project.Version),
+			GitCommitTemplateFunction(productTaskOutputInfo.Project.ProjectDir),
+			BuildTimeTemplateFunction(buildTime),
+		}
+		for _, spec := range versionVarSpecs {
+			valueTemplate := spec.ValueTemplate
+			if valueTemplate == "" {
+				valueTemplate = "{{Version}}"
+			}
+			value, err := RenderTemplate(valueTemplate, productTaskOutputInfo, templateFns...)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to render value template for version variable %s", spec.Variable)
+			}
+			ldFlags = append(ldFlags, fmt.Sprintf("-X %s=%s", spec.Variable, value))
+		}
+	}
+	return ldFlags, nil
+}
@@ -0,0 +1,1556 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/palantir/pkg/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildArgs(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		want       []string
+	}{
+		{
+			name:       "no build arguments",
+			buildParam: distgo.BuildParam{},
+			want:       nil,
+		},
+		{
+			name: "trimpath",
+			buildParam: distgo.BuildParam{
+				Trimpath: true,
+			},
+			want: []string{"-trimpath"},
+		},
+		{
+			name: "trimpath with version variable",
+			buildParam: distgo.BuildParam{
+				VersionVar: "main.version",
+				Trimpath:   true,
+			},
+			want: []string{"-trimpath", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "version variable without trimpath",
+			buildParam: distgo.BuildParam{
+				VersionVar: "main.version",
+			},
+			want: []string{"-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "trimpath with build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-tags"
+echo "foo"`,
+				VersionVar: "main.version",
+				Trimpath:   true,
+			},
+			want: []string{"-tags", "foo", "-trimpath", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "empty build tags",
+			buildParam: distgo.BuildParam{
+				BuildTags: []string{},
+			},
+			want: nil,
+		},
+		{
+			name: "single build tag",
+			buildParam: distgo.BuildParam{
+				BuildTags: []string{"enterprise"},
+			},
+			want: []string{"-tags", "enterprise"},
+		},
+		{
+			name: "multiple build tags",
+			buildParam: distgo.BuildParam{
+				BuildTags: []string{"enterprise", "netgo"},
+			},
+			want: []string{"-tags", "enterprise netgo"},
+		},
+		{
+			name: "build tags containing commas",
+			buildParam: distgo.BuildParam{
+				BuildTags: []string{"enterprise,netgo", "osusergo"},
+			},
+			want: []string{"-tags", "enterprise,netgo osusergo"},
+		},
+		{
+			name: "build tags with trimpath, build arguments script and version variable",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-v"`,
+				VersionVar:      "main.version",
+				Trimpath:        true,
+				BuildTags:       []string{"oss"},
+			},
+			want: []string{"-v", "-trimpath", "-tags", "oss", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "empty gcflags and asmflags",
+			buildParam: distgo.BuildParam{
+				GCFlags:  []string{},
+				AsmFlags: []string{},
+			},
+			want: nil,
+		},
+		{
+			name: "gcflags",
+			buildParam: distgo.BuildParam{
+				GCFlags: []string{"all=-N", "-l"},
+			},
+			want: []string{"-gcflags", "all=-N -l"},
+		},
+		{
+			name: "asmflags",
+			buildParam: distgo.BuildParam{
+				AsmFlags: []string{"-trimpath"},
+			},
+			want: []string{"-asmflags", "-trimpath"},
+		},
+		{
+			name: "gcflags and asmflags with other build arguments",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-v"`,
+				VersionVar:      "main.version",
+				BuildTags:       []string{"oss"},
+				GCFlags:         []string{"all=-N -l"},
+				AsmFlags:        []string{"all=-trimpath=/tmp"},
+			},
+			want: []string{"-v", "-tags", "oss", "-gcflags", "all=-N -l", "-asmflags", "all=-trimpath=/tmp", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "race",
+			buildParam: distgo.BuildParam{
+				VersionVar: "main.version",
+				Race:       true,
+			},
+			want: []string{"-race", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "default build mode",
+			buildParam: distgo.BuildParam{
+				BuildMode: "default",
+			},
+			want: []string{"-buildmode=default"},
+		},
+		{
+			name: "pie build mode",
+			buildParam: distgo.BuildParam{
+				BuildMode: "pie",
+			},
+			want: []string{"-buildmode=pie"},
+		},
+		{
+			name: "c-shared build mode with version variable",
+			buildParam: distgo.BuildParam{
+				VersionVar: "main.version",
+				BuildMode:  "c-shared",
+			},
+			want: []string{"-buildmode=c-shared", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "multiple version variables",
+			buildParam: distgo.BuildParam{
+				VersionVars: []distgo.VersionVarSpec{
+					{
+						Variable: "main.version",
+					},
+					{
+						Variable:      "github.com/org/foo/internal/build.Product",
+						ValueTemplate: "{{Product}}",
+					},
+				},
+			},
+			want: []string{"-ldflags", "-X main.version=1.0.0 -X github.com/org/foo/internal/build.Product=foo"},
+		},
+		{
+			name: "strip debug",
+			buildParam: distgo.BuildParam{
+				StripDebug: true,
+			},
+			want: []string{"-ldflags", "-s -w"},
+		},
+		{
+			name: "strip debug is merged with version variables",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-v"`,
+				VersionVar:      "main.version",
+				VersionVars: []distgo.VersionVarSpec{
+					{
+						Variable:      "main.product",
+						ValueTemplate: "{{Product}}",
+					},
+				},
+				Trimpath:   true,
+				StripDebug: true,
+			},
+			want: []string{"-v", "-trimpath", "-ldflags", "-s -w -X main.version=1.0.0 -X main.product=foo"},
+		},
+		{
+			name: "version variable is merged into ldflags from build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-v"
+echo "-ldflags"
+echo "-extldflags=-static"
+echo "-a"`,
+				VersionVar: "main.version",
+				Trimpath:   true,
+			},
+			want: []string{"-v", "-ldflags", "-extldflags=-static -X main.version=1.0.0", "-a", "-trimpath"},
+		},
+		{
+			name: "version variable is merged into ldflags with value from build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-ldflags=-extldflags=-static"
+echo "-a"`,
+				VersionVar: "main.version",
+				StripDebug: true,
+			},
+			want: []string{"-ldflags=-extldflags=-static -s -w -X main.version=1.0.0", "-a"},
+		},
+		{
+			name: "version variable is merged into last ldflags from build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-ldflags"
+echo "-X main.ignored=true"
+echo "--ldflags"
+echo "-extldflags=-static"`,
+				VersionVar: "main.version",
+			},
+			want: []string{"-ldflags", "-X main.ignored=true", "--ldflags", "-extldflags=-static -X main.version=1.0.0"},
+		},
+		{
+			name: "build arguments script without ldflags",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-a"`,
+				VersionVar:      "main.version",
+			},
+			want: []string{"-a", "-ldflags", "-X main.version=1.0.0"},
+		},
+		{
+			name: "ldflags",
+			buildParam: distgo.BuildParam{
+				LDFlags: []string{"-linkmode", "external", "-extldflags", "'-static -lm'"},
+			},
+			want: []string{"-ldflags", "-linkmode external -extldflags '-static -lm'"},
+		},
+		{
+			name: "ldflags are provided in order before version variables",
+			buildParam: distgo.BuildParam{
+				LDFlags:    []string{"-linkmode", "external", "-extldflags=-static"},
+				VersionVar: "main.version",
+				VersionVars: []distgo.VersionVarSpec{
+					{
+						Variable:      "main.product",
+						ValueTemplate: "{{Product}}",
+					},
+				},
+			},
+			want: []string{"-ldflags", "-linkmode external -extldflags=-static -X main.version=1.0.0 -X main.product=foo"},
+		},
+		{
+			name: "ldflags are provided after strip debug",
+			buildParam: distgo.BuildParam{
+				LDFlags:    []string{"-linkmode=internal"},
+				VersionVar: "main.version",
+				StripDebug: true,
+			},
+			want: []string{"-ldflags", "-s -w -linkmode=internal -X main.version=1.0.0"},
+		},
+		{
+			name: "ldflags and version variable are merged into ldflags from build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-ldflags"
+echo "-buildid="`,
+				LDFlags:    []string{"-linkmode", "external"},
+				VersionVar: "main.version",
+			},
+			want: []string{"-ldflags", "-buildid= -linkmode external -X main.version=1.0.0"},
+		},
+		{
+			name: "version variable is combined with version variables",
+			buildParam: distgo.BuildParam{
+				VersionVar: "main.version",
+				VersionVars: []distgo.VersionVarSpec{
+					{
+						Variable:      "main.productVersion",
+						ValueTemplate: "{{Product}}-{{Version}}",
+					},
+				},
+			},
+			want: []string{"-ldflags", "-X main.version=1.0.0 -X main.productVersion=foo-1.0.0"},
+		},
+	} {
+		got, err := tc.buildParam.BuildArgs(productTaskOutputInfo)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestBuildArgsVersionVarValueTemplate(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+	gittest.InitGitDir(t, tmp)
+	gitCommit := strings.TrimSpace(gittest.RunGitCommand(t, tmp, "rev-parse", "HEAD"))
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+
+	for i, tc := range []struct {
+		valueTemplate string
+		wantValue     *regexp.Regexp
+	}{
+		{
+			valueTemplate: "",
+			wantValue:     regexp.MustCompile(`^1\.0\.0$`),
+		},
+		{
+			valueTemplate: "{{Version}}",
+			wantValue:     regexp.MustCompile(`^1\.0\.0$`),
+		},
+		{
+			valueTemplate: "{{Product}}",
+			wantValue:     regexp.MustCompile(`^foo$`),
+		},
+		{
+			valueTemplate: "{{GitCommit}}",
+			wantValue:     regexp.MustCompile(`^` + gitCommit + `$`),
+		},
+		{
+			valueTemplate: "{{BuildTime}}",
+			wantValue:     regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`),
+		},
+		{
+			valueTemplate: "{{.Product.ID}}-{{.Project.Version}}",
+			wantValue:     regexp.MustCompile(`^foo-1\.0\.0$`),
+		},
+	} {
+		buildParam := distgo.BuildParam{
+			VersionVar:              "main.version",
+			VersionVarValueTemplate: tc.valueTemplate,
+		}
+		got, err := buildParam.BuildArgs(productTaskOutputInfo)
+		require.NoError(t, err, "Case %d", i)
+		require.Len(t, got, 2, "Case %d", i)
+		assert.Equal(t, "-ldflags", got[0], "Case %d", i)
+		require.True(t, strings.HasPrefix(got[1], "-X main.version="), "Case %d: %s", i, got[1])
+		assert.Regexp(t, tc.wantValue, strings.TrimPrefix(got[1], "-X main.version="), "Case %d", i)
+	}
+}
+
+func TestBuildArgsBuildTimeIsCurrentTime(t *testing.T) {
+	defer setSourceDateEpoch(t, nil)()
+
+	buildParam := distgo.BuildParam{
+		VersionVars: []distgo.VersionVarSpec{
+			{
+				Variable:      "main.buildTime",
+				ValueTemplate: "{{BuildTime}}",
+			},
+		},
+	}
+	before := time.Now().UTC().Truncate(time.Second)
+	got, err := buildParam.BuildArgs(distgo.ProductTaskOutputInfo{})
+	require.NoError(t, err)
+	after := time.Now().UTC()
+
+	require.Len(t, got, 2)
+	var buildTimeStr string
+	_, err = fmt.Sscanf(got[1], "-X main.buildTime=%s", &buildTimeStr)
+	require.NoError(t, err)
+	buildTime, err := time.Parse(time.RFC3339, buildTimeStr)
+	require.NoError(t, err)
+	assert.False(t, buildTime.Before(before), "build time %v was before %v", buildTime, before)
+	assert.False(t, buildTime.After(after), "build time %v was after %v", buildTime, after)
+}
+
+func TestBuildArgsBuildTimeFromSourceDateEpoch(t *testing.T) {
+	epoch := "1600000000"
+	defer setSourceDateEpoch(t, &epoch)()
+
+	buildParam := distgo.BuildParam{
+		VersionVars: []distgo.VersionVarSpec{
+			{
+				Variable:      "main.buildTime",
+				ValueTemplate: "{{BuildTime}}",
+			},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		got, err := buildParam.BuildArgs(distgo.ProductTaskOutputInfo{})
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, []string{"-ldflags", "-X main.buildTime=2020-09-13T12:26:40Z"}, got, "Case %d", i)
+	}
+}
+
+func TestBuildArgsBuildTimeFromProjectSourceDateEpoch(t *testing.T) {
+	envEpoch := "1500000000"
+	defer setSourceDateEpoch(t, &envEpoch)()
+
+	buildParam := distgo.BuildParam{
+		VersionVars: []distgo.VersionVarSpec{
+			{
+				Variable:      "main.buildTime",
+				ValueTemplate: "{{BuildTime}}",
+			},
+		},
+	}
+	// SOURCE_DATE_EPOCH of the project takes precedence over the environment variable
+	got, err := buildParam.BuildArgs(distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			SourceDateEpoch: "1600000000",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-ldflags", "-X main.buildTime=2020-09-13T12:26:40Z"}, got)
+}
+
+func TestBuildArgsInvalidBuildMode(t *testing.T) {
+	buildParam := distgo.BuildParam{
+		BuildMode: "dll",
+	}
+	_, err := buildParam.BuildArgs(distgo.ProductTaskOutputInfo{})
+	assert.EqualError(t, err, `invalid build mode "dll": must be one of [archive c-archive c-shared default exe pie plugin shared]`)
+}
+
+func TestToBuildOutputInfoBuildMode(t *testing.T) {
+	for i, tc := range []struct {
+		buildMode        string
+		wantRenderedName string
+		wantWindowsName  string
+		wantDarwinName   string
+	}{
+		{
+			buildMode:        "",
+			wantRenderedName: "foo",
+			wantWindowsName:  "foo.exe",
+			wantDarwinName:   "foo",
+		},
+		{
+			buildMode:        "default",
+			wantRenderedName: "foo",
+			wantWindowsName:  "foo.exe",
+			wantDarwinName:   "foo",
+		},
+		{
+			buildMode:        "pie",
+			wantRenderedName: "foo",
+			wantWindowsName:  "foo.exe",
+			wantDarwinName:   "foo",
+		},
+		{
+			buildMode:        "c-shared",
+			wantRenderedName: "foo.so",
+			wantWindowsName:  "foo.dll",
+			wantDarwinName:   "foo.dylib",
+		},
+		{
+			buildMode:        "c-archive",
+			wantRenderedName: "foo.a",
+			wantWindowsName:  "foo.a",
+			wantDarwinName:   "foo.a",
+		},
+	} {
+		buildParam := distgo.BuildParam{
+			NameTemplate: "{{Product}}",
+			BuildMode:    tc.buildMode,
+		}
+		got, err := buildParam.ToBuildOutputInfo("foo", "1.0.0")
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.wantRenderedName, got.BuildNameTemplateRendered, "Case %d", i)
+		assert.Equal(t, tc.buildMode, got.BuildMode, "Case %d", i)
+		assert.Equal(t, tc.wantWindowsName, got.ArtifactName(osarch.OSArch{OS: "windows", Arch: "amd64"}), "Case %d", i)
+		assert.Equal(t, tc.wantDarwinName, got.ArtifactName(osarch.OSArch{OS: "darwin", Arch: "arm64"}), "Case %d", i)
+	}
+}
+
+func TestToBuildOutputInfoBuildModeOSArchs(t *testing.T) {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	windowsAMD64 := osarch.OSArch{OS: "windows", Arch: "amd64"}
+	darwinARM64 := osarch.OSArch{OS: "darwin", Arch: "arm64"}
+
+	buildParam := distgo.BuildParam{
+		NameTemplate: "{{Product}}",
+		BuildMode:    "c-shared",
+		OSArchs:      []osarch.OSArch{linuxAMD64, windowsAMD64, darwinARM64},
+	}
+	got, err := buildParam.ToBuildOutputInfo("foo", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, map[distgo.BuildOSArchID]string{
+		"linux-amd64":   "foo.so",
+		"windows-amd64": "foo.dll",
+		"darwin-arm64":  "foo.dylib",
+	}, got.OSArchBuildNamesRendered)
+	assert.Equal(t, "foo.so", got.ArtifactName(linuxAMD64))
+	assert.Equal(t, "foo.dll", got.ArtifactName(windowsAMD64))
+	assert.Equal(t, "foo.dylib", got.ArtifactName(darwinARM64))
+}
+
+func TestToBuildOutputInfoOSArchMainPkg(t *testing.T) {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	windowsAMD64 := osarch.OSArch{OS: "windows", Arch: "amd64"}
+	darwinARM64 := osarch.OSArch{OS: "darwin", Arch: "arm64"}
+
+	for i, tc := range []struct {
+		name               string
+		osArchMainPkg      map[osarch.OSArch]string
+		wantOSArchMainPkgs map[distgo.BuildOSArchID]string
+		wantMainPkgs       map[osarch.OSArch]string
+	}{
+		{
+			name: "OSArch-specific main package overrides default",
+			osArchMainPkg: map[osarch.OSArch]string{
+				windowsAMD64: "./cmd/foo-windows",
+			},
+			wantOSArchMainPkgs: map[distgo.BuildOSArchID]string{
+				"windows-amd64": "./cmd/foo-windows",
+			},
+			wantMainPkgs: map[osarch.OSArch]string{
+				linuxAMD64:   "./cmd/foo",
+				windowsAMD64: "./cmd/foo-windows",
+			},
+		},
+		{
+			name: "default is used if there are no entries for the built OSArchs",
+			osArchMainPkg: map[osarch.OSArch]string{
+				darwinARM64: "./cmd/foo-darwin",
+			},
+			wantMainPkgs: map[osarch.OSArch]string{
+				linuxAMD64:   "./cmd/foo",
+				windowsAMD64: "./cmd/foo",
+			},
+		},
+	} {
+		buildParam := distgo.BuildParam{
+			NameTemplate:  "{{Product}}",
+			MainPkg:       "./cmd/foo",
+			OSArchMainPkg: tc.osArchMainPkg,
+			OSArchs:       []osarch.OSArch{linuxAMD64, windowsAMD64},
+		}
+		got, err := buildParam.ToBuildOutputInfo("foo", "1.0.0")
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, "./cmd/foo", got.MainPkg, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantOSArchMainPkgs, got.OSArchMainPkgs, "Case %d: %s", i, tc.name)
+		for osArch, wantMainPkg := range tc.wantMainPkgs {
+			assert.Equal(t, wantMainPkg, got.MainPkgForOSArch(osArch), "Case %d: %s: %s", i, tc.name, osArch)
+			assert.Equal(t, wantMainPkg, buildParam.MainPkgForOSArch(osArch), "Case %d: %s: %s", i, tc.name, osArch)
+		}
+	}
+}
+
+func TestBuildArgsScriptEnvironment(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+	buildParam := distgo.BuildParam{
+		BuildArgsScript: `#!/usr/bin/env bash
+echo "PRODUCT=$PRODUCT"
+echo "VERSION=$VERSION"
+echo "OS=$OS"
+echo "ARCH=$ARCH"
+echo "ARCH_VARIANT=${ARCH_VARIANT-unset}"
+`,
+	}
+
+	for i, tc := range []struct {
+		osArch osarch.OSArch
+		want   []string
+	}{
+		{
+			osArch: osarch.OSArch{OS: "darwin", Arch: "arm64"},
+			want: []string{
+				"PRODUCT=foo",
+				"VERSION=1.0.0",
+				"OS=darwin",
+				"ARCH=arm64",
+				"ARCH_VARIANT=unset",
+			},
+		},
+		{
+			osArch: osarch.OSArch{OS: "linux", Arch: "arm-7"},
+			want: []string{
+				"PRODUCT=foo",
+				"VERSION=1.0.0",
+				"OS=linux",
+				"ARCH=arm",
+				"ARCH_VARIANT=7",
+			},
+		},
+	} {
+		got, err := buildParam.BuildArgsForOSArch(productTaskOutputInfo, tc.osArch)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.want, got, "Case %d", i)
+	}
+}
+
+func TestBuildArgsScriptFile(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	const script = `#!/usr/bin/env bash
+echo "-ldflags"
+echo "-X main.product=$PRODUCT"
+`
+	require.NoError(t, os.MkdirAll(path.Join(tmp, "scripts"), 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(tmp, "scripts", "build-args.sh"), []byte(script), 0644))
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		want       []string
+		wantError  string
+	}{
+		{
+			name: "inline script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: script,
+			},
+			want: []string{"-ldflags", "-X main.product=foo"},
+		},
+		{
+			name: "script file",
+			buildParam: distgo.BuildParam{
+				BuildArgsScriptFile: "scripts/build-args.sh",
+			},
+			want: []string{"-ldflags", "-X main.product=foo"},
+		},
+		{
+			name: "script file with includes",
+			buildParam: distgo.BuildParam{
+				BuildArgsScriptFile:     "scripts/build-args.sh",
+				BuildArgsScriptIncludes: `PRODUCT="included-$PRODUCT"`,
+			},
+			want: []string{"-ldflags", "-X main.product=included-foo"},
+		},
+		{
+			name: "both inline script and script file",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript:     script,
+				BuildArgsScriptFile: "scripts/build-args.sh",
+			},
+			wantError: "BuildArgsScript and BuildArgsScriptFile cannot both be specified",
+		},
+		{
+			name: "missing script file",
+			buildParam: distgo.BuildParam{
+				BuildArgsScriptFile: "scripts/missing.sh",
+			},
+			wantError: fmt.Sprintf("failed to read build arguments script file: open %s: no such file or directory", path.Join(tmp, "scripts", "missing.sh")),
+		},
+	} {
+		got, err := tc.buildParam.BuildArgs(productTaskOutputInfo)
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestBuildArgsGoFlags(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		want       []string
+	}{
+		{
+			name: "go flags are provided before generated flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:  []string{"-mod=vendor", "-a"},
+				Trimpath: true,
+			},
+			want: []string{"-mod=vendor", "-a", "-trimpath"},
+		},
+		{
+			name: "go flags are provided after build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-v"`,
+				GoFlags:         []string{"-mod=vendor"},
+			},
+			want: []string{"-v", "-mod=vendor"},
+		},
+		{
+			name: "version variable is merged into ldflags from go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:    []string{"-ldflags=-extldflags=-static", "-a"},
+				VersionVar: "main.version",
+				StripDebug: true,
+			},
+			want: []string{"-ldflags=-extldflags=-static -s -w -X main.version=1.0.0", "-a"},
+		},
+		{
+			name: "version variable is merged into ldflags with separate value from go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:    []string{"-ldflags", "-extldflags=-static"},
+				VersionVar: "main.version",
+			},
+			want: []string{"-ldflags", "-extldflags=-static -X main.version=1.0.0"},
+		},
+		{
+			name: "build tags are merged into space-separated tags from go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:   []string{"-tags", "netgo osusergo"},
+				BuildTags: []string{"enterprise"},
+			},
+			want: []string{"-tags", "netgo osusergo enterprise"},
+		},
+		{
+			name: "build tags are merged into comma-separated tags from go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:   []string{"-tags=netgo,osusergo"},
+				BuildTags: []string{"enterprise", "fips"},
+			},
+			want: []string{"-tags=netgo osusergo enterprise fips"},
+		},
+		{
+			name: "build tags are merged into tags from go flags rather than tags from build arguments script",
+			buildParam: distgo.BuildParam{
+				BuildArgsScript: `echo "-tags"
+echo "ignored"`,
+				GoFlags:   []string{"-tags=netgo"},
+				BuildTags: []string{"enterprise"},
+			},
+			want: []string{"-tags", "ignored", "-tags=netgo enterprise"},
+		},
+		{
+			name: "gcflags and asmflags are merged into flags from go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:  []string{"-gcflags=all=-N", "-asmflags=-trimpath"},
+				GCFlags:  []string{"-l"},
+				AsmFlags: []string{"-shared"},
+			},
+			want: []string{"-gcflags=all=-N -l", "-asmflags=-trimpath -shared"},
+		},
+		{
+			name: "build tags and version variable are merged into go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:    []string{"-tags=netgo", "-ldflags=-extldflags=-static"},
+				BuildTags:  []string{"enterprise"},
+				VersionVar: "main.version",
+				Trimpath:   true,
+			},
+			want: []string{"-tags=netgo enterprise", "-ldflags=-extldflags=-static -X main.version=1.0.0", "-trimpath"},
+		},
+	} {
+		got, err := tc.buildParam.BuildArgs(productTaskOutputInfo)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestPackageLoadFlags(t *testing.T) {
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		want       []string
+	}{
+		{
+			name: "no flags",
+		},
+		{
+			name: "go flags are provided",
+			buildParam: distgo.BuildParam{
+				GoFlags:  []string{"-mod=vendor"},
+				Trimpath: true,
+			},
+			want: []string{"-mod=vendor"},
+		},
+		{
+			name: "build tags are merged into tags from go flags",
+			buildParam: distgo.BuildParam{
+				GoFlags:   []string{"-tags=netgo", "-mod=vendor"},
+				BuildTags: []string{"prod"},
+			},
+			want: []string{"-tags=netgo prod", "-mod=vendor"},
+		},
+		{
+			name: "race is provided",
+			buildParam: distgo.BuildParam{
+				BuildTags: []string{"prod"},
+				Race:      true,
+			},
+			want: []string{"-tags", "prod", "-race"},
+		},
+	} {
+		assert.Equal(t, tc.want, tc.buildParam.PackageLoadFlags(), "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestEnvironmentForOSArch(t *testing.T) {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	linuxARM64 := osarch.OSArch{OS: "linux", Arch: "arm64"}
+
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		osArch     osarch.OSArch
+		want       map[string]string
+	}{
+		{
+			name:       "no environment",
+			buildParam: distgo.BuildParam{},
+			osArch:     linuxAMD64,
+			want:       nil,
+		},
+		{
+			name: "variable only in base environment",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"CGO_ENABLED": "0",
+				},
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"CC": "aarch64-linux-gnu-gcc",
+					},
+				},
+			},
+			osArch: linuxAMD64,
+			want: map[string]string{
+				"CGO_ENABLED": "0",
+			},
+		},
+		{
+			name: "variable only in OSArch environment",
+			buildParam: distgo.BuildParam{
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"CC": "aarch64-linux-gnu-gcc",
+					},
+				},
+			},
+			osArch: linuxARM64,
+			want: map[string]string{
+				"CC": "aarch64-linux-gnu-gcc",
+			},
+		},
+		{
+			name: "OSArch environment takes precedence over base environment",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"CC":          "gcc",
+					"CGO_ENABLED": "1",
+				},
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"CC": "aarch64-linux-gnu-gcc",
+					},
+				},
+			},
+			osArch: linuxARM64,
+			want: map[string]string{
+				"CC":          "aarch64-linux-gnu-gcc",
+				"CGO_ENABLED": "1",
+			},
+		},
+		{
+			name: "go experiment without environment",
+			buildParam: distgo.BuildParam{
+				GoExperiment: "loopvar",
+			},
+			osArch: linuxAMD64,
+			want: map[string]string{
+				"GOEXPERIMENT": "loopvar",
+			},
+		},
+		{
+			name: "go experiment is appended to GOEXPERIMENT from environment",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"GOEXPERIMENT": "arenas",
+				},
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"GOEXPERIMENT": "boringcrypto",
+					},
+				},
+				GoExperiment: "loopvar",
+			},
+			osArch: linuxARM64,
+			want: map[string]string{
+				"GOEXPERIMENT": "boringcrypto,loopvar",
+			},
+		},
+		{
+			name: "cross compiler is exported for matching OSArch",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"CC":         "gcc",
+					"CGO_CFLAGS": "-O2",
+				},
+				CrossCompilers: map[osarch.OSArch]distgo.CrossCompilerSpec{
+					linuxARM64: {
+						CC:        "aarch64-linux-gnu-gcc",
+						CXX:       "aarch64-linux-gnu-g++",
+						CGOCFlags: "-march=armv8-a",
+					},
+				},
+			},
+			osArch: linuxARM64,
+			want: map[string]string{
+				"CC":         "aarch64-linux-gnu-gcc",
+				"CXX":        "aarch64-linux-gnu-g++",
+				"CGO_CFLAGS": "-O2 -march=armv8-a",
+			},
+		},
+		{
+			name: "cross compiler is not exported for non-matching OSArch",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"CGO_CFLAGS": "-O2",
+				},
+				CrossCompilers: map[osarch.OSArch]distgo.CrossCompilerSpec{
+					linuxARM64: {
+						CC:        "aarch64-linux-gnu-gcc",
+						CGOCFlags: "-march=armv8-a",
+					},
+				},
+			},
+			osArch: linuxAMD64,
+			want: map[string]string{
+				"CGO_CFLAGS": "-O2",
+			},
+		},
+		{
+			name: "OSArch environment takes precedence over cross compiler",
+			buildParam: distgo.BuildParam{
+				CrossCompilers: map[osarch.OSArch]distgo.CrossCompilerSpec{
+					linuxARM64: {
+						CC: "aarch64-linux-gnu-gcc",
+					},
+				},
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"CC": "clang",
+					},
+				},
+			},
+			osArch: linuxARM64,
+			want: map[string]string{
+				"CC": "clang",
+			},
+		},
+	} {
+		got := tc.buildParam.EnvironmentForOSArch(tc.osArch)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestEnvironmentForOSArchExpandsVariables(t *testing.T) {
+	const (
+		setVar     = "DISTGO_TEST_GO_MOD_MODE"
+		missingVar = "DISTGO_TEST_MISSING_VAR"
+	)
+	origSetVal, origSetValOK := os.LookupEnv(setVar)
+	origMissingVal, origMissingValOK := os.LookupEnv(missingVar)
+	defer func() {
+		for k, v := range map[string]struct {
+			val string
+			ok  bool
+		}{
+			setVar:     {origSetVal, origSetValOK},
+			missingVar: {origMissingVal, origMissingValOK},
+		} {
+			if v.ok {
+				_ = os.Setenv(k, v.val)
+			} else {
+				_ = os.Unsetenv(k)
+			}
+		}
+	}()
+	require.NoError(t, os.Setenv(setVar, "vendor"))
+	require.NoError(t, os.Unsetenv(missingVar))
+
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		want       map[string]string
+	}{
+		{
+			name: "variables are expanded",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"GOFLAGS":   "-mod=$" + setVar,
+					"GOFLAGS_2": "-mod=${" + setVar + "}",
+				},
+			},
+			want: map[string]string{
+				"GOFLAGS":   "-mod=vendor",
+				"GOFLAGS_2": "-mod=vendor",
+			},
+		},
+		{
+			name: "missing variable expands to empty string",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"GOFLAGS": "-mod=${" + missingVar + "}",
+				},
+			},
+			want: map[string]string{
+				"GOFLAGS": "-mod=",
+			},
+		},
+		{
+			name: "escaped dollar is literal",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"LITERAL":       "$$" + setVar,
+					"LITERAL_PRICE": "costs $$5",
+				},
+			},
+			want: map[string]string{
+				"LITERAL":       "$" + setVar,
+				"LITERAL_PRICE": "costs $5",
+			},
+		},
+		{
+			name: "OSArch environment variables are expanded",
+			buildParam: distgo.BuildParam{
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxAMD64: {
+						"GOFLAGS": "-mod=$" + setVar,
+					},
+				},
+			},
+			want: map[string]string{
+				"GOFLAGS": "-mod=vendor",
+			},
+		},
+	} {
+		got := tc.buildParam.EnvironmentForOSArch(linuxAMD64)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestValidateEnvironmentForOSArch(t *testing.T) {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	linuxARM64 := osarch.OSArch{OS: "linux", Arch: "arm64"}
+	hostOSArch := osarch.Current()
+	crossOSArch := osarch.OSArch{OS: "linux", Arch: "arm64"}
+	if crossOSArch == hostOSArch {
+		crossOSArch.Arch = "amd64"
+	}
+
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		osArch     osarch.OSArch
+		wantError  string
+	}{
+		{
+			name: "race with CGo enabled",
+			buildParam: distgo.BuildParam{
+				Race: true,
+				Environment: map[string]string{
+					"CGO_ENABLED": "1",
+				},
+			},
+			osArch: linuxAMD64,
+		},
+		{
+			name: "CGo disabled without race",
+			buildParam: distgo.BuildParam{
+				Environment: map[string]string{
+					"CGO_ENABLED": "0",
+				},
+			},
+			osArch: linuxAMD64,
+		},
+		{
+			name: "race with CGo disabled",
+			buildParam: distgo.BuildParam{
+				Race: true,
+				Environment: map[string]string{
+					"CGO_ENABLED": "0",
+				},
+			},
+			osArch:    linuxAMD64,
+			wantError: "race detector requires CGo, but CGO_ENABLED is set to 0 for linux-amd64",
+		},
+		{
+			name: "race with CGo disabled for a different OSArch",
+			buildParam: distgo.BuildParam{
+				Race: true,
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"CGO_ENABLED": "0",
+					},
+				},
+			},
+			osArch: linuxAMD64,
+		},
+		{
+			name: "race with CGo disabled for OSArch",
+			buildParam: distgo.BuildParam{
+				Race: true,
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					linuxARM64: {
+						"CGO_ENABLED": "0",
+					},
+				},
+			},
+			osArch:    linuxARM64,
+			wantError: "race detector requires CGo, but CGO_ENABLED is set to 0 for linux-arm64",
+		},
+		{
+			name: "race with CGo disabled for cross build without CC",
+			buildParam: distgo.BuildParam{
+				Race:                      true,
+				DisableCgoForCrossCompile: true,
+			},
+			osArch:    crossOSArch,
+			wantError: fmt.Sprintf("race detector requires CGo, but CGo is disabled for %s because it is cross-compiled and no C compiler is configured for it: configure a C compiler for %s or disable the race detector", crossOSArch, crossOSArch),
+		},
+		{
+			name: "race with CGo disabled for cross compile for cross build with CC",
+			buildParam: distgo.BuildParam{
+				Race:                      true,
+				DisableCgoForCrossCompile: true,
+				CrossCompilers: map[osarch.OSArch]distgo.CrossCompilerSpec{
+					crossOSArch: {
+						CC: "cross-gcc",
+					},
+				},
+			},
+			osArch: crossOSArch,
+		},
+		{
+			name: "race with CGo disabled for cross compile for host build",
+			buildParam: distgo.BuildParam{
+				Race:                      true,
+				DisableCgoForCrossCompile: true,
+			},
+			osArch: hostOSArch,
+		},
+		{
+			name: "CGo disabled for cross build without race",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+			},
+			osArch: crossOSArch,
+		},
+	} {
+		err := tc.buildParam.ValidateEnvironmentForOSArch(tc.osArch)
+		if tc.wantError == "" {
+			assert.NoError(t, err, "Case %d: %s", i, tc.name)
+		} else {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestCgoDisabledForOSArch(t *testing.T) {
+	hostOSArch := osarch.Current()
+	crossOSArch := osarch.OSArch{OS: "linux", Arch: "arm64"}
+	if crossOSArch == hostOSArch {
+		crossOSArch.Arch = "amd64"
+	}
+
+	for i, tc := range []struct {
+		name       string
+		buildParam distgo.BuildParam
+		osArch     osarch.OSArch
+		want       bool
+	}{
+		{
+			name: "host build",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+			},
+			osArch: hostOSArch,
+			want:   false,
+		},
+		{
+			name: "cross build without CC",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+			},
+			osArch: crossOSArch,
+			want:   true,
+		},
+		{
+			name: "cross build with CC from cross compilers",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+				CrossCompilers: map[osarch.OSArch]distgo.CrossCompilerSpec{
+					crossOSArch: {
+						CC: "cross-gcc",
+					},
+				},
+			},
+			osArch: crossOSArch,
+			want:   false,
+		},
+		{
+			name: "cross build with CC from environment",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+				OSArchEnvironment: map[osarch.OSArch]map[string]string{
+					crossOSArch: {
+						"CC": "cross-gcc",
+					},
+				},
+			},
+			osArch: crossOSArch,
+			want:   false,
+		},
+		{
+			name: "cross build with CC for a different OSArch",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+				CrossCompilers: map[osarch.OSArch]distgo.CrossCompilerSpec{
+					hostOSArch: {
+						CC: "gcc",
+					},
+				},
+			},
+			osArch: crossOSArch,
+			want:   true,
+		},
+		{
+			name: "cross build with CGO_ENABLED set explicitly",
+			buildParam: distgo.BuildParam{
+				DisableCgoForCrossCompile: true,
+				Environment: map[string]string{
+					"CGO_ENABLED": "1",
+				},
+			},
+			osArch: crossOSArch,
+			want:   false,
+		},
+		{
+			name:       "cross build without option",
+			buildParam: distgo.BuildParam{},
+			osArch:     crossOSArch,
+			want:       false,
+		},
+	} {
+		got := tc.buildParam.CgoDisabledForOSArch(tc.osArch)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestToBuildOutputInfoOSArchNameTemplate(t *testing.T) {
+	linuxAMD64 := osarch.OSArch{OS: "linux", Arch: "amd64"}
+	windowsAMD64 := osarch.OSArch{OS: "windows", Arch: "amd64"}
+	linuxARM7 := osarch.OSArch{OS: "linux", Arch: "arm-7"}
+	jsWASM := osarch.OSArch{OS: "js", Arch: "wasm"}
+
+	for i, tc := range []struct {
+		name              string
+		nameTemplate      string
+		osArchs           []osarch.OSArch
+		wantRenderedName  string
+		wantOSArchNames   map[distgo.BuildOSArchID]string
+		wantArtifactNames map[osarch.OSArch]string
+	}{
+		{
+			name:             "template without OSArch parameters",
+			nameTemplate:     "{{Product}}-{{Version}}",
+			wantRenderedName: "foo-1.0.0",
+			wantOSArchNames:  nil,
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64:   "foo-1.0.0",
+				windowsAMD64: "foo-1.0.0.exe",
+			},
+		},
+		{
+			name:             "template with OSArch",
+			nameTemplate:     "{{Product}}-{{OSArch}}",
+			wantRenderedName: "",
+			wantOSArchNames: map[distgo.BuildOSArchID]string{
+				"linux-amd64":   "foo-linux-amd64",
+				"windows-amd64": "foo-windows-amd64",
+			},
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64:   "foo-linux-amd64",
+				windowsAMD64: "foo-windows-amd64.exe",
+			},
+		},
+		{
+			name:             "template with GOOS and GOARCH",
+			nameTemplate:     "{{Product}}_{{GOOS}}_{{GOARCH}}",
+			osArchs:          []osarch.OSArch{linuxAMD64, windowsAMD64, linuxARM7},
+			wantRenderedName: "",
+			wantOSArchNames: map[distgo.BuildOSArchID]string{
+				"linux-amd64":   "foo_linux_amd64",
+				"windows-amd64": "foo_windows_amd64",
+				"linux-arm-7":   "foo_linux_arm",
+			},
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64:   "foo_linux_amd64",
+				windowsAMD64: "foo_windows_amd64.exe",
+				linuxARM7:    "foo_linux_arm",
+			},
+		},
+		{
+			name:             "js-wasm output has wasm extension",
+			nameTemplate:     "{{Product}}",
+			osArchs:          []osarch.OSArch{linuxAMD64, jsWASM},
+			wantRenderedName: "foo",
+			wantOSArchNames:  nil,
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64: "foo",
+				jsWASM:     "foo.wasm",
+			},
+		},
+		{
+			name:             "js-wasm output with ExeExt",
+			nameTemplate:     "{{Product}}_{{GOOS}}{{ExeExt}}",
+			osArchs:          []osarch.OSArch{linuxAMD64, jsWASM},
+			wantRenderedName: "",
+			wantOSArchNames: map[distgo.BuildOSArchID]string{
+				"linux-amd64": "foo_linux",
+				"js-wasm":     "foo_js.wasm",
+			},
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64: "foo_linux",
+				jsWASM:     "foo_js.wasm",
+			},
+		},
+		{
+			name:             "template with ExeExt",
+			nameTemplate:     "{{Product}}{{ExeExt}}",
+			wantRenderedName: "",
+			wantOSArchNames: map[distgo.BuildOSArchID]string{
+				"linux-amd64":   "foo",
+				"windows-amd64": "foo.exe",
+			},
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64:   "foo",
+				windowsAMD64: "foo.exe",
+			},
+		},
+		{
+			name:             "template with OSArch for a single OSArch",
+			nameTemplate:     "{{Product}}-{{OSArch}}",
+			osArchs:          []osarch.OSArch{linuxAMD64},
+			wantRenderedName: "",
+			wantOSArchNames: map[distgo.BuildOSArchID]string{
+				"linux-amd64": "foo-linux-amd64",
+			},
+			wantArtifactNames: map[osarch.OSArch]string{
+				linuxAMD64: "foo-linux-amd64",
+			},
+		},
+	} {
+		buildParam := distgo.BuildParam{
+			NameTemplate: tc.nameTemplate,
+			OSArchs:      []osarch.OSArch{linuxAMD64, windowsAMD64},
+		}
+		if tc.osArchs != nil {
+			buildParam.OSArchs = tc.osArchs
+		}
+		got, err := buildParam.ToBuildOutputInfo("foo", "1.0.0")
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantRenderedName, got.BuildNameTemplateRendered, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantOSArchNames, got.OSArchBuildNamesRendered, "Case %d: %s", i, tc.name)
+		for osArch, wantArtifactName := range tc.wantArtifactNames {
+			assert.Equal(t, wantArtifactName, got.ArtifactName(osArch), "Case %d: %s: %s", i, tc.name, osArch)
+		}
+	}
+}
+
+func TestGoBinaryPath(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	stubGoPath := path.Join(tmp, "bin", "go")
+	require.NoError(t, os.MkdirAll(path.Dir(stubGoPath), 0755))
+	require.NoError(t, ioutil.WriteFile(stubGoPath, []byte("#!/bin/sh\n"), 0755))
+	nonExecutablePath := path.Join(tmp, "bin", "go-not-executable")
+	require.NoError(t, ioutil.WriteFile(nonExecutablePath, []byte("#!/bin/sh\n"), 0644))
+	// set modes explicitly so that they are not affected by umask
+	require.NoError(t, os.Chmod(path.Dir(stubGoPath), 0755))
+	require.NoError(t, os.Chmod(nonExecutablePath, 0644))
+
+	defaultGoPath, err := exec.LookPath("go")
+	require.NoError(t, err)
+
+	for i, tc := range []struct {
+		name      string
+		goBinary  string
+		want      string
+		wantError string
+	}{
+		{
+			name:     "default",
+			goBinary: "",
+			want:     defaultGoPath,
+		},
+		{
+			name:     "absolute path",
+			goBinary: stubGoPath,
+			want:     stubGoPath,
+		},
+		{
+			name:     "path relative to project directory",
+			goBinary: "./bin/go",
+			want:     stubGoPath,
+		},
+		{
+			name:      "non-executable file",
+			goBinary:  nonExecutablePath,
+			wantError: fmt.Sprintf("Go executable %s is not an executable file (mode -rw-r--r--)", nonExecutablePath),
+		},
+		{
+			name:      "directory",
+			goBinary:  path.Join(tmp, "bin"),
+			wantError: fmt.Sprintf("Go executable %s is not an executable file (mode drwxr-xr-x)", path.Join(tmp, "bin")),
+		},
+		{
+			name:      "missing file",
+			goBinary:  path.Join(tmp, "missing", "go"),
+			wantError: fmt.Sprintf("failed to stat Go executable %s: stat %s: no such file or directory", path.Join(tmp, "missing", "go"), path.Join(tmp, "missing", "go")),
+		},
+		{
+			name:      "missing executable on PATH",
+			goBinary:  "go-executable-that-does-not-exist",
+			wantError: `failed to find Go executable "go-executable-that-does-not-exist" on PATH: exec: "go-executable-that-does-not-exist": executable file not found in $PATH`,
+		},
+	} {
+		buildParam := distgo.BuildParam{
+			GoBinary: tc.goBinary,
+		}
+		got, err := buildParam.GoBinaryPath(tmp)
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestAbsoluteBuildOutputDir(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	for i, tc := range []struct {
+		name       string
+		projectDir string
+		outputDir  string
+		want       string
+	}{
+		{
+			name:       "relative output directory",
+			projectDir: "/project",
+			outputDir:  "out/build",
+			want:       "/project/out/build",
+		},
+		{
+			name:       "relative output directory is cleaned",
+			projectDir: "/project",
+			outputDir:  "./out/../build/",
+			want:       "/project/build",
+		},
+		{
+			name:       "relative output directory and relative project directory",
+			projectDir: "project",
+			outputDir:  "out/build",
+			want:       path.Join(wd, "project", "out", "build"),
+		},
+		{
+			name:       "absolute output directory",
+			projectDir: "/project",
+			outputDir:  "/var/build/",
+			want:       "/var/build",
+		},
+	} {
+		info := distgo.BuildOutputInfo{
+			BuildOutputDir: tc.outputDir,
+		}
+		got, err := info.AbsoluteBuildOutputDir(tc.projectDir)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.outputDir, info.BuildOutputDir, "Case %d: %s", i, tc.name)
+	}
+}
@@ -15,8 +15,10 @@
 package distgo
 
 import (
+	"os"
 	"sort"
 
+	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/palantir/pkg/matcher"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -24,6 +26,10 @@ import (
 
 type DistID string
 
+// DefaultExecutableMode is the permission bits of the executables in a distribution if DisterParam.ExecutableMode is
+// not specified.
+const DefaultExecutableMode os.FileMode = 0755
+
 type ByDistID []DistID
 
 func (a ByDistID) Len() int           { return len(a) }
@@ -39,6 +45,13 @@ type DistParam struct {
 
 	// DistParams contains the dist params for this distribution.
 	DistParams map[DistID]DisterParam
+
+	// GPGKey specifies the GPG key used to create detached armored signatures for the dist artifacts. The value is
+	// either the path to a file that contains the key (absolute or relative to the project directory) or the ID of a
+	// key in the default keyring. If non-empty, "gpg --detach-sign --armor" is run for every dist artifact and the
+	// signature is written to "{{Artifact}}.asc". The passphrase for the key is read from the DISTGO_GPG_PASSPHRASE
+	// environment variable.
+	GPGKey string
 }
 
 type DistOutputInfos struct {
@@ -79,6 +92,23 @@ type DisterParam struct {
 	// InputDir specifies the configuration for copying files from an input directory.
 	InputDir InputDirParam
 
+	// InputFiles specifies the files that are copied to the dist work directory after the contents of InputDir.
+	InputFiles []FileMapping
+
+	// ExcludePatterns specifies glob patterns for the files that are removed from the dist work directory before the
+	// dist artifacts are generated. Patterns are matched against the slash-separated path of each file and directory
+	// relative to the dist work directory using path.Match. A pattern that does not contain a '/' is matched against
+	// the base name of the path. A directory that matches a pattern is removed with all of its contents. The patterns
+	// are applied after the dist script is run, so they apply to the build artifacts, the input directory, the input
+	// files and the output of the script.
+	ExcludePatterns []string
+
+	// ExecutableMode specifies the permission bits of the executables of the product (and its dependencies) in the
+	// distribution. The executables are given this mode when they are copied to the dist work directory regardless of
+	// the mode of the build outputs, and the disters that create archives use this mode for all of the executable files
+	// in the archive. If 0, DefaultExecutableMode is used.
+	ExecutableMode os.FileMode
+
 	// Script is the content of a script that is written to a file and run after the initial distribution process but
 	// before the artifact generation process. The content of this value is written to a file and executed with the
 	// project directory as the working directory. The script process inherits the environment variables of the Go
@@ -86,19 +116,86 @@ type DisterParam struct {
 	// distgo.DistScriptEnvVariables function for the extra environment variables.
 	Script string
 
+	// Manifest specifies where the manifest of the files in each dist artifact is written.
+	Manifest ManifestLocation
+
+	// ChecksumAlgorithm specifies the algorithm used to compute the checksum file that is written next to each dist
+	// artifact when checksum files are requested. If empty, ChecksumSHA256 is used.
+	ChecksumAlgorithm ChecksumAlgorithm
+
 	// Dister is the Dister that performs the dist operation for this parameter.
 	Dister Dister
 }
 
+// FileMapping specifies a file (or the files matched by a glob pattern) that is copied to a destination.
+type FileMapping struct {
+	// Source is the path to the file relative to the project directory. May be a glob pattern.
+	Source string
+
+	// Destination is the destination path relative to the dist work directory. If the source is a glob pattern or the
+	// destination ends with "/", the destination is a directory into which the matching files are copied.
+	Destination string
+
+	// Mode is the permission bits of the copied files. If 0, the mode of the source file is used.
+	Mode os.FileMode
+}
+
+// ManifestLocation specifies where the manifest for a distribution is written.
+type ManifestLocation string
+
+const (
+	// ManifestNone specifies that no manifest is written.
+	ManifestNone ManifestLocation = ""
+	// ManifestArchive specifies that the manifest of each dist artifact is written to the directory that is packaged in
+	// the artifact (see ArtifactContents) so that it is included in the artifact.
+	ManifestArchive ManifestLocation = "archive"
+	// ManifestSidecar specifies that the manifest of each dist artifact is written to a separate file next to the
+	// artifact.
+	ManifestSidecar ManifestLocation = "sidecar"
+)
+
+// ChecksumAlgorithm specifies the algorithm used to compute the checksums of dist artifacts. The value is also used as
+// the extension of the checksum file that is written next to each artifact.
+type ChecksumAlgorithm string
+
+const (
+	// ChecksumSHA256 specifies the SHA-256 algorithm (checksum files are in the format used by "sha256sum").
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	// ChecksumSHA512 specifies the SHA-512 algorithm (checksum files are in the format used by "sha512sum").
+	ChecksumSHA512 ChecksumAlgorithm = "sha512"
+	// ChecksumBLAKE2b specifies the BLAKE2b-512 algorithm (checksum files are in the format used by "b2sum").
+	ChecksumBLAKE2b ChecksumAlgorithm = "blake2b"
+)
+
+// ChecksumAlgorithms returns all of the supported checksum algorithms.
+func ChecksumAlgorithms() []ChecksumAlgorithm {
+	return []ChecksumAlgorithm{ChecksumSHA256, ChecksumSHA512, ChecksumBLAKE2b}
+}
+
 type InputDirParam struct {
 	Path    string
 	Exclude matcher.Matcher
 }
 
 type DistOutputInfo struct {
-	DistNameTemplateRendered string   `json:"distNameTemplateRendered"`
-	DistArtifactNames        []string `json:"distArtifactNames"`
-	PackagingExtension       string   `json:"packagingExtension"`
+	DistNameTemplateRendered string      `json:"distNameTemplateRendered"`
+	DistArtifactNames        []string    `json:"distArtifactNames"`
+	PackagingExtension       string      `json:"packagingExtension"`
+	ExecutableMode           os.FileMode `json:"executableMode,omitempty"`
+
+	// DistArtifactOSArchs contains the OS/architecture of each of the artifacts in DistArtifactNames that is specific
+	// to an OS/architecture keyed by the name of the artifact. Only populated if the Dister implements
+	// OSArchArtifactsDister.
+	DistArtifactOSArchs map[string]osarch.OSArch `json:"distArtifactOSArchs,omitempty"`
+}
+
+// ExecutableFileMode returns the permission bits of the executables in the distribution, which is ExecutableMode if it
+// is non-zero and DefaultExecutableMode otherwise.
+func (i DistOutputInfo) ExecutableFileMode() os.FileMode {
+	if i.ExecutableMode == 0 {
+		return DefaultExecutableMode
+	}
+	return i.ExecutableMode
 }
 
 func (p *DisterParam) ToDistOutputInfo(productID ProductID, version string) (DistOutputInfo, error) {
@@ -114,10 +211,18 @@ func (p *DisterParam) ToDistOutputInfo(productID ProductID, version string) (Dis
 	if err != nil {
 		return DistOutputInfo{}, errors.Wrapf(err, "failed to determine artifact extensions")
 	}
+	var artifactOSArchs map[string]osarch.OSArch
+	if osArchArtifactsDister, ok := p.Dister.(OSArchArtifactsDister); ok {
+		if artifactOSArchs, err = osArchArtifactsDister.ArtifactOSArchs(renderedName); err != nil {
+			return DistOutputInfo{}, errors.Wrapf(err, "failed to determine OS/architectures of artifacts")
+		}
+	}
 	return DistOutputInfo{
 		DistNameTemplateRendered: renderedName,
 		DistArtifactNames:        artifactNames,
 		PackagingExtension:       packagingExtension,
+		ExecutableMode:           p.ExecutableMode,
+		DistArtifactOSArchs:      artifactOSArchs,
 	}, nil
 }
 
@@ -37,18 +37,87 @@ func (a ByDockerTagID) Len() int           { return len(a) }
 func (a ByDockerTagID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ByDockerTagID) Less(i, j int) bool { return a[i] < a[j] }
 
+type DockerManifestListID string
+
+type ByDockerManifestListID []DockerManifestListID
+
+func (a ByDockerManifestListID) Len() int           { return len(a) }
+func (a ByDockerManifestListID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByDockerManifestListID) Less(i, j int) bool { return a[i] < a[j] }
+
 type DockerParam struct {
 	// Repository is the Docker repository. This value is made available to TagTemplates as {{Repository}}.
 	Repository string
 
 	// DockerBuilderParams contains the Docker params for this distribution.
 	DockerBuilderParams map[DockerID]DockerBuilderParam
+
+	// ManifestListParams contains the multi-architecture manifest lists that are pushed for this product.
+	ManifestListParams map[DockerManifestListID]DockerManifestListParam
 }
 
 type DockerOutputInfos struct {
-	DockerIDs                []DockerID                           `json:"dockerIds"`
-	Repository               string                               `json:"repository"`
-	DockerBuilderOutputInfos map[DockerID]DockerBuilderOutputInfo `json:"dockerBuilderOutputInfos"`
+	DockerIDs                []DockerID                                            `json:"dockerIds"`
+	Repository               string                                                `json:"repository"`
+	DockerBuilderOutputInfos map[DockerID]DockerBuilderOutputInfo                  `json:"dockerBuilderOutputInfos"`
+	ManifestListIDs          []DockerManifestListID                                `json:"manifestListIds,omitempty"`
+	ManifestListOutputInfos  map[DockerManifestListID]DockerManifestListOutputInfo `json:"manifestListOutputInfos,omitempty"`
+}
+
+type DockerManifestListParam struct {
+	// TagTemplate is the template for the tag of the manifest list. The following template parameters can be used in
+	// the template:
+	//   * {{Product}}: the name of the product
+	//   * {{Version}}: the version of the project
+	//   * {{Repository}}: the Docker repository. If the repository is non-empty and does not end in a '/', appends '/'.
+	//   * {{RepositoryLiteral}}: the Docker repository exactly as specified (does not append a trailing '/')
+	TagTemplate string
+
+	// ImageTagTemplates maps each OS/architecture to the template for the tag of the image for that OS/architecture.
+	// The templates support the same parameters as TagTemplate.
+	ImageTagTemplates map[osarch.OSArch]string
+}
+
+type DockerManifestListOutputInfo struct {
+	RenderedTag       string              `json:"renderedTag"`
+	RenderedImageTags map[OSArchID]string `json:"renderedImageTags"`
+}
+
+// OSArchIDs returns the OS/architectures of the images referenced by the manifest list in sorted order.
+func (mloi *DockerManifestListOutputInfo) OSArchIDs() []OSArchID {
+	var osArchIDs []OSArchID
+	for k := range mloi.RenderedImageTags {
+		osArchIDs = append(osArchIDs, k)
+	}
+	sort.Sort(ByOSArchID(osArchIDs))
+	return osArchIDs
+}
+
+func (p *DockerManifestListParam) ToDockerManifestListOutputInfo(productID ProductID, version, repository string) (DockerManifestListOutputInfo, error) {
+	render := func(tmpl string) (string, error) {
+		return RenderTemplate(tmpl, nil,
+			ProductTemplateFunction(productID),
+			VersionTemplateFunction(version),
+			RepositoryTemplateFunction(repository),
+			RepositoryLiteralTemplateFunction(repository),
+		)
+	}
+	renderedTag, err := render(p.TagTemplate)
+	if err != nil {
+		return DockerManifestListOutputInfo{}, err
+	}
+	renderedImageTags := make(map[OSArchID]string, len(p.ImageTagTemplates))
+	for osArch, tmpl := range p.ImageTagTemplates {
+		renderedImageTag, err := render(tmpl)
+		if err != nil {
+			return DockerManifestListOutputInfo{}, err
+		}
+		renderedImageTags[OSArchID(osArch.String())] = renderedImageTag
+	}
+	return DockerManifestListOutputInfo{
+		RenderedTag:       renderedTag,
+		RenderedImageTags: renderedImageTags,
+	}, nil
 }
 
 type OSArchID string
@@ -121,10 +190,27 @@ func (p *DockerParam) ToDockerOutputInfos(productID ProductID, version string) (
 		}
 	}
 	sort.Sort(ByDockerID(dockerIDs))
+
+	var manifestListIDs []DockerManifestListID
+	var manifestListOutputInfos map[DockerManifestListID]DockerManifestListOutputInfo
+	if len(p.ManifestListParams) > 0 {
+		manifestListOutputInfos = make(map[DockerManifestListID]DockerManifestListOutputInfo)
+		for manifestListID, manifestListParam := range p.ManifestListParams {
+			manifestListIDs = append(manifestListIDs, manifestListID)
+			currOutputInfo, err := manifestListParam.ToDockerManifestListOutputInfo(productID, version, p.Repository)
+			if err != nil {
+				return DockerOutputInfos{}, err
+			}
+			manifestListOutputInfos[manifestListID] = currOutputInfo
+		}
+	}
+	sort.Sort(ByDockerManifestListID(manifestListIDs))
 	return DockerOutputInfos{
 		DockerIDs:                dockerIDs,
 		Repository:               p.Repository,
 		DockerBuilderOutputInfos: dockerOutputInfos,
+		ManifestListIDs:          manifestListIDs,
+		ManifestListOutputInfos:  manifestListOutputInfos,
 	}, nil
 }
 
@@ -174,6 +260,12 @@ type DockerBuilderParam struct {
 	// ProductDistID.
 	InputDistsOutputPaths map[ProductDistID][]string
 
+	// StagedDistArtifactsPath is the template for the path relative to ContextDir to which the OS/architecture-specific
+	// dist artifacts of the product are hard-linked before the image is built. The template can use the "{{Product}}",
+	// "{{Version}}", "{{DistID}}", "{{OSArch}}" and "{{Artifact}}" functions. If empty, artifacts are not staged. The
+	// OS/architecture of an artifact is determined using DistOutputInfo.DistArtifactOSArchs.
+	StagedDistArtifactsPath string
+
 	// TagTemplates contains the templates for the tags that will be used to tag the image generated by this builder.
 	// The tag should be the form that would be provided to the "docker tag" command -- for example,
 	// "fedora/httpd:version1.0" or "myregistryhost:5000/fedora/httpd:version1.0".
@@ -16,6 +16,11 @@ package distgo
 
 import (
 	"sort"
+	"strings"
+	"sync"
+
+	"github.com/palantir/distgo/pkg/git"
+	"github.com/pkg/errors"
 )
 
 type ProductParam struct {
@@ -44,8 +49,71 @@ type ProductParam struct {
 	// AllDependencies stores all of the dependent products of this product. It is a result of expanding all of the
 	// dependencies in FirstLevelDependencies.
 	AllDependencies map[ProductID]ProductParam
+
+	// VersionFromGit specifies whether the version of this product is derived from the git repository of the project
+	// (see GitVersion) rather than using the version of the project. If true, the version is used wherever the project
+	// version would otherwise be used for this product (see ProjectInfoForProduct).
+	VersionFromGit bool
+
+	// VersionTagPrefix specifies the prefix of the tags that are considered when deriving the version of this product
+	// from git. Only used if VersionFromGit is true.
+	VersionTagPrefix string
+}
+
+// ProjectInfoForProduct returns the ProjectInfo that should be used for the tasks of this product. If VersionFromGit is
+// false, the provided ProjectInfo is returned unmodified. Otherwise, the Version of the returned ProjectInfo is the
+// version derived from the git repository of the project directory (see GitVersion).
+func (p *ProductParam) ProjectInfoForProduct(projectInfo ProjectInfo) (ProjectInfo, error) {
+	if !p.VersionFromGit {
+		return projectInfo, nil
+	}
+	version, err := GitVersion(projectInfo.ProjectDir, p.VersionTagPrefix)
+	if err != nil {
+		return ProjectInfo{}, errors.Wrapf(err, "failed to determine version of product %s from git", p.ID)
+	}
+	projectInfo.Version = version
+	return projectInfo, nil
 }
 
+// GitVersion returns the version for the git repository that contains the provided directory based on "git describe"
+// using only the tags that start with the provided tag prefix. The tag prefix is removed from the version, and if the
+// remainder starts with 'v' followed by a digit, the leading 'v' is also removed. If the commit is not tagged, the
+// number of commits since the tag and the abbreviated commit hash are appended ("1.0.0-3-gabcdef0"). If the repository
+// has uncommitted changes (including untracked files), ".dirty" is appended. Returns "unspecified" if no tags with the
+// prefix describe the current commit. The version for a directory and tag prefix is cached for the lifetime of the
+// process, so "git describe" is run only once and all of the tasks in a single invocation use the same version.
+func GitVersion(projectDir, tagPrefix string) (string, error) {
+	key := gitVersionKey{projectDir: projectDir, tagPrefix: tagPrefix}
+	gitVersionsMutex.Lock()
+	defer gitVersionsMutex.Unlock()
+	if version, ok := gitVersions[key]; ok {
+		return version, nil
+	}
+
+	version, err := git.ProjectVersionWithPrefix(projectDir, tagPrefix)
+	if err != nil {
+		return "", err
+	}
+	if version != git.Unspecified && tagPrefix != "" {
+		version = strings.TrimPrefix(version, tagPrefix)
+		if len(version) >= 2 && version[0] == 'v' && version[1] >= '0' && version[1] <= '9' {
+			version = version[1:]
+		}
+	}
+	gitVersions[key] = version
+	return version, nil
+}
+
+type gitVersionKey struct {
+	projectDir string
+	tagPrefix  string
+}
+
+var (
+	gitVersionsMutex sync.Mutex
+	gitVersions      = make(map[gitVersionKey]string)
+)
+
 func (p *ProductParam) AllProductParams() []ProductParam {
 	allProductParams := []ProductParam{*p}
 	for _, currParam := range p.AllDependencies {
@@ -72,6 +140,11 @@ type ProductOutputInfo struct {
 	DistOutputInfos   *DistOutputInfos   `json:"distOutputInfos"`
 	PublishOutputInfo *PublishOutputInfo `json:"publishOutputInfo"`
 	DockerOutputInfos *DockerOutputInfos `json:"dockerOutputInfos"`
+
+	// Version is the version of the product, which differs from the version of the project if the product derives its
+	// version from git (see ProductParam.VersionFromGit). The output paths of the product use this version. If empty,
+	// the version of the project is used.
+	Version string `json:"version,omitempty"`
 }
 
 func (p *ProductParam) ToProductOutputInfo(version string) (ProductOutputInfo, error) {
@@ -106,6 +179,7 @@ func (p *ProductParam) ToProductOutputInfo(version string) (ProductOutputInfo, e
 	}
 	return ProductOutputInfo{
 		ID:                p.ID,
+		Version:           version,
 		BuildOutputInfo:   buildOutputInfo,
 		DistOutputInfos:   distOutputInfos,
 		PublishOutputInfo: publishOutputInfo,
@@ -0,0 +1,180 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"io/ioutil"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/godel/v2/pkg/osarch"
+	"github.com/palantir/pkg/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectInfoForProductVersionFromGit(t *testing.T) {
+	for i, tc := range []struct {
+		name             string
+		versionFromGit   bool
+		versionTagPrefix string
+		gitOperations    func(gitDir string)
+		want             *regexp.Regexp
+	}{
+		{
+			name:           "project version is used if version-from-git is false",
+			versionFromGit: false,
+			gitOperations: func(gitDir string) {
+				gittest.CreateGitTag(t, gitDir, "2.0.0")
+			},
+			want: regexp.MustCompile(`^1\.0\.0$`),
+		},
+		{
+			name:           "untagged repository",
+			versionFromGit: true,
+			gitOperations:  func(gitDir string) {},
+			want:           regexp.MustCompile(`^unspecified$`),
+		},
+		{
+			name:           "tagged commit",
+			versionFromGit: true,
+			gitOperations: func(gitDir string) {
+				gittest.CreateGitTag(t, gitDir, "v2.0.0")
+			},
+			want: regexp.MustCompile(`^2\.0\.0$`),
+		},
+		{
+			name:           "commit after tag",
+			versionFromGit: true,
+			gitOperations: func(gitDir string) {
+				gittest.CreateGitTag(t, gitDir, "2.0.0")
+				gittest.CommitRandomFile(t, gitDir, "Second commit")
+			},
+			want: regexp.MustCompile(`^2\.0\.0-1-g[0-9a-f]{7}$`),
+		},
+		{
+			name:           "dirty repository",
+			versionFromGit: true,
+			gitOperations: func(gitDir string) {
+				gittest.CreateGitTag(t, gitDir, "2.0.0")
+				require.NoError(t, ioutil.WriteFile(path.Join(gitDir, "untracked.txt"), []byte("untracked"), 0644))
+			},
+			want: regexp.MustCompile(`^2\.0\.0\.dirty$`),
+		},
+		{
+			name:             "tag prefix is removed",
+			versionFromGit:   true,
+			versionTagPrefix: "foo@",
+			gitOperations: func(gitDir string) {
+				gittest.CreateGitTag(t, gitDir, "foo@v3.0.0")
+				gittest.CommitRandomFile(t, gitDir, "Second commit")
+				gittest.CreateGitTag(t, gitDir, "bar@4.0.0")
+			},
+			want: regexp.MustCompile(`^3\.0\.0-1-g[0-9a-f]{7}$`),
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err)
+			gittest.InitGitDir(t, tmp)
+			tc.gitOperations(tmp)
+
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: tmp,
+				Version:    "1.0.0",
+			}
+			productParam := distgo.ProductParam{
+				ID: "foo",
+				Build: &distgo.BuildParam{
+					NameTemplate: "{{Product}}",
+					OutputDir:    "out/build",
+					MainPkg:      "./foo",
+					VersionVar:   "main.version",
+					OSArchs:      []osarch.OSArch{osarch.Current()},
+				},
+				VersionFromGit:   tc.versionFromGit,
+				VersionTagPrefix: tc.versionTagPrefix,
+			}
+
+			got, err := productParam.ProjectInfoForProduct(projectInfo)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			assert.Regexp(t, tc.want, got.Version, "Case %d: %s", i, tc.name)
+
+			// the version is used for the output paths and the version variable of the product
+			productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			assert.Equal(t, got.Version, productTaskOutputInfo.Project.Version, "Case %d: %s", i, tc.name)
+			assert.Equal(t, path.Join(tmp, "out", "build", "foo", got.Version), productTaskOutputInfo.ProductBuildOutputDir(), "Case %d: %s", i, tc.name)
+			buildArgs, err := productParam.Build.BuildArgs(productTaskOutputInfo)
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			assert.Equal(t, []string{"-ldflags", "-X main.version=" + got.Version}, buildArgs, "Case %d: %s", i, tc.name)
+		}()
+	}
+}
+
+func TestToProductTaskOutputInfoDependencyVersions(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+	gittest.InitGitDir(t, tmp)
+	gittest.CreateGitTag(t, tmp, "dep@2.0.0")
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "1.0.0",
+	}
+	newProductParam := func(id distgo.ProductID, versionFromGit bool) distgo.ProductParam {
+		return distgo.ProductParam{
+			ID: id,
+			Build: &distgo.BuildParam{
+				NameTemplate: "{{Product}}",
+				OutputDir:    "out/build",
+				MainPkg:      "./" + string(id),
+				OSArchs:      []osarch.OSArch{osarch.Current()},
+			},
+			VersionFromGit:   versionFromGit,
+			VersionTagPrefix: string(id) + "@",
+		}
+	}
+	depParam := newProductParam("dep", true)
+	otherDepParam := newProductParam("other-dep", false)
+	productParam := newProductParam("foo", true)
+	productParam.AllDependencies = map[distgo.ProductID]distgo.ProductParam{
+		"dep":       depParam,
+		"other-dep": otherDepParam,
+	}
+
+	productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
+	require.NoError(t, err)
+	assert.Equal(t, "unspecified", productTaskOutputInfo.Project.Version)
+	assert.Equal(t, path.Join(tmp, "out", "build", "foo", "unspecified"), productTaskOutputInfo.ProductBuildOutputDir())
+
+	// each dependency uses its own version rather than the version of the product
+	for i, tc := range []struct {
+		productID distgo.ProductID
+		want      string
+	}{
+		{productID: "dep", want: "2.0.0"},
+		{productID: "other-dep", want: "1.0.0"},
+	} {
+		depOutputInfo := productTaskOutputInfo.Deps[tc.productID]
+		assert.Equal(t, tc.want, depOutputInfo.Version, "Case %d", i)
+		assert.Equal(t, path.Join(tmp, "out", "build", string(tc.productID), tc.want), distgo.ProductBuildOutputDir(productTaskOutputInfo.Project, depOutputInfo), "Case %d", i)
+	}
+}
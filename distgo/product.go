@@ -15,30 +15,44 @@
 package distgo
 
 import (
+	"os"
 	"path"
+	"strings"
 
 	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
 )
 
+// ToProductTaskOutputInfo returns the ProductTaskOutputInfo for the provided product. The provided ProjectInfo must be
+// the ProjectInfo for the project (rather than the result of ProductParam.ProjectInfoForProduct): the version of the
+// product and of each of its dependencies is determined separately, and the Project of the returned value contains the
+// version of the product.
 func ToProductTaskOutputInfo(projectInfo ProjectInfo, productParam ProductParam) (ProductTaskOutputInfo, error) {
 	var deps map[ProductID]ProductOutputInfo
 	if len(productParam.AllDependencies) > 0 {
 		deps = make(map[ProductID]ProductOutputInfo)
 		for k, v := range productParam.AllDependencies {
-			productOutputInfo, err := v.ToProductOutputInfo(projectInfo.Version)
+			depProjectInfo, err := v.ProjectInfoForProduct(projectInfo)
+			if err != nil {
+				return ProductTaskOutputInfo{}, err
+			}
+			productOutputInfo, err := v.ToProductOutputInfo(depProjectInfo.Version)
 			if err != nil {
 				return ProductTaskOutputInfo{}, err
 			}
 			deps[k] = productOutputInfo
 		}
 	}
-	productOutputInfo, err := productParam.ToProductOutputInfo(projectInfo.Version)
+	productProjectInfo, err := productParam.ProjectInfoForProduct(projectInfo)
+	if err != nil {
+		return ProductTaskOutputInfo{}, err
+	}
+	productOutputInfo, err := productParam.ToProductOutputInfo(productProjectInfo.Version)
 	if err != nil {
 		return ProductTaskOutputInfo{}, err
 	}
 	return ProductTaskOutputInfo{
-		Project: projectInfo,
+		Project: productProjectInfo,
 		Product: productOutputInfo,
 		Deps:    deps,
 	}, nil
@@ -87,6 +101,16 @@ func (p *ProductTaskOutputInfo) ProductDistArtifactPaths() map[DistID][]string {
 	return ProductDistArtifactPaths(p.Project, p.Product)
 }
 
+// ProductDistExecutableMode returns the permission bits of the executables in the distribution with the provided ID
+// (see DistOutputInfo.ExecutableFileMode). Returns DefaultExecutableMode if the product does not have a distribution
+// with the ID.
+func (p *ProductTaskOutputInfo) ProductDistExecutableMode(distID DistID) os.FileMode {
+	if p.Product.DistOutputInfos == nil {
+		return DefaultExecutableMode
+	}
+	return p.Product.DistOutputInfos.DistInfos[distID].ExecutableFileMode()
+}
+
 func (p *ProductTaskOutputInfo) ProductDistWorkDirsAndArtifactPaths() map[DistID][]string {
 	return ProductDistWorkDirsAndArtifactPaths(p.Project, p.Product)
 }
@@ -99,35 +123,63 @@ func (p *ProductTaskOutputInfo) ProductDockerDistArtifactPaths() map[DockerID]ma
 	return ProductDockerDistArtifactPaths(p.Project, p.Product, p.Deps)
 }
 
+// ExecutableName returns the name of the executable with the provided name for the provided GOOS. If the GOOS has an
+// executable extension (see ExecutableExtension) and the name does not already end in that extension, the extension is
+// appended.
 func ExecutableName(productName, goos string) string {
 	executableName := productName
-	if goos == "windows" {
-		executableName += ".exe"
+	if ext := ExecutableExtension(goos); ext != "" && !strings.HasSuffix(executableName, ext) {
+		executableName += ext
 	}
 	return executableName
 }
 
+// ExecutableExtension returns the file extension of executables built for the provided GOOS. The extension is ".exe"
+// for "windows" and ".wasm" for the GOOS values that only support the "wasm" GOARCH ("js" and "wasip1"). Returns the
+// empty string for all other GOOS values.
+func ExecutableExtension(goos string) string {
+	switch goos {
+	case "windows":
+		return ".exe"
+	case "js", "wasip1":
+		return ".wasm"
+	default:
+		return ""
+	}
+}
+
 // ProductBuildOutputDir returns the output directory for the build outputs, which is
 // "{{ProjectDir}}/{{OutputDir}}/{{ProductID}}/{{Version}}".
 func ProductBuildOutputDir(projectInfo ProjectInfo, productOutputInfo ProductOutputInfo) string {
 	if productOutputInfo.BuildOutputInfo == nil {
 		return ""
 	}
-	return path.Join(projectInfo.ProjectDir, productOutputInfo.BuildOutputInfo.BuildOutputDir, string(productOutputInfo.ID), projectInfo.Version)
+	return path.Join(projectInfo.ProjectDir, productOutputInfo.BuildOutputInfo.BuildOutputDir, string(productOutputInfo.ID), productVersion(projectInfo, productOutputInfo))
+}
+
+// productVersion returns the version of the provided product, which is the version of the project if the product does
+// not specify a version.
+func productVersion(projectInfo ProjectInfo, productOutputInfo ProductOutputInfo) string {
+	if productOutputInfo.Version != "" {
+		return productOutputInfo.Version
+	}
+	return projectInfo.Version
 }
 
 // ProductBuildArtifactPaths returns a map that contains the paths to the executables created by the provided product
 // for the provided project. The keys in the map are the OS/architecture of the executable and the values are the
 // executable output paths for that OS/architecture. The output paths are of the form
-// "{{ProjectDir}}/{{OutputDir}}/{{ProductID}}/{{Version}}/{{OSArch}}/{{NameTemplateRendered}}" (and if the OS is
-// Windows, the ".exe" extension is appended).
+// "{{ProjectDir}}/{{OutputDir}}/{{ProductID}}/{{Version}}/{{OSArch}}/{{NameTemplateRendered}}", where the name template
+// is rendered for the OS/architecture (and if the build mode produces an executable, the executable extension for the OS
+// is appended if it is not already present: see ExecutableName). Returns nil if the build of the product does not create any
+// artifacts.
 func ProductBuildArtifactPaths(projectInfo ProjectInfo, productOutputInfo ProductOutputInfo) map[osarch.OSArch]string {
-	if productOutputInfo.BuildOutputInfo == nil {
+	if productOutputInfo.BuildOutputInfo == nil || productOutputInfo.BuildOutputInfo.NoArtifacts {
 		return nil
 	}
 	paths := make(map[osarch.OSArch]string)
 	for _, osArch := range productOutputInfo.BuildOutputInfo.OSArchs {
-		executableName := ExecutableName(productOutputInfo.BuildOutputInfo.BuildNameTemplateRendered, osArch.OS)
+		executableName := productOutputInfo.BuildOutputInfo.ArtifactName(osArch)
 		paths[osArch] = path.Join(ProductBuildOutputDir(projectInfo, productOutputInfo), osArch.String(), executableName)
 	}
 	return paths
@@ -139,7 +191,7 @@ func ProductDistOutputDir(projectInfo ProjectInfo, productOutputInfo ProductOutp
 	if productOutputInfo.DistOutputInfos == nil {
 		return ""
 	}
-	return path.Join(projectInfo.ProjectDir, productOutputInfo.DistOutputInfos.DistOutputDir, string(productOutputInfo.ID), projectInfo.Version, string(distID))
+	return path.Join(projectInfo.ProjectDir, productOutputInfo.DistOutputInfos.DistOutputDir, string(productOutputInfo.ID), productVersion(projectInfo, productOutputInfo), string(distID))
 }
 
 // ProductDistWorkDirs returns a map from DistID to the directory used to prepare the distribution for that DistID,
@@ -206,11 +258,11 @@ func ProductDockerBuildArtifactPaths(projectInfo ProjectInfo, productOutputInfo
 				currProductOutputInfo = deps[productID]
 			}
 			for osArchID := range valMap {
-				osArch, err := osarch.New(string(osArchID))
+				osArch, err := NewOSArch(string(osArchID))
 				if err != nil {
 					panic(errors.Wrapf(err, "OSArchID was not in a valid state"))
 				}
-				artifactPath := path.Join(pathToInputProductsDir, string(productID), "build", string(osArchID), ExecutableName(currProductOutputInfo.BuildOutputInfo.BuildNameTemplateRendered, osArch.OS))
+				artifactPath := path.Join(pathToInputProductsDir, string(productID), "build", string(osArchID), currProductOutputInfo.BuildOutputInfo.ArtifactName(osArch))
 				out[dockerID][productID][osArch] = artifactPath
 			}
 		}
@@ -264,4 +316,8 @@ func ProductDockerDistArtifactPaths(projectInfo ProjectInfo, productOutputInfo P
 type ProjectInfo struct {
 	ProjectDir string `json:"projectDir"`
 	Version    string `json:"version"`
+
+	// SourceDateEpoch is the value of SOURCE_DATE_EPOCH used for the tasks of the project. If empty, the value of the
+	// SOURCE_DATE_EPOCH environment variable of the process is used (see LookupSourceDateEpoch).
+	SourceDateEpoch string `json:"sourceDateEpoch,omitempty"`
 }
@@ -16,6 +16,7 @@ package distgo
 
 import (
 	"fmt"
+	"path"
 	"sort"
 	"strings"
 
@@ -23,7 +24,39 @@ import (
 	"github.com/pkg/errors"
 )
 
+// MatchProductIDs returns the IDs of the products in the provided inputProducts that match the provided pattern. If the
+// pattern contains any of the glob characters supported by path.Match ("*", "?" or "["), it is matched against all of
+// the ProductIDs in inputProducts and the matching IDs are returned in sorted order. For example, "server-*" matches
+// "server-api" and "server-worker". Returns an error if a glob pattern is malformed or does not match any products. If
+// the pattern does not contain any glob characters, it is returned unmodified (and is not validated).
+func MatchProductIDs(inputProducts map[ProductID]ProductParam, pattern ProductID) ([]ProductID, error) {
+	if !strings.ContainsAny(string(pattern), "*?[") {
+		return []ProductID{pattern}, nil
+	}
+	var matches []ProductID
+	for productID := range inputProducts {
+		match, err := path.Match(string(pattern), string(productID))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid product pattern %s", pattern)
+		}
+		if match {
+			matches = append(matches, productID)
+		}
+	}
+	if len(matches) == 0 {
+		var validIDsSorted []ProductID
+		for productID := range inputProducts {
+			validIDsSorted = append(validIDsSorted, productID)
+		}
+		sort.Sort(ByProductID(validIDsSorted))
+		return nil, errors.Errorf("product pattern %s does not match any products -- valid values are %v", pattern, validIDsSorted)
+	}
+	sort.Sort(ByProductID(matches))
+	return matches, nil
+}
+
 // ProductParamsForProductArgs returns the ProductParams from the provided inputProducts for the specified ProductIDs.
+// The ProductIDs may be glob patterns (see MatchProductIDs).
 //
 // Returns an error if any of the ProductID values cannot be resolved to a configuration in the provided inputProducts.
 func ProductParamsForProductArgs(inputProducts map[ProductID]ProductParam, productIDs ...ProductID) ([]ProductParam, error) {
@@ -36,6 +69,16 @@ func ProductParamsForProductArgs(inputProducts map[ProductID]ProductParam, produ
 		return toSortedProductParams(inputProducts), nil
 	}
 
+	var expandedProductIDs []ProductID
+	for _, currProductID := range productIDs {
+		matches, err := MatchProductIDs(inputProducts, currProductID)
+		if err != nil {
+			return nil, err
+		}
+		expandedProductIDs = append(expandedProductIDs, matches...)
+	}
+	productIDs = expandedProductIDs
+
 	productIDSet := make(map[ProductID]struct{})
 	for _, currProductID := range productIDs {
 		productIDSet[currProductID] = struct{}{}
@@ -73,6 +116,8 @@ func ProductParamsForProductArgs(inputProducts map[ProductID]ProductParam, produ
 //   * {{ProductID}} (e.g. "foo"), which specifies that all OS/Archs for the product should be built
 //   * {{ProductID}}.{{OSArch}} (e.g. "foo.darwin-amd64"), which specifies that the specified OS/Arch for the specified
 //     product should be built
+//
+// The {{ProductID}} portion may be a glob pattern (see MatchProductIDs).
 type ProductBuildID string
 
 func NewProductBuildID(productID ProductID, osArch osarch.OSArch) ProductBuildID {
@@ -87,7 +132,7 @@ func (id ProductBuildID) Parse() (ProductID, osarch.OSArch, error) {
 	var osArch osarch.OSArch
 	if dotIdx := strings.Index(string(id), "."); dotIdx != -1 {
 		currProductID = ProductID(id[:dotIdx])
-		osArchVal, err := osarch.New(string(id[dotIdx+1:]))
+		osArchVal, err := NewOSArch(string(id[dotIdx+1:]))
 		if err != nil {
 			return "", osarch.OSArch{}, errors.Wrapf(err, "failed to parse os-arch for %s", id)
 		}
@@ -96,6 +141,30 @@ func (id ProductBuildID) Parse() (ProductID, osarch.OSArch, error) {
 	return currProductID, osArch, nil
 }
 
+// ExpandProductBuildIDs returns the provided ProductBuildIDs with any ProductID glob patterns replaced by a
+// ProductBuildID for each matching product (see MatchProductIDs).
+func ExpandProductBuildIDs(inputProducts map[ProductID]ProductParam, productBuildIDs ...ProductBuildID) ([]ProductBuildID, error) {
+	var out []ProductBuildID
+	for _, currProductBuildID := range productBuildIDs {
+		currProductID, osArch, err := currProductBuildID.Parse()
+		if err != nil {
+			return nil, err
+		}
+		matches, err := MatchProductIDs(inputProducts, currProductID)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 1 && matches[0] == currProductID {
+			out = append(out, currProductBuildID)
+			continue
+		}
+		for _, currMatch := range matches {
+			out = append(out, NewProductBuildID(currMatch, osArch))
+		}
+	}
+	return out, nil
+}
+
 func ToProductBuildIDs(in []string) []ProductBuildID {
 	var ids []ProductBuildID
 	for _, id := range in {
@@ -109,16 +178,22 @@ func ToProductBuildIDs(in []string) []ProductBuildID {
 // the osArchs parameter is non-empty, then the returned results will only include ProductParam values that match the
 // provided osArchs. For example, if the project defines a product "foo" with OS-Archs "darwin-amd64" and "linux-amd64"
 // and the productBuildID is "foo.darwin-amd64", the returned ProductParam will only contain "darwin-amd64" in the build
-// configuration. Returns an error if any of the productBuildID values cannot be resolved to a configuration in the
-// provided inputProducts.
+// configuration. Products that do not build for any of the provided osArchs are omitted from the result. Returns an
+// error if any of the productBuildID values cannot be resolved to a configuration in the provided inputProducts or if
+// any of the provided osArchs is not built by any of the specified products.
 func ProductParamsForBuildProductArgs(inputProducts map[ProductID]ProductParam, osArchs []osarch.OSArch, productBuildIDs ...ProductBuildID) ([]ProductParam, error) {
+	productBuildIDs, err := ExpandProductBuildIDs(inputProducts, productBuildIDs...)
+	if err != nil {
+		return nil, err
+	}
+
 	// error if project does not contain any productBuildIDs
 	if len(inputProducts) == 0 {
 		return nil, errors.Errorf("project does not contain any products")
 	}
 	// if no productBuildIDs were specified, return project's productBuildIDs unmodified
 	if len(productBuildIDs) == 0 {
-		return filterProductParamsToOSArch(toSortedProductParams(inputProducts), osArchs), nil
+		return filterProductParamsToOSArch(toSortedProductParams(inputProducts), osArchs)
 	}
 
 	productIDToOSArchs := make(map[ProductID][]osarch.OSArch)
@@ -194,17 +269,18 @@ func ProductParamsForBuildProductArgs(inputProducts map[ProductID]ProductParam,
 
 		filteredProducts[productID] = currProductParam
 	}
-	return filterProductParamsToOSArch(toSortedProductParams(filteredProducts), osArchs), nil
+	return filterProductParamsToOSArch(toSortedProductParams(filteredProducts), osArchs)
 }
 
 // If osArchs is non-empty, returns a new ProductParam slice that contains only ProductParam values in the input where
 // at least one of the OSArchs in Build.OSArchs of the ProductParam is in the provided osArchs param. The Build.OSArchs
 // of the ProductParam values in the returned slice will also only contain the OSArchs that match the filter input. If
-// osArchs is empty, then the input is returned unmodified.
-func filterProductParamsToOSArch(in []ProductParam, osArchs []osarch.OSArch) []ProductParam {
+// osArchs is empty, then the input is returned unmodified. Returns an error if any of the provided osArchs is not in
+// the Build.OSArchs of any of the ProductParam values in the input.
+func filterProductParamsToOSArch(in []ProductParam, osArchs []osarch.OSArch) ([]ProductParam, error) {
 	// if filter set is empty, no need to filter
 	if len(osArchs) == 0 {
-		return in
+		return in, nil
 	}
 
 	osArchsMap := make(map[osarch.OSArch]struct{})
@@ -212,16 +288,37 @@ func filterProductParamsToOSArch(in []ProductParam, osArchs []osarch.OSArch) []P
 		osArchsMap[osArch] = struct{}{}
 	}
 
+	declaredOSArchs := make(map[string]struct{})
 	var out []ProductParam
 	for _, currParam := range in {
+		if currParam.Build == nil {
+			continue
+		}
+		for _, currOSArch := range currParam.Build.OSArchs {
+			declaredOSArchs[currOSArch.String()] = struct{}{}
+		}
 		filtered := filterOSArch(currParam.Build.OSArchs, osArchsMap)
 		if len(filtered) == 0 {
 			continue
 		}
-		currParam.Build.OSArchs = filtered
+		// modify copy so that original value remains the same
+		buildCopy := *currParam.Build
+		buildCopy.OSArchs = filtered
+		currParam.Build = &buildCopy
 		out = append(out, currParam)
 	}
-	return out
+
+	var undeclaredOSArchs []string
+	for osArch := range osArchsMap {
+		if _, ok := declaredOSArchs[osArch.String()]; !ok {
+			undeclaredOSArchs = append(undeclaredOSArchs, osArch.String())
+		}
+	}
+	sort.Strings(undeclaredOSArchs)
+	if len(undeclaredOSArchs) > 0 {
+		return nil, errors.Errorf("os-arch(s) %v not built by any of the specified products -- valid values are %v", undeclaredOSArchs, stringSetToSortedSlice(declaredOSArchs))
+	}
+	return out, nil
 }
 
 // if filter is non-empty, returns a new osArch slice that contains only the values in the input that are also keys in
@@ -244,6 +341,8 @@ func filterOSArch(in []osarch.OSArch, filter map[osarch.OSArch]struct{}) []osarc
 //   * {{ProductID}} (e.g. "foo"), which specifies that all dists for the product should be built
 //   * {{ProductID}}.{{DistID}} (e.g. "foo.os-arch-bin"), which specifies that the specified DistID for the specified
 //     product should be built
+//
+// The {{ProductID}} portion may be a glob pattern (see MatchProductIDs).
 type ProductDistID string
 
 func (id ProductDistID) Parse() (ProductID, DistID) {
@@ -263,6 +362,27 @@ func NewProductDistID(productID ProductID, distID DistID) ProductDistID {
 	return ProductDistID(fmt.Sprintf("%s.%s", productID, distID))
 }
 
+// ExpandProductDistIDs returns the provided ProductDistIDs with any ProductID glob patterns replaced by a ProductDistID
+// for each matching product (see MatchProductIDs).
+func ExpandProductDistIDs(inputProducts map[ProductID]ProductParam, productDistIDs ...ProductDistID) ([]ProductDistID, error) {
+	var out []ProductDistID
+	for _, currProductDistID := range productDistIDs {
+		currProductID, distID := currProductDistID.Parse()
+		matches, err := MatchProductIDs(inputProducts, currProductID)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 1 && matches[0] == currProductID {
+			out = append(out, currProductDistID)
+			continue
+		}
+		for _, currMatch := range matches {
+			out = append(out, NewProductDistID(currMatch, distID))
+		}
+	}
+	return out, nil
+}
+
 func ToProductDistIDs(in []string) []ProductDistID {
 	var ids []ProductDistID
 	for _, id := range in {
@@ -277,6 +397,11 @@ func ToProductDistIDs(in []string) []ProductDistID {
 // "foo.os-arch-bin", the returned ProductParam will only contain "os-arch-bin" in the dist configuration. Returns an
 // error if any of the productDistID values cannot be resolved to a configuration in the provided inputProducts.
 func ProductParamsForDistProductArgs(inputProducts map[ProductID]ProductParam, productDistIDs ...ProductDistID) ([]ProductParam, error) {
+	productDistIDs, err := ExpandProductDistIDs(inputProducts, productDistIDs...)
+	if err != nil {
+		return nil, err
+	}
+
 	// error if project does not contain any productDistIDs
 	if len(inputProducts) == 0 {
 		return nil, errors.Errorf("project does not contain any products")
@@ -372,6 +497,8 @@ func ProductParamsForDistProductArgs(inputProducts map[ProductID]ProductParam, p
 //     for the specified product
 //   * {{ProductID}}.{{DockerID}}.{{DockerTagID}} (e.g. "foo.prod-docker.release"), which specifies a specific tag for
 //     the specified DockerID for the specified product
+//
+// The {{ProductID}} portion may be a glob pattern (see MatchProductIDs).
 type ProductDockerID string
 
 func (id ProductDockerID) Parse() (ProductID, DockerID, DockerTagID) {
@@ -402,6 +529,27 @@ func NewProductDockerID(productID ProductID, dockerID DockerID, dockerTagID Dock
 	return ProductDockerID(idStr)
 }
 
+// ExpandProductDockerIDs returns the provided ProductDockerIDs with any ProductID glob patterns replaced by a
+// ProductDockerID for each matching product (see MatchProductIDs).
+func ExpandProductDockerIDs(inputProducts map[ProductID]ProductParam, productDockerIDs ...ProductDockerID) ([]ProductDockerID, error) {
+	var out []ProductDockerID
+	for _, currProductDockerID := range productDockerIDs {
+		currProductID, dockerID, dockerTagID := currProductDockerID.Parse()
+		matches, err := MatchProductIDs(inputProducts, currProductID)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 1 && matches[0] == currProductID {
+			out = append(out, currProductDockerID)
+			continue
+		}
+		for _, currMatch := range matches {
+			out = append(out, NewProductDockerID(currMatch, dockerID, dockerTagID))
+		}
+	}
+	return out, nil
+}
+
 func ToProductDockerIDs(in []string) []ProductDockerID {
 	var ids []ProductDockerID
 	for _, id := range in {
@@ -417,6 +565,11 @@ func ToProductDockerIDs(in []string) []ProductDockerID {
 // ProductParam will only contain "docker-prod" with the tag "release" in the Docker configuration. Returns an error if
 // any of the productDockerID values cannot be resolved to a configuration in the provided project.
 func ProductParamsForDockerProductArgs(inputProducts map[ProductID]ProductParam, productDockerIDs ...ProductDockerID) ([]ProductParam, error) {
+	productDockerIDs, err := ExpandProductDockerIDs(inputProducts, productDockerIDs...)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(inputProducts) == 0 {
 		return nil, errors.Errorf("project does not contain any products")
 	}
@@ -593,9 +746,22 @@ func ProductParamsForDockerTagKeys(inputProducts []ProductParam, tagKeys []strin
 			newDockerBuilders[dockerID] = dockerBuilderParam
 		}
 
+		// manifest lists are selected using their ID as the tag key
+		var newManifestLists map[DockerManifestListID]DockerManifestListParam
+		for manifestListID, manifestListParam := range currProductParam.Docker.ManifestListParams {
+			if _, ok := tagKeysMap[string(manifestListID)]; !ok {
+				continue
+			}
+			if newManifestLists == nil {
+				newManifestLists = make(map[DockerManifestListID]DockerManifestListParam)
+			}
+			newManifestLists[manifestListID] = manifestListParam
+		}
+
 		// modify copy so that original value remains the same
 		dockerCopy := *currProductParam.Docker
 		dockerCopy.DockerBuilderParams = newDockerBuilders
+		dockerCopy.ManifestListParams = newManifestLists
 		currProductParam.Docker = &dockerCopy
 		filteredProducts = append(filteredProducts, currProductParam)
 	}
@@ -699,11 +865,117 @@ func topologicalOrdering(graph map[ProductID]map[ProductID]struct{}) ([]ProductI
 		}
 	}
 	if len(order) != len(graph) {
-		return nil, errors.Errorf("provided graph contains a cycle")
+		return nil, errors.Errorf("provided graph contains a cycle: %s", strings.Join(findCycle(graph, indeg), " -> "))
 	}
 	return order, nil
 }
 
+// findCycle returns the IDs of the products that form a cycle in the provided graph. The provided in-degree map must be
+// the state of the in-degree computation of topologicalOrdering after all of the nodes that are not part of or
+// downstream of a cycle have been removed (that is, every node with a positive in-degree has at least one predecessor
+// that also has a positive in-degree). The returned path starts and ends with the same product and each product in the
+// path depends on the product that follows it.
+func findCycle(graph map[ProductID]map[ProductID]struct{}, indeg map[ProductID]int) []string {
+	// compute the dependencies (predecessors) of all of the remaining nodes
+	deps := make(map[ProductID][]ProductID)
+	var remaining []ProductID
+	for node, neighbors := range graph {
+		if indeg[node] == 0 {
+			continue
+		}
+		remaining = append(remaining, node)
+		for neighbor := range neighbors {
+			if indeg[neighbor] > 0 {
+				deps[neighbor] = append(deps[neighbor], node)
+			}
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	sort.Sort(ByProductID(remaining))
+
+	// walk dependencies starting from the lexicographically first remaining node until a node is visited twice
+	pathIndex := make(map[ProductID]int)
+	var path []ProductID
+	for cur := remaining[0]; ; {
+		if idx, ok := pathIndex[cur]; ok {
+			var cycle []string
+			for _, node := range append(path[idx:], cur) {
+				cycle = append(cycle, string(node))
+			}
+			return cycle
+		}
+		pathIndex[cur] = len(path)
+		path = append(path, cur)
+		sort.Sort(ByProductID(deps[cur]))
+		cur = deps[cur][0]
+	}
+}
+
+// ProductParamsByDependencyLevel groups the provided product params into levels based on the dependencies between
+// them. Every product is in a later level than all of the products that it depends on (directly or transitively), so
+// processing the levels in order processes all of the dependencies of a product before the product itself. Only
+// dependencies on products in the provided slice are considered. The products in each level are sorted by ID. Returns
+// an error that contains the IDs of the products in the cycle if the dependencies contain a cycle.
+func ProductParamsByDependencyLevel(productParams []ProductParam) ([][]ProductParam, error) {
+	paramsMap := make(map[ProductID]ProductParam)
+	for _, currProductParam := range productParams {
+		paramsMap[currProductParam.ID] = currProductParam
+	}
+	graph := make(map[ProductID]map[ProductID]struct{})
+	deps := make(map[ProductID]map[ProductID]struct{})
+	for _, currProductParam := range productParams {
+		if _, ok := graph[currProductParam.ID]; !ok {
+			graph[currProductParam.ID] = make(map[ProductID]struct{})
+		}
+		currDeps := make(map[ProductID]struct{})
+		for _, currDep := range currProductParam.FirstLevelDependencies {
+			currDeps[currDep] = struct{}{}
+		}
+		for currDep := range currProductParam.AllDependencies {
+			currDeps[currDep] = struct{}{}
+		}
+		for currDep := range currDeps {
+			if _, ok := paramsMap[currDep]; !ok {
+				continue
+			}
+			if _, ok := graph[currDep]; !ok {
+				graph[currDep] = make(map[ProductID]struct{})
+			}
+			graph[currDep][currProductParam.ID] = struct{}{}
+		}
+		deps[currProductParam.ID] = currDeps
+	}
+	order, err := topologicalOrdering(graph)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to order products based on their dependencies")
+	}
+
+	// the level of a product is one greater than the highest level of its dependencies
+	levels := make(map[ProductID]int)
+	var out [][]ProductParam
+	for _, currProductID := range order {
+		level := 0
+		for currDep := range deps[currProductID] {
+			if depLevel, ok := levels[currDep]; ok && depLevel+1 > level {
+				level = depLevel + 1
+			}
+		}
+		levels[currProductID] = level
+		for len(out) <= level {
+			out = append(out, nil)
+		}
+		out[level] = append(out[level], paramsMap[currProductID])
+	}
+	for _, currLevel := range out {
+		sort.Slice(currLevel, func(i, j int) bool {
+			return currLevel[i].ID < currLevel[j].ID
+		})
+	}
+	return out, nil
+}
+
 type byOSArch []osarch.OSArch
 
 func (a byOSArch) Len() int           { return len(a) }
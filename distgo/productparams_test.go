@@ -69,6 +69,66 @@ func TestProductParamsForProductArgs(t *testing.T) {
 			},
 			wantError: "product(s) [baz] not valid -- valid values are [bar foo]",
 		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"server-api":    {ID: "server-api"},
+					"server-worker": {ID: "server-worker"},
+					"server-cron":   {ID: "server-cron"},
+					"client":        {ID: "client"},
+				},
+			},
+			productIDs: []distgo.ProductID{
+				"server-*",
+			},
+			want: []distgo.ProductParam{
+				{ID: "server-api"},
+				{ID: "server-cron"},
+				{ID: "server-worker"},
+			},
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"server-api":    {ID: "server-api"},
+					"server-worker": {ID: "server-worker"},
+					"client":        {ID: "client"},
+				},
+			},
+			productIDs: []distgo.ProductID{
+				"server-*",
+				"client",
+				"server-api",
+			},
+			want: []distgo.ProductParam{
+				{ID: "client"},
+				{ID: "server-api"},
+				{ID: "server-worker"},
+			},
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"server-api": {ID: "server-api"},
+					"client":     {ID: "client"},
+				},
+			},
+			productIDs: []distgo.ProductID{
+				"worker-*",
+			},
+			wantError: "product pattern worker-* does not match any products -- valid values are [client server-api]",
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"server-api": {ID: "server-api"},
+				},
+			},
+			productIDs: []distgo.ProductID{
+				"server-[",
+			},
+			wantError: "invalid product pattern server-[: syntax error in pattern",
+		},
 	} {
 		products, err := distgo.ProductParamsForProductArgs(tc.projectParam.Products, tc.productIDs...)
 		if tc.wantError == "" {
@@ -81,7 +141,7 @@ func TestProductParamsForProductArgs(t *testing.T) {
 
 func TestProductParamsForBuildProductArgs(t *testing.T) {
 	mustOSArch := func(in string) osarch.OSArch {
-		out, err := osarch.New(in)
+		out, err := distgo.NewOSArch(in)
 		if err != nil {
 			panic(err)
 		}
@@ -277,6 +337,215 @@ func TestProductParamsForBuildProductArgs(t *testing.T) {
 			},
 			wantError: "build product(s) [bar.linux-amd64] not valid -- valid values are [bar bar.darwin-amd64 foo foo.darwin-amd64 foo.linux-amd64]",
 		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"server-api": {
+						ID: "server-api",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("darwin-amd64"),
+								mustOSArch("linux-amd64"),
+							},
+						},
+					},
+					"server-worker": {
+						ID: "server-worker",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("linux-amd64"),
+							},
+						},
+					},
+					"client": {
+						ID: "client",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("linux-amd64"),
+							},
+						},
+					},
+				},
+			},
+			productBuildIDs: []distgo.ProductBuildID{
+				"server-*.linux-amd64",
+			},
+			want: []distgo.ProductParam{
+				{
+					ID: "server-api",
+					Build: &distgo.BuildParam{
+						OSArchs: []osarch.OSArch{
+							mustOSArch("linux-amd64"),
+						},
+					},
+				},
+				{
+					ID: "server-worker",
+					Build: &distgo.BuildParam{
+						OSArchs: []osarch.OSArch{
+							mustOSArch("linux-amd64"),
+						},
+					},
+				},
+			},
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"foo": {
+						ID: "foo",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("darwin-amd64"),
+							},
+						},
+					},
+				},
+			},
+			productBuildIDs: []distgo.ProductBuildID{
+				"server-*",
+			},
+			wantError: "product pattern server-* does not match any products -- valid values are [foo]",
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"foo": {
+						ID: "foo",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("linux-arm-6"),
+								mustOSArch("linux-arm-7"),
+							},
+						},
+					},
+				},
+			},
+			productBuildIDs: []distgo.ProductBuildID{
+				"foo.linux-arm-7",
+			},
+			want: []distgo.ProductParam{
+				{
+					ID: "foo",
+					Build: &distgo.BuildParam{
+						OSArchs: []osarch.OSArch{
+							mustOSArch("linux-arm-7"),
+						},
+					},
+				},
+			},
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"foo": {
+						ID: "foo",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("darwin-arm64"),
+								mustOSArch("linux-amd64"),
+								mustOSArch("windows-amd64"),
+							},
+						},
+					},
+					"bar": {
+						ID: "bar",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("linux-amd64"),
+							},
+						},
+					},
+					"baz": {
+						ID: "baz",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("darwin-arm64"),
+								mustOSArch("windows-amd64"),
+							},
+						},
+					},
+					"no-build": {
+						ID: "no-build",
+					},
+				},
+			},
+			osArchs: []osarch.OSArch{
+				mustOSArch("darwin-arm64"),
+				mustOSArch("windows-amd64"),
+			},
+			want: []distgo.ProductParam{
+				{
+					ID: "baz",
+					Build: &distgo.BuildParam{
+						OSArchs: []osarch.OSArch{
+							mustOSArch("darwin-arm64"),
+							mustOSArch("windows-amd64"),
+						},
+					},
+				},
+				{
+					ID: "foo",
+					Build: &distgo.BuildParam{
+						OSArchs: []osarch.OSArch{
+							mustOSArch("darwin-arm64"),
+							mustOSArch("windows-amd64"),
+						},
+					},
+				},
+			},
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"foo": {
+						ID: "foo",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("darwin-amd64"),
+								mustOSArch("linux-amd64"),
+							},
+						},
+					},
+				},
+			},
+			osArchs: []osarch.OSArch{
+				mustOSArch("darwin-arm64"),
+				mustOSArch("linux-amd64"),
+				mustOSArch("linux-arm64"),
+			},
+			wantError: "os-arch(s) [darwin-arm64 linux-arm64] not built by any of the specified products -- valid values are [darwin-amd64 linux-amd64]",
+		},
+		{
+			projectParam: distgo.ProjectParam{
+				Products: map[distgo.ProductID]distgo.ProductParam{
+					"foo": {
+						ID: "foo",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("darwin-amd64"),
+								mustOSArch("linux-amd64"),
+							},
+						},
+					},
+					"bar": {
+						ID: "bar",
+						Build: &distgo.BuildParam{
+							OSArchs: []osarch.OSArch{
+								mustOSArch("windows-amd64"),
+							},
+						},
+					},
+				},
+			},
+			osArchs: []osarch.OSArch{
+				mustOSArch("windows-amd64"),
+			},
+			productBuildIDs: []distgo.ProductBuildID{
+				"foo",
+			},
+			wantError: "os-arch(s) [windows-amd64] not built by any of the specified products -- valid values are [darwin-amd64 linux-amd64]",
+		},
 	} {
 		products, err := distgo.ProductParamsForBuildProductArgs(tc.projectParam.Products, tc.osArchs, tc.productBuildIDs...)
 		if tc.wantError == "" {
@@ -473,3 +742,96 @@ func TestProductParamsForDistProductArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestProductParamsByDependencyLevel(t *testing.T) {
+	for i, tc := range []struct {
+		name          string
+		productParams []distgo.ProductParam
+		want          [][]distgo.ProductID
+		wantError     string
+	}{
+		{
+			name: "products without dependencies are in a single level",
+			productParams: []distgo.ProductParam{
+				{ID: "foo"},
+				{ID: "bar"},
+			},
+			want: [][]distgo.ProductID{
+				{"bar", "foo"},
+			},
+		},
+		{
+			name: "linear chain",
+			productParams: []distgo.ProductParam{
+				{ID: "a", FirstLevelDependencies: []distgo.ProductID{"b"}},
+				{ID: "b", FirstLevelDependencies: []distgo.ProductID{"c"}},
+				{ID: "c"},
+			},
+			want: [][]distgo.ProductID{
+				{"c"},
+				{"b"},
+				{"a"},
+			},
+		},
+		{
+			name: "diamond",
+			productParams: []distgo.ProductParam{
+				{ID: "top", FirstLevelDependencies: []distgo.ProductID{"left", "right"}},
+				{ID: "left", FirstLevelDependencies: []distgo.ProductID{"bottom"}},
+				{ID: "right", FirstLevelDependencies: []distgo.ProductID{"bottom"}},
+				{ID: "bottom"},
+			},
+			want: [][]distgo.ProductID{
+				{"bottom"},
+				{"left", "right"},
+				{"top"},
+			},
+		},
+		{
+			name: "transitive dependency through product that is not provided",
+			productParams: []distgo.ProductParam{
+				{
+					ID:                     "a",
+					FirstLevelDependencies: []distgo.ProductID{"b"},
+					AllDependencies: map[distgo.ProductID]distgo.ProductParam{
+						"b": {ID: "b"},
+						"c": {ID: "c"},
+					},
+				},
+				{ID: "c"},
+			},
+			want: [][]distgo.ProductID{
+				{"c"},
+				{"a"},
+			},
+		},
+		{
+			name: "cycle",
+			productParams: []distgo.ProductParam{
+				{ID: "a", FirstLevelDependencies: []distgo.ProductID{"b"}},
+				{ID: "b", FirstLevelDependencies: []distgo.ProductID{"c"}},
+				{ID: "c", FirstLevelDependencies: []distgo.ProductID{"a"}},
+				{ID: "d", FirstLevelDependencies: []distgo.ProductID{"a"}},
+				{ID: "e"},
+			},
+			wantError: "failed to order products based on their dependencies: provided graph contains a cycle: a -> b -> c -> a",
+		},
+	} {
+		got, err := distgo.ProductParamsByDependencyLevel(tc.productParams)
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			continue
+		}
+		assert.NoError(t, err, "Case %d: %s", i, tc.name)
+
+		var gotIDs [][]distgo.ProductID
+		for _, currLevel := range got {
+			var currLevelIDs []distgo.ProductID
+			for _, currProductParam := range currLevel {
+				currLevelIDs = append(currLevelIDs, currProductParam.ID)
+			}
+			gotIDs = append(gotIDs, currLevelIDs)
+		}
+		assert.Equal(t, tc.want, gotIDs, "Case %d: %s", i, tc.name)
+	}
+}
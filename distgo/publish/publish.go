@@ -52,13 +52,14 @@ func Run(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, publi
 		return nil
 	}
 
-	// verify that distribution artifacts to publish exists
-	productOutputInfo, err := productParam.ToProductOutputInfo(projectInfo.Version)
+	productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
 	if err != nil {
 		return errors.Wrapf(err, "failed to compute output info")
 	}
-	for _, currDistID := range productOutputInfo.DistOutputInfos.DistIDs {
-		for _, currArtifactPath := range distgo.ProductDistArtifactPaths(projectInfo, productOutputInfo)[currDistID] {
+
+	// verify that distribution artifacts to publish exists
+	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
+		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
 			if _, err := os.Stat(currArtifactPath); os.IsNotExist(err) {
 				return errors.Errorf("distribution artifact for product %s with dist %s does not exist at %s", productParam.ID, currDistID, currArtifactPath)
 			}
@@ -66,10 +67,6 @@ func Run(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, publi
 	}
 
 	// run publish
-	productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
-	if err != nil {
-		return err
-	}
 	publisherType, err := publisher.TypeName()
 	if err != nil {
 		return errors.Wrapf(err, "failed to determine type of publisher")
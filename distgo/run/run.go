@@ -23,54 +23,121 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
+	"sort"
 	"strings"
+	"syscall"
 
 	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/distgo/build"
+	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
 )
 
+// ExitError is the error returned by Product if the executable for the product exits with a non-zero exit code.
+type ExitError struct {
+	ProductID distgo.ProductID
+	ExitCode  int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.ProductID, e.ExitCode)
+}
+
+// Product builds the executable for the provided product for the current OS/architecture (if it is not up-to-date)
+// and runs it. The executable is run with the arguments specified in the run configuration followed by the provided
+// runArgs. The executable inherits the standard input of the current process, writes its output to the provided
+// writers and is run with the environment of the current process plus the variables specified in the "environment"
+// build configuration of the product. SIGINT and SIGTERM signals received while the executable is running are forwarded
+// to it. If the executable exits with a non-zero exit code, an *ExitError with the exit code is returned.
 func Product(projectInfo distgo.ProjectInfo, productParam distgo.ProductParam, runArgs []string, stdout, stderr io.Writer) error {
 	if productParam.Build == nil {
 		return errors.Errorf("product %s has no build configuration defined", productParam.ID)
 	}
+	if productParam.Build.MainPkg == "" {
+		return errors.Errorf("product %s does not specify a main package", productParam.ID)
+	}
 
-	mainPkgDir := path.Join(projectInfo.ProjectDir, productParam.Build.MainPkg)
-	mainPkgGoFiles, err := mainPkgGoFiles(mainPkgDir)
-	if err != nil {
+	// the product is run on the current system, so only build for the current OS/architecture
+	currOSArch := osarch.Current()
+	buildCopy := *productParam.Build
+	buildCopy.OSArchs = []osarch.OSArch{currOSArch}
+	productParam.Build = &buildCopy
+
+	if _, err := mainPkgGoFiles(productParam.Build.MainPkgPathForOSArch(projectInfo.ProjectDir, currOSArch)); err != nil {
 		return errors.Wrapf(err, "failed to find Go files for main package")
 	}
+	if err := build.Run(projectInfo, []distgo.ProductParam{productParam}, build.Options{}, stdout); err != nil {
+		return err
+	}
 
-	cmd := exec.Command("go")
-	args := []string{cmd.Path, "run"}
-
-	// add build arguments for product
 	productTaskOutputInfo, err := distgo.ToProductTaskOutputInfo(projectInfo, productParam)
 	if err != nil {
 		return errors.Wrapf(err, "failed to compute output info")
 	}
-	buildArgs, err := productParam.Build.BuildArgs(productTaskOutputInfo)
-	if err != nil {
-		return err
-	}
-	args = append(args, buildArgs...)
+	executablePath := productTaskOutputInfo.ProductBuildArtifactPaths()[currOSArch]
 
-	for _, goFiles := range mainPkgGoFiles {
-		args = append(args, path.Join(mainPkgDir, goFiles))
-	}
+	var args []string
 	if productParam.Run != nil {
 		args = append(args, productParam.Run.Args...)
 	}
 	args = append(args, runArgs...)
-	cmd.Args = args
 
+	cmd := exec.Command(executablePath, args...)
+	cmd.Dir = projectInfo.ProjectDir
+	cmd.Env = os.Environ()
+	// add the environment variables configured for the current OS/architecture in sorted order so that the environment
+	// is deterministic
+	env := productParam.Build.EnvironmentForOSArch(currOSArch)
+	var envKeys []string
+	for k := range env {
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	for _, k := range envKeys {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+	cmd.Stdin = os.Stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
-	cmd.Stdin = os.Stdin
 
-	_, _ = fmt.Fprintln(stdout, strings.Join(args, " "))
-	if err := cmd.Run(); err != nil {
-		return errors.Wrapf(err, "go run failed")
+	_, _ = fmt.Fprintln(stdout, strings.Join(cmd.Args, " "))
+	if err := cmd.Start(); err != nil {
+		return errors.Wrapf(err, "failed to run %s", executablePath)
+	}
+
+	// forward signals to the executable while it is running so that interrupting distgo stops the executable cleanly
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	err = cmd.Wait()
+	signal.Stop(signals)
+	close(done)
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode := exitErr.ExitCode()
+			if exitCode < 0 {
+				// process was terminated by a signal
+				exitCode = 1
+			}
+			return &ExitError{
+				ProductID: productParam.ID,
+				ExitCode:  exitCode,
+			}
+		}
+		return errors.Wrapf(err, "failed to run %s", executablePath)
 	}
 	return nil
 }
@@ -15,6 +15,7 @@
 package run_test
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -29,6 +30,7 @@ import (
 	distgoconfig "github.com/palantir/distgo/distgo/config"
 	"github.com/palantir/distgo/distgo/run"
 	"github.com/palantir/distgo/dockerbuilder/dockerbuilderfactory"
+	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -318,6 +320,8 @@ func main() {
 	} {
 		projectDir, err := ioutil.TempDir(tmp, "")
 		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		err = ioutil.WriteFile(path.Join(projectDir, "go.mod"), []byte("module foo\n\ngo 1.13\n"), 0644)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
 
 		if tc.preRunAction != nil {
 			tc.preRunAction(projectDir)
@@ -343,6 +347,84 @@ func main() {
 	}
 }
 
+func TestRunExitCode(t *testing.T) {
+	tmp, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path.Join(tmp, "go.mod"), []byte("module foo\n\ngo 1.13\n"), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmp, "main.go"), []byte(`package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func main() {
+	fmt.Printf("args: %v, env: %s, os-arch env: %s\n", os.Args[1:], os.Getenv("RUN_TEST_VAR"), os.Getenv("RUN_TEST_OS_ARCH_VAR"))
+	exitCode, _ := strconv.Atoi(os.Args[1])
+	os.Exit(exitCode)
+}
+`), 0644)
+	require.NoError(t, err)
+
+	disterFactory, err := disterfactory.New(nil, nil)
+	require.NoError(t, err)
+	dockerBuilderFactory, err := dockerbuilderfactory.New(nil, nil)
+	require.NoError(t, err)
+	productConfig := distgoconfig.ProductConfig{
+		Build: distgoconfig.ToBuildConfig(&distgoconfig.BuildConfig{
+			MainPkg: stringPtr("."),
+			Environment: &map[string]string{
+				"RUN_TEST_VAR": "test-value",
+			},
+			OSArchEnvironment: &map[string]map[string]string{
+				osarch.Current().String(): {
+					"RUN_TEST_OS_ARCH_VAR": "os-arch-value",
+				},
+			},
+		}),
+	}
+	productParam, err := productConfig.ToParam("foo", "", distgoconfig.ProductConfig{}, disterFactory, dockerBuilderFactory)
+	require.NoError(t, err)
+
+	projectInfo := distgo.ProjectInfo{
+		ProjectDir: tmp,
+		Version:    "0.1.0",
+	}
+
+	for i, tc := range []struct {
+		name         string
+		runArgs      []string
+		wantExitCode int
+	}{
+		{
+			"zero exit code returns no error",
+			[]string{"0", "--flag", "value"},
+			0,
+		},
+		{
+			"non-zero exit code is returned in error",
+			[]string{"3", "--flag", "value"},
+			3,
+		},
+	} {
+		stdout := &bytes.Buffer{}
+		err := run.Product(projectInfo, productParam, tc.runArgs, stdout, ioutil.Discard)
+		assert.Contains(t, stdout.String(), fmt.Sprintf("args: %v, env: test-value, os-arch env: os-arch-value\n", tc.runArgs), "Case %d: %s", i, tc.name)
+		if tc.wantExitCode == 0 {
+			assert.NoError(t, err, "Case %d: %s", i, tc.name)
+			continue
+		}
+		exitErr, ok := err.(*run.ExitError)
+		require.True(t, ok, "Case %d: %s: expected *run.ExitError, was %v", i, tc.name, err)
+		assert.Equal(t, tc.wantExitCode, exitErr.ExitCode, "Case %d: %s", i, tc.name)
+		assert.EqualError(t, err, fmt.Sprintf("foo exited with code %d", tc.wantExitCode), "Case %d: %s", i, tc.name)
+	}
+}
+
 func stringPtr(in string) *string {
 	return &in
 }
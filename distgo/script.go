@@ -16,18 +16,48 @@ package distgo
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
+	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
 )
 
+// ScriptDirEnvVar is the environment variable that specifies the directory to which scripts are written before they are
+// executed. See ScriptDir.
+const ScriptDirEnvVar = "DISTGO_SCRIPT_DIR"
+
+// ScriptDir returns the directory to which WriteScript writes scripts for the provided project. If the
+// DISTGO_SCRIPT_DIR environment variable is set to a non-empty value, its value is used (a relative path is resolved
+// relative to the project directory). This allows scripts to be written to a directory that permits execution on
+// systems where the project directory is on a file system that is mounted as "noexec". Otherwise, the project
+// directory is returned.
+func ScriptDir(projectInfo ProjectInfo) string {
+	scriptDir := os.Getenv(ScriptDirEnvVar)
+	if scriptDir == "" {
+		return projectInfo.ProjectDir
+	}
+	if !filepath.IsAbs(scriptDir) {
+		scriptDir = filepath.Join(projectInfo.ProjectDir, scriptDir)
+	}
+	return scriptDir
+}
+
+// WriteScript writes the provided script to a new executable file in the directory returned by ScriptDir (which is
+// created if it does not exist) and returns the path to the file and a function that removes it. The file is removed
+// if an error is returned.
 func WriteScript(projectInfo ProjectInfo, script string) (name string, cleanup func() error, rErr error) {
-	tmpFile, err := ioutil.TempFile(projectInfo.ProjectDir, "")
+	scriptDir := ScriptDir(projectInfo)
+	if err := os.MkdirAll(scriptDir, 0755); err != nil {
+		return "", nil, errors.Wrapf(err, "Failed to create script directory %s", scriptDir)
+	}
+	tmpFile, err := ioutil.TempFile(scriptDir, "")
 	if err != nil {
 		return "", nil, errors.Wrapf(err, "Failed to create script file")
 	}
@@ -60,7 +90,16 @@ func WriteScript(projectInfo ProjectInfo, script string) (name string, cleanup f
 	return tmpFile.Name(), cleanup, nil
 }
 
-func WriteAndExecuteScript(projectInfo ProjectInfo, script string, additionalEnvVars map[string]string, stdOut io.Writer) (rErr error) {
+// WriteAndExecuteScript writes the provided script using WriteScript and executes it with the project directory as its
+// working directory. The script file is removed after the script is executed, even if the execution fails. Does nothing
+// if the script is empty.
+func WriteAndExecuteScript(projectInfo ProjectInfo, script string, additionalEnvVars map[string]string, stdOut io.Writer) error {
+	return WriteAndExecuteScriptContext(context.Background(), projectInfo, script, additionalEnvVars, stdOut)
+}
+
+// WriteAndExecuteScriptContext is like WriteAndExecuteScript, but the script process is killed if the provided context
+// is done before the script exits.
+func WriteAndExecuteScriptContext(ctx context.Context, projectInfo ProjectInfo, script string, additionalEnvVars map[string]string, stdOut io.Writer) (rErr error) {
 	// if script exists, write it as a temporary file and execute it
 	if script != "" {
 		tmpFile, cleanup, err := WriteScript(projectInfo, script)
@@ -81,7 +120,7 @@ func WriteAndExecuteScript(projectInfo ProjectInfo, script string, additionalEnv
 			env = append(env, fmt.Sprintf("%v=%v", k, v))
 		}
 
-		cmd := exec.Command(tmpFile)
+		cmd := exec.CommandContext(ctx, tmpFile)
 		cmd.Dir = projectInfo.ProjectDir
 		cmd.Env = env
 		cmd.Stdout = stdOut
@@ -93,9 +132,26 @@ func WriteAndExecuteScript(projectInfo ProjectInfo, script string, additionalEnv
 	return nil
 }
 
+// BuildScriptSkipExitCode is the exit code that the build script of a product can use to signal that the product should
+// be skipped rather than built.
+const BuildScriptSkipExitCode = 2
+
+// IsScriptExitCode returns true if the provided error was returned by WriteAndExecuteScript because the script exited
+// with the provided exit code.
+func IsScriptExitCode(err error, exitCode int) bool {
+	exitErr, ok := errors.Cause(err).(*exec.ExitError)
+	return ok && exitErr.ExitCode() == exitCode
+}
+
 func BuildArgsFromScript(productTaskOutputInfo ProductTaskOutputInfo, buildArgsScript string) ([]string, error) {
+	return BuildArgsFromScriptForOSArch(productTaskOutputInfo, osarch.Current(), buildArgsScript)
+}
+
+// BuildArgsFromScriptForOSArch is like BuildArgsFromScript, but the environment of the script is that of a build for
+// the provided OSArch rather than the current OSArch (see BuildArgsScriptEnvVariables).
+func BuildArgsFromScriptForOSArch(productTaskOutputInfo ProductTaskOutputInfo, osArch osarch.OSArch, buildArgsScript string) ([]string, error) {
 	outputBuf := &bytes.Buffer{}
-	if err := WriteAndExecuteScript(productTaskOutputInfo.Project, buildArgsScript, BuildScriptEnvVariables(productTaskOutputInfo), outputBuf); err != nil {
+	if err := WriteAndExecuteScript(productTaskOutputInfo.Project, buildArgsScript, BuildArgsScriptEnvVariables(productTaskOutputInfo, osArch), outputBuf); err != nil {
 		return nil, errors.Wrapf(err, "failed to execute build args script for %s: %s", productTaskOutputInfo.Product.ID, outputBuf.String())
 	}
 
@@ -0,0 +1,122 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndExecuteScriptDir(t *testing.T) {
+	for i, tc := range []struct {
+		name          string
+		scriptDir     func(projectDir, tmpDir string) string
+		wantScriptDir func(projectDir, tmpDir string) string
+		exitCode      int
+	}{
+		{
+			name: "script is written to project directory by default",
+			scriptDir: func(projectDir, tmpDir string) string {
+				return ""
+			},
+			wantScriptDir: func(projectDir, tmpDir string) string {
+				return projectDir
+			},
+		},
+		{
+			name: "script is written to absolute script directory",
+			scriptDir: func(projectDir, tmpDir string) string {
+				return path.Join(tmpDir, "scripts")
+			},
+			wantScriptDir: func(projectDir, tmpDir string) string {
+				return path.Join(tmpDir, "scripts")
+			},
+		},
+		{
+			name: "relative script directory is resolved relative to project directory",
+			scriptDir: func(projectDir, tmpDir string) string {
+				return "out/scripts"
+			},
+			wantScriptDir: func(projectDir, tmpDir string) string {
+				return path.Join(projectDir, "out", "scripts")
+			},
+		},
+		{
+			name: "script is removed if it fails",
+			scriptDir: func(projectDir, tmpDir string) string {
+				return path.Join(tmpDir, "scripts")
+			},
+			wantScriptDir: func(projectDir, tmpDir string) string {
+				return path.Join(tmpDir, "scripts")
+			},
+			exitCode: 1,
+		},
+	} {
+		func() {
+			tmpDir, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			projectDir := path.Join(tmpDir, "project")
+			require.NoError(t, os.Mkdir(projectDir, 0755), "Case %d: %s", i, tc.name)
+
+			origScriptDir, hasOrigScriptDir := os.LookupEnv(distgo.ScriptDirEnvVar)
+			defer func() {
+				if hasOrigScriptDir {
+					_ = os.Setenv(distgo.ScriptDirEnvVar, origScriptDir)
+				} else {
+					_ = os.Unsetenv(distgo.ScriptDirEnvVar)
+				}
+			}()
+			require.NoError(t, os.Setenv(distgo.ScriptDirEnvVar, tc.scriptDir(projectDir, tmpDir)), "Case %d: %s", i, tc.name)
+
+			script := `echo "$0"`
+			if tc.exitCode != 0 {
+				script += "\nexit 1"
+			}
+			projectInfo := distgo.ProjectInfo{
+				ProjectDir: projectDir,
+			}
+			assert.Equal(t, tc.wantScriptDir(projectDir, tmpDir), distgo.ScriptDir(projectInfo), "Case %d: %s", i, tc.name)
+
+			buf := &bytes.Buffer{}
+			err = distgo.WriteAndExecuteScript(projectInfo, script, nil, buf)
+			if tc.exitCode != 0 {
+				assert.True(t, distgo.IsScriptExitCode(err, tc.exitCode), "Case %d: %s: unexpected error %v", i, tc.name, err)
+			} else {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+			}
+
+			scriptPath := strings.TrimSpace(buf.String())
+			assert.Equal(t, tc.wantScriptDir(projectDir, tmpDir), path.Dir(scriptPath), "Case %d: %s", i, tc.name)
+			_, err = os.Stat(scriptPath)
+			assert.True(t, os.IsNotExist(err), "Case %d: %s: script should be removed after execution", i, tc.name)
+
+			fileInfos, err := ioutil.ReadDir(tc.wantScriptDir(projectDir, tmpDir))
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			for _, fi := range fileInfos {
+				assert.True(t, fi.IsDir(), "Case %d: %s: unexpected file %s in script directory", i, tc.name, fi.Name())
+			}
+		}()
+	}
+}
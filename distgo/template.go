@@ -16,9 +16,14 @@ package distgo
 
 import (
 	"bytes"
+	"fmt"
+	"os/exec"
 	"strings"
 	"text/template"
+	"text/template/parse"
+	"time"
 
+	"github.com/palantir/godel/v2/pkg/osarch"
 	"github.com/pkg/errors"
 )
 
@@ -52,6 +57,29 @@ func RepositoryLiteralTemplateFunction(repository string) TemplateFunction {
 	return TemplateValueFunction("RepositoryLiteral", repository)
 }
 
+// GitCommitTemplateFunction returns a TemplateFunction that defines a "GitCommit" function that returns the full SHA of
+// the commit checked out in the git repository at the provided directory. The git command is only run if the function
+// is invoked by the template.
+func GitCommitTemplateFunction(projectDir string) TemplateFunction {
+	return func(fnMap template.FuncMap) {
+		fnMap["GitCommit"] = func() (string, error) {
+			cmd := exec.Command("git", "rev-parse", "HEAD")
+			cmd.Dir = projectDir
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to determine git commit: %s", strings.TrimSpace(string(output)))
+			}
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+}
+
+// BuildTimeTemplateFunction returns a TemplateFunction that defines a "BuildTime" function that returns the provided
+// time in UTC formatted using RFC 3339.
+func BuildTimeTemplateFunction(buildTime time.Time) TemplateFunction {
+	return TemplateValueFunction("BuildTime", buildTime.UTC().Format(time.RFC3339))
+}
+
 func TemplateValueFunction(key string, val interface{}) TemplateFunction {
 	return func(fnMap template.FuncMap) {
 		fnMap[key] = func() interface{} {
@@ -60,9 +88,35 @@ func TemplateValueFunction(key string, val interface{}) TemplateFunction {
 	}
 }
 
+// StringTemplateFunctions returns the string manipulation functions that are available in all templates rendered using
+// RenderTemplate. The arguments of the functions are ordered so that the string being manipulated is the last argument
+// so that the functions can be used in pipelines:
+//   - {{lower STR}}: returns STR with all letters converted to lower case
+//   - {{upper STR}}: returns STR with all letters converted to upper case
+//   - {{trimPrefix PREFIX STR}}: returns STR without the leading PREFIX (STR is returned unchanged if it does not start
+//     with PREFIX)
+//   - {{replace OLD NEW STR}}: returns STR with all occurrences of OLD replaced by NEW
+//
+// For example, "{{Version | trimPrefix \"v\" | lower}}" renders the version "V1.0.0" as "1.0.0".
+func StringTemplateFunctions() template.FuncMap {
+	return template.FuncMap{
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"trimPrefix": func(prefix, s string) string {
+			return strings.TrimPrefix(s, prefix)
+		},
+		"replace": func(old, new, s string) string {
+			return strings.Replace(s, old, new, -1)
+		},
+	}
+}
+
+// RenderTemplate renders the provided template using the provided data. The template can use the functions returned by
+// StringTemplateFunctions and the functions defined by the provided TemplateFunctions. A TemplateFunction that defines
+// a function with the same name as a string function overrides the string function.
 func RenderTemplate(tmplContent string, data interface{}, fns ...TemplateFunction) (string, error) {
 	tmpl := template.New("distgoTemplate")
-	tmplFuncs := make(map[string]interface{})
+	tmplFuncs := StringTemplateFunctions()
 	for _, fn := range fns {
 		fn(tmplFuncs)
 	}
@@ -79,6 +133,114 @@ func RenderTemplate(tmplContent string, data interface{}, fns ...TemplateFunctio
 	return output.String(), nil
 }
 
+// buildNameTemplateVariables are the variables that can be referenced by a build name template.
+var buildNameTemplateVariables = []string{"ExeExt", "GOARCH", "GOOS", "OSArch", "Product", "Version"}
+
+// ValidateBuildNameTemplate returns an error if the provided build name template cannot be parsed or if it references a
+// variable that is not supported by build name templates (see renderBuildNameTemplate).
+func ValidateBuildNameTemplate(nameTemplate string) error {
+	return validateTemplateVariables(nameTemplate, buildNameTemplateVariables)
+}
+
+// validateTemplateVariables returns an error if the provided template cannot be parsed or if it references any
+// variables other than the provided ones. Variables are provided to templates as functions, so references to fields of
+// the template data (such as {{.Product}}) are also considered invalid. The functions returned by
+// StringTemplateFunctions are always considered valid.
+func validateTemplateVariables(tmplContent string, variables []string) error {
+	fnMap := StringTemplateFunctions()
+	for _, variable := range variables {
+		TemplateValueFunction(variable, "")(fnMap)
+	}
+	tmpl, err := template.New("distgoTemplate").Funcs(fnMap).Parse(tmplContent)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse template %s (supported variables are %v)", tmplContent, variables)
+	}
+	if tmpl.Tree == nil {
+		return nil
+	}
+	if invalidRef := findFieldReference(tmpl.Tree.Root); invalidRef != "" {
+		return errors.Errorf("template %s references unknown variable %s (supported variables are %v)", tmplContent, invalidRef, variables)
+	}
+	return nil
+}
+
+// findFieldReference returns the first reference to the template data (a field such as {{.Product}} or {{.}}) in the
+// provided parse tree node. Returns the empty string if the node does not reference the template data.
+func findFieldReference(node parse.Node) string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return ""
+		}
+		for _, child := range n.Nodes {
+			if ref := findFieldReference(child); ref != "" {
+				return ref
+			}
+		}
+	case *parse.ActionNode:
+		return findFieldReference(n.Pipe)
+	case *parse.TemplateNode:
+		return findFieldReference(n.Pipe)
+	case *parse.IfNode:
+		return findBranchFieldReference(&n.BranchNode)
+	case *parse.RangeNode:
+		return findBranchFieldReference(&n.BranchNode)
+	case *parse.WithNode:
+		return findBranchFieldReference(&n.BranchNode)
+	case *parse.PipeNode:
+		if n == nil {
+			return ""
+		}
+		for _, cmd := range n.Cmds {
+			if ref := findFieldReference(cmd); ref != "" {
+				return ref
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if ref := findFieldReference(arg); ref != "" {
+				return ref
+			}
+		}
+	case *parse.ChainNode:
+		return findFieldReference(n.Node)
+	case *parse.FieldNode:
+		return fmt.Sprintf("{{.%s}}", strings.Join(n.Ident, "."))
+	case *parse.DotNode:
+		return "{{.}}"
+	}
+	return ""
+}
+
+func findBranchFieldReference(n *parse.BranchNode) string {
+	for _, child := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if ref := findFieldReference(child); ref != "" {
+			return ref
+		}
+	}
+	return ""
+}
+
+// renderBuildNameTemplate renders the provided build name template. In addition to the parameters supported by
+// renderNameTemplate, the template can use {{OSArch}}, {{GOOS}}, {{GOARCH}} and {{ExeExt}}, which are rendered based on
+// the provided OSArch. {{GOARCH}} does not include the architecture variant of the OSArch. If the provided OSArch is
+// empty, all of these parameters are rendered as the empty string.
+func renderBuildNameTemplate(nameTemplate string, productID ProductID, version string, osArch osarch.OSArch) (string, error) {
+	osArchStr := ""
+	if osArch != (osarch.OSArch{}) {
+		osArchStr = osArch.String()
+	}
+	goarch, _ := GOARCHAndVariant(osArch)
+	return RenderTemplate(nameTemplate, nil,
+		ProductTemplateFunction(productID),
+		VersionTemplateFunction(version),
+		TemplateValueFunction("OSArch", osArchStr),
+		TemplateValueFunction("GOOS", osArch.OS),
+		TemplateValueFunction("GOARCH", goarch),
+		TemplateValueFunction("ExeExt", ExecutableExtension(osArch.OS)),
+	)
+}
+
 func renderNameTemplate(nameTemplate string, productID ProductID, version string) (string, error) {
 	return RenderTemplate(nameTemplate, nil,
 		ProductTemplateFunction(productID),
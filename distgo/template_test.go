@@ -0,0 +1,89 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplateStringFunctions(t *testing.T) {
+	for i, tc := range []struct {
+		name    string
+		tmpl    string
+		fns     []distgo.TemplateFunction
+		want    string
+		wantErr string
+	}{
+		{
+			name: "lower",
+			tmpl: `{{lower "FooBar"}}`,
+			want: "foobar",
+		},
+		{
+			name: "upper",
+			tmpl: `{{upper "FooBar"}}`,
+			want: "FOOBAR",
+		},
+		{
+			name: "trimPrefix removes prefix",
+			tmpl: `{{trimPrefix "v" "v1.0.0"}}`,
+			want: "1.0.0",
+		},
+		{
+			name: "trimPrefix without matching prefix",
+			tmpl: `{{trimPrefix "v" "1.0.0"}}`,
+			want: "1.0.0",
+		},
+		{
+			name: "replace replaces all occurrences",
+			tmpl: `{{replace "." "_" "1.0.0"}}`,
+			want: "1_0_0",
+		},
+		{
+			name: "functions used in pipeline with template functions",
+			tmpl: `{{Product | upper}}-{{Version | trimPrefix "v" | replace "-" "." | lower}}`,
+			fns: []distgo.TemplateFunction{
+				distgo.ProductTemplateFunction("foo"),
+				distgo.VersionTemplateFunction("v1.0.0-RC1"),
+			},
+			want: "FOO-1.0.0.rc1",
+		},
+		{
+			name: "template function overrides string function",
+			tmpl: `{{lower}}`,
+			fns: []distgo.TemplateFunction{
+				distgo.TemplateValueFunction("lower", "overridden"),
+			},
+			want: "overridden",
+		},
+		{
+			name:    "wrong number of arguments",
+			tmpl:    `{{trimPrefix "v"}}`,
+			wantErr: `failed to execute template: template: distgoTemplate:1:2: executing "distgoTemplate" at <trimPrefix>: wrong number of args for trimPrefix: want 2 got 1`,
+		},
+	} {
+		got, err := distgo.RenderTemplate(tc.tmpl, nil, tc.fns...)
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s", i, tc.name)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo
+
+import (
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultVersionFileName is the name of the generated version file if VersionFileSpec.FileName is empty.
+	DefaultVersionFileName = "distgo_version.go"
+
+	// DefaultVersionFileTemplate is the template used to render the body of the generated version file if
+	// VersionFileSpec.Template is empty. It declares untyped string constants for the product and version.
+	DefaultVersionFileTemplate = `// Product is the ID of the product that was built.
+const Product = {{printf "%q" Product}}
+
+// Version is the version of the product that was built.
+const Version = {{printf "%q" Version}}
+`
+
+	// versionFileHeader is the header of the generated version file. It follows the convention for generated Go files
+	// (see "go help generate").
+	versionFileHeader = "// Code generated by distgo. DO NOT EDIT.\n"
+)
+
+// VersionFileSpec specifies a Go source file that contains build metadata and is generated before a product is built.
+type VersionFileSpec struct {
+	// Dir is the directory of the package in which the file is generated. It is relative to the module directory of
+	// the product (see BuildParam.ModuleDirPath). For example, "internal/version".
+	Dir string
+
+	// FileName is the name of the generated file. Must end in ".go" and must not start with "_" or "." (the "build"
+	// command ignores such files). If empty, DefaultVersionFileName is used.
+	FileName string
+
+	// Template is the template that is rendered to produce the declarations in the generated file. The package clause
+	// and a "Code generated" header are added by distgo, and the result is formatted using gofmt. The template is
+	// rendered in the same manner as VersionVarSpec.ValueTemplate (and can use the same functions). The values are not
+	// quoted, so templates should use "printf" to create string literals: for example,
+	// `var Info = VersionInfo{Version: {{printf "%q" Version}}}`. If empty, DefaultVersionFileTemplate is used.
+	Template string
+}
+
+// Validate returns an error if the provided VersionFileSpec is not valid.
+func (s *VersionFileSpec) Validate() error {
+	if s.Dir == "" {
+		return errors.Errorf("dir must be specified")
+	}
+	if cleanDir := path.Clean(s.Dir); path.IsAbs(cleanDir) || cleanDir == ".." || strings.HasPrefix(cleanDir, "../") {
+		return errors.Errorf("dir %s must be a path within the module directory", s.Dir)
+	}
+	if fileName := s.FileName; fileName != "" {
+		if strings.Contains(fileName, "/") || !strings.HasSuffix(fileName, ".go") || strings.HasSuffix(fileName, "_test.go") || strings.HasPrefix(fileName, "_") || strings.HasPrefix(fileName, ".") {
+			return errors.Errorf("file name %s must be the name of a non-test Go file that does not start with '_' or '.'", fileName)
+		}
+	}
+	return nil
+}
+
+// Path returns the path to the generated file for the provided module directory.
+func (s *VersionFileSpec) Path(moduleDir string) string {
+	fileName := s.FileName
+	if fileName == "" {
+		fileName = DefaultVersionFileName
+	}
+	return path.Join(moduleDir, s.Dir, fileName)
+}
+
+// Content returns the content of the generated file for the provided ProductTaskOutputInfo and module directory. The
+// name of the package in the package clause is the name of the package declared by the other non-test Go files in the
+// directory, or the base name of the directory if it does not contain any other Go files. The content is deterministic
+// as long as the template does not use {{BuildTime}} (or SOURCE_DATE_EPOCH is set: see ProjectInfo.BuildTime).
+func (s *VersionFileSpec) Content(productTaskOutputInfo ProductTaskOutputInfo, moduleDir string) ([]byte, error) {
+	versionFilePath := s.Path(moduleDir)
+	pkgName, err := packageName(path.Dir(versionFilePath), path.Base(versionFilePath))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := s.Template
+	if tmpl == "" {
+		tmpl = DefaultVersionFileTemplate
+	}
+	buildTime, err := productTaskOutputInfo.Project.BuildTime()
+	if err != nil {
+		return nil, err
+	}
+	body, err := RenderTemplate(tmpl, productTaskOutputInfo,
+		ProductTemplateFunction(productTaskOutputInfo.Product.ID),
+		VersionTemplateFunction(productTaskOutputInfo.Project.Version),
+		GitCommitTemplateFunction(productTaskOutputInfo.Project.ProjectDir),
+		BuildTimeTemplateFunction(buildTime),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render version file template")
+	}
+	content := fmt.Sprintf("%s\npackage %s\n\n%s", versionFileHeader, pkgName, body)
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "rendered version file is not valid Go source:\n%s", content)
+	}
+	return formatted, nil
+}
+
+// packageName returns the name of the package declared by the non-test Go files in the provided directory other than
+// the file with the provided name. Returns the base name of the directory (with '-' and '.' replaced by '_') if the
+// directory does not contain any such files.
+func packageName(dir, excludeFileName string) (string, error) {
+	goFiles, err := filepath.Glob(path.Join(dir, "*.go"))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list Go files in %s", dir)
+	}
+	sort.Strings(goFiles)
+	for _, goFile := range goFiles {
+		fileName := filepath.Base(goFile)
+		if fileName == excludeFileName || strings.HasSuffix(fileName, "_test.go") || strings.HasPrefix(fileName, "_") || strings.HasPrefix(fileName, ".") {
+			continue
+		}
+		src, err := ioutil.ReadFile(goFile)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read %s", goFile)
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), goFile, src, parser.PackageClauseOnly)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse package clause of %s", goFile)
+		}
+		return f.Name.Name, nil
+	}
+	return strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(dir)), nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distgo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionFileContent(t *testing.T) {
+	for i, tc := range []struct {
+		name      string
+		spec      distgo.VersionFileSpec
+		files     map[string]string
+		wantPath  string
+		want      string
+		wantError string
+	}{
+		{
+			name: "default template in directory without Go files",
+			spec: distgo.VersionFileSpec{
+				Dir: "internal/build-info",
+			},
+			wantPath: "internal/build-info/distgo_version.go",
+			want: `// Code generated by distgo. DO NOT EDIT.
+
+package build_info
+
+// Product is the ID of the product that was built.
+const Product = "foo"
+
+// Version is the version of the product that was built.
+const Version = "1.0.0"
+`,
+		},
+		{
+			name: "package name is determined from existing Go files",
+			spec: distgo.VersionFileSpec{
+				Dir:      "internal/version",
+				FileName: "zz_version.go",
+				Template: `var Info = BuildInfo{Product: {{printf "%q" Product}}, Version: {{printf "%q" Version}}}`,
+			},
+			files: map[string]string{
+				"internal/version/info.go":          "package buildinfo\n\ntype BuildInfo struct {\n\tProduct, Version string\n}\n",
+				"internal/version/info_test.go":     "package buildinfo_test\n",
+				"internal/version/zz_version.go":    "package placeholder\n",
+				"internal/version/_ignored_file.go": "package ignored\n",
+			},
+			wantPath: "internal/version/zz_version.go",
+			want: `// Code generated by distgo. DO NOT EDIT.
+
+package buildinfo
+
+var Info = BuildInfo{Product: "foo", Version: "1.0.0"}
+`,
+		},
+		{
+			name: "typed constant",
+			spec: distgo.VersionFileSpec{
+				Dir:      ".",
+				Template: `const Version SemVer = {{printf "%q" (trimPrefix "v" Version)}}`,
+			},
+			files: map[string]string{
+				"main.go": "package main\n\ntype SemVer string\n\nfunc main() {}\n",
+			},
+			wantPath: "distgo_version.go",
+			want: `// Code generated by distgo. DO NOT EDIT.
+
+package main
+
+const Version SemVer = "1.0.0"
+`,
+		},
+		{
+			name: "rendered template that is not valid Go",
+			spec: distgo.VersionFileSpec{
+				Dir:      "version",
+				Template: `const Version = {{Version}}`,
+			},
+			wantPath:  "version/distgo_version.go",
+			wantError: "rendered version file is not valid Go source",
+		},
+	} {
+		func() {
+			tmp, cleanup, err := dirs.TempDir("", "")
+			defer cleanup()
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			require.NoError(t, os.MkdirAll(path.Join(tmp, tc.spec.Dir), 0755), "Case %d: %s", i, tc.name)
+			for relPath, content := range tc.files {
+				require.NoError(t, ioutil.WriteFile(path.Join(tmp, relPath), []byte(content), 0644), "Case %d: %s", i, tc.name)
+			}
+
+			assert.Equal(t, path.Join(tmp, tc.wantPath), tc.spec.Path(tmp), "Case %d: %s", i, tc.name)
+
+			got, err := tc.spec.Content(distgo.ProductTaskOutputInfo{
+				Project: distgo.ProjectInfo{
+					ProjectDir: tmp,
+					Version:    "1.0.0",
+				},
+				Product: distgo.ProductOutputInfo{
+					ID: "foo",
+				},
+			}, tmp)
+			if tc.wantError != "" {
+				require.Error(t, err, "Case %d: %s", i, tc.name)
+				assert.Contains(t, err.Error(), tc.wantError, "Case %d: %s", i, tc.name)
+				return
+			}
+			require.NoError(t, err, "Case %d: %s", i, tc.name)
+			assert.Equal(t, tc.want, string(got), "Case %d: %s", i, tc.name)
+		}()
+	}
+}
@@ -27,8 +27,18 @@ func (cfg *Default) ToDockerBuilder() distgo.DockerBuilder {
 	if cfg.BuildArgsScript != nil {
 		buildArgsScript = *cfg.BuildArgsScript
 	}
+	var buildKit bool
+	if cfg.BuildKit != nil {
+		buildKit = *cfg.BuildKit
+	}
 	return &defaultdockerbuilder.DefaultDockerBuilder{
 		BuildArgs:       cfg.BuildArgs,
+		BuildArgValues:  cfg.BuildArgValues,
+		Labels:          cfg.Labels,
 		BuildArgsScript: buildArgsScript,
+		BuildKit:        buildKit,
+		Secrets:         cfg.Secrets,
+		CacheFrom:       cfg.CacheFrom,
+		CacheTo:         cfg.CacheTo,
 	}
 }
@@ -20,8 +20,45 @@ import (
 )
 
 type Config struct {
+	// BuildArgs are the raw arguments that are provided to the "docker build" command.
 	BuildArgs []string `yaml:"build-args,omitempty"`
 
+	// BuildArgValues specifies the build-time variables for the image. Each entry is provided to the "docker build"
+	// command as "--build-arg KEY=VALUE". The values are rendered as Go templates that can use the "{{Product}}",
+	// "{{Version}}", "{{Repository}}", "{{RepositoryLiteral}}" and "{{GitCommit}}" functions and the "lower", "upper",
+	// "trimPrefix" and "replace" string functions, and the distgo.ProductTaskOutputInfo for the image is provided as the
+	// template data. For example:
+	//
+	//   build-arg-values:
+	//     VERSION: "{{Version | trimPrefix \"v\"}}"
+	BuildArgValues map[string]string `yaml:"build-arg-values,omitempty"`
+
+	// Labels specifies the labels that are set on the image. Each entry is provided to the "docker build" command as
+	// "--label KEY=VALUE". The values support the same templates as BuildArgValues. For example:
+	//
+	//   labels:
+	//     org.opencontainers.image.title: "{{Product}}"
+	//     org.opencontainers.image.version: "{{Version}}"
+	//     org.opencontainers.image.revision: "{{GitCommit}}"
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// BuildKit specifies whether the image should be built using BuildKit. If true, the "docker build" command is run
+	// with "DOCKER_BUILDKIT=1" set in its environment. If not specified, defaults to false.
+	BuildKit *bool `yaml:"buildkit,omitempty"`
+
+	// Secrets specifies the secrets that are exposed to the build. Each entry is provided to the "docker build" command
+	// using the "--secret" flag (for example, "id=npmrc,src=.npmrc"). Can only be specified if BuildKit is true.
+	Secrets []string `yaml:"secrets,omitempty"`
+
+	// CacheFrom specifies the external cache sources for the build. Each entry is provided to the "docker build"
+	// command using the "--cache-from" flag (for example, "type=registry,ref=myregistryhost:5000/foo:cache"). Can only
+	// be specified if BuildKit is true.
+	CacheFrom []string `yaml:"cache-from,omitempty"`
+
+	// CacheTo specifies the cache export destinations for the build. Each entry is provided to the "docker build"
+	// command using the "--cache-to" flag. Can only be specified if BuildKit is true.
+	CacheTo []string `yaml:"cache-to,omitempty"`
+
 	// BuildArgsScript is the content of a script that is written to a file and run before this image is built to
 	// provide supplemental "docker build" arguments for the image. The content of this value is written to a file and
 	// executed. The script process uses the project directory as its working directory and inherits the environment
@@ -16,17 +16,34 @@ package defaultdockerbuilder
 
 import (
 	"io"
+	"os"
 	"os/exec"
 	"path"
+	"sort"
 
 	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
 )
 
 const TypeName = "default"
 
 type DefaultDockerBuilder struct {
-	BuildArgs       []string
+	BuildArgs []string
+	// BuildArgValues are provided to "docker build" as "--build-arg KEY=VALUE" after their values are rendered as
+	// templates.
+	BuildArgValues map[string]string
+	// Labels are provided to "docker build" as "--label KEY=VALUE" after their values are rendered as templates.
+	Labels          map[string]string
 	BuildArgsScript string
+	// BuildKit specifies whether the image is built using BuildKit. If true, "DOCKER_BUILDKIT=1" is set in the
+	// environment of the "docker build" command.
+	BuildKit bool
+	// Secrets are provided to "docker build" as "--secret" flags. Requires BuildKit.
+	Secrets []string
+	// CacheFrom are provided to "docker build" as "--cache-from" flags. Requires BuildKit.
+	CacheFrom []string
+	// CacheTo are provided to "docker build" as "--cache-to" flags. Requires BuildKit.
+	CacheTo []string
 }
 
 func NewDefaultDockerBuilder(buildArgs []string, buildArgsScript string) distgo.DockerBuilder {
@@ -41,6 +58,30 @@ func (d *DefaultDockerBuilder) TypeName() (string, error) {
 }
 
 func (d *DefaultDockerBuilder) RunDockerBuild(dockerID distgo.DockerID, productTaskOutputInfo distgo.ProductTaskOutputInfo, verbose, dryRun bool, stdout io.Writer) error {
+	cmd, err := d.BuildCommand(dockerID, productTaskOutputInfo)
+	if err != nil {
+		return err
+	}
+	return distgo.RunCommandWithVerboseOption(cmd, verbose, dryRun, stdout)
+}
+
+// BuildCommand returns the "docker build" command that builds the image for the specified Docker ID.
+func (d *DefaultDockerBuilder) BuildCommand(dockerID distgo.DockerID, productTaskOutputInfo distgo.ProductTaskOutputInfo) (*exec.Cmd, error) {
+	if !d.BuildKit {
+		for _, currOption := range []struct {
+			name   string
+			values []string
+		}{
+			{"secrets", d.Secrets},
+			{"cache-from", d.CacheFrom},
+			{"cache-to", d.CacheTo},
+		} {
+			if len(currOption.values) > 0 {
+				return nil, errors.Errorf("%s requires BuildKit and can only be specified if buildkit is true", currOption.name)
+			}
+		}
+	}
+
 	dockerBuilderOutputInfo := productTaskOutputInfo.Product.DockerOutputInfos.DockerBuilderOutputInfos[dockerID]
 	contextDirPath := path.Join(productTaskOutputInfo.Project.ProjectDir, dockerBuilderOutputInfo.ContextDir)
 	args := []string{
@@ -52,16 +93,64 @@ func (d *DefaultDockerBuilder) RunDockerBuild(dockerID distgo.DockerID, productT
 			"-t", tag,
 		)
 	}
+	buildArgValues, err := renderKeyValueArgs("--build-arg", d.BuildArgValues, productTaskOutputInfo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render build-arg-values")
+	}
+	args = append(args, buildArgValues...)
+	labels, err := renderKeyValueArgs("--label", d.Labels, productTaskOutputInfo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render labels")
+	}
+	args = append(args, labels...)
+	for _, secret := range d.Secrets {
+		args = append(args, "--secret", secret)
+	}
+	for _, cacheFrom := range d.CacheFrom {
+		args = append(args, "--cache-from", cacheFrom)
+	}
+	for _, cacheTo := range d.CacheTo {
+		args = append(args, "--cache-to", cacheTo)
+	}
 	args = append(args, d.BuildArgs...)
 	if d.BuildArgsScript != "" {
 		buildArgsFromScript, err := distgo.DockerBuildArgsFromScript(dockerID, productTaskOutputInfo, d.BuildArgsScript)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		args = append(args, buildArgsFromScript...)
 	}
 	args = append(args, contextDirPath)
 
 	cmd := exec.Command("docker", args...)
-	return distgo.RunCommandWithVerboseOption(cmd, verbose, dryRun, stdout)
+	if d.BuildKit {
+		cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	}
+	return cmd, nil
+}
+
+// renderKeyValueArgs returns the arguments that provide each entry of the map as "flag KEY=VALUE" in the order of the
+// keys. The values are rendered as templates using the provided output information.
+func renderKeyValueArgs(flag string, values map[string]string, productTaskOutputInfo distgo.ProductTaskOutputInfo) ([]string, error) {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, k := range keys {
+		rendered, err := distgo.RenderTemplate(values[k], productTaskOutputInfo,
+			distgo.ProductTemplateFunction(productTaskOutputInfo.Product.ID),
+			distgo.VersionTemplateFunction(productTaskOutputInfo.Project.Version),
+			distgo.RepositoryTemplateFunction(productTaskOutputInfo.Product.DockerOutputInfos.Repository),
+			distgo.RepositoryLiteralTemplateFunction(productTaskOutputInfo.Product.DockerOutputInfos.Repository),
+			distgo.GitCommitTemplateFunction(productTaskOutputInfo.Project.ProjectDir),
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render value for %s", k)
+		}
+		args = append(args, flag, k+"="+rendered)
+	}
+	return args, nil
 }
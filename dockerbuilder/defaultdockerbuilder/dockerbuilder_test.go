@@ -0,0 +1,237 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package defaultdockerbuilder_test
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/dockerbuilder/defaultdockerbuilder"
+	"github.com/palantir/distgo/dockerbuilder/defaultdockerbuilder/config"
+	"github.com/palantir/pkg/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRunDockerBuildArgs(t *testing.T) {
+	projectDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+	gittest.InitGitDir(t, projectDir)
+	commitOutput, err := exec.Command("git", "-C", projectDir, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+	gitCommit := strings.TrimSpace(string(commitOutput))
+
+	contextDir := path.Join(projectDir, "docker")
+	for i, tc := range []struct {
+		name            string
+		configYML       string
+		wantArgs        []string
+		wantErrorRegexp string
+	}{
+		{
+			"build args and labels are rendered in key order",
+			`
+build-arg-values:
+  VERSION: "{{Version}}"
+  PRODUCT: "{{Product}}"
+labels:
+  org.opencontainers.image.version: "{{Version}}"
+  org.opencontainers.image.title: "{{Product}}"
+  org.opencontainers.image.revision: "{{GitCommit}}"
+  org.opencontainers.image.source: "{{Repository}}{{.Product.ID}}"
+build-args:
+  - --pull
+`,
+			[]string{
+				"docker", "build",
+				"--file", path.Join(contextDir, "Dockerfile"),
+				"-t", "registry.example.com/foo:1.0.0",
+				"--build-arg", "PRODUCT=foo",
+				"--build-arg", "VERSION=1.0.0",
+				"--label", "org.opencontainers.image.revision=" + gitCommit,
+				"--label", "org.opencontainers.image.source=registry.example.com/foo",
+				"--label", "org.opencontainers.image.title=foo",
+				"--label", "org.opencontainers.image.version=1.0.0",
+				"--pull",
+				contextDir,
+			},
+			"",
+		},
+		{
+			"no build args or labels",
+			``,
+			[]string{
+				"docker", "build",
+				"--file", path.Join(contextDir, "Dockerfile"),
+				"-t", "registry.example.com/foo:1.0.0",
+				contextDir,
+			},
+			"",
+		},
+		{
+			"invalid template",
+			`
+labels:
+  version: "{{Version"
+`,
+			nil,
+			`^failed to render labels: failed to render value for version: .+`,
+		},
+	} {
+		var cfg config.Default
+		require.NoError(t, yaml.UnmarshalStrict([]byte(tc.configYML), &cfg), "Case %d: %s", i, tc.name)
+
+		buffer := &bytes.Buffer{}
+		err := cfg.ToDockerBuilder().RunDockerBuild("tester", testProductTaskOutputInfo(projectDir), false, true, buffer)
+		if tc.wantErrorRegexp != "" {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Regexp(t, tc.wantErrorRegexp, err.Error(), "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, fmt.Sprintf("[DRY RUN] Run %v\n", tc.wantArgs), buffer.String(), "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestBuildCommandBuildKit(t *testing.T) {
+	projectDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	contextDir := path.Join(projectDir, "docker")
+	for i, tc := range []struct {
+		name            string
+		configYML       string
+		wantArgs        []string
+		wantBuildKitEnv bool
+		wantErrorRegexp string
+	}{
+		{
+			"BuildKit enabled with secrets and cache options",
+			`
+buildkit: true
+secrets:
+  - id=npmrc,src=.npmrc
+cache-from:
+  - type=registry,ref=registry.example.com/foo:cache
+cache-to:
+  - type=registry,ref=registry.example.com/foo:cache,mode=max
+`,
+			[]string{
+				"docker", "build",
+				"--file", path.Join(contextDir, "Dockerfile"),
+				"-t", "registry.example.com/foo:1.0.0",
+				"--secret", "id=npmrc,src=.npmrc",
+				"--cache-from", "type=registry,ref=registry.example.com/foo:cache",
+				"--cache-to", "type=registry,ref=registry.example.com/foo:cache,mode=max",
+				contextDir,
+			},
+			true,
+			"",
+		},
+		{
+			"BuildKit disabled uses the legacy builder",
+			`
+buildkit: false
+`,
+			[]string{
+				"docker", "build",
+				"--file", path.Join(contextDir, "Dockerfile"),
+				"-t", "registry.example.com/foo:1.0.0",
+				contextDir,
+			},
+			false,
+			"",
+		},
+		{
+			"secrets are rejected if BuildKit is disabled",
+			`
+secrets:
+  - id=npmrc,src=.npmrc
+`,
+			nil,
+			false,
+			`^secrets requires BuildKit and can only be specified if buildkit is true$`,
+		},
+		{
+			"cache-from is rejected if BuildKit is disabled",
+			`
+cache-from:
+  - foo:cache
+`,
+			nil,
+			false,
+			`^cache-from requires BuildKit and can only be specified if buildkit is true$`,
+		},
+		{
+			"cache-to is rejected if BuildKit is disabled",
+			`
+buildkit: false
+cache-to:
+  - type=inline
+`,
+			nil,
+			false,
+			`^cache-to requires BuildKit and can only be specified if buildkit is true$`,
+		},
+	} {
+		var cfg config.Default
+		require.NoError(t, yaml.UnmarshalStrict([]byte(tc.configYML), &cfg), "Case %d: %s", i, tc.name)
+
+		cmd, err := cfg.ToDockerBuilder().(*defaultdockerbuilder.DefaultDockerBuilder).BuildCommand("tester", testProductTaskOutputInfo(projectDir))
+		if tc.wantErrorRegexp != "" {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Regexp(t, tc.wantErrorRegexp, err.Error(), "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.wantArgs, cmd.Args, "Case %d: %s", i, tc.name)
+		if tc.wantBuildKitEnv {
+			assert.Contains(t, cmd.Env, "DOCKER_BUILDKIT=1", "Case %d: %s", i, tc.name)
+		} else {
+			assert.Nil(t, cmd.Env, "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func testProductTaskOutputInfo(projectDir string) distgo.ProductTaskOutputInfo {
+	return distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: projectDir,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DockerOutputInfos: &distgo.DockerOutputInfos{
+				Repository: "registry.example.com",
+				DockerBuilderOutputInfos: map[distgo.DockerID]distgo.DockerBuilderOutputInfo{
+					"tester": {
+						ContextDir:     "docker",
+						DockerfilePath: "Dockerfile",
+						RenderedTags:   []string{"registry.example.com/foo:1.0.0"},
+					},
+				},
+			},
+		},
+	}
+}
@@ -29,6 +29,18 @@ type Config struct {
 	// The values are processed as Go templates. In particular, it is possible to get the value of an
 	// environment variable by using the `env` Go template function: {{ env "ENV_VAR" }}.
 	Properties map[string]string `yaml:"properties,omitempty"`
+	// PropertiesHeader specifies whether Properties are sent in the "X-JFrog-Properties" header of the upload requests
+	// (in the form "key1=val1;key2=val2") rather than as matrix parameters of the deployment URL.
+	PropertiesHeader bool `yaml:"properties-header,omitempty"`
+	// Layout is the template for the path of the directory within the repository to which the artifacts are uploaded.
+	// The template can use the "{{Product}}", "{{Version}}", "{{GroupID}}" and "{{GroupPath}}" functions, where
+	// "{{GroupPath}}" is the group ID with every "." replaced by "/". For example, "{{Product}}/{{Version}}". If not
+	// specified, the Maven layout "{{GroupPath}}/{{Product}}/{{Version}}" is used. The group ID is only required if
+	// it is used by the layout or if a POM is published.
+	Layout string `yaml:"layout,omitempty"`
+	// APIKey is the Artifactory API key used to authenticate. If specified, it is sent in the "X-JFrog-Art-Api" header
+	// of every request. If not specified, the value of the ARTIFACTORY_API_KEY environment variable is used (if set).
+	APIKey string `yaml:"api-key,omitempty"`
 }
 
 func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
@@ -82,7 +82,9 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return fmt.Sprintf(`[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-%s.tgz to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-%s.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 [DRY RUN] Uploading to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0.pom
+[DRY RUN]   Authorization: Basic [REDACTED]
 `, osarch.Current().String(), osarch.Current().String())
 				},
 			},
@@ -125,6 +127,7 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return fmt.Sprintf(`[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-%s.tgz to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-%s.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 `, osarch.Current().String(), osarch.Current().String())
 				},
 			},
@@ -165,7 +168,9 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return fmt.Sprintf(`[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-%s.tgz to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-%s.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 [DRY RUN] Uploading to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0.pom
+[DRY RUN]   Authorization: Basic [REDACTED]
 `, osarch.Current().String(), osarch.Current().String())
 				},
 			},
@@ -207,6 +212,7 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return fmt.Sprintf(`[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-%s.tgz to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-%s.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 `, osarch.Current().String(), osarch.Current().String())
 				},
 			},
@@ -259,8 +265,11 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return `[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-darwin-amd64.tgz to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 [DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-linux-amd64.tgz to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 [DRY RUN] Uploading to http://artifactory.domain.com/artifactory/testRepo/com/test/group/foo/1.0.0/foo-1.0.0.pom
+[DRY RUN]   Authorization: Basic [REDACTED]
 `
 				},
 			},
@@ -305,7 +314,9 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return fmt.Sprintf(`[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-%s.tgz to http://artifactory.domain.com/artifactory/testRepo;key1=value1;key2=value2/com/test/group/foo/1.0.0/foo-1.0.0-%s.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 [DRY RUN] Uploading to http://artifactory.domain.com/artifactory/testRepo;key1=value1;key2=value2/com/test/group/foo/1.0.0/foo-1.0.0.pom
+[DRY RUN]   Authorization: Basic [REDACTED]
 `, osarch.Current().String(), osarch.Current().String())
 				},
 			},
@@ -374,7 +385,9 @@ products:
 				},
 				WantOutput: func(projectDir string) string {
 					return fmt.Sprintf(`[DRY RUN] Uploading out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-%s.tgz to http://artifactory.domain.com/artifactory/testRepo;env-key=testValue;key1=value1/com/test/group/foo/1.0.0/foo-1.0.0-%s.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
 [DRY RUN] Uploading to http://artifactory.domain.com/artifactory/testRepo;env-key=testValue;key1=value1/com/test/group/foo/1.0.0/foo-1.0.0.pom
+[DRY RUN]   Authorization: Basic [REDACTED]
 `, osarch.Current().String(), osarch.Current().String())
 				},
 			},
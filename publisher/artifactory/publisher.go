@@ -27,6 +27,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	texttemplate "text/template"
 
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/publisher"
@@ -36,7 +37,13 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-const TypeName = "artifactory" // publishes output artifacts to Artifactory
+const (
+	TypeName = "artifactory" // publishes output artifacts to Artifactory
+
+	apiKeyEnvVar     = "ARTIFACTORY_API_KEY"
+	apiKeyHeader     = "X-JFrog-Art-Api"
+	propertiesHeader = "X-JFrog-Properties"
+)
 
 type Publisher interface {
 	distgo.Publisher
@@ -85,9 +92,9 @@ func (p *artifactoryPublisher) ArtifactoryRunPublish(productTaskOutputInfo distg
 	if err := yaml.Unmarshal(cfgYML, &cfg); err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal configuration")
 	}
-	groupID, err := publisher.GetRequiredGroupID(flagVals, productTaskOutputInfo)
-	if err != nil {
-		return nil, err
+	groupID, groupIDErr := publisher.GetRequiredGroupID(flagVals, productTaskOutputInfo)
+	if groupIDErr != nil && cfg.Layout == "" {
+		return nil, groupIDErr
 	}
 	if err := cfg.BasicConnectionInfo.SetValuesFromFlags(flagVals); err != nil {
 		return nil, err
@@ -98,10 +105,25 @@ func (p *artifactoryPublisher) ArtifactoryRunPublish(productTaskOutputInfo distg
 	if err := publisher.SetConfigValue(flagVals, maven.NoPOMFlag, &cfg.NoPOM); err != nil {
 		return nil, err
 	}
+	if groupIDErr != nil && !cfg.NoPOM {
+		return nil, groupIDErr
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv(apiKeyEnvVar)
+	}
+	if cfg.APIKey != "" {
+		if cfg.Header == nil {
+			cfg.Header = http.Header{}
+		}
+		cfg.Header.Set(apiKeyHeader, cfg.APIKey)
+	}
 
 	artifactoryURL := strings.Join([]string{cfg.URL, "artifactory"}, "/")
-	productPath := publisher.MavenProductPath(productTaskOutputInfo, groupID)
-	artifactExists := func(dstFileName string, checksums publisher.Checksums, username, password string) bool {
+	productPath, err := ProductPath(cfg.Layout, productTaskOutputInfo, groupID)
+	if err != nil {
+		return nil, err
+	}
+	artifactExists := func(dstFileName string, checksums publisher.Checksums, _, _ string) bool {
 		rawCheckArtifactURL := strings.Join([]string{artifactoryURL, "api", "storage", cfg.Repository, productPath, dstFileName}, "/")
 		checkArtifactURL, err := url.Parse(rawCheckArtifactURL)
 		if err != nil {
@@ -114,9 +136,13 @@ func (p *artifactoryPublisher) ArtifactoryRunPublish(productTaskOutputInfo distg
 			URL:    checkArtifactURL,
 			Header: header,
 		}
-		req.SetBasicAuth(username, password)
+		cfg.AuthorizeRequest(&req)
 
-		if resp, err := http.DefaultClient.Do(&req); err == nil {
+		client, err := cfg.HTTPClient()
+		if err != nil {
+			return false
+		}
+		if resp, err := client.Do(&req); err == nil {
 			defer func() {
 				// nothing to be done if close fails
 				_ = resp.Body.Close()
@@ -138,7 +164,7 @@ func (p *artifactoryPublisher) ArtifactoryRunPublish(productTaskOutputInfo distg
 		return false
 	}
 
-	deploymentURL, err := p.getDeploymentURL(cfg)
+	deploymentURL, err := p.getDeploymentURL(&cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -204,9 +230,13 @@ func (p *artifactoryPublisher) artifactorySetSHA256Checksum(cfg config.Artifacto
 		Body:          ioutil.NopCloser(reader),
 		ContentLength: int64(len([]byte(jsonContent))),
 	}
-	req.SetBasicAuth(cfg.Username, cfg.Password)
+	cfg.AuthorizeRequest(&req)
 
-	resp, err := http.DefaultClient.Do(&req)
+	client, err := cfg.HTTPClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(&req)
 	if err != nil {
 		return errors.Wrapf(err, "failed to trigger computation of SHA-256 checksum for %s", filePath)
 	}
@@ -222,16 +252,57 @@ func (p *artifactoryPublisher) artifactorySetSHA256Checksum(cfg config.Artifacto
 	return nil
 }
 
-func (p *artifactoryPublisher) getDeploymentURL(cfg config.Artifactory) (string, error) {
+// getDeploymentURL returns the URL of the repository to which artifacts are deployed. If the properties are
+// configured to be sent as a header, the header is added to the connection information of the provided configuration
+// and the returned URL does not include the properties.
+func (p *artifactoryPublisher) getDeploymentURL(cfg *config.Artifactory) (string, error) {
 	url := strings.Join([]string{cfg.URL, "artifactory", cfg.Repository}, "/")
 	encodedProps, err := encodeProperties(cfg.Properties)
 	if err != nil {
 		return "", err
 	}
+	if cfg.PropertiesHeader {
+		if len(encodedProps) > 0 {
+			if cfg.Header == nil {
+				cfg.Header = http.Header{}
+			}
+			cfg.Header.Set(propertiesHeader, strings.Join(encodedProps, ";"))
+		}
+		return url, nil
+	}
 
 	return strings.Join(append([]string{url}, encodedProps...), ";"), nil
 }
 
+// ProductPath returns the path of the directory within the repository to which the artifacts of the product are
+// uploaded. The path is rendered from the provided layout template. If the layout is empty, the Maven layout is used.
+func ProductPath(layout string, productTaskOutputInfo distgo.ProductTaskOutputInfo, groupID string) (string, error) {
+	if layout == "" {
+		return publisher.MavenProductPath(productTaskOutputInfo, groupID), nil
+	}
+	groupIDFn := func() (string, error) {
+		if groupID == "" {
+			return "", publisher.PropertyNotSpecifiedError(publisher.GroupIDFlag)
+		}
+		return groupID, nil
+	}
+	rendered, err := distgo.RenderTemplate(layout, nil,
+		distgo.ProductTemplateFunction(productTaskOutputInfo.Product.ID),
+		distgo.VersionTemplateFunction(productTaskOutputInfo.Project.Version),
+		func(fnMap texttemplate.FuncMap) {
+			fnMap["GroupID"] = groupIDFn
+			fnMap["GroupPath"] = func() (string, error) {
+				groupID, err := groupIDFn()
+				return strings.Replace(groupID, ".", "/", -1), err
+			}
+		},
+	)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to render layout")
+	}
+	return strings.Trim(path.Clean(rendered), "/"), nil
+}
+
 // encodeProperties takes in a map[string]string of properties, renders each value as a Go template, and returns
 // the sorted slice of strings of the form `key=renderedVal`. The Go template can include the `env` function,
 // which fetches an environment variable.
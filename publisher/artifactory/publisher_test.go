@@ -0,0 +1,187 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifactory_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher/artifactory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductPath(t *testing.T) {
+	info := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			Version: "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+		},
+	}
+	for i, tc := range []struct {
+		layout  string
+		groupID string
+		want    string
+		wantErr string
+	}{
+		{
+			groupID: "com.test.group",
+			want:    "com/test/group/foo/1.0.0",
+		},
+		{
+			layout: "{{Product}}/{{Version}}",
+			want:   "foo/1.0.0",
+		},
+		{
+			layout:  "/releases/{{GroupPath}}/{{Product}}-{{Version}}/",
+			groupID: "com.test.group",
+			want:    "releases/com/test/group/foo-1.0.0",
+		},
+		{
+			layout:  "{{GroupID}}/{{Product}}",
+			groupID: "com.test.group",
+			want:    "com.test.group/foo",
+		},
+		{
+			layout:  "{{GroupPath}}/{{Product}}",
+			wantErr: "failed to render layout: failed to execute template: template: distgoTemplate:1:2: executing \"distgoTemplate\" at <GroupPath>: error calling GroupPath: group-id was not specified -- it must be specified in configuration or using a flag",
+		},
+	} {
+		got, err := artifactory.ProductPath(tc.layout, info, tc.groupID)
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d", i)
+			continue
+		}
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.want, got, "Case %d", i)
+	}
+}
+
+type artifactoryRequest struct {
+	method     string
+	path       string
+	apiKey     string
+	properties string
+	basicAuth  bool
+}
+
+func TestArtifactoryPublishLayoutAndHeaders(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	info := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistArtifactNames: []string{"foo-1.0.0-linux-amd64.tgz"},
+					},
+				},
+			},
+		},
+	}
+	artifactPath := path.Join(tmp, "out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-linux-amd64.tgz")
+	require.NoError(t, os.MkdirAll(path.Dir(artifactPath), 0755))
+	require.NoError(t, ioutil.WriteFile(artifactPath, []byte("content"), 0644))
+
+	origAPIKey, hadAPIKey := os.LookupEnv("ARTIFACTORY_API_KEY")
+	require.NoError(t, os.Setenv("ARTIFACTORY_API_KEY", "envAPIKey"))
+	defer func() {
+		if hadAPIKey {
+			_ = os.Setenv("ARTIFACTORY_API_KEY", origAPIKey)
+		} else {
+			_ = os.Unsetenv("ARTIFACTORY_API_KEY")
+		}
+	}()
+
+	for i, tc := range []struct {
+		cfgYML string
+		want   []artifactoryRequest
+	}{
+		{
+			cfgYML: `repository: generic-local
+layout: "{{Product}}/{{Version}}"
+no-pom: true
+properties-header: true
+properties:
+  vcs.revision: abc123
+  team: build
+`,
+			want: []artifactoryRequest{
+				{method: http.MethodGet, path: "/artifactory/api/storage/generic-local/foo/1.0.0/foo-1.0.0-linux-amd64.tgz", apiKey: "envAPIKey", properties: "team=build;vcs.revision=abc123"},
+				{method: http.MethodPut, path: "/artifactory/generic-local/foo/1.0.0/foo-1.0.0-linux-amd64.tgz", apiKey: "envAPIKey", properties: "team=build;vcs.revision=abc123"},
+				{method: http.MethodPost, path: "/artifactory/api/checksum/sha256", apiKey: "envAPIKey", properties: "team=build;vcs.revision=abc123"},
+			},
+		},
+		{
+			cfgYML: `repository: generic-local
+layout: "releases/{{Product}}"
+username: testUsername
+password: testPassword
+api-key: configAPIKey
+no-pom: true
+properties:
+  team: build
+`,
+			want: []artifactoryRequest{
+				{method: http.MethodGet, path: "/artifactory/api/storage/generic-local/releases/foo/foo-1.0.0-linux-amd64.tgz", apiKey: "configAPIKey", basicAuth: true},
+				{method: http.MethodPut, path: "/artifactory/generic-local;team=build/releases/foo/foo-1.0.0-linux-amd64.tgz", apiKey: "configAPIKey", basicAuth: true},
+				{method: http.MethodPost, path: "/artifactory/api/checksum/sha256", apiKey: "configAPIKey", basicAuth: true},
+			},
+		},
+	} {
+		var mu sync.Mutex
+		var requests []artifactoryRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _, basicAuth := r.BasicAuth()
+			mu.Lock()
+			requests = append(requests, artifactoryRequest{
+				method:     r.Method,
+				path:       r.URL.Path,
+				apiKey:     r.Header.Get("X-JFrog-Art-Api"),
+				properties: r.Header.Get("X-JFrog-Properties"),
+				basicAuth:  basicAuth,
+			})
+			mu.Unlock()
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		cfgYML := "url: " + server.URL + "\nskip-upload-verification: true\n" + tc.cfgYML
+		_, err := artifactory.NewArtifactoryPublisher().ArtifactoryRunPublish(info, []byte(cfgYML), nil, false, ioutil.Discard)
+		server.Close()
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.want, requests, "Case %d", i)
+	}
+}
@@ -15,6 +15,7 @@
 package v0
 
 import (
+	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/publisher"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -22,18 +23,41 @@ import (
 
 type Config struct {
 	publisher.BasicConnectionInfo `yaml:",inline,omitempty"`
-	Subject                       string `yaml:"subject,omitempty"`
-	Repository                    string `yaml:"repository,omitempty"`
-	Product                       string `yaml:"product,omitempty"`
-	Publish                       bool   `yaml:"publish,omitempty"`
-	DownloadsList                 bool   `yaml:"downloads-list,omitempty"`
-	NoPOM                         bool   `yaml:"no-pom,omitempty"`
+	// Subject, Repository and Product specify the destination of the publish. They are templates that can use the
+	// "{{Product}}" and "{{Version}}" functions, which return the ID of the product being published and the version of
+	// the project. For example, "releases-{{Product}}". If Product is not specified, the ID of the product is used.
+	Subject    string `yaml:"subject,omitempty"`
+	Repository string `yaml:"repository,omitempty"`
+	Product    string `yaml:"product,omitempty"`
+	// Publish specifies whether the Bintray publish endpoint is called for the version after all of the artifacts have
+	// been uploaded. If false, the uploaded artifacts remain in the unpublished (staged) version and must be published
+	// separately before they are visible.
+	Publish       bool `yaml:"publish,omitempty"`
+	DownloadsList bool `yaml:"downloads-list,omitempty"`
+	NoPOM         bool `yaml:"no-pom,omitempty"`
+	// DistNoPOM overrides the value of NoPOM for specific dists. The key is the ID of the dist and the value specifies
+	// whether the dist should be excluded from POM generation. Dists that are not specified use the value of NoPOM. A
+	// POM is generated for the product if at least one of its dists is not excluded, and the packaging of the POM is
+	// determined using only the dists that are not excluded.
+	DistNoPOM map[distgo.DistID]bool `yaml:"dist-no-pom,omitempty"`
+	// POMTemplate is the template used to generate the POM. If it is not specified, the default POM (which specifies
+	// the group ID, artifact ID, version and packaging) is used. The template can use the "{{GroupID}}", "{{Product}}",
+	// "{{Version}}", "{{Packaging}}" and "{{Description}}" functions. The rendered POM must be well-formed XML.
+	POMTemplate string `yaml:"pom-template,omitempty"`
+	// POMDescription is the value returned by the "{{Description}}" function in POMTemplate.
+	POMDescription string `yaml:"pom-description,omitempty"`
 }
 
+// UpgradeConfig returns the canonical representation of the provided configuration: the configuration is re-marshalled
+// so that keys are in the order in which they are defined in Config and values that are empty are omitted.
 func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
 	var cfg Config
 	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal bintray publisher v0 configuration")
 	}
-	return cfgBytes, nil
+	upgradedBytes, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal bintray publisher v0 configuration")
+	}
+	return upgradedBytes, nil
 }
@@ -0,0 +1,97 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+
+	"github.com/palantir/distgo/publisher/bintray/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeConfig(t *testing.T) {
+	for i, tc := range []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{
+			"canonical configuration is unchanged",
+			`url: http://bintray.domain.com
+username: testUsername
+subject: testSubject
+repository: testRepo
+publish: true
+`,
+			`url: http://bintray.domain.com
+username: testUsername
+subject: testSubject
+repository: testRepo
+publish: true
+`,
+			"",
+		},
+		{
+			"keys are reordered and whitespace is normalized",
+			`
+repository:    testRepo
+publish: true
+subject: testSubject
+
+url:   http://bintray.domain.com
+username: testUsername
+`,
+			`url: http://bintray.domain.com
+username: testUsername
+subject: testSubject
+repository: testRepo
+publish: true
+`,
+			"",
+		},
+		{
+			"empty values are omitted",
+			`url: http://bintray.domain.com
+password: ""
+subject: testSubject
+product: ""
+publish: false
+no-pom: false
+`,
+			`url: http://bintray.domain.com
+subject: testSubject
+`,
+			"",
+		},
+		{
+			"unknown keys are rejected",
+			`url: http://bintray.domain.com
+unknown-key: value
+`,
+			"",
+			"failed to unmarshal bintray publisher v0 configuration: yaml: unmarshal errors:\n  line 2: field unknown-key not found in type v0.Config",
+		},
+	} {
+		got, err := config.UpgradeConfig([]byte(tc.in))
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, string(got), "Case %d: %s", i, tc.name)
+	}
+}
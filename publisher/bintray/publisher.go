@@ -71,6 +71,16 @@ var (
 		Description: "add uploaded artifact to downloads list for package",
 		Type:        distgo.BoolFlag,
 	}
+	bintrayPublisherDeleteVersionFlag = distgo.PublisherFlag{
+		Name:        "delete-version",
+		Description: "version of the Bintray product (and all of its files) to delete after the publish succeeds (requires confirm-delete)",
+		Type:        distgo.StringFlag,
+	}
+	bintrayPublisherConfirmDeleteFlag = distgo.PublisherFlag{
+		Name:        "confirm-delete",
+		Description: "confirm that the version specified by delete-version should be deleted",
+		Type:        distgo.BoolFlag,
+	}
 )
 
 func (p *bintrayPublisher) Flags() ([]distgo.PublisherFlag, error) {
@@ -81,6 +91,8 @@ func (p *bintrayPublisher) Flags() ([]distgo.PublisherFlag, error) {
 		bintrayPublisherProductFlag,
 		bintrayPublisherPublishFlag,
 		bintrayPublisherDownloadsListFlag,
+		bintrayPublisherDeleteVersionFlag,
+		bintrayPublisherConfirmDeleteFlag,
 		publisher.GroupIDFlag,
 		maven.NoPOMFlag,
 	), nil
@@ -111,6 +123,13 @@ func (p *bintrayPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOu
 	if cfg.Product == "" {
 		cfg.Product = string(productTaskOutputInfo.Product.ID)
 	}
+	if err := renderTemplateValues(productTaskOutputInfo,
+		bintrayPublisherSubjectFlag, &cfg.Subject,
+		bintrayPublisherRepositoryFlag, &cfg.Repository,
+		bintrayPublisherProductFlag, &cfg.Product,
+	); err != nil {
+		return err
+	}
 
 	if err := publisher.SetConfigValues(flagVals,
 		bintrayPublisherPublishFlag, &cfg.Publish,
@@ -120,14 +139,28 @@ func (p *bintrayPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOu
 		return err
 	}
 
+	// the version to delete is validated before anything is uploaded so that an invalid request has no side effects
+	var deleteVersion string
+	var confirmDelete bool
+	if err := publisher.SetConfigValues(flagVals,
+		bintrayPublisherDeleteVersionFlag, &deleteVersion,
+		bintrayPublisherConfirmDeleteFlag, &confirmDelete,
+	); err != nil {
+		return err
+	}
+	if err := validateDeleteVersion(deleteVersion, confirmDelete, productTaskOutputInfo); err != nil {
+		return err
+	}
+
 	mavenProductPath := publisher.MavenProductPath(productTaskOutputInfo, groupID)
-	baseURL := strings.Join([]string{cfg.URL, "content", cfg.Subject, cfg.Repository, cfg.Product, productTaskOutputInfo.Project.Version, mavenProductPath}, "/")
-	if _, _, err := cfg.BasicConnectionInfo.UploadDistArtifacts(productTaskOutputInfo, baseURL, nil, dryRun, stdout); err != nil {
+	baseURL := strings.Join([]string{versionContentURL(productTaskOutputInfo, cfg), mavenProductPath}, "/")
+	artifactPaths, _, err := cfg.BasicConnectionInfo.UploadDistArtifacts(productTaskOutputInfo, baseURL, nil, dryRun, stdout)
+	if err != nil {
 		return err
 	}
 
-	if !cfg.NoPOM {
-		pomName, pomContent, err := maven.POM(groupID, productTaskOutputInfo)
+	if pomOutputInfo, ok := pomProductTaskOutputInfo(productTaskOutputInfo, cfg); ok {
+		pomName, pomContent, err := maven.POMFromTemplate(cfg.POMTemplate, groupID, cfg.POMDescription, pomOutputInfo)
 		if err != nil {
 			return err
 		}
@@ -140,33 +173,119 @@ func (p *bintrayPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOu
 		if err := p.publish(productTaskOutputInfo, cfg, dryRun, stdout); err != nil {
 			_, _ = fmt.Fprintln(stdout, "Uploading artifacts succeeded, but publish of uploaded artifacts failed:", err)
 		}
+	} else {
+		// the publish endpoint is not called, so the uploaded content remains staged in an unpublished version
+		distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Uploaded artifacts are staged in unpublished version %s of %s at %s", productTaskOutputInfo.Project.Version, cfg.Product, versionContentURL(productTaskOutputInfo, cfg)), dryRun)
 	}
 	if cfg.DownloadsList {
-		if err := p.addToDownloadsList(productTaskOutputInfo, cfg, mavenProductPath, dryRun, stdout); err != nil {
-			_, _ = fmt.Fprintln(stdout, "Uploading artifacts succeeded, but adding artifact to downloads list failed:", err)
+		if err := p.addToDownloadsList(artifactPaths, cfg, mavenProductPath, dryRun, stdout); err != nil {
+			return errors.Wrapf(err, "uploading artifacts succeeded, but adding artifacts to downloads list failed")
+		}
+	}
+	if deleteVersion != "" {
+		if err := p.deleteVersion(deleteVersion, cfg, dryRun, stdout); err != nil {
+			return errors.Wrapf(err, "uploading artifacts succeeded, but deleting version %s failed", deleteVersion)
 		}
 	}
 	return nil
 }
 
-func (p *bintrayPublisher) publish(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfg config.Bintray, dryRun bool, stdout io.Writer) error {
-	publishURLString := strings.Join([]string{cfg.URL, "content", cfg.Subject, cfg.Repository, cfg.Product, productTaskOutputInfo.Project.Version, "publish"}, "/")
-	return p.runBintrayCommand(publishURLString, http.MethodPost, cfg.Username, cfg.Password, `{"publish_wait_for_secs":-1}`, "running Bintray publish for uploaded artifacts", dryRun, stdout)
+// validateDeleteVersion returns an error if the provided version should not be deleted: the deletion must be explicitly
+// confirmed and the version must not be the version that is being published.
+func validateDeleteVersion(deleteVersion string, confirmDelete bool, productTaskOutputInfo distgo.ProductTaskOutputInfo) error {
+	if deleteVersion == "" {
+		return nil
+	}
+	if !confirmDelete {
+		return errors.Errorf("%s was specified without %s: refusing to delete version %s", bintrayPublisherDeleteVersionFlag.Name, bintrayPublisherConfirmDeleteFlag.Name, deleteVersion)
+	}
+	if deleteVersion == productTaskOutputInfo.Project.Version {
+		return errors.Errorf("refusing to delete version %s because it is the version being published", deleteVersion)
+	}
+	return nil
 }
 
-func (p *bintrayPublisher) addToDownloadsList(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfg config.Bintray, mavenProductPath string, dryRun bool, stdout io.Writer) error {
+// pomProductTaskOutputInfo returns a copy of the provided output information that only contains the dists for which a
+// POM should be generated based on the NoPOM and DistNoPOM values of the configuration. Returns false if a POM should
+// not be generated.
+func pomProductTaskOutputInfo(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfg config.Bintray) (distgo.ProductTaskOutputInfo, bool) {
+	if productTaskOutputInfo.Product.DistOutputInfos == nil || len(productTaskOutputInfo.Product.DistOutputInfos.DistIDs) == 0 {
+		return productTaskOutputInfo, !cfg.NoPOM
+	}
+	var pomDistIDs []distgo.DistID
 	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
-		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
-			downloadsListURLString := strings.Join([]string{cfg.URL, "file_metadata", cfg.Subject, cfg.Repository, mavenProductPath, path.Base(currArtifactPath)}, "/")
-			if err := p.runBintrayCommand(downloadsListURLString, http.MethodPut, cfg.Username, cfg.Password, `{"list_in_downloads":true}`, "adding artifact to Bintray downloads list for package", dryRun, stdout); err != nil {
-				return err
-			}
+		noPOM, ok := cfg.DistNoPOM[currDistID]
+		if !ok {
+			noPOM = cfg.NoPOM
+		}
+		if !noPOM {
+			pomDistIDs = append(pomDistIDs, currDistID)
+		}
+	}
+	if len(pomDistIDs) == 0 {
+		return productTaskOutputInfo, false
+	}
+	distOutputInfos := *productTaskOutputInfo.Product.DistOutputInfos
+	distOutputInfos.DistIDs = pomDistIDs
+	productTaskOutputInfo.Product.DistOutputInfos = &distOutputInfos
+	return productTaskOutputInfo, true
+}
+
+// renderTemplateValues renders the provided values as templates that can use the "{{Product}}" and "{{Version}}"
+// functions and replaces each value with its rendered form. The arguments after productTaskOutputInfo must be pairs of
+// a distgo.PublisherFlag (used to identify the value in errors) and a *string.
+func renderTemplateValues(productTaskOutputInfo distgo.ProductTaskOutputInfo, flagsAndVals ...interface{}) error {
+	for i := 0; i < len(flagsAndVals); i += 2 {
+		flag := flagsAndVals[i].(distgo.PublisherFlag)
+		valPtr := flagsAndVals[i+1].(*string)
+		rendered, err := distgo.RenderTemplate(*valPtr, nil,
+			distgo.ProductTemplateFunction(productTaskOutputInfo.Product.ID),
+			distgo.VersionTemplateFunction(productTaskOutputInfo.Project.Version),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to render %s", flag.Name)
+		}
+		*valPtr = rendered
+	}
+	return nil
+}
+
+// versionContentURL returns the URL of the content of the version of the Bintray product that is the destination of
+// the publish.
+func versionContentURL(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfg config.Bintray) string {
+	return strings.Join([]string{cfg.URL, "content", cfg.Subject, cfg.Repository, cfg.Product, productTaskOutputInfo.Project.Version}, "/")
+}
+
+func (p *bintrayPublisher) publish(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfg config.Bintray, dryRun bool, stdout io.Writer) error {
+	publishURLString := versionContentURL(productTaskOutputInfo, cfg) + "/publish"
+	return p.runBintrayCommand(publishURLString, http.MethodPost, cfg.BasicConnectionInfo, `{"publish_wait_for_secs":-1}`, "running Bintray publish for uploaded artifacts", dryRun, stdout)
+}
+
+// deleteVersion deletes the provided version of the Bintray product, which also deletes all of the files in the
+// version.
+func (p *bintrayPublisher) deleteVersion(version string, cfg config.Bintray, dryRun bool, stdout io.Writer) error {
+	deleteURLString := strings.Join([]string{cfg.URL, "packages", cfg.Subject, cfg.Repository, cfg.Product, "versions", version}, "/")
+	return p.runBintrayCommand(deleteURLString, http.MethodDelete, cfg.BasicConnectionInfo, "", fmt.Sprintf("deleting version %s of Bintray package %s", version, cfg.Product), dryRun, stdout)
+}
+
+// addToDownloadsList adds each of the provided uploaded artifacts to the downloads list of the Bintray package. An
+// attempt is made to add every artifact even if adding a previous artifact failed: the returned error describes all of
+// the failures.
+func (p *bintrayPublisher) addToDownloadsList(artifactPaths []string, cfg config.Bintray, mavenProductPath string, dryRun bool, stdout io.Writer) error {
+	var errMsgs []string
+	for _, currArtifactPath := range artifactPaths {
+		downloadsListURLString := strings.Join([]string{cfg.URL, "file_metadata", cfg.Subject, cfg.Repository, mavenProductPath, path.Base(currArtifactPath)}, "/")
+		if err := p.runBintrayCommand(downloadsListURLString, http.MethodPut, cfg.BasicConnectionInfo, `{"list_in_downloads":true}`, "adding artifact to Bintray downloads list for package", dryRun, stdout); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", path.Base(currArtifactPath), err))
 		}
 	}
+	if len(errMsgs) > 0 {
+		return errors.Errorf("%d of %d artifacts could not be added to downloads list:\n%s", len(errMsgs), len(artifactPaths), strings.Join(errMsgs, "\n"))
+	}
 	return nil
 }
 
-func (p *bintrayPublisher) runBintrayCommand(urlString, httpMethod, username, password, jsonContent, cmdMsg string, dryRun bool, stdout io.Writer) (rErr error) {
+func (p *bintrayPublisher) runBintrayCommand(urlString, httpMethod string, connInfo publisher.BasicConnectionInfo, jsonContent, cmdMsg string, dryRun bool, stdout io.Writer) (rErr error) {
 	url, err := url.Parse(urlString)
 	if err != nil {
 		return errors.Wrapf(err, "failed to parse %s as URL", urlString)
@@ -195,9 +314,13 @@ func (p *bintrayPublisher) runBintrayCommand(urlString, httpMethod, username, pa
 			Body:          ioutil.NopCloser(reader),
 			ContentLength: int64(len([]byte(jsonContent))),
 		}
-		req.SetBasicAuth(username, password)
+		connInfo.AuthorizeRequest(&req)
 
-		resp, err := http.DefaultClient.Do(&req)
+		client, err := connInfo.HTTPClient()
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(&req)
 		if err != nil {
 			return errors.Wrapf(err, "%s", cmdMsg)
 		}
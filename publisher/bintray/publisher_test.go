@@ -0,0 +1,570 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bintray_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher/bintray"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBintrayPublishDryRunMakesNoRequests(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: "/project",
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistArtifactNames:  []string{"foo-1.0.0-linux-amd64.tgz"},
+						PackagingExtension: "tgz",
+					},
+				},
+			},
+		},
+	}
+	cfgYML := `
+url: ` + server.URL + `
+username: testUsername
+password: testPassword
+subject: testSubject
+repository: testRepo
+publish: true
+downloads-list: true
+`
+	buf := &bytes.Buffer{}
+	err := bintray.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), map[distgo.PublisherFlagName]interface{}{
+		"group-id": "com.test.group",
+	}, true, buf)
+	require.NoError(t, err, buf.String())
+	assert.Empty(t, requests)
+
+	output := buf.String()
+	baseURL := server.URL + "/content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0"
+	assert.Contains(t, output, "to "+baseURL+"/foo-1.0.0-linux-amd64.tgz\n[DRY RUN]   Authorization: Basic [REDACTED]\n")
+	assert.Contains(t, output, "[DRY RUN] Uploading to "+baseURL+"/foo-1.0.0.pom\n[DRY RUN]   Authorization: Basic [REDACTED]\n")
+	assert.Contains(t, output, "[DRY RUN] Running Bintray publish for uploaded artifacts...done\n")
+	assert.Contains(t, output, "[DRY RUN] Adding artifact to Bintray downloads list for package...done\n")
+}
+
+func TestBintrayPublishRendersTemplates(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	productTaskOutputInfo := writeTestArtifact(t, tmp)
+	for i, tc := range []struct {
+		subject    string
+		repository string
+		product    string
+		wantPaths  []string
+	}{
+		{
+			subject:    "testSubject",
+			repository: "testRepo",
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"POST /content/testSubject/testRepo/foo/1.0.0/publish",
+			},
+		},
+		{
+			subject:    "{{Product}}-subject",
+			repository: "testRepo",
+			wantPaths: []string{
+				"PUT /content/foo-subject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"POST /content/foo-subject/testRepo/foo/1.0.0/publish",
+			},
+		},
+		{
+			subject:    "testSubject",
+			repository: "releases-{{Product}}",
+			wantPaths: []string{
+				"PUT /content/testSubject/releases-foo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"POST /content/testSubject/releases-foo/foo/1.0.0/publish",
+			},
+		},
+		{
+			subject:    "testSubject",
+			repository: "testRepo",
+			product:    "{{Product}}-v{{Version}}",
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo-v1.0.0/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"POST /content/testSubject/testRepo/foo-v1.0.0/1.0.0/publish",
+			},
+		},
+	} {
+		var requests []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}))
+
+		cfgYML := `
+url: ` + server.URL + `
+username: testUsername
+password: testPassword
+skip-upload-verification: true
+subject: "` + tc.subject + `"
+repository: "` + tc.repository + `"
+product: "` + tc.product + `"
+publish: true
+no-pom: true
+`
+		buf := &bytes.Buffer{}
+		err := bintray.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), map[distgo.PublisherFlagName]interface{}{
+			"group-id": "com.test.group",
+		}, false, buf)
+		server.Close()
+		require.NoError(t, err, "Case %d: %s", i, buf.String())
+		assert.Equal(t, tc.wantPaths, requests, "Case %d", i)
+	}
+}
+
+func TestBintrayPublishInvalidTemplate(t *testing.T) {
+	cfgYML := `
+url: http://localhost
+subject: testSubject
+repository: "{{Repo}}"
+`
+	err := bintray.PublisherCreator().Publisher().RunPublish(distgo.ProductTaskOutputInfo{}, []byte(cfgYML), map[distgo.PublisherFlagName]interface{}{
+		"group-id": "com.test.group",
+	}, true, ioutil.Discard)
+	assert.EqualError(t, err, `failed to render repository: failed to parse template {{Repo}}: template: distgoTemplate:1: function "Repo" not defined`)
+}
+
+func TestBintrayPublishFalseDoesNotPublish(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	productTaskOutputInfo := writeTestArtifact(t, tmp)
+
+	for i, tc := range []struct {
+		publishCfg string
+		wantPaths  []string
+	}{
+		{
+			publishCfg: "publish: false\n",
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0.pom",
+				"PUT /file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+			},
+		},
+		{
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0.pom",
+				"PUT /file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+			},
+		},
+	} {
+		var requests []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}))
+
+		cfgYML := `
+url: ` + server.URL + `
+username: testUsername
+password: testPassword
+skip-upload-verification: true
+subject: testSubject
+repository: testRepo
+downloads-list: true
+` + tc.publishCfg
+		buf := &bytes.Buffer{}
+		err := bintray.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), map[distgo.PublisherFlagName]interface{}{
+			"group-id": "com.test.group",
+		}, false, buf)
+		server.Close()
+		require.NoError(t, err, "Case %d: %s", i, buf.String())
+		assert.Equal(t, tc.wantPaths, requests, "Case %d", i)
+		assert.Contains(t, buf.String(), "Uploaded artifacts are staged in unpublished version 1.0.0 of foo at "+server.URL+"/content/testSubject/testRepo/foo/1.0.0\n", "Case %d", i)
+		assert.NotContains(t, buf.String(), "Running Bintray publish", "Case %d", i)
+	}
+}
+
+func TestBintrayPublishDownloadsList(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"darwin", "linux"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"darwin": {
+						DistArtifactNames:  []string{"foo-1.0.0-darwin-amd64.tgz"},
+						PackagingExtension: "tgz",
+					},
+					"linux": {
+						DistArtifactNames:  []string{"foo-1.0.0-linux-amd64.tgz"},
+						PackagingExtension: "tgz",
+					},
+				},
+			},
+		},
+	}
+	for distID, artifactPaths := range productTaskOutputInfo.ProductDistArtifactPaths() {
+		for _, artifactPath := range artifactPaths {
+			require.NoError(t, os.MkdirAll(path.Dir(artifactPath), 0755), "dist %s", distID)
+			require.NoError(t, ioutil.WriteFile(artifactPath, []byte("content"), 0644), "dist %s", distID)
+		}
+	}
+
+	for i, tc := range []struct {
+		name          string
+		downloadsList bool
+		failingPath   string
+		wantPaths     []string
+		wantErr       string
+	}{
+		{
+			name:          "downloads list is updated for every artifact",
+			downloadsList: true,
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz",
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"PUT /file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz",
+				"PUT /file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+			},
+		},
+		{
+			name:          "downloads list is not updated if downloads-list is false",
+			downloadsList: false,
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz",
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+			},
+		},
+		{
+			name:          "failure to update downloads list returns error after upload succeeds",
+			downloadsList: true,
+			failingPath:   "/file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz",
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz",
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"PUT /file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-darwin-amd64.tgz",
+				"PUT /file_metadata/testSubject/testRepo/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+			},
+			wantErr: "uploading artifacts succeeded, but adding artifacts to downloads list failed: 1 of 2 artifacts could not be added to downloads list:\nfoo-1.0.0-darwin-amd64.tgz: adding artifact to Bintray downloads list for package resulted in response: 500 Internal Server Error",
+		},
+	} {
+		var requests []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if r.URL.Path == tc.failingPath {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		cfgYML := fmt.Sprintf(`
+url: %s
+username: testUsername
+password: testPassword
+skip-upload-verification: true
+upload-workers: 1
+subject: testSubject
+repository: testRepo
+no-pom: true
+downloads-list: %v
+`, server.URL, tc.downloadsList)
+		buf := &bytes.Buffer{}
+		err := bintray.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), map[distgo.PublisherFlagName]interface{}{
+			"group-id": "com.test.group",
+		}, false, buf)
+		server.Close()
+		if tc.wantErr == "" {
+			require.NoError(t, err, "Case %d: %s\n%s", i, tc.name, buf.String())
+		} else {
+			assert.EqualError(t, err, tc.wantErr, "Case %d: %s", i, tc.name)
+		}
+		assert.Equal(t, tc.wantPaths, requests, "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestBintrayPublishDistNoPOM(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"bin", "jar"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"bin": {
+						DistArtifactNames:  []string{"foo-1.0.0-linux-amd64.tgz"},
+						PackagingExtension: "tgz",
+					},
+					"jar": {
+						DistArtifactNames:  []string{"foo-1.0.0.jar"},
+						PackagingExtension: "jar",
+					},
+				},
+			},
+		},
+	}
+	for distID, artifactPaths := range productTaskOutputInfo.ProductDistArtifactPaths() {
+		for _, artifactPath := range artifactPaths {
+			require.NoError(t, os.MkdirAll(path.Dir(artifactPath), 0755), "dist %s", distID)
+			require.NoError(t, ioutil.WriteFile(artifactPath, []byte("content"), 0644), "dist %s", distID)
+		}
+	}
+
+	for i, tc := range []struct {
+		name           string
+		pomCfg         string
+		wantPOM        string
+		wantPOMUpload  bool
+		wantErrContain string
+	}{
+		{
+			name: "POM is generated only for dist that is not excluded",
+			pomCfg: `dist-no-pom:
+  bin: true
+`,
+			wantPOM:       "<packaging>jar</packaging>",
+			wantPOMUpload: true,
+		},
+		{
+			name: "dist can opt in to POM generation when NoPOM is true",
+			pomCfg: `no-pom: true
+dist-no-pom:
+  jar: false
+`,
+			wantPOM:       "<packaging>jar</packaging>",
+			wantPOMUpload: true,
+		},
+		{
+			name: "POM is rendered using configured template",
+			pomCfg: `dist-no-pom:
+  bin: true
+pom-template: |
+  <project>
+    <groupId>{{GroupID}}</groupId>
+    <artifactId>{{Product}}</artifactId>
+    <version>{{Version}}</version>
+    <description>{{Description}}</description>
+  </project>
+pom-description: Foo & Bar
+`,
+			wantPOM:       "<groupId>com.test.group</groupId>\n  <artifactId>foo</artifactId>\n  <version>1.0.0</version>\n  <description>Foo &amp; Bar</description>",
+			wantPOMUpload: true,
+		},
+		{
+			name: "POM template that renders malformed XML fails",
+			pomCfg: `dist-no-pom:
+  bin: true
+pom-template: <project><groupId>{{GroupID}}</project>
+`,
+			wantErrContain: "invalid POM foo-1.0.0.pom: POM is not well-formed XML",
+		},
+		{
+			name: "POM is not generated if all dists are excluded",
+			pomCfg: `dist-no-pom:
+  bin: true
+  jar: true
+`,
+		},
+		{
+			name:           "POM generation fails for dists with different packaging if neither is excluded",
+			wantErrContain: "product foo has dists with different packaging extensions",
+		},
+	} {
+		var requests []string
+		var pomContent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if path.Ext(r.URL.Path) == ".pom" {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				pomContent = string(body)
+			}
+		}))
+
+		cfgYML := `
+url: ` + server.URL + `
+username: testUsername
+password: testPassword
+skip-upload-verification: true
+subject: testSubject
+repository: testRepo
+` + tc.pomCfg
+		buf := &bytes.Buffer{}
+		err := bintray.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), map[distgo.PublisherFlagName]interface{}{
+			"group-id": "com.test.group",
+		}, false, buf)
+		server.Close()
+		if tc.wantErrContain != "" {
+			require.Error(t, err, "Case %d: %s", i, tc.name)
+			assert.Contains(t, err.Error(), tc.wantErrContain, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s\n%s", i, tc.name, buf.String())
+
+		pomPath := "PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0.pom"
+		if tc.wantPOMUpload {
+			assert.Contains(t, requests, pomPath, "Case %d: %s", i, tc.name)
+			assert.Contains(t, pomContent, tc.wantPOM, "Case %d: %s", i, tc.name)
+		} else {
+			assert.NotContains(t, requests, pomPath, "Case %d: %s", i, tc.name)
+		}
+	}
+}
+
+func TestBintrayPublishDeleteVersion(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	productTaskOutputInfo := writeTestArtifact(t, tmp)
+
+	for i, tc := range []struct {
+		name      string
+		flagVals  map[distgo.PublisherFlagName]interface{}
+		wantPaths []string
+		wantError string
+	}{
+		{
+			name: "version is deleted after publish",
+			flagVals: map[distgo.PublisherFlagName]interface{}{
+				"delete-version": "1.0.0-rc1",
+				"confirm-delete": true,
+			},
+			wantPaths: []string{
+				"PUT /content/testSubject/testRepo/foo/1.0.0/com/test/group/foo/1.0.0/foo-1.0.0-linux-amd64.tgz",
+				"POST /content/testSubject/testRepo/foo/1.0.0/publish",
+				"DELETE /packages/testSubject/testRepo/foo/versions/1.0.0-rc1",
+			},
+		},
+		{
+			name: "delete is refused without confirmation",
+			flagVals: map[distgo.PublisherFlagName]interface{}{
+				"delete-version": "1.0.0-rc1",
+			},
+			wantError: "delete-version was specified without confirm-delete: refusing to delete version 1.0.0-rc1",
+		},
+		{
+			name: "delete of version being published is refused",
+			flagVals: map[distgo.PublisherFlagName]interface{}{
+				"delete-version": "1.0.0",
+				"confirm-delete": true,
+			},
+			wantError: "refusing to delete version 1.0.0 because it is the version being published",
+		},
+	} {
+		var requests []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}))
+
+		cfgYML := `
+url: ` + server.URL + `
+username: testUsername
+password: testPassword
+skip-upload-verification: true
+subject: testSubject
+repository: testRepo
+publish: true
+no-pom: true
+`
+		flagVals := map[distgo.PublisherFlagName]interface{}{
+			"group-id": "com.test.group",
+		}
+		for k, v := range tc.flagVals {
+			flagVals[k] = v
+		}
+		buf := &bytes.Buffer{}
+		err := bintray.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), flagVals, false, buf)
+		server.Close()
+		if tc.wantError != "" {
+			assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			// nothing is uploaded or deleted if the delete is refused
+			assert.Empty(t, requests, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s: %s", i, tc.name, buf.String())
+		assert.Equal(t, tc.wantPaths, requests, "Case %d: %s", i, tc.name)
+	}
+}
+
+func writeTestArtifact(t *testing.T, projectDir string) distgo.ProductTaskOutputInfo {
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: projectDir,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistArtifactNames:  []string{"foo-1.0.0-linux-amd64.tgz"},
+						PackagingExtension: "tgz",
+					},
+				},
+			},
+		},
+	}
+	artifactPath := path.Join(projectDir, "out/dist/foo/1.0.0/os-arch-bin/foo-1.0.0-linux-amd64.tgz")
+	require.NoError(t, os.MkdirAll(path.Dir(artifactPath), 0755))
+	require.NoError(t, ioutil.WriteFile(artifactPath, []byte("content"), 0644))
+	return productTaskOutputInfo
+}
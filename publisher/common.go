@@ -30,16 +30,22 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/palantir/distgo/distgo"
 	"github.com/pkg/errors"
-	"gopkg.in/cheggaaa/pb.v1"
 )
 
 type FileInfo struct {
 	Path      string
 	Bytes     []byte
 	Checksums Checksums
+
+	// contentPath is the path of the file from which the content is read if the FileInfo was created using
+	// NewFileInfoFromPath, in which case Bytes is nil.
+	contentPath string
+	size        int64
 }
 
 func NewFileInfo(pathToFile string) (FileInfo, error) {
@@ -50,6 +56,55 @@ func NewFileInfo(pathToFile string) (FileInfo, error) {
 	return NewFileInfoFromBytes(bytes), nil
 }
 
+// NewFileInfoFromPath returns a FileInfo for the file at the provided path whose content is not held in memory: the
+// checksums are computed by reading the file and the content is read from the file again when it is uploaded. Bytes
+// is nil for the returned FileInfo, so it can only be used with functions that read the content using Open.
+func NewFileInfoFromPath(pathToFile string) (FileInfo, error) {
+	f, err := os.Open(pathToFile)
+	if err != nil {
+		return FileInfo{}, errors.Wrapf(err, "failed to read file %s", pathToFile)
+	}
+	defer func() {
+		// file is only read, so nothing to be done if close fails
+		_ = f.Close()
+	}()
+
+	sha1Hash, sha256Hash, md5Hash := sha1.New(), sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(sha1Hash, sha256Hash, md5Hash), f)
+	if err != nil {
+		return FileInfo{}, errors.Wrapf(err, "failed to read file %s", pathToFile)
+	}
+	return FileInfo{
+		Checksums: Checksums{
+			SHA1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+			SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+			MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		},
+		contentPath: pathToFile,
+		size:        size,
+	}, nil
+}
+
+// Size returns the size of the content of the file in bytes.
+func (f FileInfo) Size() int64 {
+	if f.contentPath == "" {
+		return int64(len(f.Bytes))
+	}
+	return f.size
+}
+
+// Open returns a reader for the content of the file. The caller must close the returned reader.
+func (f FileInfo) Open() (io.ReadCloser, error) {
+	if f.contentPath == "" {
+		return ioutil.NopCloser(bytes.NewReader(f.Bytes)), nil
+	}
+	file, err := os.Open(f.contentPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open file %s", f.contentPath)
+	}
+	return file, nil
+}
+
 func NewFileInfoFromBytes(bytes []byte) FileInfo {
 	sha1Bytes := sha1.Sum(bytes)
 	sha256Bytes := sha256.Sum256(bytes)
@@ -131,8 +186,64 @@ type BasicConnectionInfo struct {
 	URL      string `yaml:"url,omitempty"`
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
+
+	// UploadRetries is the number of times an upload is retried if it fails with an error that may be transient: a
+	// network error, a 5xx response or a 429 response. If not specified, defaults to 0.
+	UploadRetries int `yaml:"upload-retries,omitempty"`
+
+	// UploadRetryBackoff is the duration to wait before the first retry of an upload (for example, "5s"). The duration
+	// doubles for every subsequent retry. If the server responds with a Retry-After header, the duration specified by
+	// the header is used instead. If not specified, defaults to "1s".
+	UploadRetryBackoff string `yaml:"upload-retry-backoff,omitempty"`
+
+	// UploadWorkers is the maximum number of dist artifacts that are uploaded concurrently. If not specified, defaults
+	// to DefaultUploadWorkers.
+	UploadWorkers int `yaml:"upload-workers,omitempty"`
+
+	// SkipUploadVerification specifies whether verification of uploaded artifacts should be skipped. If false, a HEAD
+	// request is sent for every uploaded artifact after the upload completes and the upload fails if the checksum or
+	// size reported by the registry does not match the local file. Set to true for registries that do not support
+	// HEAD requests for uploaded artifacts. If not specified, defaults to false.
+	SkipUploadVerification bool `yaml:"skip-upload-verification,omitempty"`
+
+	// Proxy is the URL of the proxy through which requests are sent (for example, "http://proxy.domain.com:8080"). If
+	// not specified, the proxy is determined using the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	// Hosts that match an entry in NO_PROXY are not sent through the proxy even if this value is specified.
+	Proxy string `yaml:"proxy,omitempty"`
+
+	// ConnectTimeout is the maximum duration for establishing a connection to the server, including the TLS handshake
+	// (for example, "10s"). If not specified, defaults to "30s".
+	ConnectTimeout string `yaml:"connect-timeout,omitempty"`
+
+	// RequestTimeout is the maximum duration of a single request, including the upload of the artifact (for example,
+	// "30m"). Increase this value when uploading large artifacts over slow connections. An upload that times out is
+	// retried if UploadRetries is non-zero. If not specified, defaults to "10m".
+	RequestTimeout string `yaml:"request-timeout,omitempty"`
+
+	// Progress specifies how the progress of uploads is reported: "bar" (an interactive progress bar), "log" (a line
+	// with the percentage and throughput is printed every ProgressInterval, which is suitable for CI logs) or "none"
+	// (progress is not reported). If not specified, defaults to "bar". If more than one artifact is uploaded at a time,
+	// "bar" is treated as "log" because the progress bars of concurrent uploads would overwrite each other.
+	Progress string `yaml:"progress,omitempty"`
+
+	// ProgressInterval is the interval at which progress is printed when Progress is "log" (for example, "30s"). If not
+	// specified, defaults to "5s".
+	ProgressInterval string `yaml:"progress-interval,omitempty"`
+
+	// ContentTypes overrides the Content-Type that is set on uploads based on the extension of the uploaded file. The
+	// key is the extension (for example, ".tgz") and the value is the Content-Type. Extensions that are not specified
+	// use the Content-Type inferred by the ContentType function.
+	ContentTypes map[string]string `yaml:"content-types,omitempty"`
+
+	// Header specifies additional headers that are set on every request made using the BasicConnectionInfo. It is not
+	// part of the configuration: publishers set it to provide headers that are specific to a registry.
+	Header http.Header `yaml:"-"`
 }
 
+// DefaultUploadWorkers is the maximum number of dist artifacts that are uploaded concurrently if UploadWorkers is not
+// specified.
+const DefaultUploadWorkers = 4
+
 func (b *BasicConnectionInfo) SetValuesFromFlags(flagVals map[distgo.PublisherFlagName]interface{}) error {
 	if err := SetRequiredStringConfigValue(flagVals, ConnectionInfoURLFlag, &b.URL); err != nil {
 		return err
@@ -143,34 +254,142 @@ func (b *BasicConnectionInfo) SetValuesFromFlags(flagVals map[distgo.PublisherFl
 	return SetConfigValue(flagVals, ConnectionInfoPasswordFlag, &b.Password)
 }
 
+// UploadRequestFunc returns the method, the URL and the additional headers of the request that uploads the artifact
+// with the provided name.
+type UploadRequestFunc func(artifactName string) (method, rawUploadURL string, header http.Header, err error)
+
+// PutRequestFunc returns an UploadRequestFunc for PUT requests that upload each artifact to "<baseURL>/<artifactName>".
+func PutRequestFunc(baseURL string) UploadRequestFunc {
+	return func(artifactName string) (string, string, http.Header, error) {
+		return http.MethodPut, strings.Join([]string{baseURL, artifactName}, "/"), nil, nil
+	}
+}
+
+// UploadDistArtifacts uploads all of the dist artifacts of the provided product to the provided base URL. Up to
+// UploadWorkers artifacts are uploaded concurrently. If more than one artifact is uploaded at a time, the output of
+// each upload is written to stdout as it is produced with every line prefixed by the name of the artifact so that the
+// output of concurrent uploads can be told apart, and progress is reported using ProgressLog rather than progress
+// bars. The content of artifacts is read from disk as it is uploaded rather
+// than held in memory. Artifacts are uploaded one at a time in a dry run so that the output is in the order of the
+// artifacts. If any upload fails, the other uploads are still performed and the returned error describes all of the
+// failures.
 func (b *BasicConnectionInfo) UploadDistArtifacts(productTaskOutputInfo distgo.ProductTaskOutputInfo, baseURL string, artifactExists ArtifactExistsFunc, dryRun bool, stdout io.Writer) (artifactPaths []string, uploadedURLs []string, rErr error) {
+	return b.UploadDistArtifactsWithRequests(productTaskOutputInfo, PutRequestFunc(baseURL), artifactExists, dryRun, stdout)
+}
+
+// UploadDistArtifactsWithRequests uploads all of the dist artifacts of the provided product using the requests returned
+// by the provided function. Other than the request, uploads are performed in the same manner as UploadDistArtifacts.
+func (b *BasicConnectionInfo) UploadDistArtifactsWithRequests(productTaskOutputInfo distgo.ProductTaskOutputInfo, uploadRequest UploadRequestFunc, artifactExists ArtifactExistsFunc, dryRun bool, stdout io.Writer) (artifactPaths []string, uploadedURLs []string, rErr error) {
 	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
-		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
-			artifactPaths = append(artifactPaths, currArtifactPath)
-			var fi FileInfo
-			if !dryRun {
-				var err error
-				fi, err = NewFileInfo(currArtifactPath)
-				if err != nil {
-					return nil, nil, err
-				}
-			} else {
-				fi = FileInfo{
-					Path: currArtifactPath,
+		artifactPaths = append(artifactPaths, productTaskOutputInfo.ProductDistArtifactPaths()[currDistID]...)
+	}
+
+	type uploadResult struct {
+		url  string
+		err  error
+		done chan struct{}
+	}
+	results := make([]*uploadResult, len(artifactPaths))
+	for i := range results {
+		results[i] = &uploadResult{
+			done: make(chan struct{}),
+		}
+	}
+
+	workers := b.UploadWorkers
+	if workers <= 0 {
+		workers = DefaultUploadWorkers
+	}
+	if workers > len(artifactPaths) {
+		workers = len(artifactPaths)
+	}
+	if dryRun {
+		workers = 1
+	}
+	uploader := b
+	if workers > 1 && (b.Progress == "" || b.Progress == ProgressBar) {
+		// the progress bars of concurrent uploads would be redrawn over each other, so progress is logged with the name
+		// of the artifact instead
+		connInfo := *b
+		connInfo.Progress = ProgressLog
+		uploader = &connInfo
+	}
+	var stdoutMu sync.Mutex
+	jobs := make(chan int)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for idx := range jobs {
+				result := results[idx]
+				if workers == 1 {
+					result.url, result.err = uploader.uploadArtifact(artifactPaths[idx], uploadRequest, artifactExists, dryRun, stdout)
+				} else {
+					output := newPrefixWriter(stdout, &stdoutMu, fmt.Sprintf("[%s] ", path.Base(artifactPaths[idx])))
+					result.url, result.err = uploader.uploadArtifact(artifactPaths[idx], uploadRequest, artifactExists, dryRun, output)
+					output.Flush()
 				}
+				close(result.done)
 			}
-			uploadURL, err := b.UploadFile(fi, baseURL, path.Base(currArtifactPath), artifactExists, dryRun, stdout)
-			if err != nil {
-				return nil, nil, err
-			}
-			uploadedURLs = append(uploadedURLs, uploadURL)
+		}()
+	}
+	go func() {
+		for i := range artifactPaths {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	var errs []error
+	for _, result := range results {
+		<-result.done
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		uploadedURLs = append(uploadedURLs, result.url)
+	}
+	switch len(errs) {
+	case 0:
+		return artifactPaths, uploadedURLs, nil
+	case 1:
+		return nil, nil, errs[0]
+	default:
+		var errMsgs []string
+		for _, err := range errs {
+			errMsgs = append(errMsgs, err.Error())
+		}
+		return nil, nil, errors.Errorf("%d of %d uploads failed:\n%s", len(errs), len(artifactPaths), strings.Join(errMsgs, "\n"))
+	}
+}
+
+func (b *BasicConnectionInfo) uploadArtifact(artifactPath string, uploadRequest UploadRequestFunc, artifactExists ArtifactExistsFunc, dryRun bool, stdout io.Writer) (string, error) {
+	var fi FileInfo
+	if !dryRun {
+		var err error
+		fi, err = NewFileInfoFromPath(artifactPath)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		fi = FileInfo{
+			Path: artifactPath,
 		}
 	}
-	return artifactPaths, uploadedURLs, nil
+	return b.uploadFile(fi, path.Base(artifactPath), uploadRequest, artifactExists, dryRun, stdout)
 }
 
 func (b *BasicConnectionInfo) UploadFile(fileInfo FileInfo, baseURL, artifactName string, artifactExists ArtifactExistsFunc, dryRun bool, stdout io.Writer) (rURL string, rErr error) {
-	rawUploadURL := strings.Join([]string{baseURL, artifactName}, "/")
+	return b.uploadFile(fileInfo, artifactName, PutRequestFunc(baseURL), artifactExists, dryRun, stdout)
+}
+
+// uploadFile uploads the provided file using the request returned by uploadRequest for the provided artifact name. In a
+// dry run, the method of the request is printed if it is not PUT. The upload is only verified if the method is PUT
+// because the URL of a request that uses another method (such as POST) is not necessarily the location of the
+// uploaded artifact.
+func (b *BasicConnectionInfo) uploadFile(fileInfo FileInfo, artifactName string, uploadRequest UploadRequestFunc, artifactExists ArtifactExistsFunc, dryRun bool, stdout io.Writer) (rURL string, rErr error) {
+	method, rawUploadURL, reqHeader, err := uploadRequest(artifactName)
+	if err != nil {
+		return rawUploadURL, err
+	}
 
 	filePath := fileInfo.Path
 	if filePath != "" {
@@ -203,62 +422,131 @@ func (b *BasicConnectionInfo) UploadFile(fileInfo FileInfo, baseURL, artifactNam
 	}
 	uploadMsgParts = append(uploadMsgParts, "to", rawUploadURL)
 	distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf(strings.Join(uploadMsgParts, " ")), dryRun)
+	if dryRun {
+		if method != http.MethodPut {
+			distgo.DryRunPrintln(stdout, fmt.Sprintf("  %s %s", method, rawUploadURL))
+		}
+		// the content of artifacts is not read in a dry run, so the checksum headers are not known
+		req := http.Request{Header: copyHeader(reqHeader)}
+		b.AuthorizeRequest(&req)
+		DryRunPrintlnHeaders(stdout, req.Header)
+	}
 
 	if !dryRun {
-		header := http.Header{}
-		addChecksumToHeader(header, "Md5", fileInfo.Checksums.MD5)
-		addChecksumToHeader(header, "Sha1", fileInfo.Checksums.SHA1)
-		addChecksumToHeader(header, "Sha256", fileInfo.Checksums.SHA256)
-
-		bar := pb.New(len(fileInfo.Bytes)).SetUnits(pb.U_BYTES)
-		bar.Output = stdout
-		bar.SetMaxWidth(120)
-		bar.Start()
-		defer bar.Finish()
-		reader := bar.NewProxyReader(bytes.NewReader(fileInfo.Bytes))
-
-		req := http.Request{
-			Method:        http.MethodPut,
-			URL:           uploadURL,
-			Header:        header,
-			Body:          ioutil.NopCloser(reader),
-			ContentLength: int64(len(fileInfo.Bytes)),
+		if err := b.RunWithUploadRetries(stdout, fmt.Sprintf("Upload to %s", rawUploadURL), func() (bool, time.Duration, error) {
+			return b.sendFile(fileInfo, artifactName, method, uploadURL, reqHeader, filePath, rawUploadURL, stdout)
+		}); err != nil {
+			return rawUploadURL, err
 		}
-		req.SetBasicAuth(b.Username, b.Password)
-
-		resp, err := http.DefaultClient.Do(&req)
-		if err != nil {
-			errMsgParts := []string{"failed to upload"}
-			if filePath != "" {
-				errMsgParts = append(errMsgParts, filePath)
+		if method == http.MethodPut && !b.SkipUploadVerification {
+			if err := b.verifyUpload(fileInfo, uploadURL, reqHeader, rawUploadURL, stdout); err != nil {
+				return rawUploadURL, err
 			}
-			errMsgParts = append(errMsgParts, "to", rawUploadURL)
-			return rawUploadURL, errors.Wrapf(err, strings.Join(errMsgParts, " "))
 		}
-		defer func() {
-			if err := resp.Body.Close(); err != nil && rErr == nil {
-				rErr = errors.Wrapf(err, "failed to close response body for URL %s", rawUploadURL)
-			}
-		}()
+	}
+	return rawUploadURL, nil
+}
 
-		if resp.StatusCode >= http.StatusBadRequest {
-			msgParts := []string{"uploading"}
-			if filePath != "" {
-				msgParts = append(msgParts, filePath)
-			}
-			msgParts = append(msgParts, fmt.Sprintf("to %s resulted in response %q", rawUploadURL, resp.Status))
+// sendFile performs a single attempt of uploading the provided file to the provided URL using the provided method. The
+// Content-Type is inferred from the name of the artifact, and the provided header is set on the request over the
+// checksum and Content-Type headers. The upload succeeds only if the response has a 2xx status. Returns true if the
+// attempt failed with an error for which the upload can be retried along with the amount of time that the server
+// requested be waited before retrying (0 if the server did not specify a time). Failures are only retryable if the
+// method is idempotent (see IsIdempotentMethod), since retrying a request such as a POST whose outcome is unknown may
+// upload the artifact more than once.
+func (b *BasicConnectionInfo) sendFile(fileInfo FileInfo, artifactName, method string, uploadURL *url.URL, reqHeader http.Header, filePath, rawUploadURL string, stdout io.Writer) (rRetryable bool, rRetryAfter time.Duration, rErr error) {
+	header := http.Header{}
+	addChecksumToHeader(header, "Md5", fileInfo.Checksums.MD5)
+	addChecksumToHeader(header, "Sha1", fileInfo.Checksums.SHA1)
+	addChecksumToHeader(header, "Sha256", fileInfo.Checksums.SHA256)
+	header.Set("Content-Type", ContentType(artifactName, b.ContentTypes))
+	for k, vals := range reqHeader {
+		header[k] = append([]string(nil), vals...)
+	}
 
-			msg := fmt.Sprintf(strings.Join(msgParts, " "))
-			if body, err := ioutil.ReadAll(resp.Body); err == nil {
-				bodyStr := string(body)
-				if bodyStr != "" {
-					msg += ":\n" + bodyStr
-				}
+	content, err := fileInfo.Open()
+	if err != nil {
+		return false, 0, err
+	}
+	defer func() {
+		// content is only read, so nothing to be done if close fails
+		_ = content.Close()
+	}()
+	reader, finishProgress, err := b.UploadProgressReader(content, fileInfo.Size(), artifactName)
+	if err != nil {
+		return false, 0, err
+	}
+	defer finishProgress()
+
+	req := http.Request{
+		Method:        method,
+		URL:           uploadURL,
+		Header:        header,
+		Body:          ioutil.NopCloser(reader),
+		ContentLength: fileInfo.Size(),
+	}
+	b.AuthorizeRequest(&req)
+
+	client, err := b.HTTPClient()
+	if err != nil {
+		return false, 0, err
+	}
+	resp, err := client.Do(&req)
+	if err != nil {
+		errMsgParts := []string{"failed to upload"}
+		if filePath != "" {
+			errMsgParts = append(errMsgParts, filePath)
+		}
+		errMsgParts = append(errMsgParts, "to", rawUploadURL)
+		// the request may have been processed even though no response was received, so network errors are only
+		// retryable if the method is idempotent
+		return IsIdempotentMethod(method), 0, errors.Wrapf(err, strings.Join(errMsgParts, " "))
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for URL %s", rawUploadURL)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		msgParts := []string{"uploading"}
+		if filePath != "" {
+			msgParts = append(msgParts, filePath)
+		}
+		msgParts = append(msgParts, fmt.Sprintf("to %s resulted in response %q", rawUploadURL, resp.Status))
+
+		msg := fmt.Sprintf(strings.Join(msgParts, " "))
+		if body, err := ioutil.ReadAll(resp.Body); err == nil {
+			bodyStr := string(body)
+			if bodyStr != "" {
+				msg += ":\n" + bodyStr
 			}
-			return rawUploadURL, fmt.Errorf(msg)
 		}
+		return IsIdempotentMethod(method) && IsRetryableStatus(resp.StatusCode), RetryAfter(resp.Header), fmt.Errorf(msg)
 	}
-	return rawUploadURL, nil
+	return false, 0, nil
+}
+
+// AuthorizeRequest sets the headers in Header on the provided request. If a username or password is specified, the
+// request is also configured to use basic authentication.
+func (b *BasicConnectionInfo) AuthorizeRequest(req *http.Request) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for k, vals := range b.Header {
+		req.Header[k] = append([]string(nil), vals...)
+	}
+	if b.Username != "" || b.Password != "" {
+		req.SetBasicAuth(b.Username, b.Password)
+	}
+}
+
+func copyHeader(header http.Header) http.Header {
+	out := http.Header{}
+	for k, vals := range header {
+		out[k] = append([]string(nil), vals...)
+	}
+	return out
 }
 
 // ArtifactExistsFunc returns true if the specified file with the specified checksums already exists in the destination.
@@ -15,7 +15,20 @@
 package publisher_test
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/palantir/distgo/distgo"
 	"github.com/palantir/distgo/publisher"
@@ -117,3 +130,384 @@ func TestSetConfigValueFailsIfProvidedValNotPointer(t *testing.T) {
 	err := publisher.SetConfigValue(flagVals, flag, cfg.FooVal)
 	assert.EqualError(t, err, `configValPtr type "string" is not a pointer type`)
 }
+
+func TestUploadFileRetries(t *testing.T) {
+	for i, tc := range []struct {
+		name         string
+		statuses     []int
+		header       http.Header
+		retries      int
+		wantRequests int
+		wantError    string
+	}{
+		{
+			name:         "retries 503 until success",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			retries:      3,
+			wantRequests: 3,
+		},
+		{
+			name:         "retries 429 honoring Retry-After",
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			header:       http.Header{"Retry-After": []string{"0"}},
+			retries:      1,
+			wantRequests: 2,
+		},
+		{
+			name:         "does not retry 401",
+			statuses:     []int{http.StatusUnauthorized},
+			retries:      3,
+			wantRequests: 1,
+			wantError:    `resulted in response "401 Unauthorized"`,
+		},
+		{
+			name:         "does not retry if retries not configured",
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantRequests: 1,
+			wantError:    `resulted in response "503 Service Unavailable"`,
+		},
+		{
+			name:         "reports attempt count when retries are exhausted",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			retries:      2,
+			wantRequests: 3,
+			wantError:    `failed after 3 attempts: uploading to`,
+		},
+	} {
+		func() {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.statuses[requests]
+				requests++
+				for k, v := range tc.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			connInfo := publisher.BasicConnectionInfo{
+				URL:                    server.URL,
+				UploadRetries:          tc.retries,
+				UploadRetryBackoff:     "1ms",
+				SkipUploadVerification: true,
+			}
+			_, err := connInfo.UploadFile(publisher.NewFileInfoFromBytes([]byte("content")), server.URL, "artifact.tgz", nil, false, &bytes.Buffer{})
+			if tc.wantError == "" {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+			} else {
+				require.Error(t, err, "Case %d: %s", i, tc.name)
+				assert.Contains(t, err.Error(), tc.wantError, "Case %d: %s", i, tc.name)
+			}
+			assert.Equal(t, tc.wantRequests, requests, "Case %d: %s", i, tc.name)
+		}()
+	}
+}
+
+func TestUploadDistArtifactsConcurrently(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	var artifactNames []string
+	for i := 0; i < 10; i++ {
+		artifactNames = append(artifactNames, fmt.Sprintf("foo-1.0.0-%d.tgz", i))
+	}
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistArtifactNames: artifactNames,
+					},
+				},
+			},
+		},
+	}
+	for _, currPath := range productTaskOutputInfo.ProductDistArtifactPaths()["os-arch-bin"] {
+		require.NoError(t, os.MkdirAll(path.Dir(currPath), 0755))
+		require.NoError(t, ioutil.WriteFile(currPath, []byte(path.Base(currPath)), 0644))
+	}
+
+	const failingArtifact = "foo-1.0.0-3.tgz"
+	var mu sync.Mutex
+	var uploaded []string
+	active, maxActive := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		uploaded = append(uploaded, path.Base(r.URL.Path))
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		if path.Base(r.URL.Path) == failingArtifact {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	connInfo := publisher.BasicConnectionInfo{
+		URL:                    server.URL,
+		UploadWorkers:          3,
+		SkipUploadVerification: true,
+	}
+	buf := &bytes.Buffer{}
+	_, _, err = connInfo.UploadDistArtifacts(productTaskOutputInfo, server.URL+"/repo", nil, false, buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf(`uploading to %s/repo/%s resulted in response "400 Bad Request"`, server.URL, failingArtifact))
+
+	// all artifacts are uploaded even though one upload failed
+	sort.Strings(uploaded)
+	wantUploaded := append([]string{}, artifactNames...)
+	sort.Strings(wantUploaded)
+	assert.Equal(t, wantUploaded, uploaded)
+	assert.True(t, maxActive <= 3, "expected at most 3 concurrent uploads, was %d", maxActive)
+
+	// output of each upload is written as it is produced with every line prefixed by the name of the artifact
+	var uploadLines []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		assert.Regexp(t, `^\[foo-1\.0\.0-\d\.tgz\] `, line)
+		if strings.Contains(line, "] Uploading to ") {
+			uploadLines = append(uploadLines, line)
+		}
+	}
+	sort.Strings(uploadLines)
+	var wantLines []string
+	for _, artifactName := range artifactNames {
+		wantLines = append(wantLines, fmt.Sprintf("[%s] Uploading to %s/repo/%s", artifactName, server.URL, artifactName))
+	}
+	sort.Strings(wantLines)
+	assert.Equal(t, wantLines, uploadLines)
+}
+
+func TestUploadDistArtifactsConcurrentlyLogsProgress(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	var artifactNames []string
+	for i := 0; i < 5; i++ {
+		artifactNames = append(artifactNames, fmt.Sprintf("foo-1.0.0-%d.tgz", i))
+	}
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistArtifactNames: artifactNames,
+					},
+				},
+			},
+		},
+	}
+	for _, currPath := range productTaskOutputInfo.ProductDistArtifactPaths()["os-arch-bin"] {
+		require.NoError(t, os.MkdirAll(path.Dir(currPath), 0755))
+		require.NoError(t, ioutil.WriteFile(currPath, []byte(path.Base(currPath)), 0644))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	// progress mode is not specified, so it would be "bar" if uploads were not concurrent
+	connInfo := publisher.BasicConnectionInfo{
+		URL:                    server.URL,
+		UploadWorkers:          3,
+		SkipUploadVerification: true,
+	}
+	stderr := captureStderr(t, func() {
+		_, _, err = connInfo.UploadDistArtifacts(productTaskOutputInfo, server.URL+"/repo", nil, false, ioutil.Discard)
+	})
+	require.NoError(t, err)
+
+	// progress of every upload is logged on its own line rather than drawn as progress bars that overwrite each other
+	assert.NotContains(t, stderr, "\r")
+	for _, artifactName := range artifactNames {
+		assert.Contains(t, stderr, fmt.Sprintf("Uploading %s: 100%% (", artifactName))
+	}
+}
+
+func TestNewFileInfoFromPath(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	content := []byte("artifact content")
+	filePath := path.Join(tmp, "artifact.tgz")
+	require.NoError(t, ioutil.WriteFile(filePath, content, 0644))
+
+	fileInfo, err := publisher.NewFileInfoFromPath(filePath)
+	require.NoError(t, err)
+	assert.Nil(t, fileInfo.Bytes)
+	assert.Equal(t, publisher.NewFileInfoFromBytes(content).Checksums, fileInfo.Checksums)
+	assert.Equal(t, int64(len(content)), fileInfo.Size())
+
+	reader, err := fileInfo.Open()
+	require.NoError(t, err)
+	defer func() {
+		_ = reader.Close()
+	}()
+	gotContent, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, gotContent)
+}
+
+func TestUploadDistArtifactsDryRun(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: "/project",
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"bin": {
+						DistArtifactNames: []string{"foo-1.0.0.tgz"},
+					},
+				},
+			},
+		},
+	}
+	connInfo := publisher.BasicConnectionInfo{
+		URL:      server.URL,
+		Username: "testUsername",
+		Password: "testPassword",
+	}
+	buf := &bytes.Buffer{}
+	artifactPaths, uploadedURLs, err := connInfo.UploadDistArtifacts(productTaskOutputInfo, server.URL+"/repo", func(string, publisher.Checksums, string, string) bool {
+		t.Error("artifactExists should not be called in a dry run")
+		return false
+	}, true, buf)
+	require.NoError(t, err)
+	assert.Equal(t, 0, requests)
+	assert.Equal(t, []string{"/project/out/dist/foo/1.0.0/bin/foo-1.0.0.tgz"}, artifactPaths)
+	assert.Equal(t, []string{server.URL + "/repo/foo-1.0.0.tgz"}, uploadedURLs)
+	// paths of artifacts in the output are relative to the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	relPath, err := filepath.Rel(wd, "/project/out/dist/foo/1.0.0/bin/foo-1.0.0.tgz")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`[DRY RUN] Uploading %s to %s/repo/foo-1.0.0.tgz
+[DRY RUN]   Authorization: Basic [REDACTED]
+`, relPath, server.URL), buf.String())
+}
+
+func TestUploadFileVerification(t *testing.T) {
+	content := []byte("artifact content")
+	fileInfo := publisher.NewFileInfoFromBytes(content)
+
+	for i, tc := range []struct {
+		name       string
+		skip       bool
+		headStatus int
+		headHeader http.Header
+		wantHeads  int
+		wantError  string
+		wantOutput string
+	}{
+		{
+			name:       "sha256 checksum matches",
+			headStatus: http.StatusOK,
+			headHeader: http.Header{"X-Checksum-Sha256": []string{fileInfo.Checksums.SHA256}},
+			wantHeads:  1,
+		},
+		{
+			name:       "sha256 checksum does not match",
+			headStatus: http.StatusOK,
+			headHeader: http.Header{"X-Checksum-Sha256": []string{"0000"}},
+			wantHeads:  1,
+			wantError:  "remote sha256 checksum 0000 does not match local sha256 checksum " + fileInfo.Checksums.SHA256,
+		},
+		{
+			name:       "size matches",
+			headStatus: http.StatusOK,
+			headHeader: http.Header{"Content-Length": []string{fmt.Sprint(len(content))}},
+			wantHeads:  1,
+		},
+		{
+			name:       "truncated artifact fails",
+			headStatus: http.StatusOK,
+			headHeader: http.Header{"Content-Length": []string{"8"}},
+			wantHeads:  1,
+			wantError:  fmt.Sprintf("remote size 8 bytes does not match local size %d bytes", len(content)),
+		},
+		{
+			name:       "registry that does not support HEAD is not verified",
+			headStatus: http.StatusMethodNotAllowed,
+			wantHeads:  1,
+			wantOutput: `HEAD request resulted in response "405 Method Not Allowed"`,
+		},
+		{
+			name:      "verification can be skipped",
+			skip:      true,
+			wantHeads: 0,
+		},
+	} {
+		func() {
+			heads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodHead {
+					return
+				}
+				heads++
+				for k, v := range tc.headHeader {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tc.headStatus)
+			}))
+			defer server.Close()
+
+			connInfo := publisher.BasicConnectionInfo{
+				URL:                    server.URL,
+				SkipUploadVerification: tc.skip,
+			}
+			buf := &bytes.Buffer{}
+			_, err := connInfo.UploadFile(fileInfo, server.URL, "artifact.tgz", nil, false, buf)
+			if tc.wantError == "" {
+				require.NoError(t, err, "Case %d: %s", i, tc.name)
+			} else {
+				require.Error(t, err, "Case %d: %s", i, tc.name)
+				assert.Contains(t, err.Error(), tc.wantError, "Case %d: %s", i, tc.name)
+			}
+			assert.Equal(t, tc.wantHeads, heads, "Case %d: %s", i, tc.name)
+			assert.Contains(t, buf.String(), tc.wantOutput, "Case %d: %s", i, tc.name)
+		}()
+	}
+}
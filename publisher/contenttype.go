@@ -0,0 +1,57 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultContentType is the Content-Type of uploaded artifacts whose Content-Type cannot be inferred from their
+// extension.
+const DefaultContentType = "application/octet-stream"
+
+// contentTypes maps the extensions of common dist artifacts to their Content-Type.
+var contentTypes = map[string]string{
+	"asc":    "application/pgp-signature",
+	"deb":    "application/vnd.debian.binary-package",
+	"gz":     "application/gzip",
+	"json":   "application/json",
+	"pom":    "application/xml",
+	"rpm":    "application/x-rpm",
+	"sha256": "text/plain; charset=utf-8",
+	"tgz":    "application/gzip",
+	"zip":    "application/zip",
+}
+
+// ContentType returns the Content-Type for an artifact with the provided name based on its extension. Extensions are
+// matched case-insensitively and may be specified with or without a leading '.'. If the provided overrides map
+// contains an entry for the extension, its value is returned. Otherwise, the built-in Content-Type for the extension
+// is returned, or DefaultContentType if the extension is not known.
+func ContentType(artifactName string, overrides map[string]string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(artifactName), "."))
+	if ext == "" {
+		return DefaultContentType
+	}
+	for k, v := range overrides {
+		if strings.ToLower(strings.TrimPrefix(k, ".")) == ext {
+			return v
+		}
+	}
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	return DefaultContentType
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/palantir/distgo/publisher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentType(t *testing.T) {
+	for i, tc := range []struct {
+		artifactName string
+		overrides    map[string]string
+		want         string
+	}{
+		{artifactName: "foo-1.0.0.tgz", want: "application/gzip"},
+		{artifactName: "foo-1.0.0.tar.gz", want: "application/gzip"},
+		{artifactName: "foo-1.0.0.zip", want: "application/zip"},
+		{artifactName: "foo_1.0.0_amd64.deb", want: "application/vnd.debian.binary-package"},
+		{artifactName: "foo-1.0.0-1.x86_64.rpm", want: "application/x-rpm"},
+		{artifactName: "foo-1.0.0.json", want: "application/json"},
+		{artifactName: "foo-1.0.0.tgz.asc", want: "application/pgp-signature"},
+		{artifactName: "foo-1.0.0.tgz.sha256", want: "text/plain; charset=utf-8"},
+		{artifactName: "foo-1.0.0.pom", want: "application/xml"},
+		{artifactName: "FOO-1.0.0.TGZ", want: "application/gzip"},
+		{artifactName: "foo-1.0.0.bin", want: "application/octet-stream"},
+		{artifactName: "foo", want: "application/octet-stream"},
+		{
+			artifactName: "foo-1.0.0.tgz",
+			overrides:    map[string]string{".tgz": "application/x-gtar"},
+			want:         "application/x-gtar",
+		},
+		{
+			artifactName: "foo-1.0.0.bin",
+			overrides:    map[string]string{"bin": "application/x-executable"},
+			want:         "application/x-executable",
+		},
+		{
+			artifactName: "foo-1.0.0.zip",
+			overrides:    map[string]string{".tgz": "application/x-gtar"},
+			want:         "application/zip",
+		},
+	} {
+		assert.Equal(t, tc.want, publisher.ContentType(tc.artifactName, tc.overrides), "Case %d: %s", i, tc.artifactName)
+	}
+}
+
+func TestUploadFileSetsContentType(t *testing.T) {
+	for i, tc := range []struct {
+		artifactName string
+		contentTypes map[string]string
+		want         string
+	}{
+		{artifactName: "foo-1.0.0.tgz", want: "application/gzip"},
+		{artifactName: "foo-1.0.0.zip", want: "application/zip"},
+		{artifactName: "foo-1.0.0.deb", want: "application/vnd.debian.binary-package"},
+		{artifactName: "foo-1.0.0.rpm", want: "application/x-rpm"},
+		{artifactName: "foo-1.0.0.json", want: "application/json"},
+		{artifactName: "foo-1.0.0.tgz.asc", want: "application/pgp-signature"},
+		{artifactName: "foo-1.0.0.tgz.sha256", want: "text/plain; charset=utf-8"},
+		{artifactName: "foo-1.0.0.bin", want: "application/octet-stream"},
+		{
+			artifactName: "foo-1.0.0.tgz",
+			contentTypes: map[string]string{".tgz": "application/x-gtar"},
+			want:         "application/x-gtar",
+		},
+	} {
+		func() {
+			var gotContentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotContentType = r.Header.Get("Content-Type")
+			}))
+			defer server.Close()
+
+			connInfo := publisher.BasicConnectionInfo{
+				URL:                    server.URL,
+				SkipUploadVerification: true,
+				Progress:               "none",
+				ContentTypes:           tc.contentTypes,
+			}
+			_, err := connInfo.UploadFile(publisher.NewFileInfoFromBytes([]byte("content")), server.URL, tc.artifactName, nil, false, &bytes.Buffer{})
+			require.NoError(t, err, "Case %d: %s", i, tc.artifactName)
+			assert.Equal(t, tc.want, gotContentType, "Case %d: %s", i, tc.artifactName)
+		}()
+	}
+}
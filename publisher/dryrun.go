@@ -0,0 +1,62 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/palantir/distgo/distgo"
+)
+
+// redactedHeaders are the headers whose values are not printed by DryRunPrintlnHeaders because they contain
+// credentials.
+var redactedHeaders = map[string]bool{
+	"Authorization":        true,
+	"X-Amz-Security-Token": true,
+	"X-Jfrog-Art-Api":      true,
+}
+
+// DryRunPrintlnHeaders prints the provided headers of a request that would be made in a dry run, one header per line
+// sorted by name. The credentials in the values of the Authorization and X-Amz-Security-Token headers are redacted.
+// Publishers should call this function after printing the destination of an upload so that a dry run shows exactly
+// what would be sent.
+func DryRunPrintlnHeaders(w io.Writer, header http.Header) {
+	var names []string
+	for k := range header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, val := range header[name] {
+			if redactedHeaders[http.CanonicalHeaderKey(name)] {
+				val = redact(val)
+			}
+			distgo.DryRunPrintln(w, fmt.Sprintf("  %s: %s", name, val))
+		}
+	}
+}
+
+// redact returns the provided header value with the credentials removed. If the value specifies an authentication
+// scheme (for example, "Basic <credentials>"), the scheme is preserved.
+func redact(val string) string {
+	if idx := strings.Index(val, " "); idx > 0 {
+		return val[:idx] + " [REDACTED]"
+	}
+	return "[REDACTED]"
+}
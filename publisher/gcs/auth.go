@@ -0,0 +1,162 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/publisher"
+	"github.com/pkg/errors"
+)
+
+const (
+	storageScope          = "https://www.googleapis.com/auth/devstorage.read_write"
+	defaultTokenURI       = "https://oauth2.googleapis.com/token"
+	jwtBearerGrantType    = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	credentialsFileEnvVar = "GOOGLE_APPLICATION_CREDENTIALS"
+	accessTokenEnvVar     = "GOOGLE_OAUTH_ACCESS_TOKEN"
+)
+
+type serviceAccountKey struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// accessToken returns the OAuth2 access token used to authenticate with Google Cloud Storage. If credentialsFile is
+// empty, the value of the GOOGLE_APPLICATION_CREDENTIALS environment variable is used. If a credentials file is
+// specified, it must be the JSON key of a service account, and the key is exchanged for an access token. Otherwise,
+// the value of the GOOGLE_OAUTH_ACCESS_TOKEN environment variable is returned.
+func accessToken(credentialsFile string) (string, error) {
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv(credentialsFileEnvVar)
+	}
+	if credentialsFile == "" {
+		if token := os.Getenv(accessTokenEnvVar); token != "" {
+			return token, nil
+		}
+		return "", errors.Errorf("GCS credentials must be specified using the credentials-file configuration value or the %s or %s environment variables", credentialsFileEnvVar, accessTokenEnvVar)
+	}
+
+	keyBytes, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read credentials file %s", credentialsFile)
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(keyBytes, &key); err != nil {
+		return "", errors.Wrapf(err, "failed to unmarshal credentials file %s", credentialsFile)
+	}
+	if key.Type != "service_account" {
+		return "", errors.Errorf("credentials file %s must be a service account key, but its type was %q", credentialsFile, key.Type)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = defaultTokenURI
+	}
+	assertion, err := signedJWT(key, time.Now())
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create token request for credentials file %s", credentialsFile)
+	}
+	return exchangeJWT(key.TokenURI, assertion)
+}
+
+// signedJWT returns a JWT signed with the private key of the service account that asserts the identity of the service
+// account for the storage scope.
+func signedJWT(key serviceAccountKey, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return "", errors.Errorf("private key is not PEM-encoded")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse private key")
+	}
+	rsaKey, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.Errorf("private key must be an RSA key")
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   key.ClientEmail,
+		"scope": storageScope,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to sign token request")
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func exchangeJWT(tokenURI, assertion string) (rToken string, rErr error) {
+	client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{})
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.PostForm(tokenURI, url.Values{
+		"grant_type": []string{jwtBearerGrantType},
+		"assertion":  []string{assertion},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to request access token from %s", tokenURI)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for %s", tokenURI)
+		}
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read response from %s", tokenURI)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", errors.New(strings.TrimSpace(fmt.Sprintf("requesting access token from %s resulted in response %q:\n%s", tokenURI, resp.Status, string(body))))
+	}
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", errors.Wrapf(err, "failed to unmarshal response from %s", tokenURI)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", errors.Errorf("response from %s did not contain an access token", tokenURI)
+	}
+	return tokenResp.AccessToken, nil
+}
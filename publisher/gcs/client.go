@@ -0,0 +1,138 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/palantir/distgo/publisher"
+	"github.com/pkg/errors"
+)
+
+const defaultURL = "https://storage.googleapis.com"
+
+// ObjectMetadata is the metadata that is set on an uploaded object.
+type ObjectMetadata struct {
+	ContentType  string
+	CacheControl string
+}
+
+// Client uploads objects to Google Cloud Storage.
+type Client interface {
+	// Upload uploads the provided content, which is size bytes long, as the object with the provided name in the
+	// provided bucket. If the object already exists, it is overwritten.
+	Upload(bucket, object string, content io.Reader, size int64, metadata ObjectMetadata) error
+}
+
+// NewClient returns a Client that uses the Google Cloud Storage JSON API at the provided base URL. The provided access
+// token is used to authenticate requests.
+func NewClient(baseURL, accessToken string) Client {
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	return &apiClient{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		accessToken: accessToken,
+	}
+}
+
+// uploadURL returns the URL of the JSON API endpoint used to upload objects to the provided bucket.
+func uploadURL(baseURL, bucket string) string {
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	return fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=multipart", strings.TrimSuffix(baseURL, "/"), url.PathEscape(bucket))
+}
+
+type apiClient struct {
+	baseURL     string
+	accessToken string
+}
+
+func (c *apiClient) Upload(bucket, object string, content io.Reader, size int64, metadata ObjectMetadata) (rErr error) {
+	objectResource := map[string]string{
+		"name":        object,
+		"contentType": metadata.ContentType,
+	}
+	if metadata.CacheControl != "" {
+		objectResource["cacheControl"] = metadata.CacheControl
+	}
+	metadataJSON, err := json.Marshal(objectResource)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal object metadata")
+	}
+
+	// multipart upload: the first part is the object resource and the second part is the object content. The content is
+	// streamed between the encoded part headers and the closing boundary rather than being copied into the body.
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+	metadataWriter, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": []string{"application/json; charset=UTF-8"}})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create multipart request")
+	}
+	if _, err := metadataWriter.Write(metadataJSON); err != nil {
+		return errors.Wrapf(err, "failed to write multipart request")
+	}
+	if _, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": []string{metadata.ContentType}}); err != nil {
+		return errors.Wrapf(err, "failed to create multipart request")
+	}
+	bodyStart := append([]byte{}, buf.Bytes()...)
+	buf.Reset()
+	if err := writer.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write multipart request")
+	}
+	bodyEnd := buf.Bytes()
+	body := io.MultiReader(bytes.NewReader(bodyStart), content, bytes.NewReader(bodyEnd))
+
+	uploadURL := uploadURL(c.baseURL, bucket)
+	req, err := http.NewRequest(http.MethodPost, uploadURL, body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", uploadURL)
+	}
+	req.ContentLength = int64(len(bodyStart)) + size + int64(len(bodyEnd))
+	req.Header.Set("Content-Type", "multipart/related; boundary="+writer.Boundary())
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload gs://%s/%s", bucket, object)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for %s", uploadURL)
+		}
+	}()
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg := fmt.Sprintf("uploading gs://%s/%s resulted in response %q", bucket, object, resp.Status)
+		if respBody, err := ioutil.ReadAll(resp.Body); err == nil && len(respBody) > 0 {
+			msg += ":\n" + string(respBody)
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	// URL is the base URL of the Google Cloud Storage API. If not specified, "https://storage.googleapis.com" is used.
+	URL string `yaml:"url,omitempty"`
+	// Bucket is the name of the bucket to which the artifacts are uploaded.
+	Bucket string `yaml:"bucket,omitempty"`
+	// Prefix is the object name prefix used for uploaded artifacts. Artifacts are uploaded to
+	// "<bucket>/<prefix>/<product>/<version>/<artifact>".
+	Prefix string `yaml:"prefix,omitempty"`
+	// CredentialsFile is the path to the JSON key file of the service account used to authenticate. If not specified,
+	// the file specified by the GOOGLE_APPLICATION_CREDENTIALS environment variable is used. If neither is specified,
+	// the value of the GOOGLE_OAUTH_ACCESS_TOKEN environment variable is used as the OAuth2 access token.
+	CredentialsFile string `yaml:"credentials-file,omitempty"`
+	// ContentType is the Content-Type metadata of the uploaded objects. If not specified, the Content-Type is inferred
+	// from the extension of each artifact using ContentTypes and the built-in Content-Types of the publisher package.
+	ContentType string `yaml:"content-type,omitempty"`
+	// ContentTypes overrides the Content-Type that is inferred from the extension of each artifact when ContentType is
+	// not specified. The key is the extension (for example, ".tgz") and the value is the Content-Type.
+	ContentTypes map[string]string `yaml:"content-types,omitempty"`
+	// CacheControl is the Cache-Control metadata of the uploaded objects. If not specified, no Cache-Control metadata
+	// is set.
+	CacheControl string `yaml:"cache-control,omitempty"`
+	// Progress specifies how the progress of uploads is reported: "bar" (an interactive progress bar), "log" (a line
+	// with the percentage and throughput is printed every ProgressInterval, which is suitable for CI logs) or "none"
+	// (progress is not reported). If not specified, defaults to "bar".
+	Progress string `yaml:"progress,omitempty"`
+	// ProgressInterval is the interval at which progress is printed when Progress is "log" (for example, "30s"). If not
+	// specified, defaults to "5s".
+	ProgressInterval string `yaml:"progress-interval,omitempty"`
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal gcs publisher v0 configuration")
+	}
+	return cfgBytes, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher"
+	"github.com/palantir/distgo/publisher/gcs/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	TypeName = "gcs"
+)
+
+type gcsPublisher struct {
+	// client is the client used to upload objects. If nil, a client for the API URL and credentials in the
+	// configuration is created.
+	client Client
+}
+
+func PublisherCreator() publisher.Creator {
+	return publisher.NewCreator(TypeName, func() distgo.Publisher {
+		return &gcsPublisher{}
+	})
+}
+
+// PublisherCreatorWithClient returns a creator for a GCS publisher that uses the provided client to upload objects.
+// The API URL and credentials in the configuration are not used by publishers created by the returned creator.
+func PublisherCreatorWithClient(client Client) publisher.Creator {
+	return publisher.NewCreator(TypeName, func() distgo.Publisher {
+		return &gcsPublisher{
+			client: client,
+		}
+	})
+}
+
+func (p *gcsPublisher) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+var (
+	gcsPublisherURLFlag = distgo.PublisherFlag{
+		Name:        "url",
+		Description: "base URL of the Google Cloud Storage API (if blank, https://storage.googleapis.com is used)",
+		Type:        distgo.StringFlag,
+	}
+	gcsPublisherBucketFlag = distgo.PublisherFlag{
+		Name:        "bucket",
+		Description: "GCS bucket to which the artifacts are uploaded",
+		Type:        distgo.StringFlag,
+	}
+	gcsPublisherPrefixFlag = distgo.PublisherFlag{
+		Name:        "prefix",
+		Description: "object name prefix for the uploaded artifacts",
+		Type:        distgo.StringFlag,
+	}
+	gcsPublisherCredentialsFileFlag = distgo.PublisherFlag{
+		Name:        "credentials-file",
+		Description: "path to the JSON key file of the service account used to authenticate",
+		Type:        distgo.StringFlag,
+	}
+	gcsPublisherContentTypeFlag = distgo.PublisherFlag{
+		Name:        "content-type",
+		Description: "Content-Type metadata of the uploaded artifacts (if blank, the Content-Type is inferred from the extension of each artifact)",
+		Type:        distgo.StringFlag,
+	}
+	gcsPublisherCacheControlFlag = distgo.PublisherFlag{
+		Name:        "cache-control",
+		Description: "Cache-Control metadata of the uploaded artifacts",
+		Type:        distgo.StringFlag,
+	}
+)
+
+func (p *gcsPublisher) Flags() ([]distgo.PublisherFlag, error) {
+	return []distgo.PublisherFlag{
+		gcsPublisherURLFlag,
+		gcsPublisherBucketFlag,
+		gcsPublisherPrefixFlag,
+		gcsPublisherCredentialsFileFlag,
+		gcsPublisherContentTypeFlag,
+		gcsPublisherCacheControlFlag,
+	}, nil
+}
+
+func (p *gcsPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfgYML []byte, flagVals map[distgo.PublisherFlagName]interface{}, dryRun bool, stdout io.Writer) error {
+	var cfg config.GCS
+	if err := yaml.Unmarshal(cfgYML, &cfg); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal configuration")
+	}
+	if err := publisher.SetRequiredStringConfigValue(flagVals, gcsPublisherBucketFlag, &cfg.Bucket); err != nil {
+		return err
+	}
+	if err := publisher.SetConfigValues(flagVals,
+		gcsPublisherURLFlag, &cfg.URL,
+		gcsPublisherPrefixFlag, &cfg.Prefix,
+		gcsPublisherCredentialsFileFlag, &cfg.CredentialsFile,
+		gcsPublisherContentTypeFlag, &cfg.ContentType,
+		gcsPublisherCacheControlFlag, &cfg.CacheControl,
+	); err != nil {
+		return err
+	}
+
+	if err := publisher.ValidateProgress(cfg.Progress); err != nil {
+		return err
+	}
+	progressInterval, err := publisher.ParseProgressInterval(cfg.ProgressInterval)
+	if err != nil {
+		return err
+	}
+
+	client := p.client
+	if client == nil && !dryRun {
+		token, err := accessToken(cfg.CredentialsFile)
+		if err != nil {
+			return err
+		}
+		client = NewClient(cfg.URL, token)
+	}
+
+	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
+		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
+			object := ObjectName(cfg.Prefix, productTaskOutputInfo, path.Base(currArtifactPath))
+			metadata := ObjectMetadata{
+				ContentType:  cfg.ContentType,
+				CacheControl: cfg.CacheControl,
+			}
+			if metadata.ContentType == "" {
+				metadata.ContentType = publisher.ContentType(path.Base(currArtifactPath), cfg.ContentTypes)
+			}
+			distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Uploading %s to gs://%s/%s", currArtifactPath, cfg.Bucket, object), dryRun)
+			if dryRun {
+				distgo.DryRunPrintln(stdout, fmt.Sprintf("  POST %s", uploadURL(cfg.URL, cfg.Bucket)))
+				// Content-Type and Cache-Control are sent as object metadata in the multipart request body
+				metadataHeader := http.Header{}
+				metadataHeader.Set("Authorization", "Bearer [REDACTED]")
+				metadataHeader.Set("Content-Type", metadata.ContentType)
+				if metadata.CacheControl != "" {
+					metadataHeader.Set("Cache-Control", metadata.CacheControl)
+				}
+				publisher.DryRunPrintlnHeaders(stdout, metadataHeader)
+				continue
+			}
+			if err := uploadFile(client, cfg, object, metadata, currArtifactPath, progressInterval); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func uploadFile(client Client, cfg config.GCS, object string, metadata ObjectMetadata, filePath string, progressInterval time.Duration) (rErr error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open artifact %s for upload", filePath)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close artifact %s", filePath)
+		}
+	}()
+	fi, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat artifact %s", filePath)
+	}
+
+	reader, finishProgress, err := publisher.NewUploadProgressReader(cfg.Progress, progressInterval, f, fi.Size(), path.Base(filePath))
+	if err != nil {
+		return err
+	}
+	defer finishProgress()
+	if err := client.Upload(cfg.Bucket, object, reader, fi.Size(), metadata); err != nil {
+		return errors.Wrapf(err, "failed to upload %s", filePath)
+	}
+	return nil
+}
+
+// ObjectName returns the name of the object to which the artifact with the provided name is uploaded. The name is of
+// the form "<prefix>/<product>/<version>/<artifact>".
+func ObjectName(prefix string, productTaskOutputInfo distgo.ProductTaskOutputInfo, artifactName string) string {
+	return strings.TrimPrefix(path.Join(prefix, string(productTaskOutputInfo.Product.ID), productTaskOutputInfo.Project.Version, artifactName), "/")
+}
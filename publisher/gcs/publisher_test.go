@@ -0,0 +1,303 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcs_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher/gcs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadedObject struct {
+	bucket   string
+	object   string
+	content  string
+	metadata gcs.ObjectMetadata
+}
+
+type fakeClient struct {
+	uploads []uploadedObject
+}
+
+func (c *fakeClient) Upload(bucket, object string, content io.Reader, size int64, metadata gcs.ObjectMetadata) error {
+	contentBytes, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	if int64(len(contentBytes)) != size {
+		return fmt.Errorf("content of %s is %d bytes but size is %d", object, len(contentBytes), size)
+	}
+	c.uploads = append(c.uploads, uploadedObject{
+		bucket:   bucket,
+		object:   object,
+		content:  string(contentBytes),
+		metadata: metadata,
+	})
+	return nil
+}
+
+func TestGCSPublish(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistNameTemplateRendered: "foo-1.0.0",
+						DistArtifactNames:        []string{"foo-1.0.0-linux-amd64.tgz", "foo-1.0.0-darwin-amd64.tgz"},
+					},
+				},
+			},
+		},
+	}
+	for _, currPath := range productTaskOutputInfo.ProductDistArtifactPaths()["os-arch-bin"] {
+		require.NoError(t, os.MkdirAll(path.Dir(currPath), 0755))
+		require.NoError(t, ioutil.WriteFile(currPath, []byte(path.Base(currPath)), 0644))
+	}
+
+	for i, tc := range []struct {
+		cfgYML       string
+		flagVals     map[distgo.PublisherFlagName]interface{}
+		wantPrefix   string
+		wantMetadata gcs.ObjectMetadata
+	}{
+		{
+			cfgYML:     "bucket: releases\n",
+			wantPrefix: "",
+			wantMetadata: gcs.ObjectMetadata{
+				ContentType: "application/gzip",
+			},
+		},
+		{
+			cfgYML: `
+bucket: releases
+content-types:
+  .tgz: application/x-gtar
+`,
+			wantPrefix: "",
+			wantMetadata: gcs.ObjectMetadata{
+				ContentType: "application/x-gtar",
+			},
+		},
+		{
+			cfgYML: `
+bucket: releases
+prefix: products/
+content-type: application/octet-stream
+content-types:
+  .tgz: application/x-gtar
+cache-control: public, max-age=3600
+`,
+			wantPrefix: "products/",
+			wantMetadata: gcs.ObjectMetadata{
+				ContentType:  "application/octet-stream",
+				CacheControl: "public, max-age=3600",
+			},
+		},
+		{
+			cfgYML: "bucket: releases\nprefix: products\n",
+			flagVals: map[distgo.PublisherFlagName]interface{}{
+				"prefix":        "overridden",
+				"cache-control": "no-cache",
+			},
+			wantPrefix: "overridden/",
+			wantMetadata: gcs.ObjectMetadata{
+				ContentType:  "application/gzip",
+				CacheControl: "no-cache",
+			},
+		},
+	} {
+		client := &fakeClient{}
+		buf := &bytes.Buffer{}
+		err := gcs.PublisherCreatorWithClient(client).Publisher().RunPublish(productTaskOutputInfo, []byte(tc.cfgYML), tc.flagVals, false, buf)
+		require.NoError(t, err, "Case %d", i)
+
+		var want []uploadedObject
+		for _, artifactName := range []string{"foo-1.0.0-linux-amd64.tgz", "foo-1.0.0-darwin-amd64.tgz"} {
+			want = append(want, uploadedObject{
+				bucket:   "releases",
+				object:   tc.wantPrefix + "foo/1.0.0/" + artifactName,
+				content:  artifactName,
+				metadata: tc.wantMetadata,
+			})
+		}
+		assert.Equal(t, want, client.uploads, "Case %d", i)
+	}
+}
+
+func TestGCSPublishDryRun(t *testing.T) {
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: "/project",
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"bin": {
+						DistArtifactNames: []string{"foo-1.0.0.tgz"},
+					},
+				},
+			},
+		},
+	}
+	client := &fakeClient{}
+	buf := &bytes.Buffer{}
+	err := gcs.PublisherCreatorWithClient(client).Publisher().RunPublish(productTaskOutputInfo, []byte("bucket: releases\nprefix: products\n"), nil, true, buf)
+	require.NoError(t, err)
+	assert.Empty(t, client.uploads)
+	assert.Equal(t, `[DRY RUN] Uploading /project/out/dist/foo/1.0.0/bin/foo-1.0.0.tgz to gs://releases/products/foo/1.0.0/foo-1.0.0.tgz
+[DRY RUN]   POST https://storage.googleapis.com/upload/storage/v1/b/releases/o?uploadType=multipart
+[DRY RUN]   Authorization: Bearer [REDACTED]
+[DRY RUN]   Content-Type: application/gzip
+`, buf.String())
+}
+
+func TestGCSPublishProgress(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"bin": {
+						DistArtifactNames: []string{"foo-1.0.0.tgz"},
+					},
+				},
+			},
+		},
+	}
+	artifactPath := productTaskOutputInfo.ProductDistArtifactPaths()["bin"][0]
+	require.NoError(t, os.MkdirAll(path.Dir(artifactPath), 0755))
+	require.NoError(t, ioutil.WriteFile(artifactPath, []byte("content"), 0644))
+
+	client := &fakeClient{}
+	stderr := captureStderr(t, func() {
+		err = gcs.PublisherCreatorWithClient(client).Publisher().RunPublish(productTaskOutputInfo, []byte("bucket: releases\nprogress: log\n"), nil, false, ioutil.Discard)
+	})
+	require.NoError(t, err)
+	require.Len(t, client.uploads, 1)
+	assert.Equal(t, "content", client.uploads[0].content)
+	assert.Contains(t, stderr, "Uploading foo-1.0.0.tgz: 100% (7 B of 7 B, ")
+}
+
+func TestGCSPublishInvalidProgress(t *testing.T) {
+	err := gcs.PublisherCreatorWithClient(&fakeClient{}).Publisher().RunPublish(distgo.ProductTaskOutputInfo{}, []byte("bucket: releases\nprogress: verbose\n"), nil, true, ioutil.Discard)
+	assert.EqualError(t, err, `invalid progress "verbose": must be one of "bar", "log" or "none"`)
+}
+
+func TestClientUpload(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotContentLength, gotBodyLength int64
+	var gotParts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RequestURI()
+		gotAuth = r.Header.Get("Authorization")
+		gotContentLength = r.ContentLength
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		gotBodyLength = int64(len(body))
+
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/related", mediaType)
+		mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			partContent, err := ioutil.ReadAll(part)
+			require.NoError(t, err)
+			gotParts = append(gotParts, part.Header.Get("Content-Type")+": "+string(partContent))
+		}
+	}))
+	defer server.Close()
+
+	const content = "object content"
+	err := gcs.NewClient(server.URL, "testToken").Upload("releases", "foo/1.0.0/foo-1.0.0.tgz", strings.NewReader(content), int64(len(content)), gcs.ObjectMetadata{
+		ContentType:  "application/gzip",
+		CacheControl: "no-cache",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/upload/storage/v1/b/releases/o?uploadType=multipart", gotPath)
+	assert.Equal(t, "Bearer testToken", gotAuth)
+	assert.Equal(t, gotBodyLength, gotContentLength)
+	assert.Equal(t, []string{
+		`application/json; charset=UTF-8: {"cacheControl":"no-cache","contentType":"application/gzip","name":"foo/1.0.0/foo-1.0.0.tgz"}`,
+		"application/gzip: " + content,
+	}, gotParts)
+}
+
+func captureStderr(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	origStderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = origStderr
+	}()
+
+	outputC := make(chan string)
+	go func() {
+		output, _ := ioutil.ReadAll(r)
+		outputC <- string(output)
+	}()
+	fn()
+	require.NoError(t, w.Close())
+	return <-outputC
+}
@@ -20,11 +20,27 @@ import (
 )
 
 type Config struct {
-	APIURL     string `yaml:"api-url,omitempty"`
-	User       string `yaml:"user,omitempty"`
+	// APIURL is the base URL of the GitHub API. Specify the API URL of the GitHub Enterprise instance (for example,
+	// "https://github.domain.com/api/v3/") to publish to GitHub Enterprise. If not specified, "https://api.github.com/"
+	// is used.
+	APIURL string `yaml:"api-url,omitempty"`
+	User   string `yaml:"user,omitempty"`
+	// Token is the GitHub token used to authenticate. If not specified, the value of the GITHUB_TOKEN environment
+	// variable is used.
 	Token      string `yaml:"token,omitempty"`
 	Owner      string `yaml:"owner,omitempty"`
 	Repository string `yaml:"repository,omitempty"`
+	// AssetConflict specifies the behavior when an asset with the same name as an artifact already exists in the
+	// release. Must be one of "fail" (the publish fails), "replace" (the existing asset is deleted and the artifact is
+	// uploaded) or "skip" (the artifact is not uploaded). If not specified, "fail" is used.
+	AssetConflict string `yaml:"asset-conflict,omitempty"`
+	// Progress specifies how the progress of uploads is reported: "bar" (an interactive progress bar), "log" (a line
+	// with the percentage and throughput is printed every ProgressInterval, which is suitable for CI logs) or "none"
+	// (progress is not reported). If not specified, defaults to "bar".
+	Progress string `yaml:"progress,omitempty"`
+	// ProgressInterval is the interval at which progress is printed when Progress is "log" (for example, "30s"). If not
+	// specified, defaults to "5s".
+	ProgressInterval string `yaml:"progress-interval,omitempty"`
 }
 
 func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
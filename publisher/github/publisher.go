@@ -24,6 +24,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v28/github"
 	"github.com/jtacoma/uritemplates"
@@ -32,11 +33,19 @@ import (
 	"github.com/palantir/distgo/publisher/github/config"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
-	"gopkg.in/cheggaaa/pb.v1"
 	"gopkg.in/yaml.v2"
 )
 
-const TypeName = "github"
+const (
+	TypeName = "github"
+
+	defaultAPIURL = "https://api.github.com/"
+	tokenEnvVar   = "GITHUB_TOKEN"
+
+	assetConflictFail    = "fail"
+	assetConflictReplace = "replace"
+	assetConflictSkip    = "skip"
+)
 
 type githubPublisher struct{}
 
@@ -53,7 +62,7 @@ func (p *githubPublisher) TypeName() (string, error) {
 var (
 	githubPublisherAPIURLFlag = distgo.PublisherFlag{
 		Name:        "api-url",
-		Description: "GitHub API URL",
+		Description: "GitHub API URL (if unspecified, https://api.github.com/ will be used)",
 		Type:        distgo.StringFlag,
 	}
 	githubPublisherUserFlag = distgo.PublisherFlag{
@@ -63,7 +72,7 @@ var (
 	}
 	githubPublisherTokenFlag = distgo.PublisherFlag{
 		Name:        "token",
-		Description: "GitHub token",
+		Description: "GitHub token (if unspecified, the GITHUB_TOKEN environment variable will be used)",
 		Type:        distgo.StringFlag,
 	}
 	githubPublisherRepositoryFlag = distgo.PublisherFlag{
@@ -76,6 +85,11 @@ var (
 		Description: "GitHub owner of the destination repository for the publish (if unspecified, user will be used)",
 		Type:        distgo.StringFlag,
 	}
+	githubPublisherAssetConflictFlag = distgo.PublisherFlag{
+		Name:        "asset-conflict",
+		Description: "behavior when an asset with the same name already exists in the release: fail, replace or skip (if unspecified, fail will be used)",
+		Type:        distgo.StringFlag,
+	}
 )
 
 func (p *githubPublisher) Flags() ([]distgo.PublisherFlag, error) {
@@ -85,6 +99,7 @@ func (p *githubPublisher) Flags() ([]distgo.PublisherFlag, error) {
 		githubPublisherTokenFlag,
 		githubPublisherRepositoryFlag,
 		githubPublisherOwnerFlag,
+		githubPublisherAssetConflictFlag,
 	}, nil
 }
 
@@ -94,22 +109,52 @@ func (p *githubPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOut
 		return errors.Wrapf(err, "failed to unmarshal configuration")
 	}
 	if err := publisher.SetRequiredStringConfigValues(flagVals,
-		githubPublisherAPIURLFlag, &cfg.APIURL,
 		githubPublisherUserFlag, &cfg.User,
-		githubPublisherTokenFlag, &cfg.Token,
 		githubPublisherRepositoryFlag, &cfg.Repository,
 	); err != nil {
 		return err
 	}
 
-	if err := publisher.SetConfigValue(flagVals, githubPublisherOwnerFlag, &cfg.Owner); err != nil {
+	if err := publisher.SetConfigValues(flagVals,
+		githubPublisherAPIURLFlag, &cfg.APIURL,
+		githubPublisherTokenFlag, &cfg.Token,
+		githubPublisherOwnerFlag, &cfg.Owner,
+		githubPublisherAssetConflictFlag, &cfg.AssetConflict,
+	); err != nil {
 		return err
 	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIURL
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv(tokenEnvVar)
+	}
+	if cfg.Token == "" {
+		return errors.Errorf("%s was not specified -- it must be specified in configuration, using a flag or using the %s environment variable", githubPublisherTokenFlag.Name, tokenEnvVar)
+	}
 	if cfg.Owner == "" {
 		cfg.Owner = cfg.User
 	}
+	switch cfg.AssetConflict {
+	case "":
+		cfg.AssetConflict = assetConflictFail
+	case assetConflictFail, assetConflictReplace, assetConflictSkip:
+	default:
+		return errors.Errorf("invalid %s %q: must be one of %q, %q or %q", githubPublisherAssetConflictFlag.Name, cfg.AssetConflict, assetConflictFail, assetConflictReplace, assetConflictSkip)
+	}
+	if err := publisher.ValidateProgress(cfg.Progress); err != nil {
+		return err
+	}
+	progressInterval, err := publisher.ParseProgressInterval(cfg.ProgressInterval)
+	if err != nil {
+		return err
+	}
 
-	client := github.NewClient(oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+	httpClient, err := publisher.NewHTTPClient(publisher.HTTPClientParams{})
+	if err != nil {
+		return err
+	}
+	client := github.NewClient(oauth2.NewClient(context.WithValue(context.Background(), oauth2.HTTPClient, httpClient), oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: cfg.Token},
 	)))
 
@@ -127,29 +172,41 @@ func (p *githubPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOut
 	distgo.PrintOrDryRunPrint(stdout, fmt.Sprintf("Creating GitHub release %s for %s/%s...", productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository), dryRun)
 
 	var releaseRes *github.RepositoryRelease
+	existingAssets := make(map[string]*github.ReleaseAsset)
 	if !dryRun {
-		releaseRes, _, err = client.Repositories.CreateRelease(context.Background(), cfg.Owner, cfg.Repository, &github.RepositoryRelease{
-			TagName: github.String(productTaskOutputInfo.Project.Version),
-		})
+		releaseRes, err = createOrGetRelease(client, cfg.Owner, cfg.Repository, productTaskOutputInfo.Project.Version, stdout)
 		if err != nil {
-			// newline to complement "..." output
-			// no need for dry run print because beginning of line has already been printed
-			_, _ = fmt.Fprintln(stdout)
-
-			if ghErr, ok := err.(*github.ErrorResponse); ok && len(ghErr.Errors) > 0 {
-				if ghErr.Errors[0].Code == "already_exists" {
-					return errors.Errorf("GitHub release %s already exists for %s/%s", productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository)
-				}
-			}
-			return errors.Wrapf(err, "failed to create GitHub release %s for %s/%s...", productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository)
+			return err
+		}
+		assets, err := listReleaseAssets(client, cfg.Owner, cfg.Repository, releaseRes.GetID())
+		if err != nil {
+			return err
+		}
+		for _, currAsset := range assets {
+			existingAssets[currAsset.GetName()] = currAsset
 		}
+	} else {
+		// no need for dry run print because beginning of line has already been printed
+		_, _ = fmt.Fprintln(stdout, "done")
 	}
-	// no need for dry run print because beginning of line has already been printed
-	_, _ = fmt.Fprintln(stdout, "done")
 
 	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
 		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
-			if _, err := p.uploadFileAtPath(client, releaseRes, currArtifactPath, dryRun, stdout); err != nil {
+			if existingAsset, ok := existingAssets[path.Base(currArtifactPath)]; ok {
+				switch cfg.AssetConflict {
+				case assetConflictSkip:
+					_, _ = fmt.Fprintf(stdout, "Asset %s already exists in GitHub release %s for %s/%s, skipping upload of %s\n", existingAsset.GetName(), productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository, currArtifactPath)
+					continue
+				case assetConflictReplace:
+					_, _ = fmt.Fprintf(stdout, "Deleting existing asset %s from GitHub release %s for %s/%s\n", existingAsset.GetName(), productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository)
+					if _, err := client.Repositories.DeleteReleaseAsset(context.Background(), cfg.Owner, cfg.Repository, existingAsset.GetID()); err != nil {
+						return errors.Wrapf(err, "failed to delete existing asset %s from GitHub release %s for %s/%s", existingAsset.GetName(), productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository)
+					}
+				default:
+					return errors.Errorf("asset %s already exists in GitHub release %s for %s/%s", existingAsset.GetName(), productTaskOutputInfo.Project.Version, cfg.Owner, cfg.Repository)
+				}
+			}
+			if _, err := p.uploadFileAtPath(client, releaseRes, currArtifactPath, cfg.Progress, progressInterval, dryRun, stdout); err != nil {
 				return err
 			}
 		}
@@ -157,7 +214,47 @@ func (p *githubPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOut
 	return nil
 }
 
-func (p *githubPublisher) uploadFileAtPath(client *github.Client, release *github.RepositoryRelease, filePath string, dryRun bool, stdout io.Writer) (string, error) {
+// createOrGetRelease creates the GitHub release for the provided tag and returns it. If the release already exists, the
+// existing release is returned. Completes the "Creating GitHub release..." line of output.
+func createOrGetRelease(client *github.Client, owner, repo, tag string, stdout io.Writer) (*github.RepositoryRelease, error) {
+	releaseRes, _, err := client.Repositories.CreateRelease(context.Background(), owner, repo, &github.RepositoryRelease{
+		TagName: github.String(tag),
+	})
+	if err == nil {
+		// no need for dry run print because beginning of line has already been printed
+		_, _ = fmt.Fprintln(stdout, "done")
+		return releaseRes, nil
+	}
+	if ghErr, ok := err.(*github.ErrorResponse); ok && len(ghErr.Errors) > 0 && ghErr.Errors[0].Code == "already_exists" {
+		_, _ = fmt.Fprintln(stdout, "already exists, using existing release")
+		releaseRes, _, err := client.Repositories.GetReleaseByTag(context.Background(), owner, repo, tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get existing GitHub release %s for %s/%s", tag, owner, repo)
+		}
+		return releaseRes, nil
+	}
+	// newline to complement "..." output
+	_, _ = fmt.Fprintln(stdout)
+	return nil, errors.Wrapf(err, "failed to create GitHub release %s for %s/%s", tag, owner, repo)
+}
+
+func listReleaseAssets(client *github.Client, owner, repo string, releaseID int64) ([]*github.ReleaseAsset, error) {
+	var assets []*github.ReleaseAsset
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Repositories.ListReleaseAssets(context.Background(), owner, repo, releaseID, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list assets of GitHub release for %s/%s", owner, repo)
+		}
+		assets = append(assets, page...)
+		if resp.NextPage == 0 {
+			return assets, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *githubPublisher) uploadFileAtPath(client *github.Client, release *github.RepositoryRelease, filePath, progress string, progressInterval time.Duration, dryRun bool, stdout io.Writer) (string, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to open artifact %s for upload", filePath)
@@ -176,7 +273,7 @@ func (p *githubPublisher) uploadFileAtPath(client *github.Client, release *githu
 		return "", err
 	}
 
-	uploadRes, _, err := githubUploadReleaseAssetWithProgress(context.Background(), client, uploadURI, f, stdout)
+	uploadRes, _, err := githubUploadReleaseAssetWithProgress(context.Background(), client, uploadURI, f, progress, progressInterval, stdout)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to upload artifact %s", filePath)
 	}
@@ -202,7 +299,7 @@ func uploadURIForProduct(githubUploadURLTemplate, name string) (string, error) {
 }
 
 // Based on github.Repositories.UploadReleaseAsset. Adds support for progress reporting.
-func githubUploadReleaseAssetWithProgress(ctx context.Context, client *github.Client, uploadURI string, file *os.File, stdout io.Writer) (*github.ReleaseAsset, *github.Response, error) {
+func githubUploadReleaseAssetWithProgress(ctx context.Context, client *github.Client, uploadURI string, file *os.File, progress string, progressInterval time.Duration, stdout io.Writer) (*github.ReleaseAsset, *github.Response, error) {
 	stat, err := file.Stat()
 	if err != nil {
 		return nil, nil, err
@@ -212,12 +309,11 @@ func githubUploadReleaseAssetWithProgress(ctx context.Context, client *github.Cl
 	}
 
 	_, _ = fmt.Fprintf(stdout, "Uploading %s to %s\n", file.Name(), uploadURI)
-	bar := pb.New(int(stat.Size())).SetUnits(pb.U_BYTES)
-	bar.Output = stdout
-	bar.SetMaxWidth(120)
-	bar.Start()
-	defer bar.Finish()
-	reader := bar.NewProxyReader(file)
+	reader, finishProgress, err := publisher.NewUploadProgressReader(progress, progressInterval, file, stat.Size(), path.Base(file.Name()))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer finishProgress()
 
 	mediaType := mime.TypeByExtension(filepath.Ext(file.Name()))
 	req, err := client.NewUploadRequest(uploadURI, reader, stat.Size(), mediaType)
@@ -0,0 +1,245 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub is a mock of the GitHub releases API for the repository testOwner/testRepo.
+type fakeGitHub struct {
+	server         *httptest.Server
+	releaseExists  bool
+	existingAssets []string
+	requests       []string
+}
+
+func newFakeGitHub(releaseExists bool, existingAssets ...string) *fakeGitHub {
+	f := &fakeGitHub{
+		releaseExists:  releaseExists,
+		existingAssets: existingAssets,
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *fakeGitHub) handle(w http.ResponseWriter, r *http.Request) {
+	reqString := r.Method + " " + r.URL.Path
+	if name := r.URL.Query().Get("name"); name != "" {
+		reqString += "?name=" + name
+	}
+	f.requests = append(f.requests, reqString)
+
+	release := map[string]interface{}{
+		"id":         1,
+		"tag_name":   "1.0.0",
+		"upload_url": f.server.URL + "/uploads/repos/testOwner/testRepo/releases/1/assets{?name,label}",
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/testOwner/testRepo/releases":
+		if f.releaseExists {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"message": "Validation Failed",
+				"errors": []map[string]string{
+					{"resource": "Release", "code": "already_exists", "field": "tag_name"},
+				},
+			})
+			return
+		}
+		writeJSON(w, http.StatusCreated, release)
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/testOwner/testRepo/releases/tags/1.0.0":
+		writeJSON(w, http.StatusOK, release)
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/testOwner/testRepo/releases/1/assets":
+		var assets []map[string]interface{}
+		for i, name := range f.existingAssets {
+			assets = append(assets, map[string]interface{}{"id": i + 100, "name": name})
+		}
+		writeJSON(w, http.StatusOK, assets)
+	case r.Method == http.MethodDelete && path.Dir(r.URL.Path) == "/repos/testOwner/testRepo/releases/assets":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/uploads/repos/testOwner/testRepo/releases/1/assets":
+		name := r.URL.Query().Get("name")
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"name":                 name,
+			"browser_download_url": "https://github.domain.com/testOwner/testRepo/releases/download/1.0.0/" + name,
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestGitHubPublish(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	productTaskOutputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: tmp,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						DistArtifactNames: []string{"foo-1.0.0-linux-amd64.tgz", "foo-1.0.0-darwin-amd64.tgz"},
+					},
+				},
+			},
+		},
+	}
+	for _, currPath := range productTaskOutputInfo.ProductDistArtifactPaths()["os-arch-bin"] {
+		require.NoError(t, os.MkdirAll(path.Dir(currPath), 0755))
+		require.NoError(t, ioutil.WriteFile(currPath, []byte(path.Base(currPath)), 0644))
+	}
+	const (
+		createRelease = "POST /repos/testOwner/testRepo/releases"
+		getRelease    = "GET /repos/testOwner/testRepo/releases/tags/1.0.0"
+		listAssets    = "GET /repos/testOwner/testRepo/releases/1/assets"
+		uploadLinux   = "POST /uploads/repos/testOwner/testRepo/releases/1/assets?name=foo-1.0.0-linux-amd64.tgz"
+		uploadDarwin  = "POST /uploads/repos/testOwner/testRepo/releases/1/assets?name=foo-1.0.0-darwin-amd64.tgz"
+	)
+
+	for i, tc := range []struct {
+		name           string
+		releaseExists  bool
+		existingAssets []string
+		assetConflict  string
+		wantRequests   []string
+		wantOutput     string
+		wantError      string
+	}{
+		{
+			name:         "creates release and uploads assets",
+			wantRequests: []string{createRelease, listAssets, uploadLinux, uploadDarwin},
+			wantOutput:   "Creating GitHub release 1.0.0 for testOwner/testRepo...done\n",
+		},
+		{
+			name:          "reuses existing release",
+			releaseExists: true,
+			wantRequests:  []string{createRelease, getRelease, listAssets, uploadLinux, uploadDarwin},
+			wantOutput:    "Creating GitHub release 1.0.0 for testOwner/testRepo...already exists, using existing release\n",
+		},
+		{
+			name:           "fails if asset exists by default",
+			releaseExists:  true,
+			existingAssets: []string{"foo-1.0.0-linux-amd64.tgz"},
+			wantRequests:   []string{createRelease, getRelease, listAssets},
+			wantError:      "asset foo-1.0.0-linux-amd64.tgz already exists in GitHub release 1.0.0 for testOwner/testRepo",
+		},
+		{
+			name:           "skips existing asset",
+			releaseExists:  true,
+			existingAssets: []string{"foo-1.0.0-linux-amd64.tgz"},
+			assetConflict:  "skip",
+			wantRequests:   []string{createRelease, getRelease, listAssets, uploadDarwin},
+			wantOutput:     "Asset foo-1.0.0-linux-amd64.tgz already exists in GitHub release 1.0.0 for testOwner/testRepo, skipping upload",
+		},
+		{
+			name:           "replaces existing asset",
+			releaseExists:  true,
+			existingAssets: []string{"foo-1.0.0-linux-amd64.tgz"},
+			assetConflict:  "replace",
+			wantRequests:   []string{createRelease, getRelease, listAssets, "DELETE /repos/testOwner/testRepo/releases/assets/100", uploadLinux, uploadDarwin},
+			wantOutput:     "Deleting existing asset foo-1.0.0-linux-amd64.tgz from GitHub release 1.0.0 for testOwner/testRepo\n",
+		},
+	} {
+		func() {
+			fake := newFakeGitHub(tc.releaseExists, tc.existingAssets...)
+			defer fake.server.Close()
+
+			cfgYML := fmt.Sprintf(`
+api-url: %s
+user: testUser
+token: testToken
+owner: testOwner
+repository: testRepo
+asset-conflict: %q
+progress: none
+`, fake.server.URL, tc.assetConflict)
+			buf := &bytes.Buffer{}
+			err := github.PublisherCreator().Publisher().RunPublish(productTaskOutputInfo, []byte(cfgYML), nil, false, buf)
+			if tc.wantError != "" {
+				assert.EqualError(t, err, tc.wantError, "Case %d: %s", i, tc.name)
+			} else {
+				require.NoError(t, err, "Case %d: %s\n%s", i, tc.name, buf.String())
+			}
+			assert.Equal(t, tc.wantRequests, fake.requests, "Case %d: %s", i, tc.name)
+			assert.Contains(t, buf.String(), tc.wantOutput, "Case %d: %s", i, tc.name)
+		}()
+	}
+}
+
+func TestGitHubPublishTokenFromEnv(t *testing.T) {
+	fake := newFakeGitHub(false)
+	defer fake.server.Close()
+
+	var gotAuth string
+	fake.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	origToken, hadToken := os.LookupEnv("GITHUB_TOKEN")
+	require.NoError(t, os.Setenv("GITHUB_TOKEN", "envToken"))
+	defer func() {
+		if hadToken {
+			_ = os.Setenv("GITHUB_TOKEN", origToken)
+		} else {
+			_ = os.Unsetenv("GITHUB_TOKEN")
+		}
+	}()
+
+	cfgYML := fmt.Sprintf("api-url: %s\nuser: testUser\nrepository: testRepo\n", fake.server.URL)
+	err := github.PublisherCreator().Publisher().RunPublish(distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{Version: "1.0.0"},
+	}, []byte(cfgYML), nil, false, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Equal(t, "Bearer envToken", gotAuth)
+}
+
+func TestGitHubPublishInvalidAssetConflict(t *testing.T) {
+	err := github.PublisherCreator().Publisher().RunPublish(distgo.ProductTaskOutputInfo{}, []byte("user: testUser\ntoken: testToken\nrepository: testRepo\nasset-conflict: overwrite\n"), nil, true, &bytes.Buffer{})
+	assert.EqualError(t, err, `invalid asset-conflict "overwrite": must be one of "fail", "replace" or "skip"`)
+}
+
+func TestGitHubPublishInvalidProgress(t *testing.T) {
+	err := github.PublisherCreator().Publisher().RunPublish(distgo.ProductTaskOutputInfo{}, []byte("user: testUser\ntoken: testToken\nrepository: testRepo\nprogress: verbose\n"), nil, true, &bytes.Buffer{})
+	assert.EqualError(t, err, `invalid progress "verbose": must be one of "bar", "log" or "none"`)
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"github.com/palantir/distgo/publisher"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	// BasicConnectionInfo specifies the connection settings for the uploads. "url" is the template for the URL to which
+	// each dist artifact is uploaded. The template can use the "{{Product}}", "{{Version}}" and "{{Artifact}}"
+	// functions, which return the ID of the product, the version of the project and the file name of the artifact being
+	// uploaded. For example, "https://artifacts.domain.com/{{Product}}/{{Version}}/{{Artifact}}". If "username" or
+	// "password" is specified, the uploads use basic authentication.
+	publisher.BasicConnectionInfo `yaml:",inline,omitempty"`
+	// Method is the HTTP method used for the uploads. If not specified, "PUT" is used.
+	Method string `yaml:"method,omitempty"`
+	// Headers specifies the headers that are set on every upload request. The values are templates that can use the
+	// same functions as URL.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// EnvHeaders specifies headers whose values are read from environment variables. The keys are the names of the
+	// headers and the values are the names of the environment variables. For example, the following sets the
+	// Authorization header to the value of the ARTIFACT_STORE_AUTH environment variable:
+	//
+	//   env-headers:
+	//     Authorization: ARTIFACT_STORE_AUTH
+	//
+	// It is an error for a referenced environment variable to be unset. Values in EnvHeaders take precedence over
+	// values in Headers.
+	EnvHeaders map[string]string `yaml:"env-headers,omitempty"`
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal http publisher v0 configuration")
+	}
+	return cfgBytes, nil
+}
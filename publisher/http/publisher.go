@@ -0,0 +1,154 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher"
+	"github.com/palantir/distgo/publisher/http/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	TypeName = "http"
+
+	defaultMethod = http.MethodPut
+)
+
+type httpPublisher struct{}
+
+func PublisherCreator() publisher.Creator {
+	return publisher.NewCreator(TypeName, func() distgo.Publisher {
+		return &httpPublisher{}
+	})
+}
+
+func (p *httpPublisher) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+var (
+	httpPublisherURLFlag = distgo.PublisherFlag{
+		Name:        "url",
+		Description: "template for the URL to which the artifacts are uploaded (can use {{Product}}, {{Version}} and {{Artifact}})",
+		Type:        distgo.StringFlag,
+	}
+	httpPublisherMethodFlag = distgo.PublisherFlag{
+		Name:        "method",
+		Description: "HTTP method used for the uploads (if unspecified, PUT will be used)",
+		Type:        distgo.StringFlag,
+	}
+)
+
+func (p *httpPublisher) Flags() ([]distgo.PublisherFlag, error) {
+	return []distgo.PublisherFlag{
+		httpPublisherURLFlag,
+		publisher.ConnectionInfoUsernameFlag,
+		publisher.ConnectionInfoPasswordFlag,
+		httpPublisherMethodFlag,
+	}, nil
+}
+
+func (p *httpPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfgYML []byte, flagVals map[distgo.PublisherFlagName]interface{}, dryRun bool, stdout io.Writer) error {
+	var cfg config.HTTP
+	if err := yaml.Unmarshal(cfgYML, &cfg); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal configuration")
+	}
+	if err := publisher.SetRequiredStringConfigValue(flagVals, httpPublisherURLFlag, &cfg.URL); err != nil {
+		return err
+	}
+	if err := publisher.SetConfigValues(flagVals,
+		publisher.ConnectionInfoUsernameFlag, &cfg.Username,
+		publisher.ConnectionInfoPasswordFlag, &cfg.Password,
+		httpPublisherMethodFlag, &cfg.Method,
+	); err != nil {
+		return err
+	}
+	if cfg.Method == "" {
+		cfg.Method = defaultMethod
+	}
+
+	envHeaders, err := envHeaderValues(cfg.EnvHeaders, dryRun)
+	if err != nil {
+		return err
+	}
+	_, _, err = cfg.UploadDistArtifactsWithRequests(productTaskOutputInfo, func(artifactName string) (string, string, http.Header, error) {
+		uploadURL, header, err := UploadRequest(cfg, envHeaders, productTaskOutputInfo, artifactName)
+		return cfg.Method, uploadURL, header, err
+	}, nil, dryRun, stdout)
+	return err
+}
+
+// UploadRequest returns the URL and headers of the request that uploads the artifact with the provided name. The URL
+// and the values of the configured headers are rendered as templates, and the provided environment header values are
+// set over the rendered headers.
+func UploadRequest(cfg config.HTTP, envHeaders map[string]string, productTaskOutputInfo distgo.ProductTaskOutputInfo, artifactName string) (string, http.Header, error) {
+	templateFns := []distgo.TemplateFunction{
+		distgo.ProductTemplateFunction(productTaskOutputInfo.Product.ID),
+		distgo.VersionTemplateFunction(productTaskOutputInfo.Project.Version),
+		distgo.TemplateValueFunction("Artifact", artifactName),
+	}
+	uploadURL, err := distgo.RenderTemplate(cfg.URL, nil, templateFns...)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to render url template")
+	}
+	header := http.Header{}
+	for _, name := range sortedKeys(cfg.Headers) {
+		val, err := distgo.RenderTemplate(cfg.Headers[name], nil, templateFns...)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to render template for header %s", name)
+		}
+		header.Set(name, val)
+	}
+	for name, val := range envHeaders {
+		header.Set(name, val)
+	}
+	return uploadURL, header, nil
+}
+
+// envHeaderValues returns the values of the headers that are read from environment variables. Returns an error if any
+// of the environment variables is not set. In a dry run, the values are references to the environment variables so
+// that their content is not printed.
+func envHeaderValues(envHeaders map[string]string, dryRun bool) (map[string]string, error) {
+	vals := make(map[string]string)
+	for _, name := range sortedKeys(envHeaders) {
+		envVar := envHeaders[name]
+		if dryRun {
+			vals[name] = "$" + envVar
+			continue
+		}
+		val, ok := os.LookupEnv(envVar)
+		if !ok {
+			return nil, errors.Errorf("environment variable %s for header %s is not set", envVar, name)
+		}
+		vals[name] = val
+	}
+	return vals, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultConnectTimeout is the default maximum duration for establishing a connection (including the TLS
+	// handshake) to a server.
+	DefaultConnectTimeout = 30 * time.Second
+	// DefaultRequestTimeout is the default maximum duration of a request, which includes connecting, sending the
+	// request body and reading the response body. The default is long enough to upload large artifacts over a slow
+	// connection.
+	DefaultRequestTimeout = 10 * time.Minute
+)
+
+// HTTPClientParams specifies the parameters of the client returned by NewHTTPClient.
+type HTTPClientParams struct {
+	// Proxy is the URL of the proxy used for all requests. If empty, the proxy is determined using the environment.
+	Proxy string
+	// ConnectTimeout is the maximum duration for establishing a connection. If 0, DefaultConnectTimeout is used.
+	ConnectTimeout time.Duration
+	// RequestTimeout is the maximum duration of a request. If 0, DefaultRequestTimeout is used.
+	RequestTimeout time.Duration
+}
+
+// NewHTTPClient returns the HTTP client that publishers should use for requests. Requests are sent through the proxy
+// specified by the HTTPS_PROXY environment variable (for "https" URLs) or the HTTP_PROXY environment variable (for
+// "http" URLs) unless the host of the request matches an entry in the NO_PROXY environment variable or is a loopback
+// address. The lowercase versions of the environment variables are also supported. If params.Proxy is non-empty, it
+// is used as the proxy for all requests instead of the proxies specified by HTTPS_PROXY and HTTP_PROXY (NO_PROXY is
+// still honored). The environment variables are read every time this function is called.
+//
+// HTTPS requests are sent through the proxy using a CONNECT tunnel, so TLS is negotiated directly with the destination
+// and the headers of the request (including authentication headers) are not visible to the proxy. Credentials for the
+// proxy itself can be specified as the user information of the proxy URL.
+//
+// Requests made using the client fail if a connection cannot be established within the connect timeout or if the
+// request does not complete within the request timeout, so a stalled server cannot block a publish indefinitely.
+func NewHTTPClient(params HTTPClientParams) (*http.Client, error) {
+	proxyCfg := proxyConfigFromEnvironment()
+	if params.Proxy != "" {
+		proxyCfg.httpProxy = params.Proxy
+		proxyCfg.httpsProxy = params.Proxy
+	}
+	proxyFunc, err := proxyCfg.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	connectTimeout := params.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+	requestTimeout := params.RequestTimeout
+	if requestTimeout == 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	return &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+	}, nil
+}
+
+// HTTPClient returns the HTTP client for requests made using the BasicConnectionInfo. See NewHTTPClient for details.
+func (b *BasicConnectionInfo) HTTPClient() (*http.Client, error) {
+	params := HTTPClientParams{
+		Proxy: b.Proxy,
+	}
+	if b.ConnectTimeout != "" {
+		var err error
+		if params.ConnectTimeout, err = time.ParseDuration(b.ConnectTimeout); err != nil {
+			return nil, errors.Wrapf(err, "invalid connect-timeout")
+		}
+	}
+	if b.RequestTimeout != "" {
+		var err error
+		if params.RequestTimeout, err = time.ParseDuration(b.RequestTimeout); err != nil {
+			return nil, errors.Wrapf(err, "invalid request-timeout")
+		}
+	}
+	client, err := NewHTTPClient(params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid proxy configuration")
+	}
+	return client, nil
+}
+
+type proxyConfig struct {
+	httpProxy  string
+	httpsProxy string
+	noProxy    string
+}
+
+func proxyConfigFromEnvironment() proxyConfig {
+	return proxyConfig{
+		httpProxy:  getEnvAny("HTTP_PROXY", "http_proxy"),
+		httpsProxy: getEnvAny("HTTPS_PROXY", "https_proxy"),
+		noProxy:    getEnvAny("NO_PROXY", "no_proxy"),
+	}
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if val := os.Getenv(name); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+func (c proxyConfig) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	httpProxyURL, err := parseProxyURL(c.httpProxy)
+	if err != nil {
+		return nil, err
+	}
+	httpsProxyURL, err := parseProxyURL(c.httpsProxy)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		var proxyURL *url.URL
+		switch req.URL.Scheme {
+		case "https":
+			proxyURL = httpsProxyURL
+		case "http":
+			proxyURL = httpProxyURL
+		}
+		if proxyURL == nil || isLoopback(req.URL.Hostname()) || matchesNoProxy(c.noProxy, req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	// a proxy without a scheme (for example, "proxy.domain.com:8080") is treated as an HTTP proxy
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse proxy URL %s", proxy)
+	}
+	if proxyURL.Host == "" {
+		return nil, errors.Errorf("proxy URL %s does not specify a host", proxy)
+	}
+	return proxyURL, nil
+}
+
+// isLoopback returns true if the provided host is "localhost" or a loopback IP address. Consistent with the proxy
+// handling of the Go standard library, requests to these hosts are never sent through a proxy.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// matchesNoProxy returns true if the host of the provided URL matches an entry in the provided comma-separated NO_PROXY
+// value. An entry of "*" matches all hosts. An entry can be an IP address, a CIDR block or a domain name. A domain name
+// matches the domain and all of its subdomains, and a leading "." or "*." is ignored. If an entry specifies a port, it
+// only matches requests for that port.
+func matchesNoProxy(noProxy string, reqURL *url.URL) bool {
+	host := strings.ToLower(reqURL.Hostname())
+	port := reqURL.Port()
+	if port == "" {
+		switch reqURL.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+	hostIP := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			if hostIP != nil && ipNet.Contains(hostIP) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if hostIP != nil && entryIP.Equal(hostIP) {
+				return true
+			}
+			continue
+		}
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher_test
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/distgo/publisher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var proxyEnvVars = []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"}
+
+// setProxyEnv sets the proxy environment variables to the provided values (unset variables are cleared) and returns a
+// function that restores the original values.
+func setProxyEnv(t *testing.T, vals map[string]string) func() {
+	orig := make(map[string]*string)
+	for _, name := range proxyEnvVars {
+		if val, ok := os.LookupEnv(name); ok {
+			orig[name] = &val
+		} else {
+			orig[name] = nil
+		}
+		if val, ok := vals[name]; ok {
+			require.NoError(t, os.Setenv(name, val))
+		} else {
+			require.NoError(t, os.Unsetenv(name))
+		}
+	}
+	return func() {
+		for name, val := range orig {
+			if val != nil {
+				_ = os.Setenv(name, *val)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		}
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	for i, tc := range []struct {
+		env           map[string]string
+		proxyOverride string
+		reqURL        string
+		wantProxy     string
+	}{
+		{
+			reqURL: "https://registry.domain.com",
+		},
+		{
+			env:       map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080"},
+			reqURL:    "https://registry.domain.com",
+			wantProxy: "http://proxy.domain.com:8080",
+		},
+		{
+			env:    map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080"},
+			reqURL: "http://registry.domain.com",
+		},
+		{
+			env:       map[string]string{"http_proxy": "proxy.domain.com:3128"},
+			reqURL:    "http://registry.domain.com",
+			wantProxy: "http://proxy.domain.com:3128",
+		},
+		{
+			env:    map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080", "NO_PROXY": "internal.com,.domain.com"},
+			reqURL: "https://registry.domain.com",
+		},
+		{
+			env:       map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080", "NO_PROXY": "internal.com"},
+			reqURL:    "https://registry.domain.com",
+			wantProxy: "http://proxy.domain.com:8080",
+		},
+		{
+			env:       map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080", "NO_PROXY": "registry.domain.com:8443"},
+			reqURL:    "https://registry.domain.com",
+			wantProxy: "http://proxy.domain.com:8080",
+		},
+		{
+			env:    map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080", "NO_PROXY": "10.0.0.0/8"},
+			reqURL: "https://10.1.2.3",
+		},
+		{
+			env:    map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080", "NO_PROXY": "*"},
+			reqURL: "https://registry.domain.com",
+		},
+		{
+			env:    map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080"},
+			reqURL: "https://127.0.0.1:8443",
+		},
+		{
+			env:           map[string]string{"HTTPS_PROXY": "http://proxy.domain.com:8080"},
+			proxyOverride: "http://override.domain.com:8080",
+			reqURL:        "https://registry.domain.com",
+			wantProxy:     "http://override.domain.com:8080",
+		},
+		{
+			env:           map[string]string{"NO_PROXY": "registry.domain.com"},
+			proxyOverride: "http://override.domain.com:8080",
+			reqURL:        "https://registry.domain.com",
+		},
+	} {
+		func() {
+			defer setProxyEnv(t, tc.env)()
+
+			client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{Proxy: tc.proxyOverride})
+			require.NoError(t, err, "Case %d", i)
+			req, err := http.NewRequest(http.MethodGet, tc.reqURL, nil)
+			require.NoError(t, err, "Case %d", i)
+
+			proxyURL, err := client.Transport.(*http.Transport).Proxy(req)
+			require.NoError(t, err, "Case %d", i)
+			if tc.wantProxy == "" {
+				assert.Nil(t, proxyURL, "Case %d", i)
+			} else {
+				require.NotNil(t, proxyURL, "Case %d", i)
+				assert.Equal(t, tc.wantProxy, proxyURL.String(), "Case %d", i)
+			}
+		}()
+	}
+}
+
+func TestNewHTTPClientInvalidProxy(t *testing.T) {
+	defer setProxyEnv(t, nil)()
+	_, err := publisher.NewHTTPClient(publisher.HTTPClientParams{Proxy: "http://"})
+	assert.EqualError(t, err, "proxy URL http:// does not specify a host")
+}
+
+func TestUploadFileThroughProxy(t *testing.T) {
+	defer setProxyEnv(t, nil)()
+
+	type forwardedRequest struct {
+		method        string
+		url           string
+		authorization string
+		body          string
+	}
+	var forwarded []forwardedRequest
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		forwarded = append(forwarded, forwardedRequest{
+			method:        r.Method,
+			url:           r.URL.String(),
+			authorization: r.Header.Get("Authorization"),
+			body:          string(body),
+		})
+	}))
+	defer proxy.Close()
+
+	connInfo := publisher.BasicConnectionInfo{
+		URL:                    "http://registry.domain.com",
+		Username:               "testUsername",
+		Password:               "testPassword",
+		SkipUploadVerification: true,
+		Proxy:                  proxy.URL,
+	}
+	_, err := connInfo.UploadFile(publisher.NewFileInfoFromBytes([]byte("content")), "http://registry.domain.com/repo", "artifact.tgz", nil, false, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, "/", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("testUsername", "testPassword")
+	assert.Equal(t, []forwardedRequest{
+		{
+			method:        http.MethodPut,
+			url:           "http://registry.domain.com/repo/artifact.tgz",
+			authorization: req.Header.Get("Authorization"),
+			body:          "content",
+		},
+	}, forwarded)
+}
+
+func TestNewHTTPClientTunnelsTLSThroughProxy(t *testing.T) {
+	defer setProxyEnv(t, nil)()
+
+	var gotAuthorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// stub proxy that records CONNECT requests and tunnels them to the TLS server regardless of the requested host
+	var mu sync.Mutex
+	var connectHosts, proxyAuthorizations []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		connectHosts = append(connectHosts, r.Host)
+		proxyAuthorizations = append(proxyAuthorizations, r.Header.Get("Proxy-Authorization"))
+		mu.Unlock()
+
+		dstConn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		srcConn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		_, err = io.WriteString(srcConn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		require.NoError(t, err)
+		go func() {
+			defer func() {
+				_ = dstConn.Close()
+			}()
+			_, _ = io.Copy(dstConn, srcConn)
+		}()
+		go func() {
+			defer func() {
+				_ = srcConn.Close()
+			}()
+			_, _ = io.Copy(srcConn, dstConn)
+		}()
+	}))
+	defer proxy.Close()
+
+	client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{Proxy: strings.Replace(proxy.URL, "http://", "http://proxyUser:proxyPassword@", 1)})
+	require.NoError(t, err)
+	// the certificate of the TLS test server is valid for "example.com"
+	certPool := x509.NewCertPool()
+	certPool.AddCert(server.Certificate())
+	client.Transport.(*http.Transport).TLSClientConfig.RootCAs = certPool
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "https://example.com:"+port+"/artifact.tgz", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("testUsername", "testPassword")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, req.Header.Get("Authorization"), gotAuthorization)
+	assert.Equal(t, []string{"example.com:" + port}, connectHosts)
+	proxyReq, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	proxyReq.SetBasicAuth("proxyUser", "proxyPassword")
+	assert.Equal(t, []string{proxyReq.Header.Get("Authorization")}, proxyAuthorizations)
+}
+
+func TestNewHTTPClientRequestTimeout(t *testing.T) {
+	defer setProxyEnv(t, nil)()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{RequestTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestNewHTTPClientConnectTimeout(t *testing.T) {
+	defer setProxyEnv(t, nil)()
+
+	// listener that accepts connections but never responds, so the TLS handshake stalls
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{ConnectTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = client.Get("https://" + listener.Addr().String())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TLS handshake timeout")
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestUploadFileRequestTimeout(t *testing.T) {
+	defer setProxyEnv(t, nil)()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	connInfo := publisher.BasicConnectionInfo{
+		RequestTimeout: "100ms",
+	}
+	_, err := connInfo.UploadFile(publisher.NewFileInfoFromBytes([]byte("content")), server.URL+"/repo", "artifact.tgz", nil, false, ioutil.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+}
+
+func TestBasicConnectionInfoHTTPClientInvalidTimeouts(t *testing.T) {
+	for i, tc := range []struct {
+		connInfo publisher.BasicConnectionInfo
+		wantErr  string
+	}{
+		{
+			publisher.BasicConnectionInfo{ConnectTimeout: "soon"},
+			`invalid connect-timeout: time: invalid duration "soon"`,
+		},
+		{
+			publisher.BasicConnectionInfo{RequestTimeout: "10"},
+			`invalid request-timeout: time: missing unit in duration "10"`,
+		},
+	} {
+		_, err := tc.connInfo.HTTPClient()
+		assert.EqualError(t, err, tc.wantErr, "Case %d", i)
+	}
+}
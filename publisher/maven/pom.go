@@ -15,9 +15,13 @@
 package maven
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
+	"io"
 
 	"github.com/palantir/distgo/distgo"
+	"github.com/pkg/errors"
 )
 
 // Based on https://maven.apache.org/ref/3.5.3/maven-model/maven.html
@@ -36,16 +40,32 @@ const pomTemplate = `<project xmlns="http://maven.apache.org/POM/4.0.0" xmlns:xs
 // distributions with differing non-empty packaging extensions, since there is no well-defined way to generate a POM
 // for such distributions.
 func POM(groupID string, outputInfo distgo.ProductTaskOutputInfo) (string, string, error) {
+	return POMFromTemplate("", groupID, "", outputInfo)
+}
+
+// POMFromTemplate produces a POM file name and content for a product using the provided template. If the template is
+// empty, the default POM template is used. The template can use the following functions:
+//   - {{GroupID}}: the provided group ID
+//   - {{Product}}: the ID of the product, which is used as the artifact ID
+//   - {{Version}}: the version of the project
+//   - {{Packaging}}: the packaging extension of the dists of the product (empty if there is none)
+//   - {{Description}}: the provided description escaped for use in XML
+//
+// Returns an error if the rendered POM is not well-formed XML with a "project" root element.
+func POMFromTemplate(tmpl, groupID, description string, outputInfo distgo.ProductTaskOutputInfo) (string, string, error) {
 	packaging, err := getSinglePackagingExtensionForProduct(outputInfo)
 	if err != nil {
 		return "", "", err
 	}
 	pomName := fmt.Sprintf("%s-%s.pom", outputInfo.Product.ID, outputInfo.Project.Version)
 
-	pomContent, err := renderPOM(outputInfo.Product.ID, outputInfo.Project.Version, groupID, packaging)
+	pomContent, err := renderPOM(tmpl, outputInfo.Product.ID, outputInfo.Project.Version, groupID, packaging, description)
 	if err != nil {
 		return "", "", err
 	}
+	if err := validatePOM(pomContent); err != nil {
+		return "", "", errors.Wrapf(err, "invalid POM %s", pomName)
+	}
 	return pomName, pomContent, nil
 }
 
@@ -82,11 +102,41 @@ func Packaging(distID distgo.DistID, outputInfo distgo.ProductTaskOutputInfo) st
 	return outputInfo.Product.DistOutputInfos.DistInfos[distID].PackagingExtension
 }
 
-func renderPOM(productID distgo.ProductID, version, groupID, packaging string) (string, error) {
-	return distgo.RenderTemplate(pomTemplate, nil,
+func renderPOM(tmpl string, productID distgo.ProductID, version, groupID, packaging, description string) (string, error) {
+	if tmpl == "" {
+		tmpl = pomTemplate
+	}
+	escapedDescription := &bytes.Buffer{}
+	if err := xml.EscapeText(escapedDescription, []byte(description)); err != nil {
+		return "", errors.Wrapf(err, "failed to escape description")
+	}
+	return distgo.RenderTemplate(tmpl, nil,
 		distgo.ProductTemplateFunction(productID),
 		distgo.VersionTemplateFunction(version),
 		distgo.GroupIDTemplateFunction(groupID),
 		distgo.PackagingTemplateFunction(packaging),
+		distgo.TemplateValueFunction("Description", escapedDescription.String()),
 	)
 }
+
+// validatePOM returns an error if the provided content is not well-formed XML or if its root element is not "project".
+func validatePOM(content string) error {
+	decoder := xml.NewDecoder(bytes.NewReader([]byte(content)))
+	var rootName string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "POM is not well-formed XML")
+		}
+		if startElem, ok := token.(xml.StartElement); ok && rootName == "" {
+			rootName = startElem.Name.Local
+		}
+	}
+	if rootName != "project" {
+		return errors.Errorf(`root element of POM must be "project", was %q`, rootName)
+	}
+	return nil
+}
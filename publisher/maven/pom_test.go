@@ -65,12 +65,109 @@ func TestRenderPOM(t *testing.T) {
 `,
 		},
 	} {
-		got, err := renderPOM(tc.productID, tc.version, tc.groupID, tc.packagingType)
+		got, err := renderPOM("", tc.productID, tc.version, tc.groupID, tc.packagingType, "")
 		require.NoError(t, err, "Case %d", i)
 		assert.Equal(t, tc.want, got, "Case %d: %s\nOutput:\n%s", i, tc.name, got)
 	}
 }
 
+func TestPOMFromTemplate(t *testing.T) {
+	outputInfo := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			Version: "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "foo",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistIDs: []distgo.DistID{"os-arch-bin"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"os-arch-bin": {
+						PackagingExtension: "tgz",
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range []struct {
+		name        string
+		tmpl        string
+		description string
+		want        string
+		wantErr     string
+	}{
+		{
+			"empty template uses default POM",
+			"",
+			"",
+			`<project xmlns="http://maven.apache.org/POM/4.0.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+  xsi:schemaLocation="http://maven.apache.org/POM/4.0.0 http://maven.apache.org/xsd/maven-4.0.0.xsd">
+  <modelVersion>4.0.0</modelVersion>
+
+  <groupId>com.palantir</groupId>
+  <artifactId>foo</artifactId>
+  <version>1.0.0</version>
+  <packaging>tgz</packaging>
+</project>
+`,
+			"",
+		},
+		{
+			"custom template is rendered with product information",
+			`<project>
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>{{GroupID}}</groupId>
+  <artifactId>{{Product}}</artifactId>
+  <version>{{Version}}</version>
+  <packaging>{{Packaging}}</packaging>
+  <description>{{Description}}</description>
+</project>
+`,
+			"Foo & <Bar>",
+			`<project>
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>com.palantir</groupId>
+  <artifactId>foo</artifactId>
+  <version>1.0.0</version>
+  <packaging>tgz</packaging>
+  <description>Foo &amp; &lt;Bar&gt;</description>
+</project>
+`,
+			"",
+		},
+		{
+			"template that renders malformed XML is rejected",
+			`<project><artifactId>{{Product}}</project>`,
+			"",
+			"",
+			"invalid POM foo-1.0.0.pom: POM is not well-formed XML: XML syntax error on line 1: element <artifactId> closed by </project>",
+		},
+		{
+			"template with root element other than project is rejected",
+			`<pom><artifactId>{{Product}}</artifactId></pom>`,
+			"",
+			"",
+			`invalid POM foo-1.0.0.pom: root element of POM must be "project", was "pom"`,
+		},
+		{
+			"template that cannot be parsed is rejected",
+			`<project>{{Unknown}}</project>`,
+			"",
+			"",
+			`failed to parse template <project>{{Unknown}}</project>: template: distgoTemplate:1: function "Unknown" not defined`,
+		},
+	} {
+		gotName, got, err := POMFromTemplate(tc.tmpl, "com.palantir", tc.description, outputInfo)
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d: %s", i, tc.name)
+			continue
+		}
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, "foo-1.0.0.pom", gotName, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, got, "Case %d: %s\nOutput:\n%s", i, tc.name, got)
+	}
+}
+
 func TestGetSinglePackagingExtensionForProduct(t *testing.T) {
 	for _, tc := range []struct {
 		name         string
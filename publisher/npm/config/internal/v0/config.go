@@ -0,0 +1,52 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	// Registry is the URL of the npm registry to which the package is published. If not specified,
+	// "https://registry.npmjs.org/" is used.
+	Registry string `yaml:"registry,omitempty"`
+	// Token is the authentication token for the registry. If not specified, the value of the NPM_TOKEN environment
+	// variable is used.
+	Token string `yaml:"token,omitempty"`
+	// Scope is the scope of the published package (for example, "@org"). If specified and the name in the
+	// "package.json" of the package is not scoped, the package is published as "<scope>/<name>".
+	Scope string `yaml:"scope,omitempty"`
+	// Tag is the distribution tag that is set to the published version. If not specified, "latest" is used.
+	Tag string `yaml:"tag,omitempty"`
+	// Access specifies whether the published package is "public" or "restricted". If not specified, the default of the
+	// registry is used (for the public npm registry, scoped packages are restricted by default).
+	Access string `yaml:"access,omitempty"`
+	// Progress specifies how the progress of uploads is reported: "bar" (an interactive progress bar), "log" (a line
+	// with the percentage and throughput is printed every ProgressInterval, which is suitable for CI logs) or "none"
+	// (progress is not reported). If not specified, defaults to "bar".
+	Progress string `yaml:"progress,omitempty"`
+	// ProgressInterval is the interval at which progress is printed when Progress is "log" (for example, "30s"). If not
+	// specified, defaults to "5s".
+	ProgressInterval string `yaml:"progress-interval,omitempty"`
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal npm publisher v0 configuration")
+	}
+	return cfgBytes, nil
+}
@@ -0,0 +1,308 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher"
+	"github.com/palantir/distgo/publisher/npm/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	TypeName = "npm"
+
+	defaultRegistry = "https://registry.npmjs.org/"
+	defaultTag      = "latest"
+	tokenEnvVar     = "NPM_TOKEN"
+
+	accessPublic     = "public"
+	accessRestricted = "restricted"
+)
+
+type npmPublisher struct{}
+
+func PublisherCreator() publisher.Creator {
+	return publisher.NewCreator(TypeName, func() distgo.Publisher {
+		return &npmPublisher{}
+	})
+}
+
+func (p *npmPublisher) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+var (
+	npmPublisherRegistryFlag = distgo.PublisherFlag{
+		Name:        "registry",
+		Description: "npm registry URL (if unspecified, https://registry.npmjs.org/ will be used)",
+		Type:        distgo.StringFlag,
+	}
+	npmPublisherTokenFlag = distgo.PublisherFlag{
+		Name:        "token",
+		Description: "npm registry token (if unspecified, the NPM_TOKEN environment variable will be used)",
+		Type:        distgo.StringFlag,
+	}
+	npmPublisherScopeFlag = distgo.PublisherFlag{
+		Name:        "scope",
+		Description: "scope of the published package (such as @org)",
+		Type:        distgo.StringFlag,
+	}
+	npmPublisherTagFlag = distgo.PublisherFlag{
+		Name:        "tag",
+		Description: "distribution tag of the published version (if unspecified, latest will be used)",
+		Type:        distgo.StringFlag,
+	}
+	npmPublisherAccessFlag = distgo.PublisherFlag{
+		Name:        "access",
+		Description: "access level of the published package: public or restricted (if unspecified, the registry default will be used)",
+		Type:        distgo.StringFlag,
+	}
+)
+
+func (p *npmPublisher) Flags() ([]distgo.PublisherFlag, error) {
+	return []distgo.PublisherFlag{
+		npmPublisherRegistryFlag,
+		npmPublisherTokenFlag,
+		npmPublisherScopeFlag,
+		npmPublisherTagFlag,
+		npmPublisherAccessFlag,
+	}, nil
+}
+
+func (p *npmPublisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfgYML []byte, flagVals map[distgo.PublisherFlagName]interface{}, dryRun bool, stdout io.Writer) error {
+	var cfg config.NPM
+	if err := yaml.Unmarshal(cfgYML, &cfg); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal configuration")
+	}
+	if err := publisher.SetConfigValues(flagVals,
+		npmPublisherRegistryFlag, &cfg.Registry,
+		npmPublisherTokenFlag, &cfg.Token,
+		npmPublisherScopeFlag, &cfg.Scope,
+		npmPublisherTagFlag, &cfg.Tag,
+		npmPublisherAccessFlag, &cfg.Access,
+	); err != nil {
+		return err
+	}
+	if cfg.Registry == "" {
+		cfg.Registry = defaultRegistry
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = defaultTag
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv(tokenEnvVar)
+	}
+	if cfg.Token == "" && !dryRun {
+		return errors.Errorf("%s was not specified -- it must be specified in configuration, using a flag or using the %s environment variable", npmPublisherTokenFlag.Name, tokenEnvVar)
+	}
+	switch cfg.Access {
+	case "", accessPublic, accessRestricted:
+	default:
+		return errors.Errorf("invalid %s %q: must be one of %q or %q", npmPublisherAccessFlag.Name, cfg.Access, accessPublic, accessRestricted)
+	}
+	if cfg.Scope != "" && !strings.HasPrefix(cfg.Scope, "@") {
+		cfg.Scope = "@" + cfg.Scope
+	}
+	if err := publisher.ValidateProgress(cfg.Progress); err != nil {
+		return err
+	}
+	progressInterval, err := publisher.ParseProgressInterval(cfg.ProgressInterval)
+	if err != nil {
+		return err
+	}
+
+	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
+		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
+			if err := publishPackage(cfg, productTaskOutputInfo.Project.Version, currArtifactPath, progressInterval, dryRun, stdout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func publishPackage(cfg config.NPM, projectVersion, tarballPath string, progressInterval time.Duration, dryRun bool, stdout io.Writer) (rErr error) {
+	if dryRun {
+		distgo.DryRunPrintln(stdout, fmt.Sprintf("Publishing %s to npm registry %s with tag %s", tarballPath, cfg.Registry, cfg.Tag))
+		return nil
+	}
+
+	fileInfo, err := publisher.NewFileInfo(tarballPath)
+	if err != nil {
+		return err
+	}
+	manifest, err := PackageJSON(fileInfo.Bytes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read package.json from %s", tarballPath)
+	}
+	doc, name, version, err := publishDocument(cfg, manifest, projectVersion, fileInfo)
+	if err != nil {
+		return errors.Wrapf(err, "invalid package.json in %s", tarballPath)
+	}
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal publish request for %s", tarballPath)
+	}
+
+	packageURL := PackageURL(cfg.Registry, name)
+	_, _ = fmt.Fprintf(stdout, "Publishing %s to %s as %s@%s with tag %s\n", tarballPath, cfg.Registry, name, version, cfg.Tag)
+
+	// the tarball is embedded in the publish request, so the progress of the upload is the progress of the request body
+	body, finishProgress, err := publisher.NewUploadProgressReader(cfg.Progress, progressInterval, bytes.NewReader(docBytes), int64(len(docBytes)), path.Base(tarballPath))
+	if err != nil {
+		return err
+	}
+	defer finishProgress()
+	req, err := http.NewRequest(http.MethodPut, packageURL, body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", packageURL)
+	}
+	req.ContentLength = int64(len(docBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+
+	client, err := publisher.NewHTTPClient(publisher.HTTPClientParams{})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to publish %s@%s to %s", name, version, cfg.Registry)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for %s", packageURL)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusConflict {
+		return errors.Errorf("failed to publish %s@%s to %s: version %s of %s has already been published and a published version cannot be republished", name, version, cfg.Registry, version, name)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg := fmt.Sprintf("publishing %s@%s to %s resulted in response %q", name, version, cfg.Registry, resp.Status)
+		if body, err := ioutil.ReadAll(resp.Body); err == nil && len(body) > 0 {
+			msg += ":\n" + string(body)
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// PackageURL returns the URL of the registry document for the package with the provided name. The "/" that separates
+// the scope and name of a scoped package is escaped.
+func PackageURL(registry, name string) string {
+	return strings.TrimSuffix(registry, "/") + "/" + url.PathEscape(name)
+}
+
+// PackageJSON returns the content of the "package.json" file in the provided gzip-compressed tarball. The
+// "package.json" file must be at the root of the tarball or in a top-level directory (npm packages created by
+// "npm pack" store it as "package/package.json").
+func PackageJSON(tarballBytes []byte) (map[string]interface{}, error) {
+	gzf, err := gzip.NewReader(bytes.NewReader(tarballBytes))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open tarball as gzip")
+	}
+	tr := tar.NewReader(gzf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read tarball")
+		}
+		entryPath := strings.TrimPrefix(path.Clean(hdr.Name), "./")
+		if path.Base(entryPath) != "package.json" || strings.Count(entryPath, "/") > 1 {
+			continue
+		}
+		var manifest map[string]interface{}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal %s as JSON", hdr.Name)
+		}
+		return manifest, nil
+	}
+	return nil, errors.Errorf("tarball does not contain a package.json file")
+}
+
+// publishDocument returns the registry document that publishes the provided package along with the name and version
+// of the package. If the manifest does not specify a version, the project version is used.
+func publishDocument(cfg config.NPM, manifest map[string]interface{}, projectVersion string, fileInfo publisher.FileInfo) (map[string]interface{}, string, string, error) {
+	name, _ := manifest["name"].(string)
+	if name == "" {
+		return nil, "", "", errors.Errorf("name must be specified")
+	}
+	if cfg.Scope != "" && !strings.HasPrefix(name, "@") {
+		name = cfg.Scope + "/" + name
+	}
+	version, _ := manifest["version"].(string)
+	if version == "" {
+		version = projectVersion
+	}
+
+	tarballName := fmt.Sprintf("%s-%s.tgz", name, version)
+	sha512Sum := sha512.Sum512(fileInfo.Bytes)
+
+	manifest["name"] = name
+	manifest["version"] = version
+	manifest["_id"] = name + "@" + version
+	manifest["dist"] = map[string]interface{}{
+		"shasum":    fileInfo.Checksums.SHA1,
+		"integrity": "sha512-" + base64.StdEncoding.EncodeToString(sha512Sum[:]),
+		"tarball":   fmt.Sprintf("%s/%s/-/%s", strings.TrimSuffix(cfg.Registry, "/"), name, tarballName),
+	}
+
+	doc := map[string]interface{}{
+		"_id":  name,
+		"name": name,
+		"dist-tags": map[string]string{
+			cfg.Tag: version,
+		},
+		"versions": map[string]interface{}{
+			version: manifest,
+		},
+		"_attachments": map[string]interface{}{
+			tarballName: map[string]interface{}{
+				"content_type": "application/octet-stream",
+				"data":         base64.StdEncoding.EncodeToString(fileInfo.Bytes),
+				"length":       len(fileInfo.Bytes),
+			},
+		},
+	}
+	if description, ok := manifest["description"]; ok {
+		doc["description"] = description
+	}
+	if cfg.Access != "" {
+		doc["access"] = cfg.Access
+	}
+	return doc, name, version, nil
+}
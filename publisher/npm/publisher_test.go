@@ -0,0 +1,322 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package npm_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher/npm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type publishRequest struct {
+	method        string
+	path          string
+	authorization string
+	doc           map[string]interface{}
+}
+
+// mockRegistry returns a server that records publish requests and responds with 409 Conflict when a version of a
+// package that has already been published is published again.
+func mockRegistry(t *testing.T, requests *[]publishRequest) *httptest.Server {
+	published := make(map[string]bool)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var doc map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&doc))
+		*requests = append(*requests, publishRequest{
+			method:        r.Method,
+			path:          r.URL.EscapedPath(),
+			authorization: r.Header.Get("Authorization"),
+			doc:           doc,
+		})
+		for version := range doc["versions"].(map[string]interface{}) {
+			key := r.URL.EscapedPath() + "@" + version
+			if published[key] {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"error":"cannot modify pre-existing version"}`))
+				return
+			}
+			published[key] = true
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func TestNPMPublish(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	var requests []publishRequest
+	server := mockRegistry(t, &requests)
+	defer server.Close()
+
+	info := writeNPMArtifact(t, tmp, `{"name":"cli","version":"1.0.0","description":"Test CLI","bin":{"cli":"bin/cli"}}`)
+	cfgYML := []byte(`registry: ` + server.URL + `
+token: testToken
+scope: org
+tag: next
+access: public
+`)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, npm.PublisherCreator().Publisher().RunPublish(info, cfgYML, nil, false, buf))
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPut, requests[0].method)
+	assert.Equal(t, "/@org%2Fcli", requests[0].path)
+	assert.Equal(t, "Bearer testToken", requests[0].authorization)
+	assert.Contains(t, buf.String(), "as @org/cli@1.0.0 with tag next")
+
+	doc := requests[0].doc
+	assert.Equal(t, "@org/cli", doc["name"])
+	assert.Equal(t, "Test CLI", doc["description"])
+	assert.Equal(t, "public", doc["access"])
+	assert.Equal(t, map[string]interface{}{"next": "1.0.0"}, doc["dist-tags"])
+
+	version := doc["versions"].(map[string]interface{})["1.0.0"].(map[string]interface{})
+	assert.Equal(t, "@org/cli@1.0.0", version["_id"])
+	assert.Equal(t, map[string]interface{}{"cli": "bin/cli"}, version["bin"])
+	dist := version["dist"].(map[string]interface{})
+	assert.Equal(t, server.URL+"/@org/cli/-/@org/cli-1.0.0.tgz", dist["tarball"])
+	assert.Regexp(t, "^sha512-", dist["integrity"])
+
+	attachment := doc["_attachments"].(map[string]interface{})["@org/cli-1.0.0.tgz"].(map[string]interface{})
+	data, err := base64.StdEncoding.DecodeString(attachment["data"].(string))
+	require.NoError(t, err)
+	artifactBytes, err := ioutil.ReadFile(path.Join(tmp, "out/dist/npm-product/1.0.0/npm/npm-product-1.0.0.tgz"))
+	require.NoError(t, err)
+	assert.Equal(t, artifactBytes, data)
+	assert.Equal(t, float64(len(artifactBytes)), attachment["length"])
+
+	err = npm.PublisherCreator().Publisher().RunPublish(info, cfgYML, nil, false, ioutil.Discard)
+	assert.EqualError(t, err, "failed to publish @org/cli@1.0.0 to "+server.URL+": version 1.0.0 of @org/cli has already been published and a published version cannot be republished")
+}
+
+func TestNPMPublishUsesProjectVersion(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	var requests []publishRequest
+	server := mockRegistry(t, &requests)
+	defer server.Close()
+
+	info := writeNPMArtifact(t, tmp, `{"name":"@other/cli"}`)
+	origToken, hadToken := os.LookupEnv("NPM_TOKEN")
+	require.NoError(t, os.Setenv("NPM_TOKEN", "envToken"))
+	defer func() {
+		if hadToken {
+			_ = os.Setenv("NPM_TOKEN", origToken)
+		} else {
+			_ = os.Unsetenv("NPM_TOKEN")
+		}
+	}()
+
+	require.NoError(t, npm.PublisherCreator().Publisher().RunPublish(info, []byte("registry: "+server.URL+"\nscope: org\n"), nil, false, ioutil.Discard))
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/@other%2Fcli", requests[0].path)
+	assert.Equal(t, "Bearer envToken", requests[0].authorization)
+	assert.Equal(t, map[string]interface{}{"latest": "1.0.0"}, requests[0].doc["dist-tags"])
+	assert.NotContains(t, requests[0].doc, "access")
+}
+
+func TestNPMPublishProgress(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	var requests []publishRequest
+	server := mockRegistry(t, &requests)
+	defer server.Close()
+
+	info := writeNPMArtifact(t, tmp, `{"name":"cli"}`)
+	stderr := captureStderr(t, func() {
+		err = npm.PublisherCreator().Publisher().RunPublish(info, []byte("registry: "+server.URL+"\ntoken: testToken\nprogress: log\n"), nil, false, ioutil.Discard)
+	})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Contains(t, stderr, "Uploading npm-product-1.0.0.tgz: 100% (")
+}
+
+func TestNPMPublishErrors(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	info := writeNPMArtifact(t, tmp, `{"name":"cli"}`)
+
+	origToken, hadToken := os.LookupEnv("NPM_TOKEN")
+	require.NoError(t, os.Unsetenv("NPM_TOKEN"))
+	defer func() {
+		if hadToken {
+			_ = os.Setenv("NPM_TOKEN", origToken)
+		}
+	}()
+
+	for i, tc := range []struct {
+		cfgYML  string
+		wantErr string
+	}{
+		{
+			"registry: http://localhost\n",
+			"token was not specified -- it must be specified in configuration, using a flag or using the NPM_TOKEN environment variable",
+		},
+		{
+			"token: testToken\naccess: private\n",
+			`invalid access "private": must be one of "public" or "restricted"`,
+		},
+		{
+			"token: testToken\nprogress: verbose\n",
+			`invalid progress "verbose": must be one of "bar", "log" or "none"`,
+		},
+	} {
+		err := npm.PublisherCreator().Publisher().RunPublish(info, []byte(tc.cfgYML), nil, false, ioutil.Discard)
+		assert.EqualError(t, err, tc.wantErr, "Case %d", i)
+	}
+}
+
+func TestNPMPublishDryRun(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	var requests []publishRequest
+	server := mockRegistry(t, &requests)
+	defer server.Close()
+
+	info := writeNPMArtifact(t, tmp, `{"name":"cli"}`)
+	buf := &bytes.Buffer{}
+	require.NoError(t, npm.PublisherCreator().Publisher().RunPublish(info, []byte("registry: "+server.URL+"\n"), nil, true, buf))
+	assert.Empty(t, requests)
+	assert.Contains(t, buf.String(), "[DRY RUN] Publishing ")
+}
+
+func TestPackageJSON(t *testing.T) {
+	for i, tc := range []struct {
+		files   map[string]string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			files: map[string]string{"package/package.json": `{"name":"cli"}`},
+			want:  map[string]interface{}{"name": "cli"},
+		},
+		{
+			files: map[string]string{"./package.json": `{"name":"root"}`},
+			want:  map[string]interface{}{"name": "root"},
+		},
+		{
+			files:   map[string]string{"package/node_modules/dep/package.json": `{"name":"dep"}`},
+			wantErr: "tarball does not contain a package.json file",
+		},
+	} {
+		got, err := npm.PackageJSON(tarGZ(t, tc.files))
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d", i)
+			continue
+		}
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, tc.want, got, "Case %d", i)
+	}
+}
+
+func writeNPMArtifact(t *testing.T, projectDir, packageJSON string) distgo.ProductTaskOutputInfo {
+	info := distgo.ProductTaskOutputInfo{
+		Project: distgo.ProjectInfo{
+			ProjectDir: projectDir,
+			Version:    "1.0.0",
+		},
+		Product: distgo.ProductOutputInfo{
+			ID: "npm-product",
+			DistOutputInfos: &distgo.DistOutputInfos{
+				DistOutputDir: "out/dist",
+				DistIDs:       []distgo.DistID{"npm"},
+				DistInfos: map[distgo.DistID]distgo.DistOutputInfo{
+					"npm": {
+						DistArtifactNames: []string{"npm-product-1.0.0.tgz"},
+					},
+				},
+			},
+		},
+	}
+	for _, artifactPaths := range info.ProductDistArtifactPaths() {
+		for _, artifactPath := range artifactPaths {
+			require.NoError(t, os.MkdirAll(path.Dir(artifactPath), 0755))
+			require.NoError(t, ioutil.WriteFile(artifactPath, tarGZ(t, map[string]string{
+				"package/package.json": packageJSON,
+				"package/bin/cli":      "#!/bin/sh\n",
+			}), 0644))
+		}
+	}
+	return info
+}
+
+func tarGZ(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func captureStderr(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	origStderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = origStderr
+	}()
+
+	outputC := make(chan string)
+	go func() {
+		output, _ := ioutil.ReadAll(r)
+		outputC <- string(output)
+	}()
+	fn()
+	require.NoError(t, w.Close())
+	return <-outputC
+}
@@ -0,0 +1,71 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriter writes the output written to it to an underlying writer line by line with every line prefixed by a
+// fixed string. Writes to the underlying writer are guarded by a mutex that can be shared by multiple prefixWriters so
+// that the lines written by concurrent writers are not interleaved. Incomplete lines are buffered until they are
+// completed or the writer is flushed.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{
+		w:      w,
+		mu:     mu,
+		prefix: prefix,
+	}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		w.writeLine(w.buf[:idx+1])
+		w.buf = w.buf[idx+1:]
+	}
+	// retain only the incomplete line so that the buffer does not grow with the output
+	w.buf = append([]byte(nil), w.buf...)
+	return len(p), nil
+}
+
+// Flush writes the buffered incomplete line, if any, terminated by a newline.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	w.writeLine(append(w.buf, '\n'))
+	w.buf = nil
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = io.WriteString(w.w, w.prefix)
+	_, _ = w.w.Write(line)
+}
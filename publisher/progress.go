@@ -0,0 +1,190 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/cheggaaa/pb.v1"
+)
+
+const (
+	// ProgressBar reports the progress of uploads using an interactive progress bar.
+	ProgressBar = "bar"
+	// ProgressLog reports the progress of uploads by printing a line with the percentage and throughput at regular
+	// intervals, which is suitable for non-interactive logs.
+	ProgressLog = "log"
+	// ProgressNone disables progress reporting for uploads.
+	ProgressNone = "none"
+
+	// DefaultProgressInterval is the default interval at which progress is printed when the mode is ProgressLog.
+	DefaultProgressInterval = 5 * time.Second
+)
+
+// Progress is the progress of a read operation.
+type Progress struct {
+	// Bytes is the number of bytes read so far.
+	Bytes int64
+	// Total is the total number of bytes that will be read.
+	Total int64
+	// Elapsed is the time elapsed since the first read.
+	Elapsed time.Duration
+}
+
+// Percent returns the percentage of the total number of bytes that has been read.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 100
+	}
+	return float64(p.Bytes) * 100 / float64(p.Total)
+}
+
+// BytesPerSecond returns the average throughput of the read operation.
+func (p Progress) BytesPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%.0f%% (%s of %s, %s/s)", p.Percent(), formatBytes(float64(p.Bytes)), formatBytes(float64(p.Total)), formatBytes(p.BytesPerSecond()))
+}
+
+type progressReader struct {
+	r          io.Reader
+	interval   time.Duration
+	report     func(Progress)
+	progress   Progress
+	start      time.Time
+	lastReport time.Time
+	done       bool
+}
+
+// NewProgressReader returns a reader that reads from the provided reader and calls report with the progress of the
+// read at most once per interval. report is always called when the reader returns io.EOF or when the total number of
+// bytes has been read. If interval is not positive, report is called after every read. report is called synchronously
+// by Read, so it is never called after reading completes.
+func NewProgressReader(r io.Reader, total int64, interval time.Duration, report func(Progress)) io.Reader {
+	return &progressReader{
+		r:        r,
+		interval: interval,
+		report:   report,
+		progress: Progress{
+			Total: total,
+		},
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	now := time.Now()
+	if r.start.IsZero() {
+		r.start = now
+		r.lastReport = now
+	}
+	n, err := r.r.Read(p)
+	r.progress.Bytes += int64(n)
+	if r.done {
+		return n, err
+	}
+
+	now = time.Now()
+	r.progress.Elapsed = now.Sub(r.start)
+	complete := err == io.EOF || (r.progress.Total > 0 && r.progress.Bytes >= r.progress.Total)
+	if complete || now.Sub(r.lastReport) >= r.interval {
+		r.lastReport = now
+		r.done = complete
+		r.report(r.progress)
+	}
+	return n, err
+}
+
+// NewUploadProgressReader returns a reader for the content of the upload with the provided name that reports the
+// progress of the upload to stderr using the provided mode (one of ProgressBar, ProgressLog or ProgressNone; "" is
+// treated as ProgressBar). Progress is written to stderr so that it is not mixed into the output of the publish
+// operation. The returned function must be called once the upload completes.
+func NewUploadProgressReader(mode string, interval time.Duration, r io.Reader, size int64, name string) (io.Reader, func(), error) {
+	switch mode {
+	case "", ProgressBar:
+		bar := pb.New64(size).SetUnits(pb.U_BYTES)
+		bar.Output = os.Stderr
+		bar.SetMaxWidth(120)
+		bar.Start()
+		return bar.NewProxyReader(r), func() { bar.Finish() }, nil
+	case ProgressLog:
+		if interval == 0 {
+			interval = DefaultProgressInterval
+		}
+		return NewProgressReader(r, size, interval, func(p Progress) {
+			_, _ = fmt.Fprintf(os.Stderr, "Uploading %s: %s\n", name, p)
+		}), func() {}, nil
+	case ProgressNone:
+		return r, func() {}, nil
+	default:
+		return nil, nil, ValidateProgress(mode)
+	}
+}
+
+// ValidateProgress returns an error if the provided progress mode is not one of ProgressBar, ProgressLog or
+// ProgressNone. "" is valid and is treated as ProgressBar.
+func ValidateProgress(mode string) error {
+	switch mode {
+	case "", ProgressBar, ProgressLog, ProgressNone:
+		return nil
+	default:
+		return errors.Errorf("invalid progress %q: must be one of %q, %q or %q", mode, ProgressBar, ProgressLog, ProgressNone)
+	}
+}
+
+// UploadProgressReader returns a reader for the content of an upload that reports progress as configured by the
+// BasicConnectionInfo. See NewUploadProgressReader for details.
+func (b *BasicConnectionInfo) UploadProgressReader(r io.Reader, size int64, name string) (io.Reader, func(), error) {
+	interval, err := ParseProgressInterval(b.ProgressInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewUploadProgressReader(b.Progress, interval, r, size, name)
+}
+
+// ParseProgressInterval parses the provided progress-interval configuration value. Returns 0 if the value is empty,
+// in which case DefaultProgressInterval is used.
+func ParseProgressInterval(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid progress-interval")
+	}
+	return interval, nil
+}
+
+func formatBytes(n float64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB"}
+	i := -1
+	for n >= unit && i < len(suffixes)-1 {
+		n /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", n, suffixes[i])
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/palantir/distgo/publisher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressReader(t *testing.T) {
+	for i, tc := range []struct {
+		interval  time.Duration
+		wantBytes []int64
+	}{
+		{
+			interval:  0,
+			wantBytes: []int64{1, 2, 3, 4, 5},
+		},
+		{
+			interval:  time.Hour,
+			wantBytes: []int64{5},
+		},
+	} {
+		var reports []publisher.Progress
+		r := publisher.NewProgressReader(iotest.OneByteReader(strings.NewReader("01234")), 5, tc.interval, func(p publisher.Progress) {
+			reports = append(reports, p)
+		})
+		content, err := ioutil.ReadAll(r)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, "01234", string(content), "Case %d", i)
+
+		var gotBytes []int64
+		for _, report := range reports {
+			gotBytes = append(gotBytes, report.Bytes)
+			assert.Equal(t, int64(5), report.Total, "Case %d", i)
+		}
+		assert.Equal(t, tc.wantBytes, gotBytes, "Case %d", i)
+		assert.Equal(t, float64(100), reports[len(reports)-1].Percent(), "Case %d", i)
+	}
+}
+
+func TestProgressString(t *testing.T) {
+	for i, tc := range []struct {
+		progress publisher.Progress
+		want     string
+	}{
+		{
+			progress: publisher.Progress{Bytes: 512 * 1024 * 1024, Total: 1024 * 1024 * 1024, Elapsed: 10 * time.Second},
+			want:     "50% (512.0 MiB of 1.0 GiB, 51.2 MiB/s)",
+		},
+		{
+			progress: publisher.Progress{Bytes: 100, Total: 400},
+			want:     "25% (100 B of 400 B, 0 B/s)",
+		},
+	} {
+		assert.Equal(t, tc.want, tc.progress.String(), "Case %d", i)
+	}
+}
+
+func TestUploadFileProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	for i, tc := range []struct {
+		progress           string
+		wantStderrContains string
+		wantErr            string
+	}{
+		{
+			progress:           "log",
+			wantStderrContains: "Uploading artifact.tgz: 100% (7 B of 7 B, ",
+		},
+		{
+			progress: "none",
+		},
+		{
+			progress: "verbose",
+			wantErr:  `invalid progress "verbose": must be one of "bar", "log" or "none"`,
+		},
+	} {
+		connInfo := publisher.BasicConnectionInfo{
+			SkipUploadVerification: true,
+			Progress:               tc.progress,
+		}
+		buf := &bytes.Buffer{}
+		var err error
+		stderr := captureStderr(t, func() {
+			_, err = connInfo.UploadFile(publisher.NewFileInfoFromBytes([]byte("content")), server.URL+"/repo", "artifact.tgz", nil, false, buf)
+		})
+		if tc.wantErr != "" {
+			assert.EqualError(t, err, tc.wantErr, "Case %d", i)
+			continue
+		}
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, "Uploading to "+server.URL+"/repo/artifact.tgz\n", buf.String(), "Case %d", i)
+		if tc.wantStderrContains == "" {
+			assert.Equal(t, "", stderr, "Case %d", i)
+		}
+		assert.Contains(t, stderr, tc.wantStderrContains, "Case %d", i)
+	}
+}
+
+func captureStderr(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	origStderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = origStderr
+	}()
+
+	outputC := make(chan string)
+	go func() {
+		output, _ := ioutil.ReadAll(r)
+		outputC <- string(output)
+	}()
+	fn()
+	require.NoError(t, w.Close())
+	return <-outputC
+}
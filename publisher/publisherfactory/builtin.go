@@ -21,15 +21,35 @@ import (
 	artifactoryconfig "github.com/palantir/distgo/publisher/artifactory/config"
 	"github.com/palantir/distgo/publisher/bintray"
 	bintrayconfig "github.com/palantir/distgo/publisher/bintray/config"
+	"github.com/palantir/distgo/publisher/gcs"
+	gcsconfig "github.com/palantir/distgo/publisher/gcs/config"
 	"github.com/palantir/distgo/publisher/github"
 	githubconfig "github.com/palantir/distgo/publisher/github/config"
+	"github.com/palantir/distgo/publisher/http"
+	httpconfig "github.com/palantir/distgo/publisher/http/config"
 	"github.com/palantir/distgo/publisher/mavenlocal"
 	mavenlocalconfig "github.com/palantir/distgo/publisher/mavenlocal/config"
+	"github.com/palantir/distgo/publisher/npm"
+	npmconfig "github.com/palantir/distgo/publisher/npm/config"
+	"github.com/palantir/distgo/publisher/s3"
+	s3config "github.com/palantir/distgo/publisher/s3/config"
 )
 
 type creatorWithUpgrader struct {
 	Creator  publisher.Creator
 	Upgrader distgo.ConfigUpgrader
+	// Config is a value of the configuration struct for the publisher.
+	Config interface{}
+}
+
+// BuiltinConfigs returns a map from the type name of each built-in publisher to a value of the struct that represents
+// the configuration of the publisher.
+func BuiltinConfigs() map[string]interface{} {
+	configs := make(map[string]interface{})
+	for typeName, publisher := range builtinPublishers() {
+		configs[typeName] = publisher.Config
+	}
+	return configs
 }
 
 func builtinPublishers() map[string]creatorWithUpgrader {
@@ -37,18 +57,42 @@ func builtinPublishers() map[string]creatorWithUpgrader {
 		mavenlocal.TypeName: {
 			Creator:  mavenlocal.PublisherCreator(),
 			Upgrader: distgo.NewConfigUpgrader(mavenlocal.TypeName, mavenlocalconfig.UpgradeConfig),
+			Config:   mavenlocalconfig.MavenLocal{},
 		},
 		artifactory.TypeName: {
 			Creator:  artifactory.PublisherCreator(),
 			Upgrader: distgo.NewConfigUpgrader(artifactory.TypeName, artifactoryconfig.UpgradeConfig),
+			Config:   artifactoryconfig.Artifactory{},
 		},
 		bintray.TypeName: {
 			Creator:  bintray.PublisherCreator(),
 			Upgrader: distgo.NewConfigUpgrader(bintray.TypeName, bintrayconfig.UpgradeConfig),
+			Config:   bintrayconfig.Bintray{},
 		},
 		github.TypeName: {
 			Creator:  github.PublisherCreator(),
 			Upgrader: distgo.NewConfigUpgrader(github.TypeName, githubconfig.UpgradeConfig),
+			Config:   githubconfig.GitHub{},
+		},
+		gcs.TypeName: {
+			Creator:  gcs.PublisherCreator(),
+			Upgrader: distgo.NewConfigUpgrader(gcs.TypeName, gcsconfig.UpgradeConfig),
+			Config:   gcsconfig.GCS{},
+		},
+		s3.TypeName: {
+			Creator:  s3.PublisherCreator(),
+			Upgrader: distgo.NewConfigUpgrader(s3.TypeName, s3config.UpgradeConfig),
+			Config:   s3config.S3{},
+		},
+		npm.TypeName: {
+			Creator:  npm.PublisherCreator(),
+			Upgrader: distgo.NewConfigUpgrader(npm.TypeName, npmconfig.UpgradeConfig),
+			Config:   npmconfig.NPM{},
+		},
+		http.TypeName: {
+			Creator:  http.PublisherCreator(),
+			Upgrader: distgo.NewConfigUpgrader(http.TypeName, httpconfig.UpgradeConfig),
+			Config:   httpconfig.HTTP{},
 		},
 	}
 }
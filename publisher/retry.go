@@ -0,0 +1,118 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultUploadRetryBackoff is the amount of time to wait before the first retry of an upload if UploadRetryBackoff
+// is not specified.
+const DefaultUploadRetryBackoff = time.Second
+
+// RunWithUploadRetries runs the provided upload attempt function using the upload retry configuration of the
+// BasicConnectionInfo. See runWithRetries for the semantics of the attempt function.
+func (b *BasicConnectionInfo) RunWithUploadRetries(stdout io.Writer, description string, attempt func() (retryable bool, retryAfter time.Duration, err error)) error {
+	retries, backoff, err := b.uploadRetryParams()
+	if err != nil {
+		return err
+	}
+	return runWithRetries(retries, backoff, stdout, description, attempt)
+}
+
+func (b *BasicConnectionInfo) uploadRetryParams() (int, time.Duration, error) {
+	backoff := DefaultUploadRetryBackoff
+	if b.UploadRetryBackoff != "" {
+		var err error
+		if backoff, err = time.ParseDuration(b.UploadRetryBackoff); err != nil {
+			return 0, 0, errors.Wrapf(err, "invalid upload-retry-backoff")
+		}
+	}
+	return b.UploadRetries, backoff, nil
+}
+
+// runWithRetries runs the provided attempt function. If the attempt fails with an error that it reports as retryable,
+// it is retried up to the specified number of times, waiting for the specified backoff (which doubles after every
+// retry) between attempts. If the attempt function returns a positive retry-after duration, that duration is waited
+// instead of the backoff. If the final attempt fails after one or more retries, the returned error includes the number
+// of attempts that were made.
+func runWithRetries(retries int, backoff time.Duration, stdout io.Writer, description string, attempt func() (retryable bool, retryAfter time.Duration, err error)) error {
+	if backoff <= 0 {
+		backoff = DefaultUploadRetryBackoff
+	}
+	for currAttempt := 0; ; currAttempt++ {
+		retryable, retryAfter, err := attempt()
+		if err == nil {
+			return nil
+		}
+		if !retryable || currAttempt >= retries {
+			if currAttempt > 0 {
+				return errors.Wrapf(err, "failed after %d attempts", currAttempt+1)
+			}
+			return err
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		_, _ = fmt.Fprintf(stdout, "%s failed with a transient error, retrying in %v (retry %d of %d): %v\n", description, wait, currAttempt+1, retries, err)
+		time.Sleep(wait)
+		backoff *= 2
+	}
+}
+
+// IsRetryableStatus returns true if a request that received a response with the provided status code may succeed if
+// it is retried.
+func IsRetryableStatus(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+}
+
+// IsIdempotentMethod returns true if the provided HTTP method is idempotent, in which case a request that fails with
+// an unknown outcome can be retried without changing the result (see RFC 7231 section 4.2.2).
+func IsIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns the duration specified by the Retry-After header of the provided response header. The header may
+// specify either a number of seconds or an HTTP date. Returns 0 if the header is not present or is not valid.
+func RetryAfter(header http.Header) time.Duration {
+	val := header.Get("Retry-After")
+	if val == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(val); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(val); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
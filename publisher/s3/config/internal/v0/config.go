@@ -0,0 +1,59 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"github.com/palantir/distgo/publisher"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Config struct {
+	// BasicConnectionInfo specifies the S3 endpoint and the credentials used to authenticate. "url" is the endpoint
+	// (for example, "https://s3.us-west-2.amazonaws.com"), "username" is the access key ID and "password" is the secret
+	// access key. If "url" is not specified, the AWS endpoint for the region is used. If the credentials are not
+	// specified, the values of the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are used.
+	publisher.BasicConnectionInfo `yaml:",inline,omitempty"`
+	// Bucket is the name of the bucket to which the artifacts are uploaded.
+	Bucket string `yaml:"bucket,omitempty"`
+	// Prefix is the key prefix used for uploaded artifacts. Artifacts are uploaded to
+	// "<bucket>/<prefix>/<product>/<version>/<artifact>".
+	Prefix string `yaml:"prefix,omitempty"`
+	// Region is the AWS region of the bucket. If not specified, "us-east-1" is used.
+	Region string `yaml:"region,omitempty"`
+	// ACL is the canned ACL applied to the uploaded objects (for example, "public-read"). If not specified, no ACL is
+	// set and the bucket default is used.
+	ACL string `yaml:"acl,omitempty"`
+	// ContentType is the Content-Type of all of the uploaded objects. If not specified, the Content-Type of each object
+	// is inferred from its extension (see "content-types").
+	ContentType string `yaml:"content-type,omitempty"`
+	// MultipartThreshold is the size in bytes above which artifacts are uploaded using a multipart upload. Every part
+	// of a multipart upload is retried independently, and if a publish fails, publishing again resumes the unfinished
+	// upload by uploading only the parts that were not uploaded. If the endpoint does not support multipart uploads,
+	// the artifact is uploaded using a single request. If not specified, 104857600 (100 MiB) is used. If negative,
+	// multipart uploads are not used.
+	MultipartThreshold int64 `yaml:"multipart-threshold,omitempty"`
+	// PartSize is the size in bytes of the parts of a multipart upload. S3 requires every part other than the last one
+	// to be at least 5 MiB. If not specified, 67108864 (64 MiB) is used.
+	PartSize int64 `yaml:"part-size,omitempty"`
+}
+
+func UpgradeConfig(cfgBytes []byte) ([]byte, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(cfgBytes, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal s3 publisher v0 configuration")
+	}
+	return cfgBytes, nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/palantir/distgo/publisher"
+	"github.com/palantir/distgo/publisher/s3/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMultipartThreshold = 100 * 1024 * 1024
+	defaultPartSize           = 64 * 1024 * 1024
+)
+
+// useMultipartUpload returns true if a file of the provided size should be uploaded using a multipart upload.
+func useMultipartUpload(cfg config.S3, size int) bool {
+	threshold := cfg.MultipartThreshold
+	if threshold == 0 {
+		threshold = defaultMultipartThreshold
+	}
+	return threshold >= 0 && int64(size) > threshold
+}
+
+// multipartUpload uploads an object using the S3 multipart upload API.
+type multipartUpload struct {
+	cfg       config.S3
+	creds     credentials
+	client    *http.Client
+	bucketURL *url.URL
+	objectURL *url.URL
+	key       string
+	s3URI     string
+	stdout    io.Writer
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type listMultipartUploadsResult struct {
+	Uploads []struct {
+		Key      string `xml:"Key"`
+		UploadID string `xml:"UploadId"`
+	} `xml:"Upload"`
+}
+
+type listPartsResult struct {
+	IsTruncated          bool   `xml:"IsTruncated"`
+	NextPartNumberMarker string `xml:"NextPartNumberMarker"`
+	Parts                []part `xml:"Part"`
+}
+
+type part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size,omitempty"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []part   `xml:"Part"`
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// upload uploads the provided content as a multipart upload. If an unfinished multipart upload for the object already
+// exists, it is resumed and only the parts that are missing or whose content differs are uploaded. Returns false if
+// the endpoint does not support multipart uploads, in which case nothing is uploaded.
+func (u *multipartUpload) upload(content []byte) (bool, error) {
+	partSize := u.cfg.PartSize
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	var parts [][]byte
+	for start := int64(0); start < int64(len(content)); start += partSize {
+		end := start + partSize
+		if end > int64(len(content)) {
+			end = int64(len(content))
+		}
+		parts = append(parts, content[start:end])
+	}
+
+	uploadID, uploadedParts, err := u.existingUpload()
+	if err != nil {
+		return true, err
+	}
+	if uploadID != "" {
+		_, _ = fmt.Fprintf(u.stdout, "Resuming multipart upload %s for %s: %d of %d parts already uploaded\n", uploadID, u.s3URI, countMatchingParts(parts, uploadedParts), len(parts))
+	} else {
+		var supported bool
+		uploadID, supported, err = u.initiate()
+		if err != nil || !supported {
+			return supported, err
+		}
+	}
+
+	// progress is reported as parts complete: parts that were already uploaded count as progress immediately
+	progress, finishProgress, err := u.cfg.UploadProgressReader(bytes.NewReader(content), int64(len(content)), path.Base(u.key))
+	if err != nil {
+		return true, err
+	}
+	defer finishProgress()
+
+	var completed []part
+	for i, currPart := range parts {
+		partNumber := i + 1
+		etag := partETag(currPart)
+		if uploaded, ok := uploadedParts[partNumber]; !ok || uploaded.ETag != etag || uploaded.Size != int64(len(currPart)) {
+			if err := u.cfg.RunWithUploadRetries(u.stdout, fmt.Sprintf("Upload of part %d of %s", partNumber, u.s3URI), func() (bool, time.Duration, error) {
+				var retryable bool
+				var retryAfter time.Duration
+				retryable, retryAfter, etag, err = u.uploadPart(uploadID, partNumber, currPart)
+				return retryable, retryAfter, err
+			}); err != nil {
+				return true, errors.Wrapf(err, "multipart upload %s to %s failed (publishing again will resume the upload)", uploadID, u.s3URI)
+			}
+			_, _ = fmt.Fprintf(u.stdout, "Uploaded part %d of %d to %s\n", partNumber, len(parts), u.s3URI)
+		}
+		if _, err := io.CopyN(ioutil.Discard, progress, int64(len(currPart))); err != nil {
+			return true, errors.Wrapf(err, "failed to report progress of multipart upload to %s", u.s3URI)
+		}
+		completed = append(completed, part{
+			PartNumber: partNumber,
+			ETag:       etag,
+		})
+	}
+	if err := u.cfg.RunWithUploadRetries(u.stdout, fmt.Sprintf("Completion of multipart upload to %s", u.s3URI), func() (bool, time.Duration, error) {
+		return u.complete(uploadID, completed)
+	}); err != nil {
+		return true, errors.Wrapf(err, "failed to complete multipart upload %s to %s (publishing again will resume the upload)", uploadID, u.s3URI)
+	}
+	return true, nil
+}
+
+// existingUpload returns the ID and the uploaded parts of an unfinished multipart upload for the object. Returns an
+// empty ID if there is no such upload or if the endpoint does not support listing multipart uploads.
+func (u *multipartUpload) existingUpload() (string, map[int]part, error) {
+	status, body, _, err := u.do(http.MethodGet, u.bucketURL, url.Values{"uploads": {""}, "prefix": {u.key}}, nil, http.Header{})
+	if err != nil || status != http.StatusOK {
+		// resuming is best-effort: if unfinished uploads cannot be listed, start a new upload
+		return "", nil, nil
+	}
+	var uploads listMultipartUploadsResult
+	if err := xml.Unmarshal(body, &uploads); err != nil {
+		return "", nil, nil
+	}
+	uploadID := ""
+	for _, currUpload := range uploads.Uploads {
+		if currUpload.Key == u.key {
+			// uploads are listed in ascending order of initiation, so use the most recent one
+			uploadID = currUpload.UploadID
+		}
+	}
+	if uploadID == "" {
+		return "", nil, nil
+	}
+
+	uploadedParts := make(map[int]part)
+	marker := ""
+	for {
+		query := url.Values{"uploadId": {uploadID}}
+		if marker != "" {
+			query.Set("part-number-marker", marker)
+		}
+		status, body, _, err := u.do(http.MethodGet, u.objectURL, query, nil, http.Header{})
+		if err != nil || status != http.StatusOK {
+			return "", nil, nil
+		}
+		var result listPartsResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return "", nil, nil
+		}
+		for _, currPart := range result.Parts {
+			uploadedParts[currPart.PartNumber] = currPart
+		}
+		if !result.IsTruncated || result.NextPartNumberMarker == "" {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+	return uploadID, uploadedParts, nil
+}
+
+// initiate starts a new multipart upload and returns its ID. Returns false if the endpoint does not support multipart
+// uploads.
+func (u *multipartUpload) initiate() (string, bool, error) {
+	status, body, _, err := u.do(http.MethodPost, u.objectURL, url.Values{"uploads": {""}}, nil, objectHeader(u.cfg, u.key))
+	if err != nil {
+		return "", true, errors.Wrapf(err, "failed to initiate multipart upload to %s", u.s3URI)
+	}
+	switch {
+	case status == http.StatusNotImplemented || status == http.StatusMethodNotAllowed:
+		return "", false, nil
+	case status >= http.StatusBadRequest:
+		return "", true, errors.New(responseErrorMessage(fmt.Sprintf("initiating multipart upload to %s", u.s3URI), status, body))
+	}
+	var result initiateMultipartUploadResult
+	if err := xml.Unmarshal(body, &result); err != nil || result.UploadID == "" {
+		return "", true, errors.Errorf("initiating multipart upload to %s returned an invalid response: %s", u.s3URI, string(body))
+	}
+	return result.UploadID, true, nil
+}
+
+func (u *multipartUpload) uploadPart(uploadID string, partNumber int, content []byte) (bool, time.Duration, string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	status, body, header, err := u.do(http.MethodPut, u.objectURL, query, content, http.Header{})
+	if err != nil {
+		return true, 0, "", errors.Wrapf(err, "failed to upload part %d to %s", partNumber, u.s3URI)
+	}
+	if status >= http.StatusBadRequest {
+		return publisher.IsRetryableStatus(status), publisher.RetryAfter(header), "", errors.New(responseErrorMessage(fmt.Sprintf("uploading part %d to %s", partNumber, u.s3URI), status, body))
+	}
+	etag := header.Get("ETag")
+	if etag == "" {
+		etag = partETag(content)
+	}
+	return false, 0, etag, nil
+}
+
+func (u *multipartUpload) complete(uploadID string, parts []part) (bool, time.Duration, error) {
+	reqBody, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return false, 0, errors.Wrapf(err, "failed to marshal request to complete multipart upload")
+	}
+	status, body, header, err := u.do(http.MethodPost, u.objectURL, url.Values{"uploadId": {uploadID}}, reqBody, http.Header{})
+	if err != nil {
+		return true, 0, errors.Wrapf(err, "failed to complete multipart upload to %s", u.s3URI)
+	}
+	if status >= http.StatusBadRequest {
+		return publisher.IsRetryableStatus(status), publisher.RetryAfter(header), errors.New(responseErrorMessage(fmt.Sprintf("completing multipart upload to %s", u.s3URI), status, body))
+	}
+	// S3 can respond with 200 OK and report an error in the body once the response has started
+	var respErr s3Error
+	if xml.Unmarshal(body, &respErr) == nil && respErr.Code != "" {
+		return respErr.Code == "InternalError" || respErr.Code == "SlowDown", 0, errors.Errorf("completing multipart upload to %s failed: %s: %s", u.s3URI, respErr.Code, respErr.Message)
+	}
+	return false, 0, nil
+}
+
+// do sends a signed request with the provided query and body to the provided URL and returns the status, body and
+// headers of the response.
+func (u *multipartUpload) do(method string, reqURL *url.URL, query url.Values, body []byte, header http.Header) (int, []byte, http.Header, error) {
+	urlWithQuery := *reqURL
+	urlWithQuery.RawQuery = query.Encode()
+	req, err := http.NewRequest(method, urlWithQuery.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, errors.Wrapf(err, "failed to create request for %s", u.s3URI)
+	}
+	req.ContentLength = int64(len(body))
+	req.Header = header
+	signRequest(req, hexSHA256(body), u.cfg.Region, u.creds, time.Now())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer func() {
+		// body has been read completely, so nothing to be done if close fails
+		_ = resp.Body.Close()
+	}()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, errors.Wrapf(err, "failed to read response for %s", u.s3URI)
+	}
+	return resp.StatusCode, respBody, resp.Header, nil
+}
+
+func responseErrorMessage(action string, status int, body []byte) string {
+	msg := fmt.Sprintf("%s resulted in response %q", action, fmt.Sprintf("%d %s", status, http.StatusText(status)))
+	if len(body) > 0 {
+		msg += ":\n" + string(body)
+	}
+	return msg
+}
+
+// partETag returns the ETag that S3 assigns to a part with the provided content (the quoted hex-encoded MD5 hash).
+func partETag(content []byte) string {
+	sum := md5.Sum(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func countMatchingParts(parts [][]byte, uploadedParts map[int]part) int {
+	count := 0
+	for i, currPart := range parts {
+		if uploaded, ok := uploadedParts[i+1]; ok && uploaded.ETag == partETag(currPart) && uploaded.Size == int64(len(currPart)) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,302 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/palantir/distgo/distgo"
+	"github.com/palantir/distgo/publisher"
+	"github.com/palantir/distgo/publisher/s3/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	TypeName = "s3"
+
+	defaultRegion = "us-east-1"
+)
+
+type s3Publisher struct{}
+
+func PublisherCreator() publisher.Creator {
+	return publisher.NewCreator(TypeName, func() distgo.Publisher {
+		return &s3Publisher{}
+	})
+}
+
+func (p *s3Publisher) TypeName() (string, error) {
+	return TypeName, nil
+}
+
+var (
+	s3PublisherBucketFlag = distgo.PublisherFlag{
+		Name:        "bucket",
+		Description: "S3 bucket to which the artifacts are uploaded",
+		Type:        distgo.StringFlag,
+	}
+	s3PublisherPrefixFlag = distgo.PublisherFlag{
+		Name:        "prefix",
+		Description: "key prefix for the uploaded artifacts",
+		Type:        distgo.StringFlag,
+	}
+	s3PublisherRegionFlag = distgo.PublisherFlag{
+		Name:        "region",
+		Description: "AWS region of the bucket (if blank, us-east-1 is used)",
+		Type:        distgo.StringFlag,
+	}
+	s3PublisherACLFlag = distgo.PublisherFlag{
+		Name:        "acl",
+		Description: "canned ACL applied to the uploaded artifacts (such as public-read)",
+		Type:        distgo.StringFlag,
+	}
+	s3PublisherContentTypeFlag = distgo.PublisherFlag{
+		Name:        "content-type",
+		Description: "Content-Type of the uploaded artifacts (if blank, the Content-Type is inferred from the extension of each artifact)",
+		Type:        distgo.StringFlag,
+	}
+)
+
+func (p *s3Publisher) Flags() ([]distgo.PublisherFlag, error) {
+	return append(
+		publisher.BasicConnectionInfoFlags(),
+		s3PublisherBucketFlag,
+		s3PublisherPrefixFlag,
+		s3PublisherRegionFlag,
+		s3PublisherACLFlag,
+		s3PublisherContentTypeFlag,
+	), nil
+}
+
+func (p *s3Publisher) RunPublish(productTaskOutputInfo distgo.ProductTaskOutputInfo, cfgYML []byte, flagVals map[distgo.PublisherFlagName]interface{}, dryRun bool, stdout io.Writer) error {
+	var cfg config.S3
+	if err := yaml.Unmarshal(cfgYML, &cfg); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal configuration")
+	}
+	if err := publisher.SetRequiredStringConfigValue(flagVals, s3PublisherBucketFlag, &cfg.Bucket); err != nil {
+		return err
+	}
+	if err := publisher.SetConfigValues(flagVals,
+		publisher.ConnectionInfoURLFlag, &cfg.URL,
+		publisher.ConnectionInfoUsernameFlag, &cfg.Username,
+		publisher.ConnectionInfoPasswordFlag, &cfg.Password,
+		s3PublisherPrefixFlag, &cfg.Prefix,
+		s3PublisherRegionFlag, &cfg.Region,
+		s3PublisherACLFlag, &cfg.ACL,
+		s3PublisherContentTypeFlag, &cfg.ContentType,
+	); err != nil {
+		return err
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultRegion
+	}
+	if cfg.URL == "" {
+		cfg.URL = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	creds := credentials{
+		AccessKeyID:     cfg.Username,
+		SecretAccessKey: cfg.Password,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" {
+		creds.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if creds.SecretAccessKey == "" {
+		creds.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if !dryRun && (creds.AccessKeyID == "" || creds.SecretAccessKey == "") {
+		return errors.Errorf("S3 credentials must be specified using the username and password configuration values or the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+	}
+
+	for _, currDistID := range productTaskOutputInfo.Product.DistOutputInfos.DistIDs {
+		for _, currArtifactPath := range productTaskOutputInfo.ProductDistArtifactPaths()[currDistID] {
+			key := ObjectKey(cfg.Prefix, productTaskOutputInfo, path.Base(currArtifactPath))
+			if err := p.uploadFile(cfg, creds, currArtifactPath, key, dryRun, stdout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ObjectKey returns the key of the object to which the artifact with the provided name is uploaded. The key is of the
+// form "<prefix>/<product>/<version>/<artifact>".
+func ObjectKey(prefix string, productTaskOutputInfo distgo.ProductTaskOutputInfo, artifactName string) string {
+	return strings.TrimPrefix(path.Join(prefix, string(productTaskOutputInfo.Product.ID), productTaskOutputInfo.Project.Version, artifactName), "/")
+}
+
+func (p *s3Publisher) uploadFile(cfg config.S3, creds credentials, filePath, key string, dryRun bool, stdout io.Writer) error {
+	s3URI := fmt.Sprintf("s3://%s/%s", cfg.Bucket, key)
+	uploadURL, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s as URL", cfg.URL)
+	}
+	bucketURL := *uploadURL
+	bucketURL.Path = path.Join("/", uploadURL.Path, cfg.Bucket)
+	bucketURL.RawPath = awsURIEncode(bucketURL.Path, false)
+	uploadURL.Path = path.Join("/", uploadURL.Path, cfg.Bucket, key)
+	uploadURL.RawPath = awsURIEncode(uploadURL.Path, false)
+
+	distgo.PrintlnOrDryRunPrintln(stdout, fmt.Sprintf("Uploading %s to %s", filePath, s3URI), dryRun)
+	if dryRun {
+		distgo.DryRunPrintln(stdout, fmt.Sprintf("  PUT %s", uploadURL.String()))
+		// the signature depends on the content and time of the request, so only the scheme of the Authorization
+		// header is known in a dry run
+		header := objectHeader(cfg, key)
+		header.Set("Authorization", sigV4Algorithm+" [REDACTED]")
+		publisher.DryRunPrintlnHeaders(stdout, header)
+		return nil
+	}
+
+	fileInfo, err := publisher.NewFileInfo(filePath)
+	if err != nil {
+		return err
+	}
+
+	uploaded := false
+	if useMultipartUpload(cfg, len(fileInfo.Bytes)) {
+		client, err := cfg.HTTPClient()
+		if err != nil {
+			return err
+		}
+		upload := &multipartUpload{
+			cfg:       cfg,
+			creds:     creds,
+			client:    client,
+			bucketURL: &bucketURL,
+			objectURL: uploadURL,
+			key:       key,
+			s3URI:     s3URI,
+			stdout:    stdout,
+		}
+		if uploaded, err = upload.upload(fileInfo.Bytes); err != nil {
+			return err
+		}
+		if !uploaded {
+			_, _ = fmt.Fprintf(stdout, "Multipart uploads are not supported by %s, uploading %s using a single request\n", cfg.URL, filePath)
+		}
+	}
+	if !uploaded {
+		if err := cfg.RunWithUploadRetries(stdout, fmt.Sprintf("Upload to %s", s3URI), func() (bool, time.Duration, error) {
+			return putObject(cfg, creds, fileInfo, uploadURL, filePath, s3URI, stdout)
+		}); err != nil {
+			return err
+		}
+	}
+	if cfg.SkipUploadVerification {
+		return nil
+	}
+	return verifyObject(cfg, creds, fileInfo, uploadURL, s3URI, stdout)
+}
+
+// verifyObject sends a HEAD request for the uploaded object and verifies that its size matches the size of the local
+// file. If the HEAD request fails, a warning is printed and verification is skipped.
+func verifyObject(cfg config.S3, creds credentials, fileInfo publisher.FileInfo, uploadURL *url.URL, s3URI string, stdout io.Writer) (rErr error) {
+	req, err := http.NewRequest(http.MethodHead, uploadURL.String(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", s3URI)
+	}
+	signRequest(req, hexSHA256(nil), cfg.Region, creds, time.Now())
+
+	client, err := cfg.HTTPClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "Unable to verify upload to %s: %v\n", s3URI, err)
+		return nil
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for %s", s3URI)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = fmt.Fprintf(stdout, "Unable to verify upload to %s: HEAD request resulted in response %q\n", s3URI, resp.Status)
+		return nil
+	}
+	if _, err := publisher.VerifyRemoteArtifact(resp.Header, resp.ContentLength, fileInfo); err != nil {
+		return errors.Wrapf(err, "verification of upload to %s failed", s3URI)
+	}
+	return nil
+}
+
+// putObject performs a single attempt of uploading the provided file to the provided URL. Returns true if the attempt
+// failed with an error for which the upload can be retried along with the retry-after duration requested by the server.
+func putObject(cfg config.S3, creds credentials, fileInfo publisher.FileInfo, uploadURL *url.URL, filePath, s3URI string, stdout io.Writer) (rRetryable bool, rRetryAfter time.Duration, rErr error) {
+	reader, finishProgress, err := cfg.UploadProgressReader(bytes.NewReader(fileInfo.Bytes), int64(len(fileInfo.Bytes)), path.Base(filePath))
+	if err != nil {
+		return false, 0, err
+	}
+	defer finishProgress()
+
+	req, err := http.NewRequest(http.MethodPut, uploadURL.String(), ioutil.NopCloser(reader))
+	if err != nil {
+		return false, 0, errors.Wrapf(err, "failed to create request for %s", s3URI)
+	}
+	req.ContentLength = int64(len(fileInfo.Bytes))
+	req.Header = objectHeader(cfg, path.Base(uploadURL.Path))
+	// sign for every attempt so that the X-Amz-Date of a retried request is current
+	signRequest(req, fileInfo.Checksums.SHA256, cfg.Region, creds, time.Now())
+
+	client, err := cfg.HTTPClient()
+	if err != nil {
+		return false, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, 0, errors.Wrapf(err, "failed to upload %s to %s", filePath, s3URI)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for %s", s3URI)
+		}
+	}()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg := fmt.Sprintf("uploading %s to %s resulted in response %q", filePath, s3URI, resp.Status)
+		if body, err := ioutil.ReadAll(resp.Body); err == nil && len(body) > 0 {
+			msg += ":\n" + string(body)
+		}
+		return publisher.IsRetryableStatus(resp.StatusCode), publisher.RetryAfter(resp.Header), errors.New(msg)
+	}
+	return false, 0, nil
+}
+
+// objectHeader returns the headers that specify the metadata of the uploaded object with the provided key. If the
+// configuration does not specify a ContentType, the Content-Type is inferred from the extension of the key.
+func objectHeader(cfg config.S3, key string) http.Header {
+	contentType := cfg.ContentType
+	if contentType == "" {
+		contentType = publisher.ContentType(key, cfg.ContentTypes)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if cfg.ACL != "" {
+		header.Set("X-Amz-Acl", cfg.ACL)
+	}
+	return header
+}
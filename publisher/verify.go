@@ -0,0 +1,86 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publisher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VerifyRemoteArtifact verifies that the artifact described by the provided response to a HEAD request for an
+// uploaded artifact matches the provided local file. If the response includes an X-Checksum-Sha256 header, the
+// checksum is compared with the SHA-256 checksum of the file. Otherwise, if the response specifies a content length,
+// it is compared with the size of the file. Returns false if the response does not provide any information that can be
+// used for verification, and returns an error if the remote artifact does not match the local file.
+func VerifyRemoteArtifact(header http.Header, contentLength int64, fileInfo FileInfo) (bool, error) {
+	if remoteSHA256 := header.Get("X-Checksum-Sha256"); remoteSHA256 != "" && fileInfo.Checksums.SHA256 != "" {
+		if !strings.EqualFold(remoteSHA256, fileInfo.Checksums.SHA256) {
+			return true, errors.Errorf("remote sha256 checksum %s does not match local sha256 checksum %s", remoteSHA256, fileInfo.Checksums.SHA256)
+		}
+		return true, nil
+	}
+	if contentLength >= 0 {
+		if contentLength != fileInfo.Size() {
+			return true, errors.Errorf("remote size %d bytes does not match local size %d bytes", contentLength, fileInfo.Size())
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// verifyUpload sends a HEAD request with the provided header for the uploaded artifact and verifies that the remote
+// artifact matches the provided local file. If the registry does not support verification (the HEAD request fails or
+// the response does not provide a checksum or size), a warning is printed and verification is skipped.
+func (b *BasicConnectionInfo) verifyUpload(fileInfo FileInfo, uploadURL *url.URL, reqHeader http.Header, rawUploadURL string, stdout io.Writer) (rErr error) {
+	req := http.Request{
+		Method: http.MethodHead,
+		URL:    uploadURL,
+		Header: copyHeader(reqHeader),
+	}
+	b.AuthorizeRequest(&req)
+
+	client, err := b.HTTPClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(&req)
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "Unable to verify upload to %s: %v\n", rawUploadURL, err)
+		return nil
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close response body for URL %s", rawUploadURL)
+		}
+	}()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		_, _ = fmt.Fprintf(stdout, "Unable to verify upload to %s: HEAD request resulted in response %q\n", rawUploadURL, resp.Status)
+		return nil
+	}
+
+	verified, err := VerifyRemoteArtifact(resp.Header, resp.ContentLength, fileInfo)
+	if err != nil {
+		return errors.Wrapf(err, "verification of upload to %s failed", rawUploadURL)
+	}
+	if !verified {
+		_, _ = fmt.Fprintf(stdout, "Unable to verify upload to %s: registry did not provide a checksum or size for the artifact\n", rawUploadURL)
+	}
+	return nil
+}